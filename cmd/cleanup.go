@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"fancy-login/internal/config"
+	"fancy-login/internal/platform"
+	"fancy-login/internal/utils"
+)
+
+// runCleanupCommand handles `fancy-login-go cleanup`, removing the profile
+// scripts of terminals that haven't logged in for a while
+func runCleanupCommand(args []string) int {
+	fs := flag.NewFlagSet("cleanup", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "Only list what would be removed")
+	olderThan := fs.Duration("older-than", 24*time.Hour, "Remove profile scripts not written for this long")
+	if err := fs.Parse(args); err != nil {
+		return utils.ExitUsage
+	}
+
+	paths, err := platform.SessionProfileScripts()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s❌ %v%s\n", config.Error, err, config.Reset)
+		return utils.ExitFailure
+	}
+	stale := staleProfileScripts(paths, config.NewConfig().AWSProfileTemp, *olderThan, time.Now())
+	if len(stale) == 0 {
+		fmt.Println("No stale profile scripts.")
+		return utils.ExitOK
+	}
+
+	failed := false
+	for _, path := range stale {
+		if *dryRun {
+			fmt.Printf("Would remove %s\n", path)
+			continue
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			fmt.Fprintf(os.Stderr, "%s❌ Failed to remove %s: %v%s\n", config.Error, path, err, config.Reset)
+			failed = true
+			continue
+		}
+		fmt.Printf("🧹 Removed %s\n", path)
+	}
+	if failed {
+		return utils.ExitFailure
+	}
+	return utils.ExitOK
+}
+
+// staleProfileScripts returns the paths last written more than maxAge
+// before now. The script of the current terminal is kept regardless, as is
+// its .bat twin on Windows.
+func staleProfileScripts(paths []string, current string, maxAge time.Duration, now time.Time) []string {
+	keep := make(map[string]bool)
+	for _, script := range platform.ProfileScripts(current, "") {
+		keep[script.Path] = true
+	}
+	var stale []string
+	for _, path := range paths {
+		if keep[path] {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil || now.Sub(info.ModTime()) <= maxAge {
+			continue
+		}
+		stale = append(stale, path)
+	}
+	return stale
+}
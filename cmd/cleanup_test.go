@@ -0,0 +1,33 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStaleProfileScripts(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	write := func(name string, age time.Duration) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("export AWS_PROFILE=dev\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, now.Add(-age), now.Add(-age)); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	old := write("aws_profile.100.sh", 48*time.Hour)
+	fresh := write("aws_profile.200.sh", time.Hour)
+	current := write("aws_profile.300.sh", 48*time.Hour)
+	gone := filepath.Join(dir, "aws_profile.400.sh")
+
+	stale := staleProfileScripts([]string{old, fresh, current, gone}, current, 24*time.Hour, now)
+	if expected := []string{old}; !reflect.DeepEqual(stale, expected) {
+		t.Errorf("Expected %v, got %v", expected, stale)
+	}
+}
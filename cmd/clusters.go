@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"fancy-login/internal/aws"
+	"fancy-login/internal/config"
+	"fancy-login/internal/k8s"
+	"fancy-login/internal/prompt"
+	"fancy-login/internal/utils"
+)
+
+// runClustersCommand handles `fancy-login-go clusters list`
+func runClustersCommand(args []string) int {
+	if len(args) == 0 || args[0] != "list" {
+		fmt.Fprintln(os.Stderr, "Usage: fancy-login-go clusters list [--profile NAME] [--region REGION]")
+		return utils.ExitUsage
+	}
+	return runClustersList(args[1:])
+}
+
+// runClustersList shows the EKS clusters a profile can see. In a terminal,
+// for a configured profile, it offers to create a context for one and map
+// the profile to it, as the wizard does.
+func runClustersList(args []string) int {
+	fs := flag.NewFlagSet("clusters list", flag.ContinueOnError)
+	profileName := fs.String("profile", "", "Profile whose clusters to list (default: $AWS_PROFILE)")
+	regionName := fs.String("region", "", "Region to list the clusters of (default: the profile's region)")
+	verboseOutput := fs.Bool("v", false, "Enable verbose output")
+	if err := fs.Parse(args); err != nil {
+		return utils.ExitUsage
+	}
+
+	fancyConfig, err := config.LoadFancyConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s❌ %v%s\n", config.Error, err, config.Reset)
+		return utils.ExitConfig
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	cfg := config.NewConfig()
+	cfg.FancyVerbose = *verboseOutput
+	awsManager := aws.NewAWSManager(cfg, utils.NewLogger(cfg.FancyVerbose), fancyConfig)
+	profile, _, err := awsManager.ResolveProfile(ctx, aws.ProfileRequest{Flag: *profileName, UseEnv: true, NoExport: true})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s❌ %v%s\n", config.Error, err, config.Reset)
+		return utils.ExitCode(err, utils.ExitConfig)
+	}
+	if fancyConfig.IsKubeOnlyProfile(profile) {
+		fmt.Fprintf(os.Stderr, "%s❌ %s is a kube-only profile and has no AWS credentials%s\n", config.Error, profile, config.Reset)
+		return utils.ExitConfig
+	}
+	region := *regionName
+	if region == "" {
+		region = fancyConfig.RegionForProfile(profile)
+	}
+
+	if _, configured := fancyConfig.ProfileConfigs[profile]; configured && prompt.Interactive() {
+		if err := config.RunClusterDiscovery(fancyConfig, profile, region); err != nil {
+			return utils.ExitCode(err, utils.ExitFailure)
+		}
+		return utils.ExitOK
+	}
+
+	clusters, err := k8s.ListEKSClusters(ctx, fancyConfig.Settings.AWSNetworkTimeoutDuration(), profile, region)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s❌ %v%s\n", config.Error, err, config.Reset)
+		return utils.ExitCode(err, utils.ExitFailure)
+	}
+	if len(clusters) == 0 {
+		fmt.Printf("%sNo EKS clusters in %s for %s%s\n", config.Muted, region, profile, config.Reset)
+		return utils.ExitOK
+	}
+	for _, name := range clusters {
+		fmt.Println(name)
+	}
+	return utils.ExitOK
+}
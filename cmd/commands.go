@@ -0,0 +1,207 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"fancy-login/internal/config"
+	"fancy-login/internal/utils"
+)
+
+// command is a fancy-login-go subcommand
+type command struct {
+	name string
+	// usage lists the arguments after the name, shown in help
+	usage   string
+	summary string
+	run     func(args []string) int
+}
+
+// commands are the subcommands in help order. login is the default.
+var commands = []command{
+	{"login", "[OPTIONS] [PROFILE]", "Select a profile, log in and switch context (default)", runLoginCommand},
+	{"switch", "[OPTIONS] [PREFIX]", "Log in to the previous profile, or a recent one by prefix", runSwitchCommand},
+	{"config", "[--dry-run|schema|init|preview|validate|edit|show|set|export|import]", "Run the configuration wizard, or a config tool", runConfigCommand},
+	{"profiles", "[list|discover] [--names]", "List profiles as the picker shows them, audit them with list or add them with discover", runProfilesCommand},
+	{"version", "", "Show version information", runVersionCommand},
+	{"self-update", "[--check]", "Update to the latest GitHub release", runSelfUpdateCommand},
+	{"ecr", "[--profile NAME] [--registry HOST]", "Log in to a single ECR registry", runECRCommand},
+	{"status", "[--refresh] [--output text|json]", "Show the current session and the status of every AWS profile", runStatusCommand},
+	{"whoami", "[--cached] [--profile NAME] [-v]", "Show the caller identity of the current profile", runWhoamiCommand},
+	{"tmux-status", "[--pane ID]", "Print a tmux status-right fragment", runTmuxStatus},
+	{"k9s", "[--profile NAME]", "Switch to the profile's context and open k9s, skipping AWS", runK9sCommand},
+	{"clusters", "list [--profile NAME] [--region REGION]", "List the profile's EKS clusters and create a context for one", runClustersCommand},
+	{"logout", "[--no-ecr] [PROFILE]", "End the SSO session and clear what login left behind", runLogoutCommand},
+	{"doctor", "", "Check external tools and config files", runDoctorCommand},
+	{"undo", "", "Restore the git identity fancy-login last changed", runUndoCommand},
+	{"install-credential-helper", "[--dry-run]", "Let docker fetch ECR tokens through fancy-login", runInstallCredentialHelper},
+	{"init", "[sh|bash|zsh|powershell|cmd]", "Print the shell integration for your rc file", runInitCommand},
+	{"cleanup", "[--dry-run] [--older-than 24h]", "Remove profile scripts of terminals that are long gone", runCleanupCommand},
+	{"uninstall", "[--dry-run] [--yes]", "Remove files fancy-login created", runUninstall},
+}
+
+// hiddenCommands are run by other programs rather than people, so help
+// and typo suggestions leave them out
+var hiddenCommands = []command{
+	{"credential-process", "--profile NAME", "Print the profile's credentials for credential_process", runCredentialProcessCommand},
+	{"docker-credential", "get|store|erase|list", "Answer docker's credential helper requests", runDockerCredentialCommand},
+}
+
+// findCommand returns the subcommand called name
+func findCommand(name string) *command {
+	for i := range commands {
+		if commands[i].name == name {
+			return &commands[i]
+		}
+	}
+	for i := range hiddenCommands {
+		if hiddenCommands[i].name == name {
+			return &hiddenCommands[i]
+		}
+	}
+	return nil
+}
+
+// isHiddenCommand reports whether name is one of hiddenCommands
+func isHiddenCommand(name string) bool {
+	for _, cmd := range hiddenCommands {
+		if cmd.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// takeAllowRoot removes --allow-root from args, wherever it is before a
+// "--", and reports whether it was there. It applies to every command, so
+// their own flag sets never see it.
+func takeAllowRoot(args []string) (bool, []string) {
+	allowRoot := false
+	rest := make([]string, 0, len(args))
+	for i, arg := range args {
+		if arg == "--" {
+			rest = append(rest, args[i:]...)
+			break
+		}
+		if arg == "--allow-root" || arg == "-allow-root" {
+			allowRoot = true
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	return allowRoot, rest
+}
+
+// dispatch runs the subcommand named by the first argument. Without one,
+// or when it is a flag or a profile name, it runs login, so invocations from
+// before the subcommands existed keep working.
+func dispatch(args []string) int {
+	allowRoot, args := takeAllowRoot(args)
+	// Running as root would silently use root's configs; under sudo the
+	// user may switch back to their own, with ownership fixed up on exit.
+	// This comes before resolving the command, which reads the AWS config.
+	// The hidden commands are run by programs that can't answer it.
+	if len(args) == 0 || !isHiddenCommand(args[0]) {
+		activeRootGuard = checkRoot(allowRoot)
+		defer activeRootGuard.fixOwnership()
+		if activeRootGuard != nil {
+			applyConfiguredTheme()
+		}
+	}
+
+	snapshot := config.NewSnapshot(nil)
+	cmd, rest, err := resolveCommand(args, func(name string) bool {
+		return isKnownProfile(snapshot, name)
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		printCommands(os.Stderr)
+		return utils.ExitUsage
+	}
+	return cmd.run(rest)
+}
+
+// resolveCommand picks the subcommand for args. A word that is no command
+// and no profile but close to a command name is reported as a typo instead
+// of being searched for in the picker.
+func resolveCommand(args []string, isProfile func(string) bool) (*command, []string, error) {
+	login := findCommand("login")
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return login, args, nil
+	}
+	if cmd := findCommand(args[0]); cmd != nil {
+		return cmd, args[1:], nil
+	}
+	if !isProfile(args[0]) {
+		if suggestion := suggestCommand(args[0]); suggestion != "" {
+			return nil, nil, fmt.Errorf("unknown command %q, did you mean %q?", args[0], suggestion)
+		}
+	}
+	return login, args, nil
+}
+
+// suggestCommand returns the command name word most likely misspells, or
+// "" if it resembles none
+func suggestCommand(word string) string {
+	best, bestDistance := "", 3
+	for _, cmd := range commands {
+		if strings.HasPrefix(cmd.name, word) && len(word) >= 3 {
+			return cmd.name
+		}
+		if d := editDistance(word, cmd.name); d < bestDistance {
+			best, bestDistance = cmd.name, d
+		}
+	}
+	return best
+}
+
+// editDistance is the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(prev[j]+1, current[j-1]+1, prev[j-1]+cost)
+		}
+		prev = current
+	}
+	return prev[len(b)]
+}
+
+// isKnownProfile reports whether name is an AWS or kube-only profile
+func isKnownProfile(snapshot *config.Snapshot, name string) bool {
+	if _, ok := snapshot.AWSProfile(name); ok {
+		return true
+	}
+	fancyConfig, err := snapshot.FancyConfig()
+	return err == nil && fancyConfig.IsKubeOnlyProfile(name)
+}
+
+// printCommands lists the subcommands
+func printCommands(w *os.File) {
+	fmt.Fprintln(w, "\nCommands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-48s %s\n", strings.TrimSpace(cmd.name+" "+cmd.usage), cmd.summary)
+	}
+	fmt.Fprintln(w, "\nRun 'fancy-login-go <command> -h' for the options of a command.")
+}
+
+// runVersionCommand handles `fancy-login-go version`
+func runVersionCommand(args []string) int {
+	fs := flag.NewFlagSet("version", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return utils.ExitUsage
+	}
+	showVersion()
+	return utils.ExitOK
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestResolveCommand(t *testing.T) {
+	profiles := map[string]bool{"dev": true, "stats": true}
+	isProfile := func(name string) bool { return profiles[name] }
+
+	testCases := []struct {
+		name          string
+		args          []string
+		expected      string
+		expectedArgs  []string
+		expectedError string
+	}{
+		{"Bare invocation", nil, "login", nil, ""},
+		{"Legacy flags", []string{"-k", "--profile", "dev"}, "login", []string{"-k", "--profile", "dev"}, ""},
+		{"Legacy positional profile", []string{"dev", "-k"}, "login", []string{"dev", "-k"}, ""},
+		{"Explicit login", []string{"login", "-k", "dev"}, "login", []string{"-k", "dev"}, ""},
+		{"Config wizard", []string{"config"}, "config", []string{}, ""},
+		{"Config tool", []string{"config", "schema", "--json"}, "config", []string{"schema", "--json"}, ""},
+		{"Profiles", []string{"profiles", "--names"}, "profiles", []string{"--names"}, ""},
+		{"Version", []string{"version"}, "version", []string{}, ""},
+		{"Typo", []string{"stauts"}, "", nil, `did you mean "status"`},
+		{"Prefix", []string{"prof"}, "", nil, `did you mean "profiles"`},
+		{"Profile resembling a command", []string{"stats"}, "login", []string{"stats"}, ""},
+		{"Unrelated word is a picker query", []string{"staging"}, "login", []string{"staging"}, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cmd, args, err := resolveCommand(tc.args, isProfile)
+			if tc.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedError) {
+					t.Errorf("Expected error containing %q, got %v", tc.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if cmd.name != tc.expected {
+				t.Errorf("Expected command %s, got %s", tc.expected, cmd.name)
+			}
+			if len(args) != len(tc.expectedArgs) || (len(args) > 0 && !reflect.DeepEqual(args, tc.expectedArgs)) {
+				t.Errorf("Expected args %q, got %q", tc.expectedArgs, args)
+			}
+		})
+	}
+}
+
+func TestTakeAllowRoot(t *testing.T) {
+	testCases := []struct {
+		name         string
+		args         []string
+		expected     bool
+		expectedArgs []string
+	}{
+		{"Absent", []string{"status", "--refresh"}, false, []string{"status", "--refresh"}},
+		{"Before the command", []string{"--allow-root", "status"}, true, []string{"status"}},
+		{"After the command", []string{"logout", "--allow-root", "dev"}, true, []string{"logout", "dev"}},
+		{"Single dash", []string{"-allow-root", "-k"}, true, []string{"-k"}},
+		{"After --", []string{"login", "--", "--allow-root"}, false, []string{"login", "--", "--allow-root"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			allowRoot, args := takeAllowRoot(tc.args)
+			if allowRoot != tc.expected {
+				t.Errorf("Expected allowRoot %v, got %v", tc.expected, allowRoot)
+			}
+			if !reflect.DeepEqual(args, tc.expectedArgs) {
+				t.Errorf("Expected args %q, got %q", tc.expectedArgs, args)
+			}
+		})
+	}
+}
+
+func TestLegacyAndLoginArgsMatch(t *testing.T) {
+	legacy := []string{"-k", "--force-aws-login", "dev"}
+
+	cmd, args, err := resolveCommand(legacy, func(string) bool { return true })
+	if err != nil || cmd.name != "login" {
+		t.Fatalf("Expected the bare invocation to reach login, got %v, %v", cmd, err)
+	}
+	fromLegacy, err := parseLoginArgs(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, args, _ = resolveCommand(append([]string{"login"}, legacy...), func(string) bool { return true })
+	fromLogin, err := parseLoginArgs(args)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if *fromLegacy != *fromLogin {
+		t.Errorf("Expected identical options, got %+v and %+v", *fromLegacy, *fromLogin)
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"fancy-login/internal/compat"
+	"fancy-login/internal/config"
+	"fancy-login/internal/utils"
+)
+
+// runLegacyShim handles invocation as `fancy`, the shell script's name. It
+// rewrites os.Args for the new CLI, migrates the old mapping files on first
+// use and prints a deprecation notice. Delete it with internal/compat once
+// the shim is retired.
+func runLegacyShim() {
+	translated, err := compat.Translate(os.Args[1:])
+	if err != nil {
+		fmt.Printf("%s: %v\n", compat.LegacyName, err)
+		os.Exit(utils.ExitUsage)
+	}
+	fmt.Printf("%s⚠️  %s%s\n", config.Warning, compat.DeprecationNotice("fancy-login-go", translated), config.Reset)
+
+	snapshot := config.NewSnapshot(nil)
+	if _, err := snapshot.FancyConfig(); err != nil {
+		fmt.Printf("%s⚠️  Skipping legacy mapping migration: %v%s\n", config.Warning, err, config.Reset)
+	} else if result, err := compat.MigrateOnce(snapshot, compat.LegacyDirs(config.NewConfig())); err != nil {
+		fmt.Printf("%s⚠️  Legacy mapping migration failed: %v%s\n", config.Warning, err, config.Reset)
+	} else if result != nil {
+		fmt.Printf("%s🔹 Migrated %d context and %d namespace mappings from %s into %s%s\n", config.Accent,
+			len(result.Contexts), len(result.Namespaces), result.Source, config.GetFancyConfigPath(), config.Reset)
+	}
+
+	os.Args = append([]string{os.Args[0]}, translated...)
+}
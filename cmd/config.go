@@ -0,0 +1,118 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"fancy-login/internal/config"
+	"fancy-login/internal/utils"
+)
+
+// runConfigCommand handles `fancy-login-go config [subcommand]`; without a
+// subcommand it runs the configuration wizard
+func runConfigCommand(args []string) int {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		fs := flag.NewFlagSet("config", flag.ContinueOnError)
+		dryRun := fs.Bool("dry-run", false, "Walk the wizard and print the resulting YAML instead of saving it")
+		if err := fs.Parse(args); err != nil {
+			return utils.ExitUsage
+		}
+		return runConfigWizard(*dryRun)
+	}
+
+	switch args[0] {
+	case "schema":
+		return runConfigSchema(args[1:])
+	case "init":
+		return runConfigInit(args[1:])
+	case "preview":
+		return runConfigPreview(args[1:])
+	case "validate":
+		return runConfigValidate(args[1:])
+	case "edit":
+		return runConfigEdit(args[1:])
+	case "show":
+		return runConfigShow(args[1:])
+	case "set":
+		return runConfigSet(args[1:])
+	case "export":
+		return runConfigExport(args[1:])
+	case "import":
+		return runConfigImport(args[1:])
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown config command: %s\n", args[0])
+		fmt.Fprintln(os.Stderr, "Usage: fancy-login-go config [--dry-run|schema|init|preview|validate|edit|show|set|export|import] [options]")
+		fmt.Fprintln(os.Stderr, "Without a command, config runs the configuration wizard.")
+		return utils.ExitUsage
+	}
+}
+
+// runConfigWizard runs the interactive configuration wizard
+func runConfigWizard(dryRun bool) int {
+	wizard := config.NewConfigWizard()
+	wizard.SetDryRun(dryRun)
+	if err := wizard.Run(); err != nil {
+		fmt.Printf("Configuration wizard failed: %v\n", err)
+		return utils.ExitFailure
+	}
+	return utils.ExitOK
+}
+
+// runConfigSchema prints the profile configuration schema
+func runConfigSchema(args []string) int {
+	fs := flag.NewFlagSet("config schema", flag.ContinueOnError)
+	jsonOutput := fs.Bool("json", false, "Print the schema as JSON Schema")
+	if err := fs.Parse(args); err != nil {
+		return utils.ExitUsage
+	}
+
+	if *jsonOutput {
+		data, err := config.JSONSchema()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to generate schema: %v\n", err)
+			return utils.ExitFailure
+		}
+		fmt.Println(string(data))
+		return utils.ExitOK
+	}
+
+	fmt.Printf("%sProfile configuration keys:%s\n", config.Bold, config.Reset)
+	for _, field := range config.ProfileSchema {
+		def := ""
+		if field.Default != "" {
+			def = fmt.Sprintf(" (default: %s)", field.Default)
+		}
+		fmt.Printf("  %-16s %-8s %s%s\n", field.Key, field.Type, field.Description, def)
+	}
+	return utils.ExitOK
+}
+
+// runConfigInit writes a commented example configuration without the wizard
+func runConfigInit(args []string) int {
+	fs := flag.NewFlagSet("config init", flag.ContinueOnError)
+	force := fs.Bool("force", false, "Overwrite an existing configuration file")
+	if err := fs.Parse(args); err != nil {
+		return utils.ExitUsage
+	}
+
+	configPath := config.GetFancyConfigPath()
+	if _, err := os.Stat(configPath); err == nil && !*force {
+		fmt.Fprintf(os.Stderr, "%s already exists; use --force to overwrite it\n", configPath)
+		return utils.ExitFailure
+	}
+
+	profiles, err := config.ParseAWSProfiles(config.GetAWSConfigPath())
+	if err != nil {
+		fmt.Printf("%s⚠️  Could not read AWS profiles: %v%s\n", config.Warning, err, config.Reset)
+	}
+
+	if err := os.WriteFile(configPath, []byte(config.GenerateConfigTemplate(profiles)), 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write %s: %v\n", configPath, err)
+		return utils.ExitFailure
+	}
+
+	fmt.Printf("%s✅ Wrote example configuration with %d profiles to %s%s\n", config.Success, len(profiles), configPath, config.Reset)
+	return utils.ExitOK
+}
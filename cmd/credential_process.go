@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"fancy-login/internal/aws"
+	"fancy-login/internal/config"
+	"fancy-login/internal/prompt"
+	"fancy-login/internal/utils"
+)
+
+// runCredentialProcessCommand handles the hidden `fancy-login-go
+// credential-process --profile NAME`, meant for credential_process in
+// ~/.aws/config. stdout carries the credentials JSON and nothing else;
+// logs, the SSO login and errors go to stderr.
+func runCredentialProcessCommand(args []string) int {
+	fs := flag.NewFlagSet("credential-process", flag.ContinueOnError)
+	profile := fs.String("profile", "", "SSO profile whose credentials to print")
+	verboseOutput := fs.Bool("v", false, "Log each step to stderr")
+	if err := fs.Parse(args); err != nil {
+		return utils.ExitUsage
+	}
+	if *profile == "" || fs.NArg() > 0 {
+		fmt.Fprintln(os.Stderr, "Usage: fancy-login-go credential-process --profile NAME")
+		return utils.ExitUsage
+	}
+
+	// stdout carries only the credentials; the log and any question go to
+	// stderr
+	out := os.Stdout
+	prompt.SetOutput(os.Stderr)
+	defer prompt.SetOutput(nil)
+
+	fancyConfig, err := config.LoadFancyConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s❌ %v%s\n", config.Error, err, config.Reset)
+		return utils.ExitConfig
+	}
+	cfg := config.NewConfig()
+	cfg.FancyVerbose = *verboseOutput
+	logger := utils.NewLogger(cfg.FancyVerbose)
+	logger.SetOutput(os.Stderr)
+	awsManager := aws.NewAWSManager(cfg, logger, fancyConfig)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	creds, err := awsManager.ProcessCredentials(ctx, fancyConfig.ResolveProfileName(*profile))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s❌ %v%s\n", config.Error, err, config.Reset)
+		return utils.ExitCode(err, utils.ExitAWSAuth)
+	}
+	fmt.Fprintln(out, string(creds))
+	return utils.ExitOK
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"fancy-login/internal/utils"
+)
+
+// runCapturingStdout runs fn and returns what it wrote to stdout
+func runCapturingStdout(t *testing.T, fn func() int) (int, string) {
+	t.Helper()
+	old := os.Stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Stdout = w
+	output := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(r)
+		output <- data
+	}()
+	code := fn()
+	w.Close()
+	os.Stdout = old
+	return code, string(<-output)
+}
+
+func TestCredentialProcess(t *testing.T) {
+	home := setupLoginFixture(t, "")
+	loginLog := filepath.Join(home, "sso-login.log")
+	binDir := t.TempDir()
+	fakeAWS := "#!/bin/sh\ncase \"$1 $2\" in\n" +
+		"\"sso login\") echo 'Attempting to automatically open the SSO authorization page'; echo \"$4\" >> " + loginLog + " ;;\n" +
+		"\"configure export-credentials\") echo '{\"Version\": 1, \"AccessKeyId\": \"ASIATEMP\", \"SecretAccessKey\": \"secret\", \"SessionToken\": \"token\", \"Expiration\": \"2099-01-01T00:00:00+00:00\"}' ;;\n" +
+		"\"sts get-caller-identity\") echo '{\"Account\": \"123456789012\"}' ;;\n" +
+		"esac\n"
+	if err := os.WriteFile(filepath.Join(binDir, "aws"), []byte(fakeAWS), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	// Verbose output and the SSO login's own chatter must stay off stdout
+	code, stdout := runCapturingStdout(t, func() int {
+		return runCredentialProcessCommand([]string{"--profile", "dev", "-v"})
+	})
+	if code != utils.ExitOK {
+		t.Fatalf("Expected exit code 0, got %d", code)
+	}
+	if strings.Count(stdout, "\n") != 1 {
+		t.Errorf("Expected a single line of JSON on stdout, got %q", stdout)
+	}
+	var creds map[string]any
+	if err := json.Unmarshal([]byte(stdout), &creds); err != nil {
+		t.Fatalf("Expected JSON on stdout, got %q: %v", stdout, err)
+	}
+	for _, key := range []string{"Version", "AccessKeyId", "SecretAccessKey", "SessionToken", "Expiration"} {
+		if _, ok := creds[key]; !ok {
+			t.Errorf("Expected %s in %q", key, stdout)
+		}
+	}
+	if data, _ := os.ReadFile(loginLog); string(data) != "dev\n" {
+		t.Errorf("Expected one SSO login for dev without a cached token, got %q", data)
+	}
+	if os.Getenv("FANCY_CREDENTIAL_PROCESS") != "" {
+		t.Error("Expected the recursion guard to be cleared again")
+	}
+}
+
+func TestCredentialProcessErrors(t *testing.T) {
+	setupLoginFixture(t, "")
+
+	testCases := []struct {
+		name         string
+		args         []string
+		guard        string
+		expectedCode int
+	}{
+		{"Missing profile", nil, "", utils.ExitUsage},
+		{"Unknown profile", []string{"--profile", "nope"}, "", utils.ExitConfig},
+		{"Called from itself", []string{"--profile", "dev"}, "1", utils.ExitAWSAuth},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("FANCY_CREDENTIAL_PROCESS", tc.guard)
+			code, stdout := runCapturingStdout(t, func() int {
+				return runCredentialProcessCommand(tc.args)
+			})
+			if code != tc.expectedCode {
+				t.Errorf("Expected exit code %d, got %d", tc.expectedCode, code)
+			}
+			if stdout != "" {
+				t.Errorf("Expected nothing on stdout, got %q", stdout)
+			}
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"fancy-login/internal/aws"
+	"fancy-login/internal/config"
+	"fancy-login/internal/prompt"
+	"fancy-login/internal/utils"
+)
+
+// credentialHelperBinary is the executable docker runs for the credHelpers
+// entry "fancy-login"
+const credentialHelperBinary = "docker-credential-" + aws.CredentialHelperName
+
+// errCredentialsNotFound is the message docker's credential helper client
+// recognizes as "no credentials for this registry"
+const errCredentialsNotFound = "credentials not found in native keychain"
+
+// credentialHelperInvoked reports whether argv0 is the credential helper
+// name, as when docker runs it through a symlink
+func credentialHelperInvoked(argv0 string) bool {
+	return strings.TrimSuffix(filepath.Base(argv0), ".exe") == credentialHelperBinary
+}
+
+// dockerCredentials is what `get` prints for a registry
+type dockerCredentials struct {
+	ServerURL string `json:"ServerURL"`
+	Username  string `json:"Username"`
+	Secret    string `json:"Secret"`
+}
+
+// runDockerCredentialCommand handles the hidden `fancy-login-go
+// docker-credential ACTION`, the docker credential helper protocol. get
+// fetches a fresh ECR token for the registry read from stdin; store and
+// erase are accepted and ignored, since tokens are fetched on demand
+// rather than kept. Errors go to stdout, where docker reads them.
+func runDockerCredentialCommand(args []string) int {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: fancy-login-go docker-credential get|store|erase|list")
+		return utils.ExitUsage
+	}
+
+	// stdout is docker's; the log and any question go to stderr
+	out := os.Stdout
+	prompt.SetOutput(os.Stderr)
+	defer prompt.SetOutput(nil)
+
+	switch args[0] {
+	case "store", "erase":
+		_, _ = io.Copy(io.Discard, os.Stdin)
+		return utils.ExitOK
+	case "get", "list":
+	default:
+		fmt.Fprintf(out, "unknown credential helper action %q\n", args[0])
+		return utils.ExitUsage
+	}
+
+	fancyConfig, err := config.LoadFancyConfig()
+	if err != nil {
+		fmt.Fprintln(out, err)
+		return utils.ExitConfig
+	}
+	logger := utils.NewLogger(false)
+	logger.SetOutput(os.Stderr)
+	awsManager := aws.NewAWSManager(config.NewConfig(), logger, fancyConfig)
+
+	if args[0] == "list" {
+		hosts := make(map[string]string)
+		for _, registry := range awsManager.HelperRegistries() {
+			hosts[registry.Host()] = "AWS"
+		}
+		data, _ := json.Marshal(hosts)
+		fmt.Fprintln(out, string(data))
+		return utils.ExitOK
+	}
+
+	input, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		fmt.Fprintln(out, err)
+		return utils.ExitFailure
+	}
+	serverURL := strings.TrimSpace(string(input))
+	if _, err := aws.ParseRegistry(serverURL); err != nil {
+		fmt.Fprintln(out, errCredentialsNotFound)
+		return utils.ExitFailure
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	password, err := awsManager.RegistryPassword(ctx, serverURL)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(out, errCredentialsNotFound)
+		return utils.ExitCode(err, utils.ExitECR)
+	}
+	data, _ := json.Marshal(dockerCredentials{ServerURL: serverURL, Username: "AWS", Secret: password})
+	fmt.Fprintln(out, string(data))
+	return utils.ExitOK
+}
+
+// runInstallCredentialHelper handles `fancy-login-go install-credential-helper`
+func runInstallCredentialHelper(args []string) int {
+	fs := flag.NewFlagSet("install-credential-helper", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "List the registries without changing the docker config")
+	if err := fs.Parse(args); err != nil {
+		return utils.ExitUsage
+	}
+
+	fancyConfig, err := config.LoadFancyConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s❌ %v%s\n", config.Error, err, config.Reset)
+		return utils.ExitConfig
+	}
+	awsManager := aws.NewAWSManager(config.NewConfig(), utils.NewLogger(false), fancyConfig)
+
+	var hosts []string
+	for _, registry := range awsManager.HelperRegistries() {
+		hosts = append(hosts, registry.Host())
+	}
+	if len(hosts) == 0 {
+		fmt.Fprintf(os.Stderr, "%s❌ No registries to register: no profile with ecr_login has account_id or ecr_registries%s\n", config.Error, config.Reset)
+		return utils.ExitConfig
+	}
+
+	if *dryRun {
+		for _, host := range hosts {
+			fmt.Printf("%s would use %s\n", host, credentialHelperBinary)
+		}
+		return utils.ExitOK
+	}
+	path, err := aws.InstallCredentialHelper(hosts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s❌ %v%s\n", config.Error, err, config.Reset)
+		return utils.ExitFailure
+	}
+	for _, host := range hosts {
+		fmt.Printf("%s✅ %s uses %s%s\n", config.Success, host, credentialHelperBinary, config.Reset)
+	}
+	fmt.Printf("%sUpdated %s%s\n", config.Muted, path, config.Reset)
+
+	if _, err := exec.LookPath(credentialHelperBinary); err != nil {
+		fmt.Printf("%s⚠️  %s is not on PATH, so docker can't run it yet:%s\n", config.Warning, credentialHelperBinary, config.Reset)
+		fmt.Printf("   ln -s \"$(command -v fancy-login-go)\" ~/.local/bin/%s\n", credentialHelperBinary)
+	}
+	return utils.ExitOK
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"fancy-login/internal/utils"
+)
+
+// setupCredentialHelperFixture configures dev with its account and a
+// shared-services registry, and installs an aws CLI that hands out tokens
+// and logs in without a browser
+func setupCredentialHelperFixture(t *testing.T) string {
+	t.Helper()
+	home := setupLoginFixture(t, "")
+	fancyConfig := "profile_configs:\n" +
+		"  dev:\n    account_id: \"123456789012\"\n    ecr_login: true\n    ecr_region: eu-central-1\n" +
+		"    ecr_registries:\n      - account_id: \"123456789012\"\n      - account_id: \"111111111111\"\n        region: eu-west-1\n" +
+		"settings:\n  config_wizard_run: true\n"
+	if err := os.WriteFile(filepath.Join(home, ".fancy-config.yaml"), []byte(fancyConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	binDir := t.TempDir()
+	fakeAWS := "#!/bin/sh\ncase \"$1 $2\" in\n" +
+		"\"ecr get-login-password\") echo \"token-$4\" ;;\n" +
+		"\"sts get-caller-identity\") echo '{\"Account\": \"123456789012\"}' ;;\n" +
+		"esac\n"
+	if err := os.WriteFile(filepath.Join(binDir, "aws"), []byte(fakeAWS), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return home
+}
+
+// withStdin makes input the process's stdin for the rest of the test
+func withStdin(t *testing.T, input string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "stdin")
+	if err := os.WriteFile(path, []byte(input), 0600); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := os.Stdin
+	os.Stdin = f
+	t.Cleanup(func() {
+		os.Stdin = old
+		f.Close()
+	})
+}
+
+func TestDockerCredentialGet(t *testing.T) {
+	setupCredentialHelperFixture(t)
+	withStdin(t, "https://111111111111.dkr.ecr.eu-west-1.amazonaws.com\n")
+
+	code, stdout := runCapturingStdout(t, func() int {
+		return runDockerCredentialCommand([]string{"get"})
+	})
+	if code != utils.ExitOK {
+		t.Fatalf("Expected exit code 0, got %d: %s", code, stdout)
+	}
+	var creds dockerCredentials
+	if err := json.Unmarshal([]byte(stdout), &creds); err != nil {
+		t.Fatalf("Expected JSON on stdout, got %q: %v", stdout, err)
+	}
+	expected := dockerCredentials{ServerURL: "https://111111111111.dkr.ecr.eu-west-1.amazonaws.com", Username: "AWS", Secret: "token-eu-west-1"}
+	if creds != expected {
+		t.Errorf("Expected %+v, got %+v", expected, creds)
+	}
+}
+
+func TestDockerCredentialNotFound(t *testing.T) {
+	testCases := []struct {
+		name     string
+		registry string
+	}{
+		{"Not ECR", "ghcr.io"},
+		{"Unconfigured account", "999999999999.dkr.ecr.eu-west-1.amazonaws.com"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			setupCredentialHelperFixture(t)
+			withStdin(t, tc.registry+"\n")
+
+			code, stdout := runCapturingStdout(t, func() int {
+				return runDockerCredentialCommand([]string{"get"})
+			})
+			if code == utils.ExitOK || strings.TrimSpace(stdout) != errCredentialsNotFound {
+				t.Errorf("Expected %q and a failure, got %d: %q", errCredentialsNotFound, code, stdout)
+			}
+		})
+	}
+}
+
+func TestDockerCredentialList(t *testing.T) {
+	setupCredentialHelperFixture(t)
+
+	code, stdout := runCapturingStdout(t, func() int {
+		return runDockerCredentialCommand([]string{"list"})
+	})
+	if code != utils.ExitOK {
+		t.Fatalf("Expected exit code 0, got %d", code)
+	}
+	var hosts map[string]string
+	if err := json.Unmarshal([]byte(stdout), &hosts); err != nil {
+		t.Fatalf("Expected JSON on stdout, got %q: %v", stdout, err)
+	}
+	for _, host := range []string{"111111111111.dkr.ecr.eu-west-1.amazonaws.com", "123456789012.dkr.ecr.eu-central-1.amazonaws.com"} {
+		if hosts[host] != "AWS" {
+			t.Errorf("Expected %s in %v", host, hosts)
+		}
+	}
+}
+
+func TestInstallCredentialHelper(t *testing.T) {
+	setupCredentialHelperFixture(t)
+	configPath := filepath.Join(os.Getenv("DOCKER_CONFIG"), "config.json")
+	existing := `{"auths": {"ghcr.io": {"auth": "secret"}}, "credHelpers": {"gcr.io": "gcloud"}}`
+	if err := os.WriteFile(configPath, []byte(existing), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if code := runInstallCredentialHelper(nil); code != utils.ExitOK {
+		t.Fatalf("Expected exit code 0, got %d", code)
+	}
+
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var dockerConfig struct {
+		Auths       map[string]json.RawMessage `json:"auths"`
+		CredHelpers map[string]string          `json:"credHelpers"`
+	}
+	if err := json.Unmarshal(data, &dockerConfig); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := dockerConfig.Auths["ghcr.io"]; !ok {
+		t.Error("Expected the existing auths to be kept")
+	}
+	expected := map[string]string{
+		"gcr.io": "gcloud",
+		"111111111111.dkr.ecr.eu-west-1.amazonaws.com":    "fancy-login",
+		"123456789012.dkr.ecr.eu-central-1.amazonaws.com": "fancy-login",
+	}
+	if len(dockerConfig.CredHelpers) != len(expected) {
+		t.Errorf("Expected %v, got %v", expected, dockerConfig.CredHelpers)
+	}
+	for host, helper := range expected {
+		if dockerConfig.CredHelpers[host] != helper {
+			t.Errorf("Expected %s for %s, got %q", helper, host, dockerConfig.CredHelpers[host])
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"fancy-login/internal/a11y"
+	"fancy-login/internal/config"
+	"fancy-login/internal/doctor"
+	"fancy-login/internal/platform"
+	"fancy-login/internal/utils"
+)
+
+// runDoctorCommand handles `fancy-login-go doctor`, checking the external
+// tools and files a login needs. It exits 1 if a required check fails.
+func runDoctorCommand(args []string) int {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return utils.ExitUsage
+	}
+
+	out := a11y.Writer(os.Stdout)
+	results := doctor.Run(doctor.DefaultEnv(), doctor.Checks)
+	printDoctorResults(out, results)
+
+	for _, line := range platform.Detect(platform.DefaultRunner).Report() {
+		fmt.Fprintf(out, "   %s\n", line)
+	}
+
+	if doctor.Failed(results) {
+		fmt.Fprintf(out, "\n%s❌ Some required checks failed%s\n", config.Error, config.Reset)
+		return utils.ExitFailure
+	}
+	fmt.Fprintf(out, "\n%s✅ All required checks passed%s\n", config.Success, config.Reset)
+	return utils.ExitOK
+}
+
+// printDoctorResults prints one line per check, with the hints of failed
+// checks below it
+func printDoctorResults(out io.Writer, results []doctor.Result) {
+	fmt.Fprintf(out, "%s🩺 fancy-login doctor%s\n", config.Bold, config.Reset)
+	for _, r := range results {
+		color, icon := config.Success, "✅"
+		switch r.Status {
+		case doctor.Warn:
+			color, icon = config.Warning, "⚠️ "
+		case doctor.Fail:
+			color, icon = config.Error, "❌"
+		}
+		fmt.Fprintf(out, "%s%s %s %s%s: %s\n", color, icon, r.Status, r.Name, config.Reset, r.Detail)
+		for _, hint := range r.Hints {
+			fmt.Fprintf(out, "     %s\n", hint)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"fancy-login/internal/aws"
+	"fancy-login/internal/config"
+	"fancy-login/internal/utils"
+)
+
+// runECRCommand handles `fancy-login-go ecr`, logging into a single registry
+// and printing one machine-readable result line
+func runECRCommand(args []string) int {
+	fs := flag.NewFlagSet("ecr", flag.ContinueOnError)
+	profileName := fs.String("profile", "", "AWS profile used to fetch the ECR token")
+	registryHost := fs.String("registry", "", "ECR registry hostname (defaults to the profile's account and ECR region)")
+	methodName := fs.String("method", "pipe", "Login method: pipe, dockercfg or podman")
+	verboseOutput := fs.Bool("v", false, "Enable verbose output")
+	if err := fs.Parse(args); err != nil {
+		return utils.ExitUsage
+	}
+
+	method, err := aws.ParseLoginMethod(*methodName)
+	if err != nil {
+		printECRResult("error", nil, method, err)
+		return utils.ExitUsage
+	}
+
+	var registry *aws.Registry
+	if *registryHost != "" {
+		parsed, err := aws.ParseRegistry(*registryHost)
+		if err != nil {
+			printECRResult("error", nil, method, err)
+			return utils.ExitUsage
+		}
+		registry = &parsed
+	}
+
+	fancyConfig, err := config.LoadFancyConfig()
+	if err != nil {
+		printECRResult("error", registry, method, err)
+		return utils.ExitConfig
+	}
+
+	cfg := config.NewConfig()
+	cfg.FancyVerbose = *verboseOutput
+	logger := utils.NewLogger(cfg.FancyVerbose)
+	awsManager := aws.NewAWSManager(cfg, logger, fancyConfig)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	profile, _, err := awsManager.ResolveProfile(ctx, aws.ProfileRequest{Flag: *profileName, UseEnv: true})
+	if err != nil {
+		printECRResult("error", registry, method, err)
+		return utils.ExitCode(err, utils.ExitConfig)
+	}
+
+	if registry == nil {
+		accountID, err := awsManager.GetAccountID(ctx, profile)
+		if err != nil {
+			printECRResult("error", nil, method, fmt.Errorf("failed to determine account ID: %w", err))
+			return utils.ExitAWSAuth
+		}
+		region, _ := awsManager.ECRRegion(profile)
+		registry = &aws.Registry{AccountID: accountID, Region: region}
+	}
+
+	if err := awsManager.LoginToRegistry(ctx, profile, *registry, method); err != nil {
+		printECRResult("error", registry, method, err)
+		return utils.ExitECR
+	}
+
+	printECRResult("ok", registry, method, nil)
+	return utils.ExitOK
+}
+
+// printECRResult prints the single key=value result line scripts can parse
+func printECRResult(status string, registry *aws.Registry, method aws.LoginMethod, err error) {
+	line := fmt.Sprintf("ecr-login status=%s", status)
+	if registry != nil {
+		line += fmt.Sprintf(" registry=%s account=%s region=%s", registry.Host(), registry.AccountID, registry.Region)
+	}
+	if method != "" {
+		line += fmt.Sprintf(" method=%s", method)
+	}
+	if err != nil {
+		line += fmt.Sprintf(" error=%q", err.Error())
+	}
+	fmt.Println(line)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"fancy-login/internal/a11y"
+	"fancy-login/internal/config"
+	"fancy-login/internal/prompt"
+	"fancy-login/internal/utils"
+)
+
+// runConfigEdit handles `fancy-login-go config edit`, opening the config
+// in $VISUAL or $EDITOR and checking it once the editor exits
+func runConfigEdit(args []string) int {
+	fs := flag.NewFlagSet("config edit", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return utils.ExitUsage
+	}
+
+	prompter, closeTTY, err := prompt.NewTTYPrompter(nil, nil)
+	if err != nil {
+		fmt.Printf("%s❌ config edit needs a terminal: %v%s\n", config.Error, err, config.Reset)
+		return utils.ExitFailure
+	}
+	defer closeTTY()
+	return editConfigFile(a11y.Writer(os.Stdout), prompter, editorCommand())
+}
+
+// editorCommand returns the user's editor with its arguments, e.g.
+// "code --wait", falling back to vi (notepad on Windows)
+func editorCommand() []string {
+	for _, name := range []string{"VISUAL", "EDITOR"} {
+		if fields := strings.Fields(os.Getenv(name)); len(fields) > 0 {
+			return fields
+		}
+	}
+	if runtime.GOOS == "windows" {
+		return []string{"notepad"}
+	}
+	return []string{"vi"}
+}
+
+// editConfigFile opens the config in editor until it parses and passes
+// the schema. A file that doesn't exist yet starts as the default config.
+// If the editor fails or the user gives up on a broken file, the previous
+// version is put back with its permissions.
+func editConfigFile(out io.Writer, prompter *prompt.Prompter, editor []string) int {
+	configPath := config.GetFancyConfigPath()
+	if _, err := os.Stat(configPath); errors.Is(err, os.ErrNotExist) {
+		if err := config.DefaultFancyConfig().SaveFancyConfig(); err != nil {
+			fmt.Fprintf(out, "%s❌ Failed to create %s: %v%s\n", config.Error, configPath, err, config.Reset)
+			return utils.ExitFailure
+		}
+		fmt.Fprintf(out, "%sCreated %s from the defaults%s\n", config.Muted, configPath, config.Reset)
+	}
+	info, err := os.Stat(configPath)
+	if err != nil {
+		fmt.Fprintf(out, "%s❌ %v%s\n", config.Error, err, config.Reset)
+		return utils.ExitFailure
+	}
+	original, err := os.ReadFile(configPath)
+	if err != nil {
+		fmt.Fprintf(out, "%s❌ %v%s\n", config.Error, err, config.Reset)
+		return utils.ExitFailure
+	}
+	// restore puts the previous version back and returns code, or
+	// ExitFailure if that fails
+	restore := func(code int) int {
+		if err := restoreFile(configPath, original, info.Mode().Perm()); err != nil {
+			fmt.Fprintf(out, "%s❌ Failed to restore %s: %v%s\n", config.Error, configPath, err, config.Reset)
+			return utils.ExitFailure
+		}
+		fmt.Fprintf(out, "%s⚠️  Restored the previous version of %s%s\n", config.Warning, configPath, config.Reset)
+		return code
+	}
+
+	for {
+		cmd := exec.Command(editor[0], append(editor[1:], configPath)...)
+		cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+		if err := cmd.Run(); err != nil {
+			fmt.Fprintf(out, "%s❌ Editor %s failed: %v%s\n", config.Error, editor[0], err, config.Reset)
+			return restore(utils.ExitFailure)
+		}
+
+		fancyConfig, err := config.LoadFancyConfig()
+		if err != nil {
+			// yaml errors name the line, e.g. "yaml: line 4: did not find expected key"
+			fmt.Fprintf(out, "%s❌ %v%s\n", config.Error, err, config.Reset)
+			if prompter.Confirm("Open the editor again to fix it?", true) {
+				continue
+			}
+			return restore(utils.ExitConfig)
+		}
+
+		if errs := fancyConfig.Validate(); len(errs) > 0 {
+			for _, err := range errs {
+				fmt.Fprintf(out, "%s❌ %v%s\n", config.Error, err, config.Reset)
+			}
+			if prompter.Confirm("Open the editor again to fix it?", true) {
+				continue
+			}
+			return restore(utils.ExitConfig)
+		}
+
+		// Stale entries still load, so they are only reported
+		snapshot := config.NewSnapshot(nil)
+		awsProfiles, awsErr := snapshot.AWSProfiles()
+		contexts, kubeErr := snapshot.KubeContexts()
+		if awsErr == nil && kubeErr == nil {
+			for _, f := range config.CrossCheck(fancyConfig, awsProfiles, contexts) {
+				fmt.Fprintf(out, "%s⚠️  %s: %s%s\n", config.Warning, f.Profile, f.Message, config.Reset)
+			}
+		}
+		fmt.Fprintf(out, "%s✅ %s is valid%s\n", config.Success, configPath, config.Reset)
+		return utils.ExitOK
+	}
+}
+
+// restoreFile writes data back to path with perm. WriteFile keeps the mode
+// of a file that exists, and editors that save by replacing the file
+// may have changed it, so it is set explicitly.
+func restoreFile(path string, data []byte, perm os.FileMode) error {
+	if err := os.WriteFile(path, data, perm); err != nil {
+		return err
+	}
+	return os.Chmod(path, perm)
+}
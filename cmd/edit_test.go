@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"fancy-login/internal/prompt"
+	"fancy-login/internal/utils"
+)
+
+// fakeEditor returns an editor that replaces the file with the next of
+// versions on each run
+func fakeEditor(t *testing.T, versions ...string) []string {
+	t.Helper()
+	dir := t.TempDir()
+	for i, v := range versions {
+		if err := os.WriteFile(filepath.Join(dir, strings.Repeat("x", i+1)), []byte(v), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Each run appends an x to the counter and copies the matching version
+	script := "#!/bin/sh\nprintf x >> " + dir + "/count\ncp " + dir + "/$(cat " + dir + "/count) \"$1\"\n"
+	editor := filepath.Join(dir, "editor")
+	if err := os.WriteFile(editor, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return []string{editor}
+}
+
+func TestEditConfigFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake editor is a POSIX shell script")
+	}
+	valid := "profile_configs:\n  dev:\n    k8s_context: dev-cluster\nsettings:\n  theme: mono\n"
+	broken := "profile_configs:\n  dev: [\n"
+	invalid := "profile_configs: {}\nsettings:\n  theme: neon\n"
+
+	testCases := []struct {
+		name         string
+		existing     string
+		versions     []string
+		answers      string
+		expectedCode int
+		expectedFile string
+		expectedOut  []string
+	}{
+		{"Creates a missing file", "", []string{valid}, "", utils.ExitOK, valid, []string{"Created", "is valid"}},
+		{"Re-opens after a YAML error", valid, []string{broken, valid}, "y\n", utils.ExitOK, valid, []string{"yaml: line 2", "is valid"}},
+		{"Restores when giving up", valid, []string{broken}, "n\n", utils.ExitConfig, valid, []string{"yaml: line 2", "Restored the previous version"}},
+		{"Re-opens after a schema error", valid, []string{invalid, valid}, "\n", utils.ExitOK, valid, []string{"settings.theme", "is valid"}},
+		{"Restores when giving up on a schema error", valid, []string{invalid}, "n\n", utils.ExitConfig, valid, []string{"settings.theme", "Restored the previous version"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			home := t.TempDir()
+			t.Setenv("HOME", home)
+			t.Setenv("AWS_CONFIG_FILE", "")
+			t.Setenv("KUBECONFIG", filepath.Join(home, "missing"))
+			configPath := filepath.Join(home, ".fancy-config.yaml")
+			if tc.existing != "" {
+				if err := os.WriteFile(configPath, []byte(tc.existing), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var out bytes.Buffer
+			p := prompt.NewPrompter(bufio.NewReader(strings.NewReader(tc.answers)), &out, nil, nil)
+			code := editConfigFile(&out, p, fakeEditor(t, tc.versions...))
+
+			if code != tc.expectedCode {
+				t.Errorf("Expected exit code %d, got %d: %s", tc.expectedCode, code, out.String())
+			}
+			data, err := os.ReadFile(configPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tc.expectedFile {
+				t.Errorf("Expected the file to be %q, got %q", tc.expectedFile, data)
+			}
+			if info, err := os.Stat(configPath); err == nil && tc.existing != "" && info.Mode().Perm() != 0600 {
+				t.Errorf("Expected the file to keep mode 0600, got %v", info.Mode().Perm())
+			}
+			for _, want := range tc.expectedOut {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("Expected %q in output, got %q", want, out.String())
+				}
+			}
+		})
+	}
+}
+
+func TestEditConfigFileRestoresWhenTheEditorFails(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake editor is a POSIX shell script")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	configPath := filepath.Join(home, ".fancy-config.yaml")
+	original := "settings:\n  theme: mono\n"
+	if err := os.WriteFile(configPath, []byte(original), 0600); err != nil {
+		t.Fatal(err)
+	}
+	// The editor replaces the file with a new, world-readable one and fails
+	editor := filepath.Join(t.TempDir(), "editor")
+	script := "#!/bin/sh\nrm \"$1\"\nprintf 'half: [' > \"$1\"\nchmod 644 \"$1\"\nexit 1\n"
+	if err := os.WriteFile(editor, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	p := prompt.NewPrompter(bufio.NewReader(strings.NewReader("")), &out, nil, nil)
+	if code := editConfigFile(&out, p, []string{editor}); code != utils.ExitFailure {
+		t.Errorf("Expected exit code %d, got %d: %s", utils.ExitFailure, code, out.String())
+	}
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != original {
+		t.Errorf("Expected the previous version back, got %q", data)
+	}
+	info, err := os.Stat(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("Expected mode 0600 back, got %v", info.Mode().Perm())
+	}
+}
+
+func TestEditorCommand(t *testing.T) {
+	t.Setenv("VISUAL", "code --wait")
+	t.Setenv("EDITOR", "nano")
+	if got := editorCommand(); strings.Join(got, " ") != "code --wait" {
+		t.Errorf("Expected VISUAL to win, got %q", got)
+	}
+	t.Setenv("VISUAL", "")
+	if got := editorCommand(); strings.Join(got, " ") != "nano" {
+		t.Errorf("Expected EDITOR, got %q", got)
+	}
+}
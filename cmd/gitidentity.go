@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"fancy-login/internal/config"
+	"fancy-login/internal/gitidentity"
+	"fancy-login/internal/prompt"
+	"fancy-login/internal/utils"
+)
+
+// offerGitIdentity sets the profile's git identity in the repository the
+// user is in, asking first unless git_identity_auto is set. Outside a
+// repository, or for profiles without an identity, it does nothing.
+func offerGitIdentity(ctx context.Context, fancyConfig *config.FancyConfig, logger *utils.Logger, profile string) {
+	pc, err := fancyConfig.GetProfileConfig(profile)
+	if err != nil || (pc.GitUserName == "" && pc.GitUserEmail == "") {
+		return
+	}
+
+	dir, err := os.Getwd()
+	if err != nil {
+		return
+	}
+	repo, ok := gitidentity.RepoRoot(ctx, dir)
+	if !ok {
+		return
+	}
+
+	plan, err := gitidentity.NewPlan(ctx, repo, profile, gitidentity.Identity{Name: pc.GitUserName, Email: pc.GitUserEmail})
+	if err != nil {
+		logger.LogWarning(fmt.Sprintf("Could not read git identity of %s: %v", repo, err))
+		return
+	}
+	if plan == nil {
+		logger.FancyLog(fmt.Sprintf("Git identity of %s already matches %s", repo, profile))
+		return
+	}
+
+	fmt.Fprintf(logger.Output(), "%sGit identity for %s:%s\n", config.Heading, repo, config.Reset)
+	for _, line := range plan.Describe() {
+		fmt.Fprintf(logger.Output(), "  %s\n", line)
+	}
+
+	if !fancyConfig.Settings.GitIdentityAuto {
+		prompter, closeTTY, err := prompt.NewTTYPrompter(fancyConfig.Settings.AffirmativeAnswers, fancyConfig.Settings.NegativeAnswers)
+		if err != nil {
+			logger.FancyLog(fmt.Sprintf("Non-interactive session, leaving git identity unchanged: %v", err))
+			return
+		}
+		defer closeTTY()
+		if !prompter.Confirm(fmt.Sprintf("%sSet this identity for the repository?%s", config.Accent, config.Reset), true) {
+			return
+		}
+	}
+
+	if err := plan.Apply(ctx); err != nil {
+		logger.LogWarning(fmt.Sprintf("Failed to set git identity: %v", err))
+		return
+	}
+	logger.LogInfo("Git identity set for this repository; `fancy-login-go undo` restores the previous one")
+}
+
+// runUndoCommand handles `fancy-login-go undo`, restoring the git identity
+// fancy-login changed most recently
+func runUndoCommand(args []string) int {
+	fs := flag.NewFlagSet("undo", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return utils.ExitUsage
+	}
+
+	change, err := gitidentity.Undo(context.Background())
+	if errors.Is(err, gitidentity.ErrNothingToUndo) {
+		fmt.Println("Nothing to undo.")
+		return utils.ExitOK
+	}
+	if err != nil {
+		fmt.Printf("%s❌ %v%s\n", config.Error, err, config.Reset)
+		return utils.ExitFailure
+	}
+
+	fmt.Printf("%s✓ Restored %s in %s%s\n", config.Success, strings.Join(change.Changed, " and "), change.Repo, config.Reset)
+	return utils.ExitOK
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"fancy-login/internal/platform"
+	"fancy-login/internal/utils"
+)
+
+// runInitCommand handles `fancy-login-go init [SHELL]`, printing the shell
+// integration for the rc file. Use it as
+//
+//	eval "$(fancy-login-go init zsh)"
+//
+// or, for Command Prompt, save `init cmd` as fancy.bat on the PATH.
+func runInitCommand(args []string) int {
+	if len(args) > 1 {
+		fmt.Fprintln(os.Stderr, "Usage: fancy-login-go init [sh|bash|zsh|powershell|cmd]")
+		return utils.ExitUsage
+	}
+	shell := ""
+	if len(args) == 1 {
+		shell = args[0]
+	}
+
+	snippet, err := platform.ShellIntegration(shell)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return utils.ExitUsage
+	}
+	fmt.Print(snippet)
+	return utils.ExitOK
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"fancy-login/internal/aws"
+	"fancy-login/internal/config"
+	"fancy-login/internal/k8s"
+	"fancy-login/internal/utils"
+)
+
+// runK9sCommand handles `fancy-login-go k9s [--profile NAME]`, switching to
+// the profile's context and opening k9s without logging in again. Without
+// --profile it uses AWS_PROFILE or AWS_DEFAULT_PROFILE, so k9s can be
+// reopened after quitting it, and otherwise opens the picker.
+func runK9sCommand(args []string) int {
+	fs := flag.NewFlagSet("k9s", flag.ContinueOnError)
+	profileName := fs.String("profile", "", "Profile whose context and namespace to open (default: $AWS_PROFILE)")
+	verboseOutput := fs.Bool("v", false, "Enable verbose output")
+	if err := fs.Parse(args); err != nil {
+		return utils.ExitUsage
+	}
+
+	fancyConfig, err := config.LoadFancyConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s❌ %v%s\n", config.Error, err, config.Reset)
+		return utils.ExitConfig
+	}
+
+	cfg := config.NewConfig()
+	cfg.FancyVerbose = *verboseOutput
+	// Asking for k9s by name is the same as passing -k to login
+	cfg.UseK9S = true
+	logger := utils.NewLogger(cfg.FancyVerbose)
+	awsManager := aws.NewAWSManager(cfg, logger, fancyConfig)
+	k8sManager := k8s.NewK8sManager(cfg, logger, fancyConfig)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	profile, _, err := awsManager.ResolveProfile(ctx, aws.ProfileRequest{Flag: *profileName, UseEnv: true, NoExport: true})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s❌ %v%s\n", config.Error, err, config.Reset)
+		return utils.ExitCode(err, utils.ExitConfig)
+	}
+	if _, err := fancyConfig.GetProfileConfig(profile); err != nil {
+		fmt.Fprintf(os.Stderr, "%s❌ %v%s\n", config.Error, err, config.Reset)
+		return utils.ExitConfig
+	}
+
+	if err := k8sManager.OpenK9s(ctx, profile); err != nil {
+		fmt.Fprintf(os.Stderr, "%s❌ %v%s\n", config.Error, err, config.Reset)
+		return utils.ExitCode(err, utils.ExitKubernetes)
+	}
+	return utils.ExitOK
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"fancy-login/internal/a11y"
+	"fancy-login/internal/config"
+	"fancy-login/internal/fzf"
+	"fancy-login/internal/kubeplugin"
+	"fancy-login/internal/platform"
+	"fancy-login/internal/prompt"
+	"fancy-login/internal/utils"
+)
+
+// pluginOptions are the flags of `kubectl fancy-login`
+type pluginOptions struct {
+	context       string
+	k9s           bool
+	verbose       bool
+	forceAWSLogin bool
+	// selectTimeout replaces the selection_timeout setting when not empty
+	selectTimeout string
+}
+
+// errNoTerminal is returned when the context picker is needed but there is
+// no terminal to show it on, e.g. when kubectl runs the plugin from a script
+var errNoTerminal = errors.New("no terminal to pick a context on; pass --context NAME")
+
+// openPluginTTY opens the terminal the picker runs on; tests replace it
+var openPluginTTY = platform.OpenTTY
+
+// runKubectlPlugin handles invocation as kubectl-fancy_login. It picks a
+// context, infers its profile and then runs the login command for both, so
+// the login itself is the same as `fancy-login-go --profile P --context C`.
+func runKubectlPlugin(args []string) int {
+	opts, err := parsePluginArgs(args)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return utils.ExitOK
+		}
+		return utils.ExitUsage
+	}
+
+	snapshot := config.NewSnapshot(nil)
+	fancyConfig, err := snapshot.FancyConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s❌ %v%s\n", config.Error, err, config.Reset)
+		return utils.ExitFailure
+	}
+	contexts, err := snapshot.KubeContexts()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s❌ %v%s\n", config.Error, err, config.Reset)
+		return utils.ExitFailure
+	}
+
+	timeout := fancyConfig.Settings.SelectionTimeoutDuration()
+	if opts.selectTimeout != "" {
+		// Already validated by parsePluginArgs
+		timeout, _ = config.ParseSelectionTimeout(opts.selectTimeout)
+	}
+	pick := func(entries []kubeplugin.PickerEntry) (string, error) {
+		return pickContextWithFzf(entries, fancyConfig.Settings.Selector, timeout)
+	}
+	loginArgs, err := pluginLoginArgs(opts, fancyConfig, contexts, pick)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s❌ %v%s\n", config.Error, err, config.Reset)
+		return utils.ExitFailure
+	}
+	return runLoginCommand(loginArgs)
+}
+
+// parsePluginArgs parses the plugin's command line
+func parsePluginArgs(args []string) (*pluginOptions, error) {
+	opts := &pluginOptions{}
+	fs := flag.NewFlagSet("kubectl fancy-login", flag.ContinueOnError)
+	fs.StringVar(&opts.context, "context", "", "Context to switch to instead of picking one")
+	fs.BoolVar(&opts.k9s, "k", false, "Auto-launch k9s without prompting")
+	fs.BoolVar(&opts.k9s, "k9s", false, "Auto-launch k9s without prompting")
+	fs.BoolVar(&opts.verbose, "v", false, "Enable verbose output")
+	fs.BoolVar(&opts.verbose, "verbose", false, "Enable verbose output")
+	fs.BoolVar(&opts.forceAWSLogin, "force-aws-login", false, "Force AWS SSO login even if a valid session exists")
+	fs.StringVar(&opts.selectTimeout, "select-timeout", "", "How long the picker waits, e.g. 5m; 0 waits forever")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: kubectl fancy-login [--context NAME] [-k] [-v] [--force-aws-login] [--select-timeout DURATION]")
+		fmt.Fprintln(fs.Output(), "\nPick a context, log in to the AWS profile it belongs to and switch to it.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if opts.selectTimeout != "" {
+		if _, err := config.ParseSelectionTimeout(opts.selectTimeout); err != nil {
+			err = fmt.Errorf("invalid --select-timeout: %w", err)
+			fmt.Fprintln(fs.Output(), err)
+			return nil, err
+		}
+	}
+	if fs.NArg() > 0 {
+		if opts.context != "" {
+			fmt.Fprintf(fs.Output(), "unexpected argument %q\n", fs.Arg(0))
+			return nil, fmt.Errorf("unexpected argument %q", fs.Arg(0))
+		}
+		// `kubectl fancy-login CONTEXT` is short for --context CONTEXT
+		opts.context = fs.Arg(0)
+	}
+	return opts, nil
+}
+
+// pluginLoginArgs resolves the context and its profile into login command
+// arguments. pick shows the picker; it is only called without --context.
+func pluginLoginArgs(opts *pluginOptions, fancyConfig *config.FancyConfig, contexts []config.KubernetesContext,
+	pick func([]kubeplugin.PickerEntry) (string, error)) ([]string, error) {
+	name := opts.context
+	if name == "" {
+		var err error
+		if name, err = pick(kubeplugin.PickerEntries(fancyConfig, contexts)); err != nil {
+			return nil, err
+		}
+	}
+
+	var selected *config.KubernetesContext
+	for i := range contexts {
+		if contexts[i].Name == name {
+			selected = &contexts[i]
+			break
+		}
+	}
+	if selected == nil {
+		available := make([]string, 0, len(contexts))
+		for _, c := range contexts {
+			available = append(available, c.Name)
+		}
+		return nil, fmt.Errorf("context %s not found; available contexts: %s", name, strings.Join(available, ", "))
+	}
+
+	profile, err := kubeplugin.ProfileForContext(fancyConfig, *selected)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{"--profile", profile, "--context", name}
+	if opts.k9s {
+		args = append(args, "-k")
+	}
+	if opts.verbose {
+		args = append(args, "-v")
+	}
+	if opts.forceAWSLogin {
+		args = append(args, "--force-aws-login")
+	}
+	return args, nil
+}
+
+// chooseContextNumbered asks for a context by number, leaving out the group
+// headers and spacers
+func chooseContextNumbered(prompter *prompt.Prompter, entries []kubeplugin.PickerEntry) (string, error) {
+	var selectable []kubeplugin.PickerEntry
+	var lines []string
+	for _, entry := range entries {
+		if entry.Context != "" {
+			selectable = append(selectable, entry)
+			lines = append(lines, entry.DisplayText)
+		}
+	}
+	index, ok := prompter.Choose("Select Kubernetes Context", lines)
+	if !ok {
+		return "", errors.New("no context selected")
+	}
+	return selectable[index].Context, nil
+}
+
+// chooseContextBuiltin shows the grouped contexts as the built-in menu
+func chooseContextBuiltin(prompter *prompt.Prompter, entries []kubeplugin.PickerEntry, timeout time.Duration) (string, error) {
+	ctx, cancel := utils.WithStepTimeout(context.Background(), timeout)
+	defer cancel()
+
+	items := make([]prompt.MenuItem, len(entries))
+	for i, entry := range entries {
+		items[i] = prompt.MenuItem{Text: entry.DisplayText, Header: entry.Context == ""}
+	}
+	index, ok := prompter.Menu(ctx, "Select Kubernetes Context", items)
+	if ctx.Err() == context.DeadlineExceeded {
+		return "", &utils.SelectionTimeoutError{What: "context selection", Timeout: timeout}
+	}
+	if !ok {
+		return "", errors.New("no context selected")
+	}
+	return entries[index].Context, nil
+}
+
+// pickContextWithFzf shows the grouped context picker. kubectl hands the
+// plugin its own stdio, which may be redirected, so the terminal is checked
+// up front rather than leaving fzf to fail. Screen reader mode gets a
+// numbered list of the contexts instead, and the built-in menu stands in
+// for fzf when selector asks for it or fzf is missing. A timeout of 0
+// waits forever.
+func pickContextWithFzf(entries []kubeplugin.PickerEntry, selector string, timeout time.Duration) (string, error) {
+	tty, err := openPluginTTY()
+	if err != nil {
+		return "", errNoTerminal
+	}
+	if a11y.Enabled() {
+		defer tty.Close()
+		return chooseContextNumbered(prompt.NewPrompter(bufio.NewReader(tty), os.Stdout, nil, nil), entries)
+	}
+	if fzf.UseBuiltin(selector) {
+		defer tty.Close()
+		prompter := prompt.NewPrompter(bufio.NewReader(tty), os.Stdout, nil, nil)
+		prompter.SetInput(tty)
+		return chooseContextBuiltin(prompter, entries, timeout)
+	}
+	tty.Close()
+
+	lines := make([]string, len(entries))
+	for i, entry := range entries {
+		lines[i] = entry.DisplayText
+	}
+
+	ctx, cancel := utils.WithStepTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "fzf", fzf.Detect().Args(fzf.Options{Prompt: "Select Kubernetes Context: "})...)
+	cmd.Stdin = strings.NewReader(strings.Join(lines, "\n"))
+	cmd.Stderr = os.Stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", &utils.SelectionTimeoutError{What: "context selection", Timeout: timeout}
+		}
+		return "", fmt.Errorf("context selection failed: %w", err)
+	}
+	return kubeplugin.SelectedContext(entries, string(output))
+}
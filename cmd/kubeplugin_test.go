@@ -0,0 +1,85 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"fancy-login/internal/config"
+	"fancy-login/internal/kubeplugin"
+)
+
+func TestPluginLoginArgs(t *testing.T) {
+	fancyConfig := config.DefaultFancyConfig()
+	fancyConfig.ProfileConfigs = map[string]config.ProfileConfig{
+		"dev": {AccountID: "111111111111"},
+	}
+	devARN := "arn:aws:eks:eu-central-1:111111111111:cluster/dev"
+	contexts := []config.KubernetesContext{{Name: devARN}, {Name: "kind-kind"}}
+
+	testCases := []struct {
+		name          string
+		args          []string
+		picked        string
+		expected      []string
+		expectedError string
+	}{
+		{"Picked context", []string{"-k"}, devARN, []string{"--profile", "dev", "--context", devARN, "-k"}, ""},
+		{"Context flag skips the picker", []string{"--context", devARN, "-v"}, "", []string{"--profile", "dev", "--context", devARN, "-v"}, ""},
+		{"Positional context", []string{devARN}, "", []string{"--profile", "dev", "--context", devARN}, ""},
+		{"Unknown context", []string{"--context", "gone"}, "", nil, "context gone not found; available contexts: " + devARN + ", kind-kind"},
+		{"No matching profile", []string{"--context", "kind-kind"}, "", nil, "cannot be inferred"},
+		{"Selection timeout", []string{"--select-timeout", "5m"}, devARN, []string{"--profile", "dev", "--context", devARN}, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			opts, err := parsePluginArgs(tc.args)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pick := func([]kubeplugin.PickerEntry) (string, error) {
+				if tc.picked == "" {
+					t.Error("Expected no picker")
+				}
+				return tc.picked, nil
+			}
+
+			args, err := pluginLoginArgs(opts, fancyConfig, contexts, pick)
+			if tc.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedError) {
+					t.Errorf("Expected error containing %q, got %v", tc.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(args, tc.expected) {
+				t.Errorf("Expected %q, got %q", tc.expected, args)
+			}
+			// The arguments must be valid for the login command
+			if _, err := parseLoginArgs(args); err != nil {
+				t.Errorf("Expected login to accept %q, got %v", args, err)
+			}
+		})
+	}
+
+	if _, err := parsePluginArgs([]string{"--select-timeout", "soon"}); err == nil {
+		t.Error("Expected an error for an invalid --select-timeout")
+	}
+}
+
+func TestPickContextWithoutTerminal(t *testing.T) {
+	original := openPluginTTY
+	defer func() { openPluginTTY = original }()
+	openPluginTTY = func() (*os.File, error) { return nil, errors.New("no such device") }
+
+	entries := []kubeplugin.PickerEntry{{Context: "dev", DisplayText: "dev"}}
+	if _, err := pickContextWithFzf(entries, "", time.Minute); !errors.Is(err, errNoTerminal) {
+		t.Errorf("Expected errNoTerminal, got %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"fancy-login/internal/a11y"
+	"fancy-login/internal/aws"
+	"fancy-login/internal/config"
+	"fancy-login/internal/platform"
+	"fancy-login/internal/utils"
+)
+
+// runLogoutCommand handles `fancy-login-go logout [PROFILE]`, ending the SSO
+// session of the profile (by default the one the shell integration exports)
+// and clearing what the login left behind
+func runLogoutCommand(args []string) int {
+	fs := flag.NewFlagSet("logout", flag.ContinueOnError)
+	noECR := fs.Bool("no-ecr", false, "Don't log docker out of the profile's ECR registry")
+	verboseOutput := fs.Bool("v", false, "Enable verbose output")
+	if err := fs.Parse(args); err != nil {
+		return utils.ExitUsage
+	}
+	if fs.NArg() > 1 {
+		fmt.Fprintln(os.Stderr, "logout takes at most one profile")
+		return utils.ExitUsage
+	}
+
+	fancyConfig, err := config.LoadFancyConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s❌ %v%s\n", config.Error, err, config.Reset)
+		return utils.ExitFailure
+	}
+	cfg := config.NewConfig()
+	cfg.FancyVerbose = *verboseOutput
+	awsManager := aws.NewAWSManager(cfg, utils.NewLogger(cfg.FancyVerbose), fancyConfig)
+
+	profile := fancyConfig.ResolveProfileName(fs.Arg(0))
+	if profile == "" {
+		profile, err = awsManager.ExportedProfile()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s❌ Failed to read %s: %v%s\n", config.Error, cfg.AWSProfileTemp, err, config.Reset)
+			return utils.ExitFailure
+		}
+	}
+	if profile == "" {
+		fmt.Fprintf(os.Stderr, "%s❌ No profile given and none exported to %s%s\n", config.Error, cfg.AWSProfileTemp, config.Reset)
+		return utils.ExitUsage
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	out := a11y.Writer(os.Stdout)
+	cleared := func(format string, args ...any) {
+		fmt.Fprintf(out, "   %s✅ %s%s\n", config.Success, fmt.Sprintf(format, args...), config.Reset)
+	}
+	skipped := func(format string, args ...any) {
+		fmt.Fprintf(out, "   %s⚠️  %s%s\n", config.Warning, fmt.Sprintf(format, args...), config.Reset)
+	}
+
+	fmt.Fprintf(out, "%s🔓 Logging out of %s%s\n", config.Bold, profile, config.Reset)
+	exitCode := 0
+
+	if !fancyConfig.IsKubeOnlyProfile(profile) {
+		switch err := awsManager.SSOLogout(ctx, profile); {
+		case errors.Is(err, aws.ErrNotSSOProfile):
+			skipped("Not an SSO profile, no session to end")
+		case err != nil:
+			fmt.Fprintf(out, "   %s❌ %v%s\n", config.Error, err, config.Reset)
+			exitCode = 1
+		default:
+			cleared("SSO session ended")
+		}
+	}
+
+	removed, err := awsManager.RemoveProfileExport(profile)
+	for _, path := range removed {
+		cleared("Removed %s", path)
+	}
+	if err != nil {
+		skipped("Could not remove the exported profile: %v", err)
+	}
+
+	if !*noECR && fancyConfig.ShouldPerformECRLogin(profile) {
+		switch registry, err := awsManager.ECRLogout(ctx, profile); {
+		case errors.Is(err, aws.ErrDockerNotInstalled):
+			skipped("docker is not installed, skipped logging out of ECR")
+		case err != nil:
+			skipped("ECR logout: %v", err)
+		default:
+			cleared("Logged docker out of %s", registry.Host())
+		}
+	}
+
+	if err := aws.ForgetSession(profile); err != nil {
+		skipped("Could not update the session cache: %v", err)
+	}
+
+	if reset := platform.ResetNamespaceIndicator(); reset != "" {
+		fmt.Print(reset)
+		cleared("Reset the terminal title")
+	}
+
+	return exitCode
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"fancy-login/internal/state"
+)
+
+// installLoggingTools puts aws and docker on PATH that append their
+// arguments to the returned log
+func installLoggingTools(t *testing.T, tools ...string) string {
+	t.Helper()
+	binDir := t.TempDir()
+	log := filepath.Join(t.TempDir(), "calls.log")
+	for _, name := range tools {
+		script := "#!/bin/sh\necho \"" + name + " $*\" >> " + log + "\n"
+		if err := os.WriteFile(filepath.Join(binDir, name), []byte(script), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return log
+}
+
+// setupLogoutFixture is the login fixture with the account of dev in the
+// AWS config and exported as the given profile. It returns the export file.
+func setupLogoutFixture(t *testing.T, exported string) string {
+	t.Helper()
+	home := setupLoginFixture(t, "")
+	awsConfig := "[profile dev]\nsso_start_url = https://example.awsapps.com/start\nsso_account_id = 123456789012\nregion = eu-central-1\n"
+	if err := os.WriteFile(filepath.Join(home, ".aws", "config"), []byte(awsConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+	profileTemp := filepath.Join(t.TempDir(), "aws_profile.sh")
+	t.Setenv("FANCY_PROFILE_TEMP", profileTemp)
+	if err := os.WriteFile(profileTemp, []byte("export AWS_PROFILE="+exported+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return profileTemp
+}
+
+func TestLogout(t *testing.T) {
+	profileTemp := setupLogoutFixture(t, "dev")
+	if err := state.Update(func(s *state.State) error {
+		s.RecordECRLogin("dev", state.ECRLoginRecord{Status: state.ECROK})
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	log := installLoggingTools(t, "aws", "docker")
+
+	if code := runLogoutCommand(nil); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d", code)
+	}
+
+	calls, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "aws sso logout --profile dev\ndocker logout 123456789012.dkr.ecr.eu-central-1.amazonaws.com\n"
+	if string(calls) != expected {
+		t.Errorf("Expected calls %q, got %q", expected, string(calls))
+	}
+	if _, err := os.Stat(profileTemp); !os.IsNotExist(err) {
+		t.Errorf("Expected %s to be removed, got %v", profileTemp, err)
+	}
+
+	s, err := state.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.ECRLogins["dev"] != nil {
+		t.Error("Expected the ECR login record to be dropped")
+	}
+	if record := s.Sessions["dev"]; record == nil || record.Status != "no session" {
+		t.Errorf("Expected the session to be recorded as ended, got %+v", record)
+	}
+}
+
+func TestLogoutWithoutDocker(t *testing.T) {
+	profileTemp := setupLogoutFixture(t, "other")
+	log := installLoggingTools(t, "aws")
+	// Only the logging aws is left on PATH, so there is no docker
+	t.Setenv("PATH", firstPathEntry())
+
+	if code := runLogoutCommand([]string{"dev"}); code != 0 {
+		t.Fatalf("Expected exit code 0 without docker, got %d", code)
+	}
+
+	calls, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(calls) != "aws sso logout --profile dev\n" {
+		t.Errorf("Expected only the SSO logout, got %q", string(calls))
+	}
+	if _, err := os.Stat(profileTemp); err != nil {
+		t.Errorf("Expected the export of another profile to be kept, got %v", err)
+	}
+}
+
+// firstPathEntry returns the directory searched first for tools
+func firstPathEntry() string {
+	entry, _, _ := strings.Cut(os.Getenv("PATH"), string(os.PathListSeparator))
+	return entry
+}
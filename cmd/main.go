@@ -1,13 +1,28 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+	"time"
 
+	"fancy-login/internal/a11y"
 	"fancy-login/internal/aws"
+	"fancy-login/internal/compat"
 	"fancy-login/internal/config"
 	"fancy-login/internal/k8s"
+	"fancy-login/internal/kubeplugin"
+	"fancy-login/internal/metrics"
+	"fancy-login/internal/progress"
+	"fancy-login/internal/prompt"
+	"fancy-login/internal/state"
+	"fancy-login/internal/summary"
 	"fancy-login/internal/utils"
 )
 
@@ -16,153 +31,848 @@ var (
 	version   = "dev"
 	buildTime = "unknown"
 	gitCommit = "unknown"
-
-	// Command-line flags
-	verbose       = flag.Bool("v", false, "Enable verbose output")
-	k9sFlag       = flag.Bool("k", false, "Auto-launch k9s without prompting")
-	forceAWSLogin = flag.Bool("force-aws-login", false, "Force AWS SSO login even if a valid session exists")
-	configFlag    = flag.Bool("config", false, "Run configuration wizard")
-	helpFlag      = flag.Bool("h", false, "Show help message")
-	versionFlag   = flag.Bool("version", false, "Show version information")
 )
 
 func main() {
-	flag.BoolVar(verbose, "verbose", false, "Enable verbose output")
-	flag.BoolVar(k9sFlag, "k9s", false, "Auto-launch k9s without prompting")
-	flag.BoolVar(helpFlag, "help", false, "Show help message")
-	flag.BoolVar(configFlag, "configure", false, "Run configuration wizard")
-	flag.Parse()
+	applyConfiguredTheme()
+	// The wizard lives in config, which can't import k8s
+	config.SetEKSClusters(&config.EKSClusters{List: k8s.ListEKSClusters, Create: k8s.UpdateKubeconfig})
+
+	// Users of the shell script still invoke it as `fancy`
+	if compat.Invoked(os.Args[0]) {
+		runLegacyShim()
+	}
+
+	// kubectl runs the binary as kubectl-fancy_login for `kubectl fancy-login`
+	if kubeplugin.Invoked(os.Args[0]) {
+		os.Exit(runKubectlPlugin(os.Args[1:]))
+	}
 
-	if *versionFlag {
+	// docker runs the binary as docker-credential-fancy-login for registries
+	// whose credHelpers entry is fancy-login
+	if credentialHelperInvoked(os.Args[0]) {
+		os.Exit(runDockerCredentialCommand(os.Args[1:]))
+	}
+
+	os.Exit(dispatch(os.Args[1:]))
+}
+
+// loginOptions are the flags and PROFILE argument of the login command
+type loginOptions struct {
+	verbose         bool
+	k9s             bool
+	forceAWSLogin   bool
+	forceECR        bool
+	config          bool
+	help            bool
+	version         bool
+	profile         string
+	reuseEnv        bool
+	theme           string
+	refreshMetadata bool
+	sort            string
+	context         string
+	namespace       string
+	noK8s           bool
+	skipNamespace   bool
+	noK8sCheck      bool
+	dryRun          bool
+	progressFile    string
+	output          string
+	region          string
+	nonInteractive  bool
+	assumeYes       bool
+	last            bool
+	selectTimeout   string
+	exportCreds     bool
+	noBrowser       bool
+	reload          bool
+	// query is the positional PROFILE argument
+	query string
+	// switchedFrom and switchContext are set by switch: the profile it
+	// swaps away from and the context the target was last used with
+	switchedFrom  string
+	switchContext string
+}
+
+// parseLoginArgs parses the login command's arguments. The legacy flags
+// --config and --version are kept so bare invocations behave as before.
+func parseLoginArgs(args []string) (*loginOptions, error) {
+	opts := &loginOptions{}
+	fs := flag.NewFlagSet("login", flag.ContinueOnError)
+	fs.BoolVar(&opts.verbose, "v", false, "Enable verbose output")
+	fs.BoolVar(&opts.verbose, "verbose", false, "Enable verbose output")
+	fs.BoolVar(&opts.k9s, "k", false, "Auto-launch k9s without prompting")
+	fs.BoolVar(&opts.k9s, "k9s", false, "Auto-launch k9s without prompting")
+	fs.BoolVar(&opts.forceAWSLogin, "force-aws-login", false, "Force AWS SSO login even if a valid session exists")
+	fs.BoolVar(&opts.forceECR, "force-ecr", false, "Log in to ECR even if docker holds a fresh token")
+	fs.BoolVar(&opts.config, "config", false, "Run configuration wizard")
+	fs.BoolVar(&opts.config, "configure", false, "Run configuration wizard")
+	fs.BoolVar(&opts.help, "h", false, "Show help message")
+	fs.BoolVar(&opts.help, "help", false, "Show help message")
+	fs.BoolVar(&opts.version, "version", false, "Show version information")
+	fs.StringVar(&opts.profile, "profile", "", "AWS profile to use instead of prompting")
+	fs.StringVar(&opts.profile, "p", "", "AWS profile to use instead of prompting")
+	fs.BoolVar(&opts.reuseEnv, "reuse-env", false, "Reuse AWS_PROFILE or AWS_DEFAULT_PROFILE from the environment")
+	fs.StringVar(&opts.theme, "theme", "", "Color theme for this run: default, high-contrast, colorblind or mono")
+	fs.BoolVar(&opts.refreshMetadata, "refresh-metadata", false, "Re-resolve account ID and alias of every configured profile and exit")
+	fs.StringVar(&opts.sort, "sort", "", "Comma-separated picker sort columns for this run, e.g. environment,name")
+	fs.StringVar(&opts.context, "context", "", "Kubernetes context to switch to instead of the configured one")
+	fs.StringVar(&opts.namespace, "namespace", "", "Kubernetes namespace for k9s instead of the configured one")
+	fs.StringVar(&opts.namespace, "n", "", "Kubernetes namespace for k9s instead of the configured one")
+	fs.BoolVar(&opts.noK8s, "no-k8s", false, "Skip Kubernetes context switching and k9s for this run")
+	fs.BoolVar(&opts.noK8sCheck, "no-k8s-check", false, "Don't check that the cluster of the new context answers")
+	fs.BoolVar(&opts.skipNamespace, "skip-namespace-select", false, "Don't offer the namespace picker of namespace_selection for this run")
+	fs.BoolVar(&opts.dryRun, "dry-run", false, "Print the actions a login would take without taking them")
+	fs.StringVar(&opts.output, "output", "text", "Summary format: text or json")
+	fs.StringVar(&opts.region, "region", "", "ECR region for this run, ahead of ecr_region, default_region and AWS_REGION")
+	fs.StringVar(&opts.selectTimeout, "select-timeout", "", "How long pickers wait for a choice, e.g. 5m; 0 waits forever")
+	fs.BoolVar(&opts.last, "last", false, "Log in to the profile of the last login again without the picker")
+	fs.BoolVar(&opts.nonInteractive, "non-interactive", false, "Never prompt, open the terminal or start fzf; requires --profile")
+	fs.BoolVar(&opts.assumeYes, "assume-yes", false, "Answer yes to every y/n question in --non-interactive mode")
+	fs.BoolVar(&opts.exportCreds, "export-creds", false, "Also export the profile's temporary credentials to the shell")
+	fs.BoolVar(&opts.noBrowser, "no-browser", false, "Print the SSO verification URL and user code instead of opening a browser")
+	fs.BoolVar(&opts.reload, "reload", false, "Re-read the AWS config, kubeconfig and fancy config on every access instead of once per run")
+	fs.StringVar(&opts.progressFile, "progress-file", "", "Append a JSON line to this file as each login phase starts and finishes")
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if opts.output != "text" && opts.output != "json" {
+		err := fmt.Errorf("invalid --output %q: must be text or json", opts.output)
+		fmt.Fprintln(fs.Output(), err)
+		return nil, err
+	}
+	if opts.region != "" {
+		if err := config.ValidateRegion(opts.region); err != nil {
+			err = fmt.Errorf("--region: %w", err)
+			fmt.Fprintln(fs.Output(), err)
+			return nil, err
+		}
+	}
+	if opts.selectTimeout != "" {
+		if _, err := config.ParseSelectionTimeout(opts.selectTimeout); err != nil {
+			err = fmt.Errorf("--select-timeout: %w", err)
+			fmt.Fprintln(fs.Output(), err)
+			return nil, err
+		}
+	}
+	if opts.output == "json" && opts.dryRun {
+		err := errors.New("--output json cannot be combined with --dry-run")
+		fmt.Fprintln(fs.Output(), err)
+		return nil, err
+	}
+	if opts.dryRun && opts.refreshMetadata {
+		err := errors.New("--dry-run cannot be combined with --refresh-metadata")
+		fmt.Fprintln(fs.Output(), err)
+		return nil, err
+	}
+	if opts.noK8s && (opts.context != "" || opts.namespace != "" || opts.k9s) {
+		err := errors.New("--no-k8s cannot be combined with --context, --namespace or -k")
+		fmt.Fprintln(fs.Output(), err)
+		return nil, err
+	}
+	if opts.nonInteractive && (opts.profile == "" || opts.k9s || opts.config) {
+		err := errors.New("--non-interactive requires --profile and cannot be combined with -k or --config")
+		fmt.Fprintln(fs.Output(), err)
+		return nil, err
+	}
+	if opts.last && (opts.profile != "" || opts.reuseEnv || fs.Arg(0) != "") {
+		err := errors.New("--last cannot be combined with --profile, --reuse-env or a profile argument")
+		fmt.Fprintln(fs.Output(), err)
+		return nil, err
+	}
+	if opts.assumeYes && !opts.nonInteractive {
+		err := errors.New("--assume-yes requires --non-interactive")
+		fmt.Fprintln(fs.Output(), err)
+		return nil, err
+	}
+	opts.query = fs.Arg(0)
+	return opts, nil
+}
+
+// runLoginCommand handles `fancy-login-go login`, the default command:
+// select a profile, log in to AWS and switch the Kubernetes context
+func runLoginCommand(args []string) int {
+	opts, err := parseLoginArgs(args)
+	if err != nil {
+		return utils.ExitUsage
+	}
+	return runLogin(opts)
+}
+
+// runLogin runs a login with parsed options
+func runLogin(opts *loginOptions) int {
+	if opts.theme != "" {
+		if err := config.ApplyTheme(opts.theme); err != nil {
+			fmt.Println(err)
+			return utils.ExitUsage
+		}
+	}
+
+	if opts.version {
 		showVersion()
-		return
+		return utils.ExitOK
+	}
+
+	// From here on nothing may wait for input that never comes
+	if opts.nonInteractive {
+		prompt.SetNonInteractive(true, opts.assumeYes)
+	}
+
+	// JSON output owns stdout. Everything else, the log, questions and
+	// what aws, kubectl and k9s print, goes to out, stderr then, so stdout
+	// stays parseable.
+	var out, jsonOut io.Writer = os.Stdout, nil
+	if opts.output == "json" {
+		out, jsonOut = os.Stderr, os.Stdout
+		prompt.SetOutput(out)
+		defer prompt.SetOutput(nil)
 	}
 
-	if *helpFlag {
+	if opts.help {
 		showHelp()
-		return
+		return utils.ExitOK
 	}
 
-	if *configFlag {
-		wizard := config.NewConfigWizard()
-		if err := wizard.Run(); err != nil {
-			fmt.Printf("Configuration wizard failed: %v\n", err)
-			os.Exit(1)
-		}
-		return
+	if opts.config {
+		return runConfigWizard(opts.dryRun)
 	}
 
-	// Run configuration wizard if needed
-	if err := config.RunConfigWizardIfNeeded(); err != nil {
-		fmt.Printf("Configuration wizard failed: %v\n", err)
-		os.Exit(1)
+	// Parse the AWS, kube and fancy configs once for the whole run, or on
+	// every access with --reload
+	snapshot := config.NewSnapshot(nil)
+	snapshot.SetReload(opts.reload)
+
+	// Run configuration wizard if needed; it is all questions, so a
+	// non-interactive run goes ahead with the config as it is
+	if prompt.Interactive() {
+		if err := config.RunConfigWizardIfNeeded(snapshot); err != nil {
+			fmt.Fprintf(out, "Configuration wizard failed: %v\n", err)
+			return utils.ExitConfig
+		}
 	}
 
-	// Load fancy configuration
-	fancyConfig, err := config.LoadFancyConfig()
+	fancyConfig, err := snapshot.FancyConfig()
 	if err != nil {
-		fmt.Printf("Failed to load configuration: %v\n", err)
-		os.Exit(1)
+		fmt.Fprintf(out, "Failed to load configuration: %v\n", err)
+		return utils.ExitConfig
 	}
 
 	// Initialize configuration
 	cfg := config.NewConfig()
-	cfg.FancyVerbose = *verbose
-	cfg.ForceAWSLogin = *forceAWSLogin
-	cfg.UseK9S = *k9sFlag
+	cfg.FancyVerbose = opts.verbose
+	cfg.ForceAWSLogin = opts.forceAWSLogin
+	cfg.UseK9S = opts.k9s
 
 	// Set debug mode
 	if cfg.FancyDebug {
-		fmt.Println("Debug mode enabled")
+		fmt.Fprintln(out, "Debug mode enabled")
 	}
 
 	// Initialize logger
 	logger := utils.NewLogger(cfg.FancyVerbose)
+	logger.SetOutput(out)
+
+	// Collect timing and failure metrics; fatal errors still write them
+	run := metrics.NewRun()
+	if !opts.dryRun {
+		logger.OnExit(func() {
+			run.Aborted = true
+			writeMetrics(fancyConfig, logger, run)
+		})
+	}
+	logger.OnExit(activeRootGuard.fixOwnership)
+
+	// Report each phase to the progress file for wrappers that time them
+	phases := &loginPhases{run: run, progress: progress.Discard}
+	if opts.progressFile != "" {
+		sink, err := progress.OpenFile(opts.progressFile, logger.LogWarning)
+		if err != nil {
+			fmt.Fprintln(out, err)
+			return utils.ExitFailure
+		}
+		defer sink.Close()
+		logger.OnExit(func() { sink.Close() })
+		phases.progress = sink
+	}
+
+	// Cancel every external command (and its children) on Ctrl-C
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	// Initialize managers
 	awsManager := aws.NewAWSManager(cfg, logger, fancyConfig)
+	awsManager.SetSnapshot(snapshot)
+	awsManager.SetDryRun(opts.dryRun)
+	awsManager.SetECRRegionOverride(opts.region)
+	awsManager.SetForceECRLogin(opts.forceECR || opts.forceAWSLogin)
+	awsManager.SetNoBrowser(opts.noBrowser || fancyConfig.Settings.SSONoBrowser)
+	defer awsManager.Cleanup()
+	logger.OnExit(awsManager.Cleanup)
+
+	// A --sort for this run overrides the persisted profile_sort
+	if opts.sort != "" {
+		keys, err := aws.ParseProfileSort(opts.sort)
+		if err != nil {
+			fmt.Fprintf(out, "--sort: %v\n", err)
+			return utils.ExitUsage
+		}
+		awsManager.SetProfileSort(keys)
+	}
 	k8sManager := k8s.NewK8sManager(cfg, logger, fancyConfig)
+	k8sManager.SetSnapshot(snapshot)
+	k8sManager.SetProfileEnv(awsManager.ProfileEnv)
+
+	// A --select-timeout for this run overrides selection_timeout
+	if opts.selectTimeout != "" {
+		timeout, _ := config.ParseSelectionTimeout(opts.selectTimeout)
+		awsManager.SetSelectionTimeout(timeout)
+		k8sManager.SetSelectionTimeout(timeout)
+	}
+	k8sManager.SetContextOverride(opts.context)
+	k8sManager.SetNamespaceOverride(opts.namespace)
+	k8sManager.SetSkipNamespaceSelect(opts.skipNamespace)
+	k8sManager.SetSkipClusterCheck(opts.noK8sCheck)
+	k8sManager.SetDryRun(opts.dryRun)
+
+	if opts.refreshMetadata {
+		if err := awsManager.RefreshAllMetadata(ctx); err != nil {
+			fatal(logger, "Metadata refresh failed", err, utils.ExitFailure)
+		}
+		return utils.ExitOK
+	}
+
+	ecrMode := fancyConfig.Settings.ECRLoginModeOrDefault()
 
 	// Variables to aggregate results
 	var k8sContextResult string
-	var ecrResult string
-	var ecrAttempted bool
+	var ecrAttempted, ecrSucceeded bool
 	var accountIDSummary string
+	var credentialsExported bool
+	var credentialsExpireAt time.Time
+	var timeouts []string
+	var metadataRefresh *aws.MetadataRefresh
 
-	// Select AWS profile
-	awsProfile, err := awsManager.SelectAWSProfile()
+	// Resolve AWS profile from flags/environment or select it interactively
+	stepStart := phases.start(progress.PhaseSelectProfile)
+	awsProfile, profileSource, err := awsManager.ResolveProfile(ctx, aws.ProfileRequest{
+		Flag:   opts.profile,
+		Query:  opts.query,
+		UseEnv: opts.reuseEnv,
+		Last:   opts.last,
+	})
+	phases.finish(progress.PhaseSelectProfile, stepStart, err)
 	if err != nil {
-		logger.Die(fmt.Sprintf("Failed to select AWS profile: %v", err))
+		fatal(logger, "Failed to select AWS profile", err, utils.ExitConfig)
+	}
+	run.Profile = awsProfile
+	if last := aws.LastLogin(); profileSource == aws.SourceLast && last != nil {
+		k8sManager.SetLastContext(last.Context)
+	}
+	if opts.switchContext != "" {
+		k8sManager.SetLastContext(opts.switchContext)
+	}
+	if opts.dryRun {
+		if _, ok := snapshot.AWSProfile(awsProfile); !ok && !fancyConfig.IsKubeOnlyProfile(awsProfile) {
+			logger.DieWithCode(fmt.Sprintf("Profile %s is neither in %s nor a kube-only profile", awsProfile, config.GetAWSConfigPath()), utils.ExitConfig)
+		}
+		logger.LogInfo(fmt.Sprintf("Dry run for profile %s; nothing will be changed", awsProfile))
 	}
+	if pc, err := fancyConfig.GetProfileConfig(awsProfile); err == nil {
+		run.Environment = pc.Environment
+	}
+
+	// Kube-only profiles authenticate through their own hook and skip all AWS steps
+	kubeOnly := fancyConfig.IsKubeOnlyProfile(awsProfile)
+	if kubeOnly && opts.noK8s {
+		logger.DieWithCode(fmt.Sprintf("%s is a kube-only profile; --no-k8s would leave nothing to do", awsProfile), utils.ExitUsage)
+	}
+	if kubeOnly {
+		stepStart = phases.start(progress.PhasePreLoginHook)
+		err := k8sManager.RunPreLoginHook(ctx, awsProfile)
+		phases.finish(progress.PhasePreLoginHook, stepStart, err)
+		if err != nil {
+			fatal(logger, "Pre-login hook failed", err, utils.ExitKubernetes)
+		}
+	} else {
+		// Set AWS_PROFILE environment variable for this process
+		os.Setenv("AWS_PROFILE", awsProfile)
 
-	// Set AWS_PROFILE environment variable for this process
-	os.Setenv("AWS_PROFILE", awsProfile)
+		// Handle AWS SSO login
+		stepStart = phases.start(progress.PhaseAWSLogin)
+		err := awsManager.HandleAWSLogin(ctx, awsProfile, cfg.ForceAWSLogin)
+		phases.finish(progress.PhaseAWSLogin, stepStart, err)
+		if err != nil {
+			fatal(logger, "AWS login failed", err, utils.ExitAWSAuth)
+		}
+		run.SessionCached = awsManager.SessionReused()
+	}
+	run.LoggedIn = true
 
-	// Handle AWS SSO login
-	if err := awsManager.HandleAWSLogin(awsProfile, cfg.ForceAWSLogin); err != nil {
-		logger.Die(fmt.Sprintf("AWS login failed: %v", err))
+	// Select Kubernetes context and get summary string. --no-k8s must
+	// leave the current context alone, so nothing here runs at all.
+	if opts.noK8s {
+		logger.FancyLog("Skipping Kubernetes context selection (--no-k8s)")
+	} else {
+		stepStart = phases.start(progress.PhaseKubeContext)
+		k8sContextResult, err = k8sManager.SelectKubernetesContext(ctx, awsProfile)
+		phases.finish(progress.PhaseKubeContext, stepStart, err)
+		if err != nil && (opts.context != "" || opts.dryRun) {
+			fatal(logger, "Kubernetes context selection failed", err, utils.ExitKubernetes)
+		}
+		if err != nil {
+			logger.LogWarning(fmt.Sprintf("Kubernetes context selection failed: %v", err))
+			k8sContextResult = fmt.Sprintf("%s🌱 Kubernetes Context:%s (failed to select)", config.Success, config.Reset)
+		}
 	}
 
-	// Select Kubernetes context and get summary string
-	k8sContextResult, err = k8sManager.SelectKubernetesContext(awsProfile)
+	if !kubeOnly {
+		// Get the AWS account ID for the summary; STS is only asked when
+		// the profile has no account_id yet
+		stepStart = phases.start(progress.PhaseAccountID)
+		accountID, fromSTS, err := awsManager.SummaryAccountID(ctx, awsProfile)
+		phases.finish(progress.PhaseAccountID, stepStart, err)
+		// A dry run leaves the cached session and metadata alone
+		if err == nil && !opts.dryRun {
+			accountIDSummary = accountID
+			// Cache the observed session so status/whoami have fresh data
+			if fromSTS {
+				aws.RecordSessionChecks([]aws.SessionCheck{{
+					Profile:   awsProfile,
+					Status:    aws.StatusValid,
+					Identity:  aws.CallerIdentity{Account: accountID},
+					CheckedAt: time.Now(),
+				}})
+			}
+			// Refresh account alias in the background while the rest runs
+			metadataRefresh = awsManager.StartMetadataRefresh(ctx, awsProfile)
+		} else if timeoutErr := asTimeout(err); timeoutErr != nil {
+			timeouts = append(timeouts, timeoutErr.Error())
+		}
+
+		// Handle ECR login based on configuration; only the blocking mode
+		// holds up the summary. A dry run reports the registry in any mode.
+		switch {
+		case opts.dryRun:
+			if err := awsManager.HandleECRLogin(ctx, awsProfile); err != nil {
+				fatal(logger, "ECR login would fail", err, utils.ExitECR)
+			}
+		case ecrMode == config.ECRLoginBlocking && fancyConfig.ShouldPerformECRLogin(awsProfile):
+			stepStart = phases.start(progress.PhaseECRLogin)
+			err = awsManager.HandleECRLogin(ctx, awsProfile)
+			phases.finish(progress.PhaseECRLogin, stepStart, err)
+			ecrAttempted, ecrSucceeded = true, err == nil
+			for _, login := range awsManager.ECRLogins() {
+				if login.Cached {
+					run.ECRCacheHits++
+				}
+			}
+			if err != nil {
+				if timeoutErr := asTimeout(err); timeoutErr != nil {
+					timeouts = append(timeouts, timeoutErr.Error())
+				}
+				logger.FancyLog(fmt.Sprintf("ECR login failed: %v", err))
+			}
+		case ecrMode == config.ECRLoginLazy:
+			if fancyConfig.ShouldPerformECRLogin(awsProfile) {
+				warnMissingCredentialHelper(awsManager, logger, awsProfile, accountIDSummary)
+			}
+		}
+		run.SessionExpiry = awsManager.SessionExpiry(awsProfile)
+
+		// Tools that can't follow AWS_PROFILE get the credentials themselves
+		if opts.exportCreds || fancyConfig.ShouldExportCredentials(awsProfile) {
+			expiresAt, err := awsManager.ExportCredentials(ctx, awsProfile)
+			if err != nil {
+				logger.LogWarning(fmt.Sprintf("Exporting credentials failed: %v", err))
+			} else if !opts.dryRun {
+				credentialsExported, credentialsExpireAt = true, expiresAt
+			}
+		}
+	}
+
+	// A dry run stops before anything that writes state or takes over the
+	// terminal; k9s is only reported
+	if opts.dryRun {
+		if !opts.noK8s {
+			k8sManager.HandleK9sLaunch(ctx, awsProfile)
+		}
+		logger.LogInfo("Dry run complete; nothing was changed")
+		return utils.ExitOK
+	}
+
+	// Pick up any context change made by another tool since we switched
+	currentContext, changed := k8sManager.VerifyCurrentContext(ctx)
+	if changed {
+		k8sContextResult = k8sManager.FormatContextSummary(currentContext, awsProfile)
+	}
+
+	// Tag the tmux pane (no-op unless enabled and running inside tmux)
+	k8sManager.TagTmuxPane(awsProfile, currentContext)
+
+	// Deliver the summary before the k9s prompt; the terminal copy is
+	// skipped in verbose mode, which already logged every step
+	loginSummary := &summary.Summary{
+		Profile:             awsProfile,
+		ProfileSource:       string(profileSource),
+		KubeOnly:            kubeOnly,
+		ContextLine:         k8sContextResult,
+		Context:             currentContext,
+		KubernetesSkipped:   opts.noK8s,
+		ECRAttempted:        ecrAttempted,
+		ECRSucceeded:        ecrSucceeded,
+		ECRCached:           awsManager.ECRLoginCached(),
+		AccountID:           accountIDSummary,
+		Timeouts:            timeouts,
+		SwitchedFrom:        opts.switchedFrom,
+		SessionExpiresAt:    run.SessionExpiry,
+		CredentialsExported: credentialsExported,
+		CredentialsExpireAt: credentialsExpireAt,
+	}
+	if pc, err := fancyConfig.GetProfileConfig(awsProfile); err == nil {
+		loginSummary.AccountAlias = pc.AccountAlias
+		loginSummary.Namespace = pc.Namespace
+	}
+	if currentContext != "" {
+		loginSummary.Namespace = k8sManager.Namespace(awsProfile)
+	}
+	if !opts.noK8s {
+		loginSummary.K9sNotInstalled = k8sManager.K9sNotInstalled(awsProfile)
+	}
+	if p, ok := snapshot.AWSProfile(awsProfile); ok {
+		loginSummary.Region = p.Region
+	}
+	if ecrAttempted {
+		region, source := awsManager.ECRRegion(awsProfile)
+		loginSummary.ECRRegion, loginSummary.ECRRegionSource = region, string(source)
+		for _, login := range awsManager.ECRLogins() {
+			registry := summary.ECRRegistry{Host: login.Host, Cached: login.Cached}
+			if login.Err != nil {
+				registry.Error = login.Err.Error()
+			}
+			loginSummary.ECRRegistries = append(loginSummary.ECRRegistries, registry)
+		}
+	}
+	loginSummary.SSOLoginPerformed = awsManager.SSOLoginPerformed()
+	loginSummary.AssumedRoleARN = awsManager.AssumedRoleARN()
+	loginSummary.Duration = time.Since(run.Started)
+
+	sinks := summarySinks(fancyConfig, logger, cfg.FancyVerbose)
+	if jsonOut != nil {
+		sinks = jsonSinks(sinks, jsonOut)
+	}
+	summary.Deliver(loginSummary, sinks, logger.LogWarning)
+
+	// Remember this login for --last and the picker
+	if err := aws.RecordLastLogin(awsProfile, currentContext); err != nil {
+		logger.FancyLog(fmt.Sprintf("Could not record last login: %v", err))
+	}
+
+	// A background ECR login starts only once the summary is out. It stays
+	// silent while k9s may own the screen and is reported before exit.
+	var ecrLogin *aws.BackgroundECRLogin
+	if ecrMode == config.ECRLoginBackground && !kubeOnly {
+		ecrLogin = awsManager.StartBackgroundECRLogin(ctx, awsProfile, accountIDSummary)
+		logger.OnExit(ecrLogin.Cancel)
+	}
+
+	// Login is complete; k9s can run for hours, so don't count it
+	writeMetrics(fancyConfig, logger, run)
+
+	// Offer the profile's git identity for the repository we're in
+	offerGitIdentity(ctx, fancyConfig, logger, awsProfile)
+
+	// Handle k9s launch based on configuration
+	if !opts.noK8s {
+		if err := k8sManager.HandleK9sLaunch(ctx, awsProfile); err != nil {
+			logger.LogError(fmt.Sprintf("Failed to launch k9s: %v", err))
+		}
+	}
+
+	// Report the background ECR login as the final line
+	if ecrLogin != nil {
+		reportBackgroundECRLogin(out, ecrLogin.Wait())
+	}
+
+	// Never let the background refresh hold up exit for more than a second
+	metadataRefresh.Finish(time.Second)
+
+	logger.LogCompletion("Script execution completed.")
+	return utils.ExitOK
+}
+
+// fatal ends the login with the exit code err maps to, after the logger's
+// exit hooks ran; errors the managers didn't classify get fallback
+func fatal(logger *utils.Logger, message string, err error, fallback int) {
+	logger.DieWithCode(fmt.Sprintf("%s: %v", message, err), utils.ExitCode(err, fallback))
+}
+
+// applyConfiguredTheme activates the theme from settings, falling back to
+// the default (or mono with NO_COLOR) when it's unset or invalid. Screen
+// reader mode, from settings or ACCESSIBLE, is switched on here too and
+// always uses mono.
+func applyConfiguredTheme() {
+	var settings config.GlobalSettings
+	if fancyConfig, err := config.LoadFancyConfig(); err == nil {
+		settings = fancyConfig.Settings
+	}
+	a11y.Enable(a11y.Requested(settings.ScreenReaderMode))
+	if a11y.Enabled() {
+		config.ApplyTheme("mono")
+		return
+	}
+	if err := config.ApplyTheme(settings.Theme); err != nil {
+		config.ApplyTheme("")
+	}
+}
+
+// summarySinks returns the configured summary sinks. Invalid configuration
+// falls back to the terminal so the summary is never lost.
+func summarySinks(fancyConfig *config.FancyConfig, logger *utils.Logger, verbose bool) []summary.Sink {
+	sinks, err := summary.ParseSinks(fancyConfig.Settings.SummarySinks)
 	if err != nil {
-		logger.LogWarning(fmt.Sprintf("Kubernetes context selection failed: %v", err))
-		k8sContextResult = fmt.Sprintf("%s🌱 Kubernetes Context:%s (failed to select)", config.Green, config.Reset)
+		logger.LogWarning(err.Error())
+		sinks, _ = summary.ParseSinks(nil)
+	}
+	if !verbose {
+		return sinks
+	}
+
+	var filtered []summary.Sink
+	for _, sink := range sinks {
+		if _, terminal := sink.(*summary.TerminalSink); !terminal {
+			filtered = append(filtered, sink)
+		}
 	}
+	return filtered
+}
 
-	// Always get AWS account ID for summary
-	if accountID, err := awsManager.GetAccountID(awsProfile); err == nil {
-		accountIDSummary = accountID
+// jsonSinks replaces the sinks that print to the terminal with one writing
+// the JSON summary to out; file sinks are kept
+func jsonSinks(sinks []summary.Sink, out io.Writer) []summary.Sink {
+	filtered := []summary.Sink{&summary.JSONSink{Out: out}}
+	for _, sink := range sinks {
+		switch sink.(type) {
+		case *summary.TerminalSink, *summary.NotifySink:
+			continue
+		}
+		filtered = append(filtered, sink)
 	}
+	return filtered
+}
 
-	// Handle ECR login based on configuration
-	if err := awsManager.HandleECRLogin(awsProfile); err != nil {
-		ecrResult = fmt.Sprintf("%s🐳 ECR login: failed%s", config.Red, config.Reset)
-		ecrAttempted = true
-		logger.FancyLog(fmt.Sprintf("ECR login failed: %v", err))
-	} else if fancyConfig.ShouldPerformECRLogin(awsProfile) {
-		ecrResult = fmt.Sprintf("%s🐳 ECR login: successful%s", config.Green, config.Reset)
-		ecrAttempted = true
+// writeMetrics writes the run's metrics to the configured textfile, once
+// per run. It is best-effort: failures are logged and never fail the run.
+func writeMetrics(fancyConfig *config.FancyConfig, logger *utils.Logger, run *metrics.Run) {
+	path := fancyConfig.Settings.MetricsTextfile
+	if path == "" || !run.Finished.IsZero() {
+		return
 	}
+	run.Finish()
+	totals, err := addRunTotals(run)
+	if err != nil {
+		logger.LogWarning(fmt.Sprintf("Could not update the metrics counters: %v", err))
+	}
+	run.Totals = totals
+	if err := run.WriteTextfile(path); err != nil {
+		logger.LogWarning(fmt.Sprintf("Could not write metrics: %v", err))
+	}
+}
 
-	// Show summary before k9s prompt (unless verbose)
-	if !cfg.FancyVerbose {
-		fmt.Println()
-		fmt.Printf("%s🦄  %sFancy Login Summary%s\n", config.Yellow, config.Bold, config.Reset)
-		fmt.Printf("%s───────────────────────────────────────────────%s\n", config.Yellow, config.Reset)
-		fmt.Printf("%s🔑 AWS Profile:%s %s%s%s\n", config.Yellow, config.Reset, config.Bold, awsProfile, config.Reset)
-		if k8sContextResult != "" {
-			fmt.Println(k8sContextResult)
+// addRunTotals adds run to the counters kept in the state file and returns
+// those of every profile, sorted by profile
+func addRunTotals(run *metrics.Run) ([]metrics.Totals, error) {
+	var totals []metrics.Totals
+	err := state.Update(func(s *state.State) error {
+		if s.RunTotals == nil {
+			s.RunTotals = make(map[string]*state.RunTotals)
+		}
+		t := s.RunTotals[run.Profile]
+		if t == nil {
+			t = &state.RunTotals{}
+			s.RunTotals[run.Profile] = t
 		}
-		if ecrAttempted {
-			fmt.Println(ecrResult)
+		t.Environment = run.Environment
+		if run.Success() {
+			t.Logins++
+		} else {
+			t.Failures++
 		}
-		if accountIDSummary != "" {
-			fmt.Printf("%s☁️  AWS Account ID:%s %s%s%s\n", config.Cyan, config.Reset, config.Bold, accountIDSummary, config.Reset)
+		if run.SessionCached {
+			t.SessionCacheHits++
 		}
-		fmt.Printf("%s───────────────────────────────────────────────%s\n", config.Yellow, config.Reset)
-		fmt.Println()
+		t.ECRCacheHits += run.ECRCacheHits
+
+		for profile, t := range s.RunTotals {
+			totals = append(totals, metrics.Totals{
+				Profile:          profile,
+				Environment:      t.Environment,
+				Logins:           t.Logins,
+				Failures:         t.Failures,
+				SessionCacheHits: t.SessionCacheHits,
+				ECRCacheHits:     t.ECRCacheHits,
+			})
+		}
+		return nil
+	})
+	sort.Slice(totals, func(i, j int) bool { return totals[i].Profile < totals[j].Profile })
+	return totals, err
+}
+
+// reportBackgroundECRLogin prints the outcome of a background ECR login
+// to out
+func reportBackgroundECRLogin(out io.Writer, err error) {
+	if err != nil {
+		fmt.Fprintf(a11y.Writer(out), "%s🐳 ECR login (background): failed: %v%s\n", config.Error, err, config.Reset)
+		return
 	}
+	fmt.Fprintf(a11y.Writer(out), "%s🐳 ECR login (background): successful%s\n", config.Success, config.Reset)
+}
 
-	// Handle k9s launch based on configuration
-	if err := k8sManager.HandleK9sLaunch(awsProfile); err != nil {
-		logger.LogError(fmt.Sprintf("Failed to launch k9s: %v", err))
+// warnMissingCredentialHelper warns when lazy ECR login is configured but
+// docker has no credential helper to log in on first pull
+func warnMissingCredentialHelper(awsManager *aws.AWSManager, logger *utils.Logger, profile, accountID string) {
+	if accountID == "" {
+		return
 	}
+	host, ok := awsManager.CheckLazyECRLogin(profile, accountID)
+	if ok {
+		logger.FancyLog(fmt.Sprintf("ECR login deferred to the docker credential helper for %s", host))
+		return
+	}
+	logger.LogWarning(fmt.Sprintf("ecr_login_mode is lazy, but docker has no credHelpers entry for %s; pulls will fail until you log in or run install-credential-helper", host))
+}
 
-	logger.LogCompletion("Script execution completed.")
+// asTimeout returns the step timeout wrapped in err, if any
+func asTimeout(err error) *utils.TimeoutError {
+	var timeoutErr *utils.TimeoutError
+	if errors.As(err, &timeoutErr) {
+		return timeoutErr
+	}
+	return nil
 }
 
 func showHelp() {
-	fmt.Printf(`Usage: %s [OPTIONS]
+	fmt.Printf(`Usage: %[1]s [login] [OPTIONS] [PROFILE]
+       %[1]s <command> [ARGS]
 
-OPTIONS:
+GLOBAL OPTIONS:
+  --allow-root        Continue when running as root without asking; accepted
+                      with any command
+
+LOGIN OPTIONS:
   -k, --k9s           Auto-launch k9s without prompting
+  -p, --profile NAME  Use the given AWS profile instead of prompting
+  --reuse-env         Reuse AWS_PROFILE (or AWS_DEFAULT_PROFILE) from the environment
   -v, --verbose       Enable verbose output
   --config            Run configuration wizard to set up or update mappings
-  --force-aws-login   Force AWS SSO login even if a valid session exists
+  --output FORMAT     Summary format: text (default) or json; json prints
+                      one object on stdout and everything else on stderr
+  --progress-file PATH
+                      Append a JSON line to PATH as each login phase starts
+                      and finishes
+  --dry-run           Print the actions a login would take without taking
+                      them; with --config, print the YAML instead of saving
+  --force-aws-login   Force AWS SSO login even if a valid session exists; also
+                      implies --force-ecr
+  --force-ecr         Log in to ECR even if docker holds a fresh token
+  --theme NAME        Color theme for this run (default, high-contrast, colorblind, mono)
+  --refresh-metadata  Re-resolve account ID and alias of every configured profile
+  --sort COLUMNS      Sort the picker by columns for this run (name, profile,
+                      account_id, account_alias, environment, region, expiry,
+                      recent, frequent)
+  --context NAME      Switch to this Kubernetes context instead of the
+                      configured one or the picker
+  -n, --namespace NS  Open k9s and label the terminal with namespace NS
+                      instead of the profile's namespace
+  --no-k8s            Log in to AWS only; leave the Kubernetes context alone
+                      and skip k9s
+  --no-k8s-check      Don't check that the cluster of the new context answers
+  --skip-namespace-select
+                      Don't offer the namespace picker of namespace_selection
+  --region REGION     Log in to ECR in REGION for this run, ahead of the
+                      profile's ecr_region, default_region and AWS_REGION
+  --select-timeout D  Wait D (e.g. 5m, or 0 for ever) for a choice in the
+                      profile and context pickers instead of selection_timeout
+  --last              Log in to the profile of the last login again, skipping
+                      the picker; the picker starts on that profile anyway
+  --non-interactive   For CI: never prompt, open the terminal or start fzf;
+                      questions take their default, k9s is skipped and SSO
+                      login prints its device code URL. Requires --profile
+  --assume-yes        With --non-interactive, answer yes to every question
+  --export-creds      Also export the profile's temporary credentials
+                      (AWS_ACCESS_KEY_ID, ...) for tools that can't use
+                      AWS_PROFILE; the export file is readable only by you
+  --no-browser        Print the SSO verification URL and user code instead of
+                      opening a browser, e.g. over SSH
+  --reload            Re-read the AWS config, kubeconfig and fancy config on
+                      every access instead of once per run, e.g. to pick up
+                      edits made while the picker is open
   -h, --help          Show this help message
   --version           Show version information
 
+COMMANDS:
+  login                   Log in (default when no command is given)
+  switch [OPTIONS] [PREFIX]
+                          Log in to the previous profile without the picker,
+                          or to the most recent profile starting with PREFIX
+  config [--dry-run]      Run the configuration wizard (same as --config)
+  config schema [--json]  Print the profile configuration schema
+  config init [--force]   Write a commented example config without the wizard
+  config preview [--watch] [--profile NAME]
+                          Render the picker, settings and a sample summary;
+                          --watch re-renders whenever the config changes
+  config validate [--fix] Check profiles, contexts, ECR regions and account IDs
+                          against the AWS config and kubeconfig; --fix offers
+                          to remove or remap stale entries
+  config edit             Open the config in $VISUAL or $EDITOR and check it
+                          when the editor exits
+  config show [--profile NAME] [--output text|yaml|json]
+                          Print the values in effect and where each came from
+  config export [--profiles a,b] [-o FILE]
+                          Write profiles without git identities for teammates
+  config import [--allow-missing-context] FILE
+                          Merge exported profiles, asking about conflicts
+  profiles [--names]      List profiles as the picker shows them
+  profiles list [--output table|json] [--unconfigured-only]
+                          Show account, ECR and context settings of every profile
+  profiles discover --start-url URL | --from-profile NAME [--all] [--dry-run]
+                          Add profiles for the accounts and roles SSO grants
+  version                 Show version information
+  self-update [--check]   Replace this binary with the latest GitHub release,
+                          or with --check only report whether there is one
+  ecr [--profile NAME] [--registry HOST] [--method pipe|dockercfg|podman]
+                          Log in to a single ECR registry and print a result line
+  status [--refresh] [--output text|json]
+                          Show the current profile, session, context and ECR
+                          login, then the session status of every AWS profile
+  whoami [--cached] [--profile NAME]
+                          Show the account, ARN, user ID and SSO role of the
+                          current profile; exits 4 if the session has expired
+  tmux-status [--pane ID] Print a tmux status-right fragment for the tagged pane
+  k9s [--profile NAME]    Switch to the profile's context and open k9s in its
+                          namespace without logging in (default: $AWS_PROFILE)
+  clusters list [--profile NAME] [--region REGION]
+                          List the profile's EKS clusters; in a terminal, pick
+                          one to create its context and map the profile to it
+  logout [--no-ecr] [PROFILE]
+                          End the SSO session of PROFILE (default: the exported
+                          one), remove the exported profile, log docker out of
+                          its ECR registry and reset the terminal title
+  doctor                  Check external tools, config files and the terminal
+  undo                    Restore the git identity fancy-login last changed
+  install-credential-helper [--dry-run]
+                          Register fancy-login in ~/.docker/config.json as the
+                          credential helper of every configured ECR registry
+  init [sh|bash|zsh|powershell|cmd]
+                          Print the fancy shell function, which sources the
+                          profile of the terminal it runs in
+  cleanup [--dry-run] [--older-than 24h]
+                          Remove profile scripts of terminals that haven't
+                          logged in for a day
+  uninstall [--dry-run] [--yes] [--binary] [--skip NAMES]
+                          Remove files fancy-login created (never ~/.aws or ~/.kube)
+
 Description:
   Interactive tool for AWS SSO login and Kubernetes context selection.
   Uses configuration-driven logic for ECR login, K9s integration, and
@@ -175,9 +885,15 @@ Description:
   Configuration is stored in ~/.fancy-config.yaml and can be edited manually
   or regenerated using the wizard.
 
-Version: %s
-Build Time: %s
-Git Commit: %s
+Profile precedence:
+  --profile > exact PROFILE argument > AWS_PROFILE > AWS_DEFAULT_PROFILE > picker
+  (environment variables are only used with --reuse-env)
+  Any other PROFILE filters the picker, or selects the only configured
+  profile matching it
+
+Version: %[2]s
+Build Time: %[3]s
+Git Commit: %[4]s
 `, os.Args[0], version, buildTime, gitCommit)
 }
 
@@ -185,4 +901,7 @@ func showVersion() {
 	fmt.Printf("fancy-login-go version %s\n", version)
 	fmt.Printf("Build time: %s\n", buildTime)
 	fmt.Printf("Git commit: %s\n", gitCommit)
+	if notice := cachedUpdateNotice(time.Now()); notice != "" {
+		fmt.Printf("%s🔸 %s%s\n", config.Accent, notice, config.Reset)
+	}
 }
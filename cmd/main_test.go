@@ -1,7 +1,20 @@
 package main
 
 import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"fancy-login/internal/a11y"
+	"fancy-login/internal/aws"
+	"fancy-login/internal/config"
+	"fancy-login/internal/metrics"
+	"fancy-login/internal/prompt"
+	"fancy-login/internal/summary"
+	"fancy-login/internal/utils"
 )
 
 func TestVersionVariables(t *testing.T) {
@@ -34,21 +47,291 @@ func TestShowVersionOutput(t *testing.T) {
 	showVersion()
 }
 
-func TestFlagVariables(t *testing.T) {
-	// Test that flag variables are properly initialized
-	if verbose == nil {
-		t.Error("verbose flag should be initialized")
+func TestParseLoginArgs(t *testing.T) {
+	testCases := []struct {
+		name     string
+		args     []string
+		expected loginOptions
+	}{
+		{"No arguments", nil, loginOptions{}},
+		{"Short flags", []string{"-k", "-v", "-p", "dev"}, loginOptions{k9s: true, verbose: true, profile: "dev"}},
+		{"Long flags", []string{"--k9s", "--verbose", "--profile", "dev"}, loginOptions{k9s: true, verbose: true, profile: "dev"}},
+		{"Positional profile", []string{"--force-aws-login", "dev"}, loginOptions{forceAWSLogin: true, query: "dev"}},
+		{"Legacy wizard flag", []string{"--configure"}, loginOptions{config: true}},
+		{"Context override", []string{"--context", "staging"}, loginOptions{context: "staging"}},
+		{"Namespace override", []string{"-n", "payments", "--context", "staging"}, loginOptions{namespace: "payments", context: "staging"}},
+		{"Skip Kubernetes", []string{"--no-k8s", "infra"}, loginOptions{noK8s: true, query: "infra"}},
+		{"Dry run", []string{"--dry-run", "-p", "dev"}, loginOptions{dryRun: true, profile: "dev"}},
+		{"No browser", []string{"--no-browser"}, loginOptions{noBrowser: true}},
+		{"JSON output", []string{"--output", "json", "dev"}, loginOptions{output: "json", query: "dev"}},
+		{"ECR region", []string{"--region", "us-east-1", "-p", "dev"}, loginOptions{region: "us-east-1", profile: "dev"}},
+		{"Last", []string{"--last", "-k"}, loginOptions{last: true, k9s: true}},
+		{"No selection timeout", []string{"--select-timeout", "0"}, loginOptions{selectTimeout: "0"}},
+		{"Selection timeout", []string{"--select-timeout", "5m"}, loginOptions{selectTimeout: "5m"}},
+		{"Non-interactive", []string{"--non-interactive", "--assume-yes", "-p", "dev"}, loginOptions{nonInteractive: true, assumeYes: true, profile: "dev"}},
 	}
-	if k9sFlag == nil {
-		t.Error("k9sFlag should be initialized")
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			opts, err := parseLoginArgs(tc.args)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if tc.expected.output == "" {
+				tc.expected.output = "text"
+			}
+			if *opts != tc.expected {
+				t.Errorf("Expected %+v, got %+v", tc.expected, *opts)
+			}
+		})
 	}
-	if forceAWSLogin == nil {
-		t.Error("forceAWSLogin flag should be initialized")
+
+	if _, err := parseLoginArgs([]string{"--no-such-flag"}); err == nil {
+		t.Error("Expected an error for an unknown flag")
+	}
+	for _, args := range [][]string{{"--no-k8s", "--context", "staging"}, {"--no-k8s", "-k"}, {"--no-k8s", "--namespace", "apps"}, {"--dry-run", "--refresh-metadata"}, {"--output", "yaml"}, {"--output", "json", "--dry-run"}, {"--region", "nowhere"},
+		{"--non-interactive", "dev"}, {"--non-interactive", "-p", "dev", "-k"}, {"--assume-yes", "-p", "dev"},
+		{"--last", "-p", "dev"}, {"--last", "dev"}, {"--select-timeout", "soon"}, {"--select-timeout", "-1m"}} {
+		if _, err := parseLoginArgs(args); err == nil {
+			t.Errorf("Expected an error for %q", args)
+		}
 	}
-	if helpFlag == nil {
-		t.Error("helpFlag should be initialized")
+}
+
+// TestScreenReaderLogin checks that a full login in screen reader mode only
+// appends plain lines: no colors, redraws, emoji or borders
+func TestScreenReaderLogin(t *testing.T) {
+	setupLoginFixture(t, "  screen_reader_mode: true\n")
+	applyConfiguredTheme()
+	defer func() {
+		a11y.Enable(false)
+		config.ApplyTheme("")
+	}()
+	if !a11y.Enabled() {
+		t.Fatal("Expected screen_reader_mode to enable screen reader mode")
 	}
-	if versionFlag == nil {
-		t.Error("versionFlag should be initialized")
+
+	old := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+	output := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(r)
+		output <- data
+	}()
+	code := runLoginCommand([]string{"--profile", "dev"})
+	w.Close()
+	os.Stdout = old
+	text := string(<-output)
+
+	if code != 0 {
+		t.Fatalf("Expected exit code 0, got %d", code)
+	}
+	if !strings.Contains(text, "Fancy Login Summary") || !strings.Contains(text, "ECR login: successful") {
+		t.Errorf("Expected the summary, got %q", text)
+	}
+	if strings.ContainsAny(text, "\r\033") {
+		t.Errorf("Expected no redraws or escapes, got %q", text)
+	}
+	if plain := a11y.Plain(text); plain != text {
+		t.Errorf("Expected no emoji or borders, got %q", text)
+	}
+}
+
+// TestJSONOutputLogin checks that --output json leaves exactly one JSON
+// object on stdout
+func TestJSONOutputLogin(t *testing.T) {
+	setupLoginFixture(t, "")
+
+	old := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+	output := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(r)
+		output <- data
+	}()
+	code := runLoginCommand([]string{"--profile", "dev", "--output", "json"})
+	w.Close()
+	os.Stdout = old
+	data := <-output
+
+	if code != 0 {
+		t.Fatalf("Expected exit code 0, got %d", code)
+	}
+	var report summary.Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("Expected a single JSON object on stdout, got %q: %v", data, err)
+	}
+	expected := summary.Report{
+		Profile:       "dev",
+		ProfileSource: "flag",
+		AccountID:     "123456789012",
+		Region:        "eu-central-1",
+		K8sContext:    "dev-cluster",
+		Namespace:     "default",
+		ECRLogin:      summary.ECRLoginSuccess,
+		ECRRegion:     "eu-central-1",
+		// ecr_region of the profile
+		ECRRegionSource: "config",
+	}
+	report.DurationMS = 0
+	if report != expected {
+		t.Errorf("Expected %+v, got %+v", expected, report)
+	}
+}
+
+// TestECRRegionOverride checks that --region reaches both the ECR token
+// request and the registry host
+func TestECRRegionOverride(t *testing.T) {
+	setupLoginFixture(t, "")
+	binDir := t.TempDir()
+	log := filepath.Join(t.TempDir(), "calls.log")
+	tools := map[string]string{
+		"aws":    "#!/bin/sh\necho \"aws $*\" >> " + log + "\necho '{\"Account\": \"123456789012\"}'\n",
+		"docker": "#!/bin/sh\necho \"docker $*\" >> " + log + "\ncat > /dev/null\n",
+	}
+	for name, script := range tools {
+		if err := os.WriteFile(filepath.Join(binDir, name), []byte(script), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	old := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+	output := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(r)
+		output <- data
+	}()
+	code := runLoginCommand([]string{"--profile", "dev", "--region", "us-west-2", "--output", "json"})
+	w.Close()
+	os.Stdout = old
+	data := <-output
+
+	if code != 0 {
+		t.Fatalf("Expected exit code 0, got %d", code)
+	}
+	var report summary.Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("Expected a JSON object, got %q: %v", data, err)
+	}
+	if report.ECRRegion != "us-west-2" || report.ECRRegionSource != "flag" {
+		t.Errorf("Expected us-west-2 from flag, got %s from %s", report.ECRRegion, report.ECRRegionSource)
+	}
+
+	calls, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"aws ecr get-login-password --region us-west-2 --profile dev",
+		"docker login --username AWS --password-stdin 123456789012.dkr.ecr.us-west-2.amazonaws.com",
+	} {
+		if !strings.Contains(string(calls), want) {
+			t.Errorf("Expected call %q in %q", want, calls)
+		}
+	}
+}
+
+// TestNonInteractiveLogin checks that a CI login with an expired session
+// runs aws sso login without a browser and shows its device code URL
+func TestNonInteractiveLogin(t *testing.T) {
+	setupLoginFixture(t, "  sso_portal_probe: false\n")
+	t.Cleanup(func() { prompt.SetNonInteractive(false, false) })
+
+	// sts fails until sso login ran, like an expired session
+	binDir := t.TempDir()
+	marker := filepath.Join(t.TempDir(), "logged-in")
+	calls := filepath.Join(t.TempDir(), "calls.log")
+	script := "#!/bin/sh\necho \"$*\" >> " + calls + "\n" +
+		"case \"$1 $2\" in\n" +
+		"  \"sso login\") touch " + marker + "; echo 'https://device.sso.eu-central-1.amazonaws.com/?user_code=ABCD-EFGH' ;;\n" +
+		"  *) [ -f " + marker + " ] || exit 255; echo '{\"Account\": \"123456789012\"}' ;;\n" +
+		"esac\n"
+	if err := os.WriteFile(filepath.Join(binDir, "aws"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	old := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+	output := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(r)
+		output <- data
+	}()
+	code := runLoginCommand([]string{"--non-interactive", "--profile", "dev"})
+	w.Close()
+	os.Stdout = old
+	text := string(<-output)
+
+	if code != 0 {
+		t.Fatalf("Expected exit code 0, got %d", code)
+	}
+	if !strings.Contains(text, "user_code=ABCD-EFGH") {
+		t.Errorf("Expected the device code URL in the output, got %q", text)
+	}
+	logged, err := os.ReadFile(calls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(logged), "sso login --profile dev --no-browser") {
+		t.Errorf("Expected aws sso login --no-browser, got %q", logged)
+	}
+}
+
+// TestLastLogin checks that a login is remembered and --last repeats it
+// without the picker
+func TestLastLogin(t *testing.T) {
+	setupLoginFixture(t, "")
+
+	if code := runLoginCommand([]string{"--profile", "dev"}); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d", code)
+	}
+	last := aws.LastLogin()
+	if last == nil || last.Profile != "dev" || last.Context != "dev-cluster" {
+		t.Fatalf("Expected dev in dev-cluster to be recorded, got %+v", last)
+	}
+
+	// Without fzf on PATH a picker would fail the login
+	if code := runLoginCommand([]string{"--last"}); code != 0 {
+		t.Errorf("Expected --last to log in to dev again, got exit code %d", code)
+	}
+}
+
+func TestWriteMetricsCounters(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("FANCY_STATE_DIR", filepath.Join(home, ".fancy-login"))
+	path := filepath.Join(home, "fancy_login.prom")
+	fancyConfig := config.DefaultFancyConfig()
+	fancyConfig.Settings.MetricsTextfile = path
+	logger := utils.NewLogger(false)
+
+	cached := metrics.NewRun()
+	cached.Profile, cached.Environment, cached.SessionCached = "dev", "dev", true
+	writeMetrics(fancyConfig, logger, cached)
+	// A second write of the same run, e.g. from an exit hook, is ignored
+	writeMetrics(fancyConfig, logger, cached)
+
+	failed := metrics.NewRun()
+	failed.Profile, failed.Environment, failed.Aborted = "dev", "dev", true
+	writeMetrics(fancyConfig, logger, failed)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`fancy_login_logins_total{profile="dev",environment="dev"} 1`,
+		`fancy_login_failures_total{profile="dev",environment="dev"} 1`,
+		`fancy_login_cache_hits_total{profile="dev",environment="dev",cache="sso_session"} 1`,
+	} {
+		if !strings.Contains(string(data), want+"\n") {
+			t.Errorf("Expected %s in:\n%s", want, data)
+		}
 	}
 }
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"fancy-login/internal/a11y"
+	"fancy-login/internal/aws"
+	"fancy-login/internal/config"
+	"fancy-login/internal/k8s"
+	"fancy-login/internal/summary"
+	"fancy-login/internal/utils"
+)
+
+const (
+	// previewPollInterval is how often --watch checks the config file
+	previewPollInterval = 250 * time.Millisecond
+	// previewQuietPeriod is how long the file must stay unchanged before the
+	// preview re-renders, so editors writing in several steps render once
+	previewQuietPeriod = 300 * time.Millisecond
+)
+
+// runConfigPreview renders what fancy-login would show with the current
+// config, without logging in. --watch re-renders whenever the file changes.
+func runConfigPreview(args []string) int {
+	fs := flag.NewFlagSet("config preview", flag.ContinueOnError)
+	watch := fs.Bool("watch", false, "Re-render whenever the config file changes")
+	profile := fs.String("profile", "", "Profile to render the sample summary for")
+	if err := fs.Parse(args); err != nil {
+		return utils.ExitUsage
+	}
+
+	snapshot := config.NewSnapshot(nil)
+	if !*watch {
+		if !renderPreview(os.Stdout, snapshot, *profile, time.Now()) {
+			return utils.ExitFailure
+		}
+		return utils.ExitOK
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	render := func() {
+		// Screen readers get each render appended instead of a redraw
+		if !a11y.Enabled() {
+			fmt.Print("\033[H\033[2J")
+		}
+		snapshot.Reload()
+		renderPreview(os.Stdout, snapshot, *profile, time.Now())
+		fmt.Printf("%sWatching for changes, press Ctrl-C to stop.%s\n", config.Muted, config.Reset)
+	}
+	render()
+	watchFile(ctx, config.GetFancyConfigPath(), previewPollInterval, previewQuietPeriod, render)
+	return utils.ExitOK
+}
+
+// renderPreview writes the picker list, effective settings and a sample
+// summary for the configs in snapshot. Problems are shown inline; it returns
+// false if there were any.
+func renderPreview(w io.Writer, snapshot *config.Snapshot, profile string, now time.Time) bool {
+	configPath := config.GetFancyConfigPath()
+	fmt.Fprintf(w, "%s%sConfig preview:%s %s %s(%s)%s\n\n",
+		config.Heading, config.Bold, config.Reset, configPath, config.Muted, now.Format("15:04:05"), config.Reset)
+
+	fancyConfig, err := snapshot.FancyConfig()
+	if err != nil {
+		fmt.Fprintf(w, "%s❌ %v%s\n", config.Error, err, config.Reset)
+		return false
+	}
+
+	problems := previewProblems(fancyConfig)
+	if len(problems) > 0 {
+		fmt.Fprintf(w, "%s%sProblems:%s\n", config.Error, config.Bold, config.Reset)
+		for _, problem := range problems {
+			fmt.Fprintf(w, "  %s❌ %v%s\n", config.Error, problem, config.Reset)
+		}
+	} else {
+		fmt.Fprintf(w, "%s✓ Configuration is valid%s\n", config.Success, config.Reset)
+	}
+
+	fmt.Fprintf(w, "\n%s%sProfile picker:%s\n", config.Heading, config.Bold, config.Reset)
+	awsManager := aws.NewAWSManager(config.NewConfig(), utils.NewLogger(false), fancyConfig)
+	awsManager.SetSnapshot(snapshot)
+	entries, err := awsManager.ProfileList()
+	if err != nil {
+		fmt.Fprintf(w, "  %s⚠️  %v%s\n", config.Warning, err, config.Reset)
+	}
+	for _, entry := range entries {
+		fmt.Fprintf(w, "  %s\n", entry.DisplayText)
+	}
+
+	fmt.Fprintf(w, "\n%s%sEffective settings:%s\n", config.Heading, config.Bold, config.Reset)
+	for _, field := range config.SettingsSchema {
+		value := config.SettingValue(&fancyConfig.Settings, field.Key)
+		if value == "" {
+			value = fmt.Sprintf("%s(default %s)%s", config.Muted, orNone(field.Default), config.Reset)
+		}
+		fmt.Fprintf(w, "  %-22s %s\n", field.Key, value)
+	}
+
+	if sample := previewSummary(fancyConfig, profile); sample != nil {
+		fmt.Fprintf(w, "\n%s%sSample summary:%s", config.Heading, config.Bold, config.Reset)
+		fmt.Fprint(w, summary.RenderTerminal(sample))
+	} else if profile != "" {
+		problems = append(problems, fmt.Errorf("profile %s is not configured", profile))
+		fmt.Fprintf(w, "\n%s❌ Profile %s is not configured%s\n", config.Error, profile, config.Reset)
+	}
+
+	return len(problems) == 0
+}
+
+// previewProblems validates the config plus the settings checked at login
+func previewProblems(fancyConfig *config.FancyConfig) []error {
+	problems := fancyConfig.Validate()
+	if _, err := summary.ParseSinks(fancyConfig.Settings.SummarySinks); err != nil {
+		problems = append(problems, fmt.Errorf("settings.summary_sinks: %w", err))
+	}
+	if err := aws.ValidateProfileSort(fancyConfig.Settings.ProfileSort); err != nil {
+		problems = append(problems, fmt.Errorf("settings.profile_sort: %w", err))
+	}
+	return problems
+}
+
+// previewSummary builds the summary a successful login of profile would
+// show; an empty profile picks the first configured one
+func previewSummary(fancyConfig *config.FancyConfig, profile string) *summary.Summary {
+	if profile == "" {
+		names := make([]string, 0, len(fancyConfig.ProfileConfigs))
+		for name := range fancyConfig.ProfileConfigs {
+			names = append(names, name)
+		}
+		if len(names) == 0 {
+			return nil
+		}
+		sort.Strings(names)
+		profile = names[0]
+	}
+	profile = fancyConfig.ResolveProfileName(profile)
+
+	if pc, ok := fancyConfig.ProfileConfigs[profile]; ok {
+		s := &summary.Summary{
+			Profile:      profile,
+			Context:      pc.K8sContext,
+			ECRAttempted: pc.ECRLogin,
+			ECRSucceeded: pc.ECRLogin,
+			AccountID:    pc.AccountID,
+			AccountAlias: pc.AccountAlias,
+		}
+		if pc.K8sContext != "" {
+			s.ContextLine = k8s.ContextSummaryLine(pc.K8sContext, pc.Namespace)
+		}
+		return s
+	}
+	if kp, ok := fancyConfig.KubeOnlyProfiles[profile]; ok {
+		return &summary.Summary{
+			Profile:     profile,
+			KubeOnly:    true,
+			Context:     kp.K8sContext,
+			ContextLine: k8s.ContextSummaryLine(kp.K8sContext, kp.Namespace),
+		}
+	}
+	return nil
+}
+
+// orNone shows an empty schema default as "none"
+func orNone(value string) string {
+	if strings.TrimSpace(value) == "" {
+		return "none"
+	}
+	return value
+}
+
+// fileStamp is what watchFile compares to notice a change
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+	exists  bool
+}
+
+func statFile(path string) fileStamp {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileStamp{}
+	}
+	return fileStamp{modTime: info.ModTime(), size: info.Size(), exists: true}
+}
+
+// watchFile polls path every interval and calls onChange once the file has
+// stopped changing for quiet. It returns when ctx is done. Polling keeps us
+// free of a file-notification dependency and also catches editors that
+// replace the file instead of writing it.
+func watchFile(ctx context.Context, path string, interval, quiet time.Duration, onChange func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	last := statFile(path)
+	var changedAt time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if current := statFile(path); current != last {
+				last = current
+				changedAt = now
+				continue
+			}
+			if !changedAt.IsZero() && now.Sub(changedAt) >= quiet {
+				changedAt = time.Time{}
+				onChange()
+			}
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"fancy-login/internal/config"
+)
+
+func TestRenderPreviewShowsAndClearsErrors(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("AWS_CONFIG_FILE", "")
+	configPath := filepath.Join(home, ".fancy-config.yaml")
+
+	testCases := []struct {
+		name          string
+		content       string
+		expectValid   bool
+		expectedLines []string
+	}{
+		{
+			name: "Invalid theme",
+			content: `profile_configs:
+  dev:
+    name: Development
+    k8s_context: dev-cluster
+    namespace: apps
+settings:
+  theme: neon
+`,
+			expectValid:   false,
+			expectedLines: []string{"settings.theme", "neon"},
+		},
+		{
+			name: "Fixed theme",
+			content: `profile_configs:
+  dev:
+    name: Development
+    account_id: "123456789012"
+    ecr_login: true
+    k8s_context: dev-cluster
+    namespace: apps
+settings:
+  theme: mono
+`,
+			expectValid:   true,
+			expectedLines: []string{"Configuration is valid", "dev-cluster", "(ns: apps)", "ECR login: successful", "123456789012"},
+		},
+		{
+			name:          "Unparseable YAML",
+			content:       "profile_configs: [\n",
+			expectValid:   false,
+			expectedLines: []string{"failed to parse config file"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := os.WriteFile(configPath, []byte(tc.content), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			var out bytes.Buffer
+			valid := renderPreview(&out, config.NewSnapshot(nil), "", time.Now())
+			if valid != tc.expectValid {
+				t.Errorf("Expected valid=%v, got %v:\n%s", tc.expectValid, valid, out.String())
+			}
+			for _, line := range tc.expectedLines {
+				if !strings.Contains(out.String(), line) {
+					t.Errorf("Expected preview to contain %q, got:\n%s", line, out.String())
+				}
+			}
+			if tc.expectValid && strings.Contains(out.String(), "❌") {
+				t.Errorf("Expected no errors once fixed, got:\n%s", out.String())
+			}
+		})
+	}
+}
+
+func TestWatchFileDebouncesWrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte("a"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var changes atomic.Int32
+	done := make(chan struct{})
+	go func() {
+		watchFile(ctx, path, 10*time.Millisecond, 100*time.Millisecond, func() { changes.Add(1) })
+		close(done)
+	}()
+
+	// An editor saving in several quick steps should render once
+	for _, content := range []string{"ab", "abc", "abcd"} {
+		time.Sleep(20 * time.Millisecond)
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for changes.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(200 * time.Millisecond)
+
+	if got := changes.Load(); got != 1 {
+		t.Errorf("Expected 1 change, got %d", got)
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Error("Expected watchFile to return after cancel")
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"fancy-login/internal/aws"
+	"fancy-login/internal/config"
+	"fancy-login/internal/utils"
+)
+
+// runProfilesCommand handles `fancy-login-go profiles`, printing the picker
+// entries without opening the picker. `profiles list` audits them instead
+// and `profiles discover` adds new ones.
+func runProfilesCommand(args []string) int {
+	if len(args) > 0 && args[0] == "list" {
+		return runProfilesList(args[1:])
+	}
+	if len(args) > 0 && args[0] == "discover" {
+		return runProfilesDiscover(args[1:])
+	}
+
+	fs := flag.NewFlagSet("profiles", flag.ContinueOnError)
+	names := fs.Bool("names", false, "Print only profile names, one per line")
+	if err := fs.Parse(args); err != nil {
+		return utils.ExitUsage
+	}
+
+	fancyConfig, err := config.LoadFancyConfig()
+	if err != nil {
+		fmt.Printf("%s❌ %v%s\n", config.Error, err, config.Reset)
+		return utils.ExitFailure
+	}
+	awsManager := aws.NewAWSManager(config.NewConfig(), utils.NewLogger(false), fancyConfig)
+	entries, err := awsManager.ProfileList()
+	if err != nil {
+		fmt.Printf("%s❌ %v%s\n", config.Error, err, config.Reset)
+		return utils.ExitFailure
+	}
+
+	for _, entry := range entries {
+		switch {
+		case !*names:
+			fmt.Println(entry.DisplayText)
+		case entry.Name != "---":
+			fmt.Println(entry.Name)
+		}
+	}
+	return utils.ExitOK
+}
+
+// profileRow is one line of `profiles list`
+type profileRow struct {
+	Name          string `json:"name"`
+	Configured    bool   `json:"configured"`
+	AccountID     string `json:"account_id"`
+	ECRLogin      bool   `json:"ecr_login"`
+	ECRRegion     string `json:"ecr_region"`
+	K8sContext    string `json:"k8s_context"`
+	K9sAutoLaunch bool   `json:"k9s_auto_launch"`
+}
+
+// runProfilesList handles `fancy-login-go profiles list`, showing what
+// fancy-login knows about every AWS profile. It reads only the AWS and
+// fancy-login configs, so it works without a kubeconfig.
+func runProfilesList(args []string) int {
+	fs := flag.NewFlagSet("profiles list", flag.ContinueOnError)
+	output := fs.String("output", "table", "Output format: table or json")
+	unconfiguredOnly := fs.Bool("unconfigured-only", false, "Only list profiles without fancy-login configuration")
+	if err := fs.Parse(args); err != nil {
+		return utils.ExitUsage
+	}
+	if *output != "table" && *output != "json" {
+		fmt.Fprintf(os.Stderr, "invalid --output %q: must be table or json\n", *output)
+		return utils.ExitUsage
+	}
+
+	snapshot := config.NewSnapshot(nil)
+	fancyConfig, err := snapshot.FancyConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s❌ %v%s\n", config.Error, err, config.Reset)
+		return utils.ExitFailure
+	}
+	names, err := snapshot.AWSProfileNames()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s❌ %v%s\n", config.Error, err, config.Reset)
+		return utils.ExitFailure
+	}
+
+	rows := profileRows(names, fancyConfig, *unconfiguredOnly)
+	if *output == "json" {
+		err = writeProfilesJSON(os.Stdout, rows)
+	} else {
+		writeProfilesTable(os.Stdout, rows)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s❌ %v%s\n", config.Error, err, config.Reset)
+		return utils.ExitFailure
+	}
+	return utils.ExitOK
+}
+
+// profileRows merges the AWS profile names with the configured profiles,
+// sorted by name. Configured profiles missing from the AWS config are kept so
+// stale entries show up too.
+func profileRows(awsProfiles []string, fancyConfig *config.FancyConfig, unconfiguredOnly bool) []profileRow {
+	seen := make(map[string]bool)
+	var rows []profileRow
+	add := func(name string) {
+		if seen[name] {
+			return
+		}
+		seen[name] = true
+		pc, configured := fancyConfig.ProfileConfigs[name]
+		if unconfiguredOnly && configured {
+			return
+		}
+		row := profileRow{Name: name, Configured: configured}
+		if configured {
+			row.AccountID = pc.AccountID
+			row.ECRLogin = pc.ECRLogin
+			row.ECRRegion = pc.ECRRegion
+			row.K8sContext = pc.K8sContext
+			row.K9sAutoLaunch = pc.K9sAutoLaunch
+		}
+		rows = append(rows, row)
+	}
+
+	for _, name := range awsProfiles {
+		add(name)
+	}
+	for name := range fancyConfig.ProfileConfigs {
+		add(name)
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Name < rows[j].Name })
+	return rows
+}
+
+// writeProfilesJSON writes rows as a JSON array; no rows is [] rather than
+// null so it pipes cleanly into jq
+func writeProfilesJSON(w io.Writer, rows []profileRow) error {
+	if rows == nil {
+		rows = []profileRow{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(rows)
+}
+
+// writeProfilesTable writes rows as aligned columns
+func writeProfilesTable(w io.Writer, rows []profileRow) {
+	headers := []string{"PROFILE", "CONFIGURED", "ACCOUNT", "ECR", "ECR REGION", "K8S CONTEXT", "K9S"}
+	cells := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		cells = append(cells, []string{
+			row.Name, yesNo(row.Configured), orDash(row.AccountID), yesNo(row.ECRLogin),
+			orDash(row.ECRRegion), orDash(row.K8sContext), yesNo(row.K9sAutoLaunch),
+		})
+	}
+
+	widths := make([]int, len(headers))
+	for _, line := range append([][]string{headers}, cells...) {
+		for i, cell := range line {
+			widths[i] = max(widths[i], len(cell))
+		}
+	}
+	for _, line := range append([][]string{headers}, cells...) {
+		for i, cell := range line {
+			if i == len(line)-1 {
+				fmt.Fprintln(w, cell)
+				continue
+			}
+			fmt.Fprintf(w, "%-*s  ", widths[i], cell)
+		}
+	}
+}
+
+func yesNo(b bool) string {
+	if b {
+		return "yes"
+	}
+	return "no"
+}
+
+// orDash shows an unset column as "-"
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
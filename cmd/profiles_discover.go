@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"fancy-login/internal/a11y"
+	"fancy-login/internal/aws"
+	"fancy-login/internal/config"
+	"fancy-login/internal/fzf"
+	"fancy-login/internal/prompt"
+	"fancy-login/internal/utils"
+)
+
+// runProfilesDiscover handles `fancy-login-go profiles discover`: it lists
+// the accounts and roles an SSO portal grants, lets the user pick some and
+// appends a profile for each to ~/.aws/config
+func runProfilesDiscover(args []string) int {
+	fs := flag.NewFlagSet("profiles discover", flag.ContinueOnError)
+	startURL := fs.String("start-url", "", "SSO start URL to discover roles of; needs a cached token for it")
+	ssoRegion := fs.String("sso-region", "", "Region of the SSO portal, if the cached token doesn't tell")
+	fromProfile := fs.String("from-profile", "", "Existing SSO profile to borrow the portal and token from")
+	region := fs.String("region", "", "Region of the new profiles; defaults to that of --from-profile or the SSO region")
+	all := fs.Bool("all", false, "Add every discovered role without the picker")
+	dryRun := fs.Bool("dry-run", false, "Print the profiles instead of adding them")
+	if err := fs.Parse(args); err != nil {
+		return utils.ExitUsage
+	}
+	if (*startURL == "") == (*fromProfile == "") {
+		fmt.Fprintln(os.Stderr, "Usage: fancy-login-go profiles discover --start-url URL | --from-profile NAME [--all] [--dry-run]")
+		return utils.ExitUsage
+	}
+	if !*all && !*dryRun && !prompt.Interactive() {
+		fmt.Fprintf(os.Stderr, "%s❌ Picking roles needs a terminal; pass --all to add every role%s\n", config.Error, config.Reset)
+		return utils.ExitUsage
+	}
+
+	fancyConfig, err := config.LoadFancyConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s❌ %v%s\n", config.Error, err, config.Reset)
+		return utils.ExitConfig
+	}
+	awsManager := aws.NewAWSManager(config.NewConfig(), utils.NewLogger(false), fancyConfig)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var portal aws.SSOPortal
+	if *fromProfile != "" {
+		portal, err = awsManager.PortalFromProfile(ctx, *fromProfile)
+	} else {
+		portal, err = awsManager.PortalFromStartURL(*startURL, *ssoRegion)
+	}
+	if err == nil && *region != "" {
+		portal.ProfileRegion = *region
+	}
+	var roles []aws.SSORole
+	if err == nil {
+		roles, err = awsManager.DiscoverSSORoles(ctx, &portal)
+	}
+	var profiles []config.AWSProfile
+	if err == nil {
+		profiles, err = awsManager.NewSSOProfiles(portal, roles)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s❌ %v%s\n", config.Error, err, config.Reset)
+		return utils.ExitCode(err, utils.ExitFailure)
+	}
+	if known := len(roles) - len(profiles); known > 0 {
+		fmt.Printf("%s%d of %d roles already have a profile%s\n", config.Muted, known, len(roles), config.Reset)
+	}
+	if len(profiles) == 0 {
+		fmt.Printf("%s✅ Every role of %s has a profile already%s\n", config.Success, portal.StartURL, config.Reset)
+		return utils.ExitOK
+	}
+
+	if *dryRun {
+		for i, p := range profiles {
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Print(config.FormatAWSProfile(p))
+		}
+		return utils.ExitOK
+	}
+
+	if !*all {
+		profiles, err = pickDiscoveredProfiles(ctx, profiles, fancyConfig.Settings)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s❌ %v%s\n", config.Error, err, config.Reset)
+			return utils.ExitCode(err, utils.ExitFailure)
+		}
+		if len(profiles) == 0 {
+			fmt.Println("No roles selected")
+			return utils.ExitOK
+		}
+	}
+
+	awsConfigPath := config.GetAWSConfigPath()
+	backup, err := config.AppendAWSProfiles(awsConfigPath, profiles, time.Now())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s❌ %v%s\n", config.Error, err, config.Reset)
+		return utils.ExitFailure
+	}
+	if backup != "" {
+		fmt.Printf("%s💾 Backed up %s to %s%s\n", config.Success, awsConfigPath, backup, config.Reset)
+	}
+	for _, p := range profiles {
+		fmt.Printf("%s✅ Added profile %s (%s, %s)%s\n", config.Success, p.Name, p.AccountID, p.SSORole, config.Reset)
+	}
+
+	if !prompt.Interactive() {
+		return utils.ExitOK
+	}
+	prompter, closeTTY, err := prompt.NewTTYPrompter(fancyConfig.Settings.AffirmativeAnswers, fancyConfig.Settings.NegativeAnswers)
+	if err != nil {
+		return utils.ExitOK
+	}
+	defer closeTTY()
+	for _, p := range profiles {
+		if !prompter.Confirm(fmt.Sprintf("%sConfigure %s for fancy-login now?%s", config.Accent, p.Name, config.Reset), true) {
+			continue
+		}
+		if err := config.RunProfileWizard(fancyConfig, p.Name); err != nil {
+			fmt.Fprintf(os.Stderr, "%s⚠️  Profile %s was not configured: %v%s\n", config.Warning, p.Name, err, config.Reset)
+		}
+	}
+	return utils.ExitOK
+}
+
+// discoveredLine is how a discovered profile is shown in the picker
+func discoveredLine(p config.AWSProfile) string {
+	return fmt.Sprintf("%s  (%s, %s)", p.Name, p.AccountID, p.SSORole)
+}
+
+// pickDiscoveredProfiles lets the user mark the profiles to add: in fzf
+// with Tab, or by number in screen reader mode and with the built-in
+// selector
+func pickDiscoveredProfiles(ctx context.Context, profiles []config.AWSProfile, settings config.GlobalSettings) ([]config.AWSProfile, error) {
+	timeout := settings.SelectionTimeoutDuration()
+	if a11y.Enabled() || fzf.UseBuiltin(settings.Selector) {
+		prompter, closeTTY, err := prompt.NewTTYPrompter(settings.AffirmativeAnswers, settings.NegativeAnswers)
+		if err != nil {
+			return nil, fmt.Errorf("picking roles needs a terminal: %w", err)
+		}
+		defer closeTTY()
+		for i, p := range profiles {
+			fmt.Printf("%3d) %s\n", i+1, discoveredLine(p))
+		}
+		indexes, err := parseSelection(prompter.AskLine("Profiles to add, e.g. 1,3-5 or all", ""), len(profiles))
+		if err != nil {
+			return nil, err
+		}
+		picked := make([]config.AWSProfile, 0, len(indexes))
+		for _, i := range indexes {
+			picked = append(picked, profiles[i])
+		}
+		return picked, nil
+	}
+
+	lines := make([]string, len(profiles))
+	for i, p := range profiles {
+		lines[i] = discoveredLine(p)
+	}
+	ctx, cancel := utils.WithStepTimeout(ctx, timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "fzf", fzf.Detect().Args(fzf.Options{
+		Prompt: "Select roles (Tab marks several): ",
+		Header: "Tab marks a role, Enter adds the marked roles",
+		Multi:  true,
+	})...)
+	cmd.Stdin = strings.NewReader(strings.Join(lines, "\n"))
+	cmd.Stderr = os.Stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, &utils.SelectionTimeoutError{What: "role selection", Timeout: timeout}
+		}
+		if fzf.Cancelled(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("role selection failed: %w", err)
+	}
+
+	selected := make(map[string]bool)
+	for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+		selected[line] = true
+	}
+	var picked []config.AWSProfile
+	for i, p := range profiles {
+		if selected[lines[i]] {
+			picked = append(picked, p)
+		}
+	}
+	return picked, nil
+}
+
+// parseSelection parses a list of 1-based numbers and ranges such as
+// "1,3-5", or "all", into 0-based indexes below n in ascending order. An
+// empty answer selects nothing.
+func parseSelection(input string, n int) ([]int, error) {
+	input = strings.TrimSpace(input)
+	if strings.EqualFold(input, "all") {
+		indexes := make([]int, n)
+		for i := range indexes {
+			indexes[i] = i
+		}
+		return indexes, nil
+	}
+
+	chosen := make([]bool, n)
+	for _, part := range strings.Split(input, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		from, to, isRange := strings.Cut(part, "-")
+		first, err := strconv.Atoi(strings.TrimSpace(from))
+		last := first
+		if err == nil && isRange {
+			last, err = strconv.Atoi(strings.TrimSpace(to))
+		}
+		if err != nil || first < 1 || last > n || first > last {
+			return nil, fmt.Errorf("%q is not a number or range between 1 and %d", part, n)
+		}
+		for i := first; i <= last; i++ {
+			chosen[i-1] = true
+		}
+	}
+
+	var indexes []int
+	for i, ok := range chosen {
+		if ok {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes, nil
+}
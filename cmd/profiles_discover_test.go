@@ -0,0 +1,106 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"fancy-login/internal/utils"
+)
+
+func TestParseSelection(t *testing.T) {
+	testCases := []struct {
+		input       string
+		expected    []int
+		expectError bool
+	}{
+		{"1", []int{0}, false},
+		{"1,3-4", []int{0, 2, 3}, false},
+		{" 4 , 2 ,2", []int{1, 3}, false},
+		{"all", []int{0, 1, 2, 3}, false},
+		{"", nil, false},
+		{"5", nil, true},
+		{"0", nil, true},
+		{"3-1", nil, true},
+		{"two", nil, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			got, err := parseSelection(tc.input, 4)
+			if (err != nil) != tc.expectError {
+				t.Fatalf("parseSelection(%q) error = %v, expectError %v", tc.input, err, tc.expectError)
+			}
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("Expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestProfilesDiscoverAll(t *testing.T) {
+	home := setupLoginFixture(t, "")
+	cacheDir := filepath.Join(home, ".aws", "sso", "cache")
+	if err := os.MkdirAll(cacheDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	token := `{"startUrl":"https://example.awsapps.com/start","region":"eu-central-1","accessToken":"cached-token","expiresAt":"` +
+		time.Now().Add(time.Hour).UTC().Format(time.RFC3339) + `"}`
+	if err := os.WriteFile(filepath.Join(cacheDir, "token.json"), []byte(token), 0600); err != nil {
+		t.Fatal(err)
+	}
+	binDir := t.TempDir()
+	fakeAWS := "#!/bin/sh\ncase \"$2\" in\n" +
+		"list-accounts) echo '{\"accountList\":[{\"accountId\":\"123456789012\",\"accountName\":\"Example Dev\"}]}' ;;\n" +
+		"list-account-roles) echo '{\"roleList\":[{\"roleName\":\"Developer\"},{\"roleName\":\"ReadOnly\"}]}' ;;\n" +
+		"esac\n"
+	if err := os.WriteFile(filepath.Join(binDir, "aws"), []byte(fakeAWS), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	code := runProfilesCommand([]string{"discover", "--start-url", "https://example.awsapps.com/start", "--all"})
+	if code != utils.ExitOK {
+		t.Fatalf("Expected exit code 0, got %d", code)
+	}
+
+	data, err := os.ReadFile(filepath.Join(home, ".aws", "config"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		"[profile dev]\n",
+		"\n[profile example-dev-developer]\nsso_start_url = https://example.awsapps.com/start\nsso_region = eu-central-1\nsso_account_id = 123456789012\nsso_role_name = Developer\nregion = eu-central-1\n",
+		"\n[profile example-dev-readonly]\n",
+	} {
+		if !strings.Contains(string(data), expected) {
+			t.Errorf("Expected %q in %q", expected, data)
+		}
+	}
+	backups, _ := filepath.Glob(filepath.Join(home, ".aws", "config.*.bak"))
+	if len(backups) != 1 {
+		t.Errorf("Expected a backup of the AWS config, got %v", backups)
+	}
+
+	// Running it again finds nothing new
+	if code := runProfilesCommand([]string{"discover", "--start-url", "https://example.awsapps.com/start", "--all"}); code != utils.ExitOK {
+		t.Fatalf("Expected exit code 0, got %d", code)
+	}
+	if again, _ := os.ReadFile(filepath.Join(home, ".aws", "config")); string(again) != string(data) {
+		t.Errorf("Expected no further profiles, got %q", again)
+	}
+}
+
+func TestProfilesDiscoverUsage(t *testing.T) {
+	for _, args := range [][]string{
+		{"discover"},
+		{"discover", "--start-url", "https://example.awsapps.com/start", "--from-profile", "dev"},
+	} {
+		if code := runProfilesCommand(args); code != utils.ExitUsage {
+			t.Errorf("Expected exit code %d for %v, got %d", utils.ExitUsage, args, code)
+		}
+	}
+}
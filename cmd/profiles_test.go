@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"fancy-login/internal/config"
+)
+
+func TestProfileRows(t *testing.T) {
+	fancyConfig := config.DefaultFancyConfig()
+	fancyConfig.ProfileConfigs = map[string]config.ProfileConfig{
+		"dev":     {AccountID: "123456789012", ECRLogin: true, ECRRegion: "eu-west-1", K8sContext: "dev-cluster", K9sAutoLaunch: true},
+		"retired": {AccountID: "210987654321"},
+	}
+	awsProfiles := []string{"sandbox", "dev"}
+
+	testCases := []struct {
+		name             string
+		unconfiguredOnly bool
+		expected         []profileRow
+	}{
+		{"All profiles", false, []profileRow{
+			{Name: "dev", Configured: true, AccountID: "123456789012", ECRLogin: true, ECRRegion: "eu-west-1", K8sContext: "dev-cluster", K9sAutoLaunch: true},
+			{Name: "retired", Configured: true, AccountID: "210987654321"},
+			{Name: "sandbox"},
+		}},
+		{"Unconfigured only", true, []profileRow{
+			{Name: "sandbox"},
+		}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rows := profileRows(awsProfiles, fancyConfig, tc.unconfiguredOnly)
+			if !reflect.DeepEqual(rows, tc.expected) {
+				t.Errorf("Expected %+v, got %+v", tc.expected, rows)
+			}
+		})
+	}
+}
+
+func TestWriteProfiles(t *testing.T) {
+	rows := []profileRow{
+		{Name: "dev", Configured: true, AccountID: "123456789012", ECRLogin: true, K8sContext: "dev-cluster"},
+		{Name: "sandbox"},
+	}
+
+	var table bytes.Buffer
+	writeProfilesTable(&table, rows)
+	lines := strings.Split(strings.TrimSpace(table.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected a header and 2 rows, got:\n%s", table.String())
+	}
+	if fields := strings.Fields(lines[2]); !reflect.DeepEqual(fields, []string{"sandbox", "no", "-", "no", "-", "-", "no"}) {
+		t.Errorf("Expected an unconfigured row, got %q", fields)
+	}
+	if strings.Index(lines[0], "K8S CONTEXT") != strings.Index(lines[1], "dev-cluster") {
+		t.Errorf("Expected aligned columns, got:\n%s", table.String())
+	}
+
+	var out bytes.Buffer
+	if err := writeProfilesJSON(&out, nil); err != nil || strings.TrimSpace(out.String()) != "[]" {
+		t.Errorf("Expected [] for no rows, got %q, %v", out.String(), err)
+	}
+	out.Reset()
+	if err := writeProfilesJSON(&out, rows); err != nil {
+		t.Fatal(err)
+	}
+	var decoded []map[string]any
+	if err := json.Unmarshal(out.Bytes(), &decoded); err != nil {
+		t.Fatalf("Expected valid JSON, got %v:\n%s", err, out.String())
+	}
+	if decoded[0]["account_id"] != "123456789012" || decoded[1]["configured"] != false {
+		t.Errorf("Unexpected JSON: %s", out.String())
+	}
+}
+
+func TestProfilesListWithoutKubeconfig(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("AWS_CONFIG_FILE", "")
+	t.Setenv("KUBECONFIG", filepath.Join(home, "missing"))
+	if err := os.MkdirAll(filepath.Join(home, ".aws"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".aws", "config"), []byte("[profile dev]\nregion = eu-west-1\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	old := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+	code := runProfilesList([]string{"--output", "json"})
+	w.Close()
+	os.Stdout = old
+	output, _ := io.ReadAll(r)
+
+	if code != 0 {
+		t.Fatalf("Expected exit code 0, got %d", code)
+	}
+	var rows []profileRow
+	if err := json.Unmarshal(output, &rows); err != nil || len(rows) != 1 || rows[0].Name != "dev" {
+		t.Errorf("Expected the dev profile, got %s (%v)", output, err)
+	}
+
+	if code := runProfilesList([]string{"--output", "yaml"}); code != 2 {
+		t.Errorf("Expected exit code 2 for an unknown format, got %d", code)
+	}
+}
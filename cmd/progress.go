@@ -0,0 +1,27 @@
+package main
+
+import (
+	"time"
+
+	"fancy-login/internal/metrics"
+	"fancy-login/internal/progress"
+)
+
+// loginPhases times each login phase for the metrics and reports it to the
+// progress sink as it starts and finishes
+type loginPhases struct {
+	run      *metrics.Run
+	progress progress.Sink
+}
+
+// start reports phase as started and returns its start time
+func (p *loginPhases) start(phase string) time.Time {
+	p.progress.Start(phase)
+	return time.Now()
+}
+
+// finish records phase in the metrics and reports its outcome
+func (p *loginPhases) finish(phase string, start time.Time, err error) {
+	p.run.Step(phase, start, err)
+	p.progress.Finish(phase, err)
+}
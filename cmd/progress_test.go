@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"fancy-login/internal/progress"
+)
+
+// installFakeLoginTools puts aws, docker and kubectl on PATH. aws reports a
+// valid session for any profile and kubectl applies use-context.
+func installFakeLoginTools(t *testing.T, kubeconfig string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake login tools require a POSIX shell")
+	}
+	binDir := t.TempDir()
+	tools := map[string]string{
+		"aws":    "#!/bin/sh\necho '{\"Account\": \"123456789012\"}'\n",
+		"docker": "#!/bin/sh\ncat > /dev/null\n",
+		"kubectl": "#!/bin/sh\n" +
+			"if [ \"$1 $2\" = \"config use-context\" ]; then\n" +
+			"  printf 'apiVersion: v1\\nkind: Config\\ncurrent-context: %s\\ncontexts:\\n- name: %s\\n  context: {cluster: dev}\\n' \"$3\" \"$3\" > " + kubeconfig + "\n" +
+			"fi\n",
+	}
+	for name, script := range tools {
+		if err := os.WriteFile(filepath.Join(binDir, name), []byte(script), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+// setupLoginFixture prepares a home with an SSO profile dev that logs in
+// to ECR and maps to dev-cluster, plus fake tools; settings are appended to
+// the settings section of the fancy config
+func setupLoginFixture(t *testing.T, settings string) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("AWS_CONFIG_FILE", "")
+	t.Setenv("FANCY_STATE_DIR", t.TempDir())
+	t.Setenv("DOCKER_CONFIG", t.TempDir())
+	kubeconfig := filepath.Join(home, "kubeconfig")
+	t.Setenv("KUBECONFIG", kubeconfig)
+	installFakeLoginTools(t, kubeconfig)
+
+	files := map[string]string{
+		filepath.Join(home, ".aws", "config"): "[profile dev]\nsso_start_url = https://example.awsapps.com/start\nregion = eu-central-1\n",
+		kubeconfig: "apiVersion: v1\nkind: Config\ncurrent-context: other\ncontexts:\n" +
+			"- name: dev-cluster\n  context: {cluster: dev}\n- name: other\n  context: {cluster: other}\n",
+		filepath.Join(home, ".fancy-config.yaml"): "profile_configs:\n" +
+			"  dev:\n    ecr_login: true\n    ecr_region: eu-central-1\n    k8s_context: dev-cluster\n" +
+			"settings:\n  config_wizard_run: true\n  ecr_login_mode: blocking\n  background_refresh: false\n" + settings,
+	}
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return home
+}
+
+// TestLoginProgressFile pins the phase sequence of a full login, which
+// wrappers rely on
+func TestLoginProgressFile(t *testing.T) {
+	home := t.TempDir()
+	setupLoginFixture(t, "  summary_sinks: [\"file:"+filepath.Join(home, "summary.log")+"\"]\n")
+
+	// CI containers often run tests as root
+	progressFile := filepath.Join(t.TempDir(), "progress.jsonl")
+	if code := runLoginCommand([]string{"--profile", "dev", "--progress-file", progressFile}); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d", code)
+	}
+
+	f, err := os.Open(progressFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var got [][2]string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var event progress.Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("Invalid progress line %q: %v", scanner.Text(), err)
+		}
+		if event.Time.IsZero() {
+			t.Errorf("Expected a timestamp in %q", scanner.Text())
+		}
+		got = append(got, [2]string{event.Phase, event.Status})
+	}
+
+	var expected [][2]string
+	for _, phase := range []string{
+		progress.PhaseSelectProfile,
+		progress.PhaseAWSLogin,
+		progress.PhaseKubeContext,
+		progress.PhaseAccountID,
+		progress.PhaseECRLogin,
+	} {
+		expected = append(expected, [2]string{phase, progress.StatusStarted}, [2]string{phase, progress.StatusSucceeded})
+	}
+	if len(got) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, got)
+	}
+	for i := range expected {
+		if got[i] != expected[i] {
+			t.Errorf("Event %d: expected %v, got %v", i, expected[i], got[i])
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"fancy-login/internal/a11y"
+	"fancy-login/internal/config"
+	"fancy-login/internal/platform"
+	"fancy-login/internal/prompt"
+	"fancy-login/internal/utils"
+)
+
+// rootGuard hands files written during a sudo run back to the invoking user
+type rootGuard struct {
+	fixer *platform.OwnershipFixer
+}
+
+// activeRootGuard is the guard dispatch got from checkRoot, for commands
+// that exit through Die and must fix ownership on the way out
+var activeRootGuard *rootGuard
+
+// checkRoot warns when fancy-login runs as root, since it would then read and
+// write root's configs instead of the user's. Under sudo it offers to use the
+// invoking user's configuration; otherwise it requires confirmation unless
+// allowRoot is set. It exits when the user declines and returns a guard whose
+// fixOwnership must run before exit.
+func checkRoot(allowRoot bool) *rootGuard {
+	if !platform.IsElevated() {
+		return nil
+	}
+
+	out := a11y.Writer(os.Stderr)
+	homeDir, _ := os.UserHomeDir()
+	fmt.Fprintf(out, "\n%s%s⚠️  fancy-login is running as root%s\n", config.Bold, config.Warning, config.Reset)
+	fmt.Fprintf(out, "%sConfiguration will be read and written under HOME=%s:%s\n", config.Warning, homeDir, config.Reset)
+	for _, path := range []string{config.GetFancyConfigPath(), config.GetKubeConfigPath(), filepath.Dir(config.GetAWSConfigPath())} {
+		fmt.Fprintf(out, "%s  %s%s\n", config.Muted, path, config.Reset)
+	}
+	fmt.Fprintf(out, "%sThis is rarely intended; run fancy-login as your own user instead.%s\n\n", config.Warning, config.Reset)
+
+	if allowRoot {
+		return nil
+	}
+
+	prompter, closeTTY, err := prompt.NewTTYPrompter(nil, nil)
+	if err != nil {
+		fmt.Fprintf(out, "%s❌ Refusing to run as root without a terminal to confirm; pass --allow-root to continue%s\n", config.Error, config.Reset)
+		os.Exit(utils.ExitFailure)
+	}
+	defer closeTTY()
+
+	sudoUser, err := platform.LookupSudoUser()
+	if err != nil {
+		fmt.Fprintf(out, "%s⚠️  %v%s\n", config.Warning, err, config.Reset)
+	}
+	if sudoUser != nil && prompter.Confirm(fmt.Sprintf("%sUse %s's configuration in %s instead?%s",
+		config.Accent, sudoUser.Name, sudoUser.Home, config.Reset), true) {
+		switchToSudoUserHome(sudoUser)
+		fmt.Fprintf(out, "%s🔹 Using HOME=%s; files written will be owned by %s%s\n", config.Accent, sudoUser.Home, sudoUser.Name, config.Reset)
+		return &rootGuard{fixer: platform.NewOwnershipFixer(sudoUser)}
+	}
+
+	if !prompter.Confirm(fmt.Sprintf("%sContinue as root anyway?%s", config.Accent, config.Reset), false) {
+		fmt.Fprintf(out, "%s❌ Aborted; pass --allow-root to skip this check%s\n", config.Error, config.Reset)
+		os.Exit(utils.ExitFailure)
+	}
+	return nil
+}
+
+// switchToSudoUserHome points every path lookup at the sudo user's home.
+// All of fancy-login, and the aws, kubectl and k9s children, derive their
+// paths from HOME.
+func switchToSudoUserHome(u *platform.SudoUser) {
+	os.Setenv("HOME", u.Home)
+}
+
+// ownershipPaths lists everything a run may write below the user's home,
+// plus the profile export file
+func ownershipPaths() []string {
+	homeDir, _ := os.UserHomeDir()
+	paths := []string{
+		config.GetFancyConfigPath(),
+		config.GetStateDir(),
+		filepath.Join(homeDir, ".kube", "cache"),
+		filepath.Join(homeDir, ".aws", "sso", "cache"),
+		filepath.Join(homeDir, ".aws", "cli", "cache"),
+		filepath.Join(homeDir, ".docker", "config.json"),
+		filepath.Join(homeDir, ".config", "k9s"),
+		filepath.Join(homeDir, ".local", "share", "k9s"),
+		filepath.Join(homeDir, ".local", "state", "k9s"),
+		config.NewConfig().AWSProfileTemp,
+	}
+	return append(paths, filepath.SplitList(config.GetKubeConfigPath())...)
+}
+
+// fixOwnership hands root-owned files back to the sudo user. It is safe to
+// call on a nil guard.
+func (g *rootGuard) fixOwnership() {
+	if g == nil {
+		return
+	}
+	if _, err := g.fixer.Fix(ownershipPaths()); err != nil {
+		fmt.Fprintf(a11y.Writer(os.Stderr), "%s⚠️  %v%s\n", config.Warning, err, config.Reset)
+	}
+}
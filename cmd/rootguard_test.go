@@ -0,0 +1,79 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"fancy-login/internal/config"
+	"fancy-login/internal/platform"
+)
+
+// isolatePaths clears overrides so every path derives from HOME
+func isolatePaths(t *testing.T, home string) {
+	t.Helper()
+	t.Setenv("HOME", home)
+	for _, key := range []string{"KUBECONFIG", "AWS_CONFIG_FILE", "FANCY_STATE_DIR", "FANCY_PROFILE_TEMP"} {
+		t.Setenv(key, "")
+	}
+}
+
+func TestSwitchToSudoUserHome(t *testing.T) {
+	rootHome := t.TempDir()
+	userHome := t.TempDir()
+	isolatePaths(t, rootHome)
+
+	switchToSudoUserHome(&platform.SudoUser{Name: "alice", Home: userHome, UID: 1000, GID: 1000})
+
+	testCases := []struct {
+		name     string
+		got      string
+		expected string
+	}{
+		{"Fancy config", config.GetFancyConfigPath(), filepath.Join(userHome, ".fancy-config.yaml")},
+		{"AWS config", config.GetAWSConfigPath(), filepath.Join(userHome, ".aws", "config")},
+		{"Kubeconfig", config.GetKubeConfigPath(), filepath.Join(userHome, ".kube", "config")},
+		{"State dir", config.GetStateDir(), filepath.Join(userHome, ".fancy-login")},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.got != tc.expected {
+				t.Errorf("Expected %s, got %s", tc.expected, tc.got)
+			}
+		})
+	}
+}
+
+func TestOwnershipPaths(t *testing.T) {
+	home := t.TempDir()
+	isolatePaths(t, home)
+	t.Setenv("KUBECONFIG", strings.Join([]string{
+		filepath.Join(home, ".kube", "config"),
+		filepath.Join(home, ".kube", "extra"),
+	}, string(os.PathListSeparator)))
+
+	paths := ownershipPaths()
+	for _, expected := range []string{
+		filepath.Join(home, ".fancy-login"),
+		filepath.Join(home, ".aws", "sso", "cache"),
+		filepath.Join(home, ".kube", "config"),
+		filepath.Join(home, ".kube", "extra"),
+	} {
+		found := false
+		for _, path := range paths {
+			if path == expected {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("Expected %s in ownership paths %v", expected, paths)
+		}
+	}
+}
+
+func TestRootGuardNilSafe(t *testing.T) {
+	var guard *rootGuard
+	guard.fixOwnership()
+}
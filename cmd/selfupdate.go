@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+
+	"fancy-login/internal/config"
+	"fancy-login/internal/selfupdate"
+	"fancy-login/internal/state"
+	"fancy-login/internal/utils"
+)
+
+// runSelfUpdateCommand handles `fancy-login-go self-update`, replacing the
+// running binary with the latest release, or with --check only reporting
+// whether there is one
+func runSelfUpdateCommand(args []string) int {
+	fs := flag.NewFlagSet("self-update", flag.ContinueOnError)
+	check := fs.Bool("check", false, "Only report whether a newer release exists")
+	if err := fs.Parse(args); err != nil {
+		return utils.ExitUsage
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return selfUpdate(ctx, selfupdate.NewClient(), *check)
+}
+
+// selfUpdate does the work of self-update against client
+func selfUpdate(ctx context.Context, client *selfupdate.Client, check bool) int {
+	release, err := client.Latest(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s❌ %v%s\n", config.Error, err, config.Reset)
+		return utils.ExitFailure
+	}
+	if err := state.Update(func(s *state.State) error {
+		s.UpdateCheck = &state.UpdateCheck{Latest: release.TagName, CheckedAt: time.Now()}
+		return nil
+	}); err != nil {
+		fmt.Fprintf(os.Stderr, "%s⚠️  Failed to cache the update check: %v%s\n", config.Warning, err, config.Reset)
+	}
+
+	if !selfupdate.IsRelease(version) {
+		fmt.Printf("Latest release is %s; this is a development build (%s), which self-update leaves alone\n", release.TagName, version)
+		return utils.ExitOK
+	}
+	if !selfupdate.Newer(version, release.TagName) {
+		fmt.Printf("%s✅ fancy-login-go %s is up to date%s\n", config.Success, version, config.Reset)
+		return utils.ExitOK
+	}
+	if check {
+		fmt.Printf("%s🔸 Update available: %s → %s; run fancy-login-go self-update%s\n", config.Accent, version, release.TagName, config.Reset)
+		return utils.ExitOK
+	}
+
+	exePath, err := os.Executable()
+	if err == nil {
+		exePath, err = filepath.EvalSymlinks(exePath)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s❌ Cannot locate the running binary: %v%s\n", config.Error, err, config.Reset)
+		return utils.ExitFailure
+	}
+	if manager := packageManager(exePath); manager != "" {
+		fmt.Fprintf(os.Stderr, "%s❌ %s is managed by %s; update it with %s instead%s\n", config.Error, exePath, manager, manager, config.Reset)
+		return utils.ExitFailure
+	}
+	selfupdate.CleanupOld(exePath)
+
+	fmt.Printf("Downloading %s...\n", selfupdate.AssetName(release.TagName, runtime.GOOS, runtime.GOARCH))
+	binary, err := client.Download(ctx, release, runtime.GOOS, runtime.GOARCH)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s❌ %v%s\n", config.Error, err, config.Reset)
+		return utils.ExitFailure
+	}
+	if err := selfupdate.Replace(exePath, binary, runtime.GOOS); err != nil {
+		fmt.Fprintf(os.Stderr, "%s❌ %v%s\n", config.Error, err, config.Reset)
+		return utils.ExitFailure
+	}
+	fmt.Printf("%s✅ Updated %s from %s to %s%s\n", config.Success, exePath, version, release.TagName, config.Reset)
+	return utils.ExitOK
+}
+
+// packageManager names the package manager that installed exePath, if any,
+// since replacing its files behind its back breaks its next upgrade
+func packageManager(exePath string) string {
+	slashed := filepath.ToSlash(exePath)
+	switch {
+	case strings.Contains(slashed, "/Cellar/"):
+		return "brew"
+	case strings.Contains(strings.ToLower(slashed), "/scoop/apps/"):
+		return "scoop"
+	}
+	return ""
+}
+
+// cachedUpdateNotice returns a line announcing a newer release if a check
+// from the last day found one, without asking GitHub
+func cachedUpdateNotice(now time.Time) string {
+	s, err := state.Load()
+	if err != nil || s.UpdateCheck == nil || now.Sub(s.UpdateCheck.CheckedAt) > selfupdate.CheckTTL {
+		return ""
+	}
+	if !selfupdate.Newer(version, s.UpdateCheck.Latest) {
+		return ""
+	}
+	return fmt.Sprintf("A newer version %s is available; run fancy-login-go self-update", s.UpdateCheck.Latest)
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"fancy-login/internal/state"
+)
+
+func TestCachedUpdateNotice(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	oldVersion := version
+	version = "v1.0.0"
+	t.Cleanup(func() { version = oldVersion })
+
+	testCases := []struct {
+		name     string
+		check    *state.UpdateCheck
+		expected string
+	}{
+		{"No check", nil, ""},
+		{"Newer release", &state.UpdateCheck{Latest: "v1.1.0", CheckedAt: now.Add(-time.Hour)}, "v1.1.0 is available"},
+		{"Up to date", &state.UpdateCheck{Latest: "v1.0.0", CheckedAt: now.Add(-time.Hour)}, ""},
+		{"Stale check", &state.UpdateCheck{Latest: "v1.1.0", CheckedAt: now.Add(-25 * time.Hour)}, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("FANCY_STATE_DIR", t.TempDir())
+			if err := state.Update(func(s *state.State) error {
+				s.UpdateCheck = tc.check
+				return nil
+			}); err != nil {
+				t.Fatal(err)
+			}
+			got := cachedUpdateNotice(now)
+			if (tc.expected == "") != (got == "") || !strings.Contains(got, tc.expected) {
+				t.Errorf("Expected notice containing %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+
+	"fancy-login/internal/config"
+	"fancy-login/internal/utils"
+)
+
+// runConfigSet handles `fancy-login-go config set [--profile NAME] KEY
+// VALUE`, setting one key after checking it against the settings schema,
+// or with --profile the profile schema. An empty VALUE unsets the key.
+func runConfigSet(args []string) int {
+	fs := flag.NewFlagSet("config set", flag.ContinueOnError)
+	profileName := fs.String("profile", "", "Profile to set the key of instead of a setting")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: fancy-login-go config set [--profile NAME] KEY VALUE")
+		fmt.Fprintln(fs.Output(), "\nSet a setting, or a profile key with --profile; run `config schema` for the keys.")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return utils.ExitUsage
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return utils.ExitUsage
+	}
+	key, value := fs.Arg(0), fs.Arg(1)
+
+	snapshot := config.NewSnapshot(nil)
+	fancyConfig, err := snapshot.FancyConfig()
+	if err != nil {
+		fmt.Printf("%s❌ %v%s\n", config.Error, err, config.Reset)
+		return utils.ExitConfig
+	}
+
+	target := "settings." + key
+	if *profileName == "" {
+		err = config.SetSetting(&fancyConfig.Settings, key, value)
+	} else {
+		name := fancyConfig.ResolveProfileName(*profileName)
+		target = fmt.Sprintf("profile_configs.%s.%s", name, key)
+		err = setProfileKey(snapshot, fancyConfig, name, key, value)
+	}
+	if err != nil {
+		fmt.Printf("%s❌ %v%s\n", config.Error, err, config.Reset)
+		return utils.ExitConfig
+	}
+
+	if err := fancyConfig.SaveFancyConfig(); err != nil {
+		fmt.Printf("%s❌ Failed to save configuration: %v%s\n", config.Error, err, config.Reset)
+		return utils.ExitFailure
+	}
+	fmt.Printf("%s✅ Set %s in %s%s\n", config.Success, target, config.GetFancyConfigPath(), config.Reset)
+	return utils.ExitOK
+}
+
+// setProfileKey sets key of the profile's configuration, adding the
+// configuration for a profile that is only in the AWS config
+func setProfileKey(snapshot *config.Snapshot, fc *config.FancyConfig, name, key, value string) error {
+	if fc.IsKubeOnlyProfile(name) {
+		return fmt.Errorf("%s is a kube-only profile; change it with `config edit`", name)
+	}
+	pc, configured := fc.ProfileConfigs[name]
+	if !configured {
+		if _, ok := snapshot.AWSProfile(name); !ok {
+			return fmt.Errorf("profile %s is neither configured nor in %s", name, config.GetAWSConfigPath())
+		}
+	}
+	if err := config.SetProfileField(&pc, key, value); err != nil {
+		return err
+	}
+	fc.ProfileConfigs[name] = pc
+	return nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"fancy-login/internal/config"
+	"fancy-login/internal/utils"
+)
+
+func TestConfigSet(t *testing.T) {
+	const fancyConfig = `profile_configs:
+  dev:
+    account_id: "111111111111"
+    aliases: [d]
+kube_only_profiles:
+  oidc:
+    k8s_context: dev-cluster
+settings:
+  default_region: eu-central-1
+`
+	testCases := []struct {
+		name         string
+		args         []string
+		expectedCode int
+		// profile is empty for a setting
+		profile       string
+		key           string
+		expectedValue string
+	}{
+		{"Setting", []string{"set", "theme", "mono"}, utils.ExitOK, "", "theme", "mono"},
+		{"Integer setting", []string{"set", "aws_timeout", "120"}, utils.ExitOK, "", "aws_timeout", "120"},
+		{"Unset setting", []string{"set", "default_region", ""}, utils.ExitOK, "", "default_region", ""},
+		{"Invalid setting value", []string{"set", "ecr_login_mode", "eager"}, utils.ExitConfig, "", "ecr_login_mode", ""},
+		{"Invalid integer", []string{"set", "aws_timeout", "5m"}, utils.ExitConfig, "", "aws_timeout", ""},
+		{"Unknown setting", []string{"set", "colour", "red"}, utils.ExitConfig, "", "", ""},
+		{"Profile key", []string{"set", "--profile", "dev", "ecr_region", "eu-west-1"}, utils.ExitOK, "dev", "ecr_region", "eu-west-1"},
+		{"Profile key by alias", []string{"set", "--profile", "d", "ecr_login", "true"}, utils.ExitOK, "dev", "ecr_login", "true"},
+		{"Profile only in the AWS config", []string{"set", "--profile", "staging", "namespace", "apps"}, utils.ExitOK, "staging", "namespace", "apps"},
+		{"Invalid profile value", []string{"set", "--profile", "dev", "account_id", "1234"}, utils.ExitConfig, "dev", "account_id", "111111111111"},
+		{"Unknown profile key", []string{"set", "--profile", "dev", "colour", "red"}, utils.ExitConfig, "", "", ""},
+		{"Unknown profile", []string{"set", "--profile", "prod", "namespace", "apps"}, utils.ExitConfig, "", "", ""},
+		{"Kube-only profile", []string{"set", "--profile", "oidc", "namespace", "apps"}, utils.ExitConfig, "", "", ""},
+		{"Missing value", []string{"set", "theme"}, utils.ExitUsage, "", "", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			setupValidateFixture(t, fancyConfig)
+			path := filepath.Join(os.Getenv("HOME"), ".fancy-config.yaml")
+
+			old, oldErr := os.Stdout, os.Stderr
+			r, w, _ := os.Pipe()
+			os.Stdout, os.Stderr = w, w
+			output := make(chan []byte)
+			go func() {
+				data, _ := io.ReadAll(r)
+				output <- data
+			}()
+			code := runConfigCommand(tc.args)
+			w.Close()
+			os.Stdout, os.Stderr = old, oldErr
+			text := string(<-output)
+
+			if code != tc.expectedCode {
+				t.Fatalf("Expected exit code %d, got %d: %s", tc.expectedCode, code, text)
+			}
+			if code != utils.ExitOK {
+				data, err := os.ReadFile(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !bytes.Equal(data, []byte(fancyConfig)) {
+					t.Errorf("Expected the config to be left alone, got:\n%s", data)
+				}
+			}
+			if tc.key == "" {
+				return
+			}
+
+			fc, err := config.LoadFancyConfig()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := config.SettingValue(&fc.Settings, tc.key)
+			if tc.profile != "" {
+				pc := fc.ProfileConfigs[tc.profile]
+				if got, err = config.GetProfileField(&pc, tc.key); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got != tc.expectedValue {
+				t.Errorf("Expected %s to be %q, got %q", tc.key, tc.expectedValue, got)
+			}
+		})
+	}
+}
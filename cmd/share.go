@@ -0,0 +1,300 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"fancy-login/internal/a11y"
+	"fancy-login/internal/config"
+	"fancy-login/internal/prompt"
+	"fancy-login/internal/utils"
+)
+
+// sharedConfig is the file `config export` writes and `config import`
+// reads: profile entries without the fields that belong to one person
+type sharedConfig struct {
+	ProfileConfigs map[string]config.ProfileConfig `yaml:"profile_configs"`
+}
+
+// Answers to an import conflict, in the order they are offered
+const (
+	conflictKeepMine = iota
+	conflictTakeTheirs
+	conflictSkip
+)
+
+// runConfigExport handles `fancy-login-go config export`, writing the
+// chosen profiles (all by default) to a file that teammates can import
+func runConfigExport(args []string) int {
+	fs := flag.NewFlagSet("config export", flag.ContinueOnError)
+	profiles := fs.String("profiles", "", "Comma-separated profiles to export (default: all)")
+	output := fs.String("o", "", "File to write (default: stdout)")
+	if err := fs.Parse(args); err != nil {
+		return utils.ExitUsage
+	}
+
+	fancyConfig, err := config.LoadFancyConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s❌ %v%s\n", config.Error, err, config.Reset)
+		return utils.ExitConfig
+	}
+	var names []string
+	for _, name := range strings.Split(*profiles, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	shared, err := exportProfiles(fancyConfig, names)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s❌ %v%s\n", config.Error, err, config.Reset)
+		return utils.ExitConfig
+	}
+
+	data, err := yaml.Marshal(shared)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to encode profiles: %v\n", err)
+		return utils.ExitFailure
+	}
+	if *output == "" || *output == "-" {
+		fmt.Print(string(data))
+		return utils.ExitOK
+	}
+	if err := os.WriteFile(*output, data, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "%s❌ Failed to write %s: %v%s\n", config.Error, *output, err, config.Reset)
+		return utils.ExitFailure
+	}
+	fmt.Printf("%s✅ Exported %d profiles to %s%s\n", config.Success, len(shared.ProfileConfigs), *output, config.Reset)
+	return utils.ExitOK
+}
+
+// exportProfiles copies the named profiles, or all of them if names is
+// empty, leaving out the git identity and kubeconfig path since they are
+// the exporter's own
+func exportProfiles(fc *config.FancyConfig, names []string) (*sharedConfig, error) {
+	if len(names) == 0 {
+		for name := range fc.ProfileConfigs {
+			names = append(names, name)
+		}
+	}
+	shared := &sharedConfig{ProfileConfigs: make(map[string]config.ProfileConfig)}
+	for _, name := range names {
+		pc, exists := fc.ProfileConfigs[name]
+		if !exists {
+			return nil, fmt.Errorf("profile %s is not configured", name)
+		}
+		pc.GitUserName, pc.GitUserEmail = "", ""
+		pc.Kubeconfig = ""
+		shared.ProfileConfigs[name] = pc
+	}
+	return shared, nil
+}
+
+// runConfigImport handles `fancy-login-go config import FILE`, merging the
+// exported profiles into the config. Entries whose k8s_context isn't in
+// the kubeconfig are refused unless --allow-missing-context is passed.
+func runConfigImport(args []string) int {
+	fs := flag.NewFlagSet("config import", flag.ContinueOnError)
+	allowMissing := fs.Bool("allow-missing-context", false, "Import entries whose k8s_context isn't in the kubeconfig")
+	if err := fs.Parse(args); err != nil {
+		return utils.ExitUsage
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "Usage: fancy-login-go config import [--allow-missing-context] FILE")
+		return utils.ExitUsage
+	}
+
+	data, err := os.ReadFile(fs.Arg(0))
+	if err != nil {
+		fmt.Printf("%s❌ %v%s\n", config.Error, err, config.Reset)
+		return utils.ExitFailure
+	}
+	shared, err := parseSharedConfig(data)
+	if err != nil {
+		fmt.Printf("%s❌ %s: %v%s\n", config.Error, fs.Arg(0), err, config.Reset)
+		return utils.ExitConfig
+	}
+
+	snapshot := config.NewSnapshot(nil)
+	fancyConfig, err := snapshot.FancyConfig()
+	if err != nil {
+		fmt.Printf("%s❌ %v%s\n", config.Error, err, config.Reset)
+		return utils.ExitConfig
+	}
+	contexts, err := snapshot.KubeContexts()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		fmt.Printf("%s❌ %v%s\n", config.Error, err, config.Reset)
+		return utils.ExitFailure
+	}
+
+	out := a11y.Writer(os.Stdout)
+	// Without a terminal conflicts can't be asked about, so they are skipped
+	prompter, closeTTY, err := prompt.NewTTYPrompter(fancyConfig.Settings.AffirmativeAnswers, fancyConfig.Settings.NegativeAnswers)
+	if err != nil {
+		prompter, closeTTY = prompt.NewAutoPrompter(out, false), func() {}
+	}
+	changed, refused := mergeSharedConfig(out, prompter, fancyConfig, shared, contexts, *allowMissing)
+	closeTTY()
+
+	if changed {
+		if _, err := os.Stat(config.GetFancyConfigPath()); err == nil {
+			backup, err := config.BackupFancyConfig(time.Now())
+			if err != nil {
+				fmt.Printf("%s❌ Failed to back up the configuration, nothing was changed: %v%s\n", config.Error, err, config.Reset)
+				return utils.ExitFailure
+			}
+			fmt.Fprintf(out, "%s💾 Backed up the current configuration to %s%s\n", config.Success, backup, config.Reset)
+		}
+		if err := fancyConfig.SaveFancyConfig(); err != nil {
+			fmt.Printf("%s❌ Failed to save configuration: %v%s\n", config.Error, err, config.Reset)
+			return utils.ExitFailure
+		}
+		fmt.Fprintf(out, "%s✅ Saved %s%s\n", config.Success, config.GetFancyConfigPath(), config.Reset)
+	}
+	if refused > 0 {
+		return utils.ExitConfig
+	}
+	return utils.ExitOK
+}
+
+// parseSharedConfig reads an exported file, rejecting keys that aren't in
+// the profile schema and values that fail its checks
+func parseSharedConfig(data []byte) (*sharedConfig, error) {
+	var shared sharedConfig
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&shared); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if len(shared.ProfileConfigs) == 0 {
+		return nil, errors.New("no profile_configs to import")
+	}
+
+	var problems []string
+	for _, name := range sortedProfileNames(shared.ProfileConfigs) {
+		pc := shared.ProfileConfigs[name]
+		for _, err := range config.ValidateProfileConfig(&pc) {
+			problems = append(problems, fmt.Sprintf("profile_configs.%s: %v", name, err))
+		}
+	}
+	if len(problems) > 0 {
+		return nil, errors.New(strings.Join(problems, "; "))
+	}
+	return &shared, nil
+}
+
+// mergeSharedConfig adds the shared profiles to fc, asking whether to keep
+// mine, take theirs or skip where an existing entry differs. Existing
+// entries keep their git identity, and their kubeconfig unless the shared
+// entry names one, either way. It reports whether fc changed
+// and how many entries were refused.
+func mergeSharedConfig(out io.Writer, p *prompt.Prompter, fc *config.FancyConfig, shared *sharedConfig, contexts []config.KubernetesContext, allowMissing bool) (changed bool, refused int) {
+	known := make(map[string]bool)
+	for _, ctx := range contexts {
+		known[ctx.Name] = true
+	}
+	if fc.ProfileConfigs == nil {
+		fc.ProfileConfigs = make(map[string]config.ProfileConfig)
+	}
+
+	var added, replaced, kept int
+	for _, name := range sortedProfileNames(shared.ProfileConfigs) {
+		theirs := shared.ProfileConfigs[name]
+		if fc.IsKubeOnlyProfile(name) {
+			fmt.Fprintf(out, "%s❌ %s: already configured as a kube-only profile%s\n", config.Error, name, config.Reset)
+			refused++
+			continue
+		}
+		if problem := importedContextProblem(name, theirs, known); problem != "" && !allowMissing {
+			fmt.Fprintf(out, "%s❌ %s: %s (pass --allow-missing-context to import it anyway)%s\n",
+				config.Error, name, problem, config.Reset)
+			refused++
+			continue
+		}
+
+		mine, exists := fc.ProfileConfigs[name]
+		if !exists {
+			fc.ProfileConfigs[name] = theirs
+			fmt.Fprintf(out, "%s✅ %s: added%s\n", config.Success, name, config.Reset)
+			added++
+			continue
+		}
+		theirs.GitUserName, theirs.GitUserEmail = mine.GitUserName, mine.GitUserEmail
+		if theirs.Kubeconfig == "" {
+			theirs.Kubeconfig = mine.Kubeconfig
+		}
+		if reflect.DeepEqual(mine, theirs) {
+			kept++
+			continue
+		}
+
+		fmt.Fprintf(out, "%s⚠️  %s differs from your configuration:%s\n", config.Warning, name, config.Reset)
+		for _, field := range config.ProfileSchema {
+			before, _ := config.GetProfileField(&mine, field.Key)
+			after, _ := config.GetProfileField(&theirs, field.Key)
+			if before != after {
+				fmt.Fprintf(out, "  %-16s %s → %s\n", field.Key, orNone(before), orNone(after))
+			}
+		}
+		index, ok := p.Choose("Resolve "+name, []string{"Keep mine", "Take theirs", "Skip"})
+		switch {
+		case ok && index == conflictTakeTheirs:
+			fc.ProfileConfigs[name] = theirs
+			replaced++
+		case ok && index == conflictKeepMine:
+			kept++
+		default:
+			fmt.Fprintf(out, "%sSkipped %s%s\n", config.Muted, name, config.Reset)
+		}
+	}
+
+	fmt.Fprintf(out, "%d added, %d replaced, %d kept, %d refused\n", added, replaced, kept, refused)
+	return added+replaced > 0, refused
+}
+
+// importedContextProblem describes why the k8s_context of a shared profile
+// can't be found, or returns "" if it is there. known are the contexts of
+// the default kubeconfig; an entry with its own kubeconfig is checked
+// against that file.
+func importedContextProblem(name string, theirs config.ProfileConfig, known map[string]bool) string {
+	if theirs.K8sContext == "" {
+		return ""
+	}
+	candidate := &config.FancyConfig{ProfileConfigs: map[string]config.ProfileConfig{name: theirs}}
+	path := candidate.KubeconfigForProfile(name)
+	if path == "" {
+		if known[theirs.K8sContext] {
+			return ""
+		}
+		return fmt.Sprintf("k8s_context %s is not in %s", theirs.K8sContext, config.GetKubeConfigPath())
+	}
+	contexts, err := config.ParseKubernetesContexts(path)
+	if err != nil {
+		return fmt.Sprintf("k8s_context %s cannot be checked: %v", theirs.K8sContext, err)
+	}
+	for _, c := range contexts {
+		if c.Name == theirs.K8sContext {
+			return ""
+		}
+	}
+	return fmt.Sprintf("k8s_context %s is not in %s", theirs.K8sContext, path)
+}
+
+// sortedProfileNames returns the keys of profiles in order
+func sortedProfileNames(profiles map[string]config.ProfileConfig) []string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
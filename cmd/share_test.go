@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"fancy-login/internal/config"
+	"fancy-login/internal/prompt"
+	"fancy-login/internal/utils"
+)
+
+func TestExportProfiles(t *testing.T) {
+	fc := config.DefaultFancyConfig()
+	fc.ProfileConfigs = map[string]config.ProfileConfig{
+		"dev":  {Name: "Dev", K8sContext: "dev-cluster", Kubeconfig: "~/.kube/dev", GitUserName: "Jo", GitUserEmail: "jo@example.com"},
+		"prod": {Name: "Prod"},
+	}
+
+	shared, err := exportProfiles(fc, []string{"dev"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dev, ok := shared.ProfileConfigs["dev"]
+	if len(shared.ProfileConfigs) != 1 || !ok {
+		t.Fatalf("Expected only dev, got %+v", shared.ProfileConfigs)
+	}
+	if dev.GitUserName != "" || dev.GitUserEmail != "" || dev.Kubeconfig != "" || dev.K8sContext != "dev-cluster" {
+		t.Errorf("Expected dev without its git identity and kubeconfig, got %+v", dev)
+	}
+	if fc.ProfileConfigs["dev"].GitUserName != "Jo" {
+		t.Error("Expected the config itself to keep the git identity")
+	}
+
+	if all, err := exportProfiles(fc, nil); err != nil || len(all.ProfileConfigs) != 2 {
+		t.Errorf("Expected all profiles, got %+v, %v", all, err)
+	}
+	if _, err := exportProfiles(fc, []string{"missing"}); err == nil {
+		t.Error("Expected an error for an unconfigured profile")
+	}
+}
+
+func TestParseSharedConfig(t *testing.T) {
+	testCases := []struct {
+		name          string
+		data          string
+		expectedError string
+	}{
+		{"Valid", "profile_configs:\n  dev:\n    ecr_region: eu-west-1\n", ""},
+		{"Unknown key", "profile_configs:\n  dev:\n    ecr_regoin: eu-west-1\n", "field ecr_regoin not found"},
+		{"Invalid value", "profile_configs:\n  dev:\n    account_id: \"12\"\n", "profile_configs.dev: account_id"},
+		{"Empty", "", "no profile_configs"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := parseSharedConfig([]byte(tc.data))
+			if tc.expectedError == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.expectedError) {
+				t.Errorf("Expected error containing %q, got %v", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestMergeSharedConfig(t *testing.T) {
+	t.Setenv("KUBECONFIG", filepath.Join(t.TempDir(), "kubeconfig"))
+	newFancyConfig := func() *config.FancyConfig {
+		fc := config.DefaultFancyConfig()
+		fc.ProfileConfigs = map[string]config.ProfileConfig{
+			"dev":     {Name: "Dev", K8sContext: "dev-cluster", Kubeconfig: "~/.kube/dev", GitUserEmail: "me@example.com"},
+			"staging": {Name: "Staging"},
+		}
+		fc.KubeOnlyProfiles = map[string]config.KubeProfileConfig{"oidc": {K8sContext: "oidc-cluster"}}
+		return fc
+	}
+	shared := &sharedConfig{ProfileConfigs: map[string]config.ProfileConfig{
+		"dev":     {Name: "Development", K8sContext: "dev-cluster"},
+		"prod":    {Name: "Prod", K8sContext: "prod-cluster"},
+		"qa":      {Name: "QA"},
+		"staging": {Name: "Staging"},
+		"oidc":    {Name: "OIDC"},
+	}}
+	contexts := []config.KubernetesContext{{Name: "dev-cluster"}}
+
+	testCases := []struct {
+		name            string
+		answer          string
+		allowMissing    bool
+		expectedDevName string
+		expectedProd    bool
+		expectedRefused int
+	}{
+		{"Keep mine", "1\n", false, "Dev", false, 2},
+		{"Take theirs", "2\n", false, "Development", false, 2},
+		{"Skip", "3\n", false, "Dev", false, 2},
+		{"Allow missing context", "\n", true, "Dev", true, 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fc := newFancyConfig()
+			p := prompt.NewPrompter(bufio.NewReader(strings.NewReader(tc.answer)), io.Discard, nil, nil)
+			changed, refused := mergeSharedConfig(io.Discard, p, fc, shared, contexts, tc.allowMissing)
+
+			if !changed {
+				t.Error("Expected qa to be added")
+			}
+			if refused != tc.expectedRefused {
+				t.Errorf("Expected %d refused, got %d", tc.expectedRefused, refused)
+			}
+			dev := fc.ProfileConfigs["dev"]
+			if dev.Name != tc.expectedDevName || dev.GitUserEmail != "me@example.com" || dev.Kubeconfig != "~/.kube/dev" {
+				t.Errorf("Expected dev named %s with my git identity and kubeconfig, got %+v", tc.expectedDevName, dev)
+			}
+			if _, ok := fc.ProfileConfigs["prod"]; ok != tc.expectedProd {
+				t.Errorf("Expected prod imported to be %v", tc.expectedProd)
+			}
+			if _, ok := fc.ProfileConfigs["oidc"]; ok {
+				t.Error("Expected the kube-only profile not to be shadowed")
+			}
+		})
+	}
+}
+
+func TestImportedContextProblem(t *testing.T) {
+	t.Setenv("KUBECONFIG", filepath.Join(t.TempDir(), "kubeconfig"))
+	own := filepath.Join(t.TempDir(), "team-kubeconfig")
+	if err := os.WriteFile(own, []byte("contexts:\n- name: team-cluster\n  context:\n    cluster: team\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	known := map[string]bool{"dev-cluster": true}
+
+	testCases := []struct {
+		name     string
+		theirs   config.ProfileConfig
+		expected string
+	}{
+		{"No context", config.ProfileConfig{}, ""},
+		{"Context in the default kubeconfig", config.ProfileConfig{K8sContext: "dev-cluster"}, ""},
+		{"Context missing from the default kubeconfig", config.ProfileConfig{K8sContext: "team-cluster"}, "k8s_context team-cluster is not in "},
+		{"Context in its own kubeconfig", config.ProfileConfig{K8sContext: "team-cluster", Kubeconfig: own}, ""},
+		{"Context missing from its own kubeconfig", config.ProfileConfig{K8sContext: "dev-cluster", Kubeconfig: own}, "k8s_context dev-cluster is not in " + own},
+		{"Own kubeconfig missing", config.ProfileConfig{K8sContext: "dev-cluster", Kubeconfig: own + ".missing"}, "cannot be checked"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := importedContextProblem("dev", tc.theirs, known)
+			if tc.expected == "" && got != "" || !strings.Contains(got, tc.expected) {
+				t.Errorf("Expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestConfigImportRefusesInvalidFile(t *testing.T) {
+	setupValidateFixture(t, "profile_configs: {}\n")
+	path := filepath.Join(t.TempDir(), "team.yaml")
+	if code := runConfigCommand([]string{"import", path}); code != utils.ExitFailure {
+		t.Errorf("Expected exit code %d for a missing file, got %d", utils.ExitFailure, code)
+	}
+	if code := runConfigCommand([]string{"import"}); code != utils.ExitUsage {
+		t.Errorf("Expected exit code %d without a file, got %d", utils.ExitUsage, code)
+	}
+}
@@ -0,0 +1,310 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+
+	"fancy-login/internal/a11y"
+	"fancy-login/internal/aws"
+	"fancy-login/internal/config"
+	"fancy-login/internal/utils"
+)
+
+// Sources of shown values that aren't a file or environment variable
+const (
+	sourceDefault = "default"
+	sourceLocal   = "current directory"
+)
+
+// shownValue is one resolved value and where it came from: "default", "env
+// NAME" or the config file's path
+type shownValue struct {
+	Key    string `json:"key" yaml:"key"`
+	Value  string `json:"value" yaml:"value"`
+	Source string `json:"source" yaml:"source"`
+}
+
+// shownProfile is one profile's resolved settings
+type shownProfile struct {
+	Name     string       `json:"name" yaml:"name"`
+	KubeOnly bool         `json:"kube_only,omitempty" yaml:"kube_only,omitempty"`
+	Fields   []shownValue `json:"fields" yaml:"fields"`
+	// ECRRegionChain lists the places the ECR region is looked up in, in
+	// order; ECRRegion is the first that is set
+	ECRRegionChain []shownValue `json:"ecr_region_chain,omitempty" yaml:"ecr_region_chain,omitempty"`
+	ECRRegion      *shownValue  `json:"ecr_region,omitempty" yaml:"ecr_region,omitempty"`
+}
+
+// shownConfig is everything `config show` prints
+type shownConfig struct {
+	Files       []shownValue   `json:"files,omitempty" yaml:"files,omitempty"`
+	Environment []shownValue   `json:"environment,omitempty" yaml:"environment,omitempty"`
+	Settings    []shownValue   `json:"settings,omitempty" yaml:"settings,omitempty"`
+	Profiles    []shownProfile `json:"profiles,omitempty" yaml:"profiles,omitempty"`
+}
+
+// rawFancyConfig holds the keys actually present in the config file, to
+// tell set values from defaults
+type rawFancyConfig struct {
+	ProfileConfigs   map[string]map[string]interface{} `yaml:"profile_configs"`
+	KubeOnlyProfiles map[string]map[string]interface{} `yaml:"kube_only_profiles"`
+	Settings         map[string]interface{}            `yaml:"settings"`
+}
+
+// runConfigShow handles `fancy-login-go config show`, printing the values
+// in effect and where each came from. --profile shows one profile with its
+// ECR region lookup instead.
+func runConfigShow(args []string) int {
+	fs := flag.NewFlagSet("config show", flag.ContinueOnError)
+	profile := fs.String("profile", "", "Show only this profile's resolved settings")
+	output := fs.String("output", "text", "Output format: text, yaml or json")
+	if err := fs.Parse(args); err != nil {
+		return utils.ExitUsage
+	}
+	if *output != "text" && *output != "yaml" && *output != "json" {
+		fmt.Fprintf(os.Stderr, "invalid --output %q: must be text, yaml or json\n", *output)
+		return utils.ExitUsage
+	}
+
+	fancyConfig, err := config.LoadFancyConfig()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s❌ %v%s\n", config.Error, err, config.Reset)
+		return utils.ExitConfig
+	}
+	shown, err := resolveShownConfig(config.NewConfig(), fancyConfig, fancyConfig.ResolveProfileName(*profile))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s❌ %v%s\n", config.Error, err, config.Reset)
+		return utils.ExitConfig
+	}
+
+	switch *output {
+	case "json":
+		data, err := json.MarshalIndent(shown, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to encode configuration: %v\n", err)
+			return utils.ExitFailure
+		}
+		fmt.Println(string(data))
+	case "yaml":
+		data, err := yaml.Marshal(shown)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to encode configuration: %v\n", err)
+			return utils.ExitFailure
+		}
+		fmt.Print(string(data))
+	default:
+		printShownConfig(a11y.Writer(os.Stdout), shown)
+	}
+	return utils.ExitOK
+}
+
+// resolveShownConfig collects the effective configuration. With profile
+// set, only that profile is resolved.
+func resolveShownConfig(cfg *config.Config, fc *config.FancyConfig, profile string) (*shownConfig, error) {
+	configPath := config.GetFancyConfigPath()
+	var raw rawFancyConfig
+	if data, err := os.ReadFile(configPath); err == nil {
+		if err := yaml.Unmarshal(data, &raw); err != nil {
+			return nil, fmt.Errorf("failed to parse config file %s: %w", configPath, err)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	if profile != "" {
+		p, err := resolveShownProfile(cfg, fc, raw, configPath, profile)
+		if err != nil {
+			return nil, err
+		}
+		return &shownConfig{Profiles: []shownProfile{*p}}, nil
+	}
+
+	shown := &shownConfig{}
+	fancySource := sourceDefault
+	if cwd, err := os.Getwd(); err == nil && filepath.Dir(configPath) == cwd {
+		fancySource = sourceLocal
+	}
+	shown.Files = []shownValue{
+		{Key: "fancy_config", Value: configPath, Source: fancySource},
+		envValue("aws_config", config.GetAWSConfigPath(), "AWS_CONFIG_FILE"),
+		envValue("kubeconfig", config.GetKubeConfigPath(), "KUBECONFIG"),
+		envValue("state_dir", config.GetStateDir(), "FANCY_STATE_DIR"),
+	}
+	shown.Environment = []shownValue{
+		envValue("default_region", cfg.DefaultRegion, "FANCY_DEFAULT_REGION"),
+		envValue("profile_temp", cfg.AWSProfileTemp, "FANCY_PROFILE_TEMP"),
+		envValue("bin_dir", cfg.BinDir, "FANCY_BIN_DIR"),
+		envValue("aws_dir", cfg.AWSDir, "FANCY_AWS_DIR"),
+		envValue("kube_dir", cfg.KubeDir, "FANCY_KUBE_DIR"),
+		envValue("verbose", fmt.Sprint(cfg.FancyVerbose), "FANCY_VERBOSE"),
+		envValue("debug", fmt.Sprint(cfg.FancyDebug), "FANCY_DEBUG"),
+	}
+
+	for _, field := range config.SettingsSchema {
+		value := shownValue{Key: field.Key, Value: field.Default, Source: sourceDefault}
+		if _, set := raw.Settings[field.Key]; set {
+			value.Value, value.Source = config.SettingValue(&fc.Settings, field.Key), configPath
+		}
+		switch {
+		case value.Source != sourceDefault:
+		case field.Key == "theme" && os.Getenv("NO_COLOR") != "":
+			value.Value, value.Source = "mono", "env NO_COLOR"
+		case field.Key == "screen_reader_mode" && a11y.Requested(false):
+			value.Value, value.Source = "true", "env ACCESSIBLE"
+		}
+		shown.Settings = append(shown.Settings, value)
+	}
+
+	var names []string
+	for name := range fc.ProfileConfigs {
+		names = append(names, name)
+	}
+	for name := range fc.KubeOnlyProfiles {
+		if !containsString(names, name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		p, err := resolveShownProfile(cfg, fc, raw, configPath, name)
+		if err != nil {
+			return nil, err
+		}
+		shown.Profiles = append(shown.Profiles, *p)
+	}
+	return shown, nil
+}
+
+// resolveShownProfile collects one profile's values: those set in the file
+// and the schema defaults of the rest. AWS profiles also get their ECR
+// region lookup, in the order ECRRegion tries it.
+func resolveShownProfile(cfg *config.Config, fc *config.FancyConfig, raw rawFancyConfig, configPath, name string) (*shownProfile, error) {
+	schema, rawFields := config.ProfileSchema, raw.ProfileConfigs[name]
+	kubeOnly := fc.IsKubeOnlyProfile(name)
+	if kubeOnly {
+		schema, rawFields = config.KubeProfileSchema, raw.KubeOnlyProfiles[name]
+	} else if _, exists := fc.ProfileConfigs[name]; !exists {
+		return nil, fmt.Errorf("profile %s is not configured", name)
+	}
+
+	p := &shownProfile{Name: name, KubeOnly: kubeOnly}
+	for _, field := range schema {
+		value := shownValue{Key: field.Key, Value: field.Default, Source: sourceDefault}
+		if v, set := rawFields[field.Key]; set {
+			value.Value, value.Source = fmt.Sprint(v), configPath
+		}
+		if field.Key == "k9s_readonly" && value.Source == sourceDefault && fc.ShouldK9sReadonly(name) {
+			value.Value, value.Source = "true", "environment prod"
+		}
+		if value.Value != "" {
+			p.Fields = append(p.Fields, value)
+		}
+	}
+	if kubeOnly {
+		return p, nil
+	}
+
+	defaultRegion := shownValue{Key: "settings.default_region", Value: fc.Settings.DefaultRegion, Source: sourceDefault}
+	if _, set := raw.Settings["default_region"]; set {
+		defaultRegion.Source = configPath
+	}
+	ecrRegion := shownValue{Key: "ecr_region", Value: fc.ProfileConfigs[name].ECRRegion, Source: sourceDefault}
+	if ecrRegion.Value != "" {
+		ecrRegion.Source = configPath
+	}
+	snapshot := config.NewSnapshot(nil)
+	profileRegion := shownValue{Key: "region", Source: sourceDefault}
+	if awsProfile, ok := snapshot.AWSProfile(name); ok && awsProfile.Region != "" {
+		profileRegion.Value, profileRegion.Source = awsProfile.Region, config.GetAWSConfigPath()
+	}
+	p.ECRRegionChain = []shownValue{
+		ecrRegion,
+		profileRegion,
+		envValue("AWS_REGION", os.Getenv("AWS_REGION"), "AWS_REGION"),
+		defaultRegion,
+		envValue("FANCY_DEFAULT_REGION", cfg.DefaultRegion, "FANCY_DEFAULT_REGION"),
+	}
+
+	manager := aws.NewAWSManager(cfg, utils.NewLogger(false), fc)
+	manager.SetSnapshot(snapshot)
+	region, _ := manager.ECRRegion(name)
+	for i := range p.ECRRegionChain {
+		if p.ECRRegionChain[i].Value == region {
+			p.ECRRegion = &p.ECRRegionChain[i]
+			break
+		}
+	}
+	return p, nil
+}
+
+// envValue describes a value that the environment variable env overrides
+func envValue(key, value, env string) shownValue {
+	if os.Getenv(env) != "" {
+		return shownValue{Key: key, Value: value, Source: "env " + env}
+	}
+	return shownValue{Key: key, Value: value, Source: sourceDefault}
+}
+
+// containsString reports whether list contains s
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// printShownConfig prints the resolved configuration as aligned sections,
+// with the source of each value muted after it
+func printShownConfig(w io.Writer, shown *shownConfig) {
+	section := func(title string, values []shownValue, indent string) {
+		if len(values) == 0 {
+			return
+		}
+		if title != "" {
+			fmt.Fprintf(w, "%s%s%s%s:%s\n", indent, config.Heading, config.Bold, title, config.Reset)
+		}
+		for _, v := range values {
+			fmt.Fprintf(w, "%s  %-24s %s %s(%s)%s\n", indent, v.Key, orNone(v.Value), config.Muted, v.Source, config.Reset)
+		}
+	}
+
+	section("Files", shown.Files, "")
+	if len(shown.Environment) > 0 {
+		fmt.Fprintln(w)
+	}
+	section("Environment", shown.Environment, "")
+	if len(shown.Settings) > 0 {
+		fmt.Fprintln(w)
+	}
+	section("Settings", shown.Settings, "")
+
+	for _, p := range shown.Profiles {
+		kind := "Profile"
+		if p.KubeOnly {
+			kind = "Kube-only profile"
+		}
+		fmt.Fprintf(w, "\n%s%s%s %s:%s\n", config.Heading, config.Bold, kind, p.Name, config.Reset)
+		section("", p.Fields, "")
+		if len(p.ECRRegionChain) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "  ECR region lookup, first set value wins:\n")
+		for _, v := range p.ECRRegionChain {
+			marker := " "
+			if p.ECRRegion != nil && p.ECRRegion.Key == v.Key {
+				marker = "→"
+			}
+			fmt.Fprintf(w, "   %s %-23s %s %s(%s)%s\n", marker, v.Key, orNone(v.Value), config.Muted, v.Source, config.Reset)
+		}
+	}
+}
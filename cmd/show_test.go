@@ -0,0 +1,96 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"fancy-login/internal/config"
+	"fancy-login/internal/utils"
+)
+
+func TestResolveShownConfig(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("FANCY_DEFAULT_REGION", "ap-south-1")
+	t.Setenv("AWS_REGION", "")
+	t.Setenv("NO_COLOR", "1")
+	configPath := filepath.Join(home, ".fancy-config.yaml")
+	content := `profile_configs:
+  dev:
+    account_id: "123456789012"
+    ecr_region: us-east-1
+  staging:
+    k8s_context: staging-cluster
+settings:
+  ecr_login_mode: lazy
+kube_only_profiles:
+  oidc:
+    k8s_context: oidc-cluster
+`
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	fancyConfig, err := config.LoadFancyConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	shown, err := resolveShownConfig(config.NewConfig(), fancyConfig, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	find := func(values []shownValue, key string) shownValue {
+		for _, v := range values {
+			if v.Key == key {
+				return v
+			}
+		}
+		t.Fatalf("Expected %s in %+v", key, values)
+		return shownValue{}
+	}
+	testCases := []struct {
+		name     string
+		got      shownValue
+		expected shownValue
+	}{
+		{"Environment override", find(shown.Environment, "default_region"), shownValue{"default_region", "ap-south-1", "env FANCY_DEFAULT_REGION"}},
+		{"Set in file", find(shown.Settings, "ecr_login_mode"), shownValue{"ecr_login_mode", "lazy", configPath}},
+		{"Schema default", find(shown.Settings, "kubectl_timeout"), shownValue{"kubectl_timeout", "30", "default"}},
+		{"NO_COLOR theme", find(shown.Settings, "theme"), shownValue{"theme", "mono", "env NO_COLOR"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.got != tc.expected {
+				t.Errorf("Expected %+v, got %+v", tc.expected, tc.got)
+			}
+		})
+	}
+	if len(shown.Profiles) != 3 || shown.Profiles[1].Name != "oidc" || !shown.Profiles[1].KubeOnly {
+		t.Errorf("Expected dev, oidc (kube-only) and staging, got %+v", shown.Profiles)
+	}
+
+	// staging has no ecr_region and no default_region, so FANCY_DEFAULT_REGION wins
+	for profile, expected := range map[string]shownValue{
+		"dev":     {"ecr_region", "us-east-1", configPath},
+		"staging": {"FANCY_DEFAULT_REGION", "ap-south-1", "env FANCY_DEFAULT_REGION"},
+	} {
+		one, err := resolveShownConfig(config.NewConfig(), fancyConfig, profile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(one.Profiles) != 1 || one.Settings != nil {
+			t.Fatalf("Expected only the %s profile, got %+v", profile, one)
+		}
+		if got := one.Profiles[0].ECRRegion; got == nil || *got != expected {
+			t.Errorf("Expected %s to resolve the ECR region from %+v, got %+v", profile, expected, got)
+		}
+	}
+
+	if _, err := resolveShownConfig(config.NewConfig(), fancyConfig, "missing"); err == nil {
+		t.Error("Expected an error for an unconfigured profile")
+	}
+	if code := runConfigShow([]string{"--output", "toml"}); code != utils.ExitUsage {
+		t.Errorf("Expected exit code %d for an unknown format, got %d", utils.ExitUsage, code)
+	}
+}
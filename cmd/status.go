@@ -0,0 +1,401 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"fancy-login/internal/aws"
+	"fancy-login/internal/config"
+	"fancy-login/internal/k8s"
+	"fancy-login/internal/state"
+	"fancy-login/internal/utils"
+)
+
+// runStatusCommand handles `fancy-login-go status`, showing the session the
+// terminal points at, then the session status of every AWS profile from
+// cached state or, with --refresh, live, followed by any k9s sessions still
+// running. With --output json only the current session is printed.
+func runStatusCommand(args []string) int {
+	fs := flag.NewFlagSet("status", flag.ContinueOnError)
+	refresh := fs.Bool("refresh", false, "Re-validate every profile with STS instead of showing cached data")
+	concurrency := fs.Int("concurrency", 4, "Maximum number of concurrent STS checks with --refresh")
+	timeout := fs.Int("timeout", 15, "Seconds before a single profile check is cancelled")
+	output := fs.String("output", "text", "Output format: text or json")
+	if err := fs.Parse(args); err != nil {
+		return utils.ExitUsage
+	}
+	if *output != "text" && *output != "json" {
+		fmt.Fprintf(os.Stderr, "invalid --output %q: must be text or json\n", *output)
+		return utils.ExitUsage
+	}
+
+	// Every part of the report sees the same parsed configs
+	snapshot := config.NewSnapshot(nil)
+	report, err := currentSessionReport(snapshot)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s⚠️  %v%s\n", config.Warning, err, config.Reset)
+	}
+	if *output == "json" {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s❌ %v%s\n", config.Error, err, config.Reset)
+			return utils.ExitFailure
+		}
+		fmt.Println(string(data))
+		return utils.ExitOK
+	}
+	fmt.Print(renderSessionReport(report, time.Now()))
+
+	profiles, err := snapshot.AWSProfiles()
+	if err != nil {
+		fmt.Printf("%s❌ %v%s\n", config.Error, err, config.Reset)
+		return utils.ExitFailure
+	}
+
+	records, oldest, err := sessionRecords(snapshot, *refresh, profiles, *concurrency, time.Duration(*timeout)*time.Second)
+	if err != nil {
+		fmt.Printf("%s⚠️  %v%s\n", config.Warning, err, config.Reset)
+	}
+
+	now := time.Now()
+	switch {
+	case *refresh:
+		fmt.Printf("%s🔐 AWS session status%s (live)\n", config.Bold, config.Reset)
+	case oldest.IsZero():
+		fmt.Printf("%s🔐 AWS session status%s (no cached data; use --refresh to check)\n", config.Bold, config.Reset)
+	default:
+		fmt.Printf("%s🔐 AWS session status%s (cached, data from %s; use --refresh to re-validate)\n",
+			config.Bold, config.Reset, aws.FormatAge(oldest, now))
+	}
+
+	width := 0
+	for _, p := range profiles {
+		if len(p.Name) > width {
+			width = len(p.Name)
+		}
+	}
+	for _, p := range profiles {
+		record := records[p.Name]
+		if record == nil {
+			record = &state.SessionRecord{Status: string(aws.StatusUnknown)}
+		}
+		fmt.Printf("  %-*s  %s%-12s%s %-12s %s%s%s\n", width, p.Name,
+			statusColor(record.Status), record.Status, config.Reset, record.Account,
+			config.Muted, aws.FormatAge(record.CheckedAt, now), config.Reset)
+	}
+
+	printK9sSessions(now)
+	return utils.ExitOK
+}
+
+// runWhoamiCommand handles `fancy-login-go whoami`, showing the caller
+// identity of a profile live from STS or, with --cached, from cached state.
+// It exits with utils.ExitSessionExpired when the session needs a new login.
+func runWhoamiCommand(args []string) int {
+	fs := flag.NewFlagSet("whoami", flag.ContinueOnError)
+	profileName := fs.String("profile", "", "AWS profile to show (defaults to AWS_PROFILE, AWS_DEFAULT_PROFILE, then the exported profile)")
+	cached := fs.Bool("cached", false, "Show cached data instead of calling STS")
+	fs.Bool("refresh", true, "Call STS (the default; kept for compatibility)")
+	timeout := fs.Int("timeout", 15, "Seconds before the check is cancelled")
+	verboseOutput := fs.Bool("v", false, "Enable verbose output")
+	if err := fs.Parse(args); err != nil {
+		return utils.ExitUsage
+	}
+
+	snapshot := config.NewSnapshot(nil)
+	fancyConfig, err := snapshot.FancyConfig()
+	if err != nil {
+		fmt.Printf("%s❌ %v%s\n", config.Error, err, config.Reset)
+		return utils.ExitConfig
+	}
+	cfg := config.NewConfig()
+	cfg.FancyVerbose = *verboseOutput
+	awsManager := aws.NewAWSManager(cfg, utils.NewLogger(cfg.FancyVerbose), fancyConfig)
+	awsManager.SetSnapshot(snapshot)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	name, _, err := awsManager.ResolveProfile(ctx, aws.ProfileRequest{
+		Flag:     *profileName,
+		UseEnv:   true,
+		Exported: true,
+		NoExport: true,
+	})
+	if err != nil {
+		fmt.Printf("%s❌ %v%s\n", config.Error, err, config.Reset)
+		return utils.ExitCode(err, utils.ExitUsage)
+	}
+
+	profile := config.AWSProfile{Name: name}
+	if p, ok := snapshot.AWSProfile(name); ok {
+		profile = p
+	}
+
+	var record *state.SessionRecord
+	identity := aws.CallerIdentity{}
+	if *cached {
+		records, _, err := sessionRecords(snapshot, false, []config.AWSProfile{profile}, 1, 0)
+		if err != nil {
+			fmt.Printf("%s⚠️  %v%s\n", config.Warning, err, config.Reset)
+		}
+		record = records[profile.Name]
+		if record == nil {
+			fmt.Printf("%s: %s (no cached data; run without --cached to check)\n", profile.Name, aws.StatusUnknown)
+			return utils.ExitFailure
+		}
+		identity = aws.CallerIdentity{Account: record.Account, Arn: record.Arn}
+	} else {
+		checks := newSessionChecker(snapshot, 1, time.Duration(*timeout)*time.Second).Check(ctx, []config.AWSProfile{profile})
+		if err := aws.RecordSessionChecks(checks); err != nil {
+			fmt.Printf("%s⚠️  failed to update cached state: %v%s\n", config.Warning, err, config.Reset)
+		}
+		checked := checks[0].Record()
+		record = &checked
+		identity = checks[0].Identity
+	}
+
+	source := "live"
+	if *cached {
+		source = "cached, " + aws.FormatAge(record.CheckedAt, time.Now())
+	}
+	fmt.Printf("%sProfile:%s %s\n", config.Bold, config.Reset, profile.Name)
+	fmt.Printf("%sStatus:%s  %s%s%s %s(%s)%s\n", config.Bold, config.Reset,
+		statusColor(record.Status), record.Status, config.Reset, config.Muted, source, config.Reset)
+	if identity.Account != "" {
+		fmt.Printf("%sAccount:%s %s\n", config.Bold, config.Reset, identity.Account)
+	}
+	if identity.Arn != "" {
+		fmt.Printf("%sARN:%s     %s\n", config.Bold, config.Reset, identity.Arn)
+	}
+	if identity.UserID != "" {
+		fmt.Printf("%sUserId:%s  %s\n", config.Bold, config.Reset, identity.UserID)
+	}
+	if profile.SSORole != "" {
+		fmt.Printf("%sRole:%s    %s\n", config.Bold, config.Reset, profile.SSORole)
+	}
+	if record.Error != "" {
+		fmt.Printf("%sError:%s   %s\n", config.Bold, config.Reset, record.Error)
+	}
+
+	switch status := aws.SessionStatus(record.Status); {
+	case status == aws.StatusValid:
+		return utils.ExitOK
+	case status.NeedsLogin():
+		return utils.ExitSessionExpired
+	}
+	return utils.ExitFailure
+}
+
+// sessionReport is the session a terminal points at, as status prints it
+type sessionReport struct {
+	Profile             string     `json:"profile"`
+	EnvProfile          string     `json:"env_profile,omitempty"`
+	ExportedProfile     string     `json:"exported_profile,omitempty"`
+	KubeOnly            bool       `json:"kube_only,omitempty"`
+	SessionValid        bool       `json:"session_valid"`
+	AccountID           string     `json:"account_id,omitempty"`
+	Arn                 string     `json:"arn,omitempty"`
+	ExpiresAt           *time.Time `json:"expires_at,omitempty"`
+	K8sContext          string     `json:"k8s_context,omitempty"`
+	Namespace           string     `json:"namespace,omitempty"`
+	ECRRegistry         string     `json:"ecr_registry,omitempty"`
+	DockerAuthenticated bool       `json:"docker_authenticated"`
+}
+
+// currentSessionReport gathers the current profile, its session and ECR
+// login and the kubectl context without prompting. Whatever could be read
+// is returned along with the first error.
+func currentSessionReport(snapshot *config.Snapshot) (*sessionReport, error) {
+	report := &sessionReport{}
+	var firstErr error
+	keep := func(err error) {
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	report.K8sContext, report.Namespace = currentKubeContext()
+
+	fancyConfig, err := snapshot.FancyConfig()
+	if err != nil {
+		return report, err
+	}
+	awsManager := aws.NewAWSManager(config.NewConfig(), utils.NewLogger(false), fancyConfig)
+	awsManager.SetSnapshot(snapshot)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	session, err := awsManager.CheckCurrentSession(ctx)
+	keep(err)
+	if session == nil {
+		return report, firstErr
+	}
+
+	report.Profile = session.Profile
+	report.EnvProfile = session.EnvProfile
+	report.ExportedProfile = session.FileProfile
+	report.KubeOnly = session.KubeOnly
+	report.SessionValid = session.Valid
+	report.AccountID = session.Identity.Account
+	report.Arn = session.Identity.Arn
+	if !session.ExpiresAt.IsZero() {
+		report.ExpiresAt = &session.ExpiresAt
+	}
+	if session.Registry != nil {
+		report.ECRRegistry = session.Registry.Host()
+		report.DockerAuthenticated = session.DockerAuthenticated
+	}
+	return report, firstErr
+}
+
+// currentKubeContext returns kubectl's current context and its namespace,
+// read from the kubeconfig rather than by running kubectl
+func currentKubeContext() (string, string) {
+	current, err := config.ReadCurrentContext("")
+	if err != nil || current == "" {
+		return "", ""
+	}
+	namespace := "default"
+	if contexts, err := config.ParseKubernetesContexts(config.GetKubeConfigPath()); err == nil {
+		for _, c := range contexts {
+			if c.Name == current && c.Namespace != "" {
+				namespace = c.Namespace
+			}
+		}
+	}
+	return current, namespace
+}
+
+// renderSessionReport renders the current session in the style of the
+// login summary box
+func renderSessionReport(r *sessionReport, now time.Time) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s🦄  %sCurrent Session%s\n", config.Heading, config.Bold, config.Reset)
+	fmt.Fprintf(&b, "%s───────────────────────────────────────────────%s\n", config.Muted, config.Reset)
+	switch {
+	case r.Profile == "":
+		fmt.Fprintf(&b, "%s🔑 AWS Profile: none selected%s\n", config.Muted, config.Reset)
+	case r.KubeOnly:
+		fmt.Fprintf(&b, "%s⎈  Kube-only Profile:%s %s%s%s\n", config.Heading, config.Reset, config.Bold, r.Profile, config.Reset)
+	default:
+		fmt.Fprintf(&b, "%s🔑 AWS Profile:%s %s%s%s\n", config.Heading, config.Reset, config.Bold, r.Profile, config.Reset)
+	}
+	if r.EnvProfile != "" && r.ExportedProfile != "" && r.EnvProfile != r.ExportedProfile {
+		fmt.Fprintf(&b, "%s   AWS_PROFILE is %s, new shells get %s%s\n", config.Warning, r.EnvProfile, r.ExportedProfile, config.Reset)
+	}
+	if r.Profile != "" && !r.KubeOnly {
+		if r.SessionValid {
+			fmt.Fprintf(&b, "%s✅ Session: valid%s", config.Success, config.Reset)
+		} else {
+			fmt.Fprintf(&b, "%s⚠️  Session: not valid%s", config.Warning, config.Reset)
+		}
+		if r.ExpiresAt != nil {
+			fmt.Fprintf(&b, " %s(%s)%s", config.Muted, aws.FormatRemaining(*r.ExpiresAt, now), config.Reset)
+		}
+		b.WriteString("\n")
+	}
+	if r.K8sContext != "" {
+		b.WriteString(k8s.ContextSummaryLine(r.K8sContext, r.Namespace) + "\n")
+	} else {
+		fmt.Fprintf(&b, "%s🌱 Kubernetes Context: none%s\n", config.Muted, config.Reset)
+	}
+	if r.ECRRegistry != "" {
+		if r.DockerAuthenticated {
+			fmt.Fprintf(&b, "%s🐳 ECR: docker is logged in to %s%s\n", config.Success, r.ECRRegistry, config.Reset)
+		} else {
+			fmt.Fprintf(&b, "%s🐳 ECR: docker is not logged in to %s%s\n", config.Warning, r.ECRRegistry, config.Reset)
+		}
+	}
+	if r.AccountID != "" {
+		fmt.Fprintf(&b, "%s☁️  AWS Account ID:%s %s%s%s\n", config.Accent, config.Reset, config.Bold, r.AccountID, config.Reset)
+	}
+	fmt.Fprintf(&b, "%s───────────────────────────────────────────────%s\n", config.Muted, config.Reset)
+	b.WriteString("\n")
+	return b.String()
+}
+
+// newSessionChecker creates a session checker honoring the credential
+// backends of the fancy-login config, if it can be loaded
+func newSessionChecker(snapshot *config.Snapshot, concurrency int, timeout time.Duration) *aws.SessionChecker {
+	checker := aws.NewSessionChecker(concurrency, timeout)
+	if fancyConfig, err := snapshot.FancyConfig(); err == nil {
+		checker.SetBackends(fancyConfig)
+	}
+	return checker
+}
+
+// sessionRecords returns session records keyed by profile, either live
+// (updating the cache) or from the state file, plus the oldest check time
+func sessionRecords(snapshot *config.Snapshot, refresh bool, profiles []config.AWSProfile, concurrency int, timeout time.Duration) (map[string]*state.SessionRecord, time.Time, error) {
+	records := make(map[string]*state.SessionRecord)
+	var oldest time.Time
+
+	if refresh {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		checks := newSessionChecker(snapshot, concurrency, timeout).Check(ctx, profiles)
+		for _, check := range checks {
+			record := check.Record()
+			records[check.Profile] = &record
+		}
+		if err := aws.RecordSessionChecks(checks); err != nil {
+			return records, time.Now(), fmt.Errorf("failed to update cached state: %w", err)
+		}
+		return records, time.Now(), nil
+	}
+
+	st, err := state.Load()
+	if err != nil {
+		return records, oldest, err
+	}
+	for _, p := range profiles {
+		if record := st.Sessions[p.Name]; record != nil {
+			records[p.Name] = record
+			if oldest.IsZero() || record.CheckedAt.Before(oldest) {
+				oldest = record.CheckedAt
+			}
+		}
+	}
+	return records, oldest, nil
+}
+
+// printK9sSessions lists the k9s sessions fancy-login launched that are still running
+func printK9sSessions(now time.Time) {
+	sessions, err := k8s.ActiveK9sSessions()
+	if err != nil {
+		fmt.Printf("%s⚠️  %v%s\n", config.Warning, err, config.Reset)
+	}
+	if len(sessions) == 0 {
+		return
+	}
+
+	fmt.Printf("\n%s⎈ Active k9s sessions%s\n", config.Bold, config.Reset)
+	for _, session := range sessions {
+		target := session.Namespace
+		if session.Context != "" {
+			target = session.Context + "/" + session.Namespace
+		}
+		fmt.Printf("  pid %-7d %-20s %s %s(started %s)%s\n", session.PID, session.Profile, target,
+			config.Muted, aws.FormatAge(session.StartedAt, now), config.Reset)
+	}
+}
+
+// statusColor returns the theme color for a session status
+func statusColor(status string) string {
+	switch aws.SessionStatus(status) {
+	case aws.StatusValid:
+		return config.Success
+	case aws.StatusExpired, aws.StatusNoSession, aws.StatusUnknown:
+		return config.Warning
+	}
+	return config.Error
+}
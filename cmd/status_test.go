@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"fancy-login/internal/a11y"
+)
+
+func TestStatusCurrentSessionJSON(t *testing.T) {
+	setupLogoutFixture(t, "dev")
+	t.Setenv("AWS_PROFILE", "")
+	binDir := t.TempDir()
+	identity := `{"Account": "123456789012", "Arn": "arn:aws:sts::123456789012:assumed-role/Dev/me"}`
+	if err := os.WriteFile(filepath.Join(binDir, "aws"), []byte("#!/bin/sh\necho '"+identity+"'\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	dockerConfig := `{"auths": {"123456789012.dkr.ecr.eu-central-1.amazonaws.com": {}}}`
+	if err := os.WriteFile(filepath.Join(os.Getenv("DOCKER_CONFIG"), "config.json"), []byte(dockerConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	old := os.Stdout
+	r, w, _ := os.Pipe()
+	os.Stdout = w
+	code := runStatusCommand([]string{"--output", "json"})
+	w.Close()
+	os.Stdout = old
+	data, _ := io.ReadAll(r)
+
+	if code != 0 {
+		t.Fatalf("Expected exit code 0, got %d", code)
+	}
+	var report sessionReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		t.Fatalf("Expected a JSON object, got %q: %v", data, err)
+	}
+	expected := sessionReport{
+		Profile:             "dev",
+		ExportedProfile:     "dev",
+		SessionValid:        true,
+		AccountID:           "123456789012",
+		Arn:                 "arn:aws:sts::123456789012:assumed-role/Dev/me",
+		K8sContext:          "other",
+		Namespace:           "default",
+		ECRRegistry:         "123456789012.dkr.ecr.eu-central-1.amazonaws.com",
+		DockerAuthenticated: true,
+	}
+	if report != expected {
+		t.Errorf("Expected %+v, got %+v", expected, report)
+	}
+}
+
+func TestRenderSessionReport(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	expiresAt := now.Add(90 * time.Minute)
+
+	testCases := []struct {
+		name     string
+		report   sessionReport
+		expected []string
+	}{
+		{"No profile", sessionReport{}, []string{"AWS Profile: none selected", "Kubernetes Context: none"}},
+		{"Valid session", sessionReport{
+			Profile: "dev", SessionValid: true, ExpiresAt: &expiresAt,
+			K8sContext: "dev-cluster", Namespace: "apps",
+			ECRRegistry: "1.dkr.ecr.eu-central-1.amazonaws.com",
+		}, []string{"Session: valid", "(1h30m left)", "dev-cluster", "ns: apps", "docker is not logged in"}},
+		{"Stale shell", sessionReport{Profile: "dev", EnvProfile: "dev", ExportedProfile: "prod"},
+			[]string{"Session: not valid", "AWS_PROFILE is dev, new shells get prod"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			text := renderSessionReport(&tc.report, now)
+			for _, want := range tc.expected {
+				if !strings.Contains(text, want) {
+					t.Errorf("Expected %q in %q", want, text)
+				}
+			}
+		})
+	}
+}
+
+func TestWhoami(t *testing.T) {
+	testCases := []struct {
+		name     string
+		aws      string
+		code     int
+		expected []string
+	}{
+		{"Valid session", "echo '{\"Account\": \"123456789012\", \"Arn\": \"arn:aws:sts::123456789012:assumed-role/Dev/me\", \"UserId\": \"AROAEXAMPLE:me\"}'",
+			0, []string{"Account: 123456789012", "ARN:     arn:aws:sts::123456789012:assumed-role/Dev/me", "UserId:  AROAEXAMPLE:me", "Role:    Developer"}},
+		{"Expired session", "echo 'Error when retrieving token from sso: Token has expired' >&2; exit 255",
+			4, []string{"Status:  no session"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			setupLogoutFixture(t, "dev")
+			t.Setenv("AWS_PROFILE", "")
+			awsConfig := "[profile dev]\nsso_start_url = https://example.awsapps.com/start\nsso_role_name = Developer\nregion = eu-central-1\n"
+			if err := os.WriteFile(filepath.Join(os.Getenv("HOME"), ".aws", "config"), []byte(awsConfig), 0600); err != nil {
+				t.Fatal(err)
+			}
+			binDir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(binDir, "aws"), []byte("#!/bin/sh\n"+tc.aws+"\n"), 0755); err != nil {
+				t.Fatal(err)
+			}
+			t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+			old := os.Stdout
+			r, w, _ := os.Pipe()
+			os.Stdout = w
+			code := runWhoamiCommand(nil)
+			w.Close()
+			os.Stdout = old
+			data, _ := io.ReadAll(r)
+			text := a11y.Plain(string(data))
+
+			if code != tc.code {
+				t.Errorf("Expected exit code %d, got %d", tc.code, code)
+			}
+			for _, want := range tc.expected {
+				if !strings.Contains(text, want) {
+					t.Errorf("Expected %q in %q", want, text)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"fancy-login/internal/aws"
+	"fancy-login/internal/config"
+	"fancy-login/internal/state"
+	"fancy-login/internal/utils"
+)
+
+// runSwitchCommand handles `fancy-login-go switch [PREFIX]`, logging in to
+// the previous profile like `cd -`, or to the most recent one starting with
+// PREFIX, without the picker. Other login flags such as -k still apply.
+func runSwitchCommand(args []string) int {
+	opts, err := parseLoginArgs(args)
+	if err != nil {
+		return utils.ExitUsage
+	}
+	if opts.profile != "" || opts.last || opts.reuseEnv {
+		fmt.Fprintln(os.Stderr, "switch picks the profile from the recent logins; --profile, --last and --reuse-env don't apply")
+		return utils.ExitUsage
+	}
+
+	// An alias stands for its profile; anything else is a prefix
+	fancyConfig, configErr := config.LoadFancyConfig()
+	query := opts.query
+	if configErr == nil && query != "" {
+		query = fancyConfig.ResolveProfileName(query)
+	}
+
+	from, to, err := resolveSwitch(aws.RecentLogins(), query)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s❌ %v%s\n", config.Error, err, config.Reset)
+		return utils.ExitConfig
+	}
+	opts.profile, opts.query = to.Profile, ""
+	opts.switchedFrom, opts.switchContext = from, to.Context
+
+	// An unconfigured profile without a remembered context would bring up
+	// the context picker, which switch never shows
+	if configErr == nil && to.Context == "" {
+		if _, err := fancyConfig.GetProfileConfig(to.Profile); err != nil {
+			opts.noK8s = true
+		}
+	}
+	return runLogin(opts)
+}
+
+// resolveSwitch picks the target of a switch from recent, most recent
+// first. Without a prefix it is the login before the last one; with one,
+// an exact name wins over the most recent profile starting with it, and
+// the current profile is only matched if nothing else does.
+func resolveSwitch(recent []state.LastLogin, prefix string) (from string, to state.LastLogin, err error) {
+	if len(recent) == 0 {
+		return "", state.LastLogin{}, errors.New("no logins recorded yet; log in to two profiles to switch between them")
+	}
+	from = recent[0].Profile
+	if prefix == "" {
+		if len(recent) < 2 {
+			return "", state.LastLogin{}, fmt.Errorf("%s is the only profile logged in to so far; nothing to switch to", from)
+		}
+		return from, recent[1], nil
+	}
+
+	candidates := append(append([]state.LastLogin{}, recent[1:]...), recent[0])
+	for _, login := range candidates {
+		if login.Profile == prefix {
+			return from, login, nil
+		}
+	}
+	for _, login := range candidates {
+		if strings.HasPrefix(login.Profile, prefix) {
+			return from, login, nil
+		}
+	}
+	var names []string
+	for _, login := range recent {
+		names = append(names, login.Profile)
+	}
+	return "", state.LastLogin{}, fmt.Errorf("no recent profile starts with %q; recent profiles: %s", prefix, strings.Join(names, ", "))
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"fancy-login/internal/aws"
+	"fancy-login/internal/state"
+)
+
+func TestResolveSwitch(t *testing.T) {
+	recent := []state.LastLogin{{Profile: "prod"}, {Profile: "dev", Context: "dev-cluster"}, {Profile: "dev-admin"}, {Profile: "staging"}}
+
+	testCases := []struct {
+		name          string
+		recent        []state.LastLogin
+		prefix        string
+		expected      string
+		expectedError string
+	}{
+		{"Previous profile", recent, "", "dev", ""},
+		{"Exact name", recent, "dev", "dev", ""},
+		{"Most recent prefix match", recent, "dev-", "dev-admin", ""},
+		{"Prefix", recent, "st", "staging", ""},
+		{"Current profile last", recent, "pro", "prod", ""},
+		{"No match", recent, "qa", "", `no recent profile starts with "qa"`},
+		{"Nothing recorded", nil, "", "", "no logins recorded yet"},
+		{"Single login", recent[:1], "", "", "nothing to switch to"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			from, to, err := resolveSwitch(tc.recent, tc.prefix)
+			if tc.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedError) {
+					t.Errorf("Expected error containing %q, got %v", tc.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if from != "prod" || to.Profile != tc.expected {
+				t.Errorf("Expected prod → %s, got %s → %s", tc.expected, from, to.Profile)
+			}
+		})
+	}
+}
+
+// TestSwitch checks that switch logs in to the previous profile without the
+// picker and says so in the summary
+func TestSwitch(t *testing.T) {
+	home := t.TempDir()
+	summaryLog := filepath.Join(home, "summary.log")
+	setupLoginFixture(t, "  summary_sinks: [\"file:"+summaryLog+"\"]\n")
+
+	if code := runLoginCommand([]string{"--profile", "dev"}); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d", code)
+	}
+	if err := aws.RecordLastLogin("prod", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without fzf on PATH a picker would fail the login
+	if code := runSwitchCommand(nil); code != 0 {
+		t.Fatalf("Expected switch to log in to dev, got exit code %d", code)
+	}
+	data, err := os.ReadFile(summaryLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), "switched: prod -> dev\n") {
+		t.Errorf("Expected the swap in the summary, got %q", data)
+	}
+
+	recent := aws.RecentLogins()
+	if len(recent) != 2 || recent[0].Profile != "dev" || recent[1].Profile != "prod" {
+		t.Errorf("Expected dev then prod in the recent logins, got %+v", recent)
+	}
+	if code := runSwitchCommand([]string{"--profile", "dev"}); code != 2 {
+		t.Errorf("Expected exit code 2 for --profile, got %d", code)
+	}
+}
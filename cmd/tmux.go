@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"fancy-login/internal/state"
+	"fancy-login/internal/tmux"
+	"fancy-login/internal/utils"
+)
+
+// runTmuxStatus handles `fancy-login-go tmux-status`, printing a status-right
+// fragment from the pane options set after login. Use it in tmux.conf as
+//
+//	set -g status-right '#(fancy-login-go tmux-status --pane #{pane_id})'
+func runTmuxStatus(args []string) int {
+	fs := flag.NewFlagSet("tmux-status", flag.ContinueOnError)
+	pane := fs.String("pane", os.Getenv("TMUX_PANE"), "tmux pane to read (defaults to the current pane)")
+	if err := fs.Parse(args); err != nil {
+		return utils.ExitUsage
+	}
+
+	fragment, err := tmux.StatusFragment(tmux.DefaultRunner, *pane, ecrMarker)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return utils.ExitFailure
+	}
+	if fragment != "" {
+		fmt.Println(fragment)
+	}
+	return utils.ExitOK
+}
+
+// ecrMarker shows a pending or failed ECR login of a profile, as recorded
+// by the login flow; a successful login needs no marker
+func ecrMarker(profile string) string {
+	st, err := state.Load()
+	if err != nil || st.ECRLogins[profile] == nil {
+		return ""
+	}
+	switch st.ECRLogins[profile].Status {
+	case state.ECRPending:
+		return "🐳…"
+	case state.ECRFailed:
+		return "🐳✗"
+	}
+	return ""
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"fancy-login/internal/config"
+	"fancy-login/internal/prompt"
+	"fancy-login/internal/uninstall"
+	"fancy-login/internal/utils"
+)
+
+// runUninstall handles `fancy-login-go uninstall`
+func runUninstall(args []string) int {
+	fs := flag.NewFlagSet("uninstall", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "Only list what would be removed")
+	yes := fs.Bool("yes", false, "Remove every target without asking")
+	binary := fs.Bool("binary", false, "Also remove the fancy-login-go binary from the bin directory")
+	skip := fs.String("skip", "", "Comma-separated target names to keep")
+	if err := fs.Parse(args); err != nil {
+		return utils.ExitUsage
+	}
+
+	skipped := make(map[string]bool)
+	for _, name := range strings.Split(*skip, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			skipped[name] = true
+		}
+	}
+
+	targets := uninstall.Plan(config.NewConfig(), *binary)
+	if len(targets) == 0 {
+		fmt.Println("Nothing to remove. ~/.aws and ~/.kube are never touched.")
+		return utils.ExitOK
+	}
+
+	fmt.Printf("%s🧹 fancy-login uninstall%s\n", config.Bold, config.Reset)
+	for _, t := range targets {
+		status := ""
+		if skipped[t.Name] {
+			status = " (skipped)"
+		}
+		fmt.Printf("  %-24s %s: %s%s\n", t.Name, t.Description, t.Path, status)
+	}
+	fmt.Println("~/.aws and ~/.kube are never touched.")
+
+	if *dryRun {
+		return utils.ExitOK
+	}
+
+	prompter := prompt.NewPrompter(bufio.NewReader(os.Stdin), os.Stdout, nil, nil)
+	var failed []string
+	for _, t := range targets {
+		if skipped[t.Name] {
+			continue
+		}
+		if !*yes && !prompter.Confirm(fmt.Sprintf("Remove %s (%s)?", t.Name, t.Path), false) {
+			continue
+		}
+		if err := t.Remove(); err != nil {
+			failed = append(failed, fmt.Sprintf("%s (%s): %v", t.Name, t.Path, err))
+			continue
+		}
+		fmt.Printf("%s✅ Removed %s%s\n", config.Success, t.Path, config.Reset)
+	}
+
+	if len(failed) > 0 {
+		fmt.Printf("%sCould not remove:%s\n", config.Error, config.Reset)
+		for _, f := range failed {
+			fmt.Printf("  %s\n", f)
+		}
+		return utils.ExitFailure
+	}
+	return utils.ExitOK
+}
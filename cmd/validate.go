@@ -0,0 +1,254 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"fancy-login/internal/a11y"
+	"fancy-login/internal/config"
+	"fancy-login/internal/prompt"
+	"fancy-login/internal/utils"
+)
+
+// runConfigValidate handles `fancy-login-go config validate`, checking the
+// fancy config against its schema, for suspected secrets and against the
+// AWS config and kubeconfig that actually exist. It exits with ExitConfig
+// if any check fails; --fix offers to remove or remap each stale entry and
+// saves the answers.
+func runConfigValidate(args []string) int {
+	fs := flag.NewFlagSet("config validate", flag.ContinueOnError)
+	fix := fs.Bool("fix", false, "Offer to remove or remap each stale entry")
+	if err := fs.Parse(args); err != nil {
+		return utils.ExitUsage
+	}
+
+	snapshot := config.NewSnapshot(nil)
+	fancyConfig, err := snapshot.FancyConfig()
+	if err != nil {
+		fmt.Printf("%s❌ %v%s\n", config.Error, err, config.Reset)
+		return utils.ExitConfig
+	}
+	// Missing files just have nothing in them, so every entry is stale
+	awsProfiles, err := snapshot.AWSProfiles()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		fmt.Printf("%s❌ %v%s\n", config.Error, err, config.Reset)
+		return utils.ExitFailure
+	}
+	contexts, err := snapshot.KubeContexts()
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		fmt.Printf("%s❌ %v%s\n", config.Error, err, config.Reset)
+		return utils.ExitFailure
+	}
+
+	out := a11y.Writer(os.Stdout)
+	findings := config.CrossCheck(fancyConfig, awsProfiles, contexts)
+	printFindings(out, fancyConfig, findings)
+
+	if *fix && len(findings) > 0 {
+		prompter, closeTTY, err := prompt.NewTTYPrompter(fancyConfig.Settings.AffirmativeAnswers, fancyConfig.Settings.NegativeAnswers)
+		if err != nil {
+			fmt.Printf("%s❌ --fix needs a terminal: %v%s\n", config.Error, err, config.Reset)
+			return utils.ExitFailure
+		}
+		changed := fixFindings(out, prompter, fancyConfig, findings, awsProfiles, contexts)
+		closeTTY()
+
+		if changed {
+			if err := fancyConfig.SaveFancyConfig(); err != nil {
+				fmt.Printf("%s❌ Failed to save configuration: %v%s\n", config.Error, err, config.Reset)
+				return utils.ExitFailure
+			}
+			fmt.Fprintf(out, "%s✅ Saved %s%s\n\n", config.Success, config.GetFancyConfigPath(), config.Reset)
+			findings = config.CrossCheck(fancyConfig, awsProfiles, contexts)
+			printFindings(out, fancyConfig, findings)
+		}
+	}
+
+	problems := printConfigProblems(out, fancyConfig)
+	if len(findings) > 0 || problems > 0 {
+		return utils.ExitConfig
+	}
+	return utils.ExitOK
+}
+
+// printConfigProblems prints invalid settings and suspected secrets in fc
+// and returns how many there are
+func printConfigProblems(out io.Writer, fc *config.FancyConfig) int {
+	problems := previewProblems(fc)
+	secrets, err := fc.ScanSecrets()
+	if err != nil {
+		problems = append(problems, fmt.Errorf("settings.secret_patterns: %w", err))
+	}
+
+	for _, problem := range problems {
+		fmt.Fprintf(out, "%s❌ %v%s\n", config.Error, problem, config.Reset)
+	}
+	for _, secret := range secrets {
+		fmt.Fprintf(out, "%s❌ Possible long-lived credential at %s%s\n", config.Error, secret, config.Reset)
+	}
+	if len(problems) > 0 || len(secrets) > 0 {
+		fmt.Fprintf(out, "\n%s❌ %d invalid settings and %d suspected secrets in %s%s\n",
+			config.Error, len(problems), len(secrets), config.GetFancyConfigPath(), config.Reset)
+	}
+	return len(problems) + len(secrets)
+}
+
+// printFindings prints every profile with the problems found for it,
+// followed by a one-line verdict
+func printFindings(out io.Writer, fc *config.FancyConfig, findings []config.Finding) {
+	byProfile := make(map[string][]config.Finding)
+	for _, f := range findings {
+		key := f.Profile
+		if f.KubeOnly {
+			key += " (kube-only)"
+		}
+		byProfile[key] = append(byProfile[key], f)
+	}
+
+	var names, kubeOnly []string
+	for name := range fc.ProfileConfigs {
+		names = append(names, name)
+	}
+	for name := range fc.KubeOnlyProfiles {
+		kubeOnly = append(kubeOnly, name+" (kube-only)")
+	}
+	sort.Strings(names)
+	sort.Strings(kubeOnly)
+	names = append(names, kubeOnly...)
+
+	fmt.Fprintf(out, "%s🔎 Checking %s against the AWS config and kubeconfig%s\n", config.Bold, config.GetFancyConfigPath(), config.Reset)
+	broken := 0
+	for _, name := range names {
+		problems := byProfile[name]
+		if len(problems) == 0 {
+			fmt.Fprintf(out, "%s✅ %s%s\n", config.Success, name, config.Reset)
+			continue
+		}
+		broken++
+		fmt.Fprintf(out, "%s❌ %s%s\n", config.Error, name, config.Reset)
+		for _, f := range problems {
+			fmt.Fprintf(out, "     %s\n", f.Message)
+		}
+	}
+
+	if len(findings) == 0 {
+		fmt.Fprintf(out, "\n%s✅ All %d profiles match the AWS config and kubeconfig%s\n", config.Success, len(names), config.Reset)
+		return
+	}
+	fmt.Fprintf(out, "\n%s❌ %d problems in %d of %d profiles; run `fancy-login-go config validate --fix` to repair them%s\n",
+		config.Error, len(findings), broken, len(names), config.Reset)
+}
+
+// fixFindings asks how to repair each finding and applies the answers to
+// fc. An empty answer keeps the entry as it is. It reports whether
+// anything changed.
+func fixFindings(out io.Writer, p *prompt.Prompter, fc *config.FancyConfig, findings []config.Finding, awsProfiles []config.AWSProfile, contexts []config.KubernetesContext) bool {
+	changed := false
+	// moved maps profiles to the name they were moved to; "" means removed
+	moved := make(map[string]string)
+	for _, f := range findings {
+		name := f.Profile
+		if to, ok := moved[name]; ok && !f.KubeOnly {
+			// Removed, or it now belongs to the other profile's account
+			if to == "" || f.Kind == config.FindingAccountMismatch {
+				continue
+			}
+			name = to
+		}
+		fmt.Fprintf(out, "\n%s: %s\n", f.Profile, f.Message)
+
+		switch {
+		case f.KubeOnly:
+			options := []string{"Remove the kube-only profile"}
+			for _, c := range contexts {
+				options = append(options, "Use context "+c.Name)
+			}
+			index, ok := p.Choose("Fix it", options)
+			switch {
+			case !ok:
+			case index == 0:
+				delete(fc.KubeOnlyProfiles, name)
+				changed = true
+			default:
+				kp := fc.KubeOnlyProfiles[name]
+				kp.K8sContext = contexts[index-1].Name
+				fc.KubeOnlyProfiles[name] = kp
+				changed = true
+			}
+
+		case f.Kind == config.FindingMissingProfile:
+			options := []string{"Remove it from profile_configs"}
+			var targets []string
+			for _, ap := range awsProfiles {
+				if _, configured := fc.ProfileConfigs[ap.Name]; !configured {
+					targets = append(targets, ap.Name)
+					options = append(options, "Move it to AWS profile "+ap.Name)
+				}
+			}
+			index, ok := p.Choose("Fix it", options)
+			switch {
+			case !ok:
+			case index == 0:
+				delete(fc.ProfileConfigs, name)
+				moved[f.Profile] = ""
+				changed = true
+			default:
+				fc.ProfileConfigs[targets[index-1]] = fc.ProfileConfigs[name]
+				delete(fc.ProfileConfigs, name)
+				moved[f.Profile] = targets[index-1]
+				changed = true
+			}
+
+		case f.Kind == config.FindingMissingContext:
+			options := []string{"Clear k8s_context"}
+			for _, c := range contexts {
+				options = append(options, "Use context "+c.Name)
+			}
+			index, ok := p.Choose("Fix it", options)
+			if ok {
+				pc := fc.ProfileConfigs[name]
+				pc.K8sContext = ""
+				if index > 0 {
+					pc.K8sContext = contexts[index-1].Name
+				}
+				fc.ProfileConfigs[name] = pc
+				changed = true
+			}
+
+		case f.Kind == config.FindingInvalidECRRegion:
+			if p.Confirm("Clear ecr_region so ECR uses the default region?", false) {
+				pc := fc.ProfileConfigs[name]
+				pc.ECRRegion = ""
+				fc.ProfileConfigs[name] = pc
+				changed = true
+			}
+
+		case f.Kind == config.FindingAccountMismatch:
+			if p.Confirm(fmt.Sprintf("Set account_id to %s?", f.Expected), false) {
+				pc := fc.ProfileConfigs[name]
+				pc.AccountID = f.Expected
+				fc.ProfileConfigs[name] = pc
+				changed = true
+			}
+
+		case f.Kind == config.FindingAliasCollision:
+			if p.Confirm(fmt.Sprintf("Remove alias %s from %s?", f.Alias, name), false) {
+				pc := fc.ProfileConfigs[name]
+				var aliases []string
+				for _, alias := range pc.Aliases {
+					if alias != f.Alias {
+						aliases = append(aliases, alias)
+					}
+				}
+				pc.Aliases = aliases
+				fc.ProfileConfigs[name] = pc
+				changed = true
+			}
+		}
+	}
+	return changed
+}
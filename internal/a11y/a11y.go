@@ -0,0 +1,102 @@
+// Package a11y holds the screen reader mode. With it on, output is plain,
+// line-oriented and append-only: no spinners or redraws, no emoji or box
+// borders, and pickers are numbered lists instead of fzf.
+package a11y
+
+import (
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+var enabled bool
+
+// Enabled reports whether screen reader mode is on
+func Enabled() bool {
+	return enabled
+}
+
+// Enable turns screen reader mode on or off for this run
+func Enable(on bool) {
+	enabled = on
+}
+
+// Requested reports whether screen reader mode is asked for, by the
+// screen_reader_mode setting or by ACCESSIBLE in the environment, which
+// other terminal tools honor too. ACCESSIBLE=0 or false does not count.
+func Requested(setting bool) bool {
+	if setting {
+		return true
+	}
+	switch strings.ToLower(os.Getenv("ACCESSIBLE")) {
+	case "", "0", "false":
+		return false
+	}
+	return true
+}
+
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+
+// Plain strips color escapes, emoji and box-drawing characters from text,
+// along with the space after a leading icon. Lines that only held
+// decoration, including ===== and ----- rules, are dropped; blank lines
+// are kept.
+func Plain(text string) string {
+	lines := strings.Split(ansiEscape.ReplaceAllString(text, ""), "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		stripped := strings.Map(dropDecoration, line)
+		if strings.TrimSpace(line) != "" && (strings.TrimSpace(stripped) == "" || isRule(stripped)) {
+			continue
+		}
+		if first := strings.TrimLeftFunc(line, unicode.IsSpace); first != "" && dropDecoration([]rune(first)[0]) < 0 {
+			stripped = strings.TrimLeftFunc(stripped, unicode.IsSpace)
+		}
+		kept = append(kept, stripped)
+	}
+	return strings.Join(kept, "\n")
+}
+
+// isRule reports whether line is a horizontal rule drawn with = or -
+func isRule(line string) bool {
+	line = strings.TrimSpace(line)
+	return len(line) >= 3 && strings.Trim(line, "=-") == ""
+}
+
+// dropDecoration maps emoji, their modifiers and box-drawing characters to
+// nothing and keeps everything else
+func dropDecoration(r rune) rune {
+	switch {
+	case r >= 0x2500 && r <= 0x257F, // box drawing
+		r >= 0x2190 && r <= 0x21FF,   // arrows
+		r >= 0x2300 && r <= 0x23FF,   // technical symbols such as ⎈ and ⏱
+		r >= 0x2600 && r <= 0x27BF,   // misc symbols and dingbats
+		r >= 0x1F000 && r <= 0x1FAFF, // emoji
+		r == 0xFE0F, r == 0x200D:     // emoji presentation and joiner
+		return -1
+	}
+	return r
+}
+
+// plainWriter passes every write through Plain
+type plainWriter struct {
+	w io.Writer
+}
+
+// Writer returns w, wrapped to strip decoration when screen reader mode is
+// on. Callers write whole lines, so stripping per write is enough.
+func Writer(w io.Writer) io.Writer {
+	if !enabled {
+		return w
+	}
+	return plainWriter{w: w}
+}
+
+func (p plainWriter) Write(b []byte) (int, error) {
+	if _, err := io.WriteString(p.w, Plain(string(b))); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
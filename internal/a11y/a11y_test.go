@@ -0,0 +1,71 @@
+package a11y
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestPlain(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"Text unchanged", "Selected profile dev\n", "Selected profile dev\n"},
+		{"Leading emoji", "🔑 AWS Profile: dev\n", "AWS Profile: dev\n"},
+		{"Emoji with variation selector", "☁️  AWS Account ID: 123\n", "AWS Account ID: 123\n"},
+		{"Colors", "\033[0;32m✅ done\033[0m\n", "done\n"},
+		{"Box border dropped", "title\n───────\nbody\n", "title\nbody\n"},
+		{"ASCII rule dropped", "Wizard\n========\n", "Wizard\n"},
+		{"Blank lines kept", "\nsummary\n\n", "\nsummary\n\n"},
+		{"Indentation kept", "  - dev\n", "  - dev\n"},
+		{"Inline emoji", "Profile 🦄 dev", "Profile  dev"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := Plain(tc.input); got != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestRequested(t *testing.T) {
+	testCases := []struct {
+		setting    bool
+		accessible string
+		expected   bool
+	}{
+		{false, "", false},
+		{true, "", true},
+		{false, "1", true},
+		{false, "true", true},
+		{false, "0", false},
+		{false, "FALSE", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("%v/%s", tc.setting, tc.accessible), func(t *testing.T) {
+			t.Setenv("ACCESSIBLE", tc.accessible)
+			if got := Requested(tc.setting); got != tc.expected {
+				t.Errorf("Expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestWriter(t *testing.T) {
+	defer Enable(false)
+
+	var buf bytes.Buffer
+	Enable(false)
+	fmt.Fprint(Writer(&buf), "🦄 Wizard\n")
+	Enable(true)
+	fmt.Fprint(Writer(&buf), "🦄 Wizard\n")
+
+	if expected := "🦄 Wizard\nWizard\n"; buf.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, buf.String())
+	}
+}
@@ -1,19 +1,24 @@
 package aws
 
 import (
-	"bufio"
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
-	"regexp"
 	"runtime"
 	"sort"
 	"strings"
 	"time"
 
+	"fancy-login/internal/a11y"
 	"fancy-login/internal/config"
+	"fancy-login/internal/fzf"
+	"fancy-login/internal/platform"
+	"fancy-login/internal/prompt"
+	"fancy-login/internal/state"
 	"fancy-login/internal/utils"
 )
 
@@ -22,79 +27,185 @@ type AWSManager struct {
 	config      *config.Config
 	logger      *utils.Logger
 	fancyConfig *config.FancyConfig
+
+	// portalProbe checks that an SSO start URL is reachable
+	portalProbe func(ctx context.Context, startURL string) error
+	// sortOverride replaces the configured picker sort for this run
+	sortOverride []string
+	// snapshot holds the AWS config as parsed once for this run
+	snapshot *config.Snapshot
+	// dryRun reports logins and exports instead of performing them
+	dryRun bool
+	// ssoLoginPerformed is set once HandleAWSLogin logged in via SSO
+	ssoLoginPerformed bool
+	// sessionReused is set when HandleAWSLogin found valid credentials and
+	// had nothing to do
+	sessionReused bool
+	// ecrRegionOverride replaces the resolved ECR region for this run
+	ecrRegionOverride string
+	// selectionTimeout bounds the profile picker; 0 waits forever
+	selectionTimeout time.Duration
+	// sts checks sessions and looks up account IDs
+	sts STSClient
+	// assumedRoleARN is the identity of the last role chain HandleAWSLogin
+	// checked
+	assumedRoleARN string
+	// ecrLogins are the outcomes of HandleECRLogin
+	ecrLogins []ECRLoginResult
+	// forceECRLogin logs in to ECR despite a fresh token
+	forceECRLogin bool
+	// ssoSessionsLoggedIn are the sso-session names logged in to during
+	// this run; one device authorization covers every profile using them
+	ssoSessionsLoggedIn map[string]bool
+	// accountPrefetch holds the account lookups started while the picker
+	// was open
+	accountPrefetch *accountPrefetch
+	// noBrowser makes aws sso login print a device code instead of
+	// opening a browser
+	noBrowser bool
+	// mfaSessions are the MFA sessions without a role started or reused
+	// during this run, written to mfaCredentialsFile
+	mfaSessions        map[string]mfaCredentials
+	mfaCredentialsFile string
 }
 
 // NewAWSManager creates a new AWS manager
 func NewAWSManager(cfg *config.Config, logger *utils.Logger, fancyConfig *config.FancyConfig) *AWSManager {
-	return &AWSManager{
+	aws := &AWSManager{
 		config:      cfg,
 		logger:      logger,
 		fancyConfig: fancyConfig,
+		portalProbe: func(ctx context.Context, startURL string) error {
+			return probeSSOPortal(ctx, http.DefaultClient, startURL)
+		},
+		snapshot:         config.NewSnapshot(nil),
+		selectionTimeout: fancyConfig.Settings.SelectionTimeoutDuration(),
 	}
+	aws.sts = newSDKSTSClient(fancyConfig.ProfileBackend, aws.ProfileEnv)
+	return aws
+}
+
+// SetSnapshot makes the manager share the run's parsed configs
+func (aws *AWSManager) SetSnapshot(snapshot *config.Snapshot) {
+	aws.snapshot = snapshot
+}
+
+// SetDryRun makes the manager report the aws and docker commands it would
+// run instead of running them. Read-only session checks still run, since
+// they decide what would happen.
+func (aws *AWSManager) SetDryRun(dryRun bool) {
+	aws.dryRun = dryRun
 }
 
-// SelectAWSProfile allows user to select an AWS profile using fzf
-func (aws *AWSManager) SelectAWSProfile() (string, error) {
+// SetSelectionTimeout overrides how long the profile picker waits for a
+// choice; 0 waits forever
+func (aws *AWSManager) SetSelectionTimeout(timeout time.Duration) {
+	aws.selectionTimeout = timeout
+}
+
+// SetECRRegionOverride makes this run log in to ECR in region, ahead of the
+// configured, environment and default regions
+func (aws *AWSManager) SetECRRegionOverride(region string) {
+	aws.ecrRegionOverride = region
+}
+
+// SetNoBrowser makes SSO logins print the verification URL and user code
+// instead of opening a browser, for sessions without one such as SSH
+func (aws *AWSManager) SetNoBrowser(noBrowser bool) {
+	aws.noBrowser = noBrowser
+}
+
+// SSOLoginPerformed reports whether HandleAWSLogin had to log in via SSO,
+// rather than finding a valid session
+func (aws *AWSManager) SSOLoginPerformed() bool {
+	return aws.ssoLoginPerformed
+}
+
+// SessionReused reports whether HandleAWSLogin found the profile's
+// credentials still valid, so no login was needed
+func (aws *AWSManager) SessionReused() bool {
+	return aws.sessionReused
+}
+
+// AssumedRoleARN returns the ARN STS reported for the assumed role of a
+// role_arn/source_profile profile, or "" for other profiles
+func (aws *AWSManager) AssumedRoleARN() string {
+	return aws.assumedRoleARN
+}
+
+// SelectAWSProfile allows user to select an AWS profile using fzf, the
+// built-in menu without fzf, or a numbered list in screen reader mode. A
+// query pre-filters the list; when nothing matches it, all profiles are
+// shown.
+func (aws *AWSManager) SelectAWSProfile(ctx context.Context, query string) (string, error) {
+	if !prompt.Interactive() {
+		return "", utils.WithExitCode(utils.ExitUsage, fmt.Errorf("no profile given; --non-interactive requires --profile"))
+	}
+
 	displayProfiles, err := aws.getProfilesWithMetadata()
 	if err != nil {
 		return "", err
 	}
 
 	if len(displayProfiles) == 0 {
-		aws.logger.Die("No AWS profiles found in ~/.aws/config")
+		return aws.guidedProfileSetup(ctx)
 	}
 
 	configuredCount := aws.countConfiguredProfiles(displayProfiles)
 	totalCount := aws.countRealProfiles(displayProfiles)
 
+	// Most logins pick a configured profile, so their accounts are looked
+	// up while the user chooses; the other lookups stop once one is picked
+	var configured []string
+	for _, p := range displayProfiles {
+		if p.IsConfigured && !p.KubeOnly {
+			configured = append(configured, p.Name)
+		}
+	}
+	aws.accountPrefetch = aws.startAccountPrefetch(ctx, configured)
+	var selectedProfile string
+	defer func() { aws.accountPrefetch.keep(selectedProfile) }()
+
 	aws.logger.FancyLog("☁️ AWS Profile Selection")
 	aws.logger.FancyLog(fmt.Sprintf("Found %d configured profiles out of %d total AWS profiles",
 		configuredCount, totalCount))
 
-	// Create display text for fzf
-	var displayTexts []string
-	for _, p := range displayProfiles {
-		displayTexts = append(displayTexts, p.DisplayText)
+	// fzf filters by the query itself; the other pickers only get the
+	// matching profiles
+	choices := displayProfiles
+	if query != "" {
+		if matching := aws.filterProfiles(displayProfiles, query); len(matching) > 0 {
+			choices = matching
+		} else {
+			aws.logger.LogWarning(fmt.Sprintf("No profile matches %q, showing all profiles", query))
+			query = ""
+		}
 	}
 
-	// Use fzf to select profile with proper TTY handling and timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
-	defer cancel()
-
-	cmd := exec.CommandContext(ctx, "fzf", "--prompt=Select AWS Profile: ")
-	cmd.Stdin = strings.NewReader(strings.Join(displayTexts, "\n"))
-
-	// fzf needs full terminal access - redirect both stderr and pass through TTY
-	cmd.Stderr = os.Stderr
-
-	// Try to open /dev/tty for fzf to use for input/output
-	if tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0); err == nil {
-		defer tty.Close()
-		// Let fzf use the TTY for its interface
-		cmd.ExtraFiles = []*os.File{tty}
+	var selectedDisplayText string
+	switch {
+	case a11y.Enabled():
+		selectedDisplayText, err = aws.chooseProfileNumbered(choices)
+	case fzf.UseBuiltin(aws.fancyConfig.Settings.Selector):
+		selectedDisplayText, err = aws.chooseProfileBuiltin(ctx, choices)
+	default:
+		selectedDisplayText, err = aws.pickProfileWithFzf(ctx, displayProfiles, query)
 	}
-
-	output, err := cmd.Output()
 	if err != nil {
-		if ctx.Err() == context.DeadlineExceeded {
-			return "", fmt.Errorf("profile selection timed out after 60 seconds")
-		}
-		return "", fmt.Errorf("profile selection failed: %w", err)
+		return "", err
 	}
-
-	selectedDisplayText := strings.TrimSpace(string(output))
 	if selectedDisplayText == "" {
-		aws.logger.Die("No profile selected. Exiting.")
+		return "", fmt.Errorf("no profile selected: %w", utils.ErrCancelled)
 	}
 
 	// Find the actual profile name from the selected display text
-	var selectedProfile string
-	var isConfigured bool
+	var isConfigured, isKubeOnly bool
 	for _, p := range displayProfiles {
 		// Handle both exact match and trimmed match (fzf may strip leading whitespace)
 		if p.DisplayText == selectedDisplayText || strings.TrimSpace(p.DisplayText) == selectedDisplayText {
 			selectedProfile = p.Name
 			isConfigured = p.IsConfigured
+			isKubeOnly = p.KubeOnly
 			break
 		}
 	}
@@ -104,39 +215,156 @@ func (aws *AWSManager) SelectAWSProfile() (string, error) {
 		return "", fmt.Errorf("invalid profile selection")
 	}
 
+	// Kube-only profiles have no fancy-config entry to offer configuring
+	if isKubeOnly {
+		return selectedProfile, nil
+	}
+
 	aws.logger.FancyLog(fmt.Sprintf("Profile selected: %s (configured: %v)", selectedProfile, isConfigured))
 
 	// If profile is not configured, offer to run configuration
 	if !isConfigured {
 		aws.logger.LogWarning(fmt.Sprintf("Profile '%s' is not configured in fancy-config", selectedProfile))
-		fmt.Printf("%sWould you like to configure this profile now? (y/N): %s", config.Cyan, config.Reset)
 
 		// Use /dev/tty for proper terminal input handling
-		tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+		prompter, closeTTY, err := aws.newPrompter()
 		if err != nil {
 			aws.logger.LogWarning("Failed to open /dev/tty for input, continuing with unconfigured profile")
 		} else {
-			defer tty.Close()
-			var response string
-			if _, err := fmt.Fscanln(tty, &response); err != nil {
-				aws.logger.LogWarning("Failed to read user input, continuing with unconfigured profile")
-			}
-
-			if strings.ToLower(response) == "y" || strings.ToLower(response) == "yes" {
+			defer closeTTY()
+			question := fmt.Sprintf("%sWould you like to configure this profile now?%s", config.Accent, config.Reset)
+			if prompter.Confirm(question, false) {
 				aws.logger.LogInfo("Run 'fancy-login-go --config' to configure profiles")
 				return "", fmt.Errorf("profile configuration needed")
 			}
 		}
 		aws.logger.LogWarning("Continuing with unconfigured profile...")
 	}
+	return selectedProfile, nil
+}
 
-	// Export profile to temp file for shell integration
-	if err := aws.exportProfileToTemp(selectedProfile); err != nil {
-		aws.logger.LogWarning(fmt.Sprintf("Failed to export profile to temp file: %v", err))
+// filterProfiles returns the selectable entries whose profile matches
+// query, without group headers and separators
+func (aws *AWSManager) filterProfiles(displayProfiles []ProfileDisplayInfo, query string) []ProfileDisplayInfo {
+	var matching []ProfileDisplayInfo
+	for _, p := range displayProfiles {
+		if p.Name != "---" && matchesQuery(aws.fancyConfig, p.Name, query) {
+			matching = append(matching, p)
+		}
 	}
+	return matching
+}
 
-	aws.logger.LogSuccess(fmt.Sprintf("Selected AWS Profile: %s", selectedProfile))
-	return selectedProfile, nil
+// pickProfileWithFzf shows the profile picker, typed into with query, and
+// returns the selected line
+func (aws *AWSManager) pickProfileWithFzf(ctx context.Context, displayProfiles []ProfileDisplayInfo, query string) (string, error) {
+	// Create display text for fzf; metadata is muted when fzf can render
+	// colors, and fzf strips them again from the selection
+	caps := fzf.Detect()
+	colorize := config.Muted != "" && caps.Supports(fzf.FeatureANSI)
+	var displayTexts []string
+	for _, p := range displayProfiles {
+		if colorize {
+			displayTexts = append(displayTexts, muteMetadata(p.DisplayText))
+		} else {
+			displayTexts = append(displayTexts, p.DisplayText)
+		}
+	}
+
+	// Use fzf to select profile with proper TTY handling and timeout
+	ctx, cancel := utils.WithStepTimeout(ctx, aws.selectionTimeout)
+	defer cancel()
+
+	// The cursor starts on the last used profile
+	var startPos int
+	if last := LastLogin(); last != nil {
+		startPos = pickerStartPos(displayProfiles, last.Profile)
+	}
+
+	cmd := exec.CommandContext(ctx, "fzf", caps.Args(fzf.Options{Prompt: "Select AWS Profile: ", Query: query, ANSI: colorize, StartPos: startPos})...)
+	cmd.Stdin = strings.NewReader(strings.Join(displayTexts, "\n"))
+
+	// fzf needs full terminal access - redirect both stderr and pass through TTY
+	cmd.Stderr = os.Stderr
+
+	// Try to open the terminal for fzf to use for input/output. Windows
+	// can't pass extra file descriptors to a child process; fzf opens the
+	// console itself there.
+	if runtime.GOOS != "windows" {
+		if tty, err := platform.OpenTTY(); err == nil {
+			defer tty.Close()
+			// Let fzf use the TTY for its interface
+			cmd.ExtraFiles = []*os.File{tty}
+		}
+	}
+
+	output, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", &utils.SelectionTimeoutError{What: "profile selection", Timeout: aws.selectionTimeout}
+		}
+		if fzf.Cancelled(err) {
+			return "", fmt.Errorf("no profile selected: %w", utils.ErrCancelled)
+		}
+		return "", fmt.Errorf("profile selection failed: %w", err)
+	}
+
+	return strings.TrimSpace(string(output)), nil
+}
+
+// chooseProfileBuiltin shows the profile list, group headers included, as
+// the built-in menu when fzf isn't used. It returns the chosen profile's
+// line, or "" if none was chosen.
+func (aws *AWSManager) chooseProfileBuiltin(ctx context.Context, displayProfiles []ProfileDisplayInfo) (string, error) {
+	prompter, closeTTY, err := aws.newPrompter()
+	if err != nil {
+		return "", fmt.Errorf("profile selection needs a terminal: %w", err)
+	}
+	defer closeTTY()
+
+	ctx, cancel := utils.WithStepTimeout(ctx, aws.selectionTimeout)
+	defer cancel()
+
+	items := make([]prompt.MenuItem, len(displayProfiles))
+	for i, p := range displayProfiles {
+		items[i] = prompt.MenuItem{Text: p.DisplayText, Header: p.Name == "---"}
+	}
+	index, ok := prompter.Menu(ctx, "Select AWS Profile", items)
+	if ctx.Err() == context.DeadlineExceeded {
+		return "", &utils.SelectionTimeoutError{What: "profile selection", Timeout: aws.selectionTimeout}
+	}
+	if !ok {
+		return "", nil
+	}
+	return displayProfiles[index].DisplayText, nil
+}
+
+// chooseProfileNumbered is the screen reader picker: a numbered list of the
+// selectable profiles, without group headers and separators. It returns
+// the chosen profile's line, or "" if none was chosen.
+func (aws *AWSManager) chooseProfileNumbered(displayProfiles []ProfileDisplayInfo) (string, error) {
+	prompter, closeTTY, err := aws.newPrompter()
+	if err != nil {
+		return "", fmt.Errorf("profile selection needs a terminal: %w", err)
+	}
+	defer closeTTY()
+
+	var lines []string
+	for _, p := range displayProfiles {
+		if p.Name != "---" && !strings.HasPrefix(p.DisplayText, "===") {
+			lines = append(lines, p.DisplayText)
+		}
+	}
+	index, ok := prompter.Choose("Select AWS Profile", lines)
+	if !ok {
+		return "", nil
+	}
+	return lines[index], nil
+}
+
+// newPrompter opens a y/n prompter on the terminal using the configured answers
+func (aws *AWSManager) newPrompter() (*prompt.Prompter, func(), error) {
+	return prompt.NewTTYPrompter(aws.fancyConfig.Settings.AffirmativeAnswers, aws.fancyConfig.Settings.NegativeAnswers)
 }
 
 // countConfiguredProfiles counts how many profiles are configured
@@ -162,133 +390,262 @@ func (aws *AWSManager) countRealProfiles(profiles []ProfileDisplayInfo) int {
 }
 
 // HandleAWSLogin checks and handles AWS SSO authentication
-func (aws *AWSManager) HandleAWSLogin(profile string, forceLogin bool) error {
+func (aws *AWSManager) HandleAWSLogin(ctx context.Context, profile string, forceLogin bool) error {
 	aws.logger.FancyLog(fmt.Sprintf("Checking AWS SSO session for profile %s...", profile))
 
-	if !forceLogin {
-		if aws.isSessionValid(profile) {
-			aws.logger.LogSuccess(fmt.Sprintf("AWS SSO session is still valid for %s.", profile))
-			return nil
+	// A credential_process helper is the login, so it is always checked
+	var info LoginProfileInfo
+	p, ok := aws.snapshot.AWSProfile(profile)
+	// aws-vault does the SSO, MFA or role login itself, so the profile is
+	// checked like one whose credential_process helper logs in
+	vault := aws.usesAWSVault(profile)
+	if vault {
+		info.CredentialProcess, ok = awsVaultCredentialProcess(profile), false
+	}
+	if ok && !p.IsSSO {
+		info.CredentialProcess = p.CredentialProcess
+	}
+	if ok && p.IsRoleChain() {
+		if _, err := aws.roleChain(profile); err != nil {
+			return utils.WithExitCode(utils.ExitConfig, err)
 		}
+		info.SourceProfile, info.RoleARN, info.ForceSource = p.SourceProfile, p.RoleARN, forceLogin
 	}
-
-	isSSO, err := aws.isSSOMProfile(profile)
-	if err != nil {
-		return err
+	if ok && p.MFASerial != "" {
+		info.MFASerial, info.ForceSource = p.MFASerial, forceLogin
 	}
-
-	if isSSO {
-		return aws.performSSOMLogin(profile)
+	// Keys that need an MFA session or feed a role chain take those paths
+	if ok && !p.IsSSO && p.StaticCredentials && p.CredentialProcess == "" && !p.IsRoleChain() && p.MFASerial == "" {
+		info.StaticCredentials = true
 	}
 
-	aws.logger.LogWarning(fmt.Sprintf("Unable to authenticate with profile %s. This might not be an SSO profile.", profile))
-
-	fmt.Printf("%sDo you want to continue anyway? (y/n): %s", config.Cyan, config.Reset)
-
-	// Use /dev/tty for proper terminal input handling
-	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
-	if err != nil {
-		aws.logger.LogError(fmt.Sprintf("Failed to open /dev/tty for input: %v", err))
-		return err
+	// A cached SSO token answers without a network call; STS is only asked
+	// when the cache can't tell
+	var session SessionState
+	if !forceLogin || info.CredentialProcess != "" || info.StaticCredentials {
+		if info.MFASerial != "" {
+			// STS answers for the long-term keys without MFA, so only the
+			// cached MFA session counts
+			if creds, found := aws.cachedMFASession(p, time.Now()); found {
+				if err := aws.useMFASession(p, creds); err != nil {
+					return err
+				}
+				session.Valid = true
+			}
+		} else if valid, decided := aws.cachedSessionValid(profile, time.Now()); decided {
+			session.Valid = valid
+		} else {
+			identity, err := aws.callerIdentity(ctx, profile)
+			// A check that hung or couldn't reach AWS says nothing about
+			// the session; a login over the same network would fail too
+			var timeoutErr *utils.TimeoutError
+			var networkErr *STSNetworkError
+			if errors.As(err, &timeoutErr) || errors.As(err, &networkErr) {
+				return utils.WithExitCode(utils.ExitAWSAuth, err)
+			}
+			session.Err, session.Valid = err, err == nil
+			if err == nil && info.RoleARN != "" {
+				aws.assumedRoleARN = identity.Arn
+			}
+		}
 	}
-	defer tty.Close()
 
-	var response string
-	_, err = fmt.Fscanln(tty, &response)
-	if err != nil {
-		aws.logger.LogError(fmt.Sprintf("Error reading user input: %v", err))
-		return err
+	if info.SourceProfile == "" && !vault && (forceLogin || !session.Valid) {
+		isSSO, err := aws.isSSOMProfile(profile)
+		if err != nil {
+			return err
+		}
+		info.SSO = isSSO
 	}
 
-	if response != "y" {
-		aws.logger.Die("User chose to exit due to authentication issues.")
-	}
+	// Use /dev/tty for proper terminal input handling
+	prompter, closeTTY, err := aws.newPrompter()
+	defer closeTTY()
+	info.Interactive = err == nil
 
-	aws.logger.LogWarning("Continuing with potentially invalid credentials...")
-	return nil
+	plan := PlanLogin(info, session, forceLogin)
+	if aws.dryRun {
+		return aws.reportLoginPlan(profile, plan, info, session)
+	}
+	return aws.executeLoginPlan(ctx, profile, plan, info, session, prompter)
 }
 
-// HandleECRLogin performs ECR login based on configuration
-func (aws *AWSManager) HandleECRLogin(profile string) error {
+// HandleECRLogin performs ECR login based on configuration: to the
+// registries of ecr_registries, or to the one of the profile's own account
+// without them, and to ECR Public if enabled. Each registry is logged in to
+// on its own, so one that fails doesn't keep docker out of the others;
+// ECRLogins lists the outcomes. Registries docker holds a fresh token for
+// are skipped unless SetForceECRLogin was called.
+func (aws *AWSManager) HandleECRLogin(ctx context.Context, profile string) error {
 	if !aws.fancyConfig.ShouldPerformECRLogin(profile) {
 		return nil
 	}
 
+	if aws.dryRun {
+		return aws.reportECRLogin(ctx, profile)
+	}
+
 	aws.logger.FancyLog("ECR login based on configuration...")
 
-	accountID, err := aws.getAccountID(profile)
-	if err != nil {
-		aws.logger.LogError("Failed to retrieve AWS account ID. Your session may have expired or is not authenticated.")
-		return err
-	}
+	var errs []error
+	registries := aws.configuredECRRegistries(profile)
+	if len(registries) == 0 {
+		accountID, err := aws.getAccountID(ctx, profile)
+		if err != nil {
+			aws.logger.LogError("Failed to retrieve AWS account ID. Your session may have expired or is not authenticated.")
+			recordECRLogin(profile, finishedECRLogin("", err))
+			aws.ecrLogins = append(aws.ecrLogins, ECRLoginResult{Err: err})
+			errs = append(errs, err)
+		} else {
+			// ECR needs the account the session really belongs to, so this
+			// is where a stale account_id gets noticed
+			aws.cacheAccountID(profile, accountID)
 
-	region := aws.fancyConfig.GetECRRegionForProfile(profile)
-	if region == "" {
-		region = os.Getenv("AWS_REGION")
-		if region == "" {
-			region = aws.config.DefaultRegion
+			registry := aws.ecrRegistry(profile, accountID)
+			aws.logger.FancyLog(fmt.Sprintf("Account ID: %s, Region: %s", registry.AccountID, registry.Region))
+			registries = append(registries, registry)
 		}
 	}
+	if aws.ecrPublic(profile) {
+		registries = append(registries, PublicRegistry)
+	}
 
-	aws.logger.FancyLog(fmt.Sprintf("Account ID: %s, Region: %s", accountID, region))
+	cached := aws.cachedECRTokens(registries)
+	var stale []Registry
+	for _, registry := range registries {
+		if !cached[registry.Host()] {
+			stale = append(stale, registry)
+		}
+	}
 
 	var spinner *utils.Spinner
-	if !aws.config.FancyVerbose {
-		spinner = utils.NewSpinner("🐳 Logging in to ECR...")
+	if !aws.config.FancyVerbose && len(stale) > 0 {
+		spinner = utils.NewSpinner(aws.logger.Output(), "🐳 Logging in to ECR...")
 		spinner.Start()
 	}
-
-	// Get ECR login password and login to docker
-	cmd1 := exec.Command("aws", "ecr", "get-login-password", "--region", region, "--profile", profile)
-	cmd2 := exec.Command("docker", "login", "--username", "AWS", "--password-stdin",
-		fmt.Sprintf("%s.dkr.ecr.%s.amazonaws.com", accountID, region))
-
-	cmd2.Stdin, _ = cmd1.StdoutPipe()
-
-	if err := cmd1.Start(); err != nil {
-		if spinner != nil {
-			spinner.Stop()
-		}
-		return fmt.Errorf("failed to start ECR login command: %w", err)
+	for _, registry := range stale {
+		aws.logger.FancyLog(fmt.Sprintf("Registry: %s, Method: %s", registry.Host(), MethodPipe))
 	}
+	loggedIn := aws.loginToRegistries(ctx, profile, stale, func(host string, attempt, attempts int) {
+		aws.reportECRRetry(spinner, host, attempt, attempts)
+	})
+	if spinner != nil {
+		spinner.Stop()
+	}
+	recordRegistryLogins(profile, stale, loggedIn)
 
-	if err := cmd2.Start(); err != nil {
-		if spinner != nil {
-			spinner.Stop()
+	// Report the registries in configured order, cached or not
+	results := make([]ECRLoginResult, 0, len(registries))
+	for _, registry := range registries {
+		if cached[registry.Host()] {
+			results = append(results, ECRLoginResult{Host: registry.Host(), Cached: true})
+		} else {
+			results, loggedIn = append(results, loggedIn[0]), loggedIn[1:]
 		}
-		return fmt.Errorf("failed to start docker login command: %w", err)
 	}
 
-	if err := cmd1.Wait(); err != nil {
-		if spinner != nil {
-			spinner.Stop()
+	for _, result := range results {
+		aws.ecrLogins = append(aws.ecrLogins, result)
+		if result.Cached {
+			continue
+		}
+		if result.Err != nil {
+			aws.logger.LogError(fmt.Sprintf("ECR login to %s failed.", result.Host))
+			errs = append(errs, fmt.Errorf("%s: %w", result.Host, result.Err))
+			continue
+		}
+		if aws.config.FancyVerbose {
+			aws.logger.LogSuccess(fmt.Sprintf("Docker: Login Succeeded (%s)", result.Host))
 		}
-		return fmt.Errorf("ECR get-login-password failed: %w", err)
 	}
 
-	if err := cmd2.Wait(); err != nil {
-		if spinner != nil {
-			spinner.Stop()
+	return errors.Join(errs...)
+}
+
+// ECRLogins returns the outcome of each registry HandleECRLogin logged in
+// to, in order
+func (aws *AWSManager) ECRLogins() []ECRLoginResult {
+	return aws.ecrLogins
+}
+
+// ECRLoginCached reports whether HandleECRLogin skipped every registry for
+// a fresh token
+func (aws *AWSManager) ECRLoginCached() bool {
+	for _, login := range aws.ecrLogins {
+		if !login.Cached {
+			return false
 		}
-		aws.logger.LogError("ECR login failed.")
-		return fmt.Errorf("docker login failed: %w", err)
 	}
+	return len(aws.ecrLogins) > 0
+}
 
-	if spinner != nil {
-		spinner.Stop()
+// ECRRegion returns the region a profile logs in to ECR in and where it came
+// from: the --region override, else the region FancyConfig resolves for the
+// profile, else the built-in default
+func (aws *AWSManager) ECRRegion(profile string) (string, config.RegionSource) {
+	if aws.ecrRegionOverride != "" {
+		return aws.ecrRegionOverride, config.RegionFromFlag
 	}
-
-	aws.logger.FancyLog("ECR login successful")
-	if aws.config.FancyVerbose {
-		aws.logger.LogSuccess("Docker: Login Succeeded")
+	awsProfile, ok := aws.snapshot.AWSProfile(profile)
+	if !ok {
+		awsProfile = config.AWSProfile{Name: profile}
+	}
+	if region, source := aws.fancyConfig.GetECRRegionForProfile(awsProfile); region != "" {
+		return region, source
 	}
+	return aws.config.DefaultRegion, config.RegionFromDefault
+}
 
-	return nil
+// ecrRegistry returns the ECR registry a profile logs in to, in the region
+// ECRRegion resolves
+func (aws *AWSManager) ecrRegistry(profile, accountID string) Registry {
+	region, _ := aws.ECRRegion(profile)
+	return Registry{AccountID: accountID, Region: region}
 }
 
 // GetAccountID retrieves the AWS account ID for the current profile
-func (aws *AWSManager) GetAccountID(profile string) (string, error) {
-	return aws.getAccountID(profile)
+func (aws *AWSManager) GetAccountID(ctx context.Context, profile string) (string, error) {
+	return aws.getAccountID(ctx, profile)
+}
+
+// SummaryAccountID returns the account ID to show for profile. A configured
+// account_id is used as it is; otherwise STS is asked and the answer cached
+// in the profile config. fromSTS reports whether STS was called.
+func (aws *AWSManager) SummaryAccountID(ctx context.Context, profile string) (accountID string, fromSTS bool, err error) {
+	if pc, exists := aws.fancyConfig.ProfileConfigs[profile]; exists && pc.AccountID != "" {
+		aws.logger.FancyLog(fmt.Sprintf("Using the configured account ID of %s: %s", profile, pc.AccountID))
+		return pc.AccountID, false, nil
+	}
+	accountID, err = aws.getAccountID(ctx, profile)
+	if err != nil {
+		return "", true, err
+	}
+	aws.cacheAccountID(profile, accountID)
+	return accountID, true, nil
+}
+
+// cacheAccountID saves an account ID STS returned into the profile config
+// when auto_update_account_ids is on. A configured ID that differs is
+// warned about, then replaced.
+func (aws *AWSManager) cacheAccountID(profile, accountID string) {
+	pc, exists := aws.fancyConfig.ProfileConfigs[profile]
+	if !exists || accountID == "" || pc.AccountID == accountID {
+		return
+	}
+	if pc.AccountID != "" {
+		aws.logger.LogWarning(fmt.Sprintf("account_id %s of %s does not match %s from STS", pc.AccountID, profile, accountID))
+	}
+	if aws.dryRun || !aws.fancyConfig.Settings.AutoUpdateAccountIDsEnabled() {
+		return
+	}
+
+	pc.AccountID = accountID
+	aws.fancyConfig.ProfileConfigs[profile] = pc
+	if err := aws.fancyConfig.SaveFancyConfig(); err != nil {
+		aws.logger.LogWarning(fmt.Sprintf("Failed to save the account ID of %s: %v", profile, err))
+		return
+	}
+	aws.logger.FancyLog(fmt.Sprintf("Saved account ID %s for %s", accountID, profile))
 }
 
 // ProfileDisplayInfo holds information for displaying profiles in selection
@@ -297,13 +654,20 @@ type ProfileDisplayInfo struct {
 	DisplayText  string
 	IsConfigured bool
 	Metadata     string
+	KubeOnly     bool // kube-only profile; selecting it skips all AWS steps
+}
+
+// ProfileList returns the picker entries, including group headers and
+// separators, without showing the picker
+func (aws *AWSManager) ProfileList() ([]ProfileDisplayInfo, error) {
+	return aws.getProfilesWithMetadata()
 }
 
 // getProfilesWithMetadata returns profiles with rich metadata for display
 func (aws *AWSManager) getProfilesWithMetadata() ([]ProfileDisplayInfo, error) {
-	// Get profiles from AWS config
+	// Get profiles from AWS config; kube-only setups may not have one
 	awsProfiles, err := aws.getAWSConfigProfiles()
-	if err != nil {
+	if err != nil && len(aws.fancyConfig.KubeOnlyProfiles) == 0 {
 		return nil, err
 	}
 
@@ -322,35 +686,53 @@ func (aws *AWSManager) getProfilesWithMetadata() ([]ProfileDisplayInfo, error) {
 	}
 	var allConfiguredProfiles []profileInfo
 
+	var configuredNames []string
 	for profileName := range aws.fancyConfig.ProfileConfigs {
 		// Check if this profile exists in AWS config
-		found := false
 		for _, awsProfile := range awsProfiles {
 			if awsProfile == profileName {
-				found = true
+				configuredNames = append(configuredNames, profileName)
 				break
 			}
 		}
+	}
 
-		if found {
-			profileConfig := aws.fancyConfig.ProfileConfigs[profileName]
-			allConfiguredProfiles = append(allConfiguredProfiles, profileInfo{
-				ProfileName: profileName,
-				Config:      profileConfig,
-				IsK9s:       profileConfig.K9sAutoLaunch,
-			})
-			configuredCount++
+	// Order by the configured sort columns; grouping below keeps the order
+	for _, record := range aws.sortConfiguredProfiles(configuredNames) {
+		allConfiguredProfiles = append(allConfiguredProfiles, profileInfo{
+			ProfileName: record.Profile,
+			Config:      record.Config,
+			IsK9s:       record.Config.K9sAutoLaunch,
+		})
+		configuredCount++
+	}
+
+	// Profiles backed by an external credential helper or static keys are
+	// labeled as such; the SSO token caches of the others are read while
+	// the list is built
+	credentials := make(map[string]string)
+	regions := make(map[string]string)
+	var ssoProfiles []config.AWSProfile
+	if profiles, err := aws.snapshot.AWSProfiles(); err == nil {
+		for _, p := range profiles {
+			if kind := p.Type(); kind == "external process" || kind == "static credentials" {
+				credentials[p.Name] = kind
+			}
+			regions[p.Name] = p.Region
+			if _, configured := aws.fancyConfig.ProfileConfigs[p.Name]; configured && p.IsSSO {
+				ssoProfiles = append(ssoProfiles, p)
+			}
 		}
 	}
+	sessionStatus := startSessionIndicators(ssoProfiles, lookupSSOToken,
+		aws.fancyConfig.Settings.SSOExpiryMarginDuration(), sessionIndicatorWait)
+	usage := ProfileUsage()
+	now := time.Now()
 
 	// Calculate the maximum length for alignment
 	maxNameLength := 0
 	for _, profile := range allConfiguredProfiles {
-		// Use the custom name from config if set, otherwise use the profile name
-		displayName := profile.ProfileName
-		if profile.Config.Name != "" {
-			displayName = profile.Config.Name
-		}
+		displayName := profileDisplayName(profile.ProfileName, profile.Config)
 
 		var prefixedName string
 		if profile.IsK9s {
@@ -365,17 +747,15 @@ func (aws *AWSManager) getProfilesWithMetadata() ([]ProfileDisplayInfo, error) {
 	}
 
 	// Second pass: format profiles with proper alignment
+	status := sessionStatus()
 	for _, profile := range allConfiguredProfiles {
-		metadata := aws.buildProfileMetadata(profile.Config)
+		metadata := withUsage(aws.buildProfileMetadata(profile.Config, credentials[profile.ProfileName], regions[profile.ProfileName]),
+			usage[profile.ProfileName], now)
 
 		var displayText string
 		var prefixedName string
 
-		// Use the custom name from config if set, otherwise use the profile name
-		displayName := profile.ProfileName
-		if profile.Config.Name != "" {
-			displayName = profile.Config.Name
-		}
+		displayName := profileDisplayName(profile.ProfileName, profile.Config)
 
 		if profile.IsK9s {
 			prefixedName = fmt.Sprintf("★ %s", displayName)
@@ -394,6 +774,7 @@ func (aws *AWSManager) getProfilesWithMetadata() ([]ProfileDisplayInfo, error) {
 		} else {
 			displayText = prefixedName
 		}
+		displayText = withSessionIndicator(displayText, profile.ProfileName, status)
 
 		profileInfo := ProfileDisplayInfo{
 			Name:         profile.ProfileName,
@@ -409,23 +790,6 @@ func (aws *AWSManager) getProfilesWithMetadata() ([]ProfileDisplayInfo, error) {
 		}
 	}
 
-	// Sort profiles by display name within each category
-	sort.Slice(k9sProfiles, func(i, j int) bool {
-		// Extract display name from DisplayText (remove prefix and metadata)
-		nameI := strings.TrimSpace(strings.Split(k9sProfiles[i].DisplayText, "|")[0])
-		nameJ := strings.TrimSpace(strings.Split(k9sProfiles[j].DisplayText, "|")[0])
-		nameI = strings.TrimPrefix(nameI, "★")
-		nameJ = strings.TrimPrefix(nameJ, "★")
-		return strings.TrimSpace(nameI) < strings.TrimSpace(nameJ)
-	})
-
-	sort.Slice(configuredProfiles, func(i, j int) bool {
-		// Extract display name from DisplayText (remove prefix and metadata)
-		nameI := strings.TrimSpace(strings.Split(configuredProfiles[i].DisplayText, "|")[0])
-		nameJ := strings.TrimSpace(strings.Split(configuredProfiles[j].DisplayText, "|")[0])
-		return strings.TrimSpace(nameI) < strings.TrimSpace(nameJ)
-	})
-
 	// Add k9s profiles first (most important for daily use)
 	if len(k9sProfiles) > 0 {
 		displayProfiles = append(displayProfiles, ProfileDisplayInfo{
@@ -456,6 +820,26 @@ func (aws *AWSManager) getProfilesWithMetadata() ([]ProfileDisplayInfo, error) {
 		displayProfiles = append(displayProfiles, configuredProfiles...)
 	}
 
+	// Add kube-only profiles in their own group
+	if kubeProfiles := aws.kubeOnlyDisplayProfiles(); len(kubeProfiles) > 0 {
+		if len(displayProfiles) > 0 {
+			displayProfiles = append(displayProfiles, ProfileDisplayInfo{
+				Name:         "---",
+				DisplayText:  "",
+				IsConfigured: false,
+				Metadata:     "",
+			})
+		}
+		displayProfiles = append(displayProfiles, ProfileDisplayInfo{
+			Name:         "---",
+			DisplayText:  "=== KUBERNETES ONLY (NO AWS) ===",
+			IsConfigured: false,
+			Metadata:     "",
+		})
+		displayProfiles = append(displayProfiles, kubeProfiles...)
+		configuredCount += len(kubeProfiles)
+	}
+
 	// Add separator if we have both configured and unconfigured profiles
 	unconfiguredProfiles := []string{}
 	for _, awsProfile := range awsProfiles {
@@ -464,8 +848,14 @@ func (aws *AWSManager) getProfilesWithMetadata() ([]ProfileDisplayInfo, error) {
 		}
 	}
 
-	// Sort unconfigured profiles alphabetically
+	// Sort unconfigured profiles alphabetically, or by recency first when
+	// the configured ones are
 	sort.Strings(unconfiguredProfiles)
+	if needsSortKey(aws.profileSort(), "recent") {
+		sort.SliceStable(unconfiguredProfiles, func(i, j int) bool {
+			return lastUsed(usage, unconfiguredProfiles[i]).After(lastUsed(usage, unconfiguredProfiles[j]))
+		})
+	}
 
 	if len(unconfiguredProfiles) > 0 {
 		if configuredCount > 0 {
@@ -485,11 +875,19 @@ func (aws *AWSManager) getProfilesWithMetadata() ([]ProfileDisplayInfo, error) {
 
 		// Add unconfigured profiles
 		for _, profileName := range unconfiguredProfiles {
+			displayText := fmt.Sprintf("           %s", profileName)
+			var metadata string
+			if kind := credentials[profileName]; kind != "" {
+				metadata = "| " + kind
+			}
+			if metadata = withUsage(metadata, usage[profileName], now); metadata != "" {
+				displayText += " " + metadata
+			}
 			displayProfiles = append(displayProfiles, ProfileDisplayInfo{
 				Name:         profileName,
-				DisplayText:  fmt.Sprintf("           %s", profileName),
+				DisplayText:  displayText,
 				IsConfigured: false,
-				Metadata:     "",
+				Metadata:     metadata,
 			})
 		}
 	} else if configuredCount > 0 {
@@ -511,42 +909,97 @@ func (aws *AWSManager) getProfilesWithMetadata() ([]ProfileDisplayInfo, error) {
 	return displayProfiles, nil
 }
 
-// getAWSConfigProfiles reads AWS profiles from ~/.aws/config
+// getAWSConfigProfiles returns the profile names from the AWS config
 func (aws *AWSManager) getAWSConfigProfiles() ([]string, error) {
-	homeDir, _ := os.UserHomeDir()
-	configPath := filepath.Join(homeDir, ".aws", "config")
+	return aws.snapshot.AWSProfileNames()
+}
 
-	file, err := os.Open(configPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open AWS config: %w", err)
+// muteMetadata renders the "| ECR | k8s:..." part of a picker line in the
+// muted theme color
+func muteMetadata(displayText string) string {
+	idx := strings.Index(displayText, "|")
+	if idx < 0 {
+		return displayText
 	}
-	defer file.Close()
+	return displayText[:idx] + config.Muted + displayText[idx:] + config.Reset
+}
 
-	var profiles []string
-	re := regexp.MustCompile(`^\[profile\s+(.+)\]`)
-	defaultRe := regexp.MustCompile(`^\[default\]`)
+// kubeOnlyDisplayProfiles returns picker entries for kube-only profiles,
+// sorted by display name. Names shadowed by an AWS profile config are skipped.
+func (aws *AWSManager) kubeOnlyDisplayProfiles() []ProfileDisplayInfo {
+	var kubeProfiles []ProfileDisplayInfo
+	for profileName, kube := range aws.fancyConfig.KubeOnlyProfiles {
+		if !aws.fancyConfig.IsKubeOnlyProfile(profileName) {
+			continue
+		}
 
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
+		displayName := profileName
+		if kube.Name != "" {
+			displayName = kube.Name
+		}
 
-		// Check for named profiles
-		if matches := re.FindStringSubmatch(line); len(matches) == 2 {
-			profiles = append(profiles, matches[1])
+		var parts []string
+		if kube.K8sContext != "" {
+			parts = append(parts, fmt.Sprintf("k8s:%s", kube.K8sContext))
 		}
-		// Check for default profile
-		if defaultRe.MatchString(line) {
-			profiles = append(profiles, "default")
+		if kube.K9sAutoLaunch {
+			parts = append(parts, "auto-k9s")
 		}
+
+		displayText := fmt.Sprintf("⎈ %s", displayName)
+		metadata := ""
+		if len(parts) > 0 {
+			metadata = fmt.Sprintf("| %s", strings.Join(parts, " | "))
+			displayText = fmt.Sprintf("%s %s", displayText, metadata)
+		}
+
+		kubeProfiles = append(kubeProfiles, ProfileDisplayInfo{
+			Name:         profileName,
+			DisplayText:  displayText,
+			IsConfigured: true,
+			Metadata:     metadata,
+			KubeOnly:     true,
+		})
 	}
 
-	return profiles, scanner.Err()
+	sort.Slice(kubeProfiles, func(i, j int) bool {
+		return kubeProfiles[i].DisplayText < kubeProfiles[j].DisplayText
+	})
+	return kubeProfiles
 }
 
-// buildProfileMetadata creates a display string with profile configuration info
-func (aws *AWSManager) buildProfileMetadata(config config.ProfileConfig) string {
+// profileDisplayName is the picker name of a configured profile: its custom
+// name, else the profile name, followed by its primary alias
+func profileDisplayName(profile string, pc config.ProfileConfig) string {
+	name := profile
+	if pc.Name != "" {
+		name = pc.Name
+	}
+	if len(pc.Aliases) > 0 {
+		name = fmt.Sprintf("%s (%s)", name, pc.Aliases[0])
+	}
+	return name
+}
+
+// buildProfileMetadata creates a display string with profile configuration
+// info; credentials names where credentials come from if not from SSO,
+// e.g. "external process", and region is the one the profile sets in the
+// AWS config
+func (aws *AWSManager) buildProfileMetadata(config config.ProfileConfig, credentials, region string) string {
 	var parts []string
 
+	if credentials != "" {
+		parts = append(parts, credentials)
+	}
+
+	if config.AccountAlias != "" {
+		parts = append(parts, config.AccountAlias)
+	}
+
+	if region != "" {
+		parts = append(parts, region)
+	}
+
 	if config.ECRLogin {
 		parts = append(parts, "ECR")
 	}
@@ -566,82 +1019,209 @@ func (aws *AWSManager) buildProfileMetadata(config config.ProfileConfig) string
 	return fmt.Sprintf("| %s", strings.Join(parts, " | "))
 }
 
-// isSessionValid checks if the AWS session is valid for the given profile
-func (aws *AWSManager) isSessionValid(profile string) bool {
-	cmd := exec.Command("aws", "sts", "get-caller-identity", "--profile", profile, "--query", "Account", "--output", "text")
-	cmd.Stdout = nil
-	cmd.Stderr = nil
-	return cmd.Run() == nil
+// withUsage appends when the profile was last logged in to, if ever, to
+// its picker metadata
+func withUsage(metadata string, usage *state.ProfileUsage, now time.Time) string {
+	if usage == nil || usage.LastUsed.IsZero() {
+		return metadata
+	}
+	used := "used " + FormatAge(usage.LastUsed, now)
+	if metadata == "" {
+		return "| " + used
+	}
+	return metadata + " · " + used
 }
 
-// isSSOMProfile checks if the profile is an SSO profile
-func (aws *AWSManager) isSSOMProfile(profile string) (bool, error) {
-	homeDir, _ := os.UserHomeDir()
-	configPath := filepath.Join(homeDir, ".aws", "config")
-
-	file, err := os.Open(configPath)
-	if err != nil {
-		return false, err
+// lastUsed is when profile was last logged in to, zero if never
+func lastUsed(usage map[string]*state.ProfileUsage, profile string) time.Time {
+	if u := usage[profile]; u != nil {
+		return u.LastUsed
 	}
-	defer file.Close()
+	return time.Time{}
+}
+
+// isSessionValid checks if the AWS session is valid for the given profile
+func (aws *AWSManager) isSessionValid(ctx context.Context, profile string) bool {
+	return aws.checkSession(ctx, profile) == nil
+}
 
-	scanner := bufio.NewScanner(file)
-	inProfile := false
-	profilePattern := fmt.Sprintf("[profile %s]", profile)
+// checkSession calls GetCallerIdentity for the profile. A failure matches
+// ErrSessionExpired or is an *STSNetworkError when STS was called directly,
+// or an *exec.ExitError carrying stderr when the aws CLI answered.
+func (aws *AWSManager) checkSession(ctx context.Context, profile string) error {
+	_, err := aws.callerIdentity(ctx, profile)
+	return err
+}
 
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
+// callerIdentity asks STS who the session of profile belongs to. A
+// timeout is returned as a *utils.TimeoutError naming the call.
+func (aws *AWSManager) callerIdentity(ctx context.Context, profile string) (CallerIdentity, error) {
+	timeout := aws.stsTimeout(profile)
+	ctx, cancel := utils.WithStepTimeout(ctx, timeout)
+	defer cancel()
 
-		if line == profilePattern {
-			inProfile = true
-			continue
-		}
+	identity, err := aws.sts.CallerIdentity(ctx, profile)
+	return identity, utils.StepError(ctx, "aws sts get-caller-identity", timeout, err)
+}
 
-		if strings.HasPrefix(line, "[") && inProfile {
-			break
+// stsTimeout is the network timeout for an STS call with profile, unless a
+// credential_process helper or aws-vault runs first, which may wait for
+// the user. For a role chain that includes the source profiles, since the
+// CLI runs the helper of the one that logs in.
+func (aws *AWSManager) stsTimeout(profile string) time.Duration {
+	// A broken chain fails the call anyway; its first link is still checked
+	chain, _ := aws.roleChain(profile)
+	for _, name := range append([]string{profile}, chain...) {
+		if aws.usesAWSVault(name) {
+			return aws.fancyConfig.Settings.AWSTimeoutDuration()
+		}
+		if p, ok := aws.snapshot.AWSProfile(name); ok && !p.IsSSO && p.CredentialProcess != "" {
+			return aws.fancyConfig.Settings.AWSTimeoutDuration()
 		}
+	}
+	return aws.fancyConfig.Settings.AWSNetworkTimeoutDuration()
+}
 
-		if inProfile && strings.Contains(line, "sso_") {
-			return true, nil
+// roleChain returns the source profiles profile assumes its role through,
+// nearest first, ending with the one that logs in. A cycle or a missing
+// source profile is an error.
+func (aws *AWSManager) roleChain(profile string) ([]string, error) {
+	var chain []string
+	seen := map[string]bool{profile: true}
+	for {
+		p, ok := aws.snapshot.AWSProfile(profile)
+		if !ok || !p.IsRoleChain() {
+			return chain, nil
 		}
+		source := p.SourceProfile
+		if seen[source] {
+			return nil, fmt.Errorf("source_profile of %s leads back to %s", profile, source)
+		}
+		if _, ok := aws.snapshot.AWSProfile(source); !ok {
+			return nil, fmt.Errorf("source_profile %s of %s is not in the AWS config", source, profile)
+		}
+		seen[source] = true
+		chain = append(chain, source)
+		profile = source
 	}
+}
 
-	return false, scanner.Err()
+// cachedSessionValid decides from the SSO token cache whether the session
+// of profile is valid: its token must have more than sso_expiry_margin
+// left. decided is false when the cache can't tell, for profiles without
+// SSO, with the aws-vault backend, whose cache is its own, or without a
+// cached token. A token revoked server-side still counts
+// as valid here.
+func (aws *AWSManager) cachedSessionValid(profile string, now time.Time) (valid, decided bool) {
+	p, ok := aws.snapshot.AWSProfile(profile)
+	if !ok || !p.IsSSO || aws.usesAWSVault(profile) {
+		return false, false
+	}
+	token := lookupSSOToken(p)
+	if !token.Found {
+		return false, false
+	}
+	remaining := FormatRemaining(token.ExpiresAt, now)
+	if token.ExpiresAt.Sub(now) > aws.fancyConfig.Settings.SSOExpiryMarginDuration() {
+		aws.logger.FancyLog(fmt.Sprintf("Cached SSO token of %s is valid (%s)", profile, remaining))
+		return true, true
+	}
+	aws.logger.FancyLog(fmt.Sprintf("Cached SSO token of %s is about to expire or has expired (%s)", profile, remaining))
+	return false, true
 }
 
-// performSSOMLogin performs AWS SSO login
-func (aws *AWSManager) performSSOMLogin(profile string) error {
+// isSSOMProfile checks if the profile is an SSO profile. A profile whose
+// sso_session names no [sso-session] section with a start URL is an error,
+// since `aws sso login` can't log in to it either.
+func (aws *AWSManager) isSSOMProfile(profile string) (bool, error) {
+	if _, err := aws.snapshot.AWSProfiles(); err != nil {
+		return false, err
+	}
+	p, _ := aws.snapshot.AWSProfile(profile)
+	if p.SSOSession != "" && p.SSOStartURL == "" {
+		return false, utils.WithExitCode(utils.ExitConfig, fmt.Errorf("profile %s uses sso_session %s, but %s has no [sso-session %s] section with an sso_start_url", profile, p.SSOSession, config.GetAWSConfigPath(), p.SSOSession))
+	}
+	return p.IsSSO, nil
+}
+
+// performSSOMLogin performs AWS SSO login. Profiles sharing an sso-session
+// log in to the session once per run: a later profile using it is only
+// verified.
+func (aws *AWSManager) performSSOMLogin(ctx context.Context, profile string) error {
+	p, _ := aws.snapshot.AWSProfile(profile)
+	if session := p.SSOSession; session != "" && aws.ssoSessionsLoggedIn[session] {
+		aws.logger.FancyLog(fmt.Sprintf("SSO session %s was logged in to during this run, reusing it for %s", session, profile))
+		if aws.isSessionValid(ctx, profile) {
+			aws.logger.LogSuccess(fmt.Sprintf("AWS SSO login successful for %s.", profile))
+			return nil
+		}
+		aws.logger.FancyLog(fmt.Sprintf("The session doesn't cover %s, logging in again", profile))
+	}
+
 	aws.logger.FancyLog(fmt.Sprintf("SSO profile detected. Session expired or not found for %s.", profile))
 	aws.logger.FancyLog(fmt.Sprintf("Attempting SSO login for profile %s...", profile))
 
-	var cmd *exec.Cmd
-	if !aws.config.FancyVerbose {
-		spinner := utils.NewSpinner("🔑 AWS SSO login...")
+	timeout := aws.fancyConfig.Settings.AWSTimeoutDuration()
+	loginCtx, cancel := utils.WithStepTimeout(ctx, timeout)
+	defer cancel()
+
+	// Log in to the sso-session itself, so the token covers every profile
+	// using it. Without a terminal nobody can use a browser here; the
+	// device code URL is printed instead, so it shows up in CI logs.
+	args := []string{"sso", "login", "--profile", profile}
+	if p.SSOSession != "" {
+		args = []string{"sso", "login", "--sso-session", p.SSOSession}
+	}
+	if aws.noBrowser || !prompt.Interactive() {
+		args = append(args, "--no-browser")
+	}
+	cmd := utils.CommandContext(loginCtx, "aws", args...)
+
+	// Under WSL the CLI's browser launch fails silently, so point it at a
+	// helper that reaches the Windows browser
+	if os.Getenv("BROWSER") == "" {
+		if browser := platform.Detect(platform.DefaultRunner).BrowserEnv(); browser != "" {
+			aws.logger.FancyLog(fmt.Sprintf("WSL detected, using BROWSER=%s", browser))
+			cmd.Env = append(os.Environ(), "BROWSER="+browser)
+		}
+	}
+	if !aws.config.FancyVerbose && prompt.Interactive() {
+		spinner := utils.NewSpinner(aws.logger.Output(), "🔑 AWS SSO login...")
 		spinner.Start()
 
-		cmd = exec.Command("aws", "sso", "login", "--profile", profile)
-		cmd.Stdout = nil
-		cmd.Stderr = nil
+		// The CLI's output stays hidden, except for the URL and code a
+		// login in a browser elsewhere needs
+		filter := &deviceAuthFilter{show: spinner.Println}
+		cmd.Stdout = filter
+		cmd.Stderr = filter
 
 		err := cmd.Run()
+		filter.Flush()
 		spinner.Stop()
 
 		if err != nil {
-			aws.logger.Die(fmt.Sprintf("AWS SSO login failed for %s.", profile))
+			return utils.StepError(loginCtx, "aws sso login", timeout,
+				fmt.Errorf("AWS SSO login failed for %s", profile))
 		}
 	} else {
-		cmd = exec.Command("aws", "sso", "login", "--profile", profile)
-		cmd.Stdout = os.Stdout
+		cmd.Stdout = aws.logger.Output()
 		cmd.Stderr = os.Stderr
 
 		if err := cmd.Run(); err != nil {
-			aws.logger.Die(fmt.Sprintf("AWS SSO login failed for %s.", profile))
+			return utils.StepError(loginCtx, "aws sso login", timeout,
+				fmt.Errorf("AWS SSO login failed for %s", profile))
 		}
 	}
 
 	// Verify login
-	if !aws.isSessionValid(profile) {
-		aws.logger.Die(fmt.Sprintf("AWS SSO login verification failed for %s.", profile))
+	if !aws.isSessionValid(ctx, profile) {
+		return fmt.Errorf("AWS SSO login verification failed for %s", profile)
+	}
+	if p.SSOSession != "" {
+		if aws.ssoSessionsLoggedIn == nil {
+			aws.ssoSessionsLoggedIn = make(map[string]bool)
+		}
+		aws.ssoSessionsLoggedIn[p.SSOSession] = true
 	}
 
 	aws.logger.LogSuccess(fmt.Sprintf("AWS SSO login successful for %s.", profile))
@@ -649,31 +1229,70 @@ func (aws *AWSManager) performSSOMLogin(profile string) error {
 }
 
 // getAccountID gets the AWS account ID for a profile
-func (aws *AWSManager) getAccountID(profile string) (string, error) {
-	cmd := exec.Command("aws", "sts", "get-caller-identity", "--profile", profile, "--query", "Account", "--output", "text")
-	output, err := cmd.Output()
+func (aws *AWSManager) getAccountID(ctx context.Context, profile string) (string, error) {
+	if accountID, ok := aws.accountPrefetch.accountID(ctx, profile); ok {
+		aws.logger.FancyLog(fmt.Sprintf("Using the account ID prefetched for %s: %s", profile, accountID))
+		return accountID, nil
+	}
+
+	identity, err := aws.callerIdentity(ctx, profile)
 	if err != nil {
 		return "", err
 	}
-	return strings.TrimSpace(string(output)), nil
+	return identity.Account, nil
 }
 
-// exportProfileToTemp exports the AWS profile to a temp file for shell integration
+// exportProfileToTemp exports the AWS profile to a temp file for shell
+// integration: AWS_PROFILE, or an aws alias running through aws-vault if
+// the profile asks for one
 func (aws *AWSManager) exportProfileToTemp(profile string) error {
-	if runtime.GOOS == "windows" {
-		// Create both PowerShell and batch files for Windows
-		psContent := fmt.Sprintf("$env:AWS_PROFILE=\"%s\"\n", profile)
-		if err := os.WriteFile(aws.config.AWSProfileTemp, []byte(psContent), 0644); err != nil {
+	scripts := platform.ProfileScripts(aws.config.AWSProfileTemp, profile)
+	export := "AWS_PROFILE=" + profile
+	if aws.fancyConfig.ShouldExportVaultAlias(profile) {
+		scripts = platform.VaultProfileScripts(aws.config.AWSProfileTemp, profile, vaultAlias(profile))
+		export = fmt.Sprintf("alias aws='%s'", vaultAlias(profile))
+	}
+	if aws.dryRun {
+		paths := make([]string, len(scripts))
+		for i, script := range scripts {
+			paths[i] = script.Path
+		}
+		aws.logger.LogPlanned(fmt.Sprintf("export %s to %s", export, strings.Join(paths, ", ")))
+		return nil
+	}
+	return writeProfileScripts(scripts, 0644)
+}
+
+// writeProfileScripts writes the shell export files with perm, also when
+// they already exist with another one. Each goes through a temporary file
+// that has perm before anything is written to it, so exported credentials
+// are never readable by others, not even briefly.
+func writeProfileScripts(scripts []platform.Script, perm os.FileMode) error {
+	for _, script := range scripts {
+		if err := writeFileWithPerm(script.Path, []byte(script.Content), perm); err != nil {
 			return err
 		}
+	}
+	return nil
+}
 
-		// Also create a .bat file for Command Prompt users
-		batFile := strings.Replace(aws.config.AWSProfileTemp, ".ps1", ".bat", 1)
-		batContent := fmt.Sprintf("set AWS_PROFILE=%s\n", profile)
-		return os.WriteFile(batFile, []byte(batContent), 0644)
-	} else {
-		// Unix shell script format
-		content := fmt.Sprintf("export AWS_PROFILE=%s\n", profile)
-		return os.WriteFile(aws.config.AWSProfileTemp, []byte(content), 0644)
+// writeFileWithPerm atomically replaces path with data and perm
+func writeFileWithPerm(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+"-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
 	}
+	return os.Rename(tmp.Name(), path)
 }
@@ -1,11 +1,15 @@
 package aws
 
 import (
+	"os"
+	"path/filepath"
 	"sort"
 	"strings"
 	"testing"
+	"time"
 
 	"fancy-login/internal/config"
+	"fancy-login/internal/utils"
 )
 
 func TestProfileDisplayInfo_Sorting(t *testing.T) {
@@ -267,6 +271,26 @@ func TestCustomDisplayName(t *testing.T) {
 	}
 }
 
+func TestProfileDisplayName(t *testing.T) {
+	testCases := []struct {
+		name     string
+		pc       config.ProfileConfig
+		expected string
+	}{
+		{"Profile name", config.ProfileConfig{}, "prod-123"},
+		{"Custom name", config.ProfileConfig{Name: "Production"}, "Production"},
+		{"Primary alias", config.ProfileConfig{Name: "Production", Aliases: []string{"prod", "p"}}, "Production (prod)"},
+		{"Alias without custom name", config.ProfileConfig{Aliases: []string{"prod"}}, "prod-123 (prod)"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := profileDisplayName("prod-123", tc.pc); got != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
 func TestProfileDisplaySeparators(t *testing.T) {
 	// Test that separator selection is properly handled
 	selectedProfile := ""
@@ -279,3 +303,268 @@ func TestProfileDisplaySeparators(t *testing.T) {
 		}
 	}
 }
+
+func TestMuteMetadata(t *testing.T) {
+	defer config.ApplyTheme(config.DefaultThemeName)
+	config.ApplyTheme(config.DefaultThemeName)
+
+	testCases := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"With metadata", "  Dev | ECR", "  Dev " + config.Muted + "| ECR" + config.Reset},
+		{"Without metadata", "           plain-profile", "           plain-profile"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := muteMetadata(tc.input); got != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestManagerParsesAWSConfigOnce(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("AWS_CONFIG_FILE", "")
+	awsConfigPath := filepath.Join(home, ".aws", "config")
+	if err := os.MkdirAll(filepath.Dir(awsConfigPath), 0700); err != nil {
+		t.Fatal(err)
+	}
+	awsConfig := "[profile dev]\nsso_start_url = https://acme.awsapps.com/start\nsso_region = eu-central-1\n\n[profile ops]\nregion = eu-west-1\n"
+	if err := os.WriteFile(awsConfigPath, []byte(awsConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	reads := 0
+	snapshot := config.NewSnapshot(func(path string) ([]byte, error) {
+		if path == awsConfigPath {
+			reads++
+		}
+		return os.ReadFile(path)
+	})
+	fancyConfig, err := snapshot.FancyConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	manager := NewAWSManager(config.NewConfig(), utils.NewLogger(false), fancyConfig)
+	manager.SetSnapshot(snapshot)
+
+	if _, err := manager.ProfileList(); err != nil {
+		t.Fatal(err)
+	}
+	if names, err := manager.getAWSConfigProfiles(); err != nil || len(names) != 2 {
+		t.Errorf("Expected 2 profiles, got %v, %v", names, err)
+	}
+	if isSSO, err := manager.isSSOMProfile("dev"); err != nil || !isSSO {
+		t.Errorf("Expected dev to be an SSO profile, got %v, %v", isSSO, err)
+	}
+	if url := manager.ssoStartURL("dev"); url != "https://acme.awsapps.com/start" {
+		t.Errorf("Expected the start URL of dev, got %q", url)
+	}
+	if region := fancyConfig.RegionForProfile("ops"); region != "eu-west-1" {
+		t.Errorf("Expected eu-west-1, got %q", region)
+	}
+
+	if reads != 1 {
+		t.Errorf("Expected the AWS config to be read once, got %d", reads)
+	}
+}
+
+func TestECRRegion(t *testing.T) {
+	testCases := []struct {
+		name           string
+		override       string
+		ecrRegion      string
+		profileRegion  string
+		defaultRegion  string
+		env            string
+		expected       string
+		expectedSource config.RegionSource
+	}{
+		{"Flag wins", "us-west-2", "eu-west-1", "eu-north-1", "eu-central-1", "ap-south-1", "us-west-2", config.RegionFromFlag},
+		{"Profile ecr_region", "", "eu-west-1", "eu-north-1", "eu-central-1", "ap-south-1", "eu-west-1", config.RegionFromConfig},
+		{"Region of the AWS profile", "", "", "eu-north-1", "eu-central-1", "ap-south-1", "eu-north-1", config.RegionFromProfile},
+		{"AWS_REGION", "", "", "", "eu-central-1", "ap-south-1", "ap-south-1", config.RegionFromEnv},
+		{"default_region setting", "", "", "", "eu-central-1", "", "eu-central-1", config.RegionFromDefault},
+		{"Built-in default", "", "", "", "", "", "eu-central-1", config.RegionFromDefault},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("AWS_REGION", tc.env)
+			t.Setenv("FANCY_DEFAULT_REGION", "")
+			awsConfig := "[profile dev]\nsso_start_url = https://dev.awsapps.com/start\n"
+			if tc.profileRegion != "" {
+				awsConfig += "region = " + tc.profileRegion + "\n"
+			}
+			awsConfigPath := filepath.Join(t.TempDir(), "config")
+			if err := os.WriteFile(awsConfigPath, []byte(awsConfig), 0600); err != nil {
+				t.Fatal(err)
+			}
+			t.Setenv("AWS_CONFIG_FILE", awsConfigPath)
+			fancyConfig := &config.FancyConfig{
+				ProfileConfigs: map[string]config.ProfileConfig{"dev": {ECRRegion: tc.ecrRegion}},
+				Settings:       config.GlobalSettings{DefaultRegion: tc.defaultRegion},
+			}
+			manager := NewAWSManager(config.NewConfig(), utils.NewLogger(false), fancyConfig)
+			manager.SetECRRegionOverride(tc.override)
+
+			region, source := manager.ECRRegion("dev")
+			if region != tc.expected || source != tc.expectedSource {
+				t.Errorf("Expected %s from %s, got %s from %s", tc.expected, tc.expectedSource, region, source)
+			}
+			if host := manager.ecrRegistry("dev", "123456789012").Host(); !strings.Contains(host, "."+tc.expected+".") {
+				t.Errorf("Expected the registry in %s, got %s", tc.expected, host)
+			}
+		})
+	}
+}
+
+func TestCachedSessionValid(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("AWS_CONFIG_FILE", "")
+	awsConfig := "[profile dev]\nsso_start_url = https://dev.awsapps.com/start\n\n" +
+		"[profile prod]\nsso_start_url = https://prod.awsapps.com/start\n\n" +
+		"[profile fresh]\nsso_start_url = https://fresh.awsapps.com/start\n\n" +
+		"[profile static]\nregion = eu-west-1\n"
+	cacheDir := filepath.Join(home, ".aws", "sso", "cache")
+	if err := os.MkdirAll(cacheDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".aws", "config"), []byte(awsConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tokens := map[string]time.Time{
+		"dev":  now.Add(2 * time.Hour),
+		"prod": now.Add(3 * time.Minute),
+	}
+	for name, expiresAt := range tokens {
+		token := `{"startUrl": "https://` + name + `.awsapps.com/start", "accessToken": "x", "expiresAt": "` + expiresAt.Format(time.RFC3339) + `"}`
+		if err := os.WriteFile(filepath.Join(cacheDir, name+".json"), []byte(token), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	testCases := []struct {
+		profile         string
+		margin          string
+		expectedValid   bool
+		expectedDecided bool
+	}{
+		{"dev", "", true, true},
+		{"dev", "3h", false, true},
+		{"prod", "", false, true},
+		{"prod", "1m", true, true},
+		{"fresh", "", false, false},
+		{"static", "", false, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.profile+" "+tc.margin, func(t *testing.T) {
+			fancyConfig := config.DefaultFancyConfig()
+			fancyConfig.Settings.SSOExpiryMargin = tc.margin
+			manager := NewAWSManager(config.NewConfig(), utils.NewLogger(false), fancyConfig)
+
+			valid, decided := manager.cachedSessionValid(tc.profile, now)
+			if valid != tc.expectedValid || decided != tc.expectedDecided {
+				t.Errorf("Expected valid=%v decided=%v, got valid=%v decided=%v", tc.expectedValid, tc.expectedDecided, valid, decided)
+			}
+		})
+	}
+}
+
+func TestIsSSOMProfileWithSSOSession(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("AWS_CONFIG_FILE", "")
+	awsConfigPath := filepath.Join(home, ".aws", "config")
+	if err := os.MkdirAll(filepath.Dir(awsConfigPath), 0700); err != nil {
+		t.Fatal(err)
+	}
+	awsConfig := "[profile dev]\nsso_session = acme\nsso_account_id = 123456789012\n\n" +
+		"[sso-session acme]\nsso_start_url = https://acme.awsapps.com/start\nsso_region = eu-central-1\n\n" +
+		"[profile orphan]\nsso_session = missing\n\n" +
+		"[profile ops]\nregion = eu-west-1\n"
+	if err := os.WriteFile(awsConfigPath, []byte(awsConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+	manager := NewAWSManager(config.NewConfig(), utils.NewLogger(false), config.DefaultFancyConfig())
+
+	testCases := []struct {
+		profile      string
+		expectedSSO  bool
+		expectedCode int
+	}{
+		{"dev", true, utils.ExitOK},
+		{"ops", false, utils.ExitOK},
+		{"orphan", false, utils.ExitConfig},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.profile, func(t *testing.T) {
+			isSSO, err := manager.isSSOMProfile(tc.profile)
+			if isSSO != tc.expectedSSO {
+				t.Errorf("Expected SSO %v, got %v", tc.expectedSSO, isSSO)
+			}
+			if code := utils.ExitCode(err, utils.ExitOK); code != tc.expectedCode {
+				t.Errorf("Expected exit code %d, got %d (%v)", tc.expectedCode, code, err)
+			}
+		})
+	}
+	if url := manager.ssoStartURL("dev"); url != "https://acme.awsapps.com/start" {
+		t.Errorf("Expected the session's start URL for the portal probe, got %q", url)
+	}
+}
+
+func TestAWSConfigFileEnv(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	homeConfig := filepath.Join(home, ".aws", "config")
+	if err := os.MkdirAll(filepath.Dir(homeConfig), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(homeConfig, []byte("[profile personal]\nregion = us-east-1\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	workConfig := filepath.Join(t.TempDir(), "work-config")
+	awsConfig := "[profile work-dev]\nsso_start_url = https://work.awsapps.com/start\nsso_region = eu-central-1\n\n" +
+		"[profile work-ops]\nregion = eu-west-1\n"
+	if err := os.WriteFile(workConfig, []byte(awsConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AWS_CONFIG_FILE", workConfig)
+
+	manager := NewAWSManager(config.NewConfig(), utils.NewLogger(false), config.DefaultFancyConfig())
+	names, err := manager.getAWSConfigProfiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Without a path, parsing falls back to the file the wizard reads
+	parsed, err := config.ParseAWSProfiles("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wizardNames []string
+	for _, p := range parsed {
+		wizardNames = append(wizardNames, p.Name)
+	}
+	sort.Strings(names)
+	sort.Strings(wizardNames)
+	if strings.Join(names, ",") != "work-dev,work-ops" || strings.Join(wizardNames, ",") != "work-dev,work-ops" {
+		t.Errorf("Expected both to see work-dev and work-ops, got %v and %v", names, wizardNames)
+	}
+
+	if isSSO, err := manager.isSSOMProfile("work-dev"); err != nil || !isSSO {
+		t.Errorf("Expected work-dev to be an SSO profile, got %v, %v", isSSO, err)
+	}
+	if isSSO, _ := manager.isSSOMProfile("personal"); isSSO {
+		t.Error("Expected profiles of ~/.aws/config to be ignored")
+	}
+}
@@ -0,0 +1,132 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// CredentialHelperName is the name fancy-login is registered under in
+// docker's credHelpers; docker runs it as docker-credential-fancy-login
+const CredentialHelperName = "fancy-login"
+
+// profileRegistries returns the private registries profile logs in to: its
+// ecr_registries, or else the registry of its account_id or sso_account_id.
+// It is empty when neither is configured.
+func (aws *AWSManager) profileRegistries(profile string) []Registry {
+	if registries := aws.configuredECRRegistries(profile); len(registries) > 0 {
+		return registries
+	}
+	if accountID := aws.configuredAccountID(profile); accountID != "" {
+		return []Registry{aws.ecrRegistry(profile, accountID)}
+	}
+	return nil
+}
+
+// HelperRegistries returns the registries a credential helper can fetch
+// tokens for: those of every profile with ecr_login, sorted by host
+func (aws *AWSManager) HelperRegistries() []Registry {
+	seen := make(map[string]bool)
+	var registries []Registry
+	for profile := range aws.fancyConfig.ProfileConfigs {
+		if !aws.fancyConfig.ShouldPerformECRLogin(profile) {
+			continue
+		}
+		for _, registry := range aws.profileRegistries(profile) {
+			if !seen[registry.Host()] {
+				seen[registry.Host()] = true
+				registries = append(registries, registry)
+			}
+		}
+	}
+	sort.Slice(registries, func(i, j int) bool { return registries[i].Host() < registries[j].Host() })
+	return registries
+}
+
+// ProfileForRegistry returns the profile to fetch a token for registry
+// with. When several profiles log in to it, the exported AWS_PROFILE wins,
+// then the one profile the registry's account belongs to.
+func (aws *AWSManager) ProfileForRegistry(registry Registry) (string, error) {
+	var candidates, owners []string
+	for profile := range aws.fancyConfig.ProfileConfigs {
+		if !aws.fancyConfig.ShouldPerformECRLogin(profile) {
+			continue
+		}
+		if !slices.ContainsFunc(aws.profileRegistries(profile), func(r Registry) bool { return r.Host() == registry.Host() }) {
+			continue
+		}
+		candidates = append(candidates, profile)
+		if aws.configuredAccountID(profile) == registry.AccountID {
+			owners = append(owners, profile)
+		}
+	}
+	sort.Strings(candidates)
+
+	switch {
+	case len(candidates) == 0:
+		return "", fmt.Errorf("no profile with ecr_login logs in to %s; set account_id or ecr_registries on the profile to use", registry.Host())
+	case len(candidates) == 1:
+		return candidates[0], nil
+	case slices.Contains(candidates, os.Getenv("AWS_PROFILE")):
+		return os.Getenv("AWS_PROFILE"), nil
+	case len(owners) == 1:
+		return owners[0], nil
+	}
+	return "", fmt.Errorf("profiles %s all log in to %s; export AWS_PROFILE to choose one", strings.Join(candidates, ", "), registry.Host())
+}
+
+// RegistryPassword returns a fresh ECR token for the registry at host,
+// fetched with the profile ProfileForRegistry picks. An expired SSO session
+// is logged in to again, as for credential_process.
+func (aws *AWSManager) RegistryPassword(ctx context.Context, host string) (string, error) {
+	registry, err := ParseRegistry(host)
+	if err != nil {
+		return "", err
+	}
+	profile, err := aws.ProfileForRegistry(registry)
+	if err != nil {
+		return "", err
+	}
+	aws.logger.FancyLog(fmt.Sprintf("Fetching a token for %s with %s", registry.Host(), profile))
+	if err := aws.refreshSSOSession(ctx, profile); err != nil {
+		return "", err
+	}
+	return aws.getLoginPassword(ctx, profile, registry)
+}
+
+// InstallCredentialHelper registers fancy-login as docker's credential
+// helper for hosts in ~/.docker/config.json, keeping everything else in
+// the file. It returns the path written.
+func InstallCredentialHelper(hosts []string) (string, error) {
+	configPath := dockerConfigPath()
+
+	raw := make(map[string]interface{})
+	if data, err := os.ReadFile(configPath); err == nil {
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return configPath, fmt.Errorf("failed to parse %s: %w", configPath, err)
+		}
+	}
+
+	helpers, _ := raw["credHelpers"].(map[string]interface{})
+	if helpers == nil {
+		helpers = make(map[string]interface{})
+	}
+	for _, host := range hosts {
+		helpers[host] = CredentialHelperName
+	}
+	raw["credHelpers"] = helpers
+
+	data, err := json.MarshalIndent(raw, "", "\t")
+	if err != nil {
+		return configPath, err
+	}
+	if err := os.MkdirAll(filepath.Dir(configPath), 0700); err != nil {
+		return configPath, fmt.Errorf("failed to create docker config directory: %w", err)
+	}
+	return configPath, os.WriteFile(configPath, append(data, '\n'), 0600)
+}
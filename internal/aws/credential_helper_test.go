@@ -0,0 +1,68 @@
+package aws
+
+import (
+	"testing"
+
+	"fancy-login/internal/config"
+	"fancy-login/internal/utils"
+)
+
+func TestProfileForRegistry(t *testing.T) {
+	shared := config.ECRRegistryConfig{AccountID: "111111111111", Region: "eu-west-1"}
+	fancyConfig := config.DefaultFancyConfig()
+	fancyConfig.ProfileConfigs = map[string]config.ProfileConfig{
+		"dev":    {ECRLogin: true, AccountID: "222222222222", ECRRegion: "eu-west-1", ECRRegistries: []config.ECRRegistryConfig{shared, {AccountID: "222222222222"}}},
+		"prod":   {ECRLogin: true, AccountID: "333333333333", ECRRegion: "eu-west-1", ECRRegistries: []config.ECRRegistryConfig{shared, {AccountID: "333333333333"}}},
+		"shared": {ECRLogin: true, AccountID: "111111111111", ECRRegion: "eu-west-1"},
+		"nolog":  {AccountID: "444444444444", ECRRegion: "eu-west-1"},
+	}
+
+	testCases := []struct {
+		name       string
+		host       string
+		awsProfile string
+		expected   string
+		expectErr  bool
+	}{
+		{"Only profile", "222222222222.dkr.ecr.eu-west-1.amazonaws.com", "", "dev", false},
+		{"Owner of a shared registry", "111111111111.dkr.ecr.eu-west-1.amazonaws.com", "", "shared", false},
+		{"AWS_PROFILE wins", "111111111111.dkr.ecr.eu-west-1.amazonaws.com", "prod", "prod", false},
+		{"Other region", "222222222222.dkr.ecr.us-east-1.amazonaws.com", "", "", true},
+		{"Profile without ecr_login", "444444444444.dkr.ecr.eu-west-1.amazonaws.com", "", "", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("AWS_PROFILE", tc.awsProfile)
+			t.Setenv("AWS_REGION", "")
+			manager := NewAWSManager(config.NewConfig(), utils.NewLogger(false), fancyConfig)
+			registry, err := ParseRegistry(tc.host)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			profile, err := manager.ProfileForRegistry(registry)
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if profile != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, profile)
+			}
+		})
+	}
+}
+
+func TestProfileForRegistryAmbiguous(t *testing.T) {
+	t.Setenv("AWS_PROFILE", "")
+	shared := config.ECRRegistryConfig{AccountID: "111111111111", Region: "eu-west-1"}
+	fancyConfig := config.DefaultFancyConfig()
+	fancyConfig.ProfileConfigs = map[string]config.ProfileConfig{
+		"dev":  {ECRLogin: true, AccountID: "222222222222", ECRRegistries: []config.ECRRegistryConfig{shared}},
+		"prod": {ECRLogin: true, AccountID: "333333333333", ECRRegistries: []config.ECRRegistryConfig{shared}},
+	}
+	manager := NewAWSManager(config.NewConfig(), utils.NewLogger(false), fancyConfig)
+
+	if _, err := manager.ProfileForRegistry(Registry{AccountID: "111111111111", Region: "eu-west-1"}); err == nil {
+		t.Error("Expected an error when neither AWS_PROFILE nor the account picks a profile")
+	}
+}
@@ -0,0 +1,85 @@
+package aws
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"fancy-login/internal/config"
+	"fancy-login/internal/utils"
+)
+
+// credentialProcessOutput is the JSON a credential_process helper prints
+type credentialProcessOutput struct {
+	Version         int    `json:"Version"`
+	AccessKeyID     string `json:"AccessKeyId"`
+	SecretAccessKey string `json:"SecretAccessKey"`
+	SessionToken    string `json:"SessionToken,omitempty"`
+	// Expiration is RFC 3339; long-term credentials have none
+	Expiration string `json:"Expiration,omitempty"`
+}
+
+// runCredentialProcess runs a profile's credential_process helper the way
+// the AWS CLI does and checks its output. The helper's stderr becomes the
+// error message, since the CLI only reports that "the process failed".
+func runCredentialProcess(ctx context.Context, command string) error {
+	args, err := config.SplitCommand(command)
+	if err != nil {
+		return fmt.Errorf("invalid credential_process %q: %w", command, err)
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("credential_process is empty")
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := utils.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%s: %s", args[0], msg)
+		}
+		return fmt.Errorf("%s: %w", args[0], err)
+	}
+
+	var output credentialProcessOutput
+	if err := json.Unmarshal(stdout.Bytes(), &output); err != nil {
+		return fmt.Errorf("%s printed invalid credentials: %w", args[0], err)
+	}
+	if output.Version != 1 || output.AccessKeyID == "" || output.SecretAccessKey == "" {
+		return fmt.Errorf("%s printed incomplete credentials (need Version 1, AccessKeyId and SecretAccessKey)", args[0])
+	}
+	return nil
+}
+
+// credentialProcessFailure explains why a credential_process profile has no
+// valid session: the helper's own error when it fails, otherwise the STS one
+func (aws *AWSManager) credentialProcessFailure(ctx context.Context, profile, command string, stsErr error) error {
+	timeout := aws.fancyConfig.Settings.AWSTimeoutDuration()
+	ctx, cancel := utils.WithStepTimeout(ctx, timeout)
+	defer cancel()
+
+	if err := runCredentialProcess(ctx, command); err != nil {
+		err = utils.StepError(ctx, "credential_process", timeout, err)
+		return fmt.Errorf("credential_process for %s failed: %w", profile, err)
+	}
+	return fmt.Errorf("credentials from credential_process for %s were rejected: %s", profile, stsErrorMessage(stsErr))
+}
+
+// stsErrorMessage returns the aws CLI's stderr from a failed command
+func stsErrorMessage(err error) string {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if msg := strings.TrimSpace(string(exitErr.Stderr)); msg != "" {
+			return msg
+		}
+	}
+	if err == nil {
+		return "unknown error"
+	}
+	return err.Error()
+}
@@ -0,0 +1,127 @@
+package aws
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"fancy-login/internal/config"
+	"fancy-login/internal/utils"
+)
+
+const validHelperOutput = `{"Version": 1, "AccessKeyId": "AKIAEXAMPLE", "SecretAccessKey": "example", "SessionToken": "token"}`
+
+// writeFakeHelper writes an executable helper script and returns its path
+func writeFakeHelper(t *testing.T, dir, name, script string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake credential helpers require a POSIX shell")
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRunCredentialProcess(t *testing.T) {
+	testCases := []struct {
+		name          string
+		script        string
+		expectedError string
+	}{
+		{"Valid credentials", "echo '" + validHelperOutput + "'\n", ""},
+		{"Helper fails", "echo 'vendor token expired, run vendor-login' >&2\nexit 1\n", "vendor token expired, run vendor-login"},
+		{"Helper fails silently", "exit 3\n", "exit status 3"},
+		{"Invalid output", "echo not-json\n", "invalid credentials"},
+		{"Incomplete output", "echo '{\"Version\": 1}'\n", "incomplete credentials"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			helper := writeFakeHelper(t, t.TempDir(), "helper", tc.script)
+
+			err := runCredentialProcess(context.Background(), helper+" --profile vendor")
+			if tc.expectedError == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.expectedError) {
+				t.Errorf("Expected error containing %q, got %v", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestPlanLoginCredentialProcess(t *testing.T) {
+	testCases := []struct {
+		name     string
+		valid    bool
+		force    bool
+		expected LoginPlan
+	}{
+		{"Valid", true, false, PlanNone},
+		{"Valid forced", true, true, PlanNone},
+		{"Helper failing", false, false, PlanProcessFailed},
+		{"Helper failing forced", false, true, PlanProcessFailed},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			info := LoginProfileInfo{CredentialProcess: "/opt/vendor/helper", Interactive: true}
+			if plan := PlanLogin(info, SessionState{Valid: tc.valid}, tc.force); plan != tc.expected {
+				t.Errorf("Expected plan %s, got %s", tc.expected, plan)
+			}
+		})
+	}
+}
+
+func TestHandleAWSLoginCredentialProcess(t *testing.T) {
+	testCases := []struct {
+		name          string
+		helperScript  string
+		expectedError string
+	}{
+		{"Helper succeeds", "echo '" + validHelperOutput + "'\n", ""},
+		{"Helper fails", "echo 'vendor token expired, run vendor-login' >&2\nexit 1\n", "vendor token expired, run vendor-login"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			home := t.TempDir()
+			t.Setenv("HOME", home)
+			t.Setenv("AWS_CONFIG_FILE", "")
+			binDir := t.TempDir()
+			t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+			helper := writeFakeHelper(t, binDir, "vendor-helper", tc.helperScript)
+			// The fake aws CLI succeeds exactly when the helper does
+			writeFakeHelper(t, binDir, "aws", "if "+helper+" > /dev/null 2>&1; then echo '{\"Account\": \"123456789012\"}'; else echo 'Error when retrieving credentials from custom-process' >&2; exit 255; fi\n")
+
+			if err := os.MkdirAll(filepath.Join(home, ".aws"), 0700); err != nil {
+				t.Fatal(err)
+			}
+			awsConfig := "[profile vendor]\ncredential_process = " + helper + " --profile vendor\n"
+			if err := os.WriteFile(filepath.Join(home, ".aws", "config"), []byte(awsConfig), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			manager := NewAWSManager(config.NewConfig(), utils.NewLogger(false), config.DefaultFancyConfig())
+			err := manager.HandleAWSLogin(context.Background(), "vendor", false)
+			if tc.expectedError == "" {
+				if err != nil {
+					t.Errorf("Expected a valid session without prompts, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.expectedError) {
+				t.Errorf("Expected the helper's stderr in the error, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package aws
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"fancy-login/internal/config"
+	"fancy-login/internal/utils"
+)
+
+// SSOPortal is the SSO portal `profiles discover` lists accounts and roles
+// of, and what new profiles log in with
+type SSOPortal struct {
+	StartURL string
+	// Region is the portal's region, empty to take it from the cached token
+	Region string
+	// Session is the sso-session new profiles refer to, empty to write the
+	// start URL into each profile instead
+	Session string
+	// ProfileRegion becomes the region of new profiles
+	ProfileRegion string
+}
+
+// SSORole is an account and role the SSO user can sign in to
+type SSORole struct {
+	AccountID   string
+	AccountName string
+	RoleName    string
+}
+
+// PortalFromProfile returns the SSO portal of an existing SSO profile,
+// logging in to it first if its token expired, so discovery can borrow it
+func (aws *AWSManager) PortalFromProfile(ctx context.Context, profile string) (SSOPortal, error) {
+	if _, err := aws.snapshot.AWSProfiles(); err != nil {
+		return SSOPortal{}, err
+	}
+	p, ok := aws.snapshot.AWSProfile(profile)
+	if !ok {
+		return SSOPortal{}, utils.WithExitCode(utils.ExitConfig, fmt.Errorf("profile %s not found in %s", profile, config.GetAWSConfigPath()))
+	}
+	if !p.IsSSO || p.SSOStartURL == "" {
+		return SSOPortal{}, utils.WithExitCode(utils.ExitConfig, fmt.Errorf("profile %s has no SSO start URL to discover roles with", profile))
+	}
+	if err := aws.refreshSSOSession(ctx, profile); err != nil {
+		return SSOPortal{}, err
+	}
+	region := p.Region
+	if region == "" {
+		region = p.SSORegion
+	}
+	return SSOPortal{StartURL: p.SSOStartURL, Region: p.SSORegion, Session: p.SSOSession, ProfileRegion: region}, nil
+}
+
+// PortalFromStartURL returns the SSO portal at startURL. New profiles refer
+// to an sso-session already using it, if there is one.
+func (aws *AWSManager) PortalFromStartURL(startURL, region string) (SSOPortal, error) {
+	profiles, err := aws.snapshot.AWSProfiles()
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return SSOPortal{}, err
+	}
+	portal := SSOPortal{StartURL: startURL, Region: region, ProfileRegion: region}
+	for _, p := range profiles {
+		if p.SSOSession != "" && strings.TrimSuffix(p.SSOStartURL, "/") == strings.TrimSuffix(startURL, "/") {
+			portal.Session = p.SSOSession
+			if portal.Region == "" {
+				portal.Region, portal.ProfileRegion = p.SSORegion, p.SSORegion
+			}
+			break
+		}
+	}
+	return portal, nil
+}
+
+// DiscoverSSORoles lists the accounts and roles the cached SSO token of
+// portal gives access to, sorted by account name and role
+func (aws *AWSManager) DiscoverSSORoles(ctx context.Context, portal *SSOPortal) ([]SSORole, error) {
+	token := lookupSSOTokenByStartURL(portal.StartURL)
+	if !token.Found || !token.ExpiresAt.After(time.Now()) {
+		return nil, utils.WithExitCode(utils.ExitSessionExpired, fmt.Errorf("no valid SSO token for %s; log in with a profile using it, or pass --from-profile", portal.StartURL))
+	}
+	if portal.Region == "" {
+		portal.Region = token.Region
+	}
+	if portal.ProfileRegion == "" {
+		portal.ProfileRegion = portal.Region
+	}
+	if portal.Region == "" {
+		return nil, utils.WithExitCode(utils.ExitConfig, fmt.Errorf("cannot tell the SSO region of %s; pass --sso-region", portal.StartURL))
+	}
+
+	var accounts struct {
+		AccountList []struct {
+			AccountID   string `json:"accountId"`
+			AccountName string `json:"accountName"`
+		} `json:"accountList"`
+	}
+	if err := aws.ssoPortalCall(ctx, &accounts, "list-accounts", "--access-token", token.AccessToken, "--region", portal.Region); err != nil {
+		return nil, err
+	}
+
+	var roles []SSORole
+	for _, account := range accounts.AccountList {
+		var accountRoles struct {
+			RoleList []struct {
+				RoleName string `json:"roleName"`
+			} `json:"roleList"`
+		}
+		if err := aws.ssoPortalCall(ctx, &accountRoles, "list-account-roles", "--account-id", account.AccountID, "--access-token", token.AccessToken, "--region", portal.Region); err != nil {
+			return nil, err
+		}
+		for _, role := range accountRoles.RoleList {
+			roles = append(roles, SSORole{AccountID: account.AccountID, AccountName: account.AccountName, RoleName: role.RoleName})
+		}
+	}
+	sort.Slice(roles, func(i, j int) bool {
+		if roles[i].AccountName != roles[j].AccountName {
+			return roles[i].AccountName < roles[j].AccountName
+		}
+		return roles[i].RoleName < roles[j].RoleName
+	})
+	return roles, nil
+}
+
+// ssoPortalCall runs `aws sso ACTION ARGS...` under the AWS timeout and
+// decodes its JSON output into v. The CLI follows pagination itself.
+func (aws *AWSManager) ssoPortalCall(ctx context.Context, v interface{}, action string, args ...string) error {
+	timeout := aws.fancyConfig.Settings.AWSNetworkTimeoutDuration()
+	ctx, cancel := utils.WithStepTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := utils.CommandContext(ctx, "aws", append([]string{"sso", action, "--output", "json"}, args...)...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return utils.StepError(ctx, "aws sso "+action, timeout, fmt.Errorf("aws sso %s failed: %w", action, &commandError{err, strings.TrimSpace(stderr.String())}))
+	}
+	if err := json.Unmarshal(output, v); err != nil {
+		return fmt.Errorf("failed to parse the output of aws sso %s: %w", action, err)
+	}
+	return nil
+}
+
+// NewSSOProfiles returns a profile for each role that no profile in the AWS
+// config signs in to yet, named after its account and role
+func (aws *AWSManager) NewSSOProfiles(portal SSOPortal, roles []SSORole) ([]config.AWSProfile, error) {
+	existing, err := aws.snapshot.AWSProfiles()
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	names := make(map[string]bool)
+	covered := make(map[string]bool)
+	for _, p := range existing {
+		names[p.Name] = true
+		if p.SSORole != "" {
+			covered[p.AccountID+"/"+p.SSORole] = true
+		}
+	}
+
+	var profiles []config.AWSProfile
+	for _, role := range roles {
+		if covered[role.AccountID+"/"+role.RoleName] {
+			continue
+		}
+		name := SSOProfileName(role)
+		if names[name] {
+			name += "-" + role.AccountID
+		}
+		names[name] = true
+		profiles = append(profiles, config.AWSProfile{
+			Name:        name,
+			AccountID:   role.AccountID,
+			Region:      portal.ProfileRegion,
+			SSOStartURL: portal.StartURL,
+			SSORegion:   portal.Region,
+			SSORole:     role.RoleName,
+			SSOSession:  portal.Session,
+			IsSSO:       true,
+		})
+	}
+	return profiles, nil
+}
+
+var profileNameUnsafe = regexp.MustCompile(`[^a-z0-9]+`)
+
+// SSOProfileName names the profile of an SSO role after its account and
+// role, e.g. "acme-prod-readonly"
+func SSOProfileName(role SSORole) string {
+	account := role.AccountName
+	if account == "" {
+		account = role.AccountID
+	}
+	slug := func(s string) string {
+		return strings.Trim(profileNameUnsafe.ReplaceAllString(strings.ToLower(s), "-"), "-")
+	}
+	return slug(account) + "-" + slug(role.RoleName)
+}
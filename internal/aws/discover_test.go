@@ -0,0 +1,140 @@
+package aws
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"fancy-login/internal/config"
+	"fancy-login/internal/utils"
+)
+
+// fakeSSOPortalCLI answers aws sso list-accounts and list-account-roles for
+// two accounts, accepting only the access token "cached-token"
+const fakeSSOPortalCLI = `case "$*" in *"--access-token cached-token"*) ;; *) echo 'UnauthorizedException' >&2; exit 255;; esac
+case "$2" in
+list-accounts) echo '{"accountList":[{"accountId":"210987654321","accountName":"Acme Prod"},{"accountId":"123456789012","accountName":"Acme Dev"}]}';;
+list-account-roles)
+  case "$*" in
+  *123456789012*) echo '{"roleList":[{"roleName":"Developer","accountId":"123456789012"}]}';;
+  *) echo '{"roleList":[{"roleName":"ReadOnly","accountId":"210987654321"},{"roleName":"Admin","accountId":"210987654321"}]}';;
+  esac;;
+esac
+`
+
+// setupSSOPortal writes an AWS config whose dev profile uses the acme
+// sso-session, a cached token for it expiring at expiresAt and a fake aws
+// CLI serving the portal
+func setupSSOPortal(t *testing.T, expiresAt time.Time) *AWSManager {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("AWS_CONFIG_FILE", "")
+	cacheDir := filepath.Join(home, ".aws", "sso", "cache")
+	if err := os.MkdirAll(cacheDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	awsConfig := "[sso-session acme]\nsso_start_url = https://acme.awsapps.com/start\nsso_region = eu-central-1\n\n" +
+		"[profile dev]\nsso_session = acme\nsso_account_id = 123456789012\nsso_role_name = Developer\nregion = eu-west-1\n"
+	if err := os.WriteFile(filepath.Join(home, ".aws", "config"), []byte(awsConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+	token := `{"startUrl":"https://acme.awsapps.com/start","region":"eu-central-1","accessToken":"cached-token","expiresAt":"` + expiresAt.UTC().Format(time.RFC3339) + `"}`
+	if err := os.WriteFile(filepath.Join(cacheDir, "token.json"), []byte(token), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	binDir := t.TempDir()
+	writeFakeHelper(t, binDir, "aws", fakeSSOPortalCLI)
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return NewAWSManager(config.NewConfig(), utils.NewLogger(false), config.DefaultFancyConfig())
+}
+
+func TestDiscoverSSORoles(t *testing.T) {
+	manager := setupSSOPortal(t, time.Now().Add(time.Hour))
+
+	portal, err := manager.PortalFromStartURL("https://acme.awsapps.com/start/", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if portal.Session != "acme" || portal.Region != "eu-central-1" {
+		t.Errorf("Expected the acme sso-session in eu-central-1, got %+v", portal)
+	}
+
+	roles, err := manager.DiscoverSSORoles(context.Background(), &portal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedRoles := []SSORole{
+		{AccountID: "123456789012", AccountName: "Acme Dev", RoleName: "Developer"},
+		{AccountID: "210987654321", AccountName: "Acme Prod", RoleName: "Admin"},
+		{AccountID: "210987654321", AccountName: "Acme Prod", RoleName: "ReadOnly"},
+	}
+	if len(roles) != len(expectedRoles) {
+		t.Fatalf("Expected %d roles, got %+v", len(expectedRoles), roles)
+	}
+	for i, expected := range expectedRoles {
+		if roles[i] != expected {
+			t.Errorf("Expected %+v, got %+v", expected, roles[i])
+		}
+	}
+
+	// dev already signs in to Acme Dev as Developer
+	profiles, err := manager.NewSSOProfiles(portal, roles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(profiles) != 2 || profiles[0].Name != "acme-prod-admin" || profiles[1].Name != "acme-prod-readonly" {
+		t.Fatalf("Expected profiles for the two Acme Prod roles, got %+v", profiles)
+	}
+	if p := profiles[1]; p.SSOSession != "acme" || p.AccountID != "210987654321" || p.SSORole != "ReadOnly" || p.Region != "eu-central-1" {
+		t.Errorf("Expected the profile to use the acme session, got %+v", p)
+	}
+}
+
+func TestPortalFromProfile(t *testing.T) {
+	manager := setupSSOPortal(t, time.Now().Add(time.Hour))
+
+	portal, err := manager.PortalFromProfile(context.Background(), "dev")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := SSOPortal{StartURL: "https://acme.awsapps.com/start", Region: "eu-central-1", Session: "acme", ProfileRegion: "eu-west-1"}
+	if portal != expected {
+		t.Errorf("Expected %+v, got %+v", expected, portal)
+	}
+	if _, err := manager.PortalFromProfile(context.Background(), "missing"); utils.ExitCode(err, utils.ExitOK) != utils.ExitConfig {
+		t.Errorf("Expected a config error for an unknown profile, got %v", err)
+	}
+}
+
+func TestDiscoverSSORolesExpiredToken(t *testing.T) {
+	manager := setupSSOPortal(t, time.Now().Add(-time.Minute))
+
+	portal, err := manager.PortalFromStartURL("https://acme.awsapps.com/start", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := manager.DiscoverSSORoles(context.Background(), &portal); utils.ExitCode(err, utils.ExitOK) != utils.ExitSessionExpired {
+		t.Errorf("Expected an expired session, got %v", err)
+	}
+}
+
+func TestSSOProfileName(t *testing.T) {
+	testCases := []struct {
+		role     SSORole
+		expected string
+	}{
+		{SSORole{AccountID: "210987654321", AccountName: "Acme Prod", RoleName: "ReadOnly"}, "acme-prod-readonly"},
+		{SSORole{AccountID: "210987654321", AccountName: "acme (legacy) / EU", RoleName: "AWSAdministratorAccess"}, "acme-legacy-eu-awsadministratoraccess"},
+		{SSORole{AccountID: "210987654321", RoleName: "Admin"}, "210987654321-admin"},
+	}
+
+	for _, tc := range testCases {
+		if got := SSOProfileName(tc.role); got != tc.expected {
+			t.Errorf("Expected %q, got %q", tc.expected, got)
+		}
+	}
+}
@@ -0,0 +1,401 @@
+package aws
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"fancy-login/internal/utils"
+)
+
+// LoginMethod selects how ECR credentials are handed to the container tooling
+type LoginMethod string
+
+const (
+	// MethodPipe pipes get-login-password into `docker login --password-stdin`
+	MethodPipe LoginMethod = "pipe"
+	// MethodDockerCfg writes the token straight into ~/.docker/config.json
+	MethodDockerCfg LoginMethod = "dockercfg"
+	// MethodPodman pipes get-login-password into `podman login --password-stdin`
+	MethodPodman LoginMethod = "podman"
+)
+
+// ParseLoginMethod validates a --method value
+func ParseLoginMethod(value string) (LoginMethod, error) {
+	switch LoginMethod(value) {
+	case MethodPipe, MethodDockerCfg, MethodPodman:
+		return LoginMethod(value), nil
+	case "":
+		return MethodPipe, nil
+	}
+	return "", fmt.Errorf("unknown ECR login method %q (expected pipe, dockercfg or podman)", value)
+}
+
+// Registry identifies an ECR registry: a private one by account and
+// region, or ECR Public
+type Registry struct {
+	AccountID string
+	Region    string
+	Public    bool
+}
+
+// PublicRegistry is ECR Public, whose tokens are only issued in us-east-1
+var PublicRegistry = Registry{Region: "us-east-1", Public: true}
+
+// ECRLoginResult is the outcome of logging in to one registry
+type ECRLoginResult struct {
+	// Host is the registry hostname, empty for a private registry whose
+	// account couldn't be resolved
+	Host string
+	Err  error
+	// Cached is set when the login was skipped for a fresh earlier token
+	Cached bool
+}
+
+var registryRegex = regexp.MustCompile(`^(\d{12})\.dkr\.ecr\.([a-z]{2}(?:-[a-z]+)+-\d+)\.amazonaws\.com(\.cn)?$`)
+
+// ParseRegistry extracts the account ID and region from an ECR registry hostname
+// such as 123456789012.dkr.ecr.eu-central-1.amazonaws.com
+func ParseRegistry(host string) (Registry, error) {
+	host = strings.TrimPrefix(strings.TrimPrefix(host, "https://"), "http://")
+	host = strings.TrimSuffix(host, "/")
+
+	matches := registryRegex.FindStringSubmatch(host)
+	if matches == nil {
+		return Registry{}, fmt.Errorf("invalid ECR registry %q: expected <12-digit-account>.dkr.ecr.<region>.amazonaws.com", host)
+	}
+	return Registry{AccountID: matches[1], Region: matches[2]}, nil
+}
+
+// Host returns the registry hostname
+func (r Registry) Host() string {
+	if r.Public {
+		return "public.ecr.aws"
+	}
+	host := fmt.Sprintf("%s.dkr.ecr.%s.amazonaws.com", r.AccountID, r.Region)
+	if strings.HasPrefix(r.Region, "cn-") {
+		host += ".cn"
+	}
+	return host
+}
+
+// service returns the aws CLI service issuing the registry's tokens
+func (r Registry) service() string {
+	if r.Public {
+		return "ecr-public"
+	}
+	return "ecr"
+}
+
+// tokenScope identifies the registries one get-login-password token is
+// good for: every registry of its service in its region
+func (r Registry) tokenScope() string {
+	return r.service() + "/" + r.Region
+}
+
+// LoginToRegistry authenticates the container tooling against an ECR registry.
+// It is shared by the login flow and the standalone ecr command.
+func (aws *AWSManager) LoginToRegistry(ctx context.Context, profile string, registry Registry, method LoginMethod) error {
+	aws.logger.FancyLog(fmt.Sprintf("Registry: %s, Method: %s", registry.Host(), method))
+
+	var spinner *utils.Spinner
+	if !aws.config.FancyVerbose {
+		spinner = utils.NewSpinner(aws.logger.Output(), "🐳 Logging in to ECR...")
+		spinner.Start()
+		defer spinner.Stop()
+	}
+
+	login := func() error { return aws.registryLogin(ctx, profile, registry, method) }
+	onRetry := func(attempt, attempts int) { aws.reportECRRetry(spinner, registry.Host(), attempt, attempts) }
+	if err := aws.withECRRetry(ctx, onRetry, login); err != nil {
+		return err
+	}
+
+	aws.logger.FancyLog("ECR login successful")
+	return nil
+}
+
+// registryLogin performs the login with the given method without printing
+// anything, so it can also run in the background
+func (aws *AWSManager) registryLogin(ctx context.Context, profile string, registry Registry, method LoginMethod) error {
+	switch method {
+	case MethodDockerCfg:
+		return aws.writeDockerConfigAuth(ctx, profile, registry)
+	case MethodPodman:
+		return aws.pipeLogin(ctx, profile, registry, "podman")
+	default:
+		return aws.pipeLogin(ctx, profile, registry, "docker")
+	}
+}
+
+// pipeLogin pipes the ECR password into `<tool> login --password-stdin`
+func (aws *AWSManager) pipeLogin(ctx context.Context, profile string, registry Registry, tool string) error {
+	awsTimeout := aws.fancyConfig.Settings.AWSNetworkTimeoutDuration()
+	awsCtx, cancelAWS := utils.WithStepTimeout(ctx, awsTimeout)
+	defer cancelAWS()
+	dockerTimeout := aws.fancyConfig.Settings.DockerTimeoutDuration()
+	dockerCtx, cancelDocker := utils.WithStepTimeout(ctx, dockerTimeout)
+	defer cancelDocker()
+
+	cmd1 := aws.awsCommand(awsCtx, profile, registry.service(), "get-login-password", "--region", registry.Region)
+	cmd2 := utils.CommandContext(dockerCtx, tool, "login", "--username", "AWS", "--password-stdin", registry.Host())
+
+	cmd2.Stdin, _ = cmd1.StdoutPipe()
+	var awsStderr, toolStderr bytes.Buffer
+	cmd1.Stderr = &awsStderr
+	cmd2.Stderr = &toolStderr
+
+	if err := cmd1.Start(); err != nil {
+		return fmt.Errorf("failed to start ECR login command: %w", err)
+	}
+
+	if err := cmd2.Start(); err != nil {
+		return fmt.Errorf("failed to start %s login command: %w", tool, err)
+	}
+
+	if err := cmd1.Wait(); err != nil {
+		// The tool sees the closed pipe and fails too; reap it
+		_ = cmd2.Wait()
+		return utils.StepError(awsCtx, "aws "+registry.service()+" get-login-password", awsTimeout,
+			fmt.Errorf("ECR get-login-password failed: %w", &commandError{err, strings.TrimSpace(awsStderr.String())}))
+	}
+
+	if err := cmd2.Wait(); err != nil {
+		return utils.StepError(dockerCtx, tool+" login", dockerTimeout,
+			fmt.Errorf("%s login failed: %w", tool, &commandError{err, strings.TrimSpace(toolStderr.String())}))
+	}
+
+	return nil
+}
+
+// loginToRegistries logs docker in to registries without printing
+// anything, so it can also run in the background. Registries sharing a
+// token scope share a single get-login-password; a registry alone in its
+// scope pipes the token straight into docker login. The results are in
+// the order of registries.
+func (aws *AWSManager) loginToRegistries(ctx context.Context, profile string, registries []Registry, onRetry func(host string, attempt, attempts int)) []ECRLoginResult {
+	shared := make(map[string]int)
+	for _, registry := range registries {
+		shared[registry.tokenScope()]++
+	}
+
+	type token struct {
+		password string
+		err      error
+	}
+	tokens := make(map[string]token)
+	results := make([]ECRLoginResult, len(registries))
+	for i, registry := range registries {
+		results[i].Host = registry.Host()
+		retry := func(attempt, attempts int) {
+			if onRetry != nil {
+				onRetry(registry.Host(), attempt, attempts)
+			}
+		}
+		scope := registry.tokenScope()
+		if shared[scope] == 1 {
+			results[i].Err = aws.withECRRetry(ctx, retry, func() error {
+				return aws.pipeLogin(ctx, profile, registry, "docker")
+			})
+			continue
+		}
+		t, fetched := tokens[scope]
+		if !fetched {
+			t.err = aws.withECRRetry(ctx, retry, func() (err error) {
+				t.password, err = aws.getLoginPassword(ctx, profile, registry)
+				return err
+			})
+			tokens[scope] = t
+		}
+		if t.err != nil {
+			results[i].Err = t.err
+			continue
+		}
+		results[i].Err = aws.withECRRetry(ctx, retry, func() error {
+			return aws.passwordLogin(ctx, registry, t.password)
+		})
+	}
+	recordECRTokens(results)
+	return results
+}
+
+// reportECRRetry shows that the login to host is being retried, on the
+// spinner unless it is nil in verbose mode
+func (aws *AWSManager) reportECRRetry(spinner *utils.Spinner, host string, attempt, attempts int) {
+	if spinner != nil {
+		spinner.SetMessage(fmt.Sprintf("🐳 Logging in to ECR (attempt %d/%d)...", attempt, attempts))
+	}
+	aws.logger.FancyLog(fmt.Sprintf("Retrying the login to %s, attempt %d/%d", host, attempt, attempts))
+}
+
+// passwordLogin hands an ECR token fetched earlier to `docker login
+// --password-stdin`
+func (aws *AWSManager) passwordLogin(ctx context.Context, registry Registry, password string) error {
+	timeout := aws.fancyConfig.Settings.DockerTimeoutDuration()
+	ctx, cancel := utils.WithStepTimeout(ctx, timeout)
+	defer cancel()
+
+	var stderr bytes.Buffer
+	cmd := utils.CommandContext(ctx, "docker", "login", "--username", "AWS", "--password-stdin", registry.Host())
+	cmd.Stdin = strings.NewReader(password)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return utils.StepError(ctx, "docker login", timeout,
+			fmt.Errorf("docker login failed: %w", &commandError{err, strings.TrimSpace(stderr.String())}))
+	}
+	return nil
+}
+
+// getLoginPassword fetches an ECR authorization token for the registry's region
+func (aws *AWSManager) getLoginPassword(ctx context.Context, profile string, registry Registry) (string, error) {
+	timeout := aws.fancyConfig.Settings.AWSNetworkTimeoutDuration()
+	ctx, cancel := utils.WithStepTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := aws.awsCommand(ctx, profile, registry.service(), "get-login-password", "--region", registry.Region)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", utils.StepError(ctx, "aws "+registry.service()+" get-login-password", timeout,
+			fmt.Errorf("ECR get-login-password failed: %w", &commandError{err, strings.TrimSpace(stderr.String())}))
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// writeDockerConfigAuth stores the ECR token in ~/.docker/config.json without
+// invoking docker, for hosts where only the config file is available
+func (aws *AWSManager) writeDockerConfigAuth(ctx context.Context, profile string, registry Registry) error {
+	password, err := aws.getLoginPassword(ctx, profile, registry)
+	if err != nil {
+		return err
+	}
+
+	configPath := dockerConfigPath()
+
+	raw := make(map[string]interface{})
+	if data, err := os.ReadFile(configPath); err == nil {
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return fmt.Errorf("failed to parse %s: %w", configPath, err)
+		}
+	}
+
+	auths, _ := raw["auths"].(map[string]interface{})
+	if auths == nil {
+		auths = make(map[string]interface{})
+	}
+	auths[registry.Host()] = map[string]string{
+		"auth": base64.StdEncoding.EncodeToString([]byte("AWS:" + password)),
+	}
+	raw["auths"] = auths
+
+	data, err := json.MarshalIndent(raw, "", "\t")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(configPath), 0700); err != nil {
+		return fmt.Errorf("failed to create docker config directory: %w", err)
+	}
+	return os.WriteFile(configPath, append(data, '\n'), 0600)
+}
+
+// DockerAuthenticated reports whether the docker CLI config holds
+// credentials for the registry host
+func DockerAuthenticated(host string) (bool, error) {
+	data, err := os.ReadFile(dockerConfigPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	var dockerConfig struct {
+		Auths map[string]json.RawMessage `json:"auths"`
+	}
+	if err := json.Unmarshal(data, &dockerConfig); err != nil {
+		return false, fmt.Errorf("failed to parse %s: %w", dockerConfigPath(), err)
+	}
+	_, ok := dockerConfig.Auths[host]
+	return ok, nil
+}
+
+// reportECRLogin tells a dry run which registry HandleECRLogin would log in
+// to. Without a valid session the account comes from the profile's
+// account_id; if neither is known the registry can't be resolved.
+func (aws *AWSManager) reportECRLogin(ctx context.Context, profile string) error {
+	if aws.ecrPublic(profile) {
+		aws.reportRegistryLogin(PublicRegistry, "log in to ECR Public registry %s")
+	}
+	if registries := aws.configuredECRRegistries(profile); len(registries) > 0 {
+		for _, registry := range registries {
+			aws.reportRegistryLogin(registry, "log in to ECR registry %s (from ecr_registries)")
+		}
+		return nil
+	}
+
+	accountID, err := aws.getAccountID(ctx, profile)
+	if err != nil {
+		if pc, pcErr := aws.fancyConfig.GetProfileConfig(profile); pcErr == nil {
+			accountID = pc.AccountID
+		}
+	}
+	if accountID == "" {
+		return fmt.Errorf("cannot resolve the ECR registry of %s: no valid session and no account_id configured", profile)
+	}
+
+	registry := aws.ecrRegistry(profile, accountID)
+	_, source := aws.ECRRegion(profile)
+	aws.reportRegistryLogin(registry, "log in to ECR registry %s (region from "+string(source)+")")
+	return nil
+}
+
+// reportRegistryLogin reports the planned login to registry, described by
+// format with its host, or that its fresh token would be reused
+func (aws *AWSManager) reportRegistryLogin(registry Registry, format string) {
+	if aws.cachedECRTokens([]Registry{registry})[registry.Host()] {
+		aws.logger.LogPlanned(fmt.Sprintf("reuse the fresh ECR token of %s", registry.Host()))
+		return
+	}
+	aws.logger.LogPlanned(fmt.Sprintf(format, registry.Host()))
+}
+
+// configuredECRRegistries returns the registries of the profile's
+// ecr_registries, in the profile's ECR region where an entry has none
+func (aws *AWSManager) configuredECRRegistries(profile string) []Registry {
+	pc, err := aws.fancyConfig.GetProfileConfig(profile)
+	if err != nil || len(pc.ECRRegistries) == 0 {
+		return nil
+	}
+	defaultRegion, _ := aws.ECRRegion(profile)
+	registries := make([]Registry, 0, len(pc.ECRRegistries))
+	for _, r := range pc.ECRRegistries {
+		region := r.Region
+		if region == "" {
+			region = defaultRegion
+		}
+		registries = append(registries, Registry{AccountID: r.AccountID, Region: region})
+	}
+	return registries
+}
+
+// ecrPublic reports whether profile also logs in to ECR Public
+func (aws *AWSManager) ecrPublic(profile string) bool {
+	pc, err := aws.fancyConfig.GetProfileConfig(profile)
+	return err == nil && pc.ECRPublic
+}
+
+// dockerConfigPath returns the docker CLI's config.json, honoring DOCKER_CONFIG
+func dockerConfigPath() string {
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return filepath.Join(dir, "config.json")
+	}
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".docker", "config.json")
+}
@@ -0,0 +1,78 @@
+package aws
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"fancy-login/internal/state"
+)
+
+// SetForceECRLogin makes HandleECRLogin log in even while docker holds a
+// fresh token from an earlier login
+func (aws *AWSManager) SetForceECRLogin(force bool) {
+	aws.forceECRLogin = force
+}
+
+// cachedECRTokens returns the hosts among registries whose token from an
+// earlier login is younger than ecr_token_max_age and still in the docker
+// config. It is empty when forced or when tokens aren't reused.
+func (aws *AWSManager) cachedECRTokens(registries []Registry) map[string]bool {
+	cached := make(map[string]bool)
+	maxAge := aws.fancyConfig.Settings.ECRTokenMaxAgeDuration()
+	if aws.forceECRLogin || maxAge == 0 {
+		return cached
+	}
+	st, err := state.Load()
+	if err != nil || len(st.ECRTokens) == 0 {
+		return cached
+	}
+	auths := dockerAuthHosts()
+	now := time.Now()
+	for _, registry := range registries {
+		host := registry.Host()
+		loggedIn, ok := st.ECRTokens[host]
+		if ok && auths[host] && now.Sub(loggedIn) < maxAge {
+			aws.logger.FancyLog(fmt.Sprintf("Registry: %s, token from %s still fresh", host, loggedIn.Local().Format("15:04")))
+			cached[host] = true
+		}
+	}
+	return cached
+}
+
+// dockerAuthHosts returns the registry hosts with an auths entry in the
+// docker config, which `docker logout` removes
+func dockerAuthHosts() map[string]bool {
+	hosts := make(map[string]bool)
+	data, err := os.ReadFile(dockerConfigPath())
+	if err != nil {
+		return hosts
+	}
+	var dockerConfig struct {
+		Auths map[string]json.RawMessage `json:"auths"`
+	}
+	if err := json.Unmarshal(data, &dockerConfig); err != nil {
+		return hosts
+	}
+	for key := range dockerConfig.Auths {
+		hosts[strings.TrimSuffix(strings.TrimPrefix(key, "https://"), "/")] = true
+	}
+	return hosts
+}
+
+// recordECRTokens stores when docker logged in to the registries of the
+// successful results. Like recordECRLogin it is best-effort: without it
+// the next run merely logs in again.
+func recordECRTokens(results []ECRLoginResult) {
+	now := time.Now()
+	state.Update(func(st *state.State) error {
+		for _, result := range results {
+			if result.Err == nil && result.Host != "" {
+				st.RecordECRToken(result.Host, now)
+			}
+		}
+		return nil
+	})
+}
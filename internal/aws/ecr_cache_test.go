@@ -0,0 +1,113 @@
+package aws
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"fancy-login/internal/config"
+	"fancy-login/internal/state"
+)
+
+const cacheTestHost = "123456789012.dkr.ecr.eu-central-1.amazonaws.com"
+
+func TestHandleECRLoginCached(t *testing.T) {
+	testCases := []struct {
+		name           string
+		tokenAge       time.Duration
+		dockerAuth     string
+		maxAge         string
+		force          bool
+		expectedCached bool
+	}{
+		{"Fresh token", time.Hour, cacheTestHost, "", false, true},
+		{"Auth stored with scheme", time.Hour, "https://" + cacheTestHost, "", false, true},
+		{"Token too old", 7 * time.Hour, cacheTestHost, "", false, false},
+		{"Shorter max age", time.Hour, cacheTestHost, "30m", false, false},
+		{"Logged out of docker", time.Hour, "public.ecr.aws", "", false, false},
+		{"Forced", time.Hour, cacheTestHost, "", true, false},
+		{"Reuse disabled", time.Hour, cacheTestHost, "0", false, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			calls := filepath.Join(t.TempDir(), "calls")
+			installFakeECRTools(t, "echo login >> "+calls+"\n")
+			dockerDir := t.TempDir()
+			t.Setenv("DOCKER_CONFIG", dockerDir)
+			if err := os.WriteFile(filepath.Join(dockerDir, "config.json"), []byte(`{"auths":{"`+tc.dockerAuth+`":{}}}`), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			manager := newECRTestManager(t)
+			manager.SetSTSClient(fakeSTS{"dev": {Account: "123456789012"}})
+			manager.fancyConfig.Settings.ECRTokenMaxAge = tc.maxAge
+			manager.SetForceECRLogin(tc.force)
+			loggedIn := time.Now().Add(-tc.tokenAge)
+			state.Update(func(st *state.State) error {
+				st.RecordECRToken(cacheTestHost, loggedIn)
+				return nil
+			})
+
+			if err := manager.HandleECRLogin(context.Background(), "dev"); err != nil {
+				t.Fatal(err)
+			}
+
+			if manager.ECRLoginCached() != tc.expectedCached {
+				t.Errorf("Expected cached=%v, got %v", tc.expectedCached, manager.ECRLogins())
+			}
+			_, err := os.Stat(calls)
+			if dockerRan := err == nil; dockerRan == tc.expectedCached {
+				t.Errorf("Expected docker login to run: %v", !tc.expectedCached)
+			}
+			st, err := state.Load()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if refreshed := st.ECRTokens[cacheTestHost].After(loggedIn); refreshed == tc.expectedCached {
+				t.Errorf("Expected the token time to be refreshed: %v", !tc.expectedCached)
+			}
+		})
+	}
+}
+
+func TestHandleECRLoginPartlyCached(t *testing.T) {
+	calls := filepath.Join(t.TempDir(), "calls")
+	installFakeECRTools(t, "echo login >> "+calls+"\n")
+	dockerDir := t.TempDir()
+	t.Setenv("DOCKER_CONFIG", dockerDir)
+	if err := os.WriteFile(filepath.Join(dockerDir, "config.json"), []byte(`{"auths":{"`+cacheTestHost+`":{}}}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	manager := newECRTestManager(t)
+	manager.fancyConfig.ProfileConfigs["dev"] = config.ProfileConfig{
+		ECRLogin:  true,
+		ECRRegion: "eu-central-1",
+		ECRRegistries: []config.ECRRegistryConfig{
+			{AccountID: "123456789012"},
+			{AccountID: "210987654321"},
+		},
+	}
+	state.Update(func(st *state.State) error {
+		st.RecordECRToken(cacheTestHost, time.Now())
+		return nil
+	})
+
+	if err := manager.HandleECRLogin(context.Background(), "dev"); err != nil {
+		t.Fatal(err)
+	}
+
+	logins := manager.ECRLogins()
+	if len(logins) != 2 || !logins[0].Cached || logins[1].Cached || logins[1].Host != "210987654321.dkr.ecr.eu-central-1.amazonaws.com" {
+		t.Errorf("Expected the first registry cached and the second logged in to, got %+v", logins)
+	}
+	if manager.ECRLoginCached() {
+		t.Error("Expected a login that reached docker not to count as cached")
+	}
+	if data, _ := os.ReadFile(calls); string(data) != "login\n" {
+		t.Errorf("Expected one docker login, got %q", data)
+	}
+}
@@ -0,0 +1,142 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"fancy-login/internal/state"
+)
+
+// BackgroundECRLogin is an ECR login running after the summary was shown
+type BackgroundECRLogin struct {
+	Started time.Time
+
+	cancel context.CancelFunc
+	done   chan struct{}
+	err    error
+}
+
+// StartBackgroundECRLogin starts the ECR login of a profile in a goroutine.
+// It prints nothing while running, so it can't interleave with prompts or
+// the k9s UI; its progress goes to the state file for the prompt segment
+// instead. It returns nil when the profile has no ECR login configured.
+func (aws *AWSManager) StartBackgroundECRLogin(ctx context.Context, profile, accountID string) *BackgroundECRLogin {
+	if !aws.fancyConfig.ShouldPerformECRLogin(profile) {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	login := &BackgroundECRLogin{Started: time.Now(), cancel: cancel, done: make(chan struct{})}
+	registries := aws.configuredECRRegistries(profile)
+	ownAccount := len(registries) == 0
+	if ownAccount {
+		registries = []Registry{aws.ecrRegistry(profile, accountID)}
+	}
+	recordECRLogin(profile, state.ECRLoginRecord{Status: state.ECRPending, Registry: registries[0].Host(), UpdatedAt: login.Started})
+	if aws.ecrPublic(profile) {
+		registries = append(registries, PublicRegistry)
+	}
+
+	go func() {
+		defer close(login.done)
+
+		var errs []error
+		if ownAccount && accountID == "" {
+			err := fmt.Errorf("AWS account ID unknown, session may have expired")
+			recordECRLogin(profile, finishedECRLogin(registries[0].Host(), err))
+			errs = append(errs, fmt.Errorf("%s: %w", registries[0].Host(), err))
+			registries = registries[1:]
+		}
+		results := aws.loginToRegistries(ctx, profile, registries, nil)
+		recordRegistryLogins(profile, registries, results)
+		for _, result := range results {
+			if result.Err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", result.Host, result.Err))
+			}
+		}
+		login.err = errors.Join(errs...)
+	}()
+	return login
+}
+
+// Wait blocks until the login has finished and returns its error. It is
+// safe to call on a nil login.
+func (l *BackgroundECRLogin) Wait() error {
+	if l == nil {
+		return nil
+	}
+	<-l.done
+	l.cancel()
+	return l.err
+}
+
+// Cancel stops a login that is still running and waits for it to wind
+// down, so no docker or aws child outlives the process. It is safe to call
+// on a nil or finished login.
+func (l *BackgroundECRLogin) Cancel() {
+	if l == nil {
+		return
+	}
+	l.cancel()
+	<-l.done
+}
+
+// finishedECRLogin describes the outcome of an ECR login
+func finishedECRLogin(host string, err error) state.ECRLoginRecord {
+	record := state.ECRLoginRecord{Status: state.ECROK, Registry: host, UpdatedAt: time.Now()}
+	if err != nil {
+		record.Status, record.Error = state.ECRFailed, err.Error()
+	}
+	return record
+}
+
+// recordRegistryLogins stores the outcome of the private registries among
+// registries as the profile's ECR login. The prompt segment shows a single
+// state, so a failure outranks the successes.
+func recordRegistryLogins(profile string, registries []Registry, results []ECRLoginResult) {
+	var record *state.ECRLoginRecord
+	for i, registry := range registries {
+		if registry.Public {
+			continue
+		}
+		r := finishedECRLogin(results[i].Host, results[i].Err)
+		if record == nil || (record.Status == state.ECROK && r.Status == state.ECRFailed) {
+			record = &r
+		}
+	}
+	if record != nil {
+		recordECRLogin(profile, *record)
+	}
+}
+
+// recordECRLogin stores an ECR login outcome in the state file. It is
+// best-effort: the prompt segment merely shows nothing without it.
+func recordECRLogin(profile string, record state.ECRLoginRecord) {
+	state.Update(func(st *state.State) error {
+		st.RecordECRLogin(profile, record)
+		return nil
+	})
+}
+
+// CheckLazyECRLogin reports whether docker will log in to the profile's
+// registry on its own on first pull, which lazy mode relies on. It returns
+// the registry host and whether a credential helper is configured for it.
+func (aws *AWSManager) CheckLazyECRLogin(profile, accountID string) (string, bool) {
+	host := aws.ecrRegistry(profile, accountID).Host()
+
+	data, err := os.ReadFile(dockerConfigPath())
+	if err != nil {
+		return host, false
+	}
+	var dockerConfig struct {
+		CredHelpers map[string]string `json:"credHelpers"`
+	}
+	if err := json.Unmarshal(data, &dockerConfig); err != nil {
+		return host, false
+	}
+	return host, dockerConfig.CredHelpers[host] != ""
+}
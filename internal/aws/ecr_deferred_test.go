@@ -0,0 +1,297 @@
+package aws
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"fancy-login/internal/config"
+	"fancy-login/internal/state"
+	"fancy-login/internal/utils"
+)
+
+// installFakeECRTools puts aws and docker on PATH; docker runs dockerScript
+func installFakeECRTools(t *testing.T, dockerScript string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake aws and docker require a POSIX shell")
+	}
+	binDir := t.TempDir()
+	tools := map[string]string{
+		"aws":    "#!/bin/sh\necho fake-password\n",
+		"docker": "#!/bin/sh\ncat > /dev/null\n" + dockerScript,
+	}
+	for name, script := range tools {
+		if err := os.WriteFile(filepath.Join(binDir, name), []byte(script), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func newECRTestManager(t *testing.T) *AWSManager {
+	t.Helper()
+	t.Setenv("FANCY_STATE_DIR", t.TempDir())
+	fancyConfig := config.DefaultFancyConfig()
+	fancyConfig.ProfileConfigs["dev"] = config.ProfileConfig{ECRLogin: true, ECRRegion: "eu-central-1"}
+	fancyConfig.ProfileConfigs["ops"] = config.ProfileConfig{}
+	return NewAWSManager(config.NewConfig(), utils.NewLogger(false), fancyConfig)
+}
+
+// ecrRecord returns the recorded ECR login of a profile
+func ecrRecord(t *testing.T, profile string) *state.ECRLoginRecord {
+	t.Helper()
+	st, err := state.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return st.ECRLogins[profile]
+}
+
+func TestBackgroundECRLogin(t *testing.T) {
+	testCases := []struct {
+		name           string
+		dockerScript   string
+		accountID      string
+		expectedStatus string
+	}{
+		{"Success", "exit 0\n", "123456789012", state.ECROK},
+		{"Docker login fails", "exit 1\n", "123456789012", state.ECRFailed},
+		{"Unknown account", "exit 0\n", "", state.ECRFailed},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			installFakeECRTools(t, tc.dockerScript)
+			manager := newECRTestManager(t)
+
+			login := manager.StartBackgroundECRLogin(context.Background(), "dev", tc.accountID)
+			if login == nil {
+				t.Fatal("Expected a background login for an ECR profile")
+			}
+			err := login.Wait()
+			if (err == nil) != (tc.expectedStatus == state.ECROK) {
+				t.Errorf("Unexpected result: %v", err)
+			}
+
+			record := ecrRecord(t, "dev")
+			if record == nil || record.Status != tc.expectedStatus {
+				t.Fatalf("Expected recorded status %s, got %+v", tc.expectedStatus, record)
+			}
+			if record.Registry != "123456789012.dkr.ecr.eu-central-1.amazonaws.com" && tc.accountID != "" {
+				t.Errorf("Expected the registry host, got %s", record.Registry)
+			}
+		})
+	}
+}
+
+func TestBackgroundECRLoginWithoutECR(t *testing.T) {
+	manager := newECRTestManager(t)
+	login := manager.StartBackgroundECRLogin(context.Background(), "ops", "123456789012")
+	if login != nil {
+		t.Fatal("Expected no background login for a profile without ECR")
+	}
+	// Both are safe on the nil login main holds in that case
+	login.Cancel()
+	if err := login.Wait(); err != nil {
+		t.Errorf("Expected nil login to succeed, got %v", err)
+	}
+	if record := ecrRecord(t, "ops"); record != nil {
+		t.Errorf("Expected nothing recorded, got %+v", record)
+	}
+}
+
+func TestBackgroundECRLoginCancel(t *testing.T) {
+	installFakeECRTools(t, "sleep 30\n")
+	manager := newECRTestManager(t)
+
+	login := manager.StartBackgroundECRLogin(context.Background(), "dev", "123456789012")
+	if record := ecrRecord(t, "dev"); record == nil || record.Status != state.ECRPending {
+		t.Fatalf("Expected a pending record while running, got %+v", record)
+	}
+
+	start := time.Now()
+	login.Cancel()
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("Expected Cancel to stop the login promptly, took %v", elapsed)
+	}
+	if record := ecrRecord(t, "dev"); record == nil || record.Status != state.ECRFailed {
+		t.Errorf("Expected a cancelled login to be recorded as failed, got %+v", record)
+	}
+}
+
+func TestCheckLazyECRLogin(t *testing.T) {
+	testCases := []struct {
+		name         string
+		dockerConfig string
+		expected     bool
+	}{
+		{"Helper for the registry", `{"credHelpers": {"123456789012.dkr.ecr.eu-central-1.amazonaws.com": "ecr-login"}}`, true},
+		{"Helper for another registry", `{"credHelpers": {"999999999999.dkr.ecr.eu-central-1.amazonaws.com": "ecr-login"}}`, false},
+		{"Only a credentials store", `{"credsStore": "desktop"}`, false},
+		{"No docker config", "", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			t.Setenv("DOCKER_CONFIG", dir)
+			if tc.dockerConfig != "" {
+				if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(tc.dockerConfig), 0600); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			host, ok := newECRTestManager(t).CheckLazyECRLogin("dev", "123456789012")
+			if host != "123456789012.dkr.ecr.eu-central-1.amazonaws.com" {
+				t.Errorf("Unexpected registry host %s", host)
+			}
+			if ok != tc.expected {
+				t.Errorf("Expected %v, got %v", tc.expected, ok)
+			}
+		})
+	}
+}
+
+func TestReportECRLogin(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake aws requires a POSIX shell")
+	}
+	// Without a session sts fails, so the registry comes from account_id
+	binDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(binDir, "aws"), []byte("#!/bin/sh\nexit 255\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	manager := newECRTestManager(t)
+	manager.SetDryRun(true)
+
+	if err := manager.HandleECRLogin(context.Background(), "dev"); err == nil {
+		t.Error("Expected an error without a session or account_id")
+	}
+
+	manager.fancyConfig.ProfileConfigs["dev"] = config.ProfileConfig{ECRLogin: true, ECRRegion: "eu-central-1", AccountID: "123456789012"}
+	if err := manager.HandleECRLogin(context.Background(), "dev"); err != nil {
+		t.Errorf("Expected the configured account_id to be used, got %v", err)
+	}
+	if record := ecrRecord(t, "dev"); record != nil {
+		t.Errorf("Expected a dry run to record no ECR login, got %+v", record)
+	}
+}
+
+func TestHandleECRLoginPublic(t *testing.T) {
+	testCases := []struct {
+		name          string
+		dockerScript  string
+		sts           fakeSTS
+		expectedHosts []string
+		expectedOK    []bool
+	}{
+		{
+			name:          "Both registries",
+			dockerScript:  "exit 0\n",
+			sts:           fakeSTS{"dev": {Account: "123456789012"}},
+			expectedHosts: []string{"123456789012.dkr.ecr.eu-central-1.amazonaws.com", "public.ecr.aws"},
+			expectedOK:    []bool{true, true},
+		},
+		{
+			name:          "Private login fails",
+			dockerScript:  "case \"$*\" in *public.ecr.aws*) exit 0 ;; esac\nexit 1\n",
+			sts:           fakeSTS{"dev": {Account: "123456789012"}},
+			expectedHosts: []string{"123456789012.dkr.ecr.eu-central-1.amazonaws.com", "public.ecr.aws"},
+			expectedOK:    []bool{false, true},
+		},
+		{
+			name:          "Unknown account",
+			dockerScript:  "exit 0\n",
+			sts:           fakeSTS{},
+			expectedHosts: []string{"", "public.ecr.aws"},
+			expectedOK:    []bool{false, true},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			installFakeECRTools(t, tc.dockerScript)
+			manager := newECRTestManager(t)
+			manager.fancyConfig.ProfileConfigs["dev"] = config.ProfileConfig{ECRLogin: true, ECRRegion: "eu-central-1", ECRPublic: true}
+			manager.SetSTSClient(tc.sts)
+
+			err := manager.HandleECRLogin(context.Background(), "dev")
+			if (err == nil) != !slices.Contains(tc.expectedOK, false) {
+				t.Errorf("Unexpected result: %v", err)
+			}
+
+			results := manager.ECRLogins()
+			if len(results) != len(tc.expectedHosts) {
+				t.Fatalf("Expected %d logins, got %+v", len(tc.expectedHosts), results)
+			}
+			for i, result := range results {
+				if result.Host != tc.expectedHosts[i] || (result.Err == nil) != tc.expectedOK[i] {
+					t.Errorf("Expected %s ok=%v, got %+v", tc.expectedHosts[i], tc.expectedOK[i], result)
+				}
+			}
+		})
+	}
+}
+
+func TestHandleECRLoginRegistries(t *testing.T) {
+	installFakeECRTools(t, "case \"$*\" in *222222222222*) exit 1 ;; esac\n")
+	// Log the aws calls to count the tokens fetched
+	awsLog := filepath.Join(t.TempDir(), "aws.log")
+	binDir := t.TempDir()
+	writeFakeHelper(t, binDir, "aws", "echo \"$*\" >> "+awsLog+"\necho fake-password\n")
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	manager := newECRTestManager(t)
+	manager.fancyConfig.ProfileConfigs["dev"] = config.ProfileConfig{ECRLogin: true, ECRRegion: "eu-central-1", ECRRegistries: []config.ECRRegistryConfig{
+		{AccountID: "111111111111", Region: "eu-west-1"},
+		{AccountID: "222222222222", Region: "eu-west-1"},
+		{AccountID: "333333333333"},
+	}}
+	// The profile's own account isn't needed, so STS is never asked
+	manager.SetSTSClient(fakeSTS{})
+
+	if err := manager.HandleECRLogin(context.Background(), "dev"); err == nil {
+		t.Error("Expected the failing registry to fail the login")
+	}
+
+	expected := []struct {
+		host string
+		ok   bool
+	}{
+		{"111111111111.dkr.ecr.eu-west-1.amazonaws.com", true},
+		{"222222222222.dkr.ecr.eu-west-1.amazonaws.com", false},
+		{"333333333333.dkr.ecr.eu-central-1.amazonaws.com", true},
+	}
+	results := manager.ECRLogins()
+	if len(results) != len(expected) {
+		t.Fatalf("Expected %d logins, got %+v", len(expected), results)
+	}
+	for i, result := range results {
+		if result.Host != expected[i].host || (result.Err == nil) != expected[i].ok {
+			t.Errorf("Expected %s ok=%v, got %+v", expected[i].host, expected[i].ok, result)
+		}
+	}
+
+	data, err := os.ReadFile(awsLog)
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := string(data)
+	if n := strings.Count(calls, "--region eu-west-1"); n != 1 {
+		t.Errorf("Expected one token for eu-west-1, got %d calls:\n%s", n, calls)
+	}
+	if n := strings.Count(calls, "--region eu-central-1"); n != 1 {
+		t.Errorf("Expected one token for eu-central-1, got %d calls:\n%s", n, calls)
+	}
+	if record := ecrRecord(t, "dev"); record == nil || record.Status != state.ECRFailed {
+		t.Errorf("Expected the failure to be recorded, got %+v", record)
+	}
+}
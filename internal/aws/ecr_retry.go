@@ -0,0 +1,133 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"strings"
+	"time"
+)
+
+// defaultECRRetryBaseDelay is the wait before the second attempt of an
+// ECR login; it doubles for each further one
+const defaultECRRetryBaseDelay = time.Second
+
+// ecrRetryBaseDelay is the base delay in use; tests set it to zero
+var ecrRetryBaseDelay = defaultECRRetryBaseDelay
+
+// commandError is a failed aws or docker command along with what it wrote
+// to stderr, which tells transient failures from permanent ones
+type commandError struct {
+	err    error
+	stderr string
+}
+
+func (e *commandError) Error() string {
+	if e.stderr == "" {
+		return e.err.Error()
+	}
+	return e.err.Error() + ": " + e.stderr
+}
+
+func (e *commandError) Unwrap() error {
+	return e.err
+}
+
+// permanentECRFailures mark errors that no retry fixes: expired or missing
+// credentials and denied access. They are checked first, since an auth
+// failure can mention a status code too.
+var permanentECRFailures = []string{
+	"expired",
+	"unauthorized",
+	"accessdenied",
+	"access denied",
+	"not authorized",
+	"invalid security token",
+	"unrecognizedclient",
+	"could not be found",
+	"sso session",
+	"error loading sso token",
+}
+
+// transientECRFailures mark network hiccups, server-side errors and a
+// docker daemon that isn't accepting connections yet
+var transientECRFailures = []string{
+	"timed out",
+	"timeout",
+	"connection refused",
+	"connection reset",
+	"could not connect to the endpoint url",
+	"temporary failure in name resolution",
+	"no such host",
+	"network is unreachable",
+	"unexpected eof",
+	"500 internal server error",
+	"502 bad gateway",
+	"503 service unavailable",
+	"504 gateway timeout",
+	"internalservererror",
+	"serviceunavailable",
+	"throttlingexception",
+	"rate exceeded",
+}
+
+// isTransientECRFailure reports whether stderr of a failed aws or docker
+// command describes a failure that may go away on retry
+func isTransientECRFailure(stderr string) bool {
+	stderr = strings.ToLower(stderr)
+	for _, marker := range permanentECRFailures {
+		if strings.Contains(stderr, marker) {
+			return false
+		}
+	}
+	for _, marker := range transientECRFailures {
+		if strings.Contains(stderr, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// retryableECRError reports whether a failed login attempt is worth
+// repeating. Step timeouts and cancellation are final: the timeout is the
+// user's bound for the whole step.
+func retryableECRError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var cmdErr *commandError
+	return errors.As(err, &cmdErr) && isTransientECRFailure(cmdErr.stderr)
+}
+
+// ecrRetryDelay is the wait before attempt (2, 3, ...): the base delay,
+// doubled per attempt, plus up to half of it as jitter
+func ecrRetryDelay(attempt int) time.Duration {
+	delay := ecrRetryBaseDelay << (attempt - 2)
+	if delay <= 0 {
+		return 0
+	}
+	return delay + rand.N(delay/2+1)
+}
+
+// withECRRetry runs login up to the configured number of attempts while it
+// fails transiently, calling onRetry, if set, before each repeat
+func (aws *AWSManager) withECRRetry(ctx context.Context, onRetry func(attempt, attempts int), login func() error) error {
+	attempts := aws.fancyConfig.Settings.ECRLoginAttemptsOrDefault()
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-ctx.Done():
+				return err
+			case <-time.After(ecrRetryDelay(attempt)):
+			}
+			if onRetry != nil {
+				onRetry(attempt, attempts)
+			}
+		}
+		if err = login(); err == nil || !retryableECRError(ctx, err) {
+			return err
+		}
+	}
+	return err
+}
@@ -0,0 +1,117 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"fancy-login/internal/config"
+	"fancy-login/internal/utils"
+)
+
+func TestIsTransientECRFailure(t *testing.T) {
+	testCases := []struct {
+		name      string
+		stderr    string
+		transient bool
+	}{
+		{"Endpoint unreachable", `Could not connect to the endpoint URL: "https://api.ecr.eu-central-1.amazonaws.com/"`, true},
+		{"Connect timeout", `Connect timeout on endpoint URL: "https://api.ecr.eu-central-1.amazonaws.com/"`, true},
+		{"DNS during VPN reconnect", `Error response from daemon: Get "https://123456789012.dkr.ecr.eu-central-1.amazonaws.com/v2/": dial tcp: lookup 123456789012.dkr.ecr.eu-central-1.amazonaws.com: no such host`, true},
+		{"Client timeout", `Error response from daemon: Get "https://123456789012.dkr.ecr.eu-central-1.amazonaws.com/v2/": net/http: request canceled while waiting for connection (Client.Timeout exceeded while awaiting headers)`, true},
+		{"ECR 503", `An error occurred (ServiceUnavailableException) when calling the GetAuthorizationToken operation (reached max retries: 2): Service Unavailable`, true},
+		{"Registry 503", `Error response from daemon: login attempt to https://123456789012.dkr.ecr.eu-central-1.amazonaws.com/v2/ failed with status: 503 Service Unavailable`, true},
+		{"Throttled", `An error occurred (ThrottlingException) when calling the GetAuthorizationToken operation: Rate exceeded`, true},
+		{"Docker daemon refusing", `error during connect: Post "http://%2Fvar%2Frun%2Fdocker.sock/v1.24/auth": dial unix /var/run/docker.sock: connect: connection refused`, true},
+		{"Expired SSO token", `Error when retrieving token from sso: Token has expired and refresh failed`, false},
+		{"Invalid token", `An error occurred (UnrecognizedClientException) when calling the GetAuthorizationToken operation: The security token included in the request is invalid.`, false},
+		{"Access denied", `An error occurred (AccessDeniedException) when calling the GetAuthorizationToken operation: User: arn:aws:sts::123456789012:assumed-role/Dev/me is not authorized to perform: ecr:GetAuthorizationToken`, false},
+		{"Registry rejects the token", `Error response from daemon: Get "https://123456789012.dkr.ecr.eu-central-1.amazonaws.com/v2/": unauthorized: authentication required`, false},
+		{"Bad request", `Error response from daemon: login attempt to https://123456789012.dkr.ecr.eu-central-1.amazonaws.com/v2/ failed with status: 400 Bad Request`, false},
+		{"Unknown profile", `The config profile (dev) could not be found`, false},
+		{"No output", "", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := isTransientECRFailure(tc.stderr); got != tc.transient {
+				t.Errorf("Expected transient=%v for %q", tc.transient, tc.stderr)
+			}
+		})
+	}
+}
+
+func TestWithECRRetry(t *testing.T) {
+	transient := fmt.Errorf("docker login failed: %w", &commandError{errors.New("exit status 1"), "connect: connection refused"})
+	permanent := fmt.Errorf("ECR get-login-password failed: %w", &commandError{errors.New("exit status 255"), "Token has expired"})
+
+	testCases := []struct {
+		name          string
+		attempts      int
+		errs          []error
+		expectedCalls int
+		expectedErr   error
+	}{
+		{"Succeeds at once", 0, []error{nil}, 1, nil},
+		{"Succeeds on the third try", 0, []error{transient, transient, nil}, 3, nil},
+		{"Gives up after the default attempts", 0, []error{transient, transient, transient, nil}, 3, transient},
+		{"Auth failures aren't retried", 0, []error{permanent, nil}, 1, permanent},
+		{"Retries disabled", 1, []error{transient, nil}, 1, transient},
+		{"More attempts configured", 5, []error{transient, transient, transient, transient, nil}, 5, nil},
+	}
+
+	ecrRetryBaseDelay = 0
+	defer func() { ecrRetryBaseDelay = defaultECRRetryBaseDelay }()
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fancyConfig := config.DefaultFancyConfig()
+			fancyConfig.Settings.ECRLoginAttempts = tc.attempts
+			manager := NewAWSManager(config.NewConfig(), utils.NewLogger(false), fancyConfig)
+
+			calls := 0
+			var retries []string
+			err := manager.withECRRetry(context.Background(), func(attempt, attempts int) {
+				retries = append(retries, fmt.Sprintf("%d/%d", attempt, attempts))
+			}, func() error {
+				calls++
+				return tc.errs[calls-1]
+			})
+
+			if err != tc.expectedErr {
+				t.Errorf("Expected %v, got %v", tc.expectedErr, err)
+			}
+			if calls != tc.expectedCalls {
+				t.Errorf("Expected %d attempts, got %d", tc.expectedCalls, calls)
+			}
+			if len(retries) != calls-1 {
+				t.Errorf("Expected a retry report before each repeat, got %v", retries)
+			}
+			if len(retries) > 0 && retries[0] != fmt.Sprintf("2/%d", fancyConfig.Settings.ECRLoginAttemptsOrDefault()) {
+				t.Errorf("Expected the first retry to be attempt 2, got %v", retries)
+			}
+		})
+	}
+}
+
+func TestHandleECRLoginRetriesTransientFailure(t *testing.T) {
+	ecrRetryBaseDelay = 0
+	defer func() { ecrRetryBaseDelay = defaultECRRetryBaseDelay }()
+
+	// docker refuses the first connection, as while a VPN reconnects
+	counter := filepath.Join(t.TempDir(), "attempts")
+	installFakeECRTools(t, "echo x >> "+counter+"\n"+
+		"if [ $(wc -l < "+counter+") -lt 2 ]; then echo 'dial unix /var/run/docker.sock: connect: connection refused' >&2; exit 1; fi\n")
+	manager := newECRTestManager(t)
+	manager.SetSTSClient(fakeSTS{"dev": {Account: "123456789012"}})
+
+	if err := manager.HandleECRLogin(context.Background(), "dev"); err != nil {
+		t.Fatalf("Expected the retry to succeed, got %v", err)
+	}
+	if data, _ := os.ReadFile(counter); len(data) != len("x\nx\n") {
+		t.Errorf("Expected two docker logins, got %q", data)
+	}
+}
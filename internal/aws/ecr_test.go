@@ -0,0 +1,65 @@
+package aws
+
+import (
+	"testing"
+)
+
+func TestParseRegistry(t *testing.T) {
+	testCases := []struct {
+		name            string
+		host            string
+		expectedAccount string
+		expectedRegion  string
+		expectErr       bool
+	}{
+		{"Standard registry", "999999999999.dkr.ecr.us-east-1.amazonaws.com", "999999999999", "us-east-1", false},
+		{"With scheme and slash", "https://123456789012.dkr.ecr.eu-central-1.amazonaws.com/", "123456789012", "eu-central-1", false},
+		{"GovCloud region", "123456789012.dkr.ecr.us-gov-west-1.amazonaws.com", "123456789012", "us-gov-west-1", false},
+		{"China partition", "123456789012.dkr.ecr.cn-north-1.amazonaws.com.cn", "123456789012", "cn-north-1", false},
+		{"Short account ID", "12345.dkr.ecr.us-east-1.amazonaws.com", "", "", true},
+		{"Missing region", "123456789012.dkr.ecr.amazonaws.com", "", "", true},
+		{"Public registry", "public.ecr.aws", "", "", true},
+		{"Not ECR", "ghcr.io", "", "", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			registry, err := ParseRegistry(tc.host)
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("ParseRegistry(%q) error = %v, expectErr %v", tc.host, err, tc.expectErr)
+			}
+			if tc.expectErr {
+				return
+			}
+			if registry.AccountID != tc.expectedAccount || registry.Region != tc.expectedRegion {
+				t.Errorf("ParseRegistry(%q) = %+v, expected %s/%s", tc.host, registry, tc.expectedAccount, tc.expectedRegion)
+			}
+		})
+	}
+}
+
+func TestRegistryHost(t *testing.T) {
+	if host := (Registry{AccountID: "123456789012", Region: "eu-central-1"}).Host(); host != "123456789012.dkr.ecr.eu-central-1.amazonaws.com" {
+		t.Errorf("Unexpected host: %s", host)
+	}
+	if host := (Registry{AccountID: "123456789012", Region: "cn-north-1"}).Host(); host != "123456789012.dkr.ecr.cn-north-1.amazonaws.com.cn" {
+		t.Errorf("Unexpected China host: %s", host)
+	}
+	if host := PublicRegistry.Host(); host != "public.ecr.aws" {
+		t.Errorf("Unexpected public host: %s", host)
+	}
+}
+
+func TestParseLoginMethod(t *testing.T) {
+	for _, valid := range []string{"pipe", "dockercfg", "podman"} {
+		if _, err := ParseLoginMethod(valid); err != nil {
+			t.Errorf("ParseLoginMethod(%q) unexpected error: %v", valid, err)
+		}
+	}
+	if method, _ := ParseLoginMethod(""); method != MethodPipe {
+		t.Errorf("Empty method should default to pipe, got %s", method)
+	}
+	if _, err := ParseLoginMethod("skopeo"); err == nil {
+		t.Error("Expected error for unknown method")
+	}
+}
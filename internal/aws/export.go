@@ -0,0 +1,133 @@
+package aws
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"fancy-login/internal/platform"
+	"fancy-login/internal/utils"
+)
+
+// ExportCredentials resolves the temporary credentials of profile and
+// writes them, readable only by the user, into the shell export file next
+// to AWS_PROFILE. It returns when they expire, zero for credentials that
+// don't.
+func (aws *AWSManager) ExportCredentials(ctx context.Context, profile string) (time.Time, error) {
+	if aws.dryRun {
+		aws.logger.LogPlanned(fmt.Sprintf("export the temporary credentials of %s to %s", profile, aws.config.AWSProfileTemp))
+		return time.Time{}, nil
+	}
+
+	creds, err := aws.resolveCredentials(ctx, profile)
+	if err != nil {
+		return time.Time{}, err
+	}
+	var expiresAt time.Time
+	if creds.Expiration != "" {
+		if expiresAt, err = time.Parse(time.RFC3339, creds.Expiration); err != nil {
+			return time.Time{}, fmt.Errorf("invalid expiration %q in the credentials of %s", creds.Expiration, profile)
+		}
+	}
+
+	vars := []platform.ExportVar{
+		{Name: "AWS_ACCESS_KEY_ID", Value: creds.AccessKeyID},
+		{Name: "AWS_SECRET_ACCESS_KEY", Value: creds.SecretAccessKey},
+	}
+	if creds.SessionToken != "" {
+		vars = append(vars, platform.ExportVar{Name: "AWS_SESSION_TOKEN", Value: creds.SessionToken})
+	}
+	if err := writeProfileScripts(platform.ProfileScripts(aws.config.AWSProfileTemp, profile, vars...), 0600); err != nil {
+		return time.Time{}, fmt.Errorf("failed to write the credentials of %s: %w", profile, err)
+	}
+	aws.logger.FancyLog(fmt.Sprintf("Exported the temporary credentials of %s", profile))
+	return expiresAt, nil
+}
+
+// resolveCredentials asks the aws CLI for the credentials profile resolves
+// to, in the credential_process format
+func (aws *AWSManager) resolveCredentials(ctx context.Context, profile string) (credentialProcessOutput, error) {
+	timeout := aws.fancyConfig.Settings.AWSNetworkTimeoutDuration()
+	ctx, cancel := utils.WithStepTimeout(ctx, timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := aws.awsCommand(ctx, profile, "configure", "export-credentials", "--format", "process")
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if msg := strings.TrimSpace(stderr.String()); errors.As(err, &exitErr) && msg != "" {
+			return credentialProcessOutput{}, fmt.Errorf("aws configure export-credentials: %s", msg)
+		}
+		return credentialProcessOutput{}, utils.StepError(ctx, "aws configure export-credentials", timeout, err)
+	}
+
+	var creds credentialProcessOutput
+	if err := json.Unmarshal(stdout.Bytes(), &creds); err != nil {
+		return credentialProcessOutput{}, fmt.Errorf("failed to parse the credentials of %s: %w", profile, err)
+	}
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return credentialProcessOutput{}, fmt.Errorf("aws configure export-credentials returned no credentials for %s", profile)
+	}
+	return creds, nil
+}
+
+// credentialProcessGuard is set for the aws commands ProcessCredentials
+// runs, so a profile whose credential_process points back at fancy-login
+// fails instead of calling itself forever
+const credentialProcessGuard = "FANCY_CREDENTIAL_PROCESS"
+
+// ProcessCredentials returns the credentials of profile as the JSON a
+// credential_process helper prints. The SSO session is refreshed as
+// refreshSSOSession does; nothing is ever asked.
+func (aws *AWSManager) ProcessCredentials(ctx context.Context, profile string) ([]byte, error) {
+	if os.Getenv(credentialProcessGuard) != "" {
+		return nil, fmt.Errorf("credential_process of %s calls fancy-login again; point it at another profile", profile)
+	}
+	if _, ok := aws.snapshot.AWSProfile(profile); !ok {
+		return nil, utils.WithExitCode(utils.ExitConfig, fmt.Errorf("profile %s is not in the AWS config", profile))
+	}
+	if err := os.Setenv(credentialProcessGuard, "1"); err != nil {
+		return nil, err
+	}
+	defer os.Unsetenv(credentialProcessGuard)
+
+	if err := aws.refreshSSOSession(ctx, profile); err != nil {
+		return nil, err
+	}
+
+	creds, err := aws.resolveCredentials(ctx, profile)
+	if err != nil {
+		return nil, err
+	}
+	creds.Version = 1
+	return json.Marshal(creds)
+}
+
+// refreshSSOSession makes sure the SSO session of profile, or of the SSO
+// profile at the end of its role chain, is valid for helpers that run
+// without a terminal: a valid token is taken from the cache, otherwise aws
+// sso login opens the browser.
+func (aws *AWSManager) refreshSSOSession(ctx context.Context, profile string) error {
+	chain, err := aws.roleChain(profile)
+	if err != nil {
+		return utils.WithExitCode(utils.ExitConfig, err)
+	}
+	loginProfile := profile
+	if len(chain) > 0 {
+		loginProfile = chain[len(chain)-1]
+	}
+	if p, _ := aws.snapshot.AWSProfile(loginProfile); p.IsSSO {
+		if valid, _ := aws.cachedSessionValid(loginProfile, time.Now()); !valid {
+			return aws.performSSOMLogin(ctx, loginProfile)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package aws
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"fancy-login/internal/config"
+	"fancy-login/internal/utils"
+)
+
+func TestExportCredentials(t *testing.T) {
+	testCases := []struct {
+		name          string
+		output        string
+		expectedLines []string
+		expectedError string
+		expiring      bool
+	}{
+		{"Session credentials",
+			`{"Version": 1, "AccessKeyId": "ASIATEMP", "SecretAccessKey": "secret", "SessionToken": "token", "Expiration": "2099-01-01T00:00:00+00:00"}`,
+			[]string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN"}, "", true},
+		{"Long-term credentials",
+			`{"Version": 1, "AccessKeyId": "AKIALONG", "SecretAccessKey": "secret"}`,
+			[]string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY"}, "", false},
+		{"No credentials", `{"Version": 1}`, nil, "returned no credentials", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			binDir := t.TempDir()
+			t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+			writeFakeHelper(t, binDir, "aws", "echo '"+tc.output+"'\n")
+
+			// An earlier plain export leaves the file world-readable
+			scriptPath := filepath.Join(t.TempDir(), "aws_profile.sh")
+			if err := os.WriteFile(scriptPath, []byte("export AWS_PROFILE=dev\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			manager := NewAWSManager(&config.Config{AWSProfileTemp: scriptPath}, utils.NewLogger(false), config.DefaultFancyConfig())
+
+			expiresAt, err := manager.ExportCredentials(context.Background(), "dev")
+			if tc.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedError) {
+					t.Errorf("Expected error containing %q, got %v", tc.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if expiresAt.IsZero() == tc.expiring {
+				t.Errorf("Expected expiring to be %v, got %v", tc.expiring, expiresAt)
+			}
+			if tc.expiring && !expiresAt.Equal(time.Date(2099, 1, 1, 0, 0, 0, 0, time.UTC)) {
+				t.Errorf("Expected the expiration of the credentials, got %v", expiresAt)
+			}
+
+			info, err := os.Stat(scriptPath)
+			if err != nil || info.Mode().Perm() != 0600 {
+				t.Errorf("Expected the export file to be 0600, got %v, %v", info, err)
+			}
+			if entries, _ := os.ReadDir(filepath.Dir(scriptPath)); len(entries) != 1 {
+				t.Errorf("Expected no temporary files next to the export file, got %v", entries)
+			}
+			data, _ := os.ReadFile(scriptPath)
+			if !strings.HasPrefix(string(data), "unalias aws 2>/dev/null\nexport AWS_PROFILE=dev\n") {
+				t.Errorf("Expected AWS_PROFILE first, got %q", data)
+			}
+			if lines := strings.Count(string(data), "\n"); lines != len(tc.expectedLines)+2 {
+				t.Errorf("Expected %d lines, got %q", len(tc.expectedLines)+2, data)
+			}
+			for _, name := range tc.expectedLines {
+				if !strings.Contains(string(data), "export "+name+"=") {
+					t.Errorf("Expected %s in %q", name, data)
+				}
+			}
+		})
+	}
+}
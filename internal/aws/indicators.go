@@ -0,0 +1,80 @@
+package aws
+
+import (
+	"time"
+
+	"fancy-login/internal/a11y"
+	"fancy-login/internal/config"
+)
+
+// Session indicators shown after profiles in the picker
+const (
+	sessionIndicatorActive  = "●"
+	sessionIndicatorExpired = "○"
+	// sessionIndicatorWorkers bounds how many token caches are read at once
+	sessionIndicatorWorkers = 8
+	// sessionIndicatorWait is how long the picker waits for the checks;
+	// profiles whose check is still running get no indicator
+	sessionIndicatorWait = 100 * time.Millisecond
+)
+
+// startSessionIndicators reads the SSO token cache of profiles in the
+// background and returns a function that collects the results, waiting
+// until wait has passed since the start at most. A profile maps to true if
+// its token outlasts margin and to false if it doesn't; profiles without a
+// cached token and checks that didn't finish in time are left out. Only
+// the cache is read, STS is never called.
+func startSessionIndicators(profiles []config.AWSProfile, lookup func(config.AWSProfile) ssoToken, margin, wait time.Duration) func() map[string]bool {
+	type result struct {
+		profile      string
+		valid, found bool
+	}
+	results := make(chan result, len(profiles))
+	sem := make(chan struct{}, sessionIndicatorWorkers)
+	deadline := time.Now().Add(wait)
+
+	for _, profile := range profiles {
+		go func(profile config.AWSProfile) {
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			token := lookup(profile)
+			results <- result{profile.Name, time.Until(token.ExpiresAt) > margin, token.Found}
+		}(profile)
+	}
+
+	return func() map[string]bool {
+		status := make(map[string]bool)
+		timer := time.NewTimer(time.Until(deadline))
+		defer timer.Stop()
+		for range profiles {
+			select {
+			case r := <-results:
+				if r.found {
+					status[r.profile] = r.valid
+				}
+			case <-timer.C:
+				return status
+			}
+		}
+		return status
+	}
+}
+
+// withSessionIndicator appends the session indicator of profile to
+// displayText, or returns it unchanged if the session state isn't known.
+// Screen reader mode spells the state out.
+func withSessionIndicator(displayText, profile string, status map[string]bool) string {
+	valid, known := status[profile]
+	switch {
+	case !known:
+		return displayText
+	case a11y.Enabled() && valid:
+		return displayText + " (session active)"
+	case a11y.Enabled():
+		return displayText + " (session expired)"
+	case valid:
+		return displayText + " " + sessionIndicatorActive
+	default:
+		return displayText + " " + sessionIndicatorExpired
+	}
+}
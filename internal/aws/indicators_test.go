@@ -0,0 +1,71 @@
+package aws
+
+import (
+	"testing"
+	"time"
+
+	"fancy-login/internal/a11y"
+	"fancy-login/internal/config"
+)
+
+func TestWithSessionIndicator(t *testing.T) {
+	status := map[string]bool{"dev": true, "prod": false}
+
+	testCases := []struct {
+		name         string
+		profile      string
+		screenReader bool
+		expected     string
+	}{
+		{"Active", "dev", false, "  Dev | eu-west-1 ●"},
+		{"Expired", "prod", false, "  Dev | eu-west-1 ○"},
+		{"Unknown", "staging", false, "  Dev | eu-west-1"},
+		{"Screen reader active", "dev", true, "  Dev | eu-west-1 (session active)"},
+		{"Screen reader expired", "prod", true, "  Dev | eu-west-1 (session expired)"},
+		{"Screen reader unknown", "staging", true, "  Dev | eu-west-1"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			a11y.Enable(tc.screenReader)
+			defer a11y.Enable(false)
+			if got := withSessionIndicator("  Dev | eu-west-1", tc.profile, status); got != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestStartSessionIndicators(t *testing.T) {
+	now := time.Now()
+	tokens := map[string]ssoToken{
+		"dev":  {Found: true, ExpiresAt: now.Add(time.Hour)},
+		"prod": {Found: true, ExpiresAt: now.Add(time.Minute)},
+	}
+	release := make(chan struct{})
+	defer close(release)
+	lookup := func(p config.AWSProfile) ssoToken {
+		if p.Name == "slow" {
+			<-release
+		}
+		return tokens[p.Name]
+	}
+	profiles := []config.AWSProfile{{Name: "dev"}, {Name: "prod"}, {Name: "none"}, {Name: "slow"}}
+
+	start := time.Now()
+	status := startSessionIndicators(profiles, lookup, 5*time.Minute, 50*time.Millisecond)()
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the slow check not to hold up the picker, waited %s", elapsed)
+	}
+	if valid, ok := status["dev"]; !ok || !valid {
+		t.Error("Expected dev to be active")
+	}
+	if valid, ok := status["prod"]; !ok || valid {
+		t.Error("Expected prod, inside the expiry margin, to be expired")
+	}
+	for _, profile := range []string{"none", "slow"} {
+		if _, ok := status[profile]; ok {
+			t.Errorf("Expected no indicator for %s", profile)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package aws
+
+import (
+	"time"
+
+	"fancy-login/internal/state"
+)
+
+// LastLogin returns the last completed login, or nil if none was recorded
+func LastLogin() *state.LastLogin {
+	st, err := state.Load()
+	if err != nil {
+		return nil
+	}
+	return st.LastLogin
+}
+
+// RecordLastLogin remembers profile and the context the login ended up in
+// for --last, the picker and switch
+func RecordLastLogin(profile, context string) error {
+	return state.Update(func(st *state.State) error {
+		st.LastLogin = &state.LastLogin{Profile: profile, Context: context, At: time.Now()}
+		st.AddRecentLogin(*st.LastLogin)
+		st.RecordProfileUse(profile, st.LastLogin.At)
+		return nil
+	})
+}
+
+// RecentLogins returns the last logins of distinct profiles, most recent
+// first, or nil if none were recorded
+func RecentLogins() []state.LastLogin {
+	st, err := state.Load()
+	if err != nil {
+		return nil
+	}
+	return st.RecentLogins
+}
+
+// ProfileUsage returns when each profile was last logged in to and how
+// often, or nil if nothing was recorded
+func ProfileUsage() map[string]*state.ProfileUsage {
+	st, err := state.Load()
+	if err != nil {
+		return nil
+	}
+	return st.ProfileUsage
+}
+
+// pickerStartPos returns the 1-based picker line of profile, or 0 if the
+// picker doesn't show it
+func pickerStartPos(displayProfiles []ProfileDisplayInfo, profile string) int {
+	if profile == "" {
+		return 0
+	}
+	for i, p := range displayProfiles {
+		if p.Name == profile {
+			return i + 1
+		}
+	}
+	return 0
+}
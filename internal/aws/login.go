@@ -0,0 +1,215 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+
+	"fancy-login/internal/config"
+	"fancy-login/internal/prompt"
+	"fancy-login/internal/utils"
+)
+
+// LoginPlan is the action HandleAWSLogin decided to take for a profile
+type LoginPlan string
+
+const (
+	PlanNone           LoginPlan = "none"            // session is valid, nothing to do
+	PlanSSOLogin       LoginPlan = "sso-login"       // run `aws sso login`
+	PlanPromptContinue LoginPlan = "prompt-continue" // non-SSO profile, ask whether to continue
+	PlanFail           LoginPlan = "fail"            // non-SSO profile and nobody to ask
+	PlanProcessFailed  LoginPlan = "process-failed"  // credential_process helper failed, report why
+	PlanAssumeRole     LoginPlan = "assume-role"     // log in to the source profile, then assume the role
+	PlanMFA            LoginPlan = "mfa"             // ask for an MFA code and start a session with it
+	PlanStaticFailed   LoginPlan = "static-failed"   // static keys were rejected, report why
+)
+
+// LoginProfileInfo describes the profile and terminal a login is planned for
+type LoginProfileInfo struct {
+	SSO         bool // profile has sso_* settings in ~/.aws/config
+	Interactive bool // a terminal is available for prompts
+	// CredentialProcess is the profile's external credential helper, if any
+	CredentialProcess string
+	// SourceProfile and RoleARN are set for a role_arn/source_profile chain
+	SourceProfile string
+	RoleARN       string
+	// MFASerial is the MFA device the profile's sessions need a code from
+	MFASerial string
+	// ForceSource logs in to the source profile of a chain even if its
+	// session is still valid
+	ForceSource bool
+	// StaticCredentials is set for a profile authenticating with an access
+	// key from the shared credentials file alone
+	StaticCredentials bool
+}
+
+// SessionState is the result of checking the profile's current session
+type SessionState struct {
+	Valid bool
+	Err   error // why the session is invalid, if it was checked
+}
+
+// ErrLoginDeclined is returned when the user chose not to continue without
+// a login. It is a cancellation, not an authentication failure.
+var ErrLoginDeclined = fmt.Errorf("%w: chose to exit due to authentication issues", utils.ErrCancelled)
+
+// PlanLogin decides how to authenticate a profile. It has no side effects, so
+// status, keepalive and pre-warm features can reuse the same decision.
+func PlanLogin(info LoginProfileInfo, session SessionState, force bool) LoginPlan {
+	// There's nothing to log in to for an external helper; forcing only
+	// re-checks that it still works
+	if !info.SSO && info.CredentialProcess != "" {
+		if session.Valid {
+			return PlanNone
+		}
+		return PlanProcessFailed
+	}
+	// Static keys can't be renewed either, only checked
+	if !info.SSO && info.StaticCredentials {
+		if session.Valid {
+			return PlanNone
+		}
+		return PlanStaticFailed
+	}
+	if !force && session.Valid {
+		return PlanNone
+	}
+	// Only a person can type an MFA code
+	if info.MFASerial != "" {
+		if info.Interactive {
+			return PlanMFA
+		}
+		return PlanFail
+	}
+	// A chained role is as good as the session of its source profile
+	if info.SourceProfile != "" {
+		return PlanAssumeRole
+	}
+	if info.SSO {
+		return PlanSSOLogin
+	}
+	if info.Interactive {
+		return PlanPromptContinue
+	}
+	return PlanFail
+}
+
+// executeLoginPlan carries out a plan. prompter may be nil unless the plan
+// is PlanPromptContinue.
+func (aws *AWSManager) executeLoginPlan(ctx context.Context, profile string, plan LoginPlan, info LoginProfileInfo, session SessionState, prompter *prompt.Prompter) error {
+	switch plan {
+	case PlanNone:
+		aws.sessionReused = true
+		if info.CredentialProcess != "" {
+			aws.logger.LogSuccess(fmt.Sprintf("Credentials from external process are valid for %s.", profile))
+			return nil
+		}
+		if info.StaticCredentials {
+			aws.logger.LogSuccess(fmt.Sprintf("Static credentials are valid for %s.", profile))
+			return nil
+		}
+		if info.RoleARN != "" {
+			aws.logger.LogSuccess(fmt.Sprintf("Assumed role %s is still valid for %s.", info.RoleARN, profile))
+			return nil
+		}
+		aws.logger.LogSuccess(fmt.Sprintf("AWS SSO session is still valid for %s.", profile))
+		return nil
+
+	case PlanProcessFailed:
+		return aws.credentialProcessFailure(ctx, profile, info.CredentialProcess, session.Err)
+
+	case PlanStaticFailed:
+		return staticCredentialsFailure(profile, session.Err)
+
+	case PlanSSOLogin:
+		if err := aws.ensurePortalReachable(ctx, profile, prompter); err != nil {
+			return err
+		}
+		if err := aws.performSSOMLogin(ctx, profile); err != nil {
+			return err
+		}
+		aws.ssoLoginPerformed = true
+		return nil
+
+	case PlanAssumeRole:
+		return aws.assumeRoleChain(ctx, profile, info)
+
+	case PlanMFA:
+		p, _ := aws.snapshot.AWSProfile(profile)
+		return aws.mfaLogin(ctx, p, info, prompter)
+
+	case PlanPromptContinue:
+		aws.logger.LogWarning(fmt.Sprintf("Unable to authenticate with profile %s. This might not be an SSO profile.", profile))
+		question := fmt.Sprintf("%sDo you want to continue anyway?%s", config.Accent, config.Reset)
+		if !prompter.Confirm(question, false) {
+			return ErrLoginDeclined
+		}
+		aws.logger.LogWarning("Continuing with potentially invalid credentials...")
+		return nil
+
+	case PlanFail:
+		if info.MFASerial != "" {
+			return fmt.Errorf("profile %s needs an MFA code and no terminal is available to enter it", profile)
+		}
+		aws.logger.LogWarning(fmt.Sprintf("Unable to authenticate with profile %s. This might not be an SSO profile.", profile))
+		return fmt.Errorf("profile %s is not an SSO profile and no terminal is available to confirm", profile)
+	}
+
+	return fmt.Errorf("unknown login plan: %s", plan)
+}
+
+// reportLoginPlan tells a dry run what executeLoginPlan would do. Plans
+// that would fail are returned as errors.
+func (aws *AWSManager) reportLoginPlan(profile string, plan LoginPlan, info LoginProfileInfo, session SessionState) error {
+	switch plan {
+	case PlanNone:
+		aws.logger.LogPlanned(fmt.Sprintf("reuse the valid session of %s without logging in", profile))
+	case PlanSSOLogin:
+		command := "run: aws sso login --profile " + profile
+		if aws.noBrowser {
+			command += " --no-browser"
+		}
+		aws.logger.LogPlanned(command)
+	case PlanAssumeRole:
+		aws.logger.LogPlanned(fmt.Sprintf("log in to source profile %s, then assume %s for %s", info.SourceProfile, info.RoleARN, profile))
+	case PlanMFA:
+		aws.logger.LogPlanned(fmt.Sprintf("ask for the MFA code of %s and start a session for %s with it", info.MFASerial, profile))
+	case PlanPromptContinue:
+		aws.logger.LogPlanned(fmt.Sprintf("ask whether to continue without a valid session for %s", profile))
+	case PlanProcessFailed:
+		return fmt.Errorf("credential_process of %s fails: %w", profile, session.Err)
+	case PlanStaticFailed:
+		return staticCredentialsFailure(profile, session.Err)
+	case PlanFail:
+		if info.MFASerial != "" {
+			return fmt.Errorf("profile %s has no valid MFA session and no terminal is available to enter a code", profile)
+		}
+		return fmt.Errorf("profile %s has no valid session, is not an SSO profile and no terminal is available to confirm", profile)
+	default:
+		return fmt.Errorf("unknown login plan: %s", plan)
+	}
+	return nil
+}
+
+// staticCredentialsFailure explains why the static keys of a profile don't
+// work; there is nothing to log in to, so they have to be replaced
+func staticCredentialsFailure(profile string, stsErr error) error {
+	return utils.WithExitCode(utils.ExitAWSAuth, fmt.Errorf("static credentials of %s in %s were rejected: %s",
+		profile, config.GetAWSCredentialsPath(), stsErrorMessage(stsErr)))
+}
+
+// assumeRoleChain logs in to the source profile of a role chain, which may
+// itself be a chain, then checks that the role can be assumed with it
+func (aws *AWSManager) assumeRoleChain(ctx context.Context, profile string, info LoginProfileInfo) error {
+	aws.logger.FancyLog(fmt.Sprintf("%s assumes %s with the credentials of %s", profile, info.RoleARN, info.SourceProfile))
+	if err := aws.HandleAWSLogin(ctx, info.SourceProfile, info.ForceSource); err != nil {
+		return fmt.Errorf("source profile %s of %s: %w", info.SourceProfile, profile, err)
+	}
+
+	identity, err := aws.callerIdentity(ctx, profile)
+	if err != nil {
+		return fmt.Errorf("failed to assume %s for %s with %s: %w", info.RoleARN, profile, info.SourceProfile, err)
+	}
+	aws.assumedRoleARN = identity.Arn
+	aws.logger.LogSuccess(fmt.Sprintf("Assumed role %s for %s.", info.RoleARN, profile))
+	return nil
+}
@@ -0,0 +1,391 @@
+package aws
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"fancy-login/internal/config"
+	"fancy-login/internal/prompt"
+	"fancy-login/internal/utils"
+)
+
+func TestPlanLogin(t *testing.T) {
+	testCases := []struct {
+		name        string
+		valid       bool
+		sso         bool
+		force       bool
+		interactive bool
+		expected    LoginPlan
+	}{
+		{"Valid SSO session", true, true, false, true, PlanNone},
+		{"Valid SSO session non-interactive", true, true, false, false, PlanNone},
+		{"Valid non-SSO session", true, false, false, true, PlanNone},
+		{"Valid non-SSO session non-interactive", true, false, false, false, PlanNone},
+		{"Valid SSO session forced", true, true, true, true, PlanSSOLogin},
+		{"Valid SSO session forced non-interactive", true, true, true, false, PlanSSOLogin},
+		{"Valid non-SSO session forced", true, false, true, true, PlanPromptContinue},
+		{"Valid non-SSO session forced non-interactive", true, false, true, false, PlanFail},
+		{"Invalid SSO session", false, true, false, true, PlanSSOLogin},
+		{"Invalid SSO session non-interactive", false, true, false, false, PlanSSOLogin},
+		{"Invalid non-SSO session", false, false, false, true, PlanPromptContinue},
+		{"Invalid non-SSO session non-interactive", false, false, false, false, PlanFail},
+		{"Invalid SSO session forced", false, true, true, true, PlanSSOLogin},
+		{"Invalid SSO session forced non-interactive", false, true, true, false, PlanSSOLogin},
+		{"Invalid non-SSO session forced", false, false, true, true, PlanPromptContinue},
+		{"Invalid non-SSO session forced non-interactive", false, false, true, false, PlanFail},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			info := LoginProfileInfo{SSO: tc.sso, Interactive: tc.interactive}
+			plan := PlanLogin(info, SessionState{Valid: tc.valid}, tc.force)
+			if plan != tc.expected {
+				t.Errorf("Expected plan %s, got %s", tc.expected, plan)
+			}
+		})
+	}
+}
+
+func TestPlanLoginStaticCredentials(t *testing.T) {
+	testCases := []struct {
+		name     string
+		valid    bool
+		force    bool
+		expected LoginPlan
+	}{
+		{"Valid", true, false, PlanNone},
+		{"Valid forced", true, true, PlanNone},
+		{"Rejected", false, false, PlanStaticFailed},
+		{"Rejected forced", false, true, PlanStaticFailed},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			info := LoginProfileInfo{StaticCredentials: true, Interactive: true}
+			if plan := PlanLogin(info, SessionState{Valid: tc.valid}, tc.force); plan != tc.expected {
+				t.Errorf("Expected plan %s, got %s", tc.expected, plan)
+			}
+		})
+	}
+}
+
+func TestHandleAWSLoginStaticCredentials(t *testing.T) {
+	testCases := []struct {
+		name          string
+		stsResponse   string
+		force         bool
+		expectedError string
+	}{
+		{"Keys valid", stsIdentityXML("123456789012"), false, ""},
+		{"Keys valid forced", stsIdentityXML("123456789012"), true, ""},
+		{"Keys rejected", stsErrorXML("InvalidClientTokenId"), false, "InvalidClientTokenId"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			t.Setenv("AWS_CONFIG_FILE", filepath.Join(dir, "config"))
+			t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(dir, "credentials"))
+			t.Setenv("FANCY_STATE_DIR", t.TempDir())
+			binDir := t.TempDir()
+			t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+			// Any attempt at an SSO login would fail the test
+			writeFakeHelper(t, binDir, "aws", "echo 'unexpected aws CLI call' >&2\nexit 1\n")
+
+			credentials := "[ci]\naws_access_key_id = AKIACI\naws_secret_access_key = secret\n"
+			if err := os.WriteFile(filepath.Join(dir, "credentials"), []byte(credentials), 0600); err != nil {
+				t.Fatal(err)
+			}
+
+			manager := NewAWSManager(config.NewConfig(), utils.NewLogger(false), config.DefaultFancyConfig())
+			useTestSTSServer(t, manager, func(w http.ResponseWriter, r *http.Request) {
+				if strings.Contains(tc.stsResponse, "ErrorResponse") {
+					w.WriteHeader(http.StatusForbidden)
+				}
+				w.Write([]byte(tc.stsResponse))
+			})
+			err := manager.HandleAWSLogin(context.Background(), "ci", tc.force)
+			if tc.expectedError == "" {
+				if err != nil {
+					t.Errorf("Expected the keys to be accepted without prompts, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.expectedError) {
+				t.Errorf("Expected the STS error, got %v", err)
+			}
+			if code := utils.ExitCode(err, utils.ExitFailure); code != utils.ExitAWSAuth {
+				t.Errorf("Expected exit code %d, got %d", utils.ExitAWSAuth, code)
+			}
+		})
+	}
+}
+
+func TestPlanLoginRoleChain(t *testing.T) {
+	info := LoginProfileInfo{SourceProfile: "sso", RoleARN: "arn:aws:iam::123456789012:role/Admin"}
+	testCases := []struct {
+		name     string
+		valid    bool
+		force    bool
+		expected LoginPlan
+	}{
+		{"Valid role session", true, false, PlanNone},
+		{"Invalid role session", false, false, PlanAssumeRole},
+		{"Forced", true, true, PlanAssumeRole},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if plan := PlanLogin(info, SessionState{Valid: tc.valid}, tc.force); plan != tc.expected {
+				t.Errorf("Expected plan %s, got %s", tc.expected, plan)
+			}
+		})
+	}
+}
+
+func TestPlanLoginMFA(t *testing.T) {
+	testCases := []struct {
+		name        string
+		valid       bool
+		force       bool
+		interactive bool
+		expected    LoginPlan
+	}{
+		{"Cached MFA session", true, false, true, PlanNone},
+		{"No MFA session", false, false, true, PlanMFA},
+		{"Forced", true, true, true, PlanMFA},
+		{"No MFA session non-interactive", false, false, false, PlanFail},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			info := LoginProfileInfo{MFASerial: "arn:aws:iam::123456789012:mfa/me", Interactive: tc.interactive}
+			if plan := PlanLogin(info, SessionState{Valid: tc.valid}, tc.force); plan != tc.expected {
+				t.Errorf("Expected plan %s, got %s", tc.expected, plan)
+			}
+		})
+	}
+}
+
+// chainSTS fails the first check of each profile in expired, as if its
+// role could only be assumed once the source logged in
+type chainSTS struct {
+	expired map[string]bool
+	calls   []string
+}
+
+func (f *chainSTS) CallerIdentity(ctx context.Context, profile string) (CallerIdentity, error) {
+	f.calls = append(f.calls, profile)
+	if f.expired[profile] {
+		f.expired[profile] = false
+		return CallerIdentity{}, errors.New("ExpiredToken")
+	}
+	return CallerIdentity{Account: "123456789012", Arn: "arn:aws:sts::123456789012:assumed-role/" + profile + "/me"}, nil
+}
+
+// setupRoleChainConfig writes an AWS config where deploy assumes a role
+// via admin, which assumes one via the SSO profile sso
+func setupRoleChainConfig(t *testing.T, extra string) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("AWS_CONFIG_FILE", "")
+	awsConfig := "[profile sso]\nsso_start_url = https://acme.awsapps.com/start\nsso_region = eu-central-1\n\n" +
+		"[profile admin]\nrole_arn = arn:aws:iam::123456789012:role/Admin\nsource_profile = sso\n\n" +
+		"[profile deploy]\nrole_arn = arn:aws:iam::210987654321:role/Deploy\nsource_profile = admin\n" + extra
+	if err := os.MkdirAll(filepath.Join(home, ".aws"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".aws", "config"), []byte(awsConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHandleAWSLoginRoleChain(t *testing.T) {
+	setupRoleChainConfig(t, "")
+	manager := NewAWSManager(config.NewConfig(), utils.NewLogger(false), config.DefaultFancyConfig())
+	sts := &chainSTS{expired: map[string]bool{"deploy": true, "admin": true}}
+	manager.SetSTSClient(sts)
+
+	if err := manager.HandleAWSLogin(context.Background(), "deploy", false); err != nil {
+		t.Fatalf("Expected the chain to log in, got %v", err)
+	}
+	if got := strings.Join(sts.calls, ","); got != "deploy,admin,sso,admin,deploy" {
+		t.Errorf("Expected each link checked after its source, got %s", got)
+	}
+	if arn := manager.AssumedRoleARN(); arn != "arn:aws:sts::123456789012:assumed-role/deploy/me" {
+		t.Errorf("Expected the assumed role of deploy, got %q", arn)
+	}
+}
+
+func TestHandleAWSLoginRoleChainCycle(t *testing.T) {
+	setupRoleChainConfig(t, "\n[profile loop-a]\nrole_arn = arn:aws:iam::123456789012:role/A\nsource_profile = loop-b\n"+
+		"\n[profile loop-b]\nrole_arn = arn:aws:iam::123456789012:role/B\nsource_profile = loop-a\n")
+	manager := NewAWSManager(config.NewConfig(), utils.NewLogger(false), config.DefaultFancyConfig())
+	manager.SetSTSClient(&chainSTS{})
+
+	err := manager.HandleAWSLogin(context.Background(), "loop-a", false)
+	if err == nil || !strings.Contains(err.Error(), "leads back to") {
+		t.Fatalf("Expected a cycle error, got %v", err)
+	}
+	if code := utils.ExitCode(err, utils.ExitAWSAuth); code != utils.ExitConfig {
+		t.Errorf("Expected exit code %d, got %d", utils.ExitConfig, code)
+	}
+}
+
+// hangingSTS never answers, like STS behind a VPN that dropped
+type hangingSTS struct{}
+
+func (hangingSTS) CallerIdentity(ctx context.Context, profile string) (CallerIdentity, error) {
+	<-ctx.Done()
+	return CallerIdentity{}, ctx.Err()
+}
+
+func TestHandleAWSLoginSessionCheckTimeout(t *testing.T) {
+	setupRoleChainConfig(t, "")
+	fancyConfig := config.DefaultFancyConfig()
+	fancyConfig.Settings.AWSNetworkTimeout = 1
+	manager := NewAWSManager(config.NewConfig(), utils.NewLogger(false), fancyConfig)
+	manager.SetSTSClient(hangingSTS{})
+
+	err := manager.HandleAWSLogin(context.Background(), "deploy", false)
+	var timeoutErr *utils.TimeoutError
+	if !errors.As(err, &timeoutErr) {
+		t.Fatalf("Expected a timeout instead of a login attempt, got %v", err)
+	}
+	if timeoutErr.Step != "aws sts get-caller-identity" || timeoutErr.Timeout != time.Second {
+		t.Errorf("Expected the STS check to time out after 1s, got %+v", timeoutErr)
+	}
+	if code := utils.ExitCode(err, utils.ExitFailure); code != utils.ExitAWSAuth {
+		t.Errorf("Expected exit code %d, got %d", utils.ExitAWSAuth, code)
+	}
+}
+
+func TestExecuteLoginPlan(t *testing.T) {
+	manager := NewAWSManager(config.NewConfig(), utils.NewLogger(false), config.DefaultFancyConfig())
+
+	testCases := []struct {
+		name        string
+		plan        LoginPlan
+		input       string
+		expectedErr error
+		expectFail  bool
+		// expectedCode is the exit code the login command ends with
+		expectedCode int
+	}{
+		{name: "Nothing to do", plan: PlanNone},
+		{name: "Continue confirmed", plan: PlanPromptContinue, input: "y\n"},
+		{name: "Continue declined", plan: PlanPromptContinue, input: "n\n", expectedErr: ErrLoginDeclined, expectedCode: utils.ExitCancelled},
+		{name: "Continue defaults to no", plan: PlanPromptContinue, input: "\n", expectedErr: ErrLoginDeclined, expectedCode: utils.ExitCancelled},
+		{name: "Fail without terminal", plan: PlanFail, expectFail: true, expectedCode: utils.ExitAWSAuth},
+		{name: "Unknown plan", plan: LoginPlan("bogus"), expectFail: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			prompter := prompt.NewPrompter(bufio.NewReader(strings.NewReader(tc.input)), io.Discard, nil, nil)
+
+			err := manager.executeLoginPlan(context.Background(), "dev", tc.plan, LoginProfileInfo{}, SessionState{}, prompter)
+
+			switch {
+			case tc.expectedErr != nil:
+				if !errors.Is(err, tc.expectedErr) {
+					t.Errorf("Expected %v, got %v", tc.expectedErr, err)
+				}
+			case tc.expectFail:
+				if err == nil {
+					t.Error("Expected an error, got nil")
+				}
+			case err != nil:
+				t.Errorf("Expected no error, got %v", err)
+			}
+			if tc.expectedCode != 0 {
+				if code := utils.ExitCode(err, utils.ExitAWSAuth); code != tc.expectedCode {
+					t.Errorf("Expected exit code %d, got %d", tc.expectedCode, code)
+				}
+			}
+		})
+	}
+}
+
+func TestReportLoginPlan(t *testing.T) {
+	manager := NewAWSManager(config.NewConfig(), utils.NewLogger(false), config.DefaultFancyConfig())
+	manager.SetDryRun(true)
+
+	testCases := []struct {
+		plan       LoginPlan
+		expectFail bool
+	}{
+		{PlanNone, false},
+		{PlanSSOLogin, false},
+		{PlanAssumeRole, false},
+		{PlanMFA, false},
+		{PlanPromptContinue, false},
+		{PlanProcessFailed, true},
+		{PlanStaticFailed, true},
+		{PlanFail, true},
+		{LoginPlan("bogus"), true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(string(tc.plan), func(t *testing.T) {
+			session := SessionState{Err: errors.New("helper exited 1")}
+			err := manager.reportLoginPlan("dev", tc.plan, LoginProfileInfo{SourceProfile: "sso", RoleARN: "arn:aws:iam::123456789012:role/Admin"}, session)
+			if tc.expectFail && err == nil {
+				t.Error("Expected an error, got nil")
+			}
+			if !tc.expectFail && err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestPerformSSOMLoginSharedSession(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("AWS_CONFIG_FILE", "")
+	awsConfig := "[sso-session acme]\nsso_start_url = https://acme.awsapps.com/start\nsso_region = eu-central-1\n\n" +
+		"[profile dev]\nsso_session = acme\nsso_account_id = 123456789012\n\n" +
+		"[profile prod]\nsso_session = acme\nsso_account_id = 210987654321\n\n" +
+		"[profile legacy]\nsso_start_url = https://legacy.awsapps.com/start\nsso_region = us-east-1\n"
+	if err := os.MkdirAll(filepath.Join(home, ".aws"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, ".aws", "config"), []byte(awsConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+	binDir := t.TempDir()
+	calls := filepath.Join(binDir, "calls")
+	writeFakeHelper(t, binDir, "aws", "echo \"$@\" >> "+calls+"\n")
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	manager := NewAWSManager(config.NewConfig(), utils.NewLogger(false), config.DefaultFancyConfig())
+	manager.SetSTSClient(fakeSTS{
+		"dev":    {Account: "123456789012"},
+		"prod":   {Account: "210987654321"},
+		"legacy": {Account: "111111111111"},
+	})
+	for _, profile := range []string{"dev", "prod", "legacy"} {
+		if err := manager.performSSOMLogin(context.Background(), profile); err != nil {
+			t.Fatalf("Login of %s failed: %v", profile, err)
+		}
+	}
+
+	data, err := os.ReadFile(calls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "sso login --sso-session acme\nsso login --profile legacy\n"
+	if got := strings.ReplaceAll(string(data), " --no-browser", ""); got != expected {
+		t.Errorf("Expected one login per sso-session, got %q", got)
+	}
+}
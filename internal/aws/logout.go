@@ -0,0 +1,115 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+
+	"fancy-login/internal/platform"
+	"fancy-login/internal/state"
+	"fancy-login/internal/utils"
+)
+
+var (
+	// ErrNotSSOProfile is returned by SSOLogout for profiles without an SSO
+	// session to end
+	ErrNotSSOProfile = errors.New("not an SSO profile")
+	// ErrDockerNotInstalled is returned by ECRLogout when there is no docker
+	// CLI to log out of
+	ErrDockerNotInstalled = errors.New("docker is not installed")
+)
+
+// ExportedProfile returns the profile the shell integration file exports, or
+// "" if there is none
+func (aws *AWSManager) ExportedProfile() (string, error) {
+	return platform.ReadProfileScript(aws.config.AWSProfileTemp)
+}
+
+// SSOLogout ends the profile's SSO session with `aws sso logout`
+func (aws *AWSManager) SSOLogout(ctx context.Context, profile string) error {
+	isSSO, err := aws.isSSOMProfile(profile)
+	if err != nil {
+		return err
+	}
+	if !isSSO {
+		return ErrNotSSOProfile
+	}
+
+	timeout := aws.fancyConfig.Settings.AWSNetworkTimeoutDuration()
+	ctx, cancel := utils.WithStepTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := utils.CommandContext(ctx, "aws", "sso", "logout", "--profile", profile)
+	if err := cmd.Run(); err != nil {
+		return utils.StepError(ctx, "aws sso logout", timeout, fmt.Errorf("aws sso logout failed: %w", err))
+	}
+	return nil
+}
+
+// ECRLogout runs `docker logout` for the profile's ECR registry. The account
+// comes from the profile's account_id or sso_account_id, since the session
+// may already be gone.
+func (aws *AWSManager) ECRLogout(ctx context.Context, profile string) (Registry, error) {
+	accountID := aws.configuredAccountID(profile)
+	if accountID == "" {
+		return Registry{}, fmt.Errorf("cannot resolve the ECR registry of %s: no account_id configured", profile)
+	}
+	registry := aws.ecrRegistry(profile, accountID)
+
+	if _, err := exec.LookPath("docker"); err != nil {
+		return registry, ErrDockerNotInstalled
+	}
+	timeout := aws.fancyConfig.Settings.DockerTimeoutDuration()
+	ctx, cancel := utils.WithStepTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := utils.CommandContext(ctx, "docker", "logout", registry.Host())
+	if err := cmd.Run(); err != nil {
+		return registry, utils.StepError(ctx, "docker logout", timeout, fmt.Errorf("docker logout failed: %w", err))
+	}
+	return registry, nil
+}
+
+// configuredAccountID returns the profile's account from its account_id or
+// sso_account_id, without asking STS
+func (aws *AWSManager) configuredAccountID(profile string) string {
+	if pc, err := aws.fancyConfig.GetProfileConfig(profile); err == nil && pc.AccountID != "" {
+		return pc.AccountID
+	}
+	p, _ := aws.snapshot.AWSProfile(profile)
+	return p.AccountID
+}
+
+// RemoveProfileExport deletes the shell integration files when they export
+// profile, so new shells don't pick up a logged out profile. It returns the
+// files removed.
+func (aws *AWSManager) RemoveProfileExport(profile string) ([]string, error) {
+	exported, err := aws.ExportedProfile()
+	if err != nil || exported != profile {
+		return nil, err
+	}
+	var removed []string
+	for _, script := range platform.ProfileScripts(aws.config.AWSProfileTemp, profile) {
+		if err := os.Remove(script.Path); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return removed, err
+		}
+		removed = append(removed, script.Path)
+	}
+	return removed, nil
+}
+
+// ForgetSession records that the profile has no session and drops its ECR
+// login, so status doesn't report a session that was just ended
+func ForgetSession(profile string) error {
+	return state.Update(func(s *state.State) error {
+		s.RecordSession(profile, state.SessionRecord{Status: string(StatusNoSession), CheckedAt: time.Now()})
+		delete(s.ECRLogins, profile)
+		return nil
+	})
+}
@@ -0,0 +1,251 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"fancy-login/internal/a11y"
+	"fancy-login/internal/config"
+	"fancy-login/internal/state"
+	"fancy-login/internal/utils"
+)
+
+// metadataRefreshInterval is how often a profile's metadata is re-resolved
+const metadataRefreshInterval = 24 * time.Hour
+
+// accountAliasTimeout bounds the alias lookup of the background refresh,
+// which is only nice to have
+const accountAliasTimeout = 5 * time.Second
+
+// ProfileMetadata is the account information resolved for a profile
+type ProfileMetadata struct {
+	Profile      string
+	AccountID    string
+	AccountAlias string
+	Err          error
+}
+
+// MetadataRefresh is a background refresh started after login
+type MetadataRefresh struct {
+	aws    *AWSManager
+	cancel context.CancelFunc
+	done   chan ProfileMetadata
+}
+
+// StartMetadataRefresh re-resolves the account alias of a configured profile
+// in the background, at most once per day. The refresh only exists to update
+// the profile config, so it needs auto_update_account_ids as well. The
+// account ID is left to SummaryAccountID. It returns nil when no refresh is
+// due.
+func (aws *AWSManager) StartMetadataRefresh(ctx context.Context, profile string) *MetadataRefresh {
+	settings := aws.fancyConfig.Settings
+	if !settings.BackgroundRefreshEnabled() || !settings.AutoUpdateAccountIDsEnabled() {
+		return nil
+	}
+	if _, exists := aws.fancyConfig.ProfileConfigs[profile]; !exists {
+		return nil
+	}
+	if st, err := state.Load(); err == nil && !metadataRefreshDue(st.MetadataRefreshedAt[profile], time.Now()) {
+		return nil
+	}
+
+	// The goroutine must not touch ProfileConfigs, which the main flow may
+	// write meanwhile, so the stored alias is read up front
+	storedAlias := aws.fancyConfig.ProfileConfigs[profile].AccountAlias
+
+	ctx, cancel := context.WithCancel(ctx)
+	refresh := &MetadataRefresh{aws: aws, cancel: cancel, done: make(chan ProfileMetadata, 1)}
+	go func() {
+		aliasCtx, cancelAlias := context.WithTimeout(ctx, accountAliasTimeout)
+		defer cancelAlias()
+		alias, err := aws.getAccountAlias(aliasCtx, profile)
+		if isAccessDenied(err) {
+			// Without iam:ListAccountAliases the bare ID is all there is;
+			// the refresh counts as done so the next runs don't ask again
+			aws.logger.FancyLog(fmt.Sprintf("No permission to read the account alias of %s", profile))
+			alias, err = storedAlias, nil
+		}
+		refresh.done <- ProfileMetadata{Profile: profile, AccountAlias: alias, Err: err}
+	}()
+	return refresh
+}
+
+// Finish waits at most deadline for the refresh, then applies any changes to
+// the configuration. A refresh still running at the deadline is cancelled so
+// it never delays process exit. Finish is safe to call on a nil refresh.
+func (r *MetadataRefresh) Finish(deadline time.Duration) {
+	if r == nil {
+		return
+	}
+	defer r.cancel()
+
+	select {
+	case md := <-r.done:
+		r.aws.applyMetadata([]ProfileMetadata{md})
+	case <-time.After(deadline):
+		r.aws.logger.FancyLog("Background metadata refresh did not finish in time, skipping")
+	}
+}
+
+// RefreshAllMetadata re-resolves account ID and alias of every configured
+// profile, printing a progress line, and saves any changes
+func (aws *AWSManager) RefreshAllMetadata(ctx context.Context) error {
+	profiles, err := aws.getAWSConfigProfiles()
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	for _, p := range profiles {
+		if _, exists := aws.fancyConfig.ProfileConfigs[p]; exists {
+			names = append(names, p)
+		}
+	}
+	if len(names) == 0 {
+		aws.logger.LogInfo("No configured profiles to refresh")
+		return nil
+	}
+
+	var results []ProfileMetadata
+	for i, profile := range names {
+		if a11y.Enabled() {
+			fmt.Fprintf(aws.logger.Output(), "Refreshing account metadata %d/%d: %s\n", i+1, len(names), profile)
+		} else {
+			fmt.Fprintf(aws.logger.Output(), "\r%sRefreshing account metadata %d/%d: %-40s%s", config.Accent, i+1, len(names), profile, config.Reset)
+		}
+
+		md := ProfileMetadata{Profile: profile}
+		md.AccountID, md.Err = aws.getAccountID(ctx, profile)
+		if md.Err == nil {
+			md.AccountAlias, md.Err = aws.getAccountAlias(ctx, profile)
+		}
+		results = append(results, md)
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	if !a11y.Enabled() {
+		fmt.Fprintf(aws.logger.Output(), "\r%80s\r", "")
+	}
+
+	failed := 0
+	for _, md := range results {
+		if md.Err != nil {
+			failed++
+			aws.logger.LogWarning(fmt.Sprintf("Could not refresh %s: %v", md.Profile, md.Err))
+		}
+	}
+	changed := aws.applyMetadata(results)
+	aws.logger.LogInfo(fmt.Sprintf("Refreshed %d profiles (%d changed, %d failed)", len(results)-failed, changed, failed))
+	return nil
+}
+
+// applyMetadata updates profile configs from refresh results, saves the
+// configuration when anything changed and records the refresh time. An empty
+// AccountID keeps the profile's current one. It returns the number of changed
+// profiles.
+func (aws *AWSManager) applyMetadata(results []ProfileMetadata) int {
+	now := time.Now()
+
+	changed := 0
+	var refreshed []string
+	for _, md := range results {
+		if md.Err != nil {
+			aws.logger.FancyLog(fmt.Sprintf("Metadata refresh for %s failed: %v", md.Profile, md.Err))
+			continue
+		}
+		refreshed = append(refreshed, md.Profile)
+
+		pc, exists := aws.fancyConfig.ProfileConfigs[md.Profile]
+		if !exists {
+			continue
+		}
+		changes := metadataChanges(pc, md)
+		if len(changes) == 0 {
+			continue
+		}
+		if md.AccountID != "" {
+			pc.AccountID = md.AccountID
+		}
+		pc.AccountAlias = md.AccountAlias
+		aws.fancyConfig.ProfileConfigs[md.Profile] = pc
+		aws.logger.LogInfo(fmt.Sprintf("Updated %s: %s", md.Profile, strings.Join(changes, ", ")))
+		changed++
+	}
+
+	if changed > 0 {
+		if err := aws.fancyConfig.SaveFancyConfig(); err != nil {
+			aws.logger.LogWarning(fmt.Sprintf("Failed to save refreshed metadata: %v", err))
+		}
+	}
+	if len(refreshed) > 0 {
+		err := state.Update(func(st *state.State) error {
+			for _, profile := range refreshed {
+				st.MarkMetadataRefreshed(profile, now)
+			}
+			return nil
+		})
+		if err != nil {
+			aws.logger.LogWarning(fmt.Sprintf("Failed to record the metadata refresh: %v", err))
+		}
+	}
+	return changed
+}
+
+// metadataChanges describes how refreshed metadata differs from a profile config
+func metadataChanges(pc config.ProfileConfig, md ProfileMetadata) []string {
+	var changes []string
+	if md.AccountID != "" && md.AccountID != pc.AccountID {
+		changes = append(changes, fmt.Sprintf("account ID %s → %s", valueOrNone(pc.AccountID), md.AccountID))
+	}
+	if md.AccountAlias != pc.AccountAlias {
+		changes = append(changes, fmt.Sprintf("account alias %s → %s", valueOrNone(pc.AccountAlias), valueOrNone(md.AccountAlias)))
+	}
+	return changes
+}
+
+// metadataRefreshDue reports whether a profile last refreshed at last is due
+func metadataRefreshDue(last, now time.Time) bool {
+	return now.Sub(last) >= metadataRefreshInterval
+}
+
+// valueOrNone renders an empty value readably in change messages
+func valueOrNone(value string) string {
+	if value == "" {
+		return "(none)"
+	}
+	return value
+}
+
+// isAccessDenied reports whether an aws CLI call failed for lack of IAM
+// permissions
+func isAccessDenied(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := stsErrorMessage(err)
+	return strings.Contains(msg, "AccessDenied") || strings.Contains(msg, "UnauthorizedOperation")
+}
+
+// getAccountAlias returns the IAM account alias of a profile, or "" if it has none
+func (aws *AWSManager) getAccountAlias(ctx context.Context, profile string) (string, error) {
+	timeout := aws.fancyConfig.Settings.AWSNetworkTimeoutDuration()
+	ctx, cancel := utils.WithStepTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := utils.CommandContext(ctx, "aws", "iam", "list-account-aliases", "--profile", profile, "--query", "AccountAliases[0]", "--output", "text")
+	cmd.Env = aws.commandEnv(profile)
+	output, err := cmd.Output()
+	if err != nil {
+		return "", utils.StepError(ctx, "aws iam list-account-aliases", timeout, err)
+	}
+
+	alias := strings.TrimSpace(string(output))
+	if alias == "None" {
+		return "", nil
+	}
+	return alias, nil
+}
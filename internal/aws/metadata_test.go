@@ -0,0 +1,198 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"fancy-login/internal/config"
+	"fancy-login/internal/state"
+	"fancy-login/internal/utils"
+)
+
+func TestMetadataRefreshDue(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name     string
+		last     time.Time
+		expected bool
+	}{
+		{"Never refreshed", time.Time{}, true},
+		{"Refreshed an hour ago", now.Add(-time.Hour), false},
+		{"Refreshed yesterday", now.Add(-25 * time.Hour), true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := metadataRefreshDue(tc.last, now); got != tc.expected {
+				t.Errorf("Expected due=%v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestMetadataChanges(t *testing.T) {
+	pc := config.ProfileConfig{AccountID: "111111111111", AccountAlias: "old-alias"}
+
+	testCases := []struct {
+		name     string
+		md       ProfileMetadata
+		expected int
+	}{
+		{"Unchanged", ProfileMetadata{AccountID: "111111111111", AccountAlias: "old-alias"}, 0},
+		{"Unknown account ID keeps stored one", ProfileMetadata{AccountAlias: "old-alias"}, 0},
+		{"Alias changed", ProfileMetadata{AccountID: "111111111111", AccountAlias: "new-alias"}, 1},
+		{"Alias removed", ProfileMetadata{AccountID: "111111111111"}, 1},
+		{"Both changed", ProfileMetadata{AccountID: "222222222222", AccountAlias: "new-alias"}, 2},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := metadataChanges(pc, tc.md); len(got) != tc.expected {
+				t.Errorf("Expected %d changes, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func newMetadataTestManager(t *testing.T) *AWSManager {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("FANCY_STATE_DIR", t.TempDir())
+
+	enabled := true
+	fc := config.DefaultFancyConfig()
+	fc.Settings.AutoUpdateAccountIDs = &enabled
+	fc.ProfileConfigs["dev"] = config.ProfileConfig{Name: "dev", AccountID: "111111111111"}
+	return NewAWSManager(config.NewConfig(), utils.NewLogger(false), fc)
+}
+
+func TestApplyMetadata(t *testing.T) {
+	manager := newMetadataTestManager(t)
+
+	changed := manager.applyMetadata([]ProfileMetadata{
+		{Profile: "dev", AccountID: "111111111111", AccountAlias: "acme-dev"},
+		{Profile: "prod", Err: errors.New("expired")},
+	})
+	if changed != 1 {
+		t.Errorf("Expected 1 changed profile, got %d", changed)
+	}
+
+	saved, err := config.LoadFancyConfig()
+	if err != nil {
+		t.Fatalf("LoadFancyConfig failed: %v", err)
+	}
+	if saved.ProfileConfigs["dev"].AccountAlias != "acme-dev" {
+		t.Errorf("Expected saved alias acme-dev, got %q", saved.ProfileConfigs["dev"].AccountAlias)
+	}
+
+	st, err := state.Load()
+	if err != nil {
+		t.Fatalf("state.Load failed: %v", err)
+	}
+	if st.MetadataRefreshedAt["dev"].IsZero() {
+		t.Error("Expected refresh time recorded for dev")
+	}
+	if _, exists := st.MetadataRefreshedAt["prod"]; exists {
+		t.Error("Failed refreshes must not be rate-limited")
+	}
+}
+
+func TestMetadataRefreshFinishDeadline(t *testing.T) {
+	manager := newMetadataTestManager(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	refresh := &MetadataRefresh{aws: manager, cancel: cancel, done: make(chan ProfileMetadata)}
+
+	start := time.Now()
+	refresh.Finish(50 * time.Millisecond)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Finish blocked for %v", elapsed)
+	}
+	if ctx.Err() == nil {
+		t.Error("Expected the refresh to be cancelled at the deadline")
+	}
+
+	var nilRefresh *MetadataRefresh
+	nilRefresh.Finish(time.Second)
+}
+
+func TestStartMetadataRefreshOptOut(t *testing.T) {
+	manager := newMetadataTestManager(t)
+	disabled := false
+	manager.fancyConfig.Settings.BackgroundRefresh = &disabled
+
+	if refresh := manager.StartMetadataRefresh(context.Background(), "dev"); refresh != nil {
+		t.Error("Expected no refresh when background_refresh is false")
+	}
+}
+
+// TestStartMetadataRefreshNeedsWriteBack checks that a login doesn't
+// refresh, and so never rewrites the config, unless auto_update_account_ids
+// is on
+func TestStartMetadataRefreshNeedsWriteBack(t *testing.T) {
+	manager := newMetadataTestManager(t)
+	manager.fancyConfig.Settings.AutoUpdateAccountIDs = nil
+
+	if refresh := manager.StartMetadataRefresh(context.Background(), "dev"); refresh != nil {
+		t.Error("Expected no refresh when auto_update_account_ids is unset")
+	}
+}
+
+// TestMetadataRefreshKeepsCorrectedAccountID checks that finishing a refresh
+// doesn't undo an account ID corrected while it ran
+func TestMetadataRefreshKeepsCorrectedAccountID(t *testing.T) {
+	manager := newMetadataTestManager(t)
+	binDir := t.TempDir()
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	writeFakeHelper(t, binDir, "aws", "echo acme-dev\n")
+
+	refresh := manager.StartMetadataRefresh(context.Background(), "dev")
+	if refresh == nil {
+		t.Fatal("Expected a refresh for a profile never refreshed")
+	}
+	manager.cacheAccountID("dev", "222222222222")
+	refresh.Finish(5 * time.Second)
+
+	saved, err := config.LoadFancyConfig()
+	if err != nil {
+		t.Fatalf("LoadFancyConfig failed: %v", err)
+	}
+	if got := saved.ProfileConfigs["dev"].AccountID; got != "222222222222" {
+		t.Errorf("Expected the corrected account ID to stay, got %q", got)
+	}
+}
+
+func TestStartMetadataRefreshAccessDenied(t *testing.T) {
+	manager := newMetadataTestManager(t)
+	pc := manager.fancyConfig.ProfileConfigs["dev"]
+	pc.AccountAlias = "acme-dev"
+	manager.fancyConfig.ProfileConfigs["dev"] = pc
+	binDir := t.TempDir()
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	writeFakeHelper(t, binDir, "aws", "echo 'An error occurred (AccessDenied) when calling the ListAccountAliases operation' >&2\nexit 254\n")
+
+	refresh := manager.StartMetadataRefresh(context.Background(), "dev")
+	if refresh == nil {
+		t.Fatal("Expected a refresh for a profile never refreshed")
+	}
+	refresh.Finish(5 * time.Second)
+
+	if alias := manager.fancyConfig.ProfileConfigs["dev"].AccountAlias; alias != "acme-dev" {
+		t.Errorf("Expected the stored alias to be kept, got %q", alias)
+	}
+	st, err := state.Load()
+	if err != nil {
+		t.Fatalf("state.Load failed: %v", err)
+	}
+	if st.MetadataRefreshedAt["dev"].IsZero() {
+		t.Error("Expected a denied alias lookup to count as refreshed")
+	}
+	if again := manager.StartMetadataRefresh(context.Background(), "dev"); again != nil {
+		t.Error("Expected no second lookup in the same day")
+	}
+}
@@ -0,0 +1,245 @@
+package aws
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"fancy-login/internal/config"
+	"fancy-login/internal/prompt"
+	"fancy-login/internal/utils"
+)
+
+// mfaAttempts is how often a rejected or malformed MFA code is asked for
+const mfaAttempts = 3
+
+// mfaCodePattern matches the 6-digit codes of virtual and hardware tokens
+var mfaCodePattern = regexp.MustCompile(`^[0-9]{6}$`)
+
+// errMFACodeRejected is returned when STS refused the MFA code
+var errMFACodeRejected = errors.New("MFA code rejected")
+
+// mfaCredentials are the temporary credentials of an MFA session, as
+// `aws sts get-session-token` and `aws sts assume-role` print them
+type mfaCredentials struct {
+	AccessKeyID     string    `json:"AccessKeyId"`
+	SecretAccessKey string    `json:"SecretAccessKey"`
+	SessionToken    string    `json:"SessionToken"`
+	Expiration      time.Time `json:"Expiration"`
+}
+
+// mfaResponse is the STS response kept in ~/.aws/cli/cache
+type mfaResponse struct {
+	Credentials mfaCredentials `json:"Credentials"`
+}
+
+// mfaCachePath returns where the MFA session of p is cached. For a role it
+// is the file the AWS CLI itself looks up before assuming the role, so
+// `aws --profile` reuses the session; sessions without a role use the same
+// scheme keyed by the device alone. duration_seconds and external_id
+// would change the CLI's key and aren't supported.
+func (aws *AWSManager) mfaCachePath(p config.AWSProfile) string {
+	serial, _ := json.Marshal(p.MFASerial)
+	args := fmt.Sprintf(`{"SerialNumber": %s}`, serial)
+	if p.IsRoleChain() {
+		role, _ := json.Marshal(p.RoleARN)
+		args = fmt.Sprintf(`{"RoleArn": %s, "SerialNumber": %s}`, role, serial)
+	}
+	sum := sha1.Sum([]byte(args))
+	return filepath.Join(aws.config.AWSDir, "cli", "cache", hex.EncodeToString(sum[:])+".json")
+}
+
+// cachedMFASession returns the cached MFA session of p if it outlasts the
+// expiry margin
+func (aws *AWSManager) cachedMFASession(p config.AWSProfile, now time.Time) (mfaCredentials, bool) {
+	data, err := os.ReadFile(aws.mfaCachePath(p))
+	if err != nil {
+		return mfaCredentials{}, false
+	}
+	var response mfaResponse
+	if err := json.Unmarshal(data, &response); err != nil || response.Credentials.SessionToken == "" {
+		return mfaCredentials{}, false
+	}
+	if response.Credentials.Expiration.Sub(now) <= aws.fancyConfig.Settings.SSOExpiryMarginDuration() {
+		return mfaCredentials{}, false
+	}
+	return response.Credentials, true
+}
+
+// mfaLogin asks for an MFA code and starts a session with it, asking again
+// up to mfaAttempts times while codes are malformed or rejected. A role's
+// source profile is logged in to first.
+func (aws *AWSManager) mfaLogin(ctx context.Context, p config.AWSProfile, info LoginProfileInfo, prompter *prompt.Prompter) error {
+	if p.IsRoleChain() {
+		if err := aws.HandleAWSLogin(ctx, p.SourceProfile, info.ForceSource); err != nil {
+			return fmt.Errorf("source profile %s of %s: %w", p.SourceProfile, p.Name, err)
+		}
+	}
+
+	aws.logger.FancyLog(fmt.Sprintf("%s needs an MFA code from %s", p.Name, p.MFASerial))
+	for attempt := 1; attempt <= mfaAttempts; attempt++ {
+		code := prompter.AskLine(fmt.Sprintf("%sMFA code for %s%s", config.Accent, p.Name, config.Reset), "")
+		if !mfaCodePattern.MatchString(code) {
+			aws.logger.LogWarning("An MFA code is 6 digits.")
+			continue
+		}
+
+		response, err := aws.startMFASession(ctx, p, code)
+		if errors.Is(err, errMFACodeRejected) {
+			aws.logger.LogWarning(fmt.Sprintf("The MFA code was rejected (attempt %d of %d).", attempt, mfaAttempts))
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		creds, err := aws.saveMFASession(p, response)
+		if err != nil {
+			return err
+		}
+		aws.logger.LogSuccess(fmt.Sprintf("MFA session for %s is valid until %s.", p.Name, creds.Expiration.Local().Format("15:04")))
+		return nil
+	}
+	return fmt.Errorf("no valid MFA code for %s after %d attempts", p.Name, mfaAttempts)
+}
+
+// startMFASession runs `aws sts assume-role` for a role or `aws sts
+// get-session-token` otherwise, returning the raw response to cache
+func (aws *AWSManager) startMFASession(ctx context.Context, p config.AWSProfile, code string) ([]byte, error) {
+	args := []string{"sts", "get-session-token", "--serial-number", p.MFASerial, "--token-code", code, "--profile", p.Name}
+	if p.IsRoleChain() {
+		args = []string{"sts", "assume-role", "--role-arn", p.RoleARN,
+			"--role-session-name", fmt.Sprintf("fancy-login-%d", time.Now().Unix()),
+			"--serial-number", p.MFASerial, "--token-code", code, "--profile", p.SourceProfile}
+	}
+	args = append(args, "--output", "json")
+
+	timeout := aws.fancyConfig.Settings.AWSNetworkTimeoutDuration()
+	ctx, cancel := utils.WithStepTimeout(ctx, timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := utils.CommandContext(ctx, "aws", args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if strings.Contains(msg, "MultiFactorAuthentication") {
+			return nil, errMFACodeRejected
+		}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && msg != "" {
+			return nil, fmt.Errorf("aws sts %s: %s", args[1], msg)
+		}
+		return nil, utils.StepError(ctx, "aws sts "+args[1], timeout, err)
+	}
+	return stdout.Bytes(), nil
+}
+
+// saveMFASession caches the STS response where the AWS CLI looks for it
+// and makes the session available to the rest of the run
+func (aws *AWSManager) saveMFASession(p config.AWSProfile, data []byte) (mfaCredentials, error) {
+	var response mfaResponse
+	if err := json.Unmarshal(data, &response); err != nil || response.Credentials.SessionToken == "" {
+		return mfaCredentials{}, fmt.Errorf("aws sts printed no session credentials for %s", p.Name)
+	}
+
+	path := aws.mfaCachePath(p)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return mfaCredentials{}, fmt.Errorf("failed to cache the MFA session: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return mfaCredentials{}, fmt.Errorf("failed to cache the MFA session: %w", err)
+	}
+	return response.Credentials, aws.useMFASession(p, response.Credentials)
+}
+
+// useMFASession makes the aws commands of this run use an MFA session.
+// The CLI finds a role's session in its cache by itself, but never uses a
+// cached get-session-token, so those credentials go into a temporary
+// credentials file. It holds only the MFA sessions of this run, never the
+// long-term keys of other profiles, so only commands for a profile with
+// one of these sessions read it, through ProfileEnv. Cleanup removes it.
+func (aws *AWSManager) useMFASession(p config.AWSProfile, creds mfaCredentials) error {
+	if p.IsRoleChain() {
+		return nil
+	}
+	if aws.mfaSessions == nil {
+		aws.mfaSessions = make(map[string]mfaCredentials)
+	}
+	aws.mfaSessions[p.Name] = creds
+
+	if aws.mfaCredentialsFile == "" {
+		dir := config.GetStateDir()
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return fmt.Errorf("failed to write the MFA session: %w", err)
+		}
+		// CreateTemp creates the file readable only by the user
+		f, err := os.CreateTemp(dir, "mfa-credentials-*")
+		if err != nil {
+			return fmt.Errorf("failed to write the MFA session: %w", err)
+		}
+		f.Close()
+		aws.mfaCredentialsFile = f.Name()
+	}
+
+	names := make([]string, 0, len(aws.mfaSessions))
+	for name := range aws.mfaSessions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, name := range names {
+		session := aws.mfaSessions[name]
+		fmt.Fprintf(&b, "[%s]\naws_access_key_id = %s\naws_secret_access_key = %s\naws_session_token = %s\n\n",
+			name, session.AccessKeyID, session.SecretAccessKey, session.SessionToken)
+	}
+	if err := os.WriteFile(aws.mfaCredentialsFile, []byte(b.String()), 0600); err != nil {
+		return fmt.Errorf("failed to write the MFA session: %w", err)
+	}
+	return nil
+}
+
+// ProfileEnv returns the variables a child started for profile needs on
+// top of the inherited environment: the temporary credentials file when
+// profile, or the source profile its role chain logs in with, has an MFA
+// session without a role in this run. It is nil otherwise.
+func (aws *AWSManager) ProfileEnv(profile string) map[string]string {
+	if len(aws.mfaSessions) == 0 {
+		return nil
+	}
+	chain, _ := aws.roleChain(profile)
+	for _, name := range append([]string{profile}, chain...) {
+		if _, ok := aws.mfaSessions[name]; ok {
+			return map[string]string{"AWS_SHARED_CREDENTIALS_FILE": aws.mfaCredentialsFile}
+		}
+	}
+	return nil
+}
+
+// commandEnv is the environment of an aws command for profile, or nil to
+// inherit this process's unchanged
+func (aws *AWSManager) commandEnv(profile string) []string {
+	return utils.ExtendEnv(os.Environ(), aws.ProfileEnv(profile))
+}
+
+// Cleanup removes the temporary MFA credentials file of this run, along
+// with the copy of the whole credentials file that older versions kept in
+// the state directory
+func (aws *AWSManager) Cleanup() {
+	if aws.mfaCredentialsFile != "" {
+		os.Remove(aws.mfaCredentialsFile)
+		aws.mfaCredentialsFile = ""
+	}
+	os.Remove(filepath.Join(config.GetStateDir(), "mfa-credentials"))
+}
@@ -0,0 +1,140 @@
+package aws
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"fancy-login/internal/config"
+	"fancy-login/internal/prompt"
+	"fancy-login/internal/utils"
+)
+
+// fakeMFAAWS is an aws CLI that accepts the MFA code 123456 and rejects
+// all others the way STS does
+const fakeMFAAWS = `case "$*" in
+*"--token-code 123456"*)
+	echo '{"Credentials": {"AccessKeyId": "ASIATEMP", "SecretAccessKey": "secret", "SessionToken": "token", "Expiration": "2099-01-01T00:00:00+00:00"}}' ;;
+*)
+	echo 'An error occurred (AccessDenied) when calling the GetSessionToken operation: MultiFactorAuthentication failed with invalid MFA one time pass code.' >&2
+	exit 254 ;;
+esac
+`
+
+// setupMFAFixture installs the fake aws CLI and an existing credentials
+// file whose long-term keys must not end up in the session file
+func setupMFAFixture(t *testing.T) *AWSManager {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("FANCY_AWS_DIR", "")
+	t.Setenv("FANCY_STATE_DIR", filepath.Join(home, ".fancy-login"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", "")
+	binDir := t.TempDir()
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	writeFakeHelper(t, binDir, "aws", fakeMFAAWS)
+
+	if err := os.MkdirAll(filepath.Join(home, ".aws"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	credentials := "[legacy]\naws_access_key_id = AKIALONG\naws_secret_access_key = long\n\n[other]\naws_access_key_id = AKIAOTHER\naws_secret_access_key = other\n"
+	if err := os.WriteFile(filepath.Join(home, ".aws", "credentials"), []byte(credentials), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return NewAWSManager(config.NewConfig(), utils.NewLogger(false), config.DefaultFancyConfig())
+}
+
+func TestMFALogin(t *testing.T) {
+	testCases := []struct {
+		name        string
+		input       string
+		expectError bool
+	}{
+		{"First code accepted", "123456\n", false},
+		{"Malformed then rejected then accepted", "12ab\n111111\n123456\n", false},
+		{"Three wrong codes", "111111\n222222\n333333\n123456\n", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			manager := setupMFAFixture(t)
+			p := config.AWSProfile{Name: "legacy", MFASerial: "arn:aws:iam::123456789012:mfa/me"}
+			prompter := prompt.NewPrompter(bufio.NewReader(strings.NewReader(tc.input)), io.Discard, nil, nil)
+
+			err := manager.mfaLogin(context.Background(), p, LoginProfileInfo{MFASerial: p.MFASerial}, prompter)
+			if tc.expectError {
+				if err == nil {
+					t.Fatal("Expected an error after three wrong codes")
+				}
+				if _, found := manager.cachedMFASession(p, time.Now()); found {
+					t.Error("Expected no cached session")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected the MFA login to succeed, got %v", err)
+			}
+
+			creds, found := manager.cachedMFASession(p, time.Now())
+			if !found || creds.SessionToken != "token" {
+				t.Errorf("Expected the session to be cached, got %+v", creds)
+			}
+			info, err := os.Stat(manager.mfaCachePath(p))
+			if err != nil || info.Mode().Perm() != 0600 {
+				t.Errorf("Expected the cache file to be 0600, got %v, %v", info, err)
+			}
+
+			if got := os.Getenv("AWS_SHARED_CREDENTIALS_FILE"); got != "" {
+				t.Errorf("Expected the process environment to be left alone, got %q", got)
+			}
+			if env := manager.ProfileEnv("other"); env != nil {
+				t.Errorf("Expected no extra environment for other, got %v", env)
+			}
+			path := manager.ProfileEnv("legacy")["AWS_SHARED_CREDENTIALS_FILE"]
+			if cmd := manager.awsCommand(context.Background(), "legacy", "s3", "ls"); !slices.Contains(cmd.Env, "AWS_SHARED_CREDENTIALS_FILE="+path) {
+				t.Errorf("Expected aws commands for legacy to read %s", path)
+			}
+			if cmd := manager.awsCommand(context.Background(), "other", "s3", "ls"); cmd.Env != nil {
+				t.Error("Expected aws commands for other to inherit the environment")
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Expected the credentials overlay at %q, got %v", path, err)
+			}
+			content := string(data)
+			if !strings.Contains(content, "[legacy]\naws_access_key_id = ASIATEMP") || !strings.Contains(content, "aws_session_token = token") {
+				t.Errorf("Expected legacy to use the session credentials, got %q", content)
+			}
+			if strings.Contains(content, "AKIALONG") || strings.Contains(content, "AKIAOTHER") {
+				t.Errorf("Expected no long-term keys in the session file, got %q", content)
+			}
+			if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+				t.Errorf("Expected the session file to be 0600, got %v, %v", info, err)
+			}
+
+			manager.Cleanup()
+			if _, err := os.Stat(path); !os.IsNotExist(err) {
+				t.Errorf("Expected Cleanup to remove %s, got %v", path, err)
+			}
+		})
+	}
+}
+
+func TestMFACachePath(t *testing.T) {
+	manager := NewAWSManager(&config.Config{AWSDir: "/home/me/.aws"}, utils.NewLogger(false), config.DefaultFancyConfig())
+	role := config.AWSProfile{Name: "admin", RoleARN: "arn:aws:iam::123456789012:role/Admin", SourceProfile: "legacy", MFASerial: "arn:aws:iam::123456789012:mfa/me"}
+	session := config.AWSProfile{Name: "legacy", MFASerial: "arn:aws:iam::123456789012:mfa/me"}
+
+	rolePath := manager.mfaCachePath(role)
+	if filepath.Dir(rolePath) != "/home/me/.aws/cli/cache" {
+		t.Errorf("Expected the AWS CLI cache directory, got %s", rolePath)
+	}
+	if rolePath == manager.mfaCachePath(session) {
+		t.Error("Expected a role and a plain session to be cached apart")
+	}
+}
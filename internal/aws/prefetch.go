@@ -0,0 +1,110 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// accountPrefetchWorkers bounds how many STS calls run while the picker is
+// open
+const accountPrefetchWorkers = 4
+
+// accountPrefetch resolves the account IDs of configured profiles in the
+// background while the picker is open, so the lookup after the choice is
+// usually answered already
+type accountPrefetch struct {
+	mu      sync.Mutex
+	entries map[string]*prefetchEntry
+}
+
+// prefetchEntry is the lookup of one profile; done is closed once
+// accountID and err are set
+type prefetchEntry struct {
+	cancel    context.CancelFunc
+	done      chan struct{}
+	accountID string
+	err       error
+}
+
+// startAccountPrefetch looks up the account of every profile whose cached
+// SSO token is still valid. Other profiles are left alone: an expired
+// session would only fail, and a credential_process helper might prompt
+// while the picker owns the terminal.
+func (aws *AWSManager) startAccountPrefetch(ctx context.Context, profiles []string) *accountPrefetch {
+	prefetch := &accountPrefetch{entries: make(map[string]*prefetchEntry)}
+	sem := make(chan struct{}, accountPrefetchWorkers)
+	margin := aws.fancyConfig.Settings.SSOExpiryMarginDuration()
+
+	for _, profile := range profiles {
+		p, ok := aws.snapshot.AWSProfile(profile)
+		if !ok || !p.IsSSO {
+			continue
+		}
+		entryCtx, cancel := context.WithCancel(ctx)
+		entry := &prefetchEntry{cancel: cancel, done: make(chan struct{})}
+		prefetch.entries[profile] = entry
+
+		go func() {
+			defer cancel()
+			defer close(entry.done)
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-entryCtx.Done():
+				entry.err = entryCtx.Err()
+				return
+			}
+			if token := lookupSSOToken(p); !token.Found || time.Until(token.ExpiresAt) <= margin {
+				entry.err = errNoValidSession
+				return
+			}
+			identity, err := aws.sts.CallerIdentity(entryCtx, p.Name)
+			entry.accountID, entry.err = identity.Account, err
+		}()
+	}
+	return prefetch
+}
+
+// errNoValidSession marks profiles skipped for want of a cached session
+var errNoValidSession = errors.New("no valid cached SSO session")
+
+// keep cancels the lookups of every profile but the chosen one. It is safe
+// to call on a nil prefetch.
+func (p *accountPrefetch) keep(profile string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for name, entry := range p.entries {
+		if name != profile {
+			entry.cancel()
+			delete(p.entries, name)
+		}
+	}
+}
+
+// accountID waits for the prefetched account of profile. ok is false if
+// there was no lookup for it or it failed, so the caller asks STS itself.
+func (p *accountPrefetch) accountID(ctx context.Context, profile string) (accountID string, ok bool) {
+	if p == nil {
+		return "", false
+	}
+	p.mu.Lock()
+	entry, exists := p.entries[profile]
+	p.mu.Unlock()
+	if !exists {
+		return "", false
+	}
+	select {
+	case <-entry.done:
+	case <-ctx.Done():
+		return "", false
+	}
+	if entry.err != nil || entry.accountID == "" {
+		return "", false
+	}
+	return entry.accountID, true
+}
@@ -0,0 +1,163 @@
+package aws
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"fancy-login/internal/config"
+	"fancy-login/internal/utils"
+)
+
+// blockingSTS answers like fakeSTS but holds the slow profile's call until
+// its context is cancelled
+type blockingSTS struct {
+	fakeSTS
+	slow string
+}
+
+func (b blockingSTS) CallerIdentity(ctx context.Context, profile string) (CallerIdentity, error) {
+	if profile == b.slow {
+		<-ctx.Done()
+		return CallerIdentity{}, ctx.Err()
+	}
+	return b.fakeSTS.CallerIdentity(ctx, profile)
+}
+
+func newPrefetchTestManager(t testing.TB) *AWSManager {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("FANCY_STATE_DIR", t.TempDir())
+	awsConfigPath := filepath.Join(home, "aws-config")
+	t.Setenv("AWS_CONFIG_FILE", awsConfigPath)
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(home, "credentials"))
+
+	awsConfig := "[profile dev]\nsso_start_url = https://dev.awsapps.com/start\n\n" +
+		"[profile ops]\nsso_start_url = https://dev.awsapps.com/start\n\n" +
+		"[profile prod]\nsso_start_url = https://prod.awsapps.com/start\n\n" +
+		"[profile static]\nregion = eu-west-1\n"
+	if err := os.WriteFile(awsConfigPath, []byte(awsConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cacheDir := filepath.Join(home, ".aws", "sso", "cache")
+	if err := os.MkdirAll(cacheDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	token := `{"startUrl": "https://dev.awsapps.com/start", "accessToken": "x", "expiresAt": "2030-01-01T00:00:00Z"}`
+	if err := os.WriteFile(filepath.Join(cacheDir, "dev.json"), []byte(token), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	manager := NewAWSManager(config.NewConfig(), utils.NewLogger(false), config.DefaultFancyConfig())
+	manager.SetSnapshot(config.NewSnapshot(nil))
+	return manager
+}
+
+func TestAccountPrefetch(t *testing.T) {
+	manager := newPrefetchTestManager(t)
+	manager.SetSTSClient(blockingSTS{
+		fakeSTS: fakeSTS{"dev": {Account: "111111111111"}, "prod": {Account: "222222222222"}},
+		slow:    "ops",
+	})
+
+	prefetch := manager.startAccountPrefetch(context.Background(), []string{"dev", "ops", "prod", "static", "missing"})
+	if len(prefetch.entries) != 3 {
+		t.Fatalf("Expected lookups for the 3 SSO profiles, got %d", len(prefetch.entries))
+	}
+
+	if id, ok := prefetch.accountID(context.Background(), "dev"); !ok || id != "111111111111" {
+		t.Errorf("Expected prefetched dev account, got %q, %v", id, ok)
+	}
+	// prod has no cached token, so STS was never asked even though it would answer
+	if id, ok := prefetch.accountID(context.Background(), "prod"); ok {
+		t.Errorf("Expected no prefetch without a valid session, got %q", id)
+	}
+	if _, ok := prefetch.accountID(context.Background(), "static"); ok {
+		t.Error("Expected no prefetch for a non-SSO profile")
+	}
+
+	ops := prefetch.entries["ops"]
+	prefetch.keep("dev")
+	<-ops.done
+	if ops.err != context.Canceled {
+		t.Errorf("Expected the ops lookup cancelled, got %v", ops.err)
+	}
+	if _, ok := prefetch.accountID(context.Background(), "ops"); ok {
+		t.Error("Expected no account for a dropped lookup")
+	}
+	if len(prefetch.entries) != 1 {
+		t.Errorf("Expected only the kept lookup, got %d", len(prefetch.entries))
+	}
+}
+
+func TestGetAccountIDUsesPrefetch(t *testing.T) {
+	manager := newPrefetchTestManager(t)
+	manager.SetSTSClient(fakeSTS{"dev": {Account: "111111111111"}})
+	manager.accountPrefetch = manager.startAccountPrefetch(context.Background(), []string{"dev"})
+	<-manager.accountPrefetch.entries["dev"].done
+
+	// STS now fails for every profile, so only the prefetch can answer
+	manager.SetSTSClient(fakeSTS{})
+	if id, err := manager.GetAccountID(context.Background(), "dev"); err != nil || id != "111111111111" {
+		t.Errorf("Expected prefetched account, got %q, %v", id, err)
+	}
+
+	var prefetch *accountPrefetch
+	prefetch.keep("dev")
+	if _, ok := prefetch.accountID(context.Background(), "dev"); ok {
+		t.Error("Expected a nil prefetch to have no accounts")
+	}
+}
+
+// slowSTS answers like fakeSTS after latency, like a real STS round trip
+type slowSTS struct {
+	fakeSTS
+	latency time.Duration
+}
+
+func (s slowSTS) CallerIdentity(ctx context.Context, profile string) (CallerIdentity, error) {
+	select {
+	case <-time.After(s.latency):
+	case <-ctx.Done():
+		return CallerIdentity{}, ctx.Err()
+	}
+	return s.fakeSTS.CallerIdentity(ctx, profile)
+}
+
+// BenchmarkAccountIDAfterPick measures how long the account lookup holds up
+// the login once a profile is picked, with STS taking 200ms and the picker
+// open for 300ms. Without the prefetch the whole round trip comes after the
+// choice; with it the answer is usually there already.
+func BenchmarkAccountIDAfterPick(b *testing.B) {
+	const latency, pickerOpen = 200 * time.Millisecond, 300 * time.Millisecond
+	manager := newPrefetchTestManager(b)
+	manager.SetSTSClient(slowSTS{fakeSTS: fakeSTS{"dev": {Account: "111111111111"}}, latency: latency})
+	ctx := context.Background()
+
+	b.Run("serial", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			manager.accountPrefetch = nil
+			if _, err := manager.GetAccountID(ctx, "dev"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("prefetched", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			b.StopTimer()
+			manager.accountPrefetch = manager.startAccountPrefetch(ctx, []string{"dev", "ops"})
+			time.Sleep(pickerOpen)
+			b.StartTimer()
+
+			manager.accountPrefetch.keep("dev")
+			if _, err := manager.GetAccountID(ctx, "dev"); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,82 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"fancy-login/internal/config"
+	"fancy-login/internal/prompt"
+)
+
+// ssoProbeTimeout bounds the SSO portal reachability check, so an
+// unreachable portal fails fast instead of hanging the CLI for 30 seconds
+const ssoProbeTimeout = 3 * time.Second
+
+// probeSSOPortal sends a HEAD request to the SSO start URL. Any HTTP
+// response, even an error status, proves the portal is reachable; only
+// connection failures and timeouts count. The client's transport decides
+// proxying; http.DefaultClient honors HTTPS_PROXY and NO_PROXY.
+func probeSSOPortal(ctx context.Context, client *http.Client, startURL string) error {
+	ctx, cancel := context.WithTimeout(ctx, ssoProbeTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, startURL, nil)
+	if err != nil {
+		return fmt.Errorf("invalid SSO start URL %q: %w", startURL, err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// ensurePortalReachable probes the profile's SSO portal before a login. When
+// it is unreachable the browser launch is skipped and, given a terminal, the
+// user may retry once they've fixed their connection.
+func (aws *AWSManager) ensurePortalReachable(ctx context.Context, profile string, prompter *prompt.Prompter) error {
+	if !aws.fancyConfig.Settings.SSOPortalProbeEnabled() {
+		return nil
+	}
+	startURL := aws.ssoStartURL(profile)
+	if startURL == "" {
+		aws.logger.FancyLog(fmt.Sprintf("No sso_start_url for %s, skipping portal check", profile))
+		return nil
+	}
+	host := startURL
+	if u, err := url.Parse(startURL); err == nil && u.Host != "" {
+		host = u.Host
+	}
+
+	for {
+		err := aws.portalProbe(ctx, startURL)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		aws.logger.LogError("SSO portal unreachable — are you on the VPN?")
+		aws.logger.LogInfo(fmt.Sprintf("Host: %s (%v)", host, err))
+
+		unreachable := fmt.Errorf("SSO portal %s is unreachable: %w", host, err)
+		if prompter == nil {
+			return unreachable
+		}
+		question := fmt.Sprintf("%sRetry once you're connected?%s", config.Accent, config.Reset)
+		if !prompter.Confirm(question, true) {
+			return unreachable
+		}
+	}
+}
+
+// ssoStartURL returns the sso_start_url of a profile, or "" if unknown
+func (aws *AWSManager) ssoStartURL(profile string) string {
+	p, _ := aws.snapshot.AWSProfile(profile)
+	return p.SSOStartURL
+}
@@ -0,0 +1,172 @@
+package aws
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"fancy-login/internal/config"
+	"fancy-login/internal/prompt"
+	"fancy-login/internal/utils"
+)
+
+func TestProbeSSOPortal(t *testing.T) {
+	reachable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead {
+			t.Errorf("Expected HEAD request, got %s", r.Method)
+		}
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer reachable.Close()
+
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closedURL := closed.URL
+	closed.Close()
+
+	hanging := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer hanging.Close()
+
+	testCases := []struct {
+		name      string
+		url       string
+		timeout   time.Duration
+		reachable bool
+	}{
+		{"Error status still reachable", reachable.URL + "/start", time.Second, true},
+		{"Connection refused", closedURL + "/start", time.Second, false},
+		{"Hanging portal", hanging.URL + "/start", 50 * time.Millisecond, false},
+		{"Malformed URL", "://not-a-url", time.Second, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), tc.timeout)
+			defer cancel()
+
+			err := probeSSOPortal(ctx, http.DefaultClient, tc.url)
+			if tc.reachable && err != nil {
+				t.Errorf("Expected reachable, got %v", err)
+			}
+			if !tc.reachable && err == nil {
+				t.Error("Expected unreachable, got nil")
+			}
+		})
+	}
+}
+
+func TestProbeSSOPortalUsesProxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+	}))
+	defer proxy.Close()
+
+	proxyURL, _ := url.Parse(proxy.URL)
+	client := &http.Client{Transport: &http.Transport{Proxy: http.ProxyURL(proxyURL)}}
+
+	// Only resolvable through the proxy
+	if err := probeSSOPortal(context.Background(), client, "http://sso.example.invalid/start"); err != nil {
+		t.Fatalf("Expected probe through proxy to succeed, got %v", err)
+	}
+	if proxied != "http://sso.example.invalid/start" {
+		t.Errorf("Expected proxied request for the start URL, got %q", proxied)
+	}
+}
+
+// newProbeTestManager writes an SSO profile and returns a manager whose
+// probe fails the given number of times before succeeding
+func newProbeTestManager(t *testing.T, failures int) (*AWSManager, *int) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("AWS_CONFIG_FILE", "")
+	if err := os.MkdirAll(filepath.Join(home, ".aws"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	awsConfig := "[profile dev]\nsso_start_url = https://acme.awsapps.com/start\nsso_region = eu-central-1\n"
+	if err := os.WriteFile(filepath.Join(home, ".aws", "config"), []byte(awsConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	manager := NewAWSManager(config.NewConfig(), utils.NewLogger(false), config.DefaultFancyConfig())
+	calls := 0
+	manager.portalProbe = func(ctx context.Context, startURL string) error {
+		calls++
+		if startURL != "https://acme.awsapps.com/start" {
+			t.Errorf("Expected the profile's start URL, got %s", startURL)
+		}
+		if calls <= failures {
+			return errors.New("dial tcp: i/o timeout")
+		}
+		return nil
+	}
+	return manager, &calls
+}
+
+func TestEnsurePortalReachable(t *testing.T) {
+	disabled := false
+
+	testCases := []struct {
+		name          string
+		failures      int
+		input         string
+		noTerminal    bool
+		probeDisabled *bool
+		expectErr     bool
+		expectedCalls int
+	}{
+		{name: "Reachable", expectedCalls: 1},
+		{name: "Retry after reconnecting", failures: 1, input: "y\n", expectedCalls: 2},
+		{name: "Retry defaults to yes", failures: 1, input: "\n", expectedCalls: 2},
+		{name: "Declined retry", failures: 1, input: "n\n", expectErr: true, expectedCalls: 1},
+		{name: "No terminal", failures: 1, noTerminal: true, expectErr: true, expectedCalls: 1},
+		{name: "Probe disabled", failures: 5, probeDisabled: &disabled, expectedCalls: 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			manager, calls := newProbeTestManager(t, tc.failures)
+			manager.fancyConfig.Settings.SSOPortalProbe = tc.probeDisabled
+
+			var prompter *prompt.Prompter
+			if !tc.noTerminal {
+				prompter = prompt.NewPrompter(bufio.NewReader(strings.NewReader(tc.input)), io.Discard, nil, nil)
+			}
+
+			err := manager.ensurePortalReachable(context.Background(), "dev", prompter)
+			if tc.expectErr && err == nil {
+				t.Error("Expected an error, got nil")
+			}
+			if !tc.expectErr && err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+			if tc.expectErr && err != nil && !strings.Contains(err.Error(), "acme.awsapps.com") {
+				t.Errorf("Expected the host in the error, got %v", err)
+			}
+			if *calls != tc.expectedCalls {
+				t.Errorf("Expected %d probes, got %d", tc.expectedCalls, *calls)
+			}
+		})
+	}
+}
+
+func TestEnsurePortalReachableWithoutStartURL(t *testing.T) {
+	manager, calls := newProbeTestManager(t, 1)
+	if err := manager.ensurePortalReachable(context.Background(), "unknown", nil); err != nil {
+		t.Errorf("Expected profiles without a start URL to skip the probe, got %v", err)
+	}
+	if *calls != 0 {
+		t.Errorf("Expected no probe, got %d", *calls)
+	}
+}
@@ -0,0 +1,190 @@
+package aws
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"fancy-login/internal/config"
+	"fancy-login/internal/state"
+)
+
+// ProfileSortKeys are the columns the profile picker can be sorted by
+var ProfileSortKeys = []string{"name", "alpha", "profile", "account_id", "account_alias", "environment", "region", "expiry", "recent", "frequent"}
+
+// DefaultProfileSort orders the picker by display name
+var DefaultProfileSort = []string{"name"}
+
+// profileSortRecord is the data a picker row is sorted by
+type profileSortRecord struct {
+	Profile string
+	Config  config.ProfileConfig
+	Region  string
+	Expiry  time.Time
+	// LastUsed and Uses come from the recorded logins to the profile
+	LastUsed time.Time
+	Uses     int
+}
+
+// ValidateProfileSort checks that every key is a known sort column
+func ValidateProfileSort(keys []string) error {
+	for _, key := range keys {
+		known := false
+		for _, k := range ProfileSortKeys {
+			if key == k {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return fmt.Errorf("unknown sort column %q (available: %s)", key, strings.Join(ProfileSortKeys, ", "))
+		}
+	}
+	return nil
+}
+
+// ParseProfileSort splits a comma-separated --sort value into columns
+func ParseProfileSort(value string) ([]string, error) {
+	var keys []string
+	for _, key := range strings.Split(value, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	if err := ValidateProfileSort(keys); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// sortValue returns the value of a sort column and whether the profile has one
+func (r profileSortRecord) sortValue(key string) (string, bool) {
+	var value string
+	switch key {
+	case "name", "alpha":
+		value = r.Profile
+		if r.Config.Name != "" {
+			value = r.Config.Name
+		}
+		value = strings.ToLower(value)
+	case "profile":
+		value = r.Profile
+	case "account_id":
+		value = r.Config.AccountID
+	case "account_alias":
+		value = strings.ToLower(r.Config.AccountAlias)
+	case "environment":
+		value = strings.ToLower(r.Config.Environment)
+	case "region":
+		value = r.Region
+	case "expiry":
+		if !r.Expiry.IsZero() {
+			// RFC 3339 in UTC sorts chronologically as a string
+			value = r.Expiry.UTC().Format(time.RFC3339)
+		}
+	case "recent":
+		// Inverted so the most recently used profile sorts first
+		if !r.LastUsed.IsZero() {
+			value = fmt.Sprintf("%020d", math.MaxInt64-r.LastUsed.UnixNano())
+		}
+	case "frequent":
+		if r.Uses > 0 {
+			value = fmt.Sprintf("%020d", math.MaxInt64-int64(r.Uses))
+		}
+	}
+	return value, value != ""
+}
+
+// sortProfileRecords orders records by each key in turn. Profiles missing a
+// value sort after those that have one, and ties keep falling through to the
+// next key and finally the profile name, so the order is deterministic.
+func sortProfileRecords(records []profileSortRecord, keys []string) {
+	keys = append(append([]string{}, keys...), "profile")
+	sort.SliceStable(records, func(i, j int) bool {
+		for _, key := range keys {
+			a, aOK := records[i].sortValue(key)
+			b, bOK := records[j].sortValue(key)
+			switch {
+			case aOK != bOK:
+				return aOK
+			case a != b:
+				return a < b
+			}
+		}
+		return false
+	})
+}
+
+// SetProfileSort overrides the configured picker sort for this run without
+// persisting it
+func (aws *AWSManager) SetProfileSort(keys []string) {
+	aws.sortOverride = keys
+}
+
+// profileSort returns the sort columns for this run, falling back to the
+// default when they are unset or invalid
+func (aws *AWSManager) profileSort() []string {
+	if len(aws.sortOverride) > 0 {
+		return aws.sortOverride
+	}
+	keys := aws.fancyConfig.Settings.ProfileSort
+	if len(keys) == 0 {
+		return DefaultProfileSort
+	}
+	if err := ValidateProfileSort(keys); err != nil {
+		aws.logger.LogWarning(fmt.Sprintf("Ignoring profile_sort: %v", err))
+		return DefaultProfileSort
+	}
+	return keys
+}
+
+// sortConfiguredProfiles orders configured profiles for the picker, loading
+// region and session expiry only when a sort column needs them
+func (aws *AWSManager) sortConfiguredProfiles(names []string) []profileSortRecord {
+	keys := aws.profileSort()
+
+	var parsed map[string]config.AWSProfile
+	if needsSortKey(keys, "region") || needsSortKey(keys, "expiry") {
+		parsed = make(map[string]config.AWSProfile)
+		if profiles, err := aws.snapshot.AWSProfiles(); err == nil {
+			for _, p := range profiles {
+				parsed[p.Name] = p
+			}
+		}
+	}
+
+	var usage map[string]*state.ProfileUsage
+	if needsSortKey(keys, "recent") || needsSortKey(keys, "frequent") {
+		usage = ProfileUsage()
+	}
+
+	records := make([]profileSortRecord, 0, len(names))
+	for _, name := range names {
+		record := profileSortRecord{Profile: name, Config: aws.fancyConfig.ProfileConfigs[name]}
+		if u := usage[name]; u != nil {
+			record.LastUsed, record.Uses = u.LastUsed, u.Count
+		}
+		if p, ok := parsed[name]; ok {
+			record.Region = p.Region
+			if needsSortKey(keys, "expiry") {
+				record.Expiry = lookupSSOToken(p).ExpiresAt
+			}
+		}
+		records = append(records, record)
+	}
+
+	sortProfileRecords(records, keys)
+	return records
+}
+
+// needsSortKey reports whether keys include key
+func needsSortKey(keys []string, key string) bool {
+	for _, k := range keys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
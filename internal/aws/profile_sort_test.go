@@ -0,0 +1,172 @@
+package aws
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"fancy-login/internal/config"
+	"fancy-login/internal/state"
+	"fancy-login/internal/utils"
+)
+
+func TestSortProfileRecords(t *testing.T) {
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	records := []profileSortRecord{
+		{Profile: "web-prod", Config: config.ProfileConfig{Name: "Web", Environment: "prod"}, Expiry: now.Add(2 * time.Hour), LastUsed: now.Add(-48 * time.Hour), Uses: 40},
+		{Profile: "api-dev", Config: config.ProfileConfig{Name: "api", Environment: "dev"}},
+		{Profile: "tools", Config: config.ProfileConfig{AccountID: "111111111111"}, Expiry: now.Add(time.Hour), LastUsed: now.Add(-time.Hour), Uses: 3},
+		{Profile: "web-dev", Config: config.ProfileConfig{Name: "Web", Environment: "dev"}, Expiry: now.Add(3 * time.Hour), LastUsed: now.Add(-2 * time.Hour), Uses: 3},
+	}
+
+	testCases := []struct {
+		name     string
+		keys     []string
+		expected []string
+	}{
+		{"Name ignores case and falls back to the profile", []string{"name"}, []string{"api-dev", "tools", "web-dev", "web-prod"}},
+		{"Environment then name, missing last", []string{"environment", "name"}, []string{"api-dev", "web-dev", "web-prod", "tools"}},
+		{"Expiry soonest first, missing last", []string{"expiry"}, []string{"tools", "web-prod", "web-dev", "api-dev"}},
+		{"Account ID, ties by profile", []string{"account_id"}, []string{"tools", "api-dev", "web-dev", "web-prod"}},
+		{"Most recently used first, unused last", []string{"recent"}, []string{"tools", "web-dev", "web-prod", "api-dev"}},
+		{"Most used first, ties by name", []string{"frequent", "name"}, []string{"web-prod", "tools", "web-dev", "api-dev"}},
+		{"Alpha is the same as name", []string{"alpha"}, []string{"api-dev", "tools", "web-dev", "web-prod"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sorted := append([]profileSortRecord{}, records...)
+			sortProfileRecords(sorted, tc.keys)
+			for i, record := range sorted {
+				if record.Profile != tc.expected[i] {
+					t.Errorf("Expected order %v, got %s at position %d", tc.expected, record.Profile, i)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestParseProfileSort(t *testing.T) {
+	testCases := []struct {
+		name        string
+		value       string
+		expected    []string
+		expectError bool
+	}{
+		{"Single column", "name", []string{"name"}, false},
+		{"Several columns with spaces", "environment, region ,name", []string{"environment", "region", "name"}, false},
+		{"Empty entries are dropped", "expiry,,", []string{"expiry"}, false},
+		{"Unknown column", "environment,owner", nil, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			keys, err := ParseProfileSort(tc.value)
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("Expected error for %q", tc.value)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(keys) != len(tc.expected) {
+				t.Fatalf("Expected %v, got %v", tc.expected, keys)
+			}
+			for i := range keys {
+				if keys[i] != tc.expected[i] {
+					t.Errorf("Expected %v, got %v", tc.expected, keys)
+				}
+			}
+		})
+	}
+}
+
+func TestGetProfilesWithMetadataSortsWithinGroups(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("AWS_CONFIG_FILE", "")
+	if err := os.MkdirAll(filepath.Join(home, ".aws"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	awsConfig := "[profile a-prod]\nregion = eu-west-1\n" +
+		"[profile b-dev]\nregion = us-east-1\n" +
+		"[profile c-dev]\nregion = eu-central-1\n" +
+		"[profile d-prod]\nregion = ap-south-1\n"
+	if err := os.WriteFile(filepath.Join(home, ".aws", "config"), []byte(awsConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	fancyConfig := config.DefaultFancyConfig()
+	fancyConfig.ProfileConfigs = map[string]config.ProfileConfig{
+		"a-prod": {Environment: "prod", K9sAutoLaunch: true},
+		"b-dev":  {Environment: "dev", K9sAutoLaunch: true},
+		"c-dev":  {Environment: "dev"},
+		"d-prod": {Environment: "prod"},
+	}
+	fancyConfig.Settings.ProfileSort = []string{"environment", "name"}
+
+	testCases := []struct {
+		name     string
+		override []string
+		expected []string
+	}{
+		{"Persisted sort", nil, []string{"=== QUICK ACCESS (K9S AUTO-LAUNCH) ===", "b-dev", "a-prod", "", "=== OTHER CONFIGURED PROFILES ===", "c-dev", "d-prod"}},
+		{"Override for this run", []string{"region"}, []string{"=== QUICK ACCESS (K9S AUTO-LAUNCH) ===", "a-prod", "b-dev", "", "=== OTHER CONFIGURED PROFILES ===", "d-prod", "c-dev"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			manager := NewAWSManager(config.NewConfig(), utils.NewLogger(false), fancyConfig)
+			manager.SetProfileSort(tc.override)
+
+			profiles, err := manager.getProfilesWithMetadata()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			var got []string
+			for _, p := range profiles {
+				if p.Name == "---" {
+					got = append(got, p.DisplayText)
+				} else {
+					got = append(got, p.Name)
+				}
+			}
+			// Footer rows after the configured groups don't depend on the sort
+			if len(got) < len(tc.expected) {
+				t.Fatalf("Expected %q, got %q", tc.expected, got)
+			}
+			for i := range tc.expected {
+				if got[i] != tc.expected[i] {
+					t.Errorf("Expected %q, got %q", tc.expected, got)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestWithUsage(t *testing.T) {
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	used := &state.ProfileUsage{LastUsed: now.Add(-2 * time.Hour), Count: 5}
+
+	testCases := []struct {
+		name     string
+		metadata string
+		usage    *state.ProfileUsage
+		expected string
+	}{
+		{"Never used", "| ECR", nil, "| ECR"},
+		{"Used with metadata", "| ECR", used, "| ECR · used 2h ago"},
+		{"Used without metadata", "", used, "| used 2h ago"},
+	}
+	for _, tc := range testCases {
+		if got := withUsage(tc.metadata, tc.usage, now); got != tc.expected {
+			t.Errorf("%s: expected %q, got %q", tc.name, tc.expected, got)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"fancy-login/internal/config"
+	"fancy-login/internal/platform"
+)
+
+// ProfileSource describes where the selected AWS profile came from
+type ProfileSource string
+
+const (
+	SourceFlag              ProfileSource = "flag"
+	SourceQuery             ProfileSource = "query"
+	SourceAWSProfile        ProfileSource = "AWS_PROFILE"
+	SourceAWSDefaultProfile ProfileSource = "AWS_DEFAULT_PROFILE"
+	SourceLast              ProfileSource = "last"
+	SourceExported          ProfileSource = "exported"
+	SourceInteractive       ProfileSource = "interactive"
+)
+
+// ProfileRequest holds the inputs used to resolve a profile without prompting
+type ProfileRequest struct {
+	Flag   string // value of --profile
+	Query  string // positional query argument
+	UseEnv bool   // whether AWS_PROFILE/AWS_DEFAULT_PROFILE may be reused
+	Last   bool   // value of --last: reuse the profile of the last login
+	// Exported falls back to the profile the last login exported before
+	// prompting, for commands that look at the terminal's current profile
+	Exported bool
+	// NoExport leaves the exported profile alone, for commands that only
+	// read a profile rather than switch to it
+	NoExport bool
+}
+
+// resolveEnvProfile applies the profile precedence shared by every command:
+//
+//	--profile flag > exact positional query match > AWS_PROFILE > AWS_DEFAULT_PROFILE > interactive
+//
+// Environment variables are only consulted when req.UseEnv is set, since
+// fancy-login itself exports AWS_PROFILE and would otherwise never prompt again.
+// An empty profile with SourceInteractive means the caller should prompt.
+func resolveEnvProfile(req ProfileRequest, profiles []string, getenv func(string) string) (string, ProfileSource) {
+	if req.Flag != "" {
+		return req.Flag, SourceFlag
+	}
+
+	if req.Query != "" {
+		for _, p := range profiles {
+			if p == req.Query {
+				return p, SourceQuery
+			}
+		}
+	}
+
+	if req.UseEnv {
+		if p := getenv("AWS_PROFILE"); p != "" {
+			return p, SourceAWSProfile
+		}
+		if p := getenv("AWS_DEFAULT_PROFILE"); p != "" {
+			return p, SourceAWSDefaultProfile
+		}
+	}
+
+	return "", SourceInteractive
+}
+
+// ResolveProfile resolves the AWS profile from flags, query and environment,
+// falling back to the interactive picker when nothing matches
+func (aws *AWSManager) ResolveProfile(ctx context.Context, req ProfileRequest) (string, ProfileSource, error) {
+	profiles, err := aws.getAWSConfigProfiles()
+	if err != nil && len(aws.fancyConfig.KubeOnlyProfiles) == 0 {
+		return "", "", err
+	}
+	for name := range aws.fancyConfig.KubeOnlyProfiles {
+		profiles = append(profiles, name)
+	}
+	req.Flag = resolveAlias(aws.fancyConfig, req.Flag, profiles)
+	req.Query = resolveAlias(aws.fancyConfig, req.Query, profiles)
+
+	profile, source := resolveEnvProfile(req, profiles, os.Getenv)
+	if req.Last && source == SourceInteractive {
+		profile, source = aws.lastProfile(profiles)
+	}
+	if req.Exported && source == SourceInteractive {
+		profile, source = aws.exportedProfile()
+	}
+	if source == SourceInteractive && req.Query != "" {
+		if matches := matchingConfiguredProfiles(aws.fancyConfig, profiles, req.Query); len(matches) == 1 {
+			profile, source = matches[0], SourceQuery
+			aws.logger.LogInfo(fmt.Sprintf("Auto-selected %s, the only configured profile matching %q", profile, req.Query))
+		}
+	}
+	if source == SourceInteractive {
+		profile, err = aws.SelectAWSProfile(ctx, req.Query)
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	aws.logger.FancyLog(fmt.Sprintf("Profile source: %s (%s)", source, profile))
+
+	if aws.fancyConfig.IsKubeOnlyProfile(profile) {
+		aws.logger.LogSuccess(fmt.Sprintf("Selected kube-only profile: %s", profile))
+		return profile, source, nil
+	}
+	if req.NoExport {
+		return profile, source, nil
+	}
+
+	if err := aws.exportProfileToTemp(profile); err != nil {
+		aws.logger.LogWarning(fmt.Sprintf("Failed to export profile to temp file: %v", err))
+	}
+
+	aws.logger.LogSuccess(fmt.Sprintf("Selected AWS Profile: %s", profile))
+	return profile, source, nil
+}
+
+// matchesQuery reports whether the profile's name, custom display name or
+// one of its aliases contains query, ignoring case
+func matchesQuery(fc *config.FancyConfig, profile, query string) bool {
+	query = strings.ToLower(query)
+	pc := fc.ProfileConfigs[profile]
+	for _, name := range append([]string{profile, pc.Name}, pc.Aliases...) {
+		if name != "" && strings.Contains(strings.ToLower(name), query) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchingConfiguredProfiles returns the profiles configured in the fancy
+// config, kube-only ones included, that match query
+func matchingConfiguredProfiles(fc *config.FancyConfig, profiles []string, query string) []string {
+	var matches []string
+	for _, p := range profiles {
+		_, configured := fc.ProfileConfigs[p]
+		if (configured || fc.IsKubeOnlyProfile(p)) && matchesQuery(fc, p, query) {
+			matches = append(matches, p)
+		}
+	}
+	return matches
+}
+
+// resolveAlias returns the profile a --profile value or query names. Names
+// of existing profiles are kept, so an alias never shadows a profile.
+func resolveAlias(fc *config.FancyConfig, name string, profiles []string) string {
+	if name == "" {
+		return name
+	}
+	for _, p := range profiles {
+		if p == name {
+			return name
+		}
+	}
+	return fc.ResolveProfileName(name)
+}
+
+// lastProfile returns the profile of the last login for --last. Without a
+// recorded login, or when the profile was removed since, it warns and
+// returns SourceInteractive so the picker opens.
+func (aws *AWSManager) lastProfile(profiles []string) (string, ProfileSource) {
+	last := LastLogin()
+	if last == nil {
+		aws.logger.LogWarning("No previous login recorded; pick a profile")
+		return "", SourceInteractive
+	}
+	for _, p := range profiles {
+		if p == last.Profile {
+			return p, SourceLast
+		}
+	}
+	aws.logger.LogWarning(fmt.Sprintf("Last used profile %s no longer exists in %s; pick a profile", last.Profile, config.GetAWSConfigPath()))
+	return "", SourceInteractive
+}
+
+// exportedProfile returns the profile the last login exported to this
+// terminal's profile script, or SourceInteractive when there is none
+func (aws *AWSManager) exportedProfile() (string, ProfileSource) {
+	profile, err := platform.ReadProfileScript(aws.config.AWSProfileTemp)
+	if err != nil {
+		aws.logger.LogWarning(err.Error())
+	}
+	if profile == "" {
+		return "", SourceInteractive
+	}
+	return profile, SourceExported
+}
@@ -0,0 +1,271 @@
+package aws
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"fancy-login/internal/config"
+	"fancy-login/internal/platform"
+	"fancy-login/internal/utils"
+)
+
+func TestResolveEnvProfile(t *testing.T) {
+	profiles := []string{"dev", "staging", "prod"}
+
+	testCases := []struct {
+		name            string
+		req             ProfileRequest
+		env             map[string]string
+		expectedProfile string
+		expectedSource  ProfileSource
+	}{
+		{
+			name:            "Nothing set falls back to interactive",
+			req:             ProfileRequest{UseEnv: true},
+			expectedProfile: "",
+			expectedSource:  SourceInteractive,
+		},
+		{
+			name:            "Flag wins over everything",
+			req:             ProfileRequest{Flag: "prod", Query: "dev", UseEnv: true},
+			env:             map[string]string{"AWS_PROFILE": "staging", "AWS_DEFAULT_PROFILE": "dev"},
+			expectedProfile: "prod",
+			expectedSource:  SourceFlag,
+		},
+		{
+			name:            "Exact query match wins over environment",
+			req:             ProfileRequest{Query: "dev", UseEnv: true},
+			env:             map[string]string{"AWS_PROFILE": "staging"},
+			expectedProfile: "dev",
+			expectedSource:  SourceQuery,
+		},
+		{
+			name:            "Partial query match is not used",
+			req:             ProfileRequest{Query: "de", UseEnv: true},
+			env:             map[string]string{"AWS_PROFILE": "staging"},
+			expectedProfile: "staging",
+			expectedSource:  SourceAWSProfile,
+		},
+		{
+			name:            "AWS_PROFILE wins over AWS_DEFAULT_PROFILE",
+			req:             ProfileRequest{UseEnv: true},
+			env:             map[string]string{"AWS_PROFILE": "staging", "AWS_DEFAULT_PROFILE": "dev"},
+			expectedProfile: "staging",
+			expectedSource:  SourceAWSProfile,
+		},
+		{
+			name:            "AWS_DEFAULT_PROFILE used when AWS_PROFILE is empty",
+			req:             ProfileRequest{UseEnv: true},
+			env:             map[string]string{"AWS_DEFAULT_PROFILE": "dev"},
+			expectedProfile: "dev",
+			expectedSource:  SourceAWSDefaultProfile,
+		},
+		{
+			name:            "Environment ignored without UseEnv",
+			req:             ProfileRequest{},
+			env:             map[string]string{"AWS_PROFILE": "staging", "AWS_DEFAULT_PROFILE": "dev"},
+			expectedProfile: "",
+			expectedSource:  SourceInteractive,
+		},
+		{
+			name:            "Unmatched query without environment is interactive",
+			req:             ProfileRequest{Query: "unknown"},
+			expectedProfile: "",
+			expectedSource:  SourceInteractive,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			getenv := func(key string) string { return tc.env[key] }
+
+			profile, source := resolveEnvProfile(tc.req, profiles, getenv)
+
+			if profile != tc.expectedProfile {
+				t.Errorf("Expected profile=%q, got %q", tc.expectedProfile, profile)
+			}
+			if source != tc.expectedSource {
+				t.Errorf("Expected source=%q, got %q", tc.expectedSource, source)
+			}
+		})
+	}
+}
+
+func TestResolveAlias(t *testing.T) {
+	fc := config.DefaultFancyConfig()
+	fc.ProfileConfigs = map[string]config.ProfileConfig{
+		"production-123456789012": {Aliases: []string{"prod"}},
+		"dev":                     {Aliases: []string{"sandbox"}},
+	}
+	profiles := []string{"production-123456789012", "dev", "sandbox"}
+
+	testCases := []struct {
+		name     string
+		expected string
+	}{
+		{"prod", "production-123456789012"},
+		{"dev", "dev"},
+		{"sandbox", "sandbox"},
+		{"pro", "pro"},
+		{"", ""},
+	}
+	for _, tc := range testCases {
+		if got := resolveAlias(fc, tc.name, profiles); got != tc.expected {
+			t.Errorf("resolveAlias(%q) = %q, expected %q", tc.name, got, tc.expected)
+		}
+	}
+}
+
+func TestMatchingConfiguredProfiles(t *testing.T) {
+	fc := config.DefaultFancyConfig()
+	fc.ProfileConfigs = map[string]config.ProfileConfig{
+		"company_PROD_admin":   {Name: "Production"},
+		"company_DEV_admin":    {Aliases: []string{"sandbox"}},
+		"company_STAGE_reader": {},
+	}
+	fc.KubeOnlyProfiles = map[string]config.KubeProfileConfig{"minikube": {}}
+	profiles := []string{"company_PROD_admin", "company_DEV_admin", "company_STAGE_reader", "prod-unconfigured", "minikube"}
+
+	testCases := []struct {
+		query    string
+		expected []string
+	}{
+		{"prod", []string{"company_PROD_admin"}},
+		{"PRODUCTION", []string{"company_PROD_admin"}},
+		{"sand", []string{"company_DEV_admin"}},
+		{"mini", []string{"minikube"}},
+		{"admin", []string{"company_PROD_admin", "company_DEV_admin"}},
+		{"nothing", nil},
+	}
+	for _, tc := range testCases {
+		if got := matchingConfiguredProfiles(fc, profiles, tc.query); !reflect.DeepEqual(got, tc.expected) {
+			t.Errorf("matchingConfiguredProfiles(%q) = %v, expected %v", tc.query, got, tc.expected)
+		}
+	}
+}
+
+func TestLastProfile(t *testing.T) {
+	testCases := []struct {
+		name            string
+		recorded        string
+		expectedProfile string
+		expectedSource  ProfileSource
+	}{
+		{"Nothing recorded", "", "", SourceInteractive},
+		{"Recorded profile", "staging", "staging", SourceLast},
+		{"Removed profile", "legacy", "", SourceInteractive},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("FANCY_STATE_DIR", t.TempDir())
+			if tc.recorded != "" {
+				if err := RecordLastLogin(tc.recorded, "staging-cluster"); err != nil {
+					t.Fatal(err)
+				}
+			}
+			manager := NewAWSManager(config.NewConfig(), utils.NewLogger(false), config.DefaultFancyConfig())
+
+			profile, source := manager.lastProfile([]string{"dev", "staging"})
+			if profile != tc.expectedProfile || source != tc.expectedSource {
+				t.Errorf("Expected %q from %s, got %q from %s", tc.expectedProfile, tc.expectedSource, profile, source)
+			}
+		})
+	}
+}
+
+func TestResolveProfileExported(t *testing.T) {
+	testCases := []struct {
+		name            string
+		req             ProfileRequest
+		env             map[string]string
+		expectedProfile string
+		expectedSource  ProfileSource
+		// expectedExport is the exported profile afterwards
+		expectedExport string
+	}{
+		{
+			name:            "Exported profile without env",
+			req:             ProfileRequest{UseEnv: true, Exported: true, NoExport: true},
+			expectedProfile: "staging",
+			expectedSource:  SourceExported,
+			expectedExport:  "staging",
+		},
+		{
+			name:            "AWS_DEFAULT_PROFILE wins over the exported profile",
+			req:             ProfileRequest{UseEnv: true, Exported: true, NoExport: true},
+			env:             map[string]string{"AWS_DEFAULT_PROFILE": "prod"},
+			expectedProfile: "prod",
+			expectedSource:  SourceAWSDefaultProfile,
+			expectedExport:  "staging",
+		},
+		{
+			name:            "AWS_PROFILE wins over AWS_DEFAULT_PROFILE",
+			req:             ProfileRequest{UseEnv: true, Exported: true, NoExport: true},
+			env:             map[string]string{"AWS_PROFILE": "dev", "AWS_DEFAULT_PROFILE": "prod"},
+			expectedProfile: "dev",
+			expectedSource:  SourceAWSProfile,
+			expectedExport:  "staging",
+		},
+		{
+			name:            "Flag is exported without NoExport",
+			req:             ProfileRequest{Flag: "prod", UseEnv: true},
+			expectedProfile: "prod",
+			expectedSource:  SourceFlag,
+			expectedExport:  "prod",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			awsConfig := filepath.Join(dir, "config")
+			if err := os.WriteFile(awsConfig, []byte("[profile dev]\n[profile staging]\n[profile prod]\n"), 0600); err != nil {
+				t.Fatal(err)
+			}
+			t.Setenv("AWS_CONFIG_FILE", awsConfig)
+			t.Setenv("AWS_PROFILE", tc.env["AWS_PROFILE"])
+			t.Setenv("AWS_DEFAULT_PROFILE", tc.env["AWS_DEFAULT_PROFILE"])
+
+			cfg := config.NewConfig()
+			cfg.AWSProfileTemp = filepath.Join(dir, "aws_profile.sh")
+			if err := writeProfileScripts(platform.ProfileScripts(cfg.AWSProfileTemp, "staging"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			manager := NewAWSManager(cfg, utils.NewLogger(false), config.DefaultFancyConfig())
+
+			profile, source, err := manager.ResolveProfile(context.Background(), tc.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if profile != tc.expectedProfile || source != tc.expectedSource {
+				t.Errorf("Expected %q from %s, got %q from %s", tc.expectedProfile, tc.expectedSource, profile, source)
+			}
+			exported, err := platform.ReadProfileScript(cfg.AWSProfileTemp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if exported != tc.expectedExport {
+				t.Errorf("Expected %q to stay exported, got %q", tc.expectedExport, exported)
+			}
+		})
+	}
+}
+
+func TestPickerStartPos(t *testing.T) {
+	profiles := []ProfileDisplayInfo{
+		{Name: "---", DisplayText: "=== Configured ==="},
+		{Name: "dev", DisplayText: "dev"},
+		{Name: "staging", DisplayText: "staging"},
+	}
+
+	if pos := pickerStartPos(profiles, "staging"); pos != 3 {
+		t.Errorf("Expected line 3, got %d", pos)
+	}
+	if pos := pickerStartPos(profiles, "prod"); pos != 0 {
+		t.Errorf("Expected 0 for a profile not in the picker, got %d", pos)
+	}
+}
@@ -0,0 +1,69 @@
+package aws
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+// CurrentSession is the AWS session a terminal points at
+type CurrentSession struct {
+	// Profile is the effective profile: AWS_PROFILE, else the exported one
+	Profile string
+	// EnvProfile is AWS_PROFILE; FileProfile the one the shell integration
+	// exports. They differ in shells started before the last login.
+	EnvProfile  string
+	FileProfile string
+	KubeOnly    bool
+	Valid       bool
+	Identity    CallerIdentity
+	// ExpiresAt is when the cached SSO token expires, zero if unknown
+	ExpiresAt time.Time
+	// Registry is the profile's ECR registry, nil if it doesn't log in to
+	// ECR or its account is unknown
+	Registry            *Registry
+	DockerAuthenticated bool
+}
+
+// CheckCurrentSession checks the session of the effective profile with a
+// single STS call and no prompts. Without a profile it returns a zero
+// CurrentSession.
+func (aws *AWSManager) CheckCurrentSession(ctx context.Context) (*CurrentSession, error) {
+	session := &CurrentSession{EnvProfile: os.Getenv("AWS_PROFILE")}
+	fileProfile, err := aws.ExportedProfile()
+	if err != nil {
+		return nil, err
+	}
+	session.FileProfile = fileProfile
+	session.Profile = session.EnvProfile
+	if session.Profile == "" {
+		session.Profile = session.FileProfile
+	}
+	if session.Profile == "" {
+		return session, nil
+	}
+	if aws.fancyConfig.IsKubeOnlyProfile(session.Profile) {
+		session.KubeOnly = true
+		return session, nil
+	}
+
+	identity, err := aws.callerIdentity(ctx, session.Profile)
+	session.Valid = err == nil
+	session.Identity = identity
+	session.ExpiresAt = aws.SessionExpiry(session.Profile)
+
+	if aws.fancyConfig.ShouldPerformECRLogin(session.Profile) {
+		accountID := identity.Account
+		if accountID == "" {
+			accountID = aws.configuredAccountID(session.Profile)
+		}
+		if accountID != "" {
+			registry := aws.ecrRegistry(session.Profile, accountID)
+			session.Registry = &registry
+			if session.DockerAuthenticated, err = DockerAuthenticated(registry.Host()); err != nil {
+				return session, err
+			}
+		}
+	}
+	return session, nil
+}
@@ -0,0 +1,96 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+
+	"fancy-login/internal/config"
+	"fancy-login/internal/platform"
+	"fancy-login/internal/utils"
+)
+
+// guidedProfileSetup walks a new user without any AWS profiles through
+// `aws configure sso` and the single-profile wizard, returning the new profile
+func (aws *AWSManager) guidedProfileSetup(ctx context.Context) (string, error) {
+	aws.logger.LogWarning("No AWS profiles found in ~/.aws/config")
+
+	if _, err := exec.LookPath("aws"); err != nil {
+		aws.logger.LogError("The AWS CLI (aws) is not installed. Install it with:")
+		for _, line := range platform.AWSCLIInstallHint() {
+			fmt.Fprintf(aws.logger.Output(), "  %s\n", line)
+		}
+		return "", utils.WithExitCode(utils.ExitMissingDependency,
+			errors.New("install the AWS CLI and run fancy-login-go again"))
+	}
+
+	fmt.Fprintln(aws.logger.Output(), "fancy-login needs at least one AWS profile. `aws configure sso` creates one")
+	fmt.Fprintln(aws.logger.Output(), "interactively from your SSO start URL.")
+
+	prompter, closeTTY, err := aws.newPrompter()
+	if err != nil {
+		return "", fmt.Errorf("no AWS profiles found and no terminal to set one up: %w", err)
+	}
+	defer closeTTY()
+
+	question := fmt.Sprintf("%sRun `aws configure sso` now?%s", config.Accent, config.Reset)
+	if !prompter.Confirm(question, true) {
+		return "", utils.WithExitCode(utils.ExitConfig, errors.New("no AWS profiles found in ~/.aws/config"))
+	}
+
+	before, err := aws.getAWSConfigProfiles()
+	if err != nil {
+		return "", err
+	}
+
+	// aws configure sso is interactive, so it keeps the terminal
+	cmd := exec.CommandContext(ctx, "aws", "configure", "sso")
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = aws.logger.Output()
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("aws configure sso failed: %w", err)
+	}
+
+	// The AWS config just changed, so parse it again
+	aws.snapshot.Reload()
+	after, err := aws.getAWSConfigProfiles()
+	if err != nil {
+		return "", err
+	}
+	created := newProfiles(before, after)
+	if len(created) == 0 {
+		return "", fmt.Errorf("aws configure sso did not create a profile")
+	}
+	profile := created[0]
+	aws.logger.LogInfo(fmt.Sprintf("Created AWS profile %s", profile))
+
+	if err := config.RunProfileWizard(aws.fancyConfig, profile); err != nil {
+		aws.logger.LogWarning(fmt.Sprintf("Profile %s was not configured: %v", profile, err))
+	}
+
+	if err := aws.exportProfileToTemp(profile); err != nil {
+		aws.logger.LogWarning(fmt.Sprintf("Failed to export profile to temp file: %v", err))
+	}
+	return profile, nil
+}
+
+// newProfiles returns the profiles in after that weren't in before, sorted
+func newProfiles(before, after []string) []string {
+	existing := make(map[string]bool, len(before))
+	for _, p := range before {
+		existing[p] = true
+	}
+
+	var created []string
+	for _, p := range after {
+		if !existing[p] {
+			created = append(created, p)
+		}
+	}
+	sort.Strings(created)
+	return created
+}
@@ -0,0 +1,27 @@
+package aws
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewProfiles(t *testing.T) {
+	testCases := []struct {
+		name     string
+		before   []string
+		after    []string
+		expected []string
+	}{
+		{"First profile on a new machine", nil, []string{"dev"}, []string{"dev"}},
+		{"Added next to existing", []string{"default"}, []string{"default", "prod", "dev"}, []string{"dev", "prod"}},
+		{"Nothing created", []string{"dev"}, []string{"dev"}, nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := newProfiles(tc.before, tc.after); !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("Expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package aws
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+)
+
+// userCodePattern matches the device authorization code aws sso login
+// prints on a line of its own, e.g. ABCD-EFGH
+var userCodePattern = regexp.MustCompile(`^[A-Z0-9]{4}-[A-Z0-9]{4}$`)
+
+// deviceAuthFilter is the output of a quiet aws sso login. It drops the
+// CLI's chatter but hands the verification URL and user code to show, since
+// without a browser nobody could complete the login otherwise.
+type deviceAuthFilter struct {
+	show    func(line string)
+	pending []byte
+}
+
+// Write splits the output into lines; a trailing partial line waits for
+// the next write
+func (f *deviceAuthFilter) Write(data []byte) (int, error) {
+	f.pending = append(f.pending, data...)
+	for {
+		i := bytes.IndexByte(f.pending, '\n')
+		if i < 0 {
+			break
+		}
+		f.line(string(f.pending[:i]))
+		f.pending = f.pending[i+1:]
+	}
+	return len(data), nil
+}
+
+// Flush handles a last line without a trailing newline
+func (f *deviceAuthFilter) Flush() {
+	if len(f.pending) > 0 {
+		f.line(string(f.pending))
+		f.pending = nil
+	}
+}
+
+func (f *deviceAuthFilter) line(line string) {
+	line = strings.TrimSpace(line)
+	switch {
+	case strings.HasPrefix(line, "https://"):
+		f.show("🌐 Open " + line)
+	case userCodePattern.MatchString(line):
+		f.show("🔢 and enter the code " + line)
+	}
+}
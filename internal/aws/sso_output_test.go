@@ -0,0 +1,29 @@
+package aws
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDeviceAuthFilter(t *testing.T) {
+	var shown []string
+	filter := &deviceAuthFilter{show: func(line string) { shown = append(shown, line) }}
+
+	// the output as aws sso login --no-browser prints it, split mid-line
+	output := "Browser will not be automatically opened.\nPlease visit the following URL:\n\n" +
+		"https://device.sso.eu-central-1.amazonaws.com/\n\nThen enter the code:\n\nABCD-EFGH\n\n" +
+		"Alternatively, you may visit the following URL which will autofill the code upon loading:\n" +
+		"https://device.sso.eu-central-1.amazonaws.com/?user_code=ABCD-EFGH"
+	filter.Write([]byte(output[:70]))
+	filter.Write([]byte(output[70:]))
+	filter.Flush()
+
+	expected := []string{
+		"🌐 Open https://device.sso.eu-central-1.amazonaws.com/",
+		"🔢 and enter the code ABCD-EFGH",
+		"🌐 Open https://device.sso.eu-central-1.amazonaws.com/?user_code=ABCD-EFGH",
+	}
+	if !reflect.DeepEqual(shown, expected) {
+		t.Errorf("Expected %q, got %q", expected, shown)
+	}
+}
@@ -0,0 +1,329 @@
+package aws
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"fancy-login/internal/config"
+	"fancy-login/internal/state"
+	"fancy-login/internal/utils"
+)
+
+// SessionStatus is the observed state of a profile's credentials
+type SessionStatus string
+
+const (
+	StatusValid     SessionStatus = "valid"
+	StatusExpired   SessionStatus = "expired"
+	StatusRevoked   SessionStatus = "revoked (token present but rejected)"
+	StatusNoSession SessionStatus = "no session"
+	StatusError     SessionStatus = "error"
+	StatusUnknown   SessionStatus = "unknown"
+)
+
+// CallerIdentity is the result of `aws sts get-caller-identity`
+type CallerIdentity struct {
+	Account string `json:"Account"`
+	Arn     string `json:"Arn"`
+	UserID  string `json:"UserId"`
+}
+
+// SessionCheck is the live status of one profile
+type SessionCheck struct {
+	Profile   string
+	Status    SessionStatus
+	Identity  CallerIdentity
+	Err       error
+	CheckedAt time.Time
+}
+
+// Record converts a check into its cached state form
+func (c SessionCheck) Record() state.SessionRecord {
+	record := state.SessionRecord{
+		Status:    string(c.Status),
+		Account:   c.Identity.Account,
+		Arn:       c.Identity.Arn,
+		CheckedAt: c.CheckedAt,
+	}
+	if c.Err != nil {
+		record.Error = c.Err.Error()
+	}
+	return record
+}
+
+// ssoToken describes the cached SSO access token of a profile, if any
+type ssoToken struct {
+	Found     bool
+	ExpiresAt time.Time
+	// AccessToken and Region are what the SSO portal API needs
+	AccessToken string
+	Region      string
+}
+
+// SessionChecker runs live STS checks for many profiles with bounded
+// concurrency and a per-profile timeout
+type SessionChecker struct {
+	Concurrency int
+	Timeout     time.Duration
+
+	// callerIdentity and lookupToken are replaced in tests
+	callerIdentity func(ctx context.Context, profile string) (CallerIdentity, error)
+	lookupToken    func(profile config.AWSProfile) ssoToken
+	now            func() time.Time
+}
+
+// NewSessionChecker creates a checker calling STS with the SDK, like
+// AWSManager does, and reading the SSO token cache
+func NewSessionChecker(concurrency int, timeout time.Duration) *SessionChecker {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &SessionChecker{
+		Concurrency:    concurrency,
+		Timeout:        timeout,
+		callerIdentity: newSDKSTSClient(func(string) string { return config.BackendCLI }, nil).CallerIdentity,
+		lookupToken:    lookupSSOToken,
+		now:            time.Now,
+	}
+}
+
+// SetBackends makes the checker call STS through the credential backend
+// each profile is configured with. The CLI's SSO token cache says nothing
+// about an aws-vault profile, so it isn't consulted for one.
+func (c *SessionChecker) SetBackends(fc *config.FancyConfig) {
+	c.callerIdentity = newSDKSTSClient(fc.ProfileBackend, nil).CallerIdentity
+	lookupToken := c.lookupToken
+	c.lookupToken = func(profile config.AWSProfile) ssoToken {
+		if fc.ProfileBackend(profile.Name) == config.BackendAWSVault {
+			return ssoToken{}
+		}
+		return lookupToken(profile)
+	}
+}
+
+// Check validates every profile live and returns the results in input order
+func (c *SessionChecker) Check(ctx context.Context, profiles []config.AWSProfile) []SessionCheck {
+	results := make([]SessionCheck, len(profiles))
+	sem := make(chan struct{}, c.Concurrency)
+	var wg sync.WaitGroup
+
+	for i, profile := range profiles {
+		wg.Add(1)
+		go func(i int, profile config.AWSProfile) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i] = c.checkOne(ctx, profile)
+		}(i, profile)
+	}
+
+	wg.Wait()
+	return results
+}
+
+// checkOne validates a single profile under the per-profile timeout
+func (c *SessionChecker) checkOne(ctx context.Context, profile config.AWSProfile) SessionCheck {
+	ctx, cancel := utils.WithStepTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	identity, err := c.callerIdentity(ctx, profile.Name)
+	if err != nil {
+		err = utils.StepError(ctx, "aws sts get-caller-identity", c.Timeout, err)
+	}
+
+	now := c.now()
+	return SessionCheck{
+		Profile:   profile.Name,
+		Status:    classifySession(err, profile.IsSSO, c.lookupToken(profile), now),
+		Identity:  identity,
+		Err:       err,
+		CheckedAt: now,
+	}
+}
+
+// NeedsLogin reports whether the status means logging in again would help,
+// as opposed to an error unrelated to the session
+func (s SessionStatus) NeedsLogin() bool {
+	return s == StatusExpired || s == StatusNoSession || s == StatusRevoked
+}
+
+// classifySession maps an STS result and the cached SSO token to a status.
+// A token that is still within its lifetime but rejected by STS has been
+// revoked server-side, which cached expiry data alone cannot show.
+func classifySession(stsErr error, isSSO bool, token ssoToken, now time.Time) SessionStatus {
+	if stsErr == nil {
+		return StatusValid
+	}
+
+	// Timeouts, network failures and a missing aws CLI say nothing about
+	// the session itself
+	var timeoutErr *utils.TimeoutError
+	var networkErr *STSNetworkError
+	var execErr *exec.Error
+	if errors.As(stsErr, &timeoutErr) || errors.As(stsErr, &networkErr) || errors.As(stsErr, &execErr) || !isSSO {
+		return StatusError
+	}
+
+	if !token.Found {
+		return StatusNoSession
+	}
+	if token.ExpiresAt.After(now) {
+		return StatusRevoked
+	}
+	return StatusExpired
+}
+
+// backendCallerIdentity calls `aws sts get-caller-identity` for a profile
+// with the given credential backend, in env, or this process's environment
+// if nil
+func backendCallerIdentity(ctx context.Context, backend, profile string, env []string) (CallerIdentity, error) {
+	name, args := awsCLIArgs(backend, profile, "sts", "get-caller-identity", "--output", "json")
+	cmd := utils.CommandContext(ctx, name, args...)
+	cmd.Env = env
+	output, err := cmd.Output()
+	if err != nil {
+		return CallerIdentity{}, err
+	}
+
+	var identity CallerIdentity
+	if err := json.Unmarshal(output, &identity); err != nil {
+		return CallerIdentity{}, fmt.Errorf("failed to parse caller identity: %w", err)
+	}
+	return identity, nil
+}
+
+// lookupSSOToken finds the cached SSO access token for a profile in
+// ~/.aws/sso/cache, matching by sso-session name or start URL
+func lookupSSOToken(profile config.AWSProfile) ssoToken {
+	if !profile.IsSSO {
+		return ssoToken{}
+	}
+
+	homeDir, _ := os.UserHomeDir()
+	cacheDir := filepath.Join(homeDir, ".aws", "sso", "cache")
+
+	// sso-session tokens are stored under the SHA-1 of the session name
+	if profile.SSOSession != "" {
+		sum := sha1.Sum([]byte(profile.SSOSession))
+		if token, ok := readSSOToken(filepath.Join(cacheDir, hex.EncodeToString(sum[:])+".json"), ""); ok {
+			return token
+		}
+	}
+
+	if profile.SSOStartURL == "" {
+		return ssoToken{}
+	}
+	return lookupSSOTokenByStartURL(profile.SSOStartURL)
+}
+
+// lookupSSOTokenByStartURL finds a cached SSO access token issued for the
+// portal at startURL
+func lookupSSOTokenByStartURL(startURL string) ssoToken {
+	homeDir, _ := os.UserHomeDir()
+	cacheDir := filepath.Join(homeDir, ".aws", "sso", "cache")
+	entries, err := os.ReadDir(cacheDir)
+	if err != nil {
+		return ssoToken{}
+	}
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		if token, ok := readSSOToken(filepath.Join(cacheDir, entry.Name()), startURL); ok {
+			return token
+		}
+	}
+	return ssoToken{}
+}
+
+// readSSOToken reads an SSO cache file, optionally requiring a start URL
+func readSSOToken(path, startURL string) (ssoToken, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ssoToken{}, false
+	}
+
+	var cached struct {
+		StartURL    string `json:"startUrl"`
+		AccessToken string `json:"accessToken"`
+		ExpiresAt   string `json:"expiresAt"`
+		Region      string `json:"region"`
+	}
+	if err := json.Unmarshal(data, &cached); err != nil || cached.AccessToken == "" {
+		return ssoToken{}, false
+	}
+	if startURL != "" && strings.TrimSuffix(cached.StartURL, "/") != strings.TrimSuffix(startURL, "/") {
+		return ssoToken{}, false
+	}
+
+	expiresAt, err := time.Parse(time.RFC3339, cached.ExpiresAt)
+	if err != nil {
+		// Older CLIs wrote "2006-01-02T15:04:05UTC"
+		expiresAt, err = time.Parse("2006-01-02T15:04:05UTC", cached.ExpiresAt)
+		if err != nil {
+			return ssoToken{}, false
+		}
+	}
+	return ssoToken{Found: true, ExpiresAt: expiresAt, AccessToken: cached.AccessToken, Region: cached.Region}, true
+}
+
+// FormatAge renders how long ago cached data was observed
+func FormatAge(checkedAt, now time.Time) string {
+	if checkedAt.IsZero() {
+		return "never checked"
+	}
+	age := now.Sub(checkedAt)
+	switch {
+	case age < time.Minute:
+		return "just now"
+	case age < time.Hour:
+		return fmt.Sprintf("%dm ago", int(age.Minutes()))
+	case age < 48*time.Hour:
+		return fmt.Sprintf("%dh ago", int(age.Hours()))
+	}
+	return fmt.Sprintf("%dd ago", int(age.Hours()/24))
+}
+
+// FormatRemaining renders how long a session has left
+func FormatRemaining(expiresAt, now time.Time) string {
+	left := expiresAt.Sub(now)
+	switch {
+	case expiresAt.IsZero():
+		return "unknown"
+	case left <= 0:
+		return "expired"
+	case left < time.Hour:
+		return fmt.Sprintf("%dm left", int(left.Minutes()))
+	}
+	return fmt.Sprintf("%dh%02dm left", int(left.Hours()), int(left.Minutes())%60)
+}
+
+// RecordSessionChecks caches live check results in the state file
+func RecordSessionChecks(checks []SessionCheck) error {
+	return state.Update(func(st *state.State) error {
+		for _, check := range checks {
+			st.RecordSession(check.Profile, check.Record())
+		}
+		return nil
+	})
+}
+
+// SessionExpiry returns when the cached SSO session of a profile expires, or
+// the zero time if it isn't an SSO profile or no token is cached
+func (aws *AWSManager) SessionExpiry(profile string) time.Time {
+	p, ok := aws.snapshot.AWSProfile(profile)
+	if !ok {
+		return time.Time{}
+	}
+	return lookupSSOToken(p).ExpiresAt
+}
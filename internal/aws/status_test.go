@@ -0,0 +1,172 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"fancy-login/internal/config"
+	"fancy-login/internal/utils"
+)
+
+func TestClassifySession(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	rejected := errors.New("exit status 255")
+
+	testCases := []struct {
+		name     string
+		err      error
+		isSSO    bool
+		token    ssoToken
+		expected SessionStatus
+	}{
+		{"Accepted", nil, true, ssoToken{}, StatusValid},
+		{"Accepted non-SSO", nil, false, ssoToken{}, StatusValid},
+		{"Token present but rejected", rejected, true, ssoToken{Found: true, ExpiresAt: now.Add(time.Hour)}, StatusRevoked},
+		{"Token expired", rejected, true, ssoToken{Found: true, ExpiresAt: now.Add(-time.Hour)}, StatusExpired},
+		{"No token", rejected, true, ssoToken{}, StatusNoSession},
+		{"Non-SSO failure", rejected, false, ssoToken{}, StatusError},
+		{"Missing aws CLI", &exec.Error{Name: "aws", Err: exec.ErrNotFound}, true, ssoToken{}, StatusError},
+		{"Timeout", &utils.TimeoutError{Step: "aws sts get-caller-identity", Timeout: time.Second}, true, ssoToken{Found: true, ExpiresAt: now.Add(time.Hour)}, StatusError},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := classifySession(tc.err, tc.isSSO, tc.token, now); got != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestSessionCheckerBoundsConcurrency(t *testing.T) {
+	var mu sync.Mutex
+	running, peak := 0, 0
+
+	checker := NewSessionChecker(2, time.Second)
+	checker.lookupToken = func(config.AWSProfile) ssoToken { return ssoToken{} }
+	checker.callerIdentity = func(ctx context.Context, profile string) (CallerIdentity, error) {
+		mu.Lock()
+		running++
+		if running > peak {
+			peak = running
+		}
+		mu.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+		if profile == "broken" {
+			return CallerIdentity{}, errors.New("rejected")
+		}
+		return CallerIdentity{Account: "123456789012"}, nil
+	}
+
+	profiles := []config.AWSProfile{
+		{Name: "a", IsSSO: true}, {Name: "b", IsSSO: true}, {Name: "broken", IsSSO: true},
+		{Name: "c", IsSSO: true}, {Name: "d", IsSSO: true},
+	}
+	results := checker.Check(context.Background(), profiles)
+
+	if peak > 2 {
+		t.Errorf("Expected at most 2 concurrent checks, got %d", peak)
+	}
+	for i, result := range results {
+		if result.Profile != profiles[i].Name {
+			t.Errorf("Expected results in input order, got %s at %d", result.Profile, i)
+		}
+	}
+	if results[2].Status != StatusNoSession {
+		t.Errorf("Expected broken profile to have no session, got %q", results[2].Status)
+	}
+	if results[0].Status != StatusValid || results[0].Identity.Account != "123456789012" {
+		t.Errorf("Expected valid session with account, got %+v", results[0])
+	}
+}
+
+func TestSessionCheckerTimeout(t *testing.T) {
+	checker := NewSessionChecker(1, 10*time.Millisecond)
+	checker.lookupToken = func(config.AWSProfile) ssoToken { return ssoToken{} }
+	checker.callerIdentity = func(ctx context.Context, profile string) (CallerIdentity, error) {
+		<-ctx.Done()
+		return CallerIdentity{}, ctx.Err()
+	}
+
+	results := checker.Check(context.Background(), []config.AWSProfile{{Name: "slow", IsSSO: true}})
+
+	var timeoutErr *utils.TimeoutError
+	if results[0].Status != StatusError || !errors.As(results[0].Err, &timeoutErr) {
+		t.Errorf("Expected timeout error, got %q / %v", results[0].Status, results[0].Err)
+	}
+}
+
+func TestLookupSSOToken(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	cacheDir := filepath.Join(home, ".aws", "sso", "cache")
+	if err := os.MkdirAll(cacheDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	token := `{"startUrl": "https://example.awsapps.com/start/", "accessToken": "x", "expiresAt": "2030-01-01T00:00:00Z"}`
+	if err := os.WriteFile(filepath.Join(cacheDir, "abc.json"), []byte(token), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	found := lookupSSOToken(config.AWSProfile{Name: "dev", IsSSO: true, SSOStartURL: "https://example.awsapps.com/start"})
+	if !found.Found || found.ExpiresAt.Year() != 2030 {
+		t.Errorf("Expected token expiring 2030, got %+v", found)
+	}
+
+	missing := lookupSSOToken(config.AWSProfile{Name: "other", IsSSO: true, SSOStartURL: "https://other.awsapps.com/start"})
+	if missing.Found {
+		t.Errorf("Expected no token for other start URL, got %+v", missing)
+	}
+}
+
+func TestFormatAge(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		checkedAt time.Time
+		expected  string
+	}{
+		{time.Time{}, "never checked"},
+		{now.Add(-10 * time.Second), "just now"},
+		{now.Add(-5 * time.Minute), "5m ago"},
+		{now.Add(-3 * time.Hour), "3h ago"},
+		{now.Add(-72 * time.Hour), "3d ago"},
+	}
+
+	for _, tc := range testCases {
+		if got := FormatAge(tc.checkedAt, now); got != tc.expected {
+			t.Errorf("FormatAge(%v) = %q, expected %q", tc.checkedAt, got, tc.expected)
+		}
+	}
+}
+
+func TestFormatRemaining(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		expiresAt time.Time
+		expected  string
+	}{
+		{time.Time{}, "unknown"},
+		{now.Add(-time.Minute), "expired"},
+		{now.Add(42 * time.Minute), "42m left"},
+		{now.Add(7*time.Hour + 5*time.Minute), "7h05m left"},
+	}
+
+	for _, tc := range testCases {
+		if got := FormatRemaining(tc.expiresAt, now); got != tc.expected {
+			t.Errorf("FormatRemaining(%v) = %q, expected %q", tc.expiresAt, got, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	awssdk "github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+
+	"fancy-login/internal/config"
+	"fancy-login/internal/utils"
+)
+
+// STSClient looks up the caller identity of a profile. It is the seam for
+// session checks and account ID lookups, so tests can stub it.
+type STSClient interface {
+	CallerIdentity(ctx context.Context, profile string) (CallerIdentity, error)
+}
+
+// ErrSessionExpired is matched by errors.Is when AWS rejected a profile's
+// session or SSO token as expired or invalid, so logging in again helps
+var ErrSessionExpired = errors.New("session expired")
+
+// STSNetworkError is an STS or SSO call that never got an answer from AWS.
+// It says nothing about the session itself.
+type STSNetworkError struct {
+	Endpoint string
+	Err      error
+}
+
+func (e *STSNetworkError) Error() string {
+	return fmt.Sprintf("could not reach %s: %v", e.Endpoint, e.Err)
+}
+
+func (e *STSNetworkError) Unwrap() error {
+	return e.Err
+}
+
+// STSAPIError is an error response of the STS or SSO API
+type STSAPIError struct {
+	Code    string
+	Message string
+}
+
+func (e *STSAPIError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// Is makes rejected credentials match ErrSessionExpired
+func (e *STSAPIError) Is(target error) bool {
+	if target != ErrSessionExpired {
+		return false
+	}
+	switch e.Code {
+	case "ExpiredToken", "ExpiredTokenException", "InvalidClientTokenId", "UnauthorizedException":
+		return true
+	}
+	return false
+}
+
+// cliSTSClient runs `aws sts get-caller-identity`, through aws-vault for
+// profiles with that backend. Its errors are the CLI's: an *exec.ExitError
+// carrying stderr, or an *exec.Error if the aws CLI isn't installed.
+type cliSTSClient struct {
+	backend func(profile string) string
+	// profileEnv returns what the command for a profile adds to the
+	// environment; nil adds nothing
+	profileEnv func(profile string) map[string]string
+}
+
+func (c cliSTSClient) CallerIdentity(ctx context.Context, profile string) (CallerIdentity, error) {
+	var env []string
+	if c.profileEnv != nil {
+		env = utils.ExtendEnv(os.Environ(), c.profileEnv(profile))
+	}
+	return backendCallerIdentity(ctx, c.backend(profile), profile, env)
+}
+
+// sdkSTSClient calls STS with aws-sdk-go-v2, saving the aws CLI's startup
+// for every check. The shared config loader resolves profiles like the CLI
+// does: SSO, static keys, credential_process, source_profile chains and web
+// identity. Profiles it can't load, such as a missing one or a role with
+// mfa_serial, whose session only the CLI's cache holds, go to fallback, as
+// do profiles with the aws-vault backend.
+type sdkSTSClient struct {
+	backend func(profile string) string
+	// profileEnv returns what the aws CLI would see added to the
+	// environment for a profile, such as the credentials file of its MFA
+	// session; nil adds nothing
+	profileEnv func(profile string) map[string]string
+	fallback   STSClient
+	// loadOptions are added to those of every config load; tests point
+	// the endpoints at a local server with them
+	loadOptions []func(*awsconfig.LoadOptions) error
+}
+
+// newSDKSTSClient creates a client falling back to the aws CLI.
+// profileEnv may be nil.
+func newSDKSTSClient(backend func(profile string) string, profileEnv func(profile string) map[string]string) *sdkSTSClient {
+	return &sdkSTSClient{
+		backend:    backend,
+		profileEnv: profileEnv,
+		fallback:   cliSTSClient{backend: backend, profileEnv: profileEnv},
+	}
+}
+
+func (c *sdkSTSClient) CallerIdentity(ctx context.Context, profile string) (CallerIdentity, error) {
+	if c.backend(profile) != config.BackendCLI {
+		return c.fallback.CallerIdentity(ctx, profile)
+	}
+	options := append([]func(*awsconfig.LoadOptions) error{awsconfig.WithSharedConfigProfile(profile)}, c.loadOptions...)
+	if c.profileEnv != nil {
+		if file := c.profileEnv(profile)["AWS_SHARED_CREDENTIALS_FILE"]; file != "" {
+			options = append(options, awsconfig.WithSharedCredentialsFiles([]string{file}))
+		}
+	}
+	cfg, err := awsconfig.LoadDefaultConfig(ctx, options...)
+	if err != nil || cfg.Credentials == nil {
+		return c.fallback.CallerIdentity(ctx, profile)
+	}
+	// The CLI calls STS in us-east-1 when nothing sets a region
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+
+	// Credentials are fetched on their own first, since the SDK doesn't
+	// type the errors of an STS call that couldn't get any. The call then
+	// reuses them.
+	if _, err := cfg.Credentials.Retrieve(ctx); err != nil {
+		return CallerIdentity{}, sdkError(ctx, err, true)
+	}
+	output, err := sts.NewFromConfig(cfg).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return CallerIdentity{}, sdkError(ctx, err, false)
+	}
+	return CallerIdentity{
+		Account: awssdk.ToString(output.Account),
+		Arn:     awssdk.ToString(output.Arn),
+		UserID:  awssdk.ToString(output.UserId),
+	}, nil
+}
+
+// sdkError maps an SDK error to the errors of this package. Cancellation
+// and deadlines are returned as they are, for utils.StepError to name;
+// requests that never got an answer become *STSNetworkError, error
+// responses *STSAPIError. Any other failure to load credentials, such as
+// an expired SSO token or a failing credential_process, matches
+// ErrSessionExpired, since logging in again is what helps.
+func sdkError(ctx context.Context, err error, loadingCredentials bool) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	var sendErr *smithyhttp.RequestSendError
+	if errors.As(err, &sendErr) {
+		return &STSNetworkError{Endpoint: failedService(err), Err: sendErr.Err}
+	}
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		return &STSAPIError{Code: apiErr.ErrorCode(), Message: apiErr.ErrorMessage()}
+	}
+	if loadingCredentials {
+		return fmt.Errorf("%w: %v", ErrSessionExpired, err)
+	}
+	return err
+}
+
+// failedService names the AWS service of the innermost operation in err,
+// e.g. SSO when the role credentials for an STS call couldn't be fetched
+func failedService(err error) string {
+	service := "AWS"
+	for err != nil {
+		var opErr *smithy.OperationError
+		if !errors.As(err, &opErr) {
+			break
+		}
+		service, err = opErr.ServiceID, opErr.Err
+	}
+	return service
+}
+
+// SetSTSClient replaces the client used for session checks and account IDs
+func (aws *AWSManager) SetSTSClient(client STSClient) {
+	aws.sts = client
+}
@@ -0,0 +1,460 @@
+package aws
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+
+	"fancy-login/internal/config"
+	"fancy-login/internal/utils"
+)
+
+// fakeSTS answers with identities per profile and fails for the rest
+type fakeSTS map[string]CallerIdentity
+
+func (f fakeSTS) CallerIdentity(ctx context.Context, profile string) (CallerIdentity, error) {
+	if identity, ok := f[profile]; ok {
+		return identity, nil
+	}
+	return CallerIdentity{}, errors.New("token expired")
+}
+
+func TestSTSClientStub(t *testing.T) {
+	manager := NewAWSManager(config.NewConfig(), utils.NewLogger(false), config.DefaultFancyConfig())
+	manager.SetSTSClient(fakeSTS{"dev": {Account: "123456789012"}})
+
+	if account, err := manager.GetAccountID(context.Background(), "dev"); err != nil || account != "123456789012" {
+		t.Errorf("Expected 123456789012, got %q, %v", account, err)
+	}
+	if _, err := manager.GetAccountID(context.Background(), "prod"); err == nil {
+		t.Error("Expected an error for a profile without a session")
+	}
+	if !manager.isSessionValid(context.Background(), "dev") || manager.isSessionValid(context.Background(), "prod") {
+		t.Error("Expected only dev to have a valid session")
+	}
+}
+
+func TestAWSCLIArgs(t *testing.T) {
+	testCases := []struct {
+		name         string
+		backend      string
+		expectedName string
+		expectedArgs []string
+	}{
+		{"CLI", config.BackendCLI, "aws", []string{"sts", "get-caller-identity", "--profile", "dev"}},
+		{"aws-vault", config.BackendAWSVault, "aws-vault", []string{"exec", "dev", "--", "aws", "sts", "get-caller-identity"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			name, args := awsCLIArgs(tc.backend, "dev", "sts", "get-caller-identity")
+			if name != tc.expectedName || !reflect.DeepEqual(args, tc.expectedArgs) {
+				t.Errorf("Expected %s %v, got %s %v", tc.expectedName, tc.expectedArgs, name, args)
+			}
+		})
+	}
+}
+
+// TestAWSVaultSessionCheck checks that the session of an aws-vault profile
+// is checked through aws-vault exec, without --profile
+func TestAWSVaultSessionCheck(t *testing.T) {
+	dir := t.TempDir()
+	argsFile := filepath.Join(dir, "args")
+	writeFakeHelper(t, dir, "aws-vault", `echo "$@" > `+argsFile+`
+echo '{"Account": "123456789012", "Arn": "arn:aws:iam::123456789012:user/dev"}'
+`)
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	fc := config.DefaultFancyConfig()
+	fc.ProfileConfigs = map[string]config.ProfileConfig{"dev": {Backend: config.BackendAWSVault}}
+	manager := NewAWSManager(config.NewConfig(), utils.NewLogger(false), fc)
+
+	account, err := manager.GetAccountID(context.Background(), "dev")
+	if err != nil || account != "123456789012" {
+		t.Fatalf("Expected 123456789012, got %q, %v", account, err)
+	}
+	data, err := os.ReadFile(argsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if args := strings.TrimSpace(string(data)); args != "exec dev -- aws sts get-caller-identity --output json" {
+		t.Errorf("Expected the check to run through aws-vault exec, got %q", args)
+	}
+}
+
+func TestSummaryAccountID(t *testing.T) {
+	enabled, disabled := true, false
+	testCases := []struct {
+		name            string
+		configured      string
+		settings        config.GlobalSettings
+		expected        string
+		expectedFromSTS bool
+		expectedSaved   string
+	}{
+		{"Configured", "210987654321", config.GlobalSettings{}, "210987654321", false, ""},
+		{"Cached", "", config.GlobalSettings{AutoUpdateAccountIDs: &enabled}, "123456789012", true, "123456789012"},
+		{"Toggle off", "", config.GlobalSettings{AutoUpdateAccountIDs: &disabled}, "123456789012", true, ""},
+		{"Toggle unset", "", config.GlobalSettings{}, "123456789012", true, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("HOME", t.TempDir())
+			fc := config.DefaultFancyConfig()
+			fc.Settings = tc.settings
+			fc.ProfileConfigs = map[string]config.ProfileConfig{"dev": {AccountID: tc.configured}}
+			manager := NewAWSManager(config.NewConfig(), utils.NewLogger(false), fc)
+			manager.SetSTSClient(fakeSTS{"dev": {Account: "123456789012"}})
+
+			account, fromSTS, err := manager.SummaryAccountID(context.Background(), "dev")
+			if err != nil || account != tc.expected || fromSTS != tc.expectedFromSTS {
+				t.Fatalf("Expected %s (STS: %v), got %s (STS: %v), %v", tc.expected, tc.expectedFromSTS, account, fromSTS, err)
+			}
+			saved, err := config.LoadFancyConfig()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := saved.ProfileConfigs["dev"].AccountID; got != tc.expectedSaved {
+				t.Errorf("Expected %q saved, got %q", tc.expectedSaved, got)
+			}
+		})
+	}
+}
+
+// TestCacheAccountIDReplacesStaleID checks that an account_id STS disagrees
+// with is replaced
+func TestCacheAccountIDReplacesStaleID(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	enabled := true
+	fc := config.DefaultFancyConfig()
+	fc.Settings.AutoUpdateAccountIDs = &enabled
+	fc.ProfileConfigs = map[string]config.ProfileConfig{"dev": {AccountID: "210987654321"}}
+	manager := NewAWSManager(config.NewConfig(), utils.NewLogger(false), fc)
+
+	manager.cacheAccountID("dev", "123456789012")
+	saved, err := config.LoadFancyConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := saved.ProfileConfigs["dev"].AccountID; got != "123456789012" {
+		t.Errorf("Expected the STS account ID to replace the stale one, got %q", got)
+	}
+}
+
+// stsIdentityXML is a GetCallerIdentity response for account
+func stsIdentityXML(account string) string {
+	return `<GetCallerIdentityResponse><GetCallerIdentityResult><Arn>arn:aws:iam::` + account +
+		`:user/dev</Arn><UserId>AIDAEXAMPLE</UserId><Account>` + account +
+		`</Account></GetCallerIdentityResult></GetCallerIdentityResponse>`
+}
+
+// stsErrorXML is an STS error response with code
+func stsErrorXML(code string) string {
+	return `<ErrorResponse><Error><Type>Sender</Type><Code>` + code +
+		`</Code><Message>The security token included in the request is invalid.</Message></Error></ErrorResponse>`
+}
+
+// useTestSTSServer makes manager call STS and SSO at a local server
+// answering with handler
+func useTestSTSServer(t *testing.T, manager *AWSManager, handler http.HandlerFunc) *sdkSTSClient {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client := newSDKSTSClient(manager.fancyConfig.ProfileBackend, manager.ProfileEnv)
+	client.loadOptions = []func(*awsconfig.LoadOptions) error{
+		awsconfig.WithBaseEndpoint(server.URL),
+		awsconfig.WithRetryMaxAttempts(1),
+	}
+	manager.SetSTSClient(client)
+	return client
+}
+
+// writeSSOTestProfile writes an AWS config with an SSO profile dev and,
+// unless expiresAt is zero, a cached token for its sso-session
+func writeSSOTestProfile(t *testing.T, expiresAt time.Time) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(home, "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(home, "credentials"))
+	t.Setenv("AWS_REGION", "")
+	t.Setenv("AWS_DEFAULT_REGION", "")
+
+	awsConfig := "[profile dev]\nsso_session = acme\nsso_account_id = 123456789012\nsso_role_name = Developer\nregion = eu-central-1\n\n" +
+		"[sso-session acme]\nsso_start_url = https://acme.awsapps.com/start\nsso_region = eu-west-1\n"
+	if err := os.WriteFile(filepath.Join(home, "config"), []byte(awsConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if expiresAt.IsZero() {
+		return
+	}
+	cacheDir := filepath.Join(home, ".aws", "sso", "cache")
+	if err := os.MkdirAll(cacheDir, 0700); err != nil {
+		t.Fatal(err)
+	}
+	sum := sha1.Sum([]byte("acme"))
+	token := `{"startUrl": "https://acme.awsapps.com/start", "region": "eu-west-1", "accessToken": "sso-token", "expiresAt": "` +
+		expiresAt.UTC().Format(time.RFC3339) + `"}`
+	if err := os.WriteFile(filepath.Join(cacheDir, hex.EncodeToString(sum[:])+".json"), []byte(token), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestSDKSTSClientSSO checks that an SSO profile's identity is looked up
+// with role credentials from SSO, without the aws CLI
+func TestSDKSTSClientSSO(t *testing.T) {
+	writeSSOTestProfile(t, time.Now().Add(time.Hour))
+	binDir := t.TempDir()
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	writeFakeHelper(t, binDir, "aws", "echo 'unexpected aws CLI call' >&2\nexit 1\n")
+
+	manager := NewAWSManager(config.NewConfig(), utils.NewLogger(false), config.DefaultFancyConfig())
+	useTestSTSServer(t, manager, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/federation/credentials":
+			q := r.URL.Query()
+			if r.Header.Get("x-amz-sso_bearer_token") != "sso-token" || q.Get("account_id") != "123456789012" || q.Get("role_name") != "Developer" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprintf(w, `{"roleCredentials": {"accessKeyId": "ASIAROLE", "secretAccessKey": "secret", "sessionToken": "role-session", "expiration": %d}}`,
+				time.Now().Add(time.Hour).UnixMilli())
+		default:
+			auth := r.Header.Get("Authorization")
+			if r.Header.Get("X-Amz-Security-Token") != "role-session" || !strings.Contains(auth, "Credential=ASIAROLE/") || !strings.Contains(auth, "/eu-central-1/sts/") {
+				w.WriteHeader(http.StatusForbidden)
+				w.Write([]byte(stsErrorXML("InvalidClientTokenId")))
+				return
+			}
+			w.Write([]byte(stsIdentityXML("123456789012")))
+		}
+	})
+
+	identity, err := manager.callerIdentity(context.Background(), "dev")
+	if err != nil || identity.Account != "123456789012" || identity.Arn == "" {
+		t.Errorf("Expected the identity of 123456789012, got %+v, %v", identity, err)
+	}
+}
+
+func TestSDKSTSClientErrors(t *testing.T) {
+	testCases := []struct {
+		name      string
+		expiresAt time.Time
+		status    int
+		body      string
+		check     func(err error) bool
+	}{
+		{"No cached token", time.Time{}, http.StatusOK, "", func(err error) bool { return errors.Is(err, ErrSessionExpired) }},
+		{"Token expired", time.Now().Add(-time.Hour), http.StatusOK, "", func(err error) bool { return errors.Is(err, ErrSessionExpired) }},
+		{"Token rejected", time.Now().Add(time.Hour), http.StatusUnauthorized, `{"message": "Session token not found or invalid"}`,
+			func(err error) bool { return errors.Is(err, ErrSessionExpired) }},
+		{"Role not assigned", time.Now().Add(time.Hour), http.StatusForbidden, `{"message": "No access"}`,
+			func(err error) bool {
+				var apiErr *STSAPIError
+				return errors.As(err, &apiErr) && !errors.Is(err, ErrSessionExpired)
+			}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			writeSSOTestProfile(t, tc.expiresAt)
+			manager := NewAWSManager(config.NewConfig(), utils.NewLogger(false), config.DefaultFancyConfig())
+			useTestSTSServer(t, manager, func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/federation/credentials" {
+					t.Error("Expected no STS call")
+				}
+				if tc.status == http.StatusUnauthorized {
+					w.Header().Set("X-Amzn-Errortype", "UnauthorizedException:http://internal.amazon.com/coral/com.amazonaws.switchboard.portal/")
+				}
+				w.WriteHeader(tc.status)
+				w.Write([]byte(tc.body))
+			})
+
+			if _, err := manager.callerIdentity(context.Background(), "dev"); !tc.check(err) {
+				t.Errorf("Unexpected error %v", err)
+			}
+		})
+	}
+}
+
+// TestSDKSTSClientNetworkError checks that an unreachable endpoint is a
+// typed network error rather than an expired session
+func TestSDKSTSClientNetworkError(t *testing.T) {
+	writeSSOTestProfile(t, time.Now().Add(time.Hour))
+	manager := NewAWSManager(config.NewConfig(), utils.NewLogger(false), config.DefaultFancyConfig())
+	client := useTestSTSServer(t, manager, func(w http.ResponseWriter, r *http.Request) {})
+	client.loadOptions[0] = awsconfig.WithBaseEndpoint("http://127.0.0.1:1")
+
+	_, err := manager.callerIdentity(context.Background(), "dev")
+	var networkErr *STSNetworkError
+	if !errors.As(err, &networkErr) {
+		t.Fatalf("Expected an STSNetworkError, got %v", err)
+	}
+	if networkErr.Endpoint != "SSO" {
+		t.Errorf("Expected the SSO call to be named, got %s", networkErr.Endpoint)
+	}
+}
+
+// TestSDKSTSClientProfiles checks that the shared config loader resolves
+// static keys, credential_process and role chains without the aws CLI
+func TestSDKSTSClientProfiles(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(home, "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(home, "credentials"))
+	binDir := t.TempDir()
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	writeFakeHelper(t, binDir, "aws", "echo 'unexpected aws CLI call' >&2\nexit 1\n")
+	helper := writeFakeHelper(t, binDir, "get-creds",
+		`echo '{"Version": 1, "AccessKeyId": "ASIAHELPER", "SecretAccessKey": "secret", "SessionToken": "helper-session", "Expiration": "2099-01-01T00:00:00Z"}'`+"\n")
+
+	awsConfig := "[profile static]\nregion = eu-west-1\n\n" +
+		"[profile helper]\ncredential_process = " + helper + "\n\n" +
+		"[profile admin]\nrole_arn = arn:aws:iam::222222222222:role/admin\nsource_profile = static\n"
+	if err := os.WriteFile(filepath.Join(home, "config"), []byte(awsConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+	credentials := "[static]\naws_access_key_id = AKIASTATIC\naws_secret_access_key = secret\n"
+	if err := os.WriteFile(filepath.Join(home, "credentials"), []byte(credentials), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each access key belongs to its own account
+	accounts := map[string]string{"AKIASTATIC": "111111111111", "ASIAROLE": "222222222222", "ASIAHELPER": "333333333333"}
+	manager := NewAWSManager(config.NewConfig(), utils.NewLogger(false), config.DefaultFancyConfig())
+	useTestSTSServer(t, manager, func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("Action") == "AssumeRole" {
+			w.Write([]byte(`<AssumeRoleResponse><AssumeRoleResult><Credentials><AccessKeyId>ASIAROLE</AccessKeyId>` +
+				`<SecretAccessKey>secret</SecretAccessKey><SessionToken>role-session</SessionToken>` +
+				`<Expiration>2099-01-01T00:00:00Z</Expiration></Credentials></AssumeRoleResult></AssumeRoleResponse>`))
+			return
+		}
+		for key, account := range accounts {
+			if strings.Contains(r.Header.Get("Authorization"), "Credential="+key+"/") {
+				w.Write([]byte(stsIdentityXML(account)))
+				return
+			}
+		}
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(stsErrorXML("InvalidClientTokenId")))
+	})
+
+	testCases := []struct {
+		profile  string
+		expected string
+	}{
+		{"static", "111111111111"},
+		{"helper", "333333333333"},
+		{"admin", "222222222222"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.profile, func(t *testing.T) {
+			if account, err := manager.GetAccountID(context.Background(), tc.profile); err != nil || account != tc.expected {
+				t.Errorf("Expected %s, got %q, %v", tc.expected, account, err)
+			}
+		})
+	}
+}
+
+// TestSDKSTSClientFallback checks that profiles the shared config loader
+// can't load are looked up through the aws CLI
+func TestSDKSTSClientFallback(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(home, "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(home, "credentials"))
+	awsConfig := "[profile base]\nregion = eu-west-1\n\n" +
+		"[profile mfa-role]\nrole_arn = arn:aws:iam::123456789012:role/admin\nsource_profile = base\nmfa_serial = arn:aws:iam::123456789012:mfa/dev\n"
+	if err := os.WriteFile(filepath.Join(home, "config"), []byte(awsConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+	credentials := "[base]\naws_access_key_id = AKIABASE\naws_secret_access_key = secret\n"
+	if err := os.WriteFile(filepath.Join(home, "credentials"), []byte(credentials), 0600); err != nil {
+		t.Fatal(err)
+	}
+	binDir := t.TempDir()
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	writeFakeHelper(t, binDir, "aws", `echo '{"Account": "123456789012"}'`+"\n")
+
+	manager := NewAWSManager(config.NewConfig(), utils.NewLogger(false), config.DefaultFancyConfig())
+	useTestSTSServer(t, manager, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Expected no API call")
+	})
+
+	for _, profile := range []string{"mfa-role", "unknown"} {
+		if account, err := manager.GetAccountID(context.Background(), profile); err != nil || account != "123456789012" {
+			t.Errorf("Expected %s to be answered by the CLI, got %q, %v", profile, account, err)
+		}
+	}
+}
+
+func TestSTSTimeout(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(home, "config"))
+	awsConfig := `[profile sso]
+sso_start_url = https://acme.awsapps.com/start
+sso_region = eu-west-1
+
+[profile helper]
+credential_process = get-creds
+
+[profile role-over-helper]
+role_arn = arn:aws:iam::123456789012:role/admin
+source_profile = helper
+
+[profile role-over-role]
+role_arn = arn:aws:iam::123456789012:role/deploy
+source_profile = role-over-helper
+
+[profile role-over-sso]
+role_arn = arn:aws:iam::123456789012:role/admin
+source_profile = sso
+
+[profile loop]
+role_arn = arn:aws:iam::123456789012:role/admin
+source_profile = loop
+`
+	if err := os.WriteFile(filepath.Join(home, "config"), []byte(awsConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+	fc := config.DefaultFancyConfig()
+	fc.Settings.AWSTimeout, fc.Settings.AWSNetworkTimeout = 300, 10
+	manager := NewAWSManager(config.NewConfig(), utils.NewLogger(false), fc)
+
+	testCases := []struct {
+		profile  string
+		expected time.Duration
+	}{
+		{"sso", 10 * time.Second},
+		{"helper", 300 * time.Second},
+		{"role-over-helper", 300 * time.Second},
+		{"role-over-role", 300 * time.Second},
+		{"role-over-sso", 10 * time.Second},
+		{"loop", 10 * time.Second},
+		{"unknown", 10 * time.Second},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.profile, func(t *testing.T) {
+			if got := manager.stsTimeout(tc.profile); got != tc.expected {
+				t.Errorf("Expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package aws
+
+import (
+	"context"
+	"os/exec"
+
+	"fancy-login/internal/config"
+	"fancy-login/internal/utils"
+)
+
+// awsCLIArgs returns the command running `aws args...` with the credentials
+// of profile. The cli backend passes --profile; aws-vault hands the CLI the
+// credentials in its environment, where a --profile would override them.
+func awsCLIArgs(backend, profile string, args ...string) (string, []string) {
+	if backend == config.BackendAWSVault {
+		return "aws-vault", append([]string{"exec", profile, "--", "aws"}, args...)
+	}
+	return "aws", append(append([]string{}, args...), "--profile", profile)
+}
+
+// awsCommand builds the aws CLI command for profile, run through
+// aws-vault if that is the profile's backend. It sees the profile's MFA
+// session of this run, if any.
+func (aws *AWSManager) awsCommand(ctx context.Context, profile string, args ...string) *exec.Cmd {
+	name, cmdArgs := awsCLIArgs(aws.fancyConfig.ProfileBackend(profile), profile, args...)
+	cmd := utils.CommandContext(ctx, name, cmdArgs...)
+	cmd.Env = aws.commandEnv(profile)
+	return cmd
+}
+
+// usesAWSVault reports whether profile gets its credentials from aws-vault
+func (aws *AWSManager) usesAWSVault(profile string) bool {
+	return aws.fancyConfig.ProfileBackend(profile) == config.BackendAWSVault
+}
+
+// awsVaultCredentialProcess is the command printing the credentials of an
+// aws-vault profile in credential_process form, so a failed session check
+// can report aws-vault's own error
+func awsVaultCredentialProcess(profile string) string {
+	return "aws-vault exec --json " + profile
+}
+
+// vaultAlias is the shell alias exported instead of AWS_PROFILE for an
+// aws-vault profile with aws_vault_alias
+func vaultAlias(profile string) string {
+	return "aws-vault exec " + profile + " -- aws"
+}
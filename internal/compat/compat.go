@@ -0,0 +1,80 @@
+// Package compat lets users of the original shell script keep invoking
+// fancy-login as `fancy`. It translates the old command line into the new
+// flags and migrates the old mapping files once. Nothing else depends on it,
+// so it can be deleted together with its call site in cmd.
+package compat
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// LegacyName is the executable name the shell script was installed as
+const LegacyName = "fancy"
+
+// flagTranslations maps the shell script's single-letter options to the new
+// flags. The script parsed them with getopts, so they may be combined (-kv).
+var flagTranslations = map[rune][]string{
+	'k': {"-k"},
+	'v': {"-v"},
+	'f': {"--force-aws-login"},
+	'h': {"-h"},
+}
+
+// Invoked reports whether argv0 is the legacy `fancy` name, as when the
+// binary is reached through a symlink
+func Invoked(argv0 string) bool {
+	name := strings.TrimSuffix(filepath.Base(argv0), ".exe")
+	return name == LegacyName
+}
+
+// Translate converts the shell script's arguments (`fancy [-kvfh] [PROFILE]`)
+// into arguments for the new CLI
+func Translate(args []string) ([]string, error) {
+	var translated []string
+	profile := ""
+	for i, arg := range args {
+		switch {
+		case arg == "--":
+			rest := args[i+1:]
+			if len(rest) > 1 {
+				return nil, fmt.Errorf("unexpected argument %q", rest[1])
+			}
+			if len(rest) == 1 {
+				if profile != "" {
+					return nil, fmt.Errorf("unexpected argument %q", rest[0])
+				}
+				profile = rest[0]
+			}
+			return withProfile(translated, profile), nil
+		case strings.HasPrefix(arg, "-") && len(arg) > 1:
+			for _, letter := range arg[1:] {
+				flags, ok := flagTranslations[letter]
+				if !ok {
+					return nil, fmt.Errorf("unknown option -%c (the old script accepted -k, -v, -f and -h)", letter)
+				}
+				translated = append(translated, flags...)
+			}
+		case profile == "":
+			profile = arg
+		default:
+			return nil, fmt.Errorf("unexpected argument %q", arg)
+		}
+	}
+	return withProfile(translated, profile), nil
+}
+
+// withProfile appends the profile selection, if one was given
+func withProfile(args []string, profile string) []string {
+	if profile == "" {
+		return args
+	}
+	return append(args, "--profile", profile)
+}
+
+// DeprecationNotice tells the user what to run instead of `fancy`
+func DeprecationNotice(binary string, translated []string) string {
+	command := strings.TrimSpace(binary + " " + strings.Join(translated, " "))
+	return fmt.Sprintf("`%s` is deprecated and will be removed; run `%s` instead", LegacyName, command)
+}
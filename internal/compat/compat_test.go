@@ -0,0 +1,72 @@
+package compat
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestInvoked(t *testing.T) {
+	testCases := []struct {
+		argv0    string
+		expected bool
+	}{
+		{"fancy", true},
+		{"/home/me/.local/bin/fancy", true},
+		{"fancy.exe", true},
+		{"fancy-login-go", false},
+		{"/usr/local/bin/fancy-go", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.argv0, func(t *testing.T) {
+			if got := Invoked(tc.argv0); got != tc.expected {
+				t.Errorf("Expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	testCases := []struct {
+		name        string
+		args        []string
+		expected    []string
+		expectError bool
+	}{
+		{"No arguments", nil, nil, false},
+		{"Profile", []string{"OV_TEST_DEVENG"}, []string{"--profile", "OV_TEST_DEVENG"}, false},
+		{"k9s", []string{"-k"}, []string{"-k"}, false},
+		{"Force login", []string{"-f"}, []string{"--force-aws-login"}, false},
+		{"Combined options and profile", []string{"-kv", "OV_PROD_MONITORING"}, []string{"-k", "-v", "--profile", "OV_PROD_MONITORING"}, false},
+		{"Profile before options", []string{"dev", "-f"}, []string{"--force-aws-login", "--profile", "dev"}, false},
+		{"Profile after --", []string{"-k", "--", "-odd-name"}, []string{"-k", "--profile", "-odd-name"}, false},
+		{"Unknown option", []string{"-x"}, nil, true},
+		{"Long options were never supported", []string{"--verbose"}, nil, true},
+		{"Two profiles", []string{"dev", "prod"}, nil, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Translate(tc.args)
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("Expected error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if strings.Join(got, " ") != strings.Join(tc.expected, " ") {
+				t.Errorf("Expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestDeprecationNotice(t *testing.T) {
+	notice := DeprecationNotice("fancy-login-go", []string{"-k", "--profile", "dev"})
+	if !strings.Contains(notice, "`fancy-login-go -k --profile dev`") {
+		t.Errorf("Expected the notice to show the new command, got %q", notice)
+	}
+}
@@ -0,0 +1,176 @@
+package compat
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"fancy-login/internal/config"
+)
+
+// Mapping files of the shell script
+const (
+	ContextsFile   = ".fancy-contexts.conf"
+	NamespacesFile = ".fancy-namespaces.conf"
+)
+
+// migratedMarker records in the state directory that the mapping files were
+// migrated, so it happens on first use only
+const migratedMarker = "legacy-mappings-migrated"
+
+// MigrationResult lists what the migration applied
+type MigrationResult struct {
+	// Source is the directory the mapping files were read from
+	Source string
+	// Contexts maps profiles to the contexts they were given
+	Contexts map[string]string
+	// Namespaces maps profiles to the namespaces they were given
+	Namespaces map[string]string
+}
+
+// Changed reports whether any profile was updated
+func (r *MigrationResult) Changed() bool {
+	return len(r.Contexts) > 0 || len(r.Namespaces) > 0
+}
+
+// LegacyDirs returns the directories the shell script kept its mapping files
+// in: next to the installed binary, and the home directory
+func LegacyDirs(cfg *config.Config) []string {
+	homeDir, _ := os.UserHomeDir()
+	return []string{cfg.BinDir, homeDir}
+}
+
+// MigrateOnce migrates the mapping files into the fancy config of snapshot
+// unless that was already done. It returns nil when there was nothing to do.
+func MigrateOnce(snapshot *config.Snapshot, dirs []string) (*MigrationResult, error) {
+	marker := filepath.Join(config.GetStateDir(), migratedMarker)
+	if _, err := os.Stat(marker); err == nil {
+		return nil, nil
+	}
+
+	fc, err := snapshot.FancyConfig()
+	if err != nil {
+		return nil, err
+	}
+	names, err := snapshot.AWSProfileNames()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read AWS profiles: %w", err)
+	}
+
+	result, err := Migrate(snapshot, fc, names, dirs)
+	if err != nil {
+		return nil, err
+	}
+	if result.Changed() {
+		if err := fc.SaveFancyConfig(); err != nil {
+			return nil, fmt.Errorf("failed to save migrated configuration: %w", err)
+		}
+	}
+
+	if err := os.MkdirAll(config.GetStateDir(), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create state directory: %w", err)
+	}
+	if err := os.WriteFile(marker, nil, 0644); err != nil {
+		return nil, fmt.Errorf("failed to record migration: %w", err)
+	}
+	if !result.Changed() {
+		return nil, nil
+	}
+	return result, nil
+}
+
+// Migrate applies the first mapping files found in dirs to the given AWS
+// profiles. Contexts come from `.fancy-contexts.conf` wildcard patterns, the
+// first matching line winning as in the script. Profiles named
+// PROJECT_ENV_DEVENG get the namespace env-name, where name is PROJECT's
+// entry in `.fancy-namespaces.conf`. Settings already in fc are never
+// overwritten. The mapping files are read through snapshot.
+func Migrate(snapshot *config.Snapshot, fc *config.FancyConfig, profiles []string, dirs []string) (*MigrationResult, error) {
+	result := &MigrationResult{Contexts: map[string]string{}, Namespaces: map[string]string{}}
+
+	dir := findLegacyDir(dirs)
+	if dir == "" {
+		return result, nil
+	}
+	result.Source = dir
+
+	contexts, err := snapshot.LegacyMappings(filepath.Join(dir, ContextsFile))
+	if err != nil {
+		return nil, err
+	}
+	namespaces, err := snapshot.LegacyMappings(filepath.Join(dir, NamespacesFile))
+	if err != nil {
+		return nil, err
+	}
+
+	if fc.ProfileConfigs == nil {
+		fc.ProfileConfigs = make(map[string]config.ProfileConfig)
+	}
+	for _, profile := range profiles {
+		pc, exists := fc.ProfileConfigs[profile]
+		if !exists {
+			pc = config.ProfileConfig{Name: profile}
+		}
+
+		if pc.K8sContext == "" {
+			if context := matchContext(contexts, profile); context != "" {
+				pc.K8sContext = context
+				result.Contexts[profile] = context
+			}
+		}
+		if pc.Namespace == "" {
+			if namespace := deriveNamespace(namespaces, profile); namespace != "" {
+				pc.Namespace = namespace
+				result.Namespaces[profile] = namespace
+			}
+		}
+
+		if result.Contexts[profile] != "" || result.Namespaces[profile] != "" {
+			fc.ProfileConfigs[profile] = pc
+		}
+	}
+	return result, nil
+}
+
+// findLegacyDir returns the first directory holding a mapping file
+func findLegacyDir(dirs []string) string {
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+		for _, name := range []string{ContextsFile, NamespacesFile} {
+			if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+				return dir
+			}
+		}
+	}
+	return ""
+}
+
+// matchContext returns the context of the first pattern matching profile.
+// Patterns use shell wildcards (*, ?), like the script's case statement.
+func matchContext(contexts []config.LegacyMapping, profile string) string {
+	for _, m := range contexts {
+		if ok, err := path.Match(m.Key, profile); err == nil && ok {
+			return m.Value
+		}
+	}
+	return ""
+}
+
+// deriveNamespace turns PROJECT_ENV_DEVENG into env-name using the
+// project's namespace mapping
+func deriveNamespace(namespaces []config.LegacyMapping, profile string) string {
+	parts := strings.Split(profile, "_")
+	if len(parts) != 3 || parts[2] != "DEVENG" {
+		return ""
+	}
+	for _, m := range namespaces {
+		if m.Key == parts[0] {
+			return strings.ToLower(parts[1]) + "-" + m.Value
+		}
+	}
+	return ""
+}
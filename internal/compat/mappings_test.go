@@ -0,0 +1,129 @@
+package compat
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"fancy-login/internal/config"
+)
+
+const (
+	testContexts   = "# cluster per stage\n*_PROD_* = prod-cluster\n*_TEST_* = test-cluster\nOV_* = ov-cluster\n\nbroken line\n"
+	testNamespaces = "IMP=myapp-importer\nOV = myapp-overviews\n"
+)
+
+// writeLegacyFiles writes the shell script's mapping files into dir
+func writeLegacyFiles(t *testing.T, dir string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, ContextsFile), []byte(testContexts), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, NamespacesFile), []byte(testNamespaces), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	dir := t.TempDir()
+	writeLegacyFiles(t, dir)
+
+	fc := config.DefaultFancyConfig()
+	fc.ProfileConfigs["IMP_PROD_DEVENG"] = config.ProfileConfig{Name: "Importer prod", K8sContext: "custom-cluster", ECRLogin: true}
+
+	profiles := []string{"OV_TEST_DEVENG", "OV_DEV_ADMIN", "IMP_PROD_DEVENG", "XX_TEST_DEVENG", "sandbox"}
+	result, err := Migrate(config.NewSnapshot(nil), fc, profiles, []string{filepath.Join(dir, "missing"), dir})
+	if err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	if result.Source != dir {
+		t.Errorf("Expected source %s, got %s", dir, result.Source)
+	}
+
+	testCases := []struct {
+		profile           string
+		expectedContext   string
+		expectedNamespace string
+	}{
+		{"OV_TEST_DEVENG", "test-cluster", "test-myapp-overviews"},
+		{"OV_DEV_ADMIN", "ov-cluster", ""},
+		{"IMP_PROD_DEVENG", "custom-cluster", "prod-myapp-importer"},
+		{"XX_TEST_DEVENG", "test-cluster", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.profile, func(t *testing.T) {
+			pc, ok := fc.ProfileConfigs[tc.profile]
+			if !ok {
+				t.Fatalf("Expected %s to be configured", tc.profile)
+			}
+			if pc.K8sContext != tc.expectedContext {
+				t.Errorf("Expected context %q, got %q", tc.expectedContext, pc.K8sContext)
+			}
+			if pc.Namespace != tc.expectedNamespace {
+				t.Errorf("Expected namespace %q, got %q", tc.expectedNamespace, pc.Namespace)
+			}
+		})
+	}
+
+	if _, ok := fc.ProfileConfigs["sandbox"]; ok {
+		t.Error("Expected a profile without mappings to stay unconfigured")
+	}
+	if pc := fc.ProfileConfigs["IMP_PROD_DEVENG"]; pc.Name != "Importer prod" || !pc.ECRLogin {
+		t.Errorf("Expected existing settings to be kept, got %+v", pc)
+	}
+	if _, ok := result.Contexts["IMP_PROD_DEVENG"]; ok {
+		t.Error("Expected an existing context not to be reported as migrated")
+	}
+}
+
+func TestMigrateWithoutLegacyFiles(t *testing.T) {
+	fc := config.DefaultFancyConfig()
+	result, err := Migrate(config.NewSnapshot(nil), fc, []string{"OV_TEST_DEVENG"}, []string{t.TempDir()})
+	if err != nil {
+		t.Fatalf("Migrate failed: %v", err)
+	}
+	if result.Changed() || len(fc.ProfileConfigs) != 0 {
+		t.Errorf("Expected no changes, got %+v", result)
+	}
+}
+
+func TestMigrateOnce(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("AWS_CONFIG_FILE", "")
+	t.Setenv("FANCY_STATE_DIR", filepath.Join(home, ".fancy-login"))
+	if err := os.MkdirAll(filepath.Join(home, ".aws"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	awsConfig := "[profile OV_TEST_DEVENG]\nregion = eu-central-1\n"
+	if err := os.WriteFile(filepath.Join(home, ".aws", "config"), []byte(awsConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+	writeLegacyFiles(t, home)
+
+	result, err := MigrateOnce(config.NewSnapshot(nil), []string{home})
+	if err != nil {
+		t.Fatalf("MigrateOnce failed: %v", err)
+	}
+	if result == nil || result.Contexts["OV_TEST_DEVENG"] != "test-cluster" {
+		t.Fatalf("Expected the context to be migrated, got %+v", result)
+	}
+
+	saved, err := config.LoadFancyConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.ProfileConfigs["OV_TEST_DEVENG"].Namespace != "test-myapp-overviews" {
+		t.Errorf("Expected the migration to be saved, got %+v", saved.ProfileConfigs)
+	}
+
+	// The second use is a no-op even though the files are still there
+	result, err = MigrateOnce(config.NewSnapshot(nil), []string{home})
+	if err != nil {
+		t.Fatalf("MigrateOnce failed: %v", err)
+	}
+	if result != nil {
+		t.Errorf("Expected the migration to run only once, got %+v", result)
+	}
+}
@@ -0,0 +1,71 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// FormatAWSProfile renders p as a [profile NAME] section of ~/.aws/config.
+// A profile with an sso_session refers to that section; others carry the
+// start URL and SSO region themselves, as legacy SSO profiles do.
+func FormatAWSProfile(p AWSProfile) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[profile %s]\n", p.Name)
+	if p.SSOSession != "" {
+		fmt.Fprintf(&b, "sso_session = %s\n", p.SSOSession)
+	} else {
+		fmt.Fprintf(&b, "sso_start_url = %s\n", p.SSOStartURL)
+		fmt.Fprintf(&b, "sso_region = %s\n", p.SSORegion)
+	}
+	fmt.Fprintf(&b, "sso_account_id = %s\n", p.AccountID)
+	fmt.Fprintf(&b, "sso_role_name = %s\n", p.SSORole)
+	if p.Region != "" {
+		fmt.Fprintf(&b, "region = %s\n", p.Region)
+	}
+	return b.String()
+}
+
+// AppendAWSProfiles appends profiles to the AWS config at path, backing the
+// file up next to itself first. It returns the backup's path, which is
+// empty when there was no config to back up.
+func AppendAWSProfiles(path string, profiles []AWSProfile, now time.Time) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to read AWS config file %s: %w", path, err)
+	}
+
+	var backupPath string
+	if err == nil {
+		backupPath = fmt.Sprintf("%s.%s.bak", path, now.Format("20060102-150405"))
+		if err := os.WriteFile(backupPath, data, 0600); err != nil {
+			return "", fmt.Errorf("failed to write backup %s: %w", backupPath, err)
+		}
+	}
+
+	var b strings.Builder
+	if len(data) > 0 && !strings.HasSuffix(string(data), "\n") {
+		b.WriteString("\n")
+	}
+	for _, p := range profiles {
+		if len(data) > 0 || b.Len() > 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString(FormatAWSProfile(p))
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return backupPath, fmt.Errorf("failed to create AWS config directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return backupPath, fmt.Errorf("failed to open AWS config file %s: %w", path, err)
+	}
+	if _, err := f.WriteString(b.String()); err != nil {
+		f.Close()
+		return backupPath, fmt.Errorf("failed to write AWS config file %s: %w", path, err)
+	}
+	return backupPath, f.Close()
+}
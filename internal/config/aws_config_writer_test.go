@@ -0,0 +1,60 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestAppendAWSProfiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	original := "[sso-session acme]\nsso_start_url = https://acme.awsapps.com/start\nsso_region = eu-central-1\n\n[profile dev]\nsso_session = acme\nsso_account_id = 123456789012\nsso_role_name = Developer"
+	if err := os.WriteFile(path, []byte(original), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	added := []AWSProfile{
+		{Name: "acme-prod-readonly", AccountID: "210987654321", Region: "eu-west-1", SSOStartURL: "https://acme.awsapps.com/start", SSORegion: "eu-central-1", SSORole: "ReadOnly", SSOSession: "acme", IsSSO: true},
+		{Name: "legacy-admin", AccountID: "111111111111", SSOStartURL: "https://legacy.awsapps.com/start", SSORegion: "us-east-1", SSORole: "Admin", IsSSO: true},
+	}
+	now := time.Date(2024, 5, 1, 17, 42, 0, 0, time.UTC)
+	backup, err := AppendAWSProfiles(path, added, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if backup != path+".20240501-174200.bak" {
+		t.Errorf("Expected a timestamped backup, got %s", backup)
+	}
+	if data, _ := os.ReadFile(backup); string(data) != original {
+		t.Errorf("Expected the backup to hold the original config, got %q", data)
+	}
+	profiles, err := ParseAWSProfiles(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(profiles) != 3 {
+		t.Fatalf("Expected 3 profiles, got %+v", profiles)
+	}
+	for i, expected := range added {
+		if profiles[i+1] != expected {
+			t.Errorf("Expected %+v to read back, got %+v", expected, profiles[i+1])
+		}
+	}
+}
+
+func TestAppendAWSProfilesCreatesConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".aws", "config")
+	backup, err := AppendAWSProfiles(path, []AWSProfile{{Name: "dev", AccountID: "123456789012", SSOStartURL: "https://acme.awsapps.com/start", SSORegion: "eu-central-1", SSORole: "Developer"}}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if backup != "" {
+		t.Errorf("Expected no backup without a config, got %s", backup)
+	}
+	expected := "[profile dev]\nsso_start_url = https://acme.awsapps.com/start\nsso_region = eu-central-1\nsso_account_id = 123456789012\nsso_role_name = Developer\n"
+	if data, _ := os.ReadFile(path); string(data) != expected {
+		t.Errorf("Expected %q, got %q", expected, data)
+	}
+}
@@ -3,19 +3,20 @@ package config
 import (
 	"os"
 	"path/filepath"
-	"runtime"
+
+	"fancy-login/internal/platform"
 )
 
-// Colors for terminal output
+// Markers delimiting shell integration snippets written by fancy-login, so
+// they can be updated idempotently and removed on uninstall
 const (
-	Green  = "\033[0;32m"
-	Yellow = "\033[1;33m"
-	Cyan   = "\033[1;36m"
-	Red    = "\033[0;31m"
-	Reset  = "\033[0m"
-	Bold   = "\033[1m"
+	ShellInitBeginMarker = "# >>> fancy-login >>>"
+	ShellInitEndMarker   = "# <<< fancy-login <<<"
 )
 
+// DockerCredHelperName is the credHelpers value fancy-login registers in ~/.docker/config.json
+const DockerCredHelperName = "fancy-login"
+
 // Config holds all configuration for fancy-login
 type Config struct {
 	AWSProfileTemp string
@@ -33,31 +34,26 @@ type Config struct {
 func NewConfig() *Config {
 	homeDir, _ := os.UserHomeDir()
 
-	// Platform-specific paths
-	var binDir string
-	var awsProfileTemp string
-
-	if runtime.GOOS == "windows" {
-		// Windows: Use AppData\Local for binaries, temp dir for profile scripts
-		binDir = filepath.Join(homeDir, "AppData", "Local", "fancy-login")
-		awsProfileTemp = filepath.Join(os.TempDir(), "aws_profile.ps1")
-	} else {
-		// Unix-like (Linux, macOS): Use .local/bin
-		binDir = filepath.Join(homeDir, ".local", "bin")
-		awsProfileTemp = "/tmp/aws_profile.sh"
-	}
-
 	return &Config{
-		AWSProfileTemp: getEnvWithDefault("FANCY_PROFILE_TEMP", awsProfileTemp),
+		AWSProfileTemp: getEnvWithDefault("FANCY_PROFILE_TEMP", platform.ProfileScriptPath()),
 		DefaultRegion:  getEnvWithDefault("FANCY_DEFAULT_REGION", "eu-central-1"),
 		FancyVerbose:   getEnvBool("FANCY_VERBOSE"),
 		FancyDebug:     getEnvBool("FANCY_DEBUG"),
-		BinDir:         getEnvWithDefault("FANCY_BIN_DIR", binDir),
+		BinDir:         getEnvWithDefault("FANCY_BIN_DIR", platform.BinDir()),
 		AWSDir:         getEnvWithDefault("FANCY_AWS_DIR", filepath.Join(homeDir, ".aws")),
 		KubeDir:        getEnvWithDefault("FANCY_KUBE_DIR", filepath.Join(homeDir, ".kube")),
 	}
 }
 
+// GetStateDir returns the directory holding fancy-login state, history and caches
+func GetStateDir() string {
+	if dir := os.Getenv("FANCY_STATE_DIR"); dir != "" {
+		return dir
+	}
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".fancy-login")
+}
+
 // getEnvWithDefault returns environment variable value or default
 func getEnvWithDefault(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
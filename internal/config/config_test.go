@@ -9,6 +9,7 @@ import (
 )
 
 func TestNewConfig(t *testing.T) {
+	t.Setenv("FANCY_SESSION_ID", "test")
 	cfg := NewConfig()
 
 	if cfg == nil {
@@ -39,16 +40,16 @@ func TestNewConfig(t *testing.T) {
 		if cfg.BinDir != expectedBinDir {
 			t.Errorf("Windows BinDir = %v, expected %v", cfg.BinDir, expectedBinDir)
 		}
-		if !strings.HasSuffix(cfg.AWSProfileTemp, "aws_profile.ps1") {
-			t.Errorf("Windows AWSProfileTemp should end with aws_profile.ps1, got %v", cfg.AWSProfileTemp)
+		if !strings.HasSuffix(cfg.AWSProfileTemp, "aws_profile.test.ps1") {
+			t.Errorf("Windows AWSProfileTemp should end with aws_profile.test.ps1, got %v", cfg.AWSProfileTemp)
 		}
 	} else {
 		expectedBinDir := filepath.Join(homeDir, ".local", "bin")
 		if cfg.BinDir != expectedBinDir {
 			t.Errorf("Unix BinDir = %v, expected %v", cfg.BinDir, expectedBinDir)
 		}
-		if cfg.AWSProfileTemp != "/tmp/aws_profile.sh" {
-			t.Errorf("Unix AWSProfileTemp = %v, expected /tmp/aws_profile.sh", cfg.AWSProfileTemp)
+		if cfg.AWSProfileTemp != "/tmp/aws_profile.test.sh" {
+			t.Errorf("Unix AWSProfileTemp = %v, expected /tmp/aws_profile.test.sh", cfg.AWSProfileTemp)
 		}
 	}
 }
@@ -94,14 +95,20 @@ func TestNewConfigEnvironmentVariables(t *testing.T) {
 }
 
 func TestColorConstants(t *testing.T) {
-	// Test that color constants are properly defined
+	// Test that the default theme defines every role
+	if err := ApplyTheme(DefaultThemeName); err != nil {
+		t.Fatalf("ApplyTheme failed: %v", err)
+	}
+
 	colors := map[string]string{
-		"Green":  Green,
-		"Yellow": Yellow,
-		"Cyan":   Cyan,
-		"Red":    Red,
-		"Reset":  Reset,
-		"Bold":   Bold,
+		"Success": Success,
+		"Warning": Warning,
+		"Error":   Error,
+		"Accent":  Accent,
+		"Muted":   Muted,
+		"Heading": Heading,
+		"Reset":   Reset,
+		"Bold":    Bold,
 	}
 
 	for name, color := range colors {
@@ -0,0 +1,147 @@
+package config
+
+import "fmt"
+
+// FindingKind is what CrossCheck found wrong with a profile
+type FindingKind string
+
+const (
+	// FindingMissingProfile is a profile_configs entry without an AWS profile
+	FindingMissingProfile FindingKind = "missing_profile"
+	// FindingMissingContext is a k8s_context the kubeconfig doesn't have
+	FindingMissingContext FindingKind = "missing_context"
+	// FindingInvalidECRRegion is an ecr_region that isn't an AWS region
+	FindingInvalidECRRegion FindingKind = "invalid_ecr_region"
+	// FindingAccountMismatch is an account_id other than the profile's account
+	FindingAccountMismatch FindingKind = "account_mismatch"
+	// FindingAliasCollision is an alias that is also another profile's name
+	// or alias
+	FindingAliasCollision FindingKind = "alias_collision"
+)
+
+// Finding is one problem CrossCheck found. Every finding is an error: the
+// entry is stale or would make a login fail.
+type Finding struct {
+	Profile string
+	// KubeOnly is set for entries of kube_only_profiles
+	KubeOnly bool
+	Kind     FindingKind
+	Message  string
+	// Expected is the value the AWS config or profile name suggests, for
+	// account mismatches
+	Expected string
+	// Alias is the colliding alias, for alias collisions
+	Alias string
+}
+
+// CrossCheck compares profile_configs and kube_only_profiles with the AWS
+// profiles and kubeconfig contexts that actually exist. Findings are
+// ordered by profile, profile_configs first.
+func CrossCheck(fc *FancyConfig, awsProfiles []AWSProfile, contexts []KubernetesContext) []Finding {
+	profiles := make(map[string]AWSProfile, len(awsProfiles))
+	for _, p := range awsProfiles {
+		profiles[p.Name] = p
+	}
+	contextNames := make(map[string]bool, len(contexts))
+	for _, c := range contexts {
+		contextNames[c.Name] = true
+	}
+	// aliasOwners maps each alias to the first profile listing it
+	aliasOwners := make(map[string]string)
+
+	var findings []Finding
+	for _, name := range sortedKeys(fc.ProfileConfigs) {
+		pc := fc.ProfileConfigs[name]
+		awsProfile, exists := profiles[name]
+		if !exists {
+			findings = append(findings, Finding{Profile: name, Kind: FindingMissingProfile,
+				Message: fmt.Sprintf("profile %s is not in %s", name, GetAWSConfigPath())})
+		}
+		if message := missingContext(fc, name, pc.K8sContext, contextNames); message != "" {
+			findings = append(findings, Finding{Profile: name, Kind: FindingMissingContext, Message: message})
+		}
+		if pc.ECRRegion != "" {
+			if err := ValidateRegion(pc.ECRRegion); err != nil {
+				findings = append(findings, Finding{Profile: name, Kind: FindingInvalidECRRegion,
+					Message: fmt.Sprintf("ecr_region: %v", err)})
+			}
+		}
+		if expected, source := expectedAccountID(name, awsProfile); pc.AccountID != "" && expected != "" && pc.AccountID != expected {
+			findings = append(findings, Finding{Profile: name, Kind: FindingAccountMismatch, Expected: expected,
+				Message: fmt.Sprintf("account_id %s does not match %s from %s", pc.AccountID, expected, source)})
+		}
+		for _, alias := range pc.Aliases {
+			if message := aliasCollision(fc, profiles, aliasOwners, name, alias); message != "" {
+				findings = append(findings, Finding{Profile: name, Kind: FindingAliasCollision, Alias: alias, Message: message})
+				continue
+			}
+			aliasOwners[alias] = name
+		}
+	}
+
+	for _, name := range sortedKeys(fc.KubeOnlyProfiles) {
+		kp := fc.KubeOnlyProfiles[name]
+		if message := missingContext(fc, name, kp.K8sContext, contextNames); message != "" {
+			findings = append(findings, Finding{Profile: name, KubeOnly: true, Kind: FindingMissingContext, Message: message})
+		}
+	}
+	return findings
+}
+
+// missingContext describes why the k8s_context of profile is missing from
+// its kubeconfig, or returns "" if it is there. contextNames are the
+// contexts of the default kubeconfig; a profile with its own kubeconfig is
+// checked against that file.
+func missingContext(fc *FancyConfig, profile, k8sContext string, contextNames map[string]bool) string {
+	if k8sContext == "" {
+		return ""
+	}
+	path := fc.KubeconfigForProfile(profile)
+	if path == "" {
+		if contextNames[k8sContext] {
+			return ""
+		}
+		return fmt.Sprintf("k8s_context %s is not in %s", k8sContext, GetKubeConfigPath())
+	}
+	contexts, err := ParseKubernetesContexts(path)
+	if err != nil {
+		return fmt.Sprintf("k8s_context %s cannot be checked: %v", k8sContext, err)
+	}
+	for _, c := range contexts {
+		if c.Name == k8sContext {
+			return ""
+		}
+	}
+	return fmt.Sprintf("k8s_context %s is not in %s", k8sContext, path)
+}
+
+// aliasCollision describes why profile may not use alias, or returns ""
+// if the alias is free. Profile names always win over aliases, so an alias
+// naming another profile could never be used.
+func aliasCollision(fc *FancyConfig, profiles map[string]AWSProfile, aliasOwners map[string]string, profile, alias string) string {
+	if alias == profile {
+		return ""
+	}
+	_, isAWSProfile := profiles[alias]
+	_, isConfigured := fc.ProfileConfigs[alias]
+	_, isKubeOnly := fc.KubeOnlyProfiles[alias]
+	if isAWSProfile || isConfigured || isKubeOnly {
+		return fmt.Sprintf("alias %s is the name of another profile", alias)
+	}
+	if owner, taken := aliasOwners[alias]; taken {
+		return fmt.Sprintf("alias %s is also an alias of %s", alias, owner)
+	}
+	return ""
+}
+
+// expectedAccountID returns the account a profile belongs to and where that
+// came from: the AWS config's sso_account_id, else an ID in the profile name
+func expectedAccountID(name string, awsProfile AWSProfile) (id, source string) {
+	if awsProfile.AccountID != "" {
+		return awsProfile.AccountID, "the AWS config"
+	}
+	if id, err := FindAccountIDForProfile(name); err == nil {
+		return id, "the profile name"
+	}
+	return "", ""
+}
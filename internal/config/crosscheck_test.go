@@ -0,0 +1,99 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCrossCheck(t *testing.T) {
+	fc := DefaultFancyConfig()
+	fc.ProfileConfigs = map[string]ProfileConfig{
+		"dev":              {AccountID: "111111111111", ECRRegion: "eu-central-1", K8sContext: "dev-cluster"},
+		"gone":             {K8sContext: "dev-cluster"},
+		"staging":          {AccountID: "222222222222", ECRRegion: "mars", K8sContext: "old-cluster"},
+		"ops_333333333333": {AccountID: "444444444444"},
+	}
+	fc.KubeOnlyProfiles = map[string]KubeProfileConfig{
+		"oidc":   {K8sContext: "oidc-cluster"},
+		"legacy": {K8sContext: "legacy-cluster"},
+	}
+	awsProfiles := []AWSProfile{
+		{Name: "dev", AccountID: "111111111111"},
+		{Name: "staging", AccountID: "999999999999"},
+		{Name: "ops_333333333333"},
+	}
+	contexts := []KubernetesContext{{Name: "dev-cluster"}, {Name: "oidc-cluster"}}
+
+	expected := []struct {
+		profile  string
+		kubeOnly bool
+		kind     FindingKind
+		want     string
+	}{
+		{"gone", false, FindingMissingProfile, ""},
+		{"ops_333333333333", false, FindingAccountMismatch, "333333333333"},
+		{"staging", false, FindingMissingContext, ""},
+		{"staging", false, FindingInvalidECRRegion, ""},
+		{"staging", false, FindingAccountMismatch, "999999999999"},
+		{"legacy", true, FindingMissingContext, ""},
+	}
+
+	findings := CrossCheck(fc, awsProfiles, contexts)
+	if len(findings) != len(expected) {
+		t.Fatalf("Expected %d findings, got %+v", len(expected), findings)
+	}
+	for i, e := range expected {
+		f := findings[i]
+		if f.Profile != e.profile || f.KubeOnly != e.kubeOnly || f.Kind != e.kind || f.Expected != e.want {
+			t.Errorf("Finding %d: expected %s %s (kube-only %v, expected %q), got %+v", i, e.profile, e.kind, e.kubeOnly, e.want, f)
+		}
+	}
+}
+
+func TestCrossCheckProfileKubeconfig(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	prod := filepath.Join(t.TempDir(), "prod.yaml")
+	if err := os.WriteFile(prod, []byte("contexts:\n- name: prod-cluster\n  context: {cluster: prod}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	fc := DefaultFancyConfig()
+	fc.ProfileConfigs = map[string]ProfileConfig{
+		"prod":    {K8sContext: "prod-cluster", Kubeconfig: prod},
+		"staging": {K8sContext: "dev-cluster", Kubeconfig: prod},
+	}
+	awsProfiles := []AWSProfile{{Name: "prod"}, {Name: "staging"}}
+	contexts := []KubernetesContext{{Name: "dev-cluster"}}
+
+	findings := CrossCheck(fc, awsProfiles, contexts)
+	if len(findings) != 1 || findings[0].Profile != "staging" || findings[0].Kind != FindingMissingContext {
+		t.Fatalf("Expected only staging's context to be missing from its own kubeconfig, got %+v", findings)
+	}
+}
+
+func TestCrossCheckAliases(t *testing.T) {
+	fc := DefaultFancyConfig()
+	fc.ProfileConfigs = map[string]ProfileConfig{
+		"dev":     {Aliases: []string{"d", "dev"}},
+		"prod":    {Aliases: []string{"p", "d"}},
+		"staging": {Aliases: []string{"sandbox", "oidc"}},
+	}
+	fc.KubeOnlyProfiles = map[string]KubeProfileConfig{"oidc": {}}
+	awsProfiles := []AWSProfile{{Name: "dev"}, {Name: "prod"}, {Name: "staging"}, {Name: "sandbox"}}
+
+	findings := CrossCheck(fc, awsProfiles, nil)
+	expected := []struct{ profile, alias string }{
+		{"prod", "d"},
+		{"staging", "sandbox"},
+		{"staging", "oidc"},
+	}
+	if len(findings) != len(expected) {
+		t.Fatalf("Expected %d findings, got %+v", len(expected), findings)
+	}
+	for i, e := range expected {
+		f := findings[i]
+		if f.Profile != e.profile || f.Kind != FindingAliasCollision || f.Alias != e.alias {
+			t.Errorf("Finding %d: expected alias %s of %s to collide, got %+v", i, e.alias, e.profile, f)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"fancy-login/internal/prompt"
+)
+
+// ErrEKSAccessDenied is returned when a profile may not list EKS clusters
+var ErrEKSAccessDenied = errors.New("not allowed to list EKS clusters")
+
+// EKSClusters lists the EKS clusters a profile sees and creates kubeconfig
+// contexts for them. The k8s package, which imports this one, provides it
+// through SetEKSClusters; without it the wizard doesn't offer discovery.
+type EKSClusters struct {
+	List   func(ctx context.Context, timeout time.Duration, profile, region string) ([]string, error)
+	Create func(ctx context.Context, timeout time.Duration, profile string, cluster EKSClusterConfig, alias, kubeconfig string) error
+}
+
+var eksClusters *EKSClusters
+
+// SetEKSClusters makes the wizard offer to discover EKS clusters
+func SetEKSClusters(c *EKSClusters) {
+	eksClusters = c
+}
+
+// discoverEKSCluster lists the EKS clusters of profile in region and, on a
+// pick, creates a context for it and maps pc to that context. It reports
+// whether pc changed. Failures are explained to the user, who can go on
+// without a cluster, and returned.
+func (w *ConfigWizard) discoverEKSCluster(pc *ProfileConfig, profile, region string) (bool, error) {
+	if eksClusters == nil {
+		return false, nil
+	}
+	timeout := w.config.Settings.AWSNetworkTimeoutDuration()
+
+	fmt.Fprintf(w.out, "Looking for EKS clusters of %s in %s...\n", profile, region)
+	clusters, err := eksClusters.List(context.Background(), timeout, profile, region)
+	switch {
+	case errors.Is(err, ErrEKSAccessDenied):
+		fmt.Fprintf(w.out, "%s⚠️  %s may not list EKS clusters in %s (eks:ListClusters is denied); skipping%s\n", Warning, profile, region, Reset)
+		return false, err
+	case err != nil:
+		fmt.Fprintf(w.out, "%s⚠️  Could not list EKS clusters: %v; skipping%s\n", Warning, err, Reset)
+		return false, err
+	case len(clusters) == 0:
+		fmt.Fprintf(w.out, "%sNo EKS clusters in %s for %s%s\n", Muted, region, profile, Reset)
+		return false, nil
+	}
+
+	fmt.Fprintf(w.out, "Select the EKS cluster for profile %s:\n", profile)
+	for i, name := range clusters {
+		fmt.Fprintf(w.out, "  %d. %s\n", i+1, name)
+	}
+	fmt.Fprintf(w.out, "  0. Skip\n")
+	idx, err := strconv.Atoi(w.prompter.AskLine("Choice", "0"))
+	if err != nil || idx < 1 || idx > len(clusters) {
+		return false, nil
+	}
+
+	cluster := EKSClusterConfig{Name: clusters[idx-1], Region: region}
+	alias := w.prompter.AskLine("Name of the new context", cluster.Name)
+	if alias == "" {
+		alias = cluster.Name
+	}
+	kubeconfig := ""
+	if pc.Kubeconfig != "" {
+		kubeconfig = resolveConfigPath(pc.Kubeconfig)
+	}
+	if err := eksClusters.Create(context.Background(), timeout, profile, cluster, alias, kubeconfig); err != nil {
+		fmt.Fprintf(w.out, "%s⚠️  Could not create context %s: %v%s\n", Warning, alias, err, Reset)
+		return false, err
+	}
+
+	fmt.Fprintf(w.out, "%s✅ Created context %s for EKS cluster %s%s\n", Success, alias, cluster.Name, Reset)
+	pc.K8sContext = alias
+	pc.EKSCluster = cluster
+	w.k8sContexts = append(w.k8sContexts, KubernetesContext{Name: alias})
+	return true, nil
+}
+
+// eksRegion is the region to look for a profile's EKS clusters in
+func (w *ConfigWizard) eksRegion(pc *ProfileConfig, profile AWSProfile) string {
+	switch {
+	case profile.Region != "":
+		return profile.Region
+	case pc.ECRRegion != "":
+		return pc.ECRRegion
+	}
+	return w.config.Settings.DefaultRegion
+}
+
+// RunClusterDiscovery lets the user pick one of the EKS clusters of a
+// configured profile, creates its context and saves the mapping into fc
+func RunClusterDiscovery(fc *FancyConfig, profile, region string) error {
+	pc, exists := fc.ProfileConfigs[profile]
+	if !exists {
+		return fmt.Errorf("profile %s is not configured", profile)
+	}
+	wizard := NewConfigWizard()
+	wizard.config = fc
+	wizard.prompter = prompt.NewPrompter(wizard.reader, wizard.out,
+		fc.Settings.AffirmativeAnswers, fc.Settings.NegativeAnswers)
+
+	changed, err := wizard.discoverEKSCluster(&pc, profile, region)
+	if !changed {
+		return err
+	}
+	fc.ProfileConfigs[profile] = pc
+	return wizard.saveConfiguration()
+}
@@ -1,28 +1,125 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
 
 // FancyConfig represents the main configuration structure
 type FancyConfig struct {
-	ProfileConfigs map[string]ProfileConfig `yaml:"profile_configs"`
-	Settings       GlobalSettings           `yaml:"settings"`
+	ProfileConfigs   map[string]ProfileConfig     `yaml:"profile_configs"`
+	KubeOnlyProfiles map[string]KubeProfileConfig `yaml:"kube_only_profiles,omitempty"`
+	Settings         GlobalSettings               `yaml:"settings"`
+
+	// snapshot is the run's parsed configs, if loaded through one
+	snapshot *Snapshot
 }
 
 // ProfileConfig holds configuration for a specific AWS profile
 type ProfileConfig struct {
 	Name          string `yaml:"name"`
 	AccountID     string `yaml:"account_id,omitempty"`
+	AccountAlias  string `yaml:"account_alias,omitempty"`
 	ECRLogin      bool   `yaml:"ecr_login"`
 	ECRRegion     string `yaml:"ecr_region"`
 	K8sContext    string `yaml:"k8s_context"`
 	K9sAutoLaunch bool   `yaml:"k9s_auto_launch"`
 	Namespace     string `yaml:"namespace,omitempty"`
+	// Environment labels the profile in exported metrics, e.g. "dev" or "prod"
+	Environment string `yaml:"environment,omitempty"`
+	// GitUserName and GitUserEmail are offered as the committer identity of
+	// the git repository fancy-login runs in
+	GitUserName  string `yaml:"git_user_name,omitempty"`
+	GitUserEmail string `yaml:"git_user_email,omitempty"`
+	// ExportCredentials writes the profile's temporary credentials into the
+	// shell export file, for tools that can't use AWS_PROFILE
+	ExportCredentials bool `yaml:"export_credentials,omitempty"`
+	// ECRPublic also logs docker in to public.ecr.aws alongside the
+	// private registry
+	ECRPublic bool `yaml:"ecr_public,omitempty"`
+	// ECRRegistries are the private registries to log in to instead of the
+	// one of the profile's own account, e.g. a shared-services account's
+	ECRRegistries []ECRRegistryConfig `yaml:"ecr_registries,omitempty"`
+	// Aliases are short names accepted wherever the profile name is, e.g.
+	// prod; the first one is shown in the profile picker
+	Aliases []string `yaml:"aliases,omitempty"`
+	// Backend runs the profile's aws calls directly ("cli", the default)
+	// or through `aws-vault exec` ("aws-vault")
+	Backend string `yaml:"backend,omitempty"`
+	// AWSVaultAlias exports an aws alias running through `aws-vault exec`
+	// instead of AWS_PROFILE; only used with the aws-vault backend
+	AWSVaultAlias bool `yaml:"aws_vault_alias,omitempty"`
+	// Kubeconfig is a kubeconfig file used for this profile instead of the
+	// default one, e.g. to keep prod credentials out of ~/.kube/config
+	Kubeconfig string `yaml:"kubeconfig,omitempty"`
+	// EKSCluster is the EKS cluster behind K8sContext; a missing context is
+	// created from it with `aws eks update-kubeconfig`
+	EKSCluster EKSClusterConfig `yaml:"eks_cluster,omitempty"`
+	// K9sArgs are passed to k9s after -n <namespace>; {{namespace}} and
+	// {{context}} are replaced
+	K9sArgs []string `yaml:"k9s_args,omitempty"`
+	// K9sReadonly starts k9s with --readonly; nil means read-only for
+	// profiles of the prod environment
+	K9sReadonly *bool `yaml:"k9s_readonly,omitempty"`
+}
+
+// EKSClusterConfig names an EKS cluster. An empty region is the profile's.
+type EKSClusterConfig struct {
+	Name   string `yaml:"name"`
+	Region string `yaml:"region,omitempty"`
+}
+
+// Credential backends of a profile
+const (
+	BackendCLI      = "cli"
+	BackendAWSVault = "aws-vault"
+)
+
+// ECRRegistryConfig is a private ECR registry a profile logs in to. An
+// empty region stands for the profile's ECR region.
+type ECRRegistryConfig struct {
+	AccountID string `yaml:"account_id"`
+	Region    string `yaml:"region,omitempty"`
+}
+
+// KubeProfileConfig holds configuration for a cluster that authenticates
+// without AWS (e.g. via kubelogin/OIDC). Selecting one skips all AWS steps.
+type KubeProfileConfig struct {
+	Name          string `yaml:"name"`
+	K8sContext    string `yaml:"k8s_context"`
+	Namespace     string `yaml:"namespace,omitempty"`
+	K9sAutoLaunch bool   `yaml:"k9s_auto_launch"`
+	// PreLoginHook is a shell command run before switching context, e.g. to
+	// refresh the kubelogin token
+	PreLoginHook string `yaml:"pre_login_hook,omitempty"`
+	// Kubeconfig is a kubeconfig file used instead of the default one
+	Kubeconfig string `yaml:"kubeconfig,omitempty"`
+}
+
+// StringList is a YAML list of strings that may also be written as a single
+// value, e.g. `profile_sort: recent` for `profile_sort: [recent]`
+type StringList []string
+
+// UnmarshalYAML accepts a sequence or a single scalar
+func (l *StringList) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*l = StringList{node.Value}
+		return nil
+	}
+	var items []string
+	if err := node.Decode(&items); err != nil {
+		return err
+	}
+	*l = items
+	return nil
 }
 
 // GlobalSettings contains global configuration options
@@ -30,6 +127,271 @@ type GlobalSettings struct {
 	DefaultRegion      string `yaml:"default_region"`
 	ConfigWizardRun    bool   `yaml:"config_wizard_run"`
 	PreferLocalConfigs bool   `yaml:"prefer_local_configs"`
+	AWSTimeout         int    `yaml:"aws_timeout,omitempty"`         // seconds
+	AWSNetworkTimeout  int    `yaml:"aws_network_timeout,omitempty"` // seconds
+	DockerTimeout      int    `yaml:"docker_timeout,omitempty"`      // seconds
+	KubectlTimeout     int    `yaml:"kubectl_timeout,omitempty"`     // seconds
+
+	// Answers accepted by y/n prompts; empty uses the built-in multilingual defaults
+	AffirmativeAnswers []string `yaml:"affirmative_answers,omitempty"`
+	NegativeAnswers    []string `yaml:"negative_answers,omitempty"`
+
+	// Secret scanning on save: "block" (default) refuses, "warn" only warns
+	SecretScanMode string   `yaml:"secret_scan_mode,omitempty"`
+	SecretPatterns []string `yaml:"secret_patterns,omitempty"`
+
+	// InheritAWSEnv passes the parent's AWS_* profile, region and
+	// credential variables to k9s and pre-login hooks as they are; resolved
+	// values only fill in the ones the parent doesn't set
+	InheritAWSEnv bool `yaml:"inherit_aws_env,omitempty"`
+	// TmuxIntegration tags the current tmux pane with profile and namespace
+	TmuxIntegration bool `yaml:"tmux_integration,omitempty"`
+	// Theme selects the output colors: default, high-contrast, colorblind or mono
+	Theme string `yaml:"theme,omitempty"`
+	// BackgroundRefresh re-resolves the account alias after login at most
+	// once per day; nil means enabled
+	BackgroundRefresh *bool `yaml:"background_refresh,omitempty"`
+	// MetricsTextfile is a Prometheus textfile collector path written after
+	// each run; empty disables metrics
+	MetricsTextfile string `yaml:"metrics_textfile,omitempty"`
+	// SummarySinks lists where the login summary goes: "terminal",
+	// "file:PATH" (appended) and "notify"; empty means the terminal only
+	SummarySinks []string `yaml:"summary_sinks,omitempty"`
+	// SSOPortalProbe checks that the SSO start URL is reachable before
+	// opening the browser for a login; nil means enabled
+	SSOPortalProbe *bool `yaml:"sso_portal_probe,omitempty"`
+	// ProfileSort orders configured profiles in the picker by these
+	// columns, e.g. [environment, name] or just recent; empty sorts by
+	// display name
+	ProfileSort StringList `yaml:"profile_sort,omitempty"`
+	// ECRLoginMode is when the ECR login runs: "blocking" (default) before
+	// the summary, "background" after it, or "lazy" not at all
+	ECRLoginMode string `yaml:"ecr_login_mode,omitempty"`
+	// GitIdentityAuto applies a profile's git identity without asking
+	GitIdentityAuto bool `yaml:"git_identity_auto,omitempty"`
+	// ScreenReaderMode makes all output plain and append-only: no spinners,
+	// emoji or borders, spelled-out prompts and numbered pickers
+	ScreenReaderMode bool `yaml:"screen_reader_mode,omitempty"`
+	// Selector picks profiles and contexts with "fzf" or the "builtin"
+	// numbered menu; empty uses fzf when it is installed
+	Selector string `yaml:"selector,omitempty"`
+	// SelectionTimeout is how long pickers wait for a choice, e.g. "5m" or
+	// a number of seconds; "0" waits forever and empty means 60 seconds
+	SelectionTimeout string `yaml:"selection_timeout,omitempty"`
+	// SSOExpiryMargin is how much lifetime a cached SSO token needs left for
+	// the session to count as valid without asking STS; empty means 5 minutes
+	SSOExpiryMargin string `yaml:"sso_expiry_margin,omitempty"`
+	// AutoUpdateAccountIDs saves account IDs looked up via STS, and account
+	// aliases from the background refresh, into the profile config, so later
+	// runs don't need to ask; nil means disabled
+	AutoUpdateAccountIDs *bool `yaml:"auto_update_account_ids,omitempty"`
+	// ECRLoginAttempts is how often an ECR login that failed for a
+	// transient reason is tried in total; 0 means 3, 1 disables retries
+	ECRLoginAttempts int `yaml:"ecr_login_attempts,omitempty"`
+	// ECRTokenMaxAge is how old the token of an earlier ECR login may be
+	// to skip logging in again, e.g. "6h"; "0" always logs in and empty
+	// means 6 hours
+	ECRTokenMaxAge string `yaml:"ecr_token_max_age,omitempty"`
+	// SSONoBrowser makes aws sso login print the verification URL and user
+	// code instead of opening a browser, e.g. on remote machines
+	SSONoBrowser bool `yaml:"sso_no_browser,omitempty"`
+	// KubeClient reads and switches kubeconfig contexts built in ("builtin",
+	// the default) or by running kubectl ("kubectl")
+	KubeClient string `yaml:"kube_client,omitempty"`
+	// NamespaceSelection offers the cluster's namespaces in a picker after
+	// switching to a context of a profile without a namespace
+	NamespaceSelection bool `yaml:"namespace_selection,omitempty"`
+	// SetContextNamespace writes the profile's namespace into the
+	// kubeconfig context, so plain kubectl uses it too
+	SetContextNamespace bool `yaml:"set_context_namespace,omitempty"`
+}
+
+// Selectors
+const (
+	SelectorFzf     = "fzf"
+	SelectorBuiltin = "builtin"
+)
+
+// Kube clients
+const (
+	KubeClientBuiltin = "builtin"
+	KubeClientKubectl = "kubectl"
+)
+
+// ECR login modes
+const (
+	ECRLoginBlocking   = "blocking"
+	ECRLoginBackground = "background"
+	ECRLoginLazy       = "lazy"
+)
+
+// ECRLoginModeOrDefault returns the configured ECR login mode, treating
+// unset and unknown values as blocking
+func (s GlobalSettings) ECRLoginModeOrDefault() string {
+	switch s.ECRLoginMode {
+	case ECRLoginBackground, ECRLoginLazy:
+		return s.ECRLoginMode
+	}
+	return ECRLoginBlocking
+}
+
+// BackgroundRefreshEnabled reports whether the daily metadata refresh runs
+func (s GlobalSettings) BackgroundRefreshEnabled() bool {
+	return s.BackgroundRefresh == nil || *s.BackgroundRefresh
+}
+
+// SSOPortalProbeEnabled reports whether the SSO portal is probed before login
+func (s GlobalSettings) SSOPortalProbeEnabled() bool {
+	return s.SSOPortalProbe == nil || *s.SSOPortalProbe
+}
+
+// AutoUpdateAccountIDsEnabled reports whether looked-up account IDs and
+// aliases are saved to the profile config
+func (s GlobalSettings) AutoUpdateAccountIDsEnabled() bool {
+	return s.AutoUpdateAccountIDs != nil && *s.AutoUpdateAccountIDs
+}
+
+// DefaultECRLoginAttempts is how often an ECR login is tried by default
+const DefaultECRLoginAttempts = 3
+
+// ECRLoginAttemptsOrDefault returns how often an ECR login is tried
+func (s GlobalSettings) ECRLoginAttemptsOrDefault() int {
+	if s.ECRLoginAttempts > 0 {
+		return s.ECRLoginAttempts
+	}
+	return DefaultECRLoginAttempts
+}
+
+// Default timeouts in seconds for external aws, docker and kubectl commands
+const (
+	DefaultAWSTimeout        = 300
+	DefaultAWSNetworkTimeout = 30
+	DefaultDockerTimeout     = 60
+	DefaultKubectlTimeout    = 30
+)
+
+// AWSTimeoutDuration returns the timeout for aws CLI invocations that wait
+// for the user, such as aws sso login
+func (s GlobalSettings) AWSTimeoutDuration() time.Duration {
+	return secondsOrDefault(s.AWSTimeout, DefaultAWSTimeout)
+}
+
+// AWSNetworkTimeoutDuration returns the timeout for aws CLI invocations that
+// only talk to AWS, such as STS checks and ECR tokens. It is much shorter
+// than AWSTimeoutDuration, so a hung VPN fails fast.
+func (s GlobalSettings) AWSNetworkTimeoutDuration() time.Duration {
+	return secondsOrDefault(s.AWSNetworkTimeout, DefaultAWSNetworkTimeout)
+}
+
+// DockerTimeoutDuration returns the timeout for docker invocations
+func (s GlobalSettings) DockerTimeoutDuration() time.Duration {
+	return secondsOrDefault(s.DockerTimeout, DefaultDockerTimeout)
+}
+
+// KubectlTimeoutDuration returns the timeout for kubectl invocations
+func (s GlobalSettings) KubectlTimeoutDuration() time.Duration {
+	return secondsOrDefault(s.KubectlTimeout, DefaultKubectlTimeout)
+}
+
+// DefaultSelectionTimeout is how long pickers wait for a choice by default
+const DefaultSelectionTimeout = 60 * time.Second
+
+// ParseSelectionTimeout parses a selection timeout: a duration such as
+// "5m" or "90s", a plain number of seconds, or "0" for no timeout. Empty
+// means the default.
+func ParseSelectionTimeout(value string) (time.Duration, error) {
+	d, ok := parseDurationSetting(value, DefaultSelectionTimeout)
+	if !ok {
+		return 0, fmt.Errorf("%q is not a timeout (use a duration like 5m, seconds, or 0 for none)", strings.TrimSpace(value))
+	}
+	return d, nil
+}
+
+// SelectionTimeoutDuration returns how long pickers wait for a choice; 0
+// means no timeout. Invalid values fall back to the default.
+func (s GlobalSettings) SelectionTimeoutDuration() time.Duration {
+	d, err := ParseSelectionTimeout(s.SelectionTimeout)
+	if err != nil {
+		return DefaultSelectionTimeout
+	}
+	return d
+}
+
+// DefaultSSOExpiryMargin is the lifetime a cached SSO token needs left by
+// default to skip the STS check
+const DefaultSSOExpiryMargin = 5 * time.Minute
+
+// ParseSSOExpiryMargin parses an SSO expiry margin: a duration such as
+// "10m" or a plain number of seconds. Empty means the default.
+func ParseSSOExpiryMargin(value string) (time.Duration, error) {
+	d, ok := parseDurationSetting(value, DefaultSSOExpiryMargin)
+	if !ok {
+		return 0, fmt.Errorf("%q is not a margin (use a duration like 10m or seconds)", strings.TrimSpace(value))
+	}
+	return d, nil
+}
+
+// SSOExpiryMarginDuration returns the SSO expiry margin. Invalid values
+// fall back to the default.
+func (s GlobalSettings) SSOExpiryMarginDuration() time.Duration {
+	d, err := ParseSSOExpiryMargin(s.SSOExpiryMargin)
+	if err != nil {
+		return DefaultSSOExpiryMargin
+	}
+	return d
+}
+
+// DefaultECRTokenMaxAge is how old an ECR token may be by default to skip
+// the login; tokens are valid for 12 hours
+const DefaultECRTokenMaxAge = 6 * time.Hour
+
+// maxECRTokenMaxAge is the lifetime of an ECR token
+const maxECRTokenMaxAge = 12 * time.Hour
+
+// ParseECRTokenMaxAge parses an ECR token max age: a duration such as "6h"
+// or a plain number of seconds, up to the 12 hour token lifetime. "0"
+// disables reusing tokens and empty means the default.
+func ParseECRTokenMaxAge(value string) (time.Duration, error) {
+	d, ok := parseDurationSetting(value, DefaultECRTokenMaxAge)
+	if !ok || d >= maxECRTokenMaxAge {
+		return 0, fmt.Errorf("%q is not a token age (use a duration under 12h like 6h, seconds, or 0 to always log in)", strings.TrimSpace(value))
+	}
+	return d, nil
+}
+
+// ECRTokenMaxAgeDuration returns how old an ECR token may be to skip the
+// login; 0 means always log in. Invalid values fall back to the default.
+func (s GlobalSettings) ECRTokenMaxAgeDuration() time.Duration {
+	d, err := ParseECRTokenMaxAge(s.ECRTokenMaxAge)
+	if err != nil {
+		return DefaultECRTokenMaxAge
+	}
+	return d
+}
+
+// parseDurationSetting parses a duration such as "5m" or a plain number of
+// seconds, with empty meaning def. ok is false for invalid or negative values.
+func parseDurationSetting(value string, def time.Duration) (d time.Duration, ok bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return def, true
+	}
+	if _, err := strconv.Atoi(value); err == nil {
+		value += "s"
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, false
+	}
+	return d, true
+}
+
+// secondsOrDefault converts a configured number of seconds to a duration
+func secondsOrDefault(seconds, def int) time.Duration {
+	if seconds <= 0 {
+		seconds = def
+	}
+	return time.Duration(seconds) * time.Second
 }
 
 // DefaultFancyConfig returns a default configuration
@@ -46,14 +408,18 @@ func DefaultFancyConfig() *FancyConfig {
 
 // LoadFancyConfig loads the fancy configuration from file
 func LoadFancyConfig() (*FancyConfig, error) {
+	return loadFancyConfig(os.ReadFile)
+}
+
+// loadFancyConfig loads the fancy configuration from a file read with read
+func loadFancyConfig(read FileReader) (*FancyConfig, error) {
 	configPath := GetFancyConfigPath()
 
+	data, err := read(configPath)
 	// If config doesn't exist, return default config
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
+	if errors.Is(err, fs.ErrNotExist) {
 		return DefaultFancyConfig(), nil
 	}
-
-	data, err := os.ReadFile(configPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read config file %s: %w", configPath, err)
 	}
@@ -75,6 +441,10 @@ func LoadFancyConfig() (*FancyConfig, error) {
 func (fc *FancyConfig) SaveFancyConfig() error {
 	configPath := GetFancyConfigPath()
 
+	if err := fc.checkSecretsBeforeSave(); err != nil {
+		return err
+	}
+
 	// Ensure directory exists
 	if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
 		return fmt.Errorf("failed to create config directory: %w", err)
@@ -92,6 +462,22 @@ func (fc *FancyConfig) SaveFancyConfig() error {
 	return nil
 }
 
+// BackupFancyConfig copies the config file next to itself, named after the
+// time of the backup, and returns the backup's path
+func BackupFancyConfig(now time.Time) (string, error) {
+	configPath := GetFancyConfigPath()
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read config file %s: %w", configPath, err)
+	}
+
+	backupPath := fmt.Sprintf("%s.%s.bak", configPath, now.Format("20060102-150405"))
+	if err := os.WriteFile(backupPath, data, 0600); err != nil {
+		return "", fmt.Errorf("failed to write backup %s: %w", backupPath, err)
+	}
+	return backupPath, nil
+}
+
 // GetFancyConfigPath returns the path to the fancy config file
 func GetFancyConfigPath() string {
 	// Check for local config first (for development)
@@ -106,14 +492,59 @@ func GetFancyConfigPath() string {
 	return filepath.Join(homeDir, ".fancy-config.yaml")
 }
 
-// GetProfileConfig returns the profile config for a given AWS profile
+// GetProfileConfig returns the profile config for a given AWS profile. A
+// kube-only profile is returned as a ProfileConfig without AWS settings so the
+// Kubernetes and k9s handling can treat both alike.
 func (fc *FancyConfig) GetProfileConfig(profile string) (*ProfileConfig, error) {
 	if config, exists := fc.ProfileConfigs[profile]; exists {
 		return &config, nil
 	}
+	if kube, exists := fc.KubeOnlyProfiles[profile]; exists {
+		return &ProfileConfig{
+			Name:          kube.Name,
+			K8sContext:    kube.K8sContext,
+			K9sAutoLaunch: kube.K9sAutoLaunch,
+			Namespace:     kube.Namespace,
+			Kubeconfig:    kube.Kubeconfig,
+		}, nil
+	}
 	return nil, fmt.Errorf("no configuration found for profile: %s", profile)
 }
 
+// ResolveProfileName returns the profile a name given on the command line
+// stands for: the name itself if a profile of that name is configured,
+// else the profile listing it among its aliases. Other names are returned
+// unchanged.
+func (fc *FancyConfig) ResolveProfileName(name string) string {
+	if name == "" {
+		return name
+	}
+	if _, exists := fc.ProfileConfigs[name]; exists {
+		return name
+	}
+	if _, exists := fc.KubeOnlyProfiles[name]; exists {
+		return name
+	}
+	for _, profile := range sortedKeys(fc.ProfileConfigs) {
+		for _, alias := range fc.ProfileConfigs[profile].Aliases {
+			if alias == name {
+				return profile
+			}
+		}
+	}
+	return name
+}
+
+// IsKubeOnlyProfile reports whether a profile is a kube-only profile. AWS
+// profiles of the same name take precedence.
+func (fc *FancyConfig) IsKubeOnlyProfile(profile string) bool {
+	if _, exists := fc.ProfileConfigs[profile]; exists {
+		return false
+	}
+	_, exists := fc.KubeOnlyProfiles[profile]
+	return exists
+}
+
 // ShouldPerformECRLogin determines if ECR login should be performed for a profile
 func (fc *FancyConfig) ShouldPerformECRLogin(profile string) bool {
 	config, err := fc.GetProfileConfig(profile)
@@ -123,6 +554,36 @@ func (fc *FancyConfig) ShouldPerformECRLogin(profile string) bool {
 	return config.ECRLogin
 }
 
+// ShouldExportCredentials determines if the shell export of a profile
+// includes its temporary credentials
+func (fc *FancyConfig) ShouldExportCredentials(profile string) bool {
+	config, err := fc.GetProfileConfig(profile)
+	if err != nil {
+		return false
+	}
+	return config.ExportCredentials
+}
+
+// ProfileBackend returns the credential backend of a profile, BackendCLI
+// unless it is configured otherwise
+func (fc *FancyConfig) ProfileBackend(profile string) string {
+	config, err := fc.GetProfileConfig(profile)
+	if err != nil || config.Backend == "" {
+		return BackendCLI
+	}
+	return config.Backend
+}
+
+// ShouldExportVaultAlias determines if the shell export of a profile is an
+// aws-vault alias instead of AWS_PROFILE
+func (fc *FancyConfig) ShouldExportVaultAlias(profile string) bool {
+	config, err := fc.GetProfileConfig(profile)
+	if err != nil {
+		return false
+	}
+	return config.Backend == BackendAWSVault && config.AWSVaultAlias
+}
+
 // ShouldAutoLaunchK9s determines if K9s should be auto-launched for a profile
 func (fc *FancyConfig) ShouldAutoLaunchK9s(profile string) bool {
 	config, err := fc.GetProfileConfig(profile)
@@ -132,6 +593,19 @@ func (fc *FancyConfig) ShouldAutoLaunchK9s(profile string) bool {
 	return config.K9sAutoLaunch
 }
 
+// ShouldK9sReadonly determines if k9s is started read-only for a profile:
+// as its k9s_readonly says, else if its environment is prod
+func (fc *FancyConfig) ShouldK9sReadonly(profile string) bool {
+	config, err := fc.GetProfileConfig(profile)
+	if err != nil {
+		return false
+	}
+	if config.K9sReadonly != nil {
+		return *config.K9sReadonly
+	}
+	return strings.EqualFold(config.Environment, "prod")
+}
+
 // GetK8sContextForProfile returns the Kubernetes context for a profile
 func (fc *FancyConfig) GetK8sContextForProfile(profile string) string {
 	config, err := fc.GetProfileConfig(profile)
@@ -141,14 +615,71 @@ func (fc *FancyConfig) GetK8sContextForProfile(profile string) string {
 	return config.K8sContext
 }
 
-// GetECRRegionForProfile returns the ECR region for a profile
-func (fc *FancyConfig) GetECRRegionForProfile(profile string) string {
+// KubeconfigForProfile returns the kubeconfig file of a profile, or "" if
+// it uses the default one. A leading ~ stands for the home directory and a
+// relative path is relative to the directory of the fancy config.
+func (fc *FancyConfig) KubeconfigForProfile(profile string) string {
 	config, err := fc.GetProfileConfig(profile)
-	if err != nil {
-		return fc.Settings.DefaultRegion
+	if err != nil || config.Kubeconfig == "" {
+		return ""
+	}
+	return resolveConfigPath(config.Kubeconfig)
+}
+
+// resolveConfigPath makes a path from the fancy config absolute
+func resolveConfigPath(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") || strings.HasPrefix(path, "~"+string(filepath.Separator)) {
+		homeDir, _ := os.UserHomeDir()
+		return filepath.Join(homeDir, path[1:])
+	}
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path)
+	}
+	return filepath.Join(filepath.Dir(GetFancyConfigPath()), path)
+}
+
+// RegionSource tells where an ECR region came from
+type RegionSource string
+
+const (
+	RegionFromFlag    RegionSource = "flag"
+	RegionFromConfig  RegionSource = "config"
+	RegionFromProfile RegionSource = "profile"
+	RegionFromEnv     RegionSource = "env"
+	RegionFromDefault RegionSource = "default"
+)
+
+// GetECRRegionForProfile returns the ECR region of an AWS profile and where
+// it came from: its ecr_region, else the region it sets in the AWS config,
+// else AWS_REGION, else the default region
+func (fc *FancyConfig) GetECRRegionForProfile(profile AWSProfile) (string, RegionSource) {
+	if config, err := fc.GetProfileConfig(profile.Name); err == nil && config.ECRRegion != "" {
+		return config.ECRRegion, RegionFromConfig
+	}
+	if profile.Region != "" {
+		return profile.Region, RegionFromProfile
+	}
+	if region := os.Getenv("AWS_REGION"); region != "" {
+		return region, RegionFromEnv
+	}
+	return fc.Settings.DefaultRegion, RegionFromDefault
+}
+
+// RegionForProfile returns the region set for a profile in the AWS config,
+// falling back to its ECR region
+func (fc *FancyConfig) RegionForProfile(profile string) string {
+	awsProfile := AWSProfile{Name: profile}
+	if profiles, err := fc.awsProfiles(); err == nil {
+		for _, p := range profiles {
+			if p.Name == profile {
+				awsProfile = p
+				break
+			}
+		}
 	}
-	if config.ECRRegion == "" {
-		return fc.Settings.DefaultRegion
+	if awsProfile.Region != "" {
+		return awsProfile.Region
 	}
-	return config.ECRRegion
+	region, _ := fc.GetECRRegionForProfile(awsProfile)
+	return region
 }
@@ -0,0 +1,266 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestKubeOnlyProfiles(t *testing.T) {
+	fc := DefaultFancyConfig()
+	fc.ProfileConfigs["shared"] = ProfileConfig{Name: "shared", K8sContext: "aws-cluster"}
+	fc.KubeOnlyProfiles = map[string]KubeProfileConfig{
+		"oidc":   {Name: "OIDC Cluster", K8sContext: "oidc-cluster", Namespace: "apps", K9sAutoLaunch: true},
+		"shared": {K8sContext: "shadowed"},
+	}
+
+	testCases := []struct {
+		name             string
+		profile          string
+		expectedKubeOnly bool
+		expectedContext  string
+		expectedK9s      bool
+	}{
+		{"Kube-only profile", "oidc", true, "oidc-cluster", true},
+		{"AWS profile wins on name clash", "shared", false, "aws-cluster", false},
+		{"Unknown profile", "missing", false, "", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := fc.IsKubeOnlyProfile(tc.profile); got != tc.expectedKubeOnly {
+				t.Errorf("Expected IsKubeOnlyProfile=%v, got %v", tc.expectedKubeOnly, got)
+			}
+			if got := fc.GetK8sContextForProfile(tc.profile); got != tc.expectedContext {
+				t.Errorf("Expected context %q, got %q", tc.expectedContext, got)
+			}
+			if got := fc.ShouldAutoLaunchK9s(tc.profile); got != tc.expectedK9s {
+				t.Errorf("Expected k9s auto-launch=%v, got %v", tc.expectedK9s, got)
+			}
+			if fc.ShouldPerformECRLogin(tc.profile) && tc.expectedKubeOnly {
+				t.Error("Kube-only profiles must never perform ECR login")
+			}
+		})
+	}
+}
+
+func TestResolveProfileName(t *testing.T) {
+	fc := DefaultFancyConfig()
+	fc.ProfileConfigs = map[string]ProfileConfig{
+		"production-123456789012": {Aliases: []string{"prod", "p"}},
+		"prod":                    {},
+	}
+	fc.KubeOnlyProfiles = map[string]KubeProfileConfig{"oidc": {}}
+
+	testCases := []struct {
+		name     string
+		expected string
+	}{
+		{"p", "production-123456789012"},
+		{"prod", "prod"},
+		{"oidc", "oidc"},
+		{"unconfigured", "unconfigured"},
+		{"", ""},
+	}
+	for _, tc := range testCases {
+		if got := fc.ResolveProfileName(tc.name); got != tc.expected {
+			t.Errorf("ResolveProfileName(%q) = %q, expected %q", tc.name, got, tc.expected)
+		}
+	}
+}
+
+func TestGetECRRegionForProfile(t *testing.T) {
+	fc := DefaultFancyConfig()
+	fc.ProfileConfigs = map[string]ProfileConfig{
+		"pinned": {ECRRegion: "us-east-1"},
+		"plain":  {},
+	}
+
+	testCases := []struct {
+		name     string
+		profile  AWSProfile
+		env      string
+		expected string
+	}{
+		{"ecr_region wins", AWSProfile{Name: "pinned", Region: "eu-west-1"}, "ap-south-1", "us-east-1"},
+		{"Profile region", AWSProfile{Name: "plain", Region: "eu-west-1"}, "ap-south-1", "eu-west-1"},
+		{"AWS_REGION", AWSProfile{Name: "plain"}, "ap-south-1", "ap-south-1"},
+		{"Default region", AWSProfile{Name: "plain"}, "", fc.Settings.DefaultRegion},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("AWS_REGION", tc.env)
+			if got, _ := fc.GetECRRegionForProfile(tc.profile); got != tc.expected {
+				t.Errorf("Expected %s, got %s", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestSSOExpiryMarginDuration(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected time.Duration
+	}{
+		{"", DefaultSSOExpiryMargin},
+		{"10m", 10 * time.Minute},
+		{"30", 30 * time.Second},
+		{"soon", DefaultSSOExpiryMargin},
+	}
+
+	for _, tc := range testCases {
+		if d := (GlobalSettings{SSOExpiryMargin: tc.value}).SSOExpiryMarginDuration(); d != tc.expected {
+			t.Errorf("SSOExpiryMarginDuration(%q) = %s, expected %s", tc.value, d, tc.expected)
+		}
+	}
+	if _, err := ParseSSOExpiryMargin("-1m"); err == nil {
+		t.Error("Expected a negative margin to be rejected")
+	}
+}
+
+func TestECRTokenMaxAgeDuration(t *testing.T) {
+	testCases := []struct {
+		value    string
+		expected time.Duration
+	}{
+		{"", DefaultECRTokenMaxAge},
+		{"2h", 2 * time.Hour},
+		{"0", 0},
+		{"12h", DefaultECRTokenMaxAge},
+		{"forever", DefaultECRTokenMaxAge},
+	}
+
+	for _, tc := range testCases {
+		if d := (GlobalSettings{ECRTokenMaxAge: tc.value}).ECRTokenMaxAgeDuration(); d != tc.expected {
+			t.Errorf("ECRTokenMaxAgeDuration(%q) = %s, expected %s", tc.value, d, tc.expected)
+		}
+	}
+	if _, err := ParseECRTokenMaxAge("13h"); err == nil {
+		t.Error("Expected an age beyond the token lifetime to be rejected")
+	}
+}
+
+func TestParseSelectionTimeout(t *testing.T) {
+	testCases := []struct {
+		value     string
+		expected  time.Duration
+		expectErr bool
+	}{
+		{"", DefaultSelectionTimeout, false},
+		{"0", 0, false},
+		{"5m", 5 * time.Minute, false},
+		{"90s", 90 * time.Second, false},
+		{"120", 2 * time.Minute, false},
+		{"soon", 0, true},
+		{"-1m", 0, true},
+		{"-5", 0, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.value, func(t *testing.T) {
+			d, err := ParseSelectionTimeout(tc.value)
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("ParseSelectionTimeout(%q) error = %v, expectErr %v", tc.value, err, tc.expectErr)
+			}
+			if d != tc.expected {
+				t.Errorf("Expected %s, got %s", tc.expected, d)
+			}
+		})
+	}
+
+	if d := (GlobalSettings{SelectionTimeout: "soon"}).SelectionTimeoutDuration(); d != DefaultSelectionTimeout {
+		t.Errorf("Expected an invalid setting to fall back to %s, got %s", DefaultSelectionTimeout, d)
+	}
+}
+
+func TestStringListUnmarshal(t *testing.T) {
+	testCases := []struct {
+		name     string
+		yaml     string
+		expected StringList
+	}{
+		{"Single value", "profile_sort: recent", StringList{"recent"}},
+		{"List", "profile_sort: [recent, alpha]", StringList{"recent", "alpha"}},
+		{"Unset", "sso_portal_probe: true", nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var settings GlobalSettings
+			if err := yaml.Unmarshal([]byte(tc.yaml), &settings); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(settings.ProfileSort, tc.expected) {
+				t.Errorf("Expected %v, got %v", tc.expected, settings.ProfileSort)
+			}
+		})
+	}
+}
+
+func TestKubeconfigForProfile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	fc := DefaultFancyConfig()
+	fc.ProfileConfigs = map[string]ProfileConfig{
+		"tilde":    {Kubeconfig: "~/.kube/prod.yaml"},
+		"relative": {Kubeconfig: "kube/prod.yaml"},
+		"absolute": {Kubeconfig: "/etc/kube/../kube/prod.yaml"},
+		"default":  {},
+	}
+	fc.KubeOnlyProfiles = map[string]KubeProfileConfig{"oidc": {Kubeconfig: "~/oidc.yaml"}}
+
+	testCases := []struct {
+		profile  string
+		expected string
+	}{
+		{"tilde", filepath.Join(home, ".kube", "prod.yaml")},
+		{"relative", filepath.Join(home, "kube", "prod.yaml")},
+		{"absolute", "/etc/kube/prod.yaml"},
+		{"default", ""},
+		{"oidc", filepath.Join(home, "oidc.yaml")},
+		{"missing", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.profile, func(t *testing.T) {
+			if got := fc.KubeconfigForProfile(tc.profile); got != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestShouldK9sReadonly(t *testing.T) {
+	on, off := true, false
+	fc := DefaultFancyConfig()
+	fc.ProfileConfigs = map[string]ProfileConfig{
+		"prod":          {Environment: "prod"},
+		"prod-writable": {Environment: "prod", K9sReadonly: &off},
+		"dev":           {Environment: "dev"},
+		"dev-readonly":  {Environment: "dev", K9sReadonly: &on},
+	}
+	fc.KubeOnlyProfiles = map[string]KubeProfileConfig{"oidc": {}}
+
+	testCases := []struct {
+		profile  string
+		expected bool
+	}{
+		{"prod", true},
+		{"prod-writable", false},
+		{"dev", false},
+		{"dev-readonly", true},
+		{"oidc", false},
+		{"missing", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.profile, func(t *testing.T) {
+			if got := fc.ShouldK9sReadonly(tc.profile); got != tc.expected {
+				t.Errorf("Expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
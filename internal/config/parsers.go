@@ -2,7 +2,10 @@ package config
 
 import (
 	"bufio"
+	"bytes"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -19,7 +22,44 @@ type AWSProfile struct {
 	SSOStartURL string
 	SSORegion   string
 	SSORole     string
-	IsSSO       bool
+	// SSOSession names the [sso-session] section the profile logs in
+	// with; SSOStartURL and SSORegion are resolved from it when set there
+	SSOSession string
+	IsSSO      bool
+	// CredentialProcess is the external helper command that supplies the
+	// profile's credentials, if any
+	CredentialProcess string
+	// RoleARN and SourceProfile make the profile assume a role with the
+	// credentials of another profile
+	RoleARN       string
+	SourceProfile string
+	// MFASerial is the MFA device whose code the profile's sessions need
+	MFASerial string
+	// StaticCredentials is set when the shared credentials file holds an
+	// access key for the profile
+	StaticCredentials bool
+}
+
+// IsRoleChain reports whether the profile assumes its role with another
+// profile's credentials. A profile naming itself as source_profile holds
+// its own static credentials, which is no chain to follow.
+func (p AWSProfile) IsRoleChain() bool {
+	return p.RoleARN != "" && p.SourceProfile != "" && p.SourceProfile != p.Name
+}
+
+// Type describes how the profile gets its credentials
+func (p AWSProfile) Type() string {
+	switch {
+	case p.IsRoleChain():
+		return "assume role"
+	case p.IsSSO:
+		return "SSO"
+	case p.CredentialProcess != "":
+		return "external process"
+	case p.StaticCredentials:
+		return "static credentials"
+	}
+	return "Standard"
 }
 
 // KubernetesContext represents a Kubernetes context from ~/.kube/config
@@ -51,25 +91,107 @@ type KubeConfig struct {
 	} `yaml:"clusters"`
 }
 
-// ParseAWSProfiles parses AWS profiles from ~/.aws/config
+// ssoSession is an [sso-session NAME] section of ~/.aws/config
+type ssoSession struct {
+	startURL string
+	region   string
+}
+
+// ParseAWSProfiles parses AWS profiles from ~/.aws/config. Profiles that
+// reference an [sso-session] section get its start URL and region.
 func ParseAWSProfiles(awsConfigPath string) ([]AWSProfile, error) {
+	return parseAWSProfiles(os.ReadFile, awsConfigPath)
+}
+
+// LoadAWSProfiles returns the profiles of the AWS config together with
+// those that only exist in the shared credentials file
+func LoadAWSProfiles() ([]AWSProfile, error) {
+	return loadAWSProfiles(os.ReadFile)
+}
+
+// loadAWSProfiles reads the AWS config and the shared credentials file
+// with read. A missing AWS config is only an error if the credentials file
+// has no profiles either.
+func loadAWSProfiles(read FileReader) ([]AWSProfile, error) {
+	profiles, err := parseAWSProfiles(read, GetAWSConfigPath())
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	data, credErr := read(GetAWSCredentialsPath())
+	if credErr != nil {
+		return profiles, err
+	}
+	names := parseAWSCredentialsProfiles(data)
+	if len(names) == 0 {
+		return profiles, err
+	}
+
+	index := make(map[string]int, len(profiles))
+	for i, p := range profiles {
+		index[p.Name] = i
+	}
+	for _, name := range names {
+		if i, ok := index[name]; ok {
+			profiles[i].StaticCredentials = true
+			continue
+		}
+		profiles = append(profiles, AWSProfile{Name: name, StaticCredentials: true})
+	}
+	return profiles, nil
+}
+
+// parseAWSCredentialsProfiles returns the names of the [NAME] sections of
+// a shared credentials file that hold an access key, in file order
+func parseAWSCredentialsProfiles(data []byte) []string {
+	sectionRegex := regexp.MustCompile(`^\[\s*(.+?)\s*\]$`)
+
+	var names []string
+	current, hasKey := "", false
+	flush := func() {
+		if current != "" && hasKey {
+			names = append(names, current)
+		}
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if matches := sectionRegex.FindStringSubmatch(line); matches != nil {
+			flush()
+			current, hasKey = matches[1], false
+			continue
+		}
+		if key, _, ok := strings.Cut(line, "="); ok && strings.TrimSpace(key) == "aws_access_key_id" {
+			hasKey = true
+		}
+	}
+	flush()
+	return names
+}
+
+// parseAWSProfiles parses AWS profiles from a file read with read
+func parseAWSProfiles(read FileReader, awsConfigPath string) ([]AWSProfile, error) {
 	if awsConfigPath == "" {
-		homeDir, _ := os.UserHomeDir()
-		awsConfigPath = filepath.Join(homeDir, ".aws", "config")
+		awsConfigPath = GetAWSConfigPath()
 	}
 
-	file, err := os.Open(awsConfigPath)
+	data, err := read(awsConfigPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open AWS config file %s: %w", awsConfigPath, err)
 	}
-	defer file.Close()
 
 	var profiles []AWSProfile
 	var currentProfile *AWSProfile
+	var currentSession *ssoSession
+	sessions := make(map[string]*ssoSession)
 	profileRegex := regexp.MustCompile(`^\[profile\s+(.+)\]$`)
 	defaultRegex := regexp.MustCompile(`^\[default\]$`)
+	sessionRegex := regexp.MustCompile(`^\[sso-session\s+(.+)\]$`)
+	sectionRegex := regexp.MustCompile(`^\[.*\]$`)
 
-	scanner := bufio.NewScanner(file)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
 	for scanner.Scan() {
 		line := strings.TrimSpace(scanner.Text())
 
@@ -78,25 +200,35 @@ func ParseAWSProfiles(awsConfigPath string) ([]AWSProfile, error) {
 			continue
 		}
 
-		// Check for profile section
-		if matches := profileRegex.FindStringSubmatch(line); matches != nil {
-			// Save previous profile if exists
+		// Any section header ends the previous profile
+		if sectionRegex.MatchString(line) {
 			if currentProfile != nil {
 				profiles = append(profiles, *currentProfile)
 			}
-			// Start new profile
+			currentProfile, currentSession = nil, nil
+		}
+
+		if matches := profileRegex.FindStringSubmatch(line); matches != nil {
 			currentProfile = &AWSProfile{
 				Name: matches[1],
 			}
 		} else if defaultRegex.MatchString(line) {
-			// Save previous profile if exists
-			if currentProfile != nil {
-				profiles = append(profiles, *currentProfile)
-			}
-			// Start default profile
 			currentProfile = &AWSProfile{
 				Name: "default",
 			}
+		} else if matches := sessionRegex.FindStringSubmatch(line); matches != nil {
+			currentSession = &ssoSession{}
+			sessions[strings.TrimSpace(matches[1])] = currentSession
+		} else if currentSession != nil {
+			parts := strings.SplitN(line, "=", 2)
+			if len(parts) == 2 {
+				switch strings.TrimSpace(parts[0]) {
+				case "sso_start_url":
+					currentSession.startURL = strings.TrimSpace(parts[1])
+				case "sso_region":
+					currentSession.region = strings.TrimSpace(parts[1])
+				}
+			}
 		} else if currentProfile != nil {
 			// Parse profile properties
 			parts := strings.SplitN(line, "=", 2)
@@ -117,6 +249,17 @@ func ParseAWSProfiles(awsConfigPath string) ([]AWSProfile, error) {
 					currentProfile.SSORegion = value
 				case "sso_role_name":
 					currentProfile.SSORole = value
+				case "sso_session":
+					currentProfile.SSOSession = value
+					currentProfile.IsSSO = true
+				case "credential_process":
+					currentProfile.CredentialProcess = value
+				case "role_arn":
+					currentProfile.RoleARN = value
+				case "source_profile":
+					currentProfile.SourceProfile = value
+				case "mfa_serial":
+					currentProfile.MFASerial = value
 				}
 			}
 		}
@@ -127,37 +270,153 @@ func ParseAWSProfiles(awsConfigPath string) ([]AWSProfile, error) {
 		profiles = append(profiles, *currentProfile)
 	}
 
+	for i := range profiles {
+		session, ok := sessions[profiles[i].SSOSession]
+		if profiles[i].SSOSession == "" || !ok {
+			continue
+		}
+		if profiles[i].SSOStartURL == "" {
+			profiles[i].SSOStartURL = session.startURL
+		}
+		if profiles[i].SSORegion == "" {
+			profiles[i].SSORegion = session.region
+		}
+	}
+
 	return profiles, scanner.Err()
 }
 
-// ParseKubernetesContexts parses Kubernetes contexts from ~/.kube/config
+// ParseKubernetesContexts parses Kubernetes contexts from ~/.kube/config,
+// or from every file of a KUBECONFIG list
 func ParseKubernetesContexts(kubeConfigPath string) ([]KubernetesContext, error) {
-	if kubeConfigPath == "" {
-		homeDir, _ := os.UserHomeDir()
-		kubeConfigPath = filepath.Join(homeDir, ".kube", "config")
+	return parseKubernetesContexts(os.ReadFile, kubeConfigPath)
+}
+
+// parseKubernetesContexts parses Kubernetes contexts from a file, or a
+// list of files, read with read. Like kubectl it merges the files of a
+// list, the first definition of a context winning, and skips files that
+// don't exist as long as one does.
+func parseKubernetesContexts(read FileReader, kubeConfigPath string) ([]KubernetesContext, error) {
+	configs, err := readKubeConfigs(read, kubeConfigPath)
+	if err != nil {
+		return nil, err
 	}
 
-	data, err := os.ReadFile(kubeConfigPath)
+	var contexts []KubernetesContext
+	seen := make(map[string]bool)
+	for _, kubeConfig := range configs {
+		for _, ctx := range kubeConfig.Contexts {
+			if seen[ctx.Name] {
+				continue
+			}
+			seen[ctx.Name] = true
+			contexts = append(contexts, KubernetesContext{
+				Name:      ctx.Name,
+				Cluster:   ctx.Context.Cluster,
+				User:      ctx.Context.User,
+				Namespace: ctx.Context.Namespace,
+			})
+		}
+	}
+
+	return contexts, nil
+}
+
+// ReadCurrentContext reads current-context directly from a kubeconfig file,
+// which is much cheaper than forking kubectl. With a KUBECONFIG list the
+// first file that sets it wins, as in kubectl.
+func ReadCurrentContext(kubeConfigPath string) (string, error) {
+	configs, err := readKubeConfigs(os.ReadFile, kubeConfigPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read Kubernetes config file %s: %w", kubeConfigPath, err)
+		return "", err
+	}
+	for _, kubeConfig := range configs {
+		if kubeConfig.CurrentContext != "" {
+			return kubeConfig.CurrentContext, nil
+		}
 	}
+	return "", nil
+}
 
-	var kubeConfig KubeConfig
-	if err := yaml.Unmarshal(data, &kubeConfig); err != nil {
-		return nil, fmt.Errorf("failed to parse Kubernetes config file %s: %w", kubeConfigPath, err)
+// readKubeConfigs reads and parses each file of kubeConfigPath, which
+// defaults to GetKubeConfigPath and may be a list. Missing files are
+// skipped unless none of them exists.
+func readKubeConfigs(read FileReader, kubeConfigPath string) ([]KubeConfig, error) {
+	if kubeConfigPath == "" {
+		kubeConfigPath = GetKubeConfigPath()
 	}
 
-	var contexts []KubernetesContext
-	for _, ctx := range kubeConfig.Contexts {
-		contexts = append(contexts, KubernetesContext{
-			Name:      ctx.Name,
-			Cluster:   ctx.Context.Cluster,
-			User:      ctx.Context.User,
-			Namespace: ctx.Context.Namespace,
-		})
+	var configs []KubeConfig
+	var firstErr error
+	for _, path := range filepath.SplitList(kubeConfigPath) {
+		if path == "" {
+			continue
+		}
+		data, err := read(path)
+		if err != nil {
+			err = fmt.Errorf("failed to read Kubernetes config file %s: %w", path, err)
+			if !errors.Is(err, fs.ErrNotExist) {
+				return nil, err
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+
+		var kubeConfig KubeConfig
+		if err := yaml.Unmarshal(data, &kubeConfig); err != nil {
+			return nil, fmt.Errorf("failed to parse Kubernetes config file %s: %w", path, err)
+		}
+		configs = append(configs, kubeConfig)
+	}
+	if len(configs) == 0 {
+		if firstErr == nil {
+			firstErr = fmt.Errorf("no Kubernetes config file in %q", kubeConfigPath)
+		}
+		return nil, firstErr
 	}
+	return configs, nil
+}
 
-	return contexts, nil
+// SplitCommand splits a command line, such as a credential_process value,
+// into arguments. Like the AWS CLI it uses POSIX shell quoting but runs no
+// shell.
+func SplitCommand(command string) ([]string, error) {
+	var args []string
+	var current strings.Builder
+	inArg := false
+	var quote rune
+
+	for _, r := range command {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, errors.New("unterminated quote")
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
 }
 
 // FindAccountIDForProfile attempts to find the AWS account ID for a profile
@@ -192,7 +451,17 @@ func GetAWSConfigPath() string {
 	return filepath.Join(homeDir, ".aws", "config")
 }
 
-// GetKubeConfigPath returns the path to Kubernetes config file
+// GetAWSCredentialsPath returns the path to the AWS shared credentials file
+func GetAWSCredentialsPath() string {
+	if path := os.Getenv("AWS_SHARED_CREDENTIALS_FILE"); path != "" {
+		return path
+	}
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".aws", "credentials")
+}
+
+// GetKubeConfigPath returns the path to Kubernetes config file. From
+// KUBECONFIG it may be a list of files, split with filepath.SplitList.
 func GetKubeConfigPath() string {
 	if path := os.Getenv("KUBECONFIG"); path != "" {
 		return path
@@ -200,3 +469,43 @@ func GetKubeConfigPath() string {
 	homeDir, _ := os.UserHomeDir()
 	return filepath.Join(homeDir, ".kube", "config")
 }
+
+// LegacyMapping is one `key = value` line of the shell script's mapping
+// files, .fancy-contexts.conf and .fancy-namespaces.conf
+type LegacyMapping struct {
+	Key   string
+	Value string
+}
+
+// parseLegacyMappings reads `key = value` lines with read, skipping blanks
+// and comments. A missing file has no mappings.
+func parseLegacyMappings(read FileReader, path string) ([]LegacyMapping, error) {
+	data, err := read(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var mappings []LegacyMapping
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if key != "" && value != "" {
+			mappings = append(mappings, LegacyMapping{Key: key, Value: value})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return mappings, nil
+}
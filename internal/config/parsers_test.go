@@ -0,0 +1,249 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseAWSProfilesTypes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	content := `[default]
+region = eu-central-1
+
+[profile sso-dev]
+sso_start_url = https://acme.awsapps.com/start
+sso_account_id = 123456789012
+
+[profile vendor]
+credential_process = /opt/vendor/bin/helper --profile "acme prod"
+region = us-east-1
+
+[profile deploy]
+role_arn = arn:aws:iam::123456789012:role/Deploy
+source_profile = sso-dev
+
+[profile static]
+role_arn = arn:aws:iam::123456789012:role/Static
+source_profile = static
+mfa_serial = arn:aws:iam::123456789012:mfa/me
+`
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	profiles, err := ParseAWSProfiles(path)
+	if err != nil {
+		t.Fatalf("ParseAWSProfiles failed: %v", err)
+	}
+
+	testCases := []struct {
+		name              string
+		expectedType      string
+		credentialProcess string
+	}{
+		{"default", "Standard", ""},
+		{"sso-dev", "SSO", ""},
+		{"vendor", "external process", `/opt/vendor/bin/helper --profile "acme prod"`},
+		{"deploy", "assume role", ""},
+		{"static", "Standard", ""},
+	}
+
+	if len(profiles) != len(testCases) {
+		t.Fatalf("Expected %d profiles, got %d", len(testCases), len(profiles))
+	}
+	for i, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := profiles[i]
+			if p.Name != tc.name {
+				t.Fatalf("Expected profile %s, got %s", tc.name, p.Name)
+			}
+			if p.Type() != tc.expectedType {
+				t.Errorf("Expected type %q, got %q", tc.expectedType, p.Type())
+			}
+			if p.CredentialProcess != tc.credentialProcess {
+				t.Errorf("Expected credential_process %q, got %q", tc.credentialProcess, p.CredentialProcess)
+			}
+		})
+	}
+	if serial := profiles[len(profiles)-1].MFASerial; serial != "arn:aws:iam::123456789012:mfa/me" {
+		t.Errorf("Expected the mfa_serial of static, got %q", serial)
+	}
+}
+
+func TestParseAWSProfilesSSOSessions(t *testing.T) {
+	testCases := []struct {
+		fixture  string
+		profiles []AWSProfile
+	}{
+		{
+			fixture: "aws_config_mixed",
+			profiles: []AWSProfile{
+				{Name: "default", Region: "eu-central-1"},
+				{Name: "legacy", AccountID: "111111111111", SSOStartURL: "https://legacy.awsapps.com/start", SSORegion: "us-east-1", SSORole: "Admin", IsSSO: true},
+				{Name: "dev", AccountID: "123456789012", Region: "eu-central-1", SSOStartURL: "https://acme.awsapps.com/start", SSORegion: "eu-west-1", SSORole: "Developer", SSOSession: "acme", IsSSO: true},
+				// A region set on the profile wins over the session's
+				{Name: "prod", AccountID: "210987654321", SSOStartURL: "https://acme.awsapps.com/start", SSORegion: "eu-central-1", SSORole: "ReadOnly", SSOSession: "acme", IsSSO: true},
+				{Name: "orphan", AccountID: "333333333333", SSOSession: "missing", IsSSO: true},
+				{Name: "static", Region: "us-west-2"},
+			},
+		},
+		{
+			// The session may follow the profiles that use it
+			fixture: "aws_config_sso_session",
+			profiles: []AWSProfile{
+				{Name: "dev", AccountID: "123456789012", SSOStartURL: "https://my-sso.awsapps.com/start", SSORegion: "eu-central-1", SSORole: "Developer", SSOSession: "my-sso", IsSSO: true},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.fixture, func(t *testing.T) {
+			profiles, err := ParseAWSProfiles(filepath.Join("testdata", tc.fixture))
+			if err != nil {
+				t.Fatalf("ParseAWSProfiles failed: %v", err)
+			}
+			if len(profiles) != len(tc.profiles) {
+				t.Fatalf("Expected %d profiles, got %d: %+v", len(tc.profiles), len(profiles), profiles)
+			}
+			for i, expected := range tc.profiles {
+				if profiles[i] != expected {
+					t.Errorf("Expected %+v, got %+v", expected, profiles[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLoadAWSProfilesWithCredentials(t *testing.T) {
+	dir := t.TempDir()
+	configPath := filepath.Join(dir, "config")
+	credentialsPath := filepath.Join(dir, "credentials")
+	t.Setenv("AWS_CONFIG_FILE", configPath)
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", credentialsPath)
+
+	credentials := "[static]\naws_access_key_id = AKIAEXAMPLE\naws_secret_access_key = secret\n\n" +
+		"# keys for the build server\n[ci]\naws_access_key_id = AKIACI\naws_secret_access_key = secret\n\n" +
+		"[empty]\nregion = eu-west-1\n"
+	if err := os.WriteFile(credentialsPath, []byte(credentials), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without an AWS config the credentials file's profiles are all there is
+	profiles, err := LoadAWSProfiles()
+	if err != nil {
+		t.Fatalf("LoadAWSProfiles failed: %v", err)
+	}
+	if len(profiles) != 2 || profiles[0] != (AWSProfile{Name: "static", StaticCredentials: true}) || profiles[1].Name != "ci" {
+		t.Errorf("Expected static and ci from the credentials file, got %+v", profiles)
+	}
+
+	config := "[profile dev]\nsso_start_url = https://acme.awsapps.com/start\n\n[profile static]\nregion = us-west-2\n"
+	if err := os.WriteFile(configPath, []byte(config), 0600); err != nil {
+		t.Fatal(err)
+	}
+	profiles, err = LoadAWSProfiles()
+	if err != nil {
+		t.Fatalf("LoadAWSProfiles failed: %v", err)
+	}
+	expected := []AWSProfile{
+		{Name: "dev", SSOStartURL: "https://acme.awsapps.com/start", IsSSO: true},
+		{Name: "static", Region: "us-west-2", StaticCredentials: true},
+		{Name: "ci", StaticCredentials: true},
+	}
+	if len(profiles) != len(expected) {
+		t.Fatalf("Expected %d profiles, got %+v", len(expected), profiles)
+	}
+	for i := range expected {
+		if profiles[i] != expected[i] {
+			t.Errorf("Profile %d: expected %+v, got %+v", i, expected[i], profiles[i])
+		}
+	}
+	if kind := profiles[2].Type(); kind != "static credentials" {
+		t.Errorf("Expected ci to have static credentials, got %s", kind)
+	}
+}
+
+func TestParseKubernetesContextsList(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "config")
+	second := filepath.Join(dir, "work-config")
+	if err := os.WriteFile(first, []byte(`contexts:
+- name: dev-cluster
+  context: {cluster: dev, namespace: apps}
+`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(second, []byte(`current-context: prod-cluster
+contexts:
+- name: prod-cluster
+  context: {cluster: prod}
+- name: dev-cluster
+  context: {cluster: shadowed, namespace: other}
+`), 0600); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(dir, "missing")
+	t.Setenv("KUBECONFIG", strings.Join([]string{first, missing, second}, string(os.PathListSeparator)))
+
+	contexts, err := ParseKubernetesContexts(GetKubeConfigPath())
+	if err != nil {
+		t.Fatalf("ParseKubernetesContexts failed: %v", err)
+	}
+	expected := []KubernetesContext{
+		{Name: "dev-cluster", Cluster: "dev", Namespace: "apps"},
+		{Name: "prod-cluster", Cluster: "prod"},
+	}
+	if !reflect.DeepEqual(contexts, expected) {
+		t.Errorf("Expected %+v, got %+v", expected, contexts)
+	}
+
+	if current, err := ReadCurrentContext(""); err != nil || current != "prod-cluster" {
+		t.Errorf("Expected the second file's current-context prod-cluster, got %q, %v", current, err)
+	}
+
+	snapshot := NewSnapshot(nil)
+	if contexts, err := snapshot.KubeContexts(); err != nil || len(contexts) != 2 {
+		t.Errorf("Expected the snapshot to merge both files, got %+v, %v", contexts, err)
+	}
+
+	t.Setenv("KUBECONFIG", missing)
+	if _, err := ParseKubernetesContexts(GetKubeConfigPath()); err == nil || !strings.Contains(err.Error(), missing) {
+		t.Errorf("Expected an error naming %s, got %v", missing, err)
+	}
+}
+
+func TestSplitCommand(t *testing.T) {
+	testCases := []struct {
+		name        string
+		command     string
+		expected    []string
+		expectError bool
+	}{
+		{"Plain", "/opt/vendor/bin/helper --account 1234", []string{"/opt/vendor/bin/helper", "--account", "1234"}, false},
+		{"Double quotes", `"/Applications/Vendor Tool/helper" get`, []string{"/Applications/Vendor Tool/helper", "get"}, false},
+		{"Single quotes and tabs", "helper\t'--role=a b'  x", []string{"helper", "--role=a b", "x"}, false},
+		{"Empty quoted argument", `helper ""`, []string{"helper", ""}, false},
+		{"Unterminated quote", `helper "oops`, nil, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			args, err := SplitCommand(tc.command)
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("Expected error, got %q", args)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if strings.Join(args, "|") != strings.Join(tc.expected, "|") || len(args) != len(tc.expected) {
+				t.Errorf("Expected %q, got %q", tc.expected, args)
+			}
+		})
+	}
+}
@@ -0,0 +1,944 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FieldType is the value type of a configuration field
+type FieldType string
+
+const (
+	FieldString FieldType = "string"
+	FieldBool   FieldType = "boolean"
+	FieldInt    FieldType = "integer"
+	FieldList   FieldType = "array"
+	FieldObject FieldType = "object"
+)
+
+// SchemaField describes a single ProfileConfig field. The wizard, `config
+// schema` and field validation are all driven from this description, so a
+// new field only needs an entry here plus its business logic.
+type SchemaField struct {
+	Key         string    `json:"key"`
+	Type        FieldType `json:"type"`
+	Default     string    `json:"default,omitempty"`
+	Description string    `json:"description"`
+	Since       string    `json:"since,omitempty"`
+
+	// Prompt is asked generically by the wizard; fields that need custom
+	// interaction (e.g. choosing from discovered contexts) leave it empty.
+	Prompt string `json:"-"`
+	// When restricts the wizard prompt to profiles where it returns true
+	When func(pc *ProfileConfig) bool `json:"-"`
+	// Validate checks a non-empty value; nil means any value of Type is fine
+	Validate func(value string) error `json:"-"`
+	// Items describes the keys of the objects in a list field, or of an
+	// object field; nil for a list of strings
+	Items []SchemaField `json:"items,omitempty"`
+}
+
+var regionRegex = regexp.MustCompile(`^[a-z]{2}(-gov|-iso[a-z]?)?-[a-z]+-\d+$`)
+var accountIDRegex = regexp.MustCompile(`^\d{12}$`)
+
+// ProfileSchema is the declarative schema for ProfileConfig
+var ProfileSchema = []SchemaField{
+	{
+		Key:         "name",
+		Type:        FieldString,
+		Description: "Display name shown in the profile picker",
+		Since:       "1.0.0",
+	},
+	{
+		Key:         "account_id",
+		Type:        FieldString,
+		Description: "12-digit AWS account ID",
+		Since:       "1.0.0",
+		Validate:    validateAccountID,
+	},
+	{
+		Key:         "account_alias",
+		Type:        FieldString,
+		Description: "IAM account alias, refreshed automatically after login",
+		Since:       "1.1.0",
+	},
+	{
+		Key:         "ecr_login",
+		Type:        FieldBool,
+		Default:     "true",
+		Description: "Log in to ECR after AWS authentication",
+		Since:       "1.0.0",
+		Prompt:      "Enable ECR login for profile %s?",
+	},
+	{
+		Key:         "ecr_region",
+		Type:        FieldString,
+		Description: "Region of the ECR registry",
+		Since:       "1.0.0",
+		Validate:    ValidateRegion,
+	},
+	{
+		Key:         "k8s_context",
+		Type:        FieldString,
+		Description: "Kubernetes context to switch to",
+		Since:       "1.0.0",
+	},
+	{
+		Key:         "k9s_auto_launch",
+		Type:        FieldBool,
+		Default:     "false",
+		Description: "Offer to launch k9s after login",
+		Since:       "1.0.0",
+		Prompt:      "Auto-launch K9s for profile %s?",
+		When:        func(pc *ProfileConfig) bool { return pc.K8sContext != "" },
+	},
+	{
+		Key:         "namespace",
+		Type:        FieldString,
+		Description: "Kubernetes namespace used for k9s",
+		Since:       "1.0.0",
+	},
+	{
+		Key:         "environment",
+		Type:        FieldString,
+		Description: "Environment label for exported metrics, e.g. dev or prod",
+		Since:       "1.1.0",
+	},
+	{
+		Key:         "git_user_name",
+		Type:        FieldString,
+		Description: "git user.name offered for the repository you log in from",
+		Since:       "1.1.0",
+	},
+	{
+		Key:         "git_user_email",
+		Type:        FieldString,
+		Description: "git user.email offered for the repository you log in from",
+		Since:       "1.1.0",
+		Validate:    validateEmail,
+	},
+	{
+		Key:         "export_credentials",
+		Type:        FieldBool,
+		Default:     "false",
+		Description: "Export temporary credentials to the shell along with AWS_PROFILE",
+		Since:       "1.1.0",
+	},
+	{
+		Key:         "ecr_public",
+		Type:        FieldBool,
+		Default:     "false",
+		Description: "Also log in to the ECR Public registry public.ecr.aws",
+		Since:       "1.1.0",
+	},
+	{
+		Key:         "ecr_registries",
+		Type:        FieldList,
+		Description: "Private ECR registries to log in to instead of the profile account's",
+		Since:       "1.1.0",
+		Validate:    validateECRRegistries,
+		Items: []SchemaField{
+			{Key: "account_id", Type: FieldString, Description: "12-digit AWS account ID of the registry"},
+			{Key: "region", Type: FieldString, Description: "Region of the registry; defaults to the profile's ECR region"},
+		},
+	},
+	{
+		Key:         "aliases",
+		Type:        FieldList,
+		Description: "Short names accepted wherever the profile name is; the first is shown in the picker",
+		Since:       "1.1.0",
+		Validate:    validateAliases,
+	},
+	{
+		Key:         "backend",
+		Type:        FieldString,
+		Default:     BackendCLI,
+		Description: "How aws calls get credentials: cli, or aws-vault to run them through aws-vault exec",
+		Since:       "1.1.0",
+		Validate:    validateBackend,
+	},
+	{
+		Key:         "aws_vault_alias",
+		Type:        FieldBool,
+		Default:     "false",
+		Description: "Export an aws alias running through aws-vault exec instead of AWS_PROFILE",
+		Since:       "1.1.0",
+	},
+	{
+		Key:         "kubeconfig",
+		Type:        FieldString,
+		Description: "Kubeconfig file used instead of the default one; ~ and paths relative to the fancy config work",
+		Since:       "1.1.0",
+	},
+	{
+		Key:         "eks_cluster",
+		Type:        FieldObject,
+		Description: "EKS cluster to create a missing k8s_context from with aws eks update-kubeconfig",
+		Since:       "1.1.0",
+		Validate:    validateEKSCluster,
+		Items: []SchemaField{
+			{Key: "name", Type: FieldString, Description: "Name of the EKS cluster"},
+			{Key: "region", Type: FieldString, Description: "Region of the cluster; defaults to the profile's region"},
+		},
+	},
+	{
+		Key:         "k9s_args",
+		Type:        FieldList,
+		Description: "Extra k9s arguments after -n <namespace>; {{namespace}} and {{context}} are replaced",
+		Since:       "1.1.0",
+	},
+	{
+		Key:         "k9s_readonly",
+		Type:        FieldBool,
+		Description: "Start k9s with --readonly; unset means read-only for environment prod",
+		Since:       "1.1.0",
+	},
+}
+
+// KubeProfileSchema is the declarative schema for KubeProfileConfig
+var KubeProfileSchema = []SchemaField{
+	{
+		Key:         "name",
+		Type:        FieldString,
+		Description: "Display name shown in the profile picker",
+		Since:       "1.1.0",
+	},
+	{
+		Key:         "k8s_context",
+		Type:        FieldString,
+		Description: "Kubernetes context to switch to",
+		Since:       "1.1.0",
+	},
+	{
+		Key:         "namespace",
+		Type:        FieldString,
+		Description: "Kubernetes namespace used for k9s",
+		Since:       "1.1.0",
+	},
+	{
+		Key:         "k9s_auto_launch",
+		Type:        FieldBool,
+		Default:     "false",
+		Description: "Offer to launch k9s after switching context",
+		Since:       "1.1.0",
+	},
+	{
+		Key:         "pre_login_hook",
+		Type:        FieldString,
+		Description: "Shell command run before switching context, e.g. kubelogin",
+		Since:       "1.1.0",
+	},
+	{
+		Key:         "kubeconfig",
+		Type:        FieldString,
+		Description: "Kubeconfig file used instead of the default one; ~ and paths relative to the fancy config work",
+		Since:       "1.1.0",
+	},
+}
+
+// SettingsSchema is the declarative schema for GlobalSettings
+var SettingsSchema = []SchemaField{
+	{
+		Key:         "default_region",
+		Type:        FieldString,
+		Default:     "eu-central-1",
+		Description: "Region used when a profile has no ECR region",
+		Since:       "1.0.0",
+		Validate:    ValidateRegion,
+	},
+	{
+		Key:         "config_wizard_run",
+		Type:        FieldBool,
+		Default:     "false",
+		Description: "Set once configuration exists so the wizard doesn't start automatically",
+		Since:       "1.0.0",
+	},
+	{
+		Key:         "prefer_local_configs",
+		Type:        FieldBool,
+		Default:     "true",
+		Description: "Prefer ./.fancy-config.yaml over the one in your home directory",
+		Since:       "1.0.0",
+	},
+	{
+		Key:         "aws_timeout",
+		Type:        FieldInt,
+		Default:     "300",
+		Description: "Seconds before aws CLI calls that wait for you, such as aws sso login, are cancelled",
+		Since:       "1.1.0",
+	},
+	{
+		Key:         "aws_network_timeout",
+		Type:        FieldInt,
+		Default:     "30",
+		Description: "Seconds before aws CLI calls that only talk to AWS, such as STS checks, are cancelled",
+		Since:       "1.1.0",
+	},
+	{
+		Key:         "docker_timeout",
+		Type:        FieldInt,
+		Default:     "60",
+		Description: "Seconds before docker login is cancelled",
+		Since:       "1.1.0",
+	},
+	{
+		Key:         "kubectl_timeout",
+		Type:        FieldInt,
+		Default:     "30",
+		Description: "Seconds before kubectl calls are cancelled",
+		Since:       "1.1.0",
+	},
+	{
+		Key:         "affirmative_answers",
+		Type:        FieldList,
+		Description: "Answers accepted as yes (empty uses y/yes/j/ja/o/oui/s/si)",
+		Since:       "1.1.0",
+	},
+	{
+		Key:         "negative_answers",
+		Type:        FieldList,
+		Description: "Answers accepted as no (empty uses n/no/nein/non)",
+		Since:       "1.1.0",
+	},
+	{
+		Key:         "secret_scan_mode",
+		Type:        FieldString,
+		Default:     "block",
+		Description: "block refuses to save suspected secrets, warn only warns",
+		Since:       "1.1.0",
+	},
+	{
+		Key:         "secret_patterns",
+		Type:        FieldList,
+		Description: "Extra regular expressions treated as secrets",
+		Since:       "1.1.0",
+	},
+	{
+		Key:         "inherit_aws_env",
+		Type:        FieldBool,
+		Default:     "false",
+		Description: "Pass inherited AWS_PROFILE/AWS_REGION and credentials to k9s and hooks instead of the resolved values",
+		Since:       "1.1.0",
+	},
+	{
+		Key:         "tmux_integration",
+		Type:        FieldBool,
+		Default:     "false",
+		Description: "Tag the tmux pane with profile and namespace for `fancy-login tmux-status`",
+		Since:       "1.1.0",
+	},
+	{
+		Key:         "theme",
+		Type:        FieldString,
+		Default:     "default",
+		Description: "Output colors: default, high-contrast, colorblind or mono",
+		Since:       "1.1.0",
+		Validate:    validateTheme,
+	},
+	{
+		Key:         "background_refresh",
+		Type:        FieldBool,
+		Default:     "true",
+		Description: "Re-resolve the account alias after login at most once per day and save it when auto_update_account_ids is on",
+		Since:       "1.1.0",
+	},
+	{
+		Key:         "metrics_textfile",
+		Type:        FieldString,
+		Description: "Path of a Prometheus .prom file written after each run (node-exporter textfile collector)",
+		Since:       "1.1.0",
+	},
+	{
+		Key:         "summary_sinks",
+		Type:        FieldList,
+		Default:     "[terminal]",
+		Description: "Where the login summary goes: terminal, file:PATH (appended, {date} expands) or notify (OSC 9)",
+		Since:       "1.1.0",
+	},
+	{
+		Key:         "sso_portal_probe",
+		Type:        FieldBool,
+		Default:     "true",
+		Description: "Check that the SSO portal is reachable (e.g. on the VPN) before opening the browser",
+		Since:       "1.1.0",
+	},
+	{
+		Key:         "profile_sort",
+		Type:        FieldList,
+		Default:     "[name]",
+		Description: "Picker sort columns, a list or a single one: name (alias alpha), profile, account_id, account_alias, environment, region, expiry, recent, frequent",
+		Since:       "1.1.0",
+	},
+	{
+		Key:         "ecr_login_mode",
+		Type:        FieldString,
+		Default:     "blocking",
+		Description: "blocking logs in to ECR before the summary, background after it, lazy leaves it to a docker credential helper",
+		Since:       "1.1.0",
+		Validate:    validateECRLoginMode,
+	},
+	{
+		Key:         "git_identity_auto",
+		Type:        FieldBool,
+		Default:     "false",
+		Description: "Apply a profile's git identity to the current repository without asking",
+		Since:       "1.1.0",
+	},
+	{
+		Key:         "screen_reader_mode",
+		Type:        FieldBool,
+		Default:     "false",
+		Description: "Plain, append-only output for screen readers: no spinners, emoji or borders, numbered pickers instead of fzf (also ACCESSIBLE=1)",
+		Since:       "1.1.0",
+	},
+	{
+		Key:         "selector",
+		Type:        FieldString,
+		Description: "Picker for profiles and contexts: fzf or builtin (a numbered menu); unset uses fzf when installed",
+		Since:       "1.1.0",
+		Validate:    validateSelector,
+	},
+	{
+		Key:         "kube_client",
+		Type:        FieldString,
+		Default:     KubeClientBuiltin,
+		Description: "How kubeconfig contexts are listed and switched: builtin, or kubectl for setups only kubectl understands",
+		Since:       "1.1.0",
+		Validate:    validateKubeClient,
+	},
+	{
+		Key:         "namespace_selection",
+		Type:        FieldBool,
+		Default:     "false",
+		Description: "Pick a namespace from the cluster after switching to the context of a profile without one",
+		Since:       "1.1.0",
+	},
+	{
+		Key:         "set_context_namespace",
+		Type:        FieldBool,
+		Default:     "false",
+		Description: "Write the profile's namespace into the kubeconfig context, so plain kubectl uses it",
+		Since:       "1.1.0",
+	},
+	{
+		Key:         "selection_timeout",
+		Type:        FieldString,
+		Default:     "60s",
+		Description: "How long profile and context pickers wait for a choice, e.g. 5m; 0 waits forever",
+		Since:       "1.1.0",
+		Validate:    validateSelectionTimeout,
+	},
+	{
+		Key:         "sso_expiry_margin",
+		Type:        FieldString,
+		Default:     "5m",
+		Description: "Lifetime a cached SSO token needs left to skip the STS session check, e.g. 10m",
+		Since:       "1.1.0",
+		Validate:    validateSSOExpiryMargin,
+	},
+	{
+		Key:         "auto_update_account_ids",
+		Type:        FieldBool,
+		Default:     "false",
+		Description: "Save account IDs looked up via STS, and account aliases, to the profile config and use them instead of asking again",
+		Since:       "1.1.0",
+	},
+	{
+		Key:         "ecr_login_attempts",
+		Type:        FieldInt,
+		Default:     "3",
+		Description: "Tries of an ECR login failing for transient reasons such as timeouts or 5xx errors; 1 disables retries",
+		Since:       "1.1.0",
+	},
+	{
+		Key:         "ecr_token_max_age",
+		Type:        FieldString,
+		Default:     "6h",
+		Description: "Skip the ECR login while docker holds a token from an earlier one younger than this; 0 always logs in",
+		Since:       "1.1.0",
+		Validate:    validateECRTokenMaxAge,
+	},
+	{
+		Key:         "sso_no_browser",
+		Type:        FieldBool,
+		Default:     "false",
+		Description: "Print the SSO verification URL and user code instead of opening a browser, e.g. over SSH",
+		Since:       "1.1.0",
+	},
+}
+
+// validateECRLoginMode checks that a value names an ECR login mode
+func validateECRLoginMode(value string) error {
+	switch value {
+	case ECRLoginBlocking, ECRLoginBackground, ECRLoginLazy:
+		return nil
+	}
+	return fmt.Errorf("%q is not an ECR login mode (use blocking, background or lazy)", value)
+}
+
+// validateSelector checks that a value names a selector
+func validateSelector(value string) error {
+	switch value {
+	case SelectorFzf, SelectorBuiltin:
+		return nil
+	}
+	return fmt.Errorf("%q is not a selector (use fzf or builtin)", value)
+}
+
+// validateKubeClient checks that a value names a kube client
+func validateKubeClient(value string) error {
+	switch value {
+	case KubeClientBuiltin, KubeClientKubectl:
+		return nil
+	}
+	return fmt.Errorf("%q is not a kube client (use builtin or kubectl)", value)
+}
+
+// validateSelectionTimeout checks that a value parses as a selection timeout
+func validateSelectionTimeout(value string) error {
+	_, err := ParseSelectionTimeout(value)
+	return err
+}
+
+// validateSSOExpiryMargin checks that a value parses as an SSO expiry margin
+func validateSSOExpiryMargin(value string) error {
+	_, err := ParseSSOExpiryMargin(value)
+	return err
+}
+
+// validateECRTokenMaxAge checks that a value parses as an ECR token max age
+func validateECRTokenMaxAge(value string) error {
+	_, err := ParseECRTokenMaxAge(value)
+	return err
+}
+
+// SchemaFieldByKey returns the schema entry for a YAML key
+func SchemaFieldByKey(key string) (*SchemaField, error) {
+	for i := range ProfileSchema {
+		if ProfileSchema[i].Key == key {
+			return &ProfileSchema[i], nil
+		}
+	}
+	return nil, fmt.Errorf("unknown profile config key: %s", key)
+}
+
+// SettingsFieldByKey returns the settings schema entry for a YAML key
+func SettingsFieldByKey(key string) (*SchemaField, error) {
+	for i := range SettingsSchema {
+		if SettingsSchema[i].Key == key {
+			return &SettingsSchema[i], nil
+		}
+	}
+	return nil, fmt.Errorf("unknown setting: %s", key)
+}
+
+// CheckValue validates a raw string value against the field's type and rules
+func (f *SchemaField) CheckValue(value string) error {
+	if f.Type == FieldBool {
+		// A bool without a default may stay unset
+		if value == "" && f.Default == "" {
+			return nil
+		}
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("%s must be true or false, got %q", f.Key, value)
+		}
+		return nil
+	}
+	if f.Type == FieldInt && value != "" {
+		if _, err := strconv.Atoi(value); err != nil {
+			return fmt.Errorf("%s must be a whole number, got %q", f.Key, value)
+		}
+	}
+	if value != "" && f.Validate != nil {
+		if err := f.Validate(value); err != nil {
+			return fmt.Errorf("%s: %w", f.Key, err)
+		}
+	}
+	return nil
+}
+
+// GetProfileField returns the string form of a ProfileConfig field
+func GetProfileField(pc *ProfileConfig, key string) (string, error) {
+	field, err := profileStructField(pc, key)
+	if err != nil {
+		return "", err
+	}
+	switch field.Kind() {
+	case reflect.Bool:
+		return strconv.FormatBool(field.Bool()), nil
+	case reflect.Ptr:
+		if field.IsNil() {
+			return "", nil
+		}
+		return fmt.Sprint(field.Elem().Interface()), nil
+	case reflect.Slice:
+		if field.Len() == 0 {
+			return "", nil
+		}
+		return flowYAML(field.Interface())
+	case reflect.Struct:
+		if field.IsZero() {
+			return "", nil
+		}
+		return flowYAML(field.Interface())
+	}
+	return field.String(), nil
+}
+
+// flowYAML renders a value as single-line YAML, e.g.
+// [{account_id: "123456789012", region: eu-west-1}]
+func flowYAML(v interface{}) (string, error) {
+	var node yaml.Node
+	if err := node.Encode(v); err != nil {
+		return "", err
+	}
+	setFlowStyle(&node)
+	out, err := yaml.Marshal(&node)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// setFlowStyle renders a YAML node and its children inline
+func setFlowStyle(node *yaml.Node) {
+	if node.Kind == yaml.SequenceNode || node.Kind == yaml.MappingNode {
+		node.Style = yaml.FlowStyle
+	}
+	for _, child := range node.Content {
+		setFlowStyle(child)
+	}
+}
+
+// SetProfileField validates and sets a ProfileConfig field from a string
+func SetProfileField(pc *ProfileConfig, key, value string) error {
+	schemaField, err := SchemaFieldByKey(key)
+	if err != nil {
+		return err
+	}
+	if err := schemaField.CheckValue(value); err != nil {
+		return err
+	}
+
+	field, err := profileStructField(pc, key)
+	if err != nil {
+		return err
+	}
+	return setField(field, key, value)
+}
+
+// SetSetting validates and sets a GlobalSettings field from a string. An
+// empty value unsets the setting, so its default applies again; settings
+// that can't be unset must be given true or false.
+func SetSetting(s *GlobalSettings, key, value string) error {
+	schemaField, err := SettingsFieldByKey(key)
+	if err != nil {
+		return err
+	}
+	field, ok := fieldByYAMLKey(reflect.ValueOf(s).Elem(), key)
+	if !ok {
+		return fmt.Errorf("unknown setting: %s", key)
+	}
+	if value != "" || field.Kind() == reflect.Bool {
+		if err := schemaField.CheckValue(value); err != nil {
+			return err
+		}
+	}
+	return setField(field, key, value)
+}
+
+// setField sets a struct field from a string that already passed its
+// schema checks; an empty value resets it
+func setField(field reflect.Value, key, value string) error {
+	switch field.Kind() {
+	case reflect.Bool:
+		b, _ := strconv.ParseBool(value)
+		field.SetBool(b)
+		return nil
+	case reflect.Int:
+		i, _ := strconv.Atoi(value)
+		field.SetInt(int64(i))
+		return nil
+	case reflect.Ptr:
+		field.Set(reflect.Zero(field.Type()))
+		if value == "" {
+			return nil
+		}
+		b, _ := strconv.ParseBool(value)
+		field.Set(reflect.ValueOf(&b))
+		return nil
+	case reflect.Slice, reflect.Struct:
+		field.Set(reflect.Zero(field.Type()))
+		if value == "" {
+			return nil
+		}
+		if err := yaml.Unmarshal([]byte(value), field.Addr().Interface()); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		return nil
+	}
+	field.SetString(value)
+	return nil
+}
+
+// ValidateProfileConfig checks every schema field of a profile configuration
+func ValidateProfileConfig(pc *ProfileConfig) []error {
+	var errs []error
+	for i := range ProfileSchema {
+		value, err := GetProfileField(pc, ProfileSchema[i].Key)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := ProfileSchema[i].CheckValue(value); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// Validate checks every profile and setting against the schema. Errors name
+// the profile or setting they belong to.
+func (fc *FancyConfig) Validate() []error {
+	var errs []error
+	for _, name := range sortedKeys(fc.ProfileConfigs) {
+		pc := fc.ProfileConfigs[name]
+		for _, err := range ValidateProfileConfig(&pc) {
+			errs = append(errs, fmt.Errorf("profile_configs.%s: %w", name, err))
+		}
+	}
+	for i := range SettingsSchema {
+		field := &SettingsSchema[i]
+		if field.Validate == nil || field.Type != FieldString {
+			continue
+		}
+		if err := field.CheckValue(SettingValue(&fc.Settings, field.Key)); err != nil {
+			errs = append(errs, fmt.Errorf("settings.%w", err))
+		}
+	}
+	return errs
+}
+
+// SettingValue returns the configured value of a setting as text, or "" if
+// it is unset
+func SettingValue(s *GlobalSettings, key string) string {
+	v := reflect.ValueOf(s).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0] != key {
+			continue
+		}
+		field := v.Field(i)
+		switch field.Kind() {
+		case reflect.Ptr:
+			if field.IsNil() {
+				return ""
+			}
+			return fmt.Sprint(field.Elem().Interface())
+		case reflect.Slice:
+			if field.Len() == 0 {
+				return ""
+			}
+			return fmt.Sprint(field.Interface())
+		case reflect.Int:
+			if field.Int() == 0 {
+				return ""
+			}
+		}
+		return fmt.Sprint(field.Interface())
+	}
+	return ""
+}
+
+// profileStructField finds the ProfileConfig struct field tagged with key
+func profileStructField(pc *ProfileConfig, key string) (reflect.Value, error) {
+	if field, ok := fieldByYAMLKey(reflect.ValueOf(pc).Elem(), key); ok {
+		return field, nil
+	}
+	return reflect.Value{}, fmt.Errorf("unknown profile config key: %s", key)
+}
+
+// fieldByYAMLKey finds the field of struct v whose yaml tag names key
+func fieldByYAMLKey(v reflect.Value, key string) (reflect.Value, bool) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		tag := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]
+		if tag == key {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// JSONSchema returns a JSON Schema document describing ~/.fancy-config.yaml,
+// suitable for YAML language servers and other external tooling
+func JSONSchema() ([]byte, error) {
+	properties := schemaProperties(ProfileSchema)
+
+	schema := map[string]interface{}{
+		"$schema": "http://json-schema.org/draft-07/schema#",
+		"title":   "fancy-login configuration",
+		"type":    "object",
+		"properties": map[string]interface{}{
+			"profile_configs": map[string]interface{}{
+				"type": "object",
+				"additionalProperties": map[string]interface{}{
+					"type":                 "object",
+					"properties":           properties,
+					"additionalProperties": false,
+				},
+			},
+			"kube_only_profiles": map[string]interface{}{
+				"type": "object",
+				"additionalProperties": map[string]interface{}{
+					"type":                 "object",
+					"properties":           schemaProperties(KubeProfileSchema),
+					"additionalProperties": false,
+				},
+			},
+			"settings": map[string]interface{}{
+				"type":       "object",
+				"properties": schemaProperties(SettingsSchema),
+			},
+		},
+	}
+
+	return json.MarshalIndent(schema, "", "  ")
+}
+
+// schemaProperties converts schema fields into JSON Schema properties
+func schemaProperties(fields []SchemaField) map[string]interface{} {
+	properties := make(map[string]interface{})
+	for _, f := range fields {
+		prop := map[string]interface{}{
+			"type":        string(f.Type),
+			"description": f.Description,
+		}
+		if f.Type == FieldObject {
+			prop["properties"] = schemaProperties(f.Items)
+			prop["additionalProperties"] = false
+		}
+		if f.Type == FieldList {
+			prop["items"] = map[string]string{"type": "string"}
+			if f.Items != nil {
+				prop["items"] = map[string]interface{}{
+					"type":                 "object",
+					"properties":           schemaProperties(f.Items),
+					"additionalProperties": false,
+				}
+			}
+		}
+		if f.Default != "" {
+			prop["default"] = typedDefault(f)
+		}
+		properties[f.Key] = prop
+	}
+	return properties
+}
+
+// typedDefault returns the field default as its native type
+func typedDefault(f SchemaField) interface{} {
+	switch f.Type {
+	case FieldBool:
+		b, _ := strconv.ParseBool(f.Default)
+		return b
+	case FieldInt:
+		i, _ := strconv.Atoi(f.Default)
+		return i
+	}
+	return f.Default
+}
+
+// ValidateRegion checks that a value looks like an AWS region
+func ValidateRegion(value string) error {
+	if !regionRegex.MatchString(value) {
+		return fmt.Errorf("%q is not a valid AWS region", value)
+	}
+	return nil
+}
+
+// validateAccountID checks that a value is a 12-digit AWS account ID
+func validateAccountID(value string) error {
+	if !accountIDRegex.MatchString(value) {
+		return fmt.Errorf("%q is not a 12-digit AWS account ID", value)
+	}
+	return nil
+}
+
+// validateEKSCluster checks the eks_cluster object in its YAML form
+func validateEKSCluster(value string) error {
+	var cluster EKSClusterConfig
+	if err := yaml.Unmarshal([]byte(value), &cluster); err != nil {
+		return fmt.Errorf("expected {name, region}: %w", err)
+	}
+	if cluster.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if cluster.Region != "" {
+		return ValidateRegion(cluster.Region)
+	}
+	return nil
+}
+
+// validateECRRegistries checks the ecr_registries list in its YAML form
+func validateECRRegistries(value string) error {
+	var registries []ECRRegistryConfig
+	if err := yaml.Unmarshal([]byte(value), &registries); err != nil {
+		return fmt.Errorf("expected a list of {account_id, region}: %w", err)
+	}
+	for i, r := range registries {
+		if err := validateAccountID(r.AccountID); err != nil {
+			return fmt.Errorf("entry %d: %w", i+1, err)
+		}
+		if r.Region != "" {
+			if err := ValidateRegion(r.Region); err != nil {
+				return fmt.Errorf("entry %d: %w", i+1, err)
+			}
+		}
+	}
+	return nil
+}
+
+// validateAliases checks the aliases list in its YAML form: every alias is
+// a single word and listed once
+func validateAliases(value string) error {
+	var aliases []string
+	if err := yaml.Unmarshal([]byte(value), &aliases); err != nil {
+		return fmt.Errorf("expected a list of names: %w", err)
+	}
+	seen := make(map[string]bool)
+	for i, alias := range aliases {
+		if alias == "" || strings.ContainsAny(alias, " \t") {
+			return fmt.Errorf("entry %d: %q is not a valid alias", i+1, alias)
+		}
+		if seen[alias] {
+			return fmt.Errorf("entry %d: %s is listed twice", i+1, alias)
+		}
+		seen[alias] = true
+	}
+	return nil
+}
+
+// validateBackend checks that a value names a credential backend
+func validateBackend(value string) error {
+	switch value {
+	case BackendCLI, BackendAWSVault:
+		return nil
+	}
+	return fmt.Errorf("%q is not a backend (use cli or aws-vault)", value)
+}
+
+// validateEmail checks that a value looks like an email address
+func validateEmail(value string) error {
+	local, domain, ok := strings.Cut(value, "@")
+	if !ok || local == "" || domain == "" || strings.ContainsAny(value, " <>") {
+		return fmt.Errorf("%q is not a valid email address", value)
+	}
+	return nil
+}
@@ -0,0 +1,245 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestProfileSchemaCoversProfileConfig(t *testing.T) {
+	// Every YAML key on ProfileConfig needs a schema entry and vice versa
+	typ := reflect.TypeOf(ProfileConfig{})
+	tags := make(map[string]bool)
+	for i := 0; i < typ.NumField(); i++ {
+		tag := strings.Split(typ.Field(i).Tag.Get("yaml"), ",")[0]
+		tags[tag] = true
+		if _, err := SchemaFieldByKey(tag); err != nil {
+			t.Errorf("ProfileConfig field %s has no schema entry", tag)
+		}
+	}
+
+	for _, field := range ProfileSchema {
+		if !tags[field.Key] {
+			t.Errorf("Schema entry %s has no ProfileConfig field", field.Key)
+		}
+	}
+}
+
+func TestSetAndGetProfileField(t *testing.T) {
+	testCases := []struct {
+		name      string
+		key       string
+		value     string
+		expectErr bool
+	}{
+		{"Set bool", "ecr_login", "true", false},
+		{"Set invalid bool", "ecr_login", "maybe", true},
+		{"Set region", "ecr_region", "us-west-2", false},
+		{"Set invalid region", "ecr_region", "mars", true},
+		{"Set account ID", "account_id", "123456789012", false},
+		{"Set invalid account ID", "account_id", "1234", true},
+		{"Set registries", "ecr_registries", `[{account_id: "123456789012", region: eu-west-1}, {account_id: "210987654321"}]`, false},
+		{"Set registry without account", "ecr_registries", "[{region: eu-west-1}]", true},
+		{"Set registry in invalid region", "ecr_registries", `[{account_id: "123456789012", region: mars}]`, true},
+		{"Set aliases", "aliases", "[prod, p]", false},
+		{"Set duplicate aliases", "aliases", "[prod, prod]", true},
+		{"Set alias with a space", "aliases", `["my prod"]`, true},
+		{"Set backend", "backend", "aws-vault", false},
+		{"Set invalid backend", "backend", "vault", true},
+		{"Set EKS cluster", "eks_cluster", "{name: prod, region: eu-west-1}", false},
+		{"Set EKS cluster without region", "eks_cluster", "{name: prod}", false},
+		{"Set EKS cluster without name", "eks_cluster", "{region: eu-west-1}", true},
+		{"Set EKS cluster in invalid region", "eks_cluster", "{name: prod, region: mars}", true},
+		{"Set optional bool", "k9s_readonly", "false", false},
+		{"Unset optional bool", "k9s_readonly", "", false},
+		{"Set invalid optional bool", "k9s_readonly", "maybe", true},
+		{"Unknown key", "does_not_exist", "x", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pc := &ProfileConfig{}
+			err := SetProfileField(pc, tc.key, tc.value)
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("SetProfileField(%s, %s) error = %v, expectErr %v", tc.key, tc.value, err, tc.expectErr)
+			}
+			if tc.expectErr {
+				return
+			}
+
+			got, err := GetProfileField(pc, tc.key)
+			if err != nil {
+				t.Fatalf("GetProfileField(%s) error = %v", tc.key, err)
+			}
+			if got != tc.value {
+				t.Errorf("GetProfileField(%s) = %s, expected %s", tc.key, got, tc.value)
+			}
+		})
+	}
+}
+
+func TestValidateProfileConfig(t *testing.T) {
+	valid := &ProfileConfig{AccountID: "123456789012", ECRRegion: "eu-central-1"}
+	if errs := ValidateProfileConfig(valid); len(errs) != 0 {
+		t.Errorf("Expected no errors, got %v", errs)
+	}
+
+	invalid := &ProfileConfig{AccountID: "abc", ECRRegion: "nowhere"}
+	if errs := ValidateProfileConfig(invalid); len(errs) != 2 {
+		t.Errorf("Expected 2 errors, got %v", errs)
+	}
+}
+
+func TestFancyConfigValidate(t *testing.T) {
+	fc := DefaultFancyConfig()
+	fc.ProfileConfigs["dev"] = ProfileConfig{AccountID: "abc"}
+	fc.Settings.Theme = "neon"
+
+	errs := fc.Validate()
+	if len(errs) != 2 {
+		t.Fatalf("Expected 2 errors, got %v", errs)
+	}
+	if !strings.HasPrefix(errs[0].Error(), "profile_configs.dev: ") {
+		t.Errorf("Expected the profile in the first error, got %v", errs[0])
+	}
+	if !strings.HasPrefix(errs[1].Error(), "settings.theme") {
+		t.Errorf("Expected the setting in the second error, got %v", errs[1])
+	}
+
+	fc.ProfileConfigs["dev"] = ProfileConfig{AccountID: "123456789012"}
+	fc.Settings.Theme = "mono"
+	if errs := fc.Validate(); len(errs) != 0 {
+		t.Errorf("Expected no errors, got %v", errs)
+	}
+}
+
+func TestSettingValue(t *testing.T) {
+	settings := &GlobalSettings{Theme: "mono", AWSTimeout: 30}
+	testCases := []struct {
+		key      string
+		expected string
+	}{
+		{"theme", "mono"},
+		{"aws_timeout", "30"},
+		{"docker_timeout", ""},
+		{"summary_sinks", ""},
+		{"unknown", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.key, func(t *testing.T) {
+			if got := SettingValue(settings, tc.key); got != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestSetSetting(t *testing.T) {
+	testCases := []struct {
+		name      string
+		key       string
+		value     string
+		expected  string
+		expectErr bool
+	}{
+		{"Set string", "theme", "mono", "mono", false},
+		{"Set invalid string", "theme", "neon", "", true},
+		{"Set region", "default_region", "us-west-2", "us-west-2", false},
+		{"Set invalid region", "default_region", "mars", "", true},
+		{"Set int", "aws_timeout", "120", "120", false},
+		{"Set invalid int", "aws_timeout", "2m", "", true},
+		{"Set bool", "tmux_integration", "true", "true", false},
+		{"Set invalid bool", "tmux_integration", "maybe", "false", true},
+		{"Unset bool", "prefer_local_configs", "", "false", true},
+		{"Set optional bool", "sso_portal_probe", "false", "false", false},
+		{"Unset optional bool", "auto_update_account_ids", "", "", false},
+		{"Set list", "summary_sinks", "[terminal, notify]", "[terminal notify]", false},
+		{"Set single sort column", "profile_sort", "recent", "[recent]", false},
+		{"Unset int", "aws_timeout", "", "", false},
+		{"Set duration", "selection_timeout", "5m", "5m", false},
+		{"Set invalid duration", "selection_timeout", "soon", "", true},
+		{"Unknown key", "does_not_exist", "x", "", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			settings := &GlobalSettings{}
+			err := SetSetting(settings, tc.key, tc.value)
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("SetSetting(%s, %s) error = %v, expectErr %v", tc.key, tc.value, err, tc.expectErr)
+			}
+			if got := SettingValue(settings, tc.key); got != tc.expected {
+				t.Errorf("SettingValue(%s) = %q, expected %q", tc.key, got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestSettingsSchemaMatchesDefaultConfig(t *testing.T) {
+	defaults := DefaultFancyConfig().Settings
+	keys := map[string]bool{"default_region": true, "config_wizard_run": true, "prefer_local_configs": true}
+	for _, field := range SettingsSchema {
+		if !keys[field.Key] {
+			continue
+		}
+		t.Run(field.Key, func(t *testing.T) {
+			if got := SettingValue(&defaults, field.Key); field.Default != got {
+				t.Errorf("Expected the schema default %q to match DefaultFancyConfig's %q", field.Default, got)
+			}
+		})
+	}
+}
+
+func TestJSONSchema(t *testing.T) {
+	data, err := JSONSchema()
+	if err != nil {
+		t.Fatalf("JSONSchema() error = %v", err)
+	}
+
+	var parsed map[string]interface{}
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		t.Fatalf("JSONSchema() produced invalid JSON: %v", err)
+	}
+
+	for _, field := range ProfileSchema {
+		if !strings.Contains(string(data), `"`+field.Key+`"`) {
+			t.Errorf("JSON schema is missing key %s", field.Key)
+		}
+	}
+}
+
+func TestSettingsSchemaCoversGlobalSettings(t *testing.T) {
+	typ := reflect.TypeOf(GlobalSettings{})
+	for i := 0; i < typ.NumField(); i++ {
+		tag := strings.Split(typ.Field(i).Tag.Get("yaml"), ",")[0]
+		found := false
+		for _, field := range SettingsSchema {
+			if field.Key == tag {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("GlobalSettings field %s has no schema entry", tag)
+		}
+	}
+}
+
+func TestKubeProfileSchemaCoversKubeProfileConfig(t *testing.T) {
+	typ := reflect.TypeOf(KubeProfileConfig{})
+	for i := 0; i < typ.NumField(); i++ {
+		tag := strings.Split(typ.Field(i).Tag.Get("yaml"), ",")[0]
+		found := false
+		for _, field := range KubeProfileSchema {
+			if field.Key == tag {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("KubeProfileConfig field %s has no schema entry", tag)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// SecretPattern is a named regular expression that indicates a long-lived credential
+type SecretPattern struct {
+	Name  string
+	Regex *regexp.Regexp
+	// check optionally filters regex matches to avoid false positives
+	check func(match string) bool
+}
+
+// builtinSecretPatterns catch the usual ways static AWS keys end up in config
+var builtinSecretPatterns = []SecretPattern{
+	{Name: "AWS access key ID", Regex: regexp.MustCompile(`(?:A3T[A-Z0-9]|AKIA|ASIA)[0-9A-Z]{16}`)},
+	{Name: "aws_secret_access_key assignment", Regex: regexp.MustCompile(`(?i)aws_secret_access_key`)},
+	{
+		Name:  "40-character secret",
+		Regex: regexp.MustCompile(`[A-Za-z0-9/+=]{40}`),
+		check: looksLikeSecret,
+	},
+}
+
+// SecretFinding is a suspected secret at a config key path
+type SecretFinding struct {
+	Path    string
+	Pattern string
+	Masked  string
+}
+
+func (f SecretFinding) String() string {
+	return fmt.Sprintf("%s: %s (%s)", f.Path, f.Pattern, f.Masked)
+}
+
+// secretPatterns returns the built-in patterns plus any from settings
+func (fc *FancyConfig) secretPatterns() ([]SecretPattern, error) {
+	patterns := append([]SecretPattern{}, builtinSecretPatterns...)
+	for _, expr := range fc.Settings.SecretPatterns {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid secret pattern %q: %w", expr, err)
+		}
+		patterns = append(patterns, SecretPattern{Name: "custom pattern " + expr, Regex: re})
+	}
+	return patterns, nil
+}
+
+// ScanSecrets walks every string value in the AWS and kube-only profile
+// configurations, including nested maps and lists, and reports values that
+// look like secrets
+func (fc *FancyConfig) ScanSecrets() ([]SecretFinding, error) {
+	patterns, err := fc.secretPatterns()
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []SecretFinding
+	visit := func(path, value string) {
+		for _, p := range patterns {
+			for _, match := range p.Regex.FindAllString(value, -1) {
+				if p.check != nil && !p.check(match) {
+					continue
+				}
+				findings = append(findings, SecretFinding{Path: path, Pattern: p.Name, Masked: maskSecret(value)})
+				return
+			}
+		}
+	}
+
+	for _, name := range sortedKeys(fc.ProfileConfigs) {
+		walkStrings(reflect.ValueOf(fc.ProfileConfigs[name]), "profile_configs."+name, visit)
+	}
+	for _, name := range sortedKeys(fc.KubeOnlyProfiles) {
+		walkStrings(reflect.ValueOf(fc.KubeOnlyProfiles[name]), "kube_only_profiles."+name, visit)
+	}
+	return findings, nil
+}
+
+// sortedKeys returns the keys of a profile map in sorted order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// checkSecretsBeforeSave blocks (or warns about) suspected secrets per settings
+func (fc *FancyConfig) checkSecretsBeforeSave() error {
+	findings, err := fc.ScanSecrets()
+	if err != nil {
+		return err
+	}
+	if len(findings) == 0 {
+		return nil
+	}
+
+	var lines []string
+	for _, f := range findings {
+		lines = append(lines, "  "+f.String())
+	}
+	message := fmt.Sprintf("possible long-lived credentials in configuration (use SSO instead):\n%s", strings.Join(lines, "\n"))
+
+	if fc.Settings.SecretScanMode == "warn" {
+		fmt.Fprintf(os.Stderr, "%s⚠️  %s%s\n", Warning, message, Reset)
+		return nil
+	}
+	return fmt.Errorf("refusing to save, %s", message)
+}
+
+// walkStrings calls fn for every string reachable from v with its YAML key path
+func walkStrings(v reflect.Value, path string, fn func(path, value string)) {
+	switch v.Kind() {
+	case reflect.String:
+		fn(path, v.String())
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			walkStrings(v.Elem(), path, fn)
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			if !t.Field(i).IsExported() {
+				continue
+			}
+			key := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]
+			if key == "" {
+				key = strings.ToLower(t.Field(i).Name)
+			}
+			walkStrings(v.Field(i), path+"."+key, fn)
+		}
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j]) })
+		for _, k := range keys {
+			walkStrings(v.MapIndex(k), fmt.Sprintf("%s.%v", path, k), fn)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			walkStrings(v.Index(i), fmt.Sprintf("%s[%d]", path, i), fn)
+		}
+	}
+}
+
+// looksLikeSecret filters 40-character runs down to mixed-case alphanumerics,
+// so long lowercase names and digit-only strings are not flagged
+func looksLikeSecret(match string) bool {
+	var upper, lower, digit bool
+	for _, r := range match {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		}
+	}
+	return upper && lower && digit
+}
+
+// maskSecret keeps only the first four characters of a value
+func maskSecret(value string) string {
+	if len(value) <= 4 {
+		return "****"
+	}
+	return value[:4] + strings.Repeat("*", 8)
+}
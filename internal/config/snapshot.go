@@ -0,0 +1,151 @@
+package config
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"sync"
+)
+
+// FileReader reads a whole file, like os.ReadFile
+type FileReader func(path string) ([]byte, error)
+
+// cached is a parse result kept by a Snapshot, errors included
+type cached[T any] struct {
+	value T
+	err   error
+}
+
+// Snapshot parses the AWS config, the kubeconfig, the fancy config and the
+// shell script's legacy mapping files at most once per run. Every part of
+// the run sees the same view, even if a file changes halfway through;
+// Reload starts over.
+type Snapshot struct {
+	read FileReader
+	// reload re-reads the files on every access
+	reload bool
+
+	mu     sync.Mutex
+	aws    *cached[[]AWSProfile]
+	kube   *cached[[]KubernetesContext]
+	fancy  *cached[*FancyConfig]
+	legacy map[string]*cached[[]LegacyMapping]
+}
+
+// NewSnapshot creates a snapshot reading files with read; nil means
+// os.ReadFile
+func NewSnapshot(read FileReader) *Snapshot {
+	if read == nil {
+		read = os.ReadFile
+	}
+	return &Snapshot{read: read}
+}
+
+// Reload forgets everything parsed so far, so the next access re-reads
+// the files. It is for flows that change them, like `aws configure sso`.
+func (s *Snapshot) Reload() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.aws, s.kube, s.fancy, s.legacy = nil, nil, nil, nil
+}
+
+// SetReload makes every access re-read the files instead of keeping the
+// first view, for long-running flows that should pick up edits
+func (s *Snapshot) SetReload(reload bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reload = reload
+}
+
+// AWSProfiles returns the profiles of the AWS config and the shared
+// credentials file
+func (s *Snapshot) AWSProfiles() ([]AWSProfile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.aws == nil || s.reload {
+		profiles, err := loadAWSProfiles(s.read)
+		s.aws = &cached[[]AWSProfile]{profiles, err}
+	}
+	return s.aws.value, s.aws.err
+}
+
+// AWSProfileNames returns the names of the AWS config's profiles. A missing
+// AWS config simply has no profiles yet.
+func (s *Snapshot) AWSProfileNames() ([]string, error) {
+	profiles, err := s.AWSProfiles()
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(profiles))
+	for _, p := range profiles {
+		names = append(names, p.Name)
+	}
+	return names, nil
+}
+
+// AWSProfile returns a profile from the AWS config
+func (s *Snapshot) AWSProfile(name string) (AWSProfile, bool) {
+	profiles, err := s.AWSProfiles()
+	if err != nil {
+		return AWSProfile{}, false
+	}
+	for _, p := range profiles {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return AWSProfile{}, false
+}
+
+// KubeContexts returns the contexts of the kubeconfig
+func (s *Snapshot) KubeContexts() ([]KubernetesContext, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.kube == nil || s.reload {
+		contexts, err := parseKubernetesContexts(s.read, GetKubeConfigPath())
+		s.kube = &cached[[]KubernetesContext]{contexts, err}
+	}
+	return s.kube.value, s.kube.err
+}
+
+// FancyConfig returns the fancy config, or the defaults if there is none.
+// Its AWS lookups use this snapshot.
+func (s *Snapshot) FancyConfig() (*FancyConfig, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fancy == nil || s.reload {
+		fc, err := loadFancyConfig(s.read)
+		if fc != nil {
+			fc.snapshot = s
+		}
+		s.fancy = &cached[*FancyConfig]{fc, err}
+	}
+	return s.fancy.value, s.fancy.err
+}
+
+// LegacyMappings returns the `key = value` lines of one of the shell
+// script's mapping files. A missing file has no mappings.
+func (s *Snapshot) LegacyMappings(path string) ([]LegacyMapping, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.legacy == nil {
+		s.legacy = make(map[string]*cached[[]LegacyMapping])
+	}
+	if s.legacy[path] == nil || s.reload {
+		mappings, err := parseLegacyMappings(s.read, path)
+		s.legacy[path] = &cached[[]LegacyMapping]{mappings, err}
+	}
+	return s.legacy[path].value, s.legacy[path].err
+}
+
+// awsProfiles returns the AWS profiles from the config's snapshot, or
+// parses them when it has none
+func (fc *FancyConfig) awsProfiles() ([]AWSProfile, error) {
+	if fc.snapshot != nil {
+		return fc.snapshot.AWSProfiles()
+	}
+	return LoadAWSProfiles()
+}
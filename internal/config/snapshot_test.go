@@ -0,0 +1,162 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// countingReader reads files and counts reads per path
+type countingReader struct {
+	reads map[string]int
+}
+
+func newCountingReader() *countingReader {
+	return &countingReader{reads: make(map[string]int)}
+}
+
+func (c *countingReader) read(path string) ([]byte, error) {
+	c.reads[path]++
+	return os.ReadFile(path)
+}
+
+// writeSnapshotFiles writes an AWS config, kubeconfig and fancy config
+// under a temporary HOME and returns their paths
+func writeSnapshotFiles(t *testing.T) (awsPath, kubePath, fancyPath string) {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("AWS_CONFIG_FILE", "")
+	t.Setenv("KUBECONFIG", "")
+
+	awsPath = filepath.Join(home, ".aws", "config")
+	kubePath = filepath.Join(home, ".kube", "config")
+	fancyPath = filepath.Join(home, ".fancy-config.yaml")
+	files := map[string]string{
+		awsPath:   "[profile dev]\nsso_start_url = https://acme.awsapps.com/start\nregion = eu-west-1\n\n[profile vendor]\ncredential_process = helper\n",
+		kubePath:  "apiVersion: v1\nkind: Config\ncontexts:\n- name: dev-cluster\n  context: {cluster: dev}\n",
+		fancyPath: "profile_configs:\n  dev:\n    name: Development\n",
+	}
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return awsPath, kubePath, fancyPath
+}
+
+func TestSnapshotReadsEachFileOnce(t *testing.T) {
+	awsPath, kubePath, fancyPath := writeSnapshotFiles(t)
+	reader := newCountingReader()
+	snapshot := NewSnapshot(reader.read)
+
+	for i := 0; i < 3; i++ {
+		if names, err := snapshot.AWSProfileNames(); err != nil || len(names) != 2 {
+			t.Fatalf("Expected 2 profiles, got %v, %v", names, err)
+		}
+		if p, ok := snapshot.AWSProfile("dev"); !ok || !p.IsSSO {
+			t.Fatalf("Expected the SSO profile dev, got %+v", p)
+		}
+		if contexts, err := snapshot.KubeContexts(); err != nil || len(contexts) != 1 {
+			t.Fatalf("Expected 1 context, got %v, %v", contexts, err)
+		}
+		fc, err := snapshot.FancyConfig()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if region := fc.RegionForProfile("dev"); region != "eu-west-1" {
+			t.Fatalf("Expected eu-west-1, got %s", region)
+		}
+	}
+
+	for _, path := range []string{awsPath, kubePath, fancyPath} {
+		if reader.reads[path] != 1 {
+			t.Errorf("Expected %s to be read once, got %d", path, reader.reads[path])
+		}
+	}
+
+	snapshot.Reload()
+	snapshot.AWSProfiles()
+	if reader.reads[awsPath] != 2 {
+		t.Errorf("Expected Reload to re-read the AWS config, got %d reads", reader.reads[awsPath])
+	}
+}
+
+func TestSnapshotKeepsItsViewWhenFilesChange(t *testing.T) {
+	awsPath, _, _ := writeSnapshotFiles(t)
+	snapshot := NewSnapshot(nil)
+	if names, _ := snapshot.AWSProfileNames(); len(names) != 2 {
+		t.Fatalf("Expected 2 profiles, got %v", names)
+	}
+
+	if err := os.WriteFile(awsPath, []byte("[profile other]\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if names, _ := snapshot.AWSProfileNames(); len(names) != 2 {
+		t.Errorf("Expected the run's view to stay unchanged, got %v", names)
+	}
+}
+
+func TestSnapshotMissingFiles(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("AWS_CONFIG_FILE", "")
+	t.Setenv("KUBECONFIG", "")
+	snapshot := NewSnapshot(nil)
+
+	if names, err := snapshot.AWSProfileNames(); err != nil || len(names) != 0 {
+		t.Errorf("Expected no profiles and no error without an AWS config, got %v, %v", names, err)
+	}
+	if _, err := snapshot.KubeContexts(); err == nil {
+		t.Error("Expected an error without a kubeconfig")
+	}
+	if fc, err := snapshot.FancyConfig(); err != nil || len(fc.ProfileConfigs) != 0 {
+		t.Errorf("Expected the default config, got %+v, %v", fc, err)
+	}
+}
+
+func TestSnapshotLegacyMappings(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, ".fancy-contexts.conf")
+	content := "# comment\n\nOV_TEST_DEVENG = test-cluster\nbroken line\n"
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	reader := newCountingReader()
+	snapshot := NewSnapshot(reader.read)
+
+	for i := 0; i < 2; i++ {
+		mappings, err := snapshot.LegacyMappings(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(mappings) != 1 || mappings[0] != (LegacyMapping{Key: "OV_TEST_DEVENG", Value: "test-cluster"}) {
+			t.Fatalf("Unexpected mappings: %+v", mappings)
+		}
+	}
+	if reader.reads[path] != 1 {
+		t.Errorf("Expected the mapping file to be read once, got %d", reader.reads[path])
+	}
+
+	if mappings, err := snapshot.LegacyMappings(filepath.Join(dir, "missing")); err != nil || mappings != nil {
+		t.Errorf("Expected no mappings and no error for a missing file, got %v, %v", mappings, err)
+	}
+}
+
+func TestSnapshotSetReload(t *testing.T) {
+	awsPath, _, _ := writeSnapshotFiles(t)
+	snapshot := NewSnapshot(nil)
+	snapshot.SetReload(true)
+	if names, _ := snapshot.AWSProfileNames(); len(names) != 2 {
+		t.Fatalf("Expected 2 profiles, got %v", names)
+	}
+
+	if err := os.WriteFile(awsPath, []byte("[profile other]\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if names, _ := snapshot.AWSProfileNames(); len(names) != 1 || names[0] != "other" {
+		t.Errorf("Expected --reload to pick up the edit, got %v", names)
+	}
+}
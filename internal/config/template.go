@@ -0,0 +1,126 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// GenerateConfigTemplate renders a fully commented ~/.fancy-config.yaml from
+// the declarative schema, with one conservative entry per discovered profile
+func GenerateConfigTemplate(profiles []AWSProfile) string {
+	var b strings.Builder
+
+	b.WriteString("# fancy-login configuration\n")
+	b.WriteString("# Generated by `fancy-login-go config init`. Edit freely; run\n")
+	b.WriteString("# `fancy-login-go config schema` for the list of supported keys.\n\n")
+
+	b.WriteString("settings:\n")
+	for _, field := range SettingsSchema {
+		value := field.Default
+		// The written file is the configuration, so the wizard is done
+		if field.Key == "config_wizard_run" {
+			value = "true"
+		}
+		writeTemplateField(&b, "  ", field, value)
+	}
+
+	b.WriteString("\nprofile_configs:\n")
+	if len(profiles) == 0 {
+		b.WriteString("  {}\n")
+		b.WriteString("  # No AWS profiles were found. Add entries keyed by profile name:\n")
+		b.WriteString("  # my-profile:\n")
+		for _, field := range ProfileSchema {
+			fmt.Fprintf(&b, "  #   %s: %s\n", field.Key, templateValue(field, field.Default))
+		}
+		writeKubeOnlyExample(&b)
+		return b.String()
+	}
+
+	defaultRegion := DefaultFancyConfig().Settings.DefaultRegion
+	for i, profile := range profiles {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "  %s:\n", yamlScalar(profile.Name))
+
+		region := profile.Region
+		if region == "" {
+			region = defaultRegion
+		}
+		values := map[string]string{
+			"name":            profile.Name,
+			"account_id":      profile.AccountID,
+			"ecr_login":       "false",
+			"ecr_region":      region,
+			"k9s_auto_launch": "false",
+		}
+		for _, field := range ProfileSchema {
+			writeTemplateField(&b, "    ", field, values[field.Key])
+		}
+	}
+	writeKubeOnlyExample(&b)
+	return b.String()
+}
+
+// writeKubeOnlyExample writes a commented kube_only_profiles section
+func writeKubeOnlyExample(b *strings.Builder) {
+	b.WriteString("\n# Clusters that authenticate without AWS (e.g. kubelogin/OIDC):\n")
+	b.WriteString("# kube_only_profiles:\n")
+	b.WriteString("#   my-oidc-cluster:\n")
+	for _, field := range KubeProfileSchema {
+		fmt.Fprintf(b, "#     %s: %s\n", field.Key, templateValue(field, field.Default))
+	}
+}
+
+// writeTemplateField writes a commented key/value line
+func writeTemplateField(b *strings.Builder, indent string, field SchemaField, value string) {
+	comment := field.Description
+	if field.Default != "" {
+		comment += fmt.Sprintf(" (default: %s)", field.Default)
+	}
+	fmt.Fprintf(b, "%s# %s\n", indent, comment)
+	if field.Type == FieldBool && field.Default == "" && value == "" {
+		// Left unset, since writing false would override what it defaults to
+		fmt.Fprintf(b, "%s# %s: true\n", indent, field.Key)
+		return
+	}
+	fmt.Fprintf(b, "%s%s: %s\n", indent, field.Key, templateValue(field, value))
+}
+
+// templateValue renders a raw schema value as YAML for the field's type
+func templateValue(field SchemaField, value string) string {
+	switch field.Type {
+	case FieldBool, FieldInt:
+		if value == "" {
+			return typedZero(field.Type)
+		}
+		return value
+	case FieldList:
+		return "[]"
+	case FieldObject:
+		return "{}"
+	}
+	return yamlScalar(value)
+}
+
+// typedZero returns the YAML zero value for a scalar type
+func typedZero(t FieldType) string {
+	if t == FieldBool {
+		return "false"
+	}
+	return "0"
+}
+
+// yamlScalar quotes a string value only when YAML requires it
+func yamlScalar(value string) string {
+	if value == "" {
+		return `""`
+	}
+	out, err := yaml.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%q", value)
+	}
+	return strings.TrimSpace(string(out))
+}
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestGenerateConfigTemplate(t *testing.T) {
+	profiles := []AWSProfile{
+		{Name: "company_DEV_developer", AccountID: "123456789012", Region: "us-west-2"},
+		{Name: "default"},
+	}
+
+	template := GenerateConfigTemplate(profiles)
+
+	var parsed FancyConfig
+	if err := yaml.Unmarshal([]byte(template), &parsed); err != nil {
+		t.Fatalf("Template is not valid YAML: %v\n%s", err, template)
+	}
+
+	if !parsed.Settings.ConfigWizardRun {
+		t.Error("Template must set config_wizard_run so the wizard stops triggering")
+	}
+	if parsed.Settings.AWSTimeout != DefaultAWSTimeout {
+		t.Errorf("aws_timeout = %d, expected %d", parsed.Settings.AWSTimeout, DefaultAWSTimeout)
+	}
+
+	dev, ok := parsed.ProfileConfigs["company_DEV_developer"]
+	if !ok {
+		t.Fatal("Expected an entry for company_DEV_developer")
+	}
+	if dev.ECRLogin || dev.K9sAutoLaunch || dev.K8sContext != "" {
+		t.Errorf("Profile entries must be conservative, got %+v", dev)
+	}
+	if dev.AccountID != "123456789012" || dev.ECRRegion != "us-west-2" {
+		t.Errorf("Expected discovered account and region, got %+v", dev)
+	}
+	if parsed.ProfileConfigs["default"].ECRRegion != "eu-central-1" {
+		t.Errorf("Expected default region fallback, got %+v", parsed.ProfileConfigs["default"])
+	}
+
+	// Every schema key is present and commented
+	for _, field := range append(append([]SchemaField{}, SettingsSchema...), ProfileSchema...) {
+		if !strings.Contains(template, field.Key+":") {
+			t.Errorf("Template is missing key %s", field.Key)
+		}
+		if !strings.Contains(template, "# "+field.Description) {
+			t.Errorf("Template is missing comment for %s", field.Key)
+		}
+	}
+}
+
+func TestGenerateConfigTemplateWithoutProfiles(t *testing.T) {
+	var parsed FancyConfig
+	if err := yaml.Unmarshal([]byte(GenerateConfigTemplate(nil)), &parsed); err != nil {
+		t.Fatalf("Template without profiles is not valid YAML: %v", err)
+	}
+	if len(parsed.ProfileConfigs) != 0 {
+		t.Errorf("Expected no profiles, got %v", parsed.ProfileConfigs)
+	}
+}
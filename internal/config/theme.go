@@ -0,0 +1,124 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+)
+
+// Theme maps semantic output roles to ANSI escape sequences
+type Theme struct {
+	Success string
+	Warning string
+	Error   string
+	Accent  string
+	Muted   string
+	Heading string
+	Bold    string
+	Reset   string
+}
+
+// DefaultThemeName is used when no theme is configured
+const DefaultThemeName = "default"
+
+// Themes are the built-in themes selectable via settings.theme or --theme.
+// mono has no escape sequences at all and doubles as the no-color mode.
+var Themes = map[string]Theme{
+	"default": {
+		Success: "\033[0;32m",
+		Warning: "\033[1;33m",
+		Error:   "\033[0;31m",
+		Accent:  "\033[1;36m",
+		Muted:   "\033[2m",
+		Heading: "\033[1;33m",
+		Bold:    "\033[1m",
+		Reset:   "\033[0m",
+	},
+	// high-contrast avoids dim and yellow, which vanish on light backgrounds
+	"high-contrast": {
+		Success: "\033[1;32m",
+		Warning: "\033[1;35m",
+		Error:   "\033[1;4;31m",
+		Accent:  "\033[1;34m",
+		Muted:   "\033[0m",
+		Heading: "\033[1;4m",
+		Bold:    "\033[1m",
+		Reset:   "\033[0m",
+	},
+	// colorblind uses a blue/orange palette that stays distinct with deuteranopia
+	"colorblind": {
+		Success: "\033[1;34m",
+		Warning: "\033[1;33m",
+		Error:   "\033[1;38;5;208m",
+		Accent:  "\033[1;36m",
+		Muted:   "\033[0;37m",
+		Heading: "\033[1;33m",
+		Bold:    "\033[1m",
+		Reset:   "\033[0m",
+	},
+	"mono": {},
+}
+
+// Active role colors, set by ApplyTheme
+var (
+	Success string
+	Warning string
+	Error   string
+	Accent  string
+	Muted   string
+	Heading string
+	Bold    string
+	Reset   string
+)
+
+func init() {
+	setTheme(Themes[DefaultThemeName])
+}
+
+// ApplyTheme activates a built-in theme by name. An empty name selects
+// mono when NO_COLOR is set and the default theme otherwise.
+func ApplyTheme(name string) error {
+	if name == "" {
+		name = DefaultThemeName
+		if os.Getenv("NO_COLOR") != "" {
+			name = "mono"
+		}
+	}
+
+	theme, ok := Themes[name]
+	if !ok {
+		return fmt.Errorf("unknown theme %q (available: %v)", name, ThemeNames())
+	}
+	setTheme(theme)
+	return nil
+}
+
+// ThemeNames returns the built-in theme names in sorted order
+func ThemeNames() []string {
+	names := make([]string, 0, len(Themes))
+	for name := range Themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// setTheme copies a theme into the active role colors
+func setTheme(t Theme) {
+	Success = t.Success
+	Warning = t.Warning
+	Error = t.Error
+	Accent = t.Accent
+	Muted = t.Muted
+	Heading = t.Heading
+	Bold = t.Bold
+	Reset = t.Reset
+}
+
+// validateTheme checks that a value names a built-in theme
+func validateTheme(value string) error {
+	if _, ok := Themes[value]; !ok {
+		return fmt.Errorf("%q is not a theme (available: %v)", value, ThemeNames())
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestThemes(t *testing.T) {
+	defer ApplyTheme(DefaultThemeName)
+
+	for _, name := range ThemeNames() {
+		t.Run(name, func(t *testing.T) {
+			if err := ApplyTheme(name); err != nil {
+				t.Fatalf("ApplyTheme(%s) failed: %v", name, err)
+			}
+
+			roles := []string{Success, Warning, Error, Accent, Muted, Heading, Bold, Reset}
+			for _, role := range roles {
+				if name == "mono" {
+					if role != "" {
+						t.Errorf("mono theme should not emit escape sequences, got %q", role)
+					}
+					continue
+				}
+				if !strings.HasPrefix(role, "\033[") {
+					t.Errorf("Expected ANSI escape sequence, got %q", role)
+				}
+			}
+
+			if name != "mono" && Success == Error {
+				t.Error("Success and Error must be distinguishable")
+			}
+		})
+	}
+}
+
+func TestApplyTheme(t *testing.T) {
+	defer ApplyTheme(DefaultThemeName)
+
+	testCases := []struct {
+		name        string
+		theme       string
+		noColor     string
+		expectError bool
+		expectMono  bool
+	}{
+		{name: "Empty selects default", theme: ""},
+		{name: "NO_COLOR selects mono", theme: "", noColor: "1", expectMono: true},
+		{name: "Explicit theme wins over NO_COLOR", theme: "colorblind", noColor: "1"},
+		{name: "Explicit mono", theme: "mono", expectMono: true},
+		{name: "Unknown theme", theme: "neon", expectError: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("NO_COLOR", tc.noColor)
+			ApplyTheme(DefaultThemeName)
+
+			err := ApplyTheme(tc.theme)
+			if tc.expectError {
+				if err == nil {
+					t.Error("Expected an error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if (Reset == "") != tc.expectMono {
+				t.Errorf("Expected mono=%v, got Reset=%q", tc.expectMono, Reset)
+			}
+		})
+	}
+}
@@ -3,9 +3,17 @@ package config
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
+
+	"fancy-login/internal/a11y"
+	"fancy-login/internal/prompt"
+
+	"gopkg.in/yaml.v3"
 )
 
 // ConfigWizard handles the interactive configuration setup
@@ -14,14 +22,26 @@ type ConfigWizard struct {
 	awsProfiles []AWSProfile
 	k8sContexts []KubernetesContext
 	reader      *bufio.Reader
+	out         io.Writer
+	prompter    *prompt.Prompter
 	addNewOnly  bool // If true, only configure new profiles
+	dryRun      bool // If true, print the resulting YAML instead of saving
 }
 
 // NewConfigWizard creates a new configuration wizard
 func NewConfigWizard() *ConfigWizard {
+	return newConfigWizard(bufio.NewReader(os.Stdin), prompt.Output())
+}
+
+// newConfigWizard creates a wizard reading answers from reader and writing
+// to out
+func newConfigWizard(reader *bufio.Reader, out io.Writer) *ConfigWizard {
+	out = a11y.Writer(out)
 	return &ConfigWizard{
-		config: DefaultFancyConfig(),
-		reader: bufio.NewReader(os.Stdin),
+		config:   DefaultFancyConfig(),
+		reader:   reader,
+		out:      out,
+		prompter: prompt.NewPrompter(reader, out, nil, nil),
 	}
 }
 
@@ -32,34 +52,42 @@ func NewConfigWizardWithMode(addNewOnly bool) *ConfigWizard {
 	return wizard
 }
 
+// SetDryRun makes the wizard print the resulting configuration instead of
+// saving it. Nothing is written, not even the backup before an override.
+func (w *ConfigWizard) SetDryRun(dryRun bool) {
+	w.dryRun = dryRun
+}
+
 // Run executes the configuration wizard
 func (w *ConfigWizard) Run() error {
-	fmt.Printf("%s🎯 Fancy Login Configuration Wizard%s\n", Yellow+Bold, Reset)
-	fmt.Printf("%s========================================%s\n\n", Yellow, Reset)
+	fmt.Fprintf(w.out, "%s🎯 Fancy Login Configuration Wizard%s\n", Heading+Bold, Reset)
+	fmt.Fprintf(w.out, "%s========================================%s\n\n", Muted, Reset)
 
 	// Try to load existing configuration
 	existingConfig, err := LoadFancyConfig()
+	if err == nil {
+		w.prompter = prompt.NewPrompter(w.reader, w.out,
+			existingConfig.Settings.AffirmativeAnswers, existingConfig.Settings.NegativeAnswers)
+	}
 	if err == nil && len(existingConfig.ProfileConfigs) > 0 {
-		fmt.Printf("%s📋 Found existing configuration with %d profiles%s\n", Cyan, len(existingConfig.ProfileConfigs), Reset)
-		fmt.Printf("Configuration mode:\n")
-		fmt.Printf("  1. Override all (reconfigure all profiles)\n")
-		fmt.Printf("  2. Add new profiles only (keep existing, add new ones)\n")
-		fmt.Printf("Choice [2]: ")
-
-		choice := w.readInput()
-		if choice == "1" {
-			fmt.Printf("%s⚠️  This will replace your existing configuration!%s\n", Yellow, Reset)
-			fmt.Printf("Are you sure? [y/N]: ")
-			confirm := w.readInput()
-			if confirm == "" || strings.ToLower(confirm)[0] != 'y' {
-				w.addNewOnly = true
-				w.config = existingConfig
+		fmt.Fprintf(w.out, "%s📋 Found existing configuration with %d profiles%s\n", Accent, len(existingConfig.ProfileConfigs), Reset)
+		fmt.Fprintf(w.out, "Configuration mode:\n")
+		fmt.Fprintf(w.out, "  1. Override all (reconfigure all profiles)\n")
+		fmt.Fprintf(w.out, "  2. Add new profiles only (keep existing, add new ones)\n")
+		override := false
+		if w.prompter.AskLine("Choice", "2") == "1" {
+			if override, err = w.confirmOverride(existingConfig); err != nil {
+				return err
 			}
+		}
+		if override {
+			// The wizard doesn't manage kube-only profiles, so keep them
+			w.config.KubeOnlyProfiles = existingConfig.KubeOnlyProfiles
 		} else {
 			w.addNewOnly = true
 			w.config = existingConfig
 		}
-		fmt.Println()
+		fmt.Fprintln(w.out)
 	}
 
 	// Load existing configurations
@@ -83,40 +111,74 @@ func (w *ConfigWizard) Run() error {
 		return fmt.Errorf("failed to save configuration: %w", err)
 	}
 
-	fmt.Printf("\n%s✅ Configuration wizard completed successfully!%s\n", Green+Bold, Reset)
-	fmt.Printf("%sConfiguration saved to: %s%s\n", Green, GetFancyConfigPath(), Reset)
+	if w.dryRun {
+		return nil
+	}
+	fmt.Fprintf(w.out, "\n%s✅ Configuration wizard completed successfully!%s\n", Success+Bold, Reset)
+	fmt.Fprintf(w.out, "%sConfiguration saved to: %s%s\n", Success, GetFancyConfigPath(), Reset)
 
 	return nil
 }
 
+// confirmOverride lists the profiles an override would discard and asks the
+// user to type "override". The current file is backed up before the wizard
+// goes on; a failed backup aborts instead of risking the configuration.
+func (w *ConfigWizard) confirmOverride(existing *FancyConfig) (bool, error) {
+	names := make([]string, 0, len(existing.ProfileConfigs))
+	for name := range existing.ProfileConfigs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(w.out, "%s⚠️  This discards the configuration of %d profiles:%s\n", Warning, len(names), Reset)
+	for _, name := range names {
+		fmt.Fprintf(w.out, "  • %s\n", name)
+	}
+	if !w.prompter.ConfirmTyped("Discard them and reconfigure all profiles?", "override") {
+		fmt.Fprintf(w.out, "Keeping the existing profiles; only new ones will be configured.\n")
+		return false, nil
+	}
+
+	if w.dryRun {
+		fmt.Fprintf(w.out, "%sDry run: no backup written.%s\n", Muted, Reset)
+		return true, nil
+	}
+	backup, err := BackupFancyConfig(time.Now())
+	if err != nil {
+		return false, fmt.Errorf("failed to back up the configuration, nothing was changed: %w", err)
+	}
+	fmt.Fprintf(w.out, "%s💾 Backed up the current configuration to %s%s\n", Success, backup, Reset)
+	return true, nil
+}
+
 // discoverConfigurations discovers existing AWS and Kubernetes configurations
 func (w *ConfigWizard) discoverConfigurations() error {
-	fmt.Printf("%s🔍 Discovering existing configurations...%s\n\n", Cyan, Reset)
+	fmt.Fprintf(w.out, "%s🔍 Discovering existing configurations...%s\n\n", Accent, Reset)
 
 	// Discover AWS profiles
 	awsConfigPath := GetAWSConfigPath()
-	fmt.Printf("Looking for AWS config at: %s\n", awsConfigPath)
+	fmt.Fprintf(w.out, "Looking for AWS config at: %s\n", awsConfigPath)
 
-	profiles, err := ParseAWSProfiles(awsConfigPath)
+	profiles, err := LoadAWSProfiles()
 	if err != nil {
-		fmt.Printf("%s⚠️  Warning: Could not parse AWS config: %v%s\n", Yellow, err, Reset)
+		fmt.Fprintf(w.out, "%s⚠️  Warning: Could not parse AWS config: %v%s\n", Warning, err, Reset)
 		w.awsProfiles = []AWSProfile{}
 	} else {
 		w.awsProfiles = profiles
-		fmt.Printf("%s✅ Found %d AWS profiles%s\n", Green, len(profiles), Reset)
+		fmt.Fprintf(w.out, "%s✅ Found %d AWS profiles%s\n", Success, len(profiles), Reset)
 	}
 
 	// Discover Kubernetes contexts
 	kubeConfigPath := GetKubeConfigPath()
-	fmt.Printf("Looking for Kubernetes config at: %s\n", kubeConfigPath)
+	fmt.Fprintf(w.out, "Looking for Kubernetes config at: %s\n", kubeConfigPath)
 
 	contexts, err := ParseKubernetesContexts(kubeConfigPath)
 	if err != nil {
-		fmt.Printf("%s⚠️  Warning: Could not parse Kubernetes config: %v%s\n", Yellow, err, Reset)
+		fmt.Fprintf(w.out, "%s⚠️  Warning: Could not parse Kubernetes config: %v%s\n", Warning, err, Reset)
 		w.k8sContexts = []KubernetesContext{}
 	} else {
 		w.k8sContexts = contexts
-		fmt.Printf("%s✅ Found %d Kubernetes contexts%s\n", Green, len(contexts), Reset)
+		fmt.Fprintf(w.out, "%s✅ Found %d Kubernetes contexts%s\n", Success, len(contexts), Reset)
 	}
 
 	return nil
@@ -124,17 +186,14 @@ func (w *ConfigWizard) discoverConfigurations() error {
 
 // showDiscoveredConfigurations displays what was found
 func (w *ConfigWizard) showDiscoveredConfigurations() {
-	fmt.Printf("\n%s📋 Discovered Configurations:%s\n", Cyan+Bold, Reset)
-	fmt.Printf("%s================================%s\n\n", Cyan, Reset)
+	fmt.Fprintf(w.out, "\n%s📋 Discovered Configurations:%s\n", Accent+Bold, Reset)
+	fmt.Fprintf(w.out, "%s================================%s\n\n", Muted, Reset)
 
 	// Show AWS profiles
 	if len(w.awsProfiles) > 0 {
-		fmt.Printf("%sAWS Profiles:%s\n", Yellow+Bold, Reset)
+		fmt.Fprintf(w.out, "%sAWS Profiles:%s\n", Heading+Bold, Reset)
 		for i, profile := range w.awsProfiles {
-			status := "Standard"
-			if profile.IsSSO {
-				status = "SSO"
-			}
+			status := profile.Type()
 			accountInfo := "Unknown Account"
 			if profile.AccountID != "" {
 				accountInfo = fmt.Sprintf("Account: %s", profile.AccountID)
@@ -143,35 +202,35 @@ func (w *ConfigWizard) showDiscoveredConfigurations() {
 			// Show if profile is already configured
 			configStatus := ""
 			if _, exists := w.config.ProfileConfigs[profile.Name]; exists {
-				configStatus = fmt.Sprintf(" %s[Configured]%s", Green, Reset)
+				configStatus = fmt.Sprintf(" %s[Configured]%s", Success, Reset)
 			}
 
-			fmt.Printf("  %d. %s (%s, %s)%s\n", i+1, profile.Name, status, accountInfo, configStatus)
+			fmt.Fprintf(w.out, "  %d. %s (%s, %s)%s\n", i+1, profile.Name, status, accountInfo, configStatus)
 		}
-		fmt.Println()
+		fmt.Fprintln(w.out)
 	}
 
 	// Show Kubernetes contexts
 	if len(w.k8sContexts) > 0 {
-		fmt.Printf("%sKubernetes Contexts:%s\n", Yellow+Bold, Reset)
+		fmt.Fprintf(w.out, "%sKubernetes Contexts:%s\n", Heading+Bold, Reset)
 		for i, ctx := range w.k8sContexts {
 			namespace := "default"
 			if ctx.Namespace != "" {
 				namespace = ctx.Namespace
 			}
-			fmt.Printf("  %d. %s (Cluster: %s, Namespace: %s)\n", i+1, ctx.Name, ctx.Cluster, namespace)
+			fmt.Fprintf(w.out, "  %d. %s (Cluster: %s, Namespace: %s)\n", i+1, ctx.Name, ctx.Cluster, namespace)
 		}
-		fmt.Println()
+		fmt.Fprintln(w.out)
 	}
 }
 
 // configureProfiles configures each AWS profile individually
 func (w *ConfigWizard) configureProfiles() error {
-	fmt.Printf("%s🔗 Configuring AWS Profiles%s\n", Cyan+Bold, Reset)
-	fmt.Printf("%s========================%s\n\n", Cyan, Reset)
+	fmt.Fprintf(w.out, "%s🔗 Configuring AWS Profiles%s\n", Accent+Bold, Reset)
+	fmt.Fprintf(w.out, "%s========================%s\n\n", Muted, Reset)
 
 	if len(w.awsProfiles) == 0 {
-		fmt.Printf("%s⚠️  No AWS profiles found. You can configure profiles manually later.%s\n\n", Yellow, Reset)
+		fmt.Fprintf(w.out, "%s⚠️  No AWS profiles found. You can configure profiles manually later.%s\n\n", Warning, Reset)
 		return nil
 	}
 
@@ -190,47 +249,50 @@ func (w *ConfigWizard) configureProfiles() error {
 		profilesToConfigure = newProfiles
 
 		if existingCount > 0 {
-			fmt.Printf("%s📋 Skipping %d existing profiles%s\n", Cyan, existingCount, Reset)
+			fmt.Fprintf(w.out, "%s📋 Skipping %d existing profiles%s\n", Accent, existingCount, Reset)
 		}
 		if len(newProfiles) == 0 {
-			fmt.Printf("%s✅ No new profiles found. All profiles are already configured.%s\n\n", Green, Reset)
+			fmt.Fprintf(w.out, "%s✅ No new profiles found. All profiles are already configured.%s\n\n", Success, Reset)
 			return nil
 		}
-		fmt.Printf("%s🆕 Found %d new profiles to configure%s\n\n", Green, len(newProfiles), Reset)
+		fmt.Fprintf(w.out, "%s🆕 Found %d new profiles to configure%s\n\n", Success, len(newProfiles), Reset)
 	}
 
-	fmt.Printf("Let's configure %s profiles. This determines:\n",
+	fmt.Fprintf(w.out, "Let's configure %s profiles. This determines:\n",
 		func() string {
 			if w.addNewOnly {
 				return "new"
 			}
 			return "each"
 		}())
-	fmt.Printf("  • Whether to auto-login to ECR\n")
-	fmt.Printf("  • Which Kubernetes context to use\n")
-	fmt.Printf("  • Whether to auto-launch K9s\n\n")
+	fmt.Fprintf(w.out, "  • Whether to auto-login to ECR\n")
+	fmt.Fprintf(w.out, "  • Which Kubernetes context to use\n")
+	fmt.Fprintf(w.out, "  • Whether to auto-launch K9s\n\n")
 
 	for i, profile := range profilesToConfigure {
-		fmt.Printf("%s📝 Configuring Profile %d/%d: %s%s%s%s\n",
-			Bold, i+1, len(profilesToConfigure), Yellow, profile.Name, Reset, Bold)
-		fmt.Printf("%s%s\n", strings.Repeat("─", 50), Reset)
+		fmt.Fprintf(w.out, "%s📝 Configuring Profile %d/%d: %s%s%s%s\n",
+			Bold, i+1, len(profilesToConfigure), Heading, profile.Name, Reset, Bold)
+		fmt.Fprintf(w.out, "%s%s\n", strings.Repeat("─", 50), Reset)
 
 		if profile.AccountID != "" {
-			fmt.Printf("Account ID: %s%s%s\n", Cyan, profile.AccountID, Reset)
+			fmt.Fprintf(w.out, "Account ID: %s%s%s\n", Accent, profile.AccountID, Reset)
 		}
 		if profile.Region != "" {
-			fmt.Printf("Region: %s%s%s\n", Cyan, profile.Region, Reset)
+			fmt.Fprintf(w.out, "Region: %s%s%s\n", Accent, profile.Region, Reset)
 		}
-		if profile.IsSSO {
-			fmt.Printf("Type: %sSSO Profile%s\n", Green, Reset)
+		switch {
+		case profile.IsSSO:
+			fmt.Fprintf(w.out, "Type: %sSSO Profile%s\n", Success, Reset)
+		case profile.CredentialProcess != "":
+			fmt.Fprintf(w.out, "Type: %sexternal process%s (%s)\n", Success, Reset, profile.CredentialProcess)
+		case profile.StaticCredentials:
+			fmt.Fprintf(w.out, "Type: %sstatic credentials%s (%s)\n", Success, Reset, GetAWSCredentialsPath())
 		}
-		fmt.Println()
+		fmt.Fprintln(w.out)
 
 		// Ask if user wants to configure this profile
-		fmt.Printf("Configure this profile? [Y/n]: ")
-		configure := w.readInput()
-		if configure != "" && strings.ToLower(configure)[0] == 'n' {
-			fmt.Println("Skipping profile.")
+		if !w.prompter.Confirm("Configure this profile?", true) {
+			fmt.Fprintln(w.out, "Skipping profile.")
 			continue
 		}
 
@@ -241,41 +303,24 @@ func (w *ConfigWizard) configureProfiles() error {
 		}
 
 		// Store profile configuration directly
-		w.config.ProfileConfigs[profile.Name] = ProfileConfig{
-			Name:          profile.Name,
-			AccountID:     profile.AccountID,
-			ECRLogin:      profileConfig.ECRLogin,
-			ECRRegion:     profileConfig.ECRRegion,
-			K8sContext:    profileConfig.K8sContext,
-			K9sAutoLaunch: profileConfig.K9sAutoLaunch,
-		}
+		profileConfig.AccountID = profile.AccountID
+		w.config.ProfileConfigs[profile.Name] = *profileConfig
 
-		fmt.Printf("%s✅ Profile %s configured%s\n\n", Green, profile.Name, Reset)
+		fmt.Fprintf(w.out, "%s✅ Profile %s configured%s\n\n", Success, profile.Name, Reset)
 	}
 
 	return nil
 }
 
-// ProfileConfiguration holds temporary configuration for a profile during wizard
-type ProfileConfiguration struct {
-	Name          string
-	ECRLogin      bool
-	ECRRegion     string
-	K8sContext    string
-	K9sAutoLaunch bool
-	Namespace     string
-}
-
 // getProfileConfiguration gets configuration for a specific profile
-func (w *ConfigWizard) getProfileConfiguration(profile AWSProfile) (*ProfileConfiguration, error) {
-	config := &ProfileConfiguration{
+func (w *ConfigWizard) getProfileConfiguration(profile AWSProfile) (*ProfileConfig, error) {
+	config := &ProfileConfig{
 		Name: profile.Name,
 	}
+	asked := make(map[string]bool)
 
 	// ECR login
-	fmt.Printf("Enable ECR login for profile %s? [Y/n]: ", profile.Name)
-	ecrInput := w.readInput()
-	config.ECRLogin = ecrInput == "" || strings.ToLower(ecrInput)[0] == 'y'
+	w.askSchemaField(config, "ecr_login", profile.Name, asked)
 
 	// ECR region
 	if config.ECRLogin {
@@ -283,8 +328,7 @@ func (w *ConfigWizard) getProfileConfiguration(profile AWSProfile) (*ProfileConf
 		if profile.Region != "" {
 			defaultRegion = profile.Region
 		}
-		fmt.Printf("ECR region for %s [%s]: ", profile.Name, defaultRegion)
-		region := w.readInput()
+		region := w.prompter.AskLine("ECR region for "+profile.Name, defaultRegion)
 		if region == "" {
 			region = defaultRegion
 		}
@@ -293,14 +337,12 @@ func (w *ConfigWizard) getProfileConfiguration(profile AWSProfile) (*ProfileConf
 
 	// Kubernetes context
 	if len(w.k8sContexts) > 0 {
-		fmt.Printf("Select Kubernetes context for profile %s:\n", profile.Name)
+		fmt.Fprintf(w.out, "Select Kubernetes context for profile %s:\n", profile.Name)
 		for i, ctx := range w.k8sContexts {
-			fmt.Printf("  %d. %s\n", i+1, ctx.Name)
+			fmt.Fprintf(w.out, "  %d. %s\n", i+1, ctx.Name)
 		}
-		fmt.Printf("  0. None\n")
-		fmt.Printf("Choice [0]: ")
-
-		choice := w.readInput()
+		fmt.Fprintf(w.out, "  0. None\n")
+		choice := w.prompter.AskLine("Choice", "0")
 		if choice != "" && choice != "0" {
 			if idx, err := strconv.Atoi(choice); err == nil && idx > 0 && idx <= len(w.k8sContexts) {
 				config.K8sContext = w.k8sContexts[idx-1].Name
@@ -308,33 +350,91 @@ func (w *ConfigWizard) getProfileConfiguration(profile AWSProfile) (*ProfileConf
 		}
 	}
 
+	// Fresh accounts have no context yet, but may have EKS clusters
+	if config.K8sContext == "" && eksClusters != nil &&
+		w.prompter.Confirm(fmt.Sprintf("Look for EKS clusters of %s?", profile.Name), len(w.k8sContexts) == 0) {
+		w.discoverEKSCluster(config, profile.Name, w.eksRegion(config, profile))
+	}
+
 	// K9s auto-launch
-	if config.K8sContext != "" {
-		fmt.Printf("Auto-launch K9s for profile %s? [y/N]: ", profile.Name)
-		k9sInput := w.readInput()
-		config.K9sAutoLaunch = k9sInput != "" && strings.ToLower(k9sInput)[0] == 'y'
-
-		// Kubernetes namespace (optional)
-		if config.K9sAutoLaunch {
-			fmt.Printf("Kubernetes namespace for K9s (optional) [default]: ")
-			namespaceInput := w.readInput()
-			if namespaceInput != "" && namespaceInput != "default" {
-				config.Namespace = namespaceInput
-			}
+	w.askSchemaField(config, "k9s_auto_launch", profile.Name, asked)
+
+	// Kubernetes namespace (optional)
+	if config.K9sAutoLaunch {
+		namespaceInput := w.prompter.AskLine("Kubernetes namespace for K9s (optional)", "default")
+		if namespaceInput != "" && namespaceInput != "default" {
+			config.Namespace = namespaceInput
 		}
+		w.askK9sArgs(config)
+	}
+
+	// Any remaining simple fields come straight from the schema
+	for _, field := range ProfileSchema {
+		w.askSchemaField(config, field.Key, profile.Name, asked)
 	}
 
 	return config, nil
 }
 
+// askK9sArgs asks for extra k9s arguments, quoted as in a shell so an
+// argument may contain spaces
+func (w *ConfigWizard) askK9sArgs(pc *ProfileConfig) {
+	for {
+		input := w.prompter.AskLine("Extra k9s arguments, e.g. --command pods --refresh 5 (optional)", "")
+		if input == "" {
+			return
+		}
+		args, err := SplitCommand(input)
+		if err != nil {
+			fmt.Fprintf(w.out, "%s⚠️  k9s_args: %v%s\n", Warning, err, Reset)
+			continue
+		}
+		pc.K9sArgs = args
+		return
+	}
+}
+
+// askSchemaField prompts for a schema field that declares a wizard prompt
+func (w *ConfigWizard) askSchemaField(pc *ProfileConfig, key, profileName string, asked map[string]bool) {
+	field, err := SchemaFieldByKey(key)
+	if err != nil || field.Prompt == "" || asked[key] {
+		return
+	}
+	asked[key] = true
+	if field.When != nil && !field.When(pc) {
+		return
+	}
+
+	question := fmt.Sprintf(field.Prompt, profileName)
+	for {
+		var value string
+		if field.Type == FieldBool {
+			value = strconv.FormatBool(w.prompter.Confirm(question, field.Default == "true"))
+		} else {
+			value = w.prompter.AskLine(question, field.Default)
+			if value == "" {
+				value = field.Default
+			}
+		}
+		if value == "" {
+			return
+		}
+
+		if err := SetProfileField(pc, key, value); err != nil {
+			fmt.Fprintf(w.out, "%s⚠️  %v%s\n", Warning, err, Reset)
+			continue
+		}
+		return
+	}
+}
+
 // configureGlobalSettings configures global settings
 func (w *ConfigWizard) configureGlobalSettings() {
-	fmt.Printf("%s⚙️  Global Settings%s\n", Cyan+Bold, Reset)
-	fmt.Printf("%s================%s\n\n", Cyan, Reset)
+	fmt.Fprintf(w.out, "%s⚙️  Global Settings%s\n", Accent+Bold, Reset)
+	fmt.Fprintf(w.out, "%s================%s\n\n", Muted, Reset)
 
 	// Default region
-	fmt.Printf("Default AWS region [%s]: ", w.config.Settings.DefaultRegion)
-	region := w.readInput()
+	region := w.prompter.AskLine("Default AWS region", w.config.Settings.DefaultRegion)
 	if region != "" {
 		w.config.Settings.DefaultRegion = region
 	}
@@ -345,30 +445,33 @@ func (w *ConfigWizard) configureGlobalSettings() {
 
 // saveConfiguration saves the configuration
 func (w *ConfigWizard) saveConfiguration() error {
-	fmt.Printf("%s💾 Saving Configuration%s\n", Cyan+Bold, Reset)
-	fmt.Printf("%s===================%s\n\n", Cyan, Reset)
+	fmt.Fprintf(w.out, "%s💾 Saving Configuration%s\n", Accent+Bold, Reset)
+	fmt.Fprintf(w.out, "%s===================%s\n\n", Muted, Reset)
 
-	configPath := GetFancyConfigPath()
-	fmt.Printf("Save configuration to: %s\n", configPath)
-	fmt.Printf("Proceed? [Y/n]: ")
+	if w.dryRun {
+		data, err := yaml.Marshal(w.config)
+		if err != nil {
+			return fmt.Errorf("failed to marshal config: %w", err)
+		}
+		fmt.Fprintf(w.out, "%sDry run, nothing saved. The configuration would be:%s\n\n", Muted, Reset)
+		_, err = w.out.Write(data)
+		return err
+	}
 
-	confirm := w.readInput()
-	if confirm != "" && strings.ToLower(confirm)[0] == 'n' {
+	configPath := GetFancyConfigPath()
+	fmt.Fprintf(w.out, "Save configuration to: %s\n", configPath)
+	if !w.prompter.Confirm("Proceed?", true) {
 		return fmt.Errorf("configuration save cancelled")
 	}
 
 	return w.config.SaveFancyConfig()
 }
 
-// readInput reads a line of input from the user
-func (w *ConfigWizard) readInput() string {
-	input, _ := w.reader.ReadString('\n')
-	return strings.TrimSpace(input)
-}
-
-// RunConfigWizardIfNeeded runs the config wizard if configuration doesn't exist or hasn't been run
-func RunConfigWizardIfNeeded() error {
-	config, err := LoadFancyConfig()
+// RunConfigWizardIfNeeded runs the config wizard if configuration doesn't
+// exist or hasn't been run. The config is read from snapshot, which is
+// reloaded when the wizard changed it.
+func RunConfigWizardIfNeeded(snapshot *Snapshot) error {
+	config, err := snapshot.FancyConfig()
 	if err != nil {
 		return err
 	}
@@ -382,19 +485,63 @@ func RunConfigWizardIfNeeded() error {
 	configPath := GetFancyConfigPath()
 	if _, err := os.Stat(configPath); err == nil {
 		// Config exists but wizard hasn't been marked as run
-		fmt.Printf("%s⚠️  Configuration file exists but wizard hasn't been completed.%s\n", Yellow, Reset)
-		fmt.Printf("Run configuration wizard to update settings? [y/N]: ")
-
-		reader := bufio.NewReader(os.Stdin)
-		input, _ := reader.ReadString('\n')
-		if strings.ToLower(strings.TrimSpace(input))[0] != 'y' {
+		fmt.Fprintf(a11y.Writer(prompt.Output()), "%s⚠️  Configuration file exists but wizard hasn't been completed.%s\n", Warning, Reset)
+		prompter := prompt.NewPrompter(bufio.NewReader(os.Stdin), prompt.Output(),
+			config.Settings.AffirmativeAnswers, config.Settings.NegativeAnswers)
+		if !prompter.Confirm("Run configuration wizard to update settings?", false) {
 			return nil
 		}
 	}
 
 	// Run the wizard
 	wizard := NewConfigWizard()
-	return wizard.Run()
+	if err := wizard.Run(); err != nil {
+		return err
+	}
+	snapshot.Reload()
+	return nil
+}
+
+// RunProfileWizard configures a single, newly created AWS profile and saves
+// it into fc, so a running login flow picks up the result immediately
+func RunProfileWizard(fc *FancyConfig, profileName string) error {
+	wizard := NewConfigWizard()
+	wizard.config = fc
+	wizard.prompter = prompt.NewPrompter(wizard.reader, wizard.out,
+		fc.Settings.AffirmativeAnswers, fc.Settings.NegativeAnswers)
+
+	profiles, err := LoadAWSProfiles()
+	if err != nil {
+		return err
+	}
+	var profile *AWSProfile
+	for i := range profiles {
+		if profiles[i].Name == profileName {
+			profile = &profiles[i]
+			break
+		}
+	}
+	if profile == nil {
+		return fmt.Errorf("profile %s not found in %s", profileName, GetAWSConfigPath())
+	}
+
+	if contexts, err := ParseKubernetesContexts(GetKubeConfigPath()); err == nil {
+		wizard.k8sContexts = contexts
+	}
+
+	fmt.Fprintf(wizard.out, "\n%s📝 Configuring Profile: %s%s%s\n", Bold, Heading, profile.Name, Reset)
+	profileConfig, err := wizard.getProfileConfiguration(*profile)
+	if err != nil {
+		return err
+	}
+	profileConfig.AccountID = profile.AccountID
+	if fc.ProfileConfigs == nil {
+		fc.ProfileConfigs = make(map[string]ProfileConfig)
+	}
+	fc.ProfileConfigs[profile.Name] = *profileConfig
+	fc.Settings.ConfigWizardRun = true
+
+	return wizard.saveConfiguration()
 }
 
 // RunConfigWizard explicitly runs the configuration wizard
@@ -0,0 +1,219 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setupWizardTest writes an AWS config with two unconfigured profiles and a
+// fancy config with two configured ones, and returns the fancy config path
+func setupWizardTest(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("AWS_CONFIG_FILE", "")
+	t.Setenv("KUBECONFIG", filepath.Join(home, "missing"))
+
+	if err := os.MkdirAll(filepath.Join(home, ".aws"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	awsConfig := "[profile new-a]\nregion = eu-west-1\n\n[profile new-b]\nregion = eu-west-1\n"
+	if err := os.WriteFile(filepath.Join(home, ".aws", "config"), []byte(awsConfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	fc := DefaultFancyConfig()
+	fc.ProfileConfigs["old-dev"] = ProfileConfig{Name: "Old Dev", K8sContext: "dev-cluster"}
+	fc.ProfileConfigs["old-prod"] = ProfileConfig{Name: "Old Prod", K8sContext: "prod-cluster"}
+	fc.Settings.ConfigWizardRun = true
+	if err := fc.SaveFancyConfig(); err != nil {
+		t.Fatal(err)
+	}
+	return GetFancyConfigPath()
+}
+
+// backups returns the backup files written next to configPath
+func backups(t *testing.T, configPath string) []string {
+	t.Helper()
+	matches, err := filepath.Glob(configPath + ".*.bak")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return matches
+}
+
+func TestWizardOverrideRequiresTypedConfirmation(t *testing.T) {
+	testCases := []struct {
+		name            string
+		confirmation    string
+		expectOverride  bool
+		expectedBackups int
+	}{
+		{"Typed word overrides", "override", true, 1},
+		{"Single letter keeps profiles", "y", false, 0},
+		{"Yes keeps profiles", "yes", false, 0},
+		{"Empty input keeps profiles", "", false, 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			configPath := setupWizardTest(t)
+			original, _ := os.ReadFile(configPath)
+
+			// mode, confirmation, skip both AWS profiles, keep region, save
+			input := "1\n" + tc.confirmation + "\nn\nn\n\ny\n"
+			var out bytes.Buffer
+			wizard := newConfigWizard(bufio.NewReader(strings.NewReader(input)), &out)
+			if err := wizard.Run(); err != nil {
+				t.Fatalf("Run failed: %v\n%s", err, out.String())
+			}
+
+			for _, expected := range []string{"discards the configuration of 2 profiles", "old-dev", "old-prod", "type 'override' to confirm"} {
+				if !strings.Contains(out.String(), expected) {
+					t.Errorf("Expected the warning to contain %q, got:\n%s", expected, out.String())
+				}
+			}
+
+			saved, err := LoadFancyConfig()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, kept := saved.ProfileConfigs["old-dev"]; kept == tc.expectOverride {
+				t.Errorf("Expected override=%v, got profiles %v", tc.expectOverride, saved.ProfileConfigs)
+			}
+
+			files := backups(t, configPath)
+			if len(files) != tc.expectedBackups {
+				t.Fatalf("Expected %d backups, got %v", tc.expectedBackups, files)
+			}
+			if len(files) == 1 {
+				if backup, _ := os.ReadFile(files[0]); !bytes.Equal(backup, original) {
+					t.Errorf("Expected the backup to hold the original configuration, got:\n%s", backup)
+				}
+			}
+		})
+	}
+}
+
+func TestWizardDryRun(t *testing.T) {
+	configPath := setupWizardTest(t)
+	original, _ := os.ReadFile(configPath)
+
+	// mode, confirmation, skip both AWS profiles, new region; no save prompt
+	input := "1\noverride\nn\nn\nus-east-2\n"
+	var out bytes.Buffer
+	wizard := newConfigWizard(bufio.NewReader(strings.NewReader(input)), &out)
+	wizard.SetDryRun(true)
+	if err := wizard.Run(); err != nil {
+		t.Fatalf("Run failed: %v\n%s", err, out.String())
+	}
+
+	if current, _ := os.ReadFile(configPath); !bytes.Equal(current, original) {
+		t.Errorf("Expected the configuration file to be unchanged, got:\n%s", current)
+	}
+	if files := backups(t, configPath); len(files) != 0 {
+		t.Errorf("Expected no backup in a dry run, got %v", files)
+	}
+
+	_, yamlOutput, found := strings.Cut(out.String(), "The configuration would be:")
+	if !found {
+		t.Fatalf("Expected the resulting YAML, got:\n%s", out.String())
+	}
+	if !strings.Contains(yamlOutput, "default_region: us-east-2") {
+		t.Errorf("Expected the new region in the YAML, got:\n%s", yamlOutput)
+	}
+	if strings.Contains(yamlOutput, "old-dev") {
+		t.Errorf("Expected the discarded profiles to be missing, got:\n%s", yamlOutput)
+	}
+}
+
+func TestWizardDiscoverEKSCluster(t *testing.T) {
+	var created []string
+	fake := &EKSClusters{
+		List: func(ctx context.Context, timeout time.Duration, profile, region string) ([]string, error) {
+			switch profile {
+			case "denied":
+				return nil, fmt.Errorf("%w: AccessDeniedException", ErrEKSAccessDenied)
+			case "empty":
+				return nil, nil
+			}
+			return []string{"platform", "sandbox"}, nil
+		},
+		Create: func(ctx context.Context, timeout time.Duration, profile string, cluster EKSClusterConfig, alias, kubeconfig string) error {
+			created = append(created, profile+" "+cluster.Name+" "+cluster.Region+" "+alias)
+			return nil
+		},
+	}
+	SetEKSClusters(fake)
+	t.Cleanup(func() { SetEKSClusters(nil) })
+
+	testCases := []struct {
+		name     string
+		profile  string
+		input    string
+		expected string
+		output   string
+	}{
+		{"Picked", "dev", "2\nsandbox-dev\n", "sandbox-dev", "Created context sandbox-dev"},
+		{"Skipped", "dev", "0\n", "", "0. Skip"},
+		{"Access denied", "denied", "", "", "eks:ListClusters is denied"},
+		{"No clusters", "empty", "", "", "No EKS clusters in eu-west-1"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			created = nil
+			var out bytes.Buffer
+			wizard := newConfigWizard(bufio.NewReader(strings.NewReader(tc.input)), &out)
+			pc := &ProfileConfig{}
+
+			changed, _ := wizard.discoverEKSCluster(pc, tc.profile, "eu-west-1")
+			if changed != (tc.expected != "") || pc.K8sContext != tc.expected {
+				t.Errorf("Expected context %q, got %q (changed %v)", tc.expected, pc.K8sContext, changed)
+			}
+			if !strings.Contains(out.String(), tc.output) {
+				t.Errorf("Expected %q in the output, got:\n%s", tc.output, out.String())
+			}
+			if tc.expected == "" {
+				return
+			}
+			if len(created) != 1 || created[0] != "dev sandbox eu-west-1 sandbox-dev" {
+				t.Errorf("Expected update-kubeconfig for sandbox, got %v", created)
+			}
+			if pc.EKSCluster != (EKSClusterConfig{Name: "sandbox", Region: "eu-west-1"}) {
+				t.Errorf("Expected eks_cluster sandbox in eu-west-1, got %+v", pc.EKSCluster)
+			}
+		})
+	}
+}
+
+func TestWizardAskK9sArgs(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{"Quoted argument stays whole", "--command pods --logFile '/tmp/k9s {{context}}.log'\n", []string{"--command", "pods", "--logFile", "/tmp/k9s {{context}}.log"}},
+		{"Unterminated quote asks again", "--logFile \"oops\n--readonly\n", []string{"--readonly"}},
+		{"Empty skips", "\n", nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			wizard := newConfigWizard(bufio.NewReader(strings.NewReader(tc.input)), &out)
+			pc := &ProfileConfig{}
+			wizard.askK9sArgs(pc)
+			if strings.Join(pc.K9sArgs, "|") != strings.Join(tc.expected, "|") || len(pc.K9sArgs) != len(tc.expected) {
+				t.Errorf("Expected %q, got %q", tc.expected, pc.K9sArgs)
+			}
+		})
+	}
+}
@@ -0,0 +1,325 @@
+// Package doctor checks the tools and files fancy-login depends on, so a
+// missing dependency is reported up front with a hint on installing it
+// instead of failing halfway through a login.
+package doctor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"fancy-login/internal/config"
+	"fancy-login/internal/platform"
+)
+
+// Status is the outcome of a check
+type Status int
+
+const (
+	Pass Status = iota
+	Warn
+	Fail
+)
+
+func (s Status) String() string {
+	switch s {
+	case Pass:
+		return "pass"
+	case Warn:
+		return "warn"
+	}
+	return "fail"
+}
+
+// Env is what checks look at; tests replace the tools and terminal
+type Env struct {
+	GOOS string
+	WSL  bool
+	// LookPath finds a tool on PATH
+	LookPath func(name string) (string, error)
+	// Output runs a tool and returns its combined output
+	Output func(ctx context.Context, name string, args ...string) ([]byte, error)
+	// OpenTTY opens the terminal prompts and pickers use
+	OpenTTY func() (io.Closer, error)
+}
+
+// DefaultEnv is the real host
+func DefaultEnv() *Env {
+	return &Env{
+		GOOS:     runtime.GOOS,
+		WSL:      platform.IsWSL(),
+		LookPath: exec.LookPath,
+		Output: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			return exec.CommandContext(ctx, name, args...).CombinedOutput()
+		},
+		OpenTTY: func() (io.Closer, error) { return platform.OpenTTY() },
+	}
+}
+
+// Check is one row of the check table. A failing check is a Fail if it is
+// Required and a Warn otherwise.
+type Check struct {
+	Name     string
+	Required bool
+	// Run returns a short detail, e.g. the version found, or why it failed
+	Run func(env *Env) (string, error)
+	// Hint tells how to fix a failure on the given platform
+	Hint func(env *Env) []string
+}
+
+// Result is the outcome of one check
+type Result struct {
+	Name   string
+	Status Status
+	Detail string
+	Hints  []string
+}
+
+// versionTimeout bounds each `--version` call; a tool that hangs is
+// reported rather than hanging doctor
+const versionTimeout = 5 * time.Second
+
+// Checks are run by `fancy-login-go doctor`, in order. A new check only
+// needs an entry here.
+var Checks = []Check{
+	toolCheck("aws", true, []string{"--version"}, awsHint),
+	// Without fzf the pickers fall back to a built-in menu
+	toolCheck("fzf", false, []string{"--version"}, installHint(map[string]string{
+		"darwin":  "brew install fzf",
+		"linux":   "sudo apt install fzf",
+		"windows": "winget install -e --id junegunn.fzf",
+	})),
+	// Contexts are switched without kubectl unless kube_client is kubectl
+	toolCheck("kubectl", false, []string{"version", "--client"}, installHint(map[string]string{
+		"darwin":  "brew install kubectl",
+		"linux":   "sudo snap install kubectl --classic (or see https://kubernetes.io/docs/tasks/tools/)",
+		"windows": "winget install -e --id Kubernetes.kubectl",
+	})),
+	toolCheck("docker", false, []string{"--version"}, installHint(map[string]string{
+		"darwin":  "brew install --cask docker",
+		"linux":   "sudo apt install docker.io",
+		"windows": "winget install -e --id Docker.DockerDesktop",
+	})),
+	{
+		Name: "k9s",
+		Run:  checkK9s,
+		Hint: func(env *Env) []string {
+			return []string{"Install with: " + platform.K9sInstallHintFor(env.GOOS, env.WSL)}
+		},
+	},
+	{
+		Name: "aws-vault",
+		Run:  checkAWSVault,
+		Hint: installHint(map[string]string{
+			"darwin":  "brew install --cask aws-vault",
+			"linux":   "download a release from https://github.com/99designs/aws-vault/releases",
+			"windows": "winget install -e --id 99designs.aws-vault",
+		}),
+	},
+	{
+		Name:     "AWS config",
+		Required: true,
+		Run:      func(env *Env) (string, error) { return checkReadable(config.GetAWSConfigPath()) },
+		Hint: func(env *Env) []string {
+			return []string{"Create profiles with: aws configure sso"}
+		},
+	},
+	{
+		Name: "kubeconfig",
+		Run: func(env *Env) (string, error) {
+			var details []string
+			for _, path := range filepath.SplitList(config.GetKubeConfigPath()) {
+				detail, err := checkReadable(path)
+				if err != nil {
+					return "", err
+				}
+				details = append(details, detail)
+			}
+			return strings.Join(details, ", "), nil
+		},
+		Hint: func(env *Env) []string {
+			return []string{"Add an EKS cluster with: aws eks update-kubeconfig --name CLUSTER --profile PROFILE"}
+		},
+	},
+	{
+		Name:     "fancy-login config",
+		Required: true,
+		Run:      checkFancyConfig,
+		Hint: func(env *Env) []string {
+			return []string{"Fix the YAML, or start over with: fancy-login-go config"}
+		},
+	},
+	{
+		Name: "terminal",
+		Run: func(env *Env) (string, error) {
+			tty, err := env.OpenTTY()
+			if err != nil {
+				return "", err
+			}
+			tty.Close()
+			return "prompts and pickers can read from the terminal", nil
+		},
+		Hint: func(env *Env) []string {
+			return []string{"Run fancy-login from an interactive terminal, or pass --profile and --context to avoid prompts"}
+		},
+	},
+}
+
+// Run runs checks in order
+func Run(env *Env, checks []Check) []Result {
+	results := make([]Result, 0, len(checks))
+	for _, check := range checks {
+		detail, err := check.Run(env)
+		result := Result{Name: check.Name, Status: Pass, Detail: detail}
+		if err != nil {
+			result.Status = Warn
+			if check.Required {
+				result.Status = Fail
+			}
+			result.Detail = err.Error()
+			if check.Hint != nil {
+				result.Hints = check.Hint(env)
+			}
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// Failed reports whether a required check failed
+func Failed(results []Result) bool {
+	for _, r := range results {
+		if r.Status == Fail {
+			return true
+		}
+	}
+	return false
+}
+
+// toolCheck finds name on PATH and reports the first line of its version
+// output. A tool that is found but can't report a version still passes;
+// only a missing tool fails.
+func toolCheck(name string, required bool, versionArgs []string, hint func(env *Env) []string) Check {
+	return Check{
+		Name:     name,
+		Required: required,
+		Run: func(env *Env) (string, error) {
+			path, err := env.LookPath(name)
+			if err != nil {
+				return "", errors.New("not found on PATH")
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), versionTimeout)
+			defer cancel()
+			output, err := env.Output(ctx, path, versionArgs...)
+			if err != nil {
+				return fmt.Sprintf("%s (version unknown: %v)", path, err), nil
+			}
+			version, _, _ := strings.Cut(strings.TrimSpace(string(output)), "\n")
+			return version, nil
+		},
+		Hint: hint,
+	}
+}
+
+// installHint returns the install command for the platform; WSL uses the
+// Linux one, since fancy-login runs inside WSL
+func installHint(commands map[string]string) func(env *Env) []string {
+	return func(env *Env) []string {
+		goos := env.GOOS
+		if env.WSL {
+			goos = "linux"
+		}
+		if command, ok := commands[goos]; ok {
+			return []string{"Install with: " + command}
+		}
+		return []string{"Install with: " + commands["linux"]}
+	}
+}
+
+// awsHint reuses the AWS CLI install instructions of the login flow
+func awsHint(env *Env) []string {
+	return platform.AWSCLIInstallHintFor(env.GOOS, env.WSL)
+}
+
+// checkReadable reads path, which must exist
+func checkReadable(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("%s does not exist", path)
+		}
+		return "", fmt.Errorf("cannot read %s: %w", path, errors.Unwrap(err))
+	}
+	return fmt.Sprintf("%s (%d bytes)", path, len(data)), nil
+}
+
+// checkAWSVault looks for aws-vault like toolCheck, but only profiles with
+// backend: aws-vault need it, so without them a missing one passes
+func checkAWSVault(env *Env) (string, error) {
+	detail, err := toolCheck("aws-vault", false, []string{"--version"}, nil).Run(env)
+	if err == nil {
+		return detail, nil
+	}
+	var profiles []string
+	if fc, loadErr := config.LoadFancyConfig(); loadErr == nil {
+		for name, pc := range fc.ProfileConfigs {
+			if pc.Backend == config.BackendAWSVault {
+				profiles = append(profiles, name)
+			}
+		}
+	}
+	if len(profiles) == 0 {
+		return "not installed, no profile uses backend: aws-vault", nil
+	}
+	sort.Strings(profiles)
+	return "", fmt.Errorf("%s, but used by %s", err, strings.Join(profiles, ", "))
+}
+
+// checkK9s looks for k9s like toolCheck. A missing one names the profiles
+// with k9s_auto_launch, whose logins skip k9s.
+func checkK9s(env *Env) (string, error) {
+	detail, err := toolCheck("k9s", false, []string{"version", "--short"}, nil).Run(env)
+	if err == nil {
+		return detail, nil
+	}
+	var profiles []string
+	if fc, loadErr := config.LoadFancyConfig(); loadErr == nil {
+		for name := range fc.ProfileConfigs {
+			if fc.ShouldAutoLaunchK9s(name) {
+				profiles = append(profiles, name)
+			}
+		}
+		for name := range fc.KubeOnlyProfiles {
+			if fc.IsKubeOnlyProfile(name) && fc.ShouldAutoLaunchK9s(name) {
+				profiles = append(profiles, name)
+			}
+		}
+	}
+	if len(profiles) == 0 {
+		return "", err
+	}
+	sort.Strings(profiles)
+	return "", fmt.Errorf("not installed, but k9s_auto_launch is on for %s", strings.Join(profiles, ", "))
+}
+
+// checkFancyConfig parses the fancy-login config. A missing file is fine:
+// the defaults apply until the wizard writes one.
+func checkFancyConfig(env *Env) (string, error) {
+	path := config.GetFancyConfigPath()
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return fmt.Sprintf("%s not found, using defaults", path), nil
+	}
+	fc, err := config.LoadFancyConfig()
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s (%d profiles)", path, len(fc.ProfileConfigs)), nil
+}
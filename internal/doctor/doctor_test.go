@@ -0,0 +1,228 @@
+package doctor
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
+
+// fakeEnv has the named tools installed, each printing "<name> 1.0\nextra"
+func fakeEnv(goos string, tools ...string) *Env {
+	installed := map[string]bool{}
+	for _, tool := range tools {
+		installed[tool] = true
+	}
+	return &Env{
+		GOOS: goos,
+		LookPath: func(name string) (string, error) {
+			if installed[name] {
+				return "/usr/bin/" + name, nil
+			}
+			return "", errors.New("not found")
+		},
+		Output: func(ctx context.Context, name string, args ...string) ([]byte, error) {
+			return []byte(filepath.Base(name) + " 1.0\nextra"), nil
+		},
+		OpenTTY: func() (io.Closer, error) { return nopCloser{}, nil },
+	}
+}
+
+func setupFiles(t *testing.T) string {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	t.Setenv("AWS_CONFIG_FILE", "")
+	t.Setenv("KUBECONFIG", "")
+	for _, path := range []string{".aws/config", ".kube/config"} {
+		full := filepath.Join(home, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, []byte("# test\n"), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return home
+}
+
+func findResult(t *testing.T, results []Result, name string) Result {
+	t.Helper()
+	for _, r := range results {
+		if r.Name == name {
+			return r
+		}
+	}
+	t.Fatalf("Expected a result for %s", name)
+	return Result{}
+}
+
+func TestRun(t *testing.T) {
+	setupFiles(t)
+	env := fakeEnv("linux", "aws", "fzf", "kubectl", "docker", "k9s")
+
+	results := Run(env, Checks)
+	if len(results) != len(Checks) {
+		t.Fatalf("Expected %d results, got %d", len(Checks), len(results))
+	}
+	for _, r := range results {
+		if r.Status != Pass {
+			t.Errorf("Expected %s to pass, got %s: %s", r.Name, r.Status, r.Detail)
+		}
+	}
+	if detail := findResult(t, results, "kubectl").Detail; detail != "kubectl 1.0" {
+		t.Errorf("Expected first line of the version output, got %q", detail)
+	}
+	if Failed(results) {
+		t.Error("Expected no failed checks")
+	}
+}
+
+func TestRunMissingTools(t *testing.T) {
+	setupFiles(t)
+
+	tests := []struct {
+		name      string
+		goos      string
+		missing   string
+		status    Status
+		hint      string
+		wantFails bool
+	}{
+		{"fzf on macOS", "darwin", "fzf", Warn, "brew install fzf", false},
+		{"fzf on Linux", "linux", "fzf", Warn, "sudo apt install fzf", false},
+		{"kubectl is optional", "windows", "kubectl", Warn, "winget install -e --id Kubernetes.kubectl", false},
+		{"docker is optional", "linux", "docker", Warn, "sudo apt install docker.io", false},
+		{"k9s is optional", "darwin", "k9s", Warn, "brew install derailed/k9s/k9s", false},
+		{"aws on macOS", "darwin", "aws", Fail, "brew install awscli", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var tools []string
+			for _, tool := range []string{"aws", "fzf", "kubectl", "docker", "k9s"} {
+				if tool != tt.missing {
+					tools = append(tools, tool)
+				}
+			}
+			results := Run(fakeEnv(tt.goos, tools...), Checks)
+
+			r := findResult(t, results, tt.missing)
+			if r.Status != tt.status {
+				t.Errorf("Expected %s, got %s", tt.status, r.Status)
+			}
+			if !strings.Contains(strings.Join(r.Hints, "\n"), tt.hint) {
+				t.Errorf("Expected hint %q, got %v", tt.hint, r.Hints)
+			}
+			if Failed(results) != tt.wantFails {
+				t.Errorf("Expected Failed %v, got %v", tt.wantFails, Failed(results))
+			}
+		})
+	}
+}
+
+func TestInstallHintWSL(t *testing.T) {
+	env := fakeEnv("windows")
+	env.WSL = true
+	hints := installHint(map[string]string{"linux": "apt", "windows": "winget"})(env)
+	if len(hints) != 1 || !strings.Contains(hints[0], "apt") {
+		t.Errorf("Expected the Linux hint inside WSL, got %v", hints)
+	}
+}
+
+func TestRunFiles(t *testing.T) {
+	home := setupFiles(t)
+	env := fakeEnv("linux", "aws", "fzf", "kubectl", "docker", "k9s")
+	env.OpenTTY = func() (io.Closer, error) { return nil, errors.New("no terminal") }
+	if err := os.Remove(filepath.Join(home, ".kube", "config")); err != nil {
+		t.Fatal(err)
+	}
+
+	results := Run(env, Checks)
+	if r := findResult(t, results, "kubeconfig"); r.Status != Warn || !strings.Contains(r.Detail, "does not exist") {
+		t.Errorf("Expected a missing kubeconfig to warn, got %s: %s", r.Status, r.Detail)
+	}
+	if r := findResult(t, results, "terminal"); r.Status != Warn {
+		t.Errorf("Expected an unopenable terminal to warn, got %s", r.Status)
+	}
+	if r := findResult(t, results, "fancy-login config"); r.Status != Pass || !strings.Contains(r.Detail, "using defaults") {
+		t.Errorf("Expected a missing fancy-login config to pass, got %s: %s", r.Status, r.Detail)
+	}
+	if Failed(results) {
+		t.Error("Expected warnings not to fail the run")
+	}
+
+	if err := os.Remove(filepath.Join(home, ".aws", "config")); err != nil {
+		t.Fatal(err)
+	}
+	if r := findResult(t, Run(env, Checks), "AWS config"); r.Status != Fail {
+		t.Errorf("Expected a missing AWS config to fail, got %s", r.Status)
+	}
+}
+
+func TestAWSVaultCheck(t *testing.T) {
+	home := setupFiles(t)
+
+	if r := findResult(t, Run(fakeEnv("linux", "aws-vault"), Checks), "aws-vault"); r.Status != Pass || r.Detail != "aws-vault 1.0" {
+		t.Errorf("Expected an installed aws-vault to pass with its version, got %s: %s", r.Status, r.Detail)
+	}
+	if r := findResult(t, Run(fakeEnv("linux"), Checks), "aws-vault"); r.Status != Pass {
+		t.Errorf("Expected a missing aws-vault nobody uses to pass, got %s: %s", r.Status, r.Detail)
+	}
+
+	content := "profile_configs:\n  dev:\n    name: dev\n    backend: aws-vault\n"
+	if err := os.WriteFile(filepath.Join(home, ".fancy-config.yaml"), []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	r := findResult(t, Run(fakeEnv("darwin"), Checks), "aws-vault")
+	if r.Status != Warn || !strings.Contains(r.Detail, "used by dev") {
+		t.Errorf("Expected a missing aws-vault used by dev to warn, got %s: %s", r.Status, r.Detail)
+	}
+	if !strings.Contains(strings.Join(r.Hints, "\n"), "brew install --cask aws-vault") {
+		t.Errorf("Expected the macOS install hint, got %v", r.Hints)
+	}
+}
+
+func TestK9sCheck(t *testing.T) {
+	home := setupFiles(t)
+
+	if r := findResult(t, Run(fakeEnv("linux"), Checks), "k9s"); r.Status != Warn || r.Detail != "not found on PATH" {
+		t.Errorf("Expected a missing k9s to warn, got %s: %s", r.Status, r.Detail)
+	}
+
+	content := "profile_configs:\n  dev:\n    name: dev\n    k9s_auto_launch: true\n" +
+		"kube_only_profiles:\n  oidc:\n    k8s_context: oidc\n    k9s_auto_launch: true\n"
+	if err := os.WriteFile(filepath.Join(home, ".fancy-config.yaml"), []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	r := findResult(t, Run(fakeEnv("windows"), Checks), "k9s")
+	if r.Status != Warn || r.Detail != "not installed, but k9s_auto_launch is on for dev, oidc" {
+		t.Errorf("Expected the auto-launching profiles, got %s: %s", r.Status, r.Detail)
+	}
+	if !strings.Contains(strings.Join(r.Hints, "\n"), "winget install -e --id Derailed.k9s") {
+		t.Errorf("Expected the Windows install hint, got %v", r.Hints)
+	}
+}
+
+func TestRunInvalidFancyConfig(t *testing.T) {
+	home := setupFiles(t)
+	path := filepath.Join(home, ".fancy-config.yaml")
+	if err := os.WriteFile(path, []byte("profiles: [unclosed\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	results := Run(fakeEnv("linux", "aws", "fzf", "kubectl"), Checks)
+	if r := findResult(t, results, "fancy-login config"); r.Status != Fail {
+		t.Errorf("Expected an unparsable config to fail, got %s: %s", r.Status, r.Detail)
+	}
+	if !Failed(results) {
+		t.Error("Expected the run to fail")
+	}
+}
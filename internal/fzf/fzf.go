@@ -0,0 +1,220 @@
+package fzf
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"fancy-login/internal/config"
+	"fancy-login/internal/state"
+)
+
+// Version is a parsed fzf version
+type Version struct {
+	Major, Minor, Patch int
+}
+
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// AtLeast reports whether v >= other
+func (v Version) AtLeast(other Version) bool {
+	if v.Major != other.Major {
+		return v.Major > other.Major
+	}
+	if v.Minor != other.Minor {
+		return v.Minor > other.Minor
+	}
+	return v.Patch >= other.Patch
+}
+
+var versionRegex = regexp.MustCompile(`(\d+)\.(\d+)(?:\.(\d+))?`)
+
+// ParseVersion parses `fzf --version` output such as "0.44.1 (d7d2ac3)"
+func ParseVersion(output string) (Version, error) {
+	matches := versionRegex.FindStringSubmatch(output)
+	if matches == nil {
+		return Version{}, fmt.Errorf("unrecognized fzf version output %q", strings.TrimSpace(output))
+	}
+	major, _ := strconv.Atoi(matches[1])
+	minor, _ := strconv.Atoi(matches[2])
+	patch, _ := strconv.Atoi(matches[3])
+	return Version{Major: major, Minor: minor, Patch: patch}, nil
+}
+
+// Feature is an optional fzf command-line feature
+type Feature string
+
+const (
+	FeatureQuery   Feature = "--query"
+	FeatureANSI    Feature = "--ansi"
+	FeatureExpect  Feature = "--expect"
+	FeatureHeader  Feature = "--header"
+	FeaturePreview Feature = "--preview"
+	// FeatureStartPos moves the cursor to an item once the input is loaded
+	FeatureStartPos Feature = "--bind=load:pos"
+)
+
+// featureMinVersions lists the first fzf release supporting each feature
+var featureMinVersions = map[Feature]Version{
+	FeatureQuery:    {0, 8, 0},
+	FeatureANSI:     {0, 9, 0},
+	FeatureExpect:   {0, 9, 7},
+	FeatureHeader:   {0, 10, 9},
+	FeaturePreview:  {0, 13, 0},
+	FeatureStartPos: {0, 36, 0},
+}
+
+// Capabilities describes what the installed fzf supports
+type Capabilities struct {
+	Version Version
+	Known   bool // false when the version could not be determined
+}
+
+// Supports reports whether a feature can be used. Unknown versions only
+// get the features every packaged fzf has had for years.
+func (c Capabilities) Supports(f Feature) bool {
+	min, ok := featureMinVersions[f]
+	if !ok {
+		return false
+	}
+	if !c.Known {
+		return f == FeatureQuery
+	}
+	return c.Version.AtLeast(min)
+}
+
+// Disabled lists the features unavailable with this fzf
+func (c Capabilities) Disabled() []Feature {
+	var disabled []Feature
+	for _, f := range []Feature{FeatureQuery, FeatureANSI, FeatureExpect, FeatureHeader, FeaturePreview, FeatureStartPos} {
+		if !c.Supports(f) {
+			disabled = append(disabled, f)
+		}
+	}
+	return disabled
+}
+
+// Options are the picker settings callers would like to use
+type Options struct {
+	Prompt  string
+	Query   string
+	Header  string
+	Preview string
+	Expect  []string
+	ANSI    bool // input contains color escape sequences
+	// StartPos is the 1-based line the cursor starts on; 0 keeps the first
+	StartPos int
+	// Multi lets Tab mark several lines, which fzf prints one per line
+	Multi bool
+}
+
+// Args builds fzf arguments, silently dropping features this fzf lacks
+func (c Capabilities) Args(opts Options) []string {
+	var args []string
+	if opts.Prompt != "" {
+		args = append(args, "--prompt="+opts.Prompt)
+	}
+	if opts.Multi {
+		args = append(args, "--multi")
+	}
+	if opts.ANSI && c.Supports(FeatureANSI) {
+		args = append(args, "--ansi")
+	}
+	if opts.Query != "" && c.Supports(FeatureQuery) {
+		args = append(args, "--query="+opts.Query)
+	}
+	if opts.Header != "" && c.Supports(FeatureHeader) {
+		args = append(args, "--header="+opts.Header)
+	}
+	if opts.Preview != "" && c.Supports(FeaturePreview) {
+		args = append(args, "--preview="+opts.Preview)
+	}
+	if len(opts.Expect) > 0 && c.Supports(FeatureExpect) {
+		args = append(args, "--expect="+strings.Join(opts.Expect, ","))
+	}
+	if opts.StartPos > 0 && c.Supports(FeatureStartPos) {
+		args = append(args, fmt.Sprintf("--bind=load:pos(%d)", opts.StartPos))
+	}
+	return args
+}
+
+var detected *Capabilities
+
+// Detect probes fzf once per run, reusing the cached probe from the state
+// file while the fzf binary's mtime is unchanged
+func Detect() Capabilities {
+	if detected != nil {
+		return *detected
+	}
+
+	caps := probe()
+	detected = &caps
+	return caps
+}
+
+// probe resolves the fzf binary and returns its capabilities
+func probe() Capabilities {
+	path, err := exec.LookPath("fzf")
+	if err != nil {
+		return Capabilities{}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return Capabilities{}
+	}
+
+	st, err := state.Load()
+	if err == nil && st.Fzf != nil && st.Fzf.Path == path && st.Fzf.ModTime == info.ModTime().Unix() {
+		if v, err := ParseVersion(st.Fzf.Version); err == nil {
+			return Capabilities{Version: v, Known: true}
+		}
+	}
+
+	output, err := exec.Command(path, "--version").Output()
+	if err != nil {
+		return Capabilities{}
+	}
+	v, err := ParseVersion(string(output))
+	if err != nil {
+		return Capabilities{}
+	}
+
+	probed := &state.FzfProbe{Path: path, ModTime: info.ModTime().Unix(), Version: v.String()}
+	if err := state.Update(func(st *state.State) error {
+		st.Fzf = probed
+		return nil
+	}); err != nil {
+		fmt.Fprintf(os.Stderr, "%s⚠️  Failed to cache the fzf version: %v%s\n", config.Warning, err, config.Reset)
+	}
+	return Capabilities{Version: v, Known: true}
+}
+
+// Cancelled reports whether fzf exited because the user pressed Esc or
+// Ctrl-C (130) or accepted without a match (1)
+func Cancelled(err error) bool {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return false
+	}
+	return exitErr.ExitCode() == 130 || exitErr.ExitCode() == 1
+}
+
+// UseBuiltin reports whether pickers should use the built-in menu rather
+// than fzf: when the selector setting says "builtin", or when it doesn't
+// insist on "fzf" and fzf isn't installed
+func UseBuiltin(selector string) bool {
+	switch selector {
+	case "builtin":
+		return true
+	case "fzf":
+		return false
+	}
+	_, err := exec.LookPath("fzf")
+	return err != nil
+}
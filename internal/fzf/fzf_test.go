@@ -0,0 +1,139 @@
+package fzf
+
+import (
+	"errors"
+	"os/exec"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestParseVersion(t *testing.T) {
+	testCases := []struct {
+		output    string
+		expected  Version
+		expectErr bool
+	}{
+		{"0.44.1 (d7d2ac3)\n", Version{0, 44, 1}, false},
+		{"0.20.0", Version{0, 20, 0}, false},
+		{"0.9", Version{0, 9, 0}, false},
+		{"1.0.0 (brew)", Version{1, 0, 0}, false},
+		{"fzf: command not found", Version{}, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.output, func(t *testing.T) {
+			v, err := ParseVersion(tc.output)
+			if (err != nil) != tc.expectErr {
+				t.Fatalf("ParseVersion(%q) error = %v, expectErr %v", tc.output, err, tc.expectErr)
+			}
+			if v != tc.expected {
+				t.Errorf("ParseVersion(%q) = %v, expected %v", tc.output, v, tc.expected)
+			}
+		})
+	}
+}
+
+func TestCapabilityTable(t *testing.T) {
+	testCases := []struct {
+		name     string
+		caps     Capabilities
+		feature  Feature
+		expected bool
+	}{
+		{"Modern fzf has preview", Capabilities{Version{0, 44, 1}, true}, FeaturePreview, true},
+		{"Ancient fzf lacks preview", Capabilities{Version{0, 10, 0}, true}, FeaturePreview, false},
+		{"Ancient fzf lacks header", Capabilities{Version{0, 10, 0}, true}, FeatureHeader, false},
+		{"Header at minimum version", Capabilities{Version{0, 10, 9}, true}, FeatureHeader, true},
+		{"Expect supported", Capabilities{Version{0, 10, 0}, true}, FeatureExpect, true},
+		{"Unknown version keeps query", Capabilities{}, FeatureQuery, true},
+		{"Unknown version drops preview", Capabilities{}, FeaturePreview, false},
+		{"Major version bump", Capabilities{Version{1, 0, 0}, true}, FeaturePreview, true},
+		{"Start position needs load event", Capabilities{Version{0, 35, 0}, true}, FeatureStartPos, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.caps.Supports(tc.feature); got != tc.expected {
+				t.Errorf("Supports(%s) = %v, expected %v", tc.feature, got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestArgsDegradeGracefully(t *testing.T) {
+	opts := Options{
+		Prompt:   "Select: ",
+		Query:    "dev",
+		Header:   "Profiles",
+		Preview:  "echo {}",
+		Expect:   []string{"ctrl-k"},
+		StartPos: 3,
+	}
+
+	modern := Capabilities{Version{0, 44, 1}, true}.Args(opts)
+	expectedModern := []string{"--prompt=Select: ", "--query=dev", "--header=Profiles", "--preview=echo {}", "--expect=ctrl-k", "--bind=load:pos(3)"}
+	if !reflect.DeepEqual(modern, expectedModern) {
+		t.Errorf("Modern args = %v, expected %v", modern, expectedModern)
+	}
+
+	ancient := Capabilities{Version{0, 9, 0}, true}.Args(opts)
+	expectedAncient := []string{"--prompt=Select: ", "--query=dev"}
+	if !reflect.DeepEqual(ancient, expectedAncient) {
+		t.Errorf("Ancient args = %v, expected %v", ancient, expectedAncient)
+	}
+
+	disabled := Capabilities{Version{0, 9, 0}, true}.Disabled()
+	if len(disabled) != 4 {
+		t.Errorf("Expected 4 disabled features, got %v", disabled)
+	}
+}
+
+func TestCancelled(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("exit statuses are produced with a POSIX shell")
+	}
+	testCases := []struct {
+		name     string
+		status   string
+		expected bool
+	}{
+		{"Esc", "130", true},
+		{"No match", "1", true},
+		{"Error", "2", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := exec.Command("sh", "-c", "exit "+tc.status).Run()
+			if got := Cancelled(err); got != tc.expected {
+				t.Errorf("Expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+	if Cancelled(errors.New("fzf not found")) {
+		t.Error("Expected a start failure not to count as cancelled")
+	}
+}
+
+func TestUseBuiltin(t *testing.T) {
+	// An empty PATH hides any installed fzf
+	t.Setenv("PATH", t.TempDir())
+
+	testCases := []struct {
+		selector string
+		expected bool
+	}{
+		{"builtin", true},
+		{"fzf", false},
+		{"", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.selector, func(t *testing.T) {
+			if got := UseBuiltin(tc.selector); got != tc.expected {
+				t.Errorf("UseBuiltin(%q) = %v, expected %v", tc.selector, got, tc.expected)
+			}
+		})
+	}
+}
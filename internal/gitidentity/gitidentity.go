@@ -0,0 +1,177 @@
+package gitidentity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"fancy-login/internal/state"
+	"fancy-login/internal/utils"
+)
+
+// ErrNothingToUndo is returned by Undo when no change is recorded
+var ErrNothingToUndo = errors.New("no git identity change to undo")
+
+// Identity is a git committer identity; empty fields are left alone
+type Identity struct {
+	Name  string
+	Email string
+}
+
+// values returns the git config keys and values the identity sets
+func (id Identity) values() [][2]string {
+	var values [][2]string
+	if id.Name != "" {
+		values = append(values, [2]string{"user.name", id.Name})
+	}
+	if id.Email != "" {
+		values = append(values, [2]string{"user.email", id.Email})
+	}
+	return values
+}
+
+// RepoRoot returns the top-level directory of the git repository containing
+// dir. It reports false outside a repository or when git is missing.
+func RepoRoot(ctx context.Context, dir string) (string, bool) {
+	output, err := utils.CommandContext(ctx, "git", "-C", dir, "rev-parse", "--show-toplevel").Output()
+	if err != nil {
+		return "", false
+	}
+	root := strings.TrimSpace(string(output))
+	return root, root != ""
+}
+
+// localValue reads a key from the repository's own config, ignoring the
+// global one
+func localValue(ctx context.Context, repo, key string) (string, bool, error) {
+	output, err := utils.CommandContext(ctx, "git", "-C", repo, "config", "--local", "--get", key).Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("failed to read %s: %w", key, err)
+	}
+	return strings.TrimSpace(string(output)), true, nil
+}
+
+// setLocal sets or, with unset, removes a key in the repository's config
+func setLocal(ctx context.Context, repo, key, value string, unset bool) error {
+	args := []string{"-C", repo, "config", "--local", key, value}
+	if unset {
+		args = []string{"-C", repo, "config", "--local", "--unset", key}
+	}
+	if output, err := utils.CommandContext(ctx, "git", args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("git config %s failed: %s", key, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// Plan is a pending identity change for one repository
+type Plan struct {
+	Repo    string
+	Profile string
+	// changes are the keys and values that differ from the repository
+	changes [][2]string
+	// previous holds the repository's current value of each changed key
+	previous map[string]string
+}
+
+// NewPlan compares want with the repository's config. It returns nil when
+// the repository already uses that identity.
+func NewPlan(ctx context.Context, repo, profile string, want Identity) (*Plan, error) {
+	plan := &Plan{Repo: repo, Profile: profile, previous: make(map[string]string)}
+	for _, kv := range want.values() {
+		current, set, err := localValue(ctx, repo, kv[0])
+		if err != nil {
+			return nil, err
+		}
+		if set && current == kv[1] {
+			continue
+		}
+		if set {
+			plan.previous[kv[0]] = current
+		}
+		plan.changes = append(plan.changes, kv)
+	}
+	if len(plan.changes) == 0 {
+		return nil, nil
+	}
+	return plan, nil
+}
+
+// Describe lists each change with the value it replaces
+func (p *Plan) Describe() []string {
+	var lines []string
+	for _, kv := range p.changes {
+		previous, ok := p.previous[kv[0]]
+		if !ok {
+			previous = "(not set for this repository)"
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s → %s", kv[0], previous, kv[1]))
+	}
+	return lines
+}
+
+// Apply writes the identity to the repository's config and records the
+// previous values for Undo. Keys already written stay recorded if a later
+// one fails.
+func (p *Plan) Apply(ctx context.Context) error {
+	change := state.GitIdentityChange{
+		Repo:      p.Repo,
+		Profile:   p.Profile,
+		Previous:  p.previous,
+		ChangedAt: time.Now(),
+	}
+
+	var applyErr error
+	for _, kv := range p.changes {
+		if applyErr = setLocal(ctx, p.Repo, kv[0], kv[1], false); applyErr != nil {
+			break
+		}
+		change.Changed = append(change.Changed, kv[0])
+	}
+	if len(change.Changed) == 0 {
+		return applyErr
+	}
+
+	if err := state.Update(func(s *state.State) error {
+		s.GitIdentityChanges = append(s.GitIdentityChanges, change)
+		return nil
+	}); err != nil {
+		return errors.Join(applyErr, fmt.Errorf("failed to record git identity change: %w", err))
+	}
+	return applyErr
+}
+
+// Undo restores the most recent recorded identity change and forgets it
+func Undo(ctx context.Context) (*state.GitIdentityChange, error) {
+	st, err := state.Load()
+	if err != nil {
+		return nil, err
+	}
+	if len(st.GitIdentityChanges) == 0 {
+		return nil, ErrNothingToUndo
+	}
+	change := st.GitIdentityChanges[len(st.GitIdentityChanges)-1]
+
+	for _, key := range change.Changed {
+		previous, wasSet := change.Previous[key]
+		if err := setLocal(ctx, change.Repo, key, previous, !wasSet); err != nil {
+			return nil, fmt.Errorf("failed to restore %s in %s: %w", key, change.Repo, err)
+		}
+	}
+
+	if err := state.Update(func(s *state.State) error {
+		if n := len(s.GitIdentityChanges); n > 0 {
+			s.GitIdentityChanges = s.GitIdentityChanges[:n-1]
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+	return &change, nil
+}
@@ -0,0 +1,128 @@
+package gitidentity
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newRepo creates a git repository with an isolated global config
+func newRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", home)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("FANCY_STATE_DIR", t.TempDir())
+
+	repo := t.TempDir()
+	if output, err := exec.Command("git", "init", "-q", repo).CombinedOutput(); err != nil {
+		t.Fatalf("git init failed: %v: %s", err, output)
+	}
+	return repo
+}
+
+// gitConfig runs git config in dir and returns its trimmed output
+func gitConfig(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	output, _ := exec.Command("git", append([]string{"-C", dir, "config"}, args...)...).Output()
+	return strings.TrimSpace(string(output))
+}
+
+func TestRepoRoot(t *testing.T) {
+	repo := newRepo(t)
+	sub := filepath.Join(repo, "src", "app")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	root, ok := RepoRoot(context.Background(), sub)
+	if !ok {
+		t.Fatal("Expected a repository")
+	}
+	// macOS temp dirs are behind a symlink
+	expected, _ := filepath.EvalSymlinks(repo)
+	if actual, _ := filepath.EvalSymlinks(root); actual != expected {
+		t.Errorf("Expected root %s, got %s", expected, actual)
+	}
+
+	if _, ok := RepoRoot(context.Background(), t.TempDir()); ok {
+		t.Error("Expected no repository outside a git checkout")
+	}
+}
+
+func TestApplyAndUndo(t *testing.T) {
+	testCases := []struct {
+		name          string
+		previousName  string
+		previousEmail string
+		expected      []string
+	}{
+		{"Unset before", "", "", []string{
+			"user.name: (not set for this repository) → Client Dev",
+			"user.email: (not set for this repository) → dev@client.example",
+		}},
+		{"Other identity before", "Me", "me@home.example", []string{
+			"user.name: Me → Client Dev",
+			"user.email: me@home.example → dev@client.example",
+		}},
+		{"Only the email differs", "Client Dev", "me@home.example", []string{
+			"user.email: me@home.example → dev@client.example",
+		}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := newRepo(t)
+			ctx := context.Background()
+			gitConfig(t, repo, "--global", "user.email", "global@example.com")
+			if tc.previousName != "" {
+				gitConfig(t, repo, "--local", "user.name", tc.previousName)
+			}
+			if tc.previousEmail != "" {
+				gitConfig(t, repo, "--local", "user.email", tc.previousEmail)
+			}
+
+			plan, err := NewPlan(ctx, repo, "client-dev", Identity{Name: "Client Dev", Email: "dev@client.example"})
+			if err != nil || plan == nil {
+				t.Fatalf("Expected a plan, got %v, %v", plan, err)
+			}
+			if lines := plan.Describe(); !reflect.DeepEqual(lines, tc.expected) {
+				t.Errorf("Expected %q, got %q", tc.expected, lines)
+			}
+			if err := plan.Apply(ctx); err != nil {
+				t.Fatalf("Apply failed: %v", err)
+			}
+			if email := gitConfig(t, repo, "--local", "--get", "user.email"); email != "dev@client.example" {
+				t.Errorf("Expected the profile's email, got %q", email)
+			}
+			if again, _ := NewPlan(ctx, repo, "client-dev", Identity{Name: "Client Dev", Email: "dev@client.example"}); again != nil {
+				t.Errorf("Expected no plan once applied, got %v", again.Describe())
+			}
+
+			if _, err := Undo(ctx); err != nil {
+				t.Fatalf("Undo failed: %v", err)
+			}
+			if name := gitConfig(t, repo, "--local", "--get", "user.name"); name != tc.previousName {
+				t.Errorf("Expected user.name %q restored, got %q", tc.previousName, name)
+			}
+			if email := gitConfig(t, repo, "--local", "--get", "user.email"); email != tc.previousEmail {
+				t.Errorf("Expected user.email %q restored, got %q", tc.previousEmail, email)
+			}
+			if global := gitConfig(t, repo, "--global", "--get", "user.email"); global != "global@example.com" {
+				t.Errorf("Expected global config untouched, got %q", global)
+			}
+			if _, err := Undo(ctx); !errors.Is(err, ErrNothingToUndo) {
+				t.Errorf("Expected nothing left to undo, got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"fancy-login/internal/config"
+	"fancy-login/internal/utils"
+)
+
+// updateKubeconfigArgs returns the aws arguments writing a context called
+// alias for cluster into kubeconfig, "" being the default kubeconfig
+func updateKubeconfigArgs(profile string, cluster config.EKSClusterConfig, alias, kubeconfig string) []string {
+	args := []string{"eks", "update-kubeconfig", "--name", cluster.Name, "--region", cluster.Region,
+		"--profile", profile, "--alias", alias}
+	if kubeconfig != "" {
+		args = append(args, "--kubeconfig", kubeconfig)
+	}
+	return args
+}
+
+// UpdateKubeconfig runs `aws eks update-kubeconfig` for cluster with the
+// credentials of profile, creating or refreshing the context alias
+func UpdateKubeconfig(ctx context.Context, timeout time.Duration, profile string, cluster config.EKSClusterConfig, alias, kubeconfig string) error {
+	return updateKubeconfig(ctx, timeout, profile, cluster, alias, kubeconfig, nil)
+}
+
+// updateKubeconfig is UpdateKubeconfig with env as the command's
+// environment, nil for this process's
+func updateKubeconfig(ctx context.Context, timeout time.Duration, profile string, cluster config.EKSClusterConfig, alias, kubeconfig string, env []string) error {
+	ctx, cancel := utils.WithStepTimeout(ctx, timeout)
+	defer cancel()
+
+	var stderr bytes.Buffer
+	cmd := utils.CommandContext(ctx, "aws", updateKubeconfigArgs(profile, cluster, alias, kubeconfig)...)
+	cmd.Env = env
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = fmt.Errorf("%w: %s", err, msg)
+		}
+		return utils.StepError(ctx, "aws eks update-kubeconfig", timeout, err)
+	}
+	return nil
+}
+
+// ListEKSClusters runs `aws eks list-clusters` with the credentials of
+// profile and returns the cluster names in region, sorted. A refusal by IAM
+// wraps config.ErrEKSAccessDenied.
+func ListEKSClusters(ctx context.Context, timeout time.Duration, profile, region string) ([]string, error) {
+	ctx, cancel := utils.WithStepTimeout(ctx, timeout)
+	defer cancel()
+
+	var stderr bytes.Buffer
+	cmd := utils.CommandContext(ctx, "aws", "eks", "list-clusters", "--region", region, "--profile", profile, "--output", "json")
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if strings.Contains(msg, "AccessDenied") {
+			return nil, fmt.Errorf("%w: %s", config.ErrEKSAccessDenied, msg)
+		}
+		if msg != "" {
+			err = fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, utils.StepError(ctx, "aws eks list-clusters", timeout, err)
+	}
+
+	var result struct {
+		Clusters []string `json:"clusters"`
+	}
+	if err := json.Unmarshal(output, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse aws eks list-clusters output: %w", err)
+	}
+	sort.Strings(result.Clusters)
+	return result.Clusters, nil
+}
+
+// eksClusterFor returns the eks_cluster of awsProfile with its region
+// filled in, and whether it has one
+func (k8s *K8sManager) eksClusterFor(awsProfile string) (config.EKSClusterConfig, bool) {
+	pc, err := k8s.fancyConfig.GetProfileConfig(awsProfile)
+	if err != nil || pc.EKSCluster.Name == "" {
+		return config.EKSClusterConfig{}, false
+	}
+	cluster := pc.EKSCluster
+	if cluster.Region == "" {
+		cluster.Region = k8s.fancyConfig.RegionForProfile(awsProfile)
+	}
+	return cluster, true
+}
+
+// ensureContext makes sure the kubeconfig has the context name. A missing
+// one is created from the profile's eks_cluster once the user agrees, or
+// with --assume-yes; otherwise the lookup's error is returned.
+func (k8s *K8sManager) ensureContext(ctx context.Context, awsProfile, name string) error {
+	missing := k8s.checkContextExists(name)
+	if missing == nil {
+		return nil
+	}
+	cluster, ok := k8s.eksClusterFor(awsProfile)
+	if !ok {
+		return missing
+	}
+
+	if k8s.dryRun {
+		k8s.logger.LogPlanned(fmt.Sprintf("create the missing context %s: aws %s", name,
+			strings.Join(updateKubeconfigArgs(awsProfile, cluster, name, k8s.kubeconfigPath), " ")))
+		k8s.createdContext = name
+		return nil
+	}
+
+	prompter, closeTTY, err := k8s.newPrompter()
+	if err != nil {
+		return fmt.Errorf("%w (no terminal to confirm creating it from EKS cluster %s)", missing, cluster.Name)
+	}
+	defer closeTTY()
+	question := fmt.Sprintf("%sContext %s does not exist. Create it from EKS cluster %s in %s?%s",
+		config.Accent, name, cluster.Name, cluster.Region, config.Reset)
+	if !prompter.Confirm(question, false) {
+		return missing
+	}
+
+	k8s.logger.FancyLog(fmt.Sprintf("Creating context %s from EKS cluster %s", name, cluster.Name))
+	if err := updateKubeconfig(ctx, k8s.fancyConfig.Settings.AWSNetworkTimeoutDuration(), awsProfile, cluster, name, k8s.kubeconfigPath,
+		utils.ExtendEnv(os.Environ(), k8s.extraEnv(awsProfile))); err != nil {
+		return fmt.Errorf("failed to create context %s from EKS cluster %s: %w", name, cluster.Name, err)
+	}
+	k8s.createdContext = name
+	return nil
+}
+
+// createdNote tells the summary that contextName was created in this run
+func (k8s *K8sManager) createdNote(contextName string) string {
+	if k8s.createdContext == "" || k8s.createdContext != contextName {
+		return ""
+	}
+	return fmt.Sprintf(" %s(created from EKS)%s", config.Accent, config.Reset)
+}
@@ -0,0 +1,117 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"fancy-login/internal/config"
+	"fancy-login/internal/prompt"
+)
+
+// installFakeAWS puts an aws on PATH that records its arguments and, for
+// eks update-kubeconfig, appends the --alias context to kubeconfig
+func installFakeAWS(t *testing.T, kubeconfig string) string {
+	t.Helper()
+	binDir := t.TempDir()
+	record := filepath.Join(binDir, "record")
+	script := "#!/bin/sh\n" +
+		"echo \"$*\" > " + record + "\n" +
+		"while [ $# -gt 0 ]; do\n" +
+		"  if [ \"$1\" = --alias ]; then printf -- '- name: %s\\n  context: {cluster: eks}\\n' \"$2\" >> " + kubeconfig + "; fi\n" +
+		"  shift\n" +
+		"done\n"
+	if err := os.WriteFile(filepath.Join(binDir, "aws"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	return record
+}
+
+func TestSelectKubernetesContextCreatesEKSContext(t *testing.T) {
+	testCases := []struct {
+		name      string
+		assumeYes bool
+		cluster   config.EKSClusterConfig
+		created   bool
+	}{
+		{"Created with --assume-yes", true, config.EKSClusterConfig{Name: "platform", Region: "eu-west-1"}, true},
+		{"Declined", false, config.EKSClusterConfig{Name: "platform", Region: "eu-west-1"}, false},
+		{"No eks_cluster", true, config.EKSClusterConfig{}, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			k8s, kubeconfig := newTestManager(t)
+			writeKubeconfig(t, kubeconfig, "dev-cluster")
+			record := installFakeAWS(t, kubeconfig)
+			prompt.SetNonInteractive(true, tc.assumeYes)
+			t.Cleanup(func() { prompt.SetNonInteractive(false, false) })
+			k8s.fancyConfig.ProfileConfigs["platform"] = config.ProfileConfig{K8sContext: "platform-eks", EKSCluster: tc.cluster}
+			k8s.SetSkipClusterCheck(true)
+
+			line, err := k8s.SelectKubernetesContext(context.Background(), "platform")
+			if err != nil {
+				t.Fatalf("SelectKubernetesContext failed: %v", err)
+			}
+			current, _ := config.ReadCurrentContext(kubeconfig)
+			if !tc.created {
+				if current != "dev-cluster" || strings.Contains(line, "created") {
+					t.Errorf("Expected no context to be created, got current-context %q and %q", current, line)
+				}
+				return
+			}
+
+			args, _ := os.ReadFile(record)
+			expected := "eks update-kubeconfig --name platform --region eu-west-1 --profile platform --alias platform-eks"
+			if strings.TrimSpace(string(args)) != expected {
+				t.Errorf("Expected aws %s, got %q", expected, args)
+			}
+			if current != "platform-eks" {
+				t.Errorf("Expected the switch to the created context, got %q", current)
+			}
+			if !strings.Contains(line, "(created from EKS)") {
+				t.Errorf("Expected the summary to mention the creation, got %q", line)
+			}
+		})
+	}
+}
+
+func TestListEKSClusters(t *testing.T) {
+	testCases := []struct {
+		name     string
+		script   string
+		expected []string
+		denied   bool
+	}{
+		{"Sorted", "echo '{\"clusters\": [\"sandbox\", \"platform\"]}'", []string{"platform", "sandbox"}, false},
+		{"None", "echo '{\"clusters\": []}'", []string{}, false},
+		{"Access denied", "echo 'An error occurred (AccessDeniedException) when calling the ListClusters operation' >&2; exit 254", nil, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			binDir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(binDir, "aws"), []byte("#!/bin/sh\n"+tc.script+"\n"), 0755); err != nil {
+				t.Fatal(err)
+			}
+			t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+			clusters, err := ListEKSClusters(context.Background(), time.Minute, "dev", "eu-west-1")
+			if tc.denied {
+				if !errors.Is(err, config.ErrEKSAccessDenied) {
+					t.Errorf("Expected ErrEKSAccessDenied, got %v", err)
+				}
+				return
+			}
+			if err != nil || len(clusters) != len(tc.expected) || (len(clusters) > 0 && !reflect.DeepEqual(clusters, tc.expected)) {
+				t.Errorf("Expected %v, got %v, %v", tc.expected, clusters, err)
+			}
+		})
+	}
+}
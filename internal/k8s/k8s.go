@@ -1,16 +1,21 @@
 package k8s
 
 import (
+	"bytes"
 	"context"
-	"encoding/base64"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
-	"runtime"
 	"strings"
 	"time"
 
+	"fancy-login/internal/a11y"
 	"fancy-login/internal/config"
+	"fancy-login/internal/fzf"
+	"fancy-login/internal/platform"
+	"fancy-login/internal/prompt"
+	"fancy-login/internal/tmux"
 	"fancy-login/internal/utils"
 )
 
@@ -19,28 +24,179 @@ type K8sManager struct {
 	config      *config.Config
 	logger      *utils.Logger
 	fancyConfig *config.FancyConfig
+
+	// appliedContext is the context fancy-login switched to during this run
+	appliedContext string
+	// reapplyPrompt asks whether to re-apply our context after an external change
+	reapplyPrompt func(applied, current string) bool
+	// contextOverride is the --context flag; it replaces mapping and picker
+	contextOverride string
+	// namespaceOverride is the --namespace flag; it replaces the profile's
+	// namespace for k9s, the summary and the terminal badge
+	namespaceOverride string
+	// lastContext is the context of the login --last repeats; it stands in
+	// for the picker
+	lastContext string
+	// snapshot holds the kubeconfig as parsed once for this run
+	snapshot *config.Snapshot
+	// dryRun reports context switches and launches instead of performing them
+	dryRun bool
+	// selectionTimeout bounds the context picker; 0 waits forever
+	selectionTimeout time.Duration
+	// kubeconfig lists and switches contexts
+	kubeconfig Kubeconfig
+	// kubeconfigPath is the profile's own kubeconfig file, or "" for the
+	// default one
+	kubeconfigPath string
+	// skipNamespaceSelect is the --skip-namespace-select flag
+	skipNamespaceSelect bool
+	// selectedNamespace is the namespace picked after the context switch
+	selectedNamespace string
+	// skipClusterCheck is the --no-k8s-check flag
+	skipClusterCheck bool
+	// checkedContext is the context whose cluster was asked for its version
+	// after the switch, clusterErr why it didn't answer
+	checkedContext string
+	clusterErr     error
+	// createdContext is the context created from the profile's eks_cluster
+	// during this run
+	createdContext string
+	// namespaceSavedContext is the context whose kubeconfig namespace was
+	// updated by set_context_namespace during this run
+	namespaceSavedContext string
+	// profileEnv returns the variables children started for a profile
+	// need on top of the resolved ones; nil adds none
+	profileEnv func(profile string) map[string]string
 }
 
 // NewK8sManager creates a new Kubernetes manager
 func NewK8sManager(cfg *config.Config, logger *utils.Logger, fancyConfig *config.FancyConfig) *K8sManager {
-	return &K8sManager{
-		config:      cfg,
-		logger:      logger,
-		fancyConfig: fancyConfig,
+	k8s := &K8sManager{
+		config:           cfg,
+		logger:           logger,
+		fancyConfig:      fancyConfig,
+		snapshot:         config.NewSnapshot(nil),
+		selectionTimeout: fancyConfig.Settings.SelectionTimeoutDuration(),
+	}
+	k8s.kubeconfig = k8s.newKubeconfig("")
+	k8s.reapplyPrompt = k8s.askReapplyContext
+	return k8s
+}
+
+// newKubeconfig returns the kube_client's view of the kubeconfig at path,
+// "" being the default one
+func (k8s *K8sManager) newKubeconfig(path string) Kubeconfig {
+	if k8s.fancyConfig.Settings.KubeClient == config.KubeClientKubectl {
+		return kubectlKubeconfig{timeout: k8s.fancyConfig.Settings.KubectlTimeoutDuration(), path: path}
+	}
+	return clientcmdKubeconfig{path: path}
+}
+
+// useProfileKubeconfig makes the manager work on the kubeconfig file of
+// awsProfile, if it has one
+func (k8s *K8sManager) useProfileKubeconfig(awsProfile string) {
+	path := k8s.fancyConfig.KubeconfigForProfile(awsProfile)
+	if path == "" || path == k8s.kubeconfigPath {
+		return
+	}
+	k8s.logger.FancyLog(fmt.Sprintf("Using the kubeconfig of %s: %s", awsProfile, path))
+	k8s.kubeconfigPath = path
+	k8s.kubeconfig = k8s.newKubeconfig(path)
+}
+
+// SetProfileEnv makes children started for a profile, such as k9s, hooks
+// and aws eks update-kubeconfig, also get the variables env returns for
+// it, e.g. the credentials file of an MFA session
+func (k8s *K8sManager) SetProfileEnv(env func(profile string) map[string]string) {
+	k8s.profileEnv = env
+}
+
+// SetSnapshot makes the manager share the run's parsed configs. The
+// current context is still read fresh, to notice changes by other tools.
+func (k8s *K8sManager) SetSnapshot(snapshot *config.Snapshot) {
+	k8s.snapshot = snapshot
+}
+
+// SetDryRun makes the manager report the context switches, k9s and hook
+// commands it would run instead of running them
+func (k8s *K8sManager) SetDryRun(dryRun bool) {
+	k8s.dryRun = dryRun
+}
+
+// SetSelectionTimeout overrides how long the context picker waits for a
+// choice; 0 waits forever
+func (k8s *K8sManager) SetSelectionTimeout(timeout time.Duration) {
+	k8s.selectionTimeout = timeout
+}
+
+// SetLastContext makes SelectKubernetesContext reuse name instead of
+// opening the picker for a profile without a configured context
+func (k8s *K8sManager) SetLastContext(name string) {
+	k8s.lastContext = name
+}
+
+// SetNamespaceOverride makes this run work in namespace instead of the
+// profile's configured one
+func (k8s *K8sManager) SetNamespaceOverride(namespace string) {
+	k8s.namespaceOverride = namespace
+}
+
+// SetSkipNamespaceSelect turns off the namespace picker of
+// namespace_selection for this run
+func (k8s *K8sManager) SetSkipNamespaceSelect(skip bool) {
+	k8s.skipNamespaceSelect = skip
+}
+
+// SetSkipClusterCheck turns off the check that the cluster of the new
+// context answers
+func (k8s *K8sManager) SetSkipClusterCheck(skip bool) {
+	k8s.skipClusterCheck = skip
+}
+
+// Namespace returns the namespace a profile works in: the --namespace
+// override, its configured namespace, the one picked after the context
+// switch or "default"
+func (k8s *K8sManager) Namespace(awsProfile string) string {
+	if namespace := k8s.chosenNamespace(awsProfile); namespace != "" {
+		return namespace
+	}
+	return "default"
+}
+
+// chosenNamespace returns the namespace set by --namespace, the profile or
+// the picker, or "" if none of them chose one
+func (k8s *K8sManager) chosenNamespace(awsProfile string) string {
+	if k8s.namespaceOverride != "" {
+		return k8s.namespaceOverride
 	}
+	if pc, err := k8s.fancyConfig.GetProfileConfig(awsProfile); err == nil && pc.Namespace != "" {
+		return pc.Namespace
+	}
+	return k8s.selectedNamespace
 }
 
 // SelectKubernetesContext selects and switches Kubernetes context
-func (k8s *K8sManager) SelectKubernetesContext(awsProfile string) (string, error) {
+func (k8s *K8sManager) SelectKubernetesContext(ctx context.Context, awsProfile string) (string, error) {
 	k8s.logger.FancyLog("Entered select_kubernetes_context")
+	k8s.useProfileKubeconfig(awsProfile)
+
+	if k8s.contextOverride != "" {
+		return k8s.selectOverriddenContext(ctx, awsProfile)
+	}
 
 	// Check if there's a direct mapping from configuration
 	configuredContext := k8s.fancyConfig.GetK8sContextForProfile(awsProfile)
 	if configuredContext != "" {
 		k8s.logger.FancyLog(fmt.Sprintf("Using configured context: %s", configuredContext))
 
-		if err := k8s.switchK8sContext(configuredContext); err != nil {
+		// A real run only warns, but a dry run is there to catch this
+		if err := k8s.ensureContext(ctx, awsProfile, configuredContext); err != nil && k8s.dryRun {
+			return "", err
+		}
+		if err := k8s.switchK8sContext(ctx, configuredContext); err != nil {
 			k8s.logger.LogWarning(fmt.Sprintf("Failed to switch to context %s: %v", configuredContext, err))
+		} else {
+			k8s.afterSwitch(ctx, awsProfile)
 		}
 
 		return k8s.formatContextSummary(configuredContext, awsProfile), nil
@@ -50,202 +206,673 @@ func (k8s *K8sManager) SelectKubernetesContext(awsProfile string) (string, error
 	if _, err := k8s.fancyConfig.GetProfileConfig(awsProfile); err == nil {
 		k8s.logger.FancyLog(fmt.Sprintf("Profile %s has no Kubernetes context configured, skipping context selection", awsProfile))
 		return fmt.Sprintf("%s🌱 Kubernetes Context:%s (not configured for this profile)",
-			config.Green, config.Reset), nil
+			config.Success, config.Reset), nil
 	}
 
 	// No profile configuration found, use fzf to select
-	context, err := k8s.selectContextWithFzf()
+	if k8s.lastContext != "" {
+		if err := k8s.checkContextExists(k8s.lastContext); err == nil {
+			k8s.logger.FancyLog(fmt.Sprintf("Using the context of the last login: %s", k8s.lastContext))
+			if err := k8s.switchK8sContext(ctx, k8s.lastContext); err != nil {
+				k8s.logger.LogWarning(fmt.Sprintf("Failed to switch to context %s: %v", k8s.lastContext, err))
+			} else {
+				k8s.afterSwitch(ctx, awsProfile)
+			}
+			return k8s.formatContextSummary(k8s.lastContext, awsProfile), nil
+		}
+		k8s.logger.LogWarning(fmt.Sprintf("Context %s of the last login no longer exists", k8s.lastContext))
+	}
+	if !prompt.Interactive() {
+		k8s.logger.LogWarning(fmt.Sprintf("Profile %s has no Kubernetes context configured; keeping the current context (--non-interactive)", awsProfile))
+		return k8s.getCurrentContextSummary(ctx, awsProfile)
+	}
+	if k8s.dryRun {
+		k8s.logger.LogPlanned("ask for the Kubernetes context with the picker")
+		return fmt.Sprintf("%s🌱 Kubernetes Context:%s (picked interactively)", config.Success, config.Reset), nil
+	}
+	selected, err := k8s.selectContextWithFzf(ctx)
 	if err != nil {
 		k8s.logger.FancyLog("No context selected or error occurred")
 		// Return current context or fallback
-		return k8s.getCurrentContextSummary(awsProfile)
+		return k8s.getCurrentContextSummary(ctx, awsProfile)
 	}
 
-	if err := k8s.switchK8sContext(context); err != nil {
-		k8s.logger.LogWarning(fmt.Sprintf("Failed to switch to context %s: %v", context, err))
+	if err := k8s.switchK8sContext(ctx, selected); err != nil {
+		k8s.logger.LogWarning(fmt.Sprintf("Failed to switch to context %s: %v", selected, err))
+	} else {
+		k8s.afterSwitch(ctx, awsProfile)
 	}
 
-	return k8s.formatContextSummary(context, awsProfile), nil
+	return k8s.formatContextSummary(selected, awsProfile), nil
+}
+
+// SetContextOverride makes SelectKubernetesContext switch to name instead
+// of the configured or picked context
+func (k8s *K8sManager) SetContextOverride(name string) {
+	k8s.contextOverride = name
+}
+
+// selectOverriddenContext switches to the --context override after checking
+// that the kubeconfig has it
+func (k8s *K8sManager) selectOverriddenContext(ctx context.Context, awsProfile string) (string, error) {
+	name := k8s.contextOverride
+	k8s.logger.FancyLog(fmt.Sprintf("Using context from --context: %s", name))
+
+	if err := k8s.checkContextExists(name); err != nil {
+		return "", err
+	}
+	if err := k8s.switchK8sContext(ctx, name); err != nil {
+		return "", fmt.Errorf("failed to switch to context %s: %w", name, err)
+	}
+	k8s.afterSwitch(ctx, awsProfile)
+	return fmt.Sprintf("%s %s(from --context)%s",
+		k8s.formatContextSummary(name, awsProfile), config.Muted, config.Reset), nil
+}
+
+// checkContextExists returns an error listing the available contexts if the
+// kubeconfig has no context called name
+func (k8s *K8sManager) checkContextExists(name string) error {
+	contexts, err := k8s.kubeContexts()
+	if err != nil {
+		return fmt.Errorf("cannot check context %s: %w", name, err)
+	}
+	var available []string
+	for _, c := range contexts {
+		if c.Name == name {
+			return nil
+		}
+		available = append(available, c.Name)
+	}
+	if len(available) == 0 {
+		return fmt.Errorf("context %s not found: the kubeconfig has no contexts", name)
+	}
+	return fmt.Errorf("context %s not found; available contexts: %s", name, strings.Join(available, ", "))
+}
+
+// kubeContexts returns the contexts of the kubeconfig in use: the run's
+// snapshot of the default one, or the profile's own file
+func (k8s *K8sManager) kubeContexts() ([]config.KubernetesContext, error) {
+	if k8s.kubeconfigPath != "" {
+		return config.ParseKubernetesContexts(k8s.kubeconfigPath)
+	}
+	return k8s.snapshot.KubeContexts()
 }
 
 // HandleK9sLaunch handles launching k9s based on configuration
-func (k8s *K8sManager) HandleK9sLaunch(awsProfile string) error {
+func (k8s *K8sManager) HandleK9sLaunch(ctx context.Context, awsProfile string) error {
+	if k8s.dryRun {
+		k8s.reportK9sLaunch(awsProfile)
+		return nil
+	}
+
 	// Check if this profile should auto-launch K9s
 	if !k8s.fancyConfig.ShouldAutoLaunchK9s(awsProfile) {
 		return nil
 	}
 
-	if k8s.config.UseK9S {
-		return k8s.launchK9sWithNamespace(awsProfile)
+	// Everything else succeeded, so a missing k9s is only pointed out
+	if k8s.K9sNotInstalled(awsProfile) {
+		k8s.logger.LogWarning("k9s is not installed; install it with: " + platform.K9sInstallHint())
+		return nil
 	}
 
-	fmt.Printf("\n%sDo you want to open k9s? (y/n): %s", config.Cyan, config.Reset)
+	// k9s needs a terminal to draw on
+	if !prompt.Interactive() {
+		k8s.logger.FancyLog("Skipping k9s (--non-interactive)")
+		return nil
+	}
 
-	// Use /dev/tty for proper terminal input handling after fzf interaction
-	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
-	if err != nil {
-		return fmt.Errorf("failed to open /dev/tty: %w", err)
+	// Make sure k9s opens against the context we reported
+	k8s.VerifyCurrentContext(ctx)
+
+	if k8s.config.UseK9S {
+		return k8s.launchK9sWithNamespace(ctx, awsProfile)
 	}
-	defer tty.Close()
 
-	var response string
-	_, err = fmt.Fscanln(tty, &response)
+	// Use /dev/tty for proper terminal input handling after fzf interaction
+	prompter, closeTTY, err := k8s.newPrompter()
 	if err != nil {
 		return err
 	}
+	defer closeTTY()
 
-	if response == "y" {
-		return k8s.launchK9sWithNamespace(awsProfile)
+	fmt.Fprintln(k8s.logger.Output())
+	if prompter.Confirm(fmt.Sprintf("%sDo you want to open k9s?%s", config.Accent, config.Reset), false) {
+		return k8s.launchK9sWithNamespace(ctx, awsProfile)
 	}
 
 	return nil
 }
 
-// selectContextWithFzf uses fzf to select a Kubernetes context
-func (k8s *K8sManager) selectContextWithFzf() (string, error) {
+// reportK9sLaunch tells a dry run whether k9s would launch
+func (k8s *K8sManager) reportK9sLaunch(awsProfile string) {
+	if !k8s.fancyConfig.ShouldAutoLaunchK9s(awsProfile) {
+		k8s.logger.LogPlanned(fmt.Sprintf("not launch k9s, k9s_auto_launch is off for %s", awsProfile))
+		return
+	}
+	if k8s.K9sNotInstalled(awsProfile) {
+		k8s.logger.LogPlanned("not launch k9s, it is not installed")
+		return
+	}
+	command := k8s.k9sCommandLine(awsProfile)
+	if k8s.config.UseK9S {
+		k8s.logger.LogPlanned("launch: " + command)
+		return
+	}
+	k8s.logger.LogPlanned("ask whether to launch: " + command)
+}
+
+// selectContextWithFzf uses fzf to select a Kubernetes context, the
+// built-in menu without fzf, or a numbered list in screen reader mode
+func (k8s *K8sManager) selectContextWithFzf(ctx context.Context) (string, error) {
 	k8s.logger.FancyLog("Selecting Kubernetes Context...")
 
-	// Get available contexts
-	cmd := exec.Command("kubectl", "config", "get-contexts", "-o", "name")
-	output, err := cmd.Output()
+	contexts, err := k8s.kubeconfig.Contexts(ctx)
 	if err != nil {
 		return "", fmt.Errorf("failed to get contexts: %w", err)
 	}
-
-	contexts := strings.TrimSpace(string(output))
-	if contexts == "" {
+	if len(contexts) == 0 {
 		return "", fmt.Errorf("no contexts available")
 	}
 
+	selected, err := k8s.pick(ctx, "context", "Select Kubernetes Context", contexts)
+	if err != nil {
+		return "", err
+	}
+	k8s.logger.FancyLog(fmt.Sprintf("K8s context selected: %s", selected))
+	return selected, nil
+}
+
+// pick chooses one of items with fzf, the built-in menu without fzf, or a
+// numbered list in screen reader mode. what names the choice in errors,
+// e.g. "context".
+func (k8s *K8sManager) pick(ctx context.Context, what, title string, items []string) (string, error) {
+	if a11y.Enabled() {
+		return k8s.chooseNumbered(what, title, items)
+	}
+	if fzf.UseBuiltin(k8s.fancyConfig.Settings.Selector) {
+		return k8s.chooseBuiltin(ctx, what, title, items)
+	}
+
 	// Use fzf to select with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	ctx, cancel := utils.WithStepTimeout(ctx, k8s.selectionTimeout)
 	defer cancel()
 
-	fzfCmd := exec.CommandContext(ctx, "fzf", "--prompt=Select Kubernetes Context: ")
-	fzfCmd.Stdin = strings.NewReader(contexts)
+	fzfCmd := exec.CommandContext(ctx, "fzf", fzf.Detect().Args(fzf.Options{Prompt: title + ": "})...)
+	fzfCmd.Stdin = strings.NewReader(strings.Join(items, "\n"))
 	fzfCmd.Stderr = os.Stderr
 
 	result, err := fzfCmd.Output()
 	if err != nil {
 		if ctx.Err() == context.DeadlineExceeded {
-			return "", fmt.Errorf("context selection timed out after 60 seconds")
+			return "", &utils.SelectionTimeoutError{What: what + " selection", Timeout: k8s.selectionTimeout}
 		}
 		return "", err
 	}
+	return strings.TrimSpace(string(result)), nil
+}
+
+// chooseBuiltin picks one of items with the built-in menu when fzf isn't
+// used
+func (k8s *K8sManager) chooseBuiltin(ctx context.Context, what, title string, items []string) (string, error) {
+	prompter, closeTTY, err := k8s.newPrompter()
+	if err != nil {
+		return "", fmt.Errorf("%s selection needs a terminal: %w", what, err)
+	}
+	defer closeTTY()
+
+	ctx, cancel := utils.WithStepTimeout(ctx, k8s.selectionTimeout)
+	defer cancel()
+
+	menu := make([]prompt.MenuItem, len(items))
+	for i, item := range items {
+		menu[i] = prompt.MenuItem{Text: item}
+	}
+	index, ok := prompter.Menu(ctx, title, menu)
+	if ctx.Err() == context.DeadlineExceeded {
+		return "", &utils.SelectionTimeoutError{What: what + " selection", Timeout: k8s.selectionTimeout}
+	}
+	if !ok {
+		return "", fmt.Errorf("no %s selected", what)
+	}
+	return items[index], nil
+}
+
+// chooseNumbered is the screen reader picker
+func (k8s *K8sManager) chooseNumbered(what, title string, items []string) (string, error) {
+	prompter, closeTTY, err := k8s.newPrompter()
+	if err != nil {
+		return "", fmt.Errorf("%s selection needs a terminal: %w", what, err)
+	}
+	defer closeTTY()
+
+	index, ok := prompter.Choose(title, items)
+	if !ok {
+		return "", fmt.Errorf("no %s selected", what)
+	}
+	return items[index], nil
+}
+
+// clusterCheckTimeout bounds the API call checking that a cluster answers
+const clusterCheckTimeout = 5 * time.Second
+
+// afterSwitch checks that the cluster of the context just switched to
+// answers and, if it does, offers the namespace picker
+func (k8s *K8sManager) afterSwitch(ctx context.Context, awsProfile string) {
+	k8s.checkCluster(ctx)
+	if k8s.clusterErr == nil {
+		k8s.selectNamespace(ctx, awsProfile)
+	}
+	k8s.saveContextNamespace(ctx, awsProfile)
+}
+
+// saveContextNamespace writes the chosen namespace into the switched-to
+// context when set_context_namespace is on, so kubectl in the same shell
+// works in it too. A failure only warns.
+func (k8s *K8sManager) saveContextNamespace(ctx context.Context, awsProfile string) {
+	namespace := k8s.chosenNamespace(awsProfile)
+	if !k8s.fancyConfig.Settings.SetContextNamespace || namespace == "" || k8s.appliedContext == "" {
+		return
+	}
+	if k8s.dryRun {
+		k8s.logger.LogPlanned(fmt.Sprintf("set the namespace of context %s to %s", k8s.appliedContext, namespace))
+		return
+	}
+
+	changed, err := k8s.kubeconfig.SetNamespace(ctx, k8s.appliedContext, namespace)
+	if err != nil {
+		k8s.logger.LogWarning(fmt.Sprintf("Failed to set the namespace of context %s: %v", k8s.appliedContext, err))
+		return
+	}
+	if changed {
+		k8s.logger.FancyLog(fmt.Sprintf("Set the namespace of context %s to %s", k8s.appliedContext, namespace))
+		k8s.namespaceSavedContext = k8s.appliedContext
+	}
+}
+
+// checkCluster asks the cluster of the current context for its version,
+// so a deleted cluster or an expired token shows in the summary instead of
+// inside k9s. It never fails the run.
+func (k8s *K8sManager) checkCluster(ctx context.Context) {
+	if k8s.skipClusterCheck || k8s.appliedContext == "" {
+		return
+	}
+	if k8s.dryRun {
+		k8s.logger.LogPlanned("check that the cluster answers")
+		return
+	}
 
-	context := strings.TrimSpace(string(result))
-	k8s.logger.FancyLog(fmt.Sprintf("K8s context selected: %s", context))
+	checkCtx, cancel := context.WithTimeout(ctx, clusterCheckTimeout)
+	defer cancel()
+	err := k8s.kubeconfig.CheckCluster(checkCtx)
+	if errors.Is(checkCtx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("no answer within %s", clusterCheckTimeout)
+	}
+	k8s.checkedContext, k8s.clusterErr = k8s.appliedContext, err
+	if err != nil {
+		k8s.logger.LogWarning(fmt.Sprintf("Cluster of context %s is unreachable: %v", k8s.appliedContext, err))
+	}
+}
+
+// clusterStatus renders the result of checkCluster for contextName, or ""
+// if its cluster wasn't checked
+func (k8s *K8sManager) clusterStatus(contextName string) string {
+	if k8s.checkedContext == "" || k8s.checkedContext != contextName {
+		return ""
+	}
+	if k8s.clusterErr != nil {
+		return fmt.Sprintf(" %s(unreachable: %s)%s", config.Warning, shortClusterError(k8s.clusterErr), config.Reset)
+	}
+	return fmt.Sprintf(" %s(reachable)%s", config.Muted, config.Reset)
+}
+
+// shortClusterError keeps the last part of a wrapped network error, e.g.
+// "connection refused" of "Get ...: dial tcp ...: connect: connection
+// refused"
+func shortClusterError(err error) string {
+	msg := err.Error()
+	if i := strings.LastIndex(msg, ": "); i >= 0 {
+		msg = msg[i+2:]
+	}
+	return msg
+}
+
+// namespaceListTimeout bounds the namespace query of the namespace picker,
+// which only offers a convenience
+const namespaceListTimeout = 3 * time.Second
+
+// selectNamespace offers the cluster's namespaces in the picker after a
+// context switch, when namespace_selection is on and neither --namespace
+// nor the profile decide the namespace. The pick stands in for a
+// configured namespace for the rest of the run.
+func (k8s *K8sManager) selectNamespace(ctx context.Context, awsProfile string) {
+	if !k8s.fancyConfig.Settings.NamespaceSelection || k8s.skipNamespaceSelect || k8s.namespaceOverride != "" {
+		return
+	}
+	if pc, err := k8s.fancyConfig.GetProfileConfig(awsProfile); err == nil && pc.Namespace != "" {
+		return
+	}
+	if !prompt.Interactive() {
+		return
+	}
+	if k8s.dryRun {
+		k8s.logger.LogPlanned("ask for the namespace with the picker")
+		return
+	}
+
+	listCtx, cancel := context.WithTimeout(ctx, namespaceListTimeout)
+	namespaces, err := k8s.kubeconfig.Namespaces(listCtx)
+	if errors.Is(listCtx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("the cluster did not answer within %s", namespaceListTimeout)
+	}
+	cancel()
+	if err != nil {
+		k8s.logger.LogWarning(fmt.Sprintf("Could not list namespaces, using the default namespace: %v", err))
+		return
+	}
+	if len(namespaces) == 0 {
+		return
+	}
 
-	return context, nil
+	selected, err := k8s.pick(ctx, "namespace", "Select Namespace", namespaces)
+	if err != nil {
+		k8s.logger.FancyLog(fmt.Sprintf("No namespace selected: %v", err))
+		return
+	}
+	k8s.logger.FancyLog(fmt.Sprintf("Namespace selected: %s", selected))
+	k8s.selectedNamespace = selected
 }
 
 // switchK8sContext switches to the specified Kubernetes context
-func (k8s *K8sManager) switchK8sContext(context string) error {
+func (k8s *K8sManager) switchK8sContext(ctx context.Context, contextName string) error {
+	if k8s.dryRun {
+		if k8s.kubeconfigPath != "" {
+			k8s.logger.LogPlanned(fmt.Sprintf("switch the current-context of %s to %s", k8s.kubeconfigPath, contextName))
+			return nil
+		}
+		k8s.logger.LogPlanned("switch the kubeconfig's current-context to " + contextName)
+		return nil
+	}
+
 	if k8s.config.FancyVerbose {
-		k8s.logger.LogInfo(fmt.Sprintf("Switching to Kubernetes context: %s", context))
-		cmd := exec.Command("kubectl", "config", "use-context", context)
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		return cmd.Run()
+		k8s.logger.LogInfo(fmt.Sprintf("Switching to Kubernetes context: %s", contextName))
+	}
+	if err := k8s.kubeconfig.UseContext(ctx, contextName); err != nil {
+		return err
 	}
 
-	cmd := exec.Command("kubectl", "config", "use-context", context)
-	cmd.Stdout = nil
-	cmd.Stderr = nil
-	return cmd.Run()
+	k8s.appliedContext = contextName
+	return nil
+}
+
+// VerifyCurrentContext re-reads the effective current-context and, if
+// something else changed it since we switched, asks whether to re-apply our
+// context or adopt the external one. It returns the context now in effect
+// and whether it differs from the one we applied.
+func (k8s *K8sManager) VerifyCurrentContext(ctx context.Context) (string, bool) {
+	if k8s.appliedContext == "" {
+		return "", false
+	}
+
+	current, err := k8s.kubeconfig.CurrentContext(ctx)
+	if err != nil {
+		k8s.logger.FancyLog(fmt.Sprintf("Could not re-read current context: %v", err))
+		return k8s.appliedContext, false
+	}
+
+	if current == k8s.appliedContext {
+		return current, false
+	}
+
+	applied := k8s.appliedContext
+	k8s.logger.LogWarning(fmt.Sprintf("Kubernetes context was changed externally: expected %s, found %s", applied, current))
+
+	if k8s.reapplyPrompt(applied, current) {
+		if err := k8s.switchK8sContext(ctx, applied); err != nil {
+			k8s.logger.LogWarning(fmt.Sprintf("Failed to re-apply context %s: %v", applied, err))
+			return current, true
+		}
+		return applied, false
+	}
+
+	k8s.logger.LogInfo(fmt.Sprintf("Adopting external Kubernetes context %s", current))
+	k8s.appliedContext = current
+	return current, true
+}
+
+// askReapplyContext asks the user whether to re-apply our context. Without a
+// terminal it defaults to re-applying.
+func (k8s *K8sManager) askReapplyContext(applied, current string) bool {
+	prompter, closeTTY, err := k8s.newPrompter()
+	if err != nil {
+		k8s.logger.LogWarning(fmt.Sprintf("Non-interactive session, re-applying %s", applied))
+		return true
+	}
+	defer closeTTY()
+
+	return prompter.Confirm(fmt.Sprintf("%sRe-apply %s? (no adopts %s)%s", config.Accent, applied, current, config.Reset), true)
+}
+
+// newPrompter opens a y/n prompter on the terminal using the configured answers
+func (k8s *K8sManager) newPrompter() (*prompt.Prompter, func(), error) {
+	return prompt.NewTTYPrompter(k8s.fancyConfig.Settings.AffirmativeAnswers, k8s.fancyConfig.Settings.NegativeAnswers)
+}
+
+// FormatContextSummary formats the summary line for a context and profile
+func (k8s *K8sManager) FormatContextSummary(contextName, awsProfile string) string {
+	return k8s.formatContextSummary(contextName, awsProfile)
 }
 
 // getCurrentContextSummary returns the current context summary
-func (k8s *K8sManager) getCurrentContextSummary(awsProfile string) (string, error) {
-	cmd := exec.Command("kubectl", "config", "current-context")
-	output, err := cmd.Output()
+func (k8s *K8sManager) getCurrentContextSummary(ctx context.Context, awsProfile string) (string, error) {
+	currentContext, err := k8s.kubeconfig.CurrentContext(ctx)
 	if err != nil {
 		return fmt.Sprintf("%s🌱 Kubernetes Context:%s (none selected)",
-			config.Green, config.Reset), nil
+			config.Success, config.Reset), nil
 	}
-
-	currentContext := strings.TrimSpace(string(output))
 	return k8s.formatContextSummary(currentContext, awsProfile), nil
 }
 
 // formatContextSummary formats the context summary with namespace if available
 func (k8s *K8sManager) formatContextSummary(context, awsProfile string) string {
-	profileConfig, err := k8s.fancyConfig.GetProfileConfig(awsProfile)
-	var namespace string
-	if err != nil {
-		namespace = "default"
-	} else {
-		namespace = profileConfig.Namespace
-		if namespace == "" {
-			namespace = "default"
-		}
+	namespace := k8s.Namespace(awsProfile)
+	if namespace != "default" && !k8s.dryRun {
+		k8s.setITerm2Namespace(namespace)
+	}
+	return ContextSummaryLine(context, namespace) + k8s.createdNote(context) + k8s.namespaceSavedNote(context) +
+		k8s.clusterStatus(context) + k8s.kubeconfigNote()
+}
+
+// namespaceSavedNote tells the summary that the namespace of contextName
+// was written into the kubeconfig
+func (k8s *K8sManager) namespaceSavedNote(contextName string) string {
+	if k8s.namespaceSavedContext == "" || k8s.namespaceSavedContext != contextName {
+		return ""
 	}
+	return fmt.Sprintf(" %s(namespace saved to kubeconfig)%s", config.Muted, config.Reset)
+}
 
-	if namespace != "default" {
-		k8s.setITerm2Namespace(namespace)
+// kubeconfigNote names the profile's own kubeconfig in the summary
+func (k8s *K8sManager) kubeconfigNote() string {
+	if k8s.kubeconfigPath == "" {
+		return ""
+	}
+	return fmt.Sprintf(" %s(kubeconfig: %s)%s", config.Muted, k8s.kubeconfigPath, config.Reset)
+}
+
+// ContextSummaryLine renders the summary's Kubernetes line; the default
+// namespace is left out
+func ContextSummaryLine(context, namespace string) string {
+	if namespace != "" && namespace != "default" {
 		return fmt.Sprintf("%s🌱 Kubernetes Context:%s %s%s%s %s(ns: %s)%s",
-			config.Green, config.Reset, config.Bold, context, config.Reset,
-			config.Cyan, namespace, config.Reset)
+			config.Success, config.Reset, config.Bold, context, config.Reset,
+			config.Accent, namespace, config.Reset)
 	}
 
 	return fmt.Sprintf("%s🌱 Kubernetes Context:%s %s%s%s",
-		config.Green, config.Reset, config.Bold, context, config.Reset)
+		config.Success, config.Reset, config.Bold, context, config.Reset)
 }
 
-// setITerm2Namespace sets the terminal tab title and badge (cross-platform)
-func (k8s *K8sManager) setITerm2Namespace(namespace string) {
-	if namespace == "" {
+// RunPreLoginHook runs the pre_login_hook of a kube-only profile, e.g. to
+// refresh a kubelogin token. The hook may need the terminal or a browser, so
+// it stays in the foreground and is only bound to cancellation.
+func (k8s *K8sManager) RunPreLoginHook(ctx context.Context, profile string) error {
+	kube, exists := k8s.fancyConfig.KubeOnlyProfiles[profile]
+	if !exists || kube.PreLoginHook == "" {
+		return nil
+	}
+
+	if k8s.dryRun {
+		k8s.logger.LogPlanned(fmt.Sprintf("run the pre-login hook of %s: %s", profile, kube.PreLoginHook))
+		return nil
+	}
+	k8s.logger.FancyLog(fmt.Sprintf("Running pre-login hook for %s: %s", profile, kube.PreLoginHook))
+
+	cmd := platform.ShellCommand(ctx, kube.PreLoginHook)
+	cmd.Env = k8s.childEnv(profile)
+	cmd.Stdout = k8s.logger.Output()
+	cmd.Stderr = os.Stderr
+	cmd.Stdin = os.Stdin
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("pre-login hook for %s failed: %w", profile, err)
+	}
+	return nil
+}
+
+// TagTmuxPane tags the current tmux pane with the profile, context and
+// namespace when tmux integration is enabled. Outside tmux it does nothing.
+func (k8s *K8sManager) TagTmuxPane(awsProfile, contextName string) {
+	if !k8s.fancyConfig.Settings.TmuxIntegration {
 		return
 	}
 
-	switch runtime.GOOS {
-	case "darwin":
-		// macOS iTerm2
-		if os.Getenv("TERM_PROGRAM") == "iTerm.app" {
-			// Set tab title
-			fmt.Printf("\033]1;ns:%s\007", namespace)
+	id := tmux.Identity{Profile: awsProfile, Context: contextName}
+	if namespace := k8s.Namespace(awsProfile); namespace != "default" {
+		id.Namespace = namespace
+	}
 
-			// Set badge
-			badge := fmt.Sprintf("🟢 ns:%s", namespace)
-			encoded := base64.StdEncoding.EncodeToString([]byte(badge))
-			fmt.Printf("\033]1337;SetBadgeFormat=%s\a", encoded)
-		}
-	case "windows":
-		// Windows Terminal
-		if os.Getenv("WT_SESSION") != "" {
-			// Set tab title for Windows Terminal
-			fmt.Printf("\033]0;ns:%s\007", namespace)
-		}
-	default:
-		// Linux terminals (most support standard title escape sequence)
-		fmt.Printf("\033]0;ns:%s\007", namespace)
+	if err := tmux.TagPane(tmux.DefaultRunner, id); err != nil {
+		k8s.logger.FancyLog(fmt.Sprintf("Failed to tag tmux pane: %v", err))
 	}
 }
 
+// setITerm2Namespace shows the namespace in the terminal tab title (and the
+// iTerm2 badge) where the terminal supports it
+func (k8s *K8sManager) setITerm2Namespace(namespace string) {
+	fmt.Fprint(k8s.logger.Output(), platform.NamespaceIndicator(namespace))
+}
+
 // launchK9sWithNamespace launches k9s with the derived namespace
-func (k8s *K8sManager) launchK9sWithNamespace(awsProfile string) error {
-	profileConfig, err := k8s.fancyConfig.GetProfileConfig(awsProfile)
-	if err != nil {
+func (k8s *K8sManager) launchK9sWithNamespace(ctx context.Context, awsProfile string) error {
+	if _, err := k8s.fancyConfig.GetProfileConfig(awsProfile); err != nil {
 		return fmt.Errorf("profile %s not configured: %w", awsProfile, err)
 	}
 
-	// Use namespace from profile configuration
-	namespace := profileConfig.Namespace
-	if namespace == "" {
-		// Use default namespace if no namespace configured
-		namespace = "default"
+	namespace := k8s.Namespace(awsProfile)
+	// An overridden namespace is typed in a hurry; a typo still opens k9s,
+	// where the namespace can be switched, but is pointed out first
+	if k8s.namespaceOverride != "" {
+		switch err := k8s.checkNamespaceExists(ctx, namespace); {
+		case errors.Is(err, errNamespaceNotFound):
+			k8s.logger.LogWarning(fmt.Sprintf("Namespace %s does not exist in the cluster; launching k9s anyway", namespace))
+		case err != nil:
+			k8s.logger.FancyLog(fmt.Sprintf("Could not check namespace %s: %v", namespace, err))
+		}
 	}
 
 	k8s.logger.FancyLog(fmt.Sprintf("Launching k9s in %s.", namespace))
+	if k8s.fancyConfig.ShouldK9sReadonly(awsProfile) {
+		fmt.Fprintf(k8s.logger.Output(), "%sk9s starting in READ-ONLY mode%s\n", config.Bold+config.Warning, config.Reset)
+	}
 
-	cmd := exec.Command("k9s", "-n", namespace)
-	cmd.Stdout = os.Stdout
+	// k9s is interactive, so it must stay in the foreground process group
+	// and is only bound to cancellation, not to a timeout
+	contextName := k8s.k9sContext()
+	cmd := exec.CommandContext(ctx, "k9s", k8s.k9sArgs(awsProfile, namespace, contextName)...)
+	cmd.Stdout = k8s.logger.Output()
 	cmd.Stderr = os.Stderr
 	cmd.Stdin = os.Stdin
 
-	// Inherit current environment and set AWS_PROFILE
-	cmd.Env = os.Environ()
-	cmd.Env = append(cmd.Env, fmt.Sprintf("AWS_PROFILE=%s", awsProfile))
+	cmd.Env = k8s.childEnv(awsProfile)
+	if k8s.kubeconfigPath != "" {
+		// A later entry wins, so this replaces an inherited KUBECONFIG
+		cmd.Env = append(cmd.Env, "KUBECONFIG="+k8s.kubeconfigPath)
+	}
+
+	// Title the window so several k9s sessions can be told apart
+	if platform.TitleSupported() {
+		pushTerminalTitle(k8s.logger.Output(), k9sWindowTitle(contextName, namespace, awsProfile))
+		defer popTerminalTitle(k8s.logger.Output())
+	}
+
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	k8s.registerK9sSession(newK9sSession(cmd.Process.Pid, awsProfile, contextName, namespace))
+	defer k8s.unregisterK9sSession(cmd.Process.Pid)
+
+	return cmd.Wait()
+}
+
+// errNamespaceNotFound is returned by checkNamespaceExists for a namespace
+// the cluster doesn't have
+var errNamespaceNotFound = errors.New("namespace not found")
 
-	return cmd.Run()
+// checkNamespaceExists asks the cluster for namespace. Other failures, such
+// as missing permissions to read namespaces, are returned as they are.
+func (k8s *K8sManager) checkNamespaceExists(ctx context.Context, namespace string) error {
+	ctx, cancel := utils.WithStepTimeout(ctx, k8s.fancyConfig.Settings.KubectlTimeoutDuration())
+	defer cancel()
+
+	var stderr bytes.Buffer
+	args := []string{"get", "namespace", namespace, "-o", "name"}
+	if k8s.kubeconfigPath != "" {
+		args = append(args, "--kubeconfig", k8s.kubeconfigPath)
+	}
+	cmd := utils.CommandContext(ctx, "kubectl", args...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if strings.Contains(stderr.String(), "NotFound") {
+			return errNamespaceNotFound
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return errors.New(msg)
+		}
+		return err
+	}
+	return nil
+}
+
+// extraEnv returns what SetProfileEnv adds for awsProfile
+func (k8s *K8sManager) extraEnv(awsProfile string) map[string]string {
+	if k8s.profileEnv == nil {
+		return nil
+	}
+	return k8s.profileEnv(awsProfile)
+}
+
+// childEnv builds the environment for children started for a profile, so a
+// stale AWS_REGION, AWS_PROFILE or exported credentials from an earlier
+// login can't leak into them. Kube-only profiles resolve no AWS variables,
+// so their children get none unless inherit_aws_env is set.
+func (k8s *K8sManager) childEnv(awsProfile string) []string {
+	var resolved map[string]string
+	if !k8s.fancyConfig.IsKubeOnlyProfile(awsProfile) {
+		region := k8s.fancyConfig.RegionForProfile(awsProfile)
+		resolved = map[string]string{
+			"AWS_PROFILE":        awsProfile,
+			"AWS_REGION":         region,
+			"AWS_DEFAULT_REGION": region,
+		}
+		for name, value := range k8s.extraEnv(awsProfile) {
+			resolved[name] = value
+		}
+	}
+
+	env, overridden := utils.BuildChildEnv(os.Environ(), resolved, k8s.fancyConfig.Settings.InheritAWSEnv)
+	if len(overridden) > 0 {
+		k8s.logger.FancyLog(fmt.Sprintf("Overriding inherited environment: %s", strings.Join(overridden, ", ")))
+	}
+	return env
 }
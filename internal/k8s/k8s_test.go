@@ -0,0 +1,350 @@
+package k8s
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"fancy-login/internal/config"
+	"fancy-login/internal/utils"
+)
+
+// writeKubeconfig writes a minimal kubeconfig with the given current-context
+// and the contexts dev-cluster and prod-cluster
+func writeKubeconfig(t *testing.T, path, current string) {
+	t.Helper()
+	content := "apiVersion: v1\nkind: Config\ncurrent-context: " + current + "\ncontexts:\n" +
+		"- name: dev-cluster\n  context: {cluster: dev}\n" +
+		"- name: prod-cluster\n  context: {cluster: prod}\n"
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write kubeconfig: %v", err)
+	}
+}
+
+// installFakeKubectl puts a kubectl on PATH that applies use-context to the
+// kubeconfig, for the kubectl kube client, and knows the namespaces default
+// and apps
+func installFakeKubectl(t *testing.T, kubeconfig string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake kubectl requires a POSIX shell")
+	}
+	binDir := t.TempDir()
+	script := "#!/bin/sh\n" +
+		"if [ \"$1 $2\" = \"config use-context\" ]; then\n" +
+		"  printf 'apiVersion: v1\\nkind: Config\\ncurrent-context: %s\\n' \"$3\" > " + kubeconfig + "\n" +
+		"fi\n" +
+		"if [ \"$1 $2\" = \"get namespace\" ]; then\n" +
+		"  case \"$3\" in default|apps) echo \"namespace/$3\" ;;\n" +
+		"  *) echo \"Error from server (NotFound): namespaces \\\"$3\\\" not found\" >&2; exit 1 ;; esac\n" +
+		"fi\n"
+	if err := os.WriteFile(filepath.Join(binDir, "kubectl"), []byte(script), 0755); err != nil {
+		t.Fatalf("Failed to write fake kubectl: %v", err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func newTestManager(t *testing.T) (*K8sManager, string) {
+	t.Helper()
+	kubeconfig := filepath.Join(t.TempDir(), "config")
+	t.Setenv("KUBECONFIG", kubeconfig)
+	installFakeKubectl(t, kubeconfig)
+
+	cfg := &config.Config{}
+	return NewK8sManager(cfg, utils.NewLogger(false), config.DefaultFancyConfig()), kubeconfig
+}
+
+func TestVerifyCurrentContextUnchanged(t *testing.T) {
+	k8s, kubeconfig := newTestManager(t)
+	writeKubeconfig(t, kubeconfig, "dev-cluster")
+
+	if err := k8s.switchK8sContext(context.Background(), "dev-cluster"); err != nil {
+		t.Fatalf("switchK8sContext failed: %v", err)
+	}
+	k8s.reapplyPrompt = func(applied, current string) bool {
+		t.Error("Should not prompt when the context is unchanged")
+		return true
+	}
+
+	current, changed := k8s.VerifyCurrentContext(context.Background())
+	if changed || current != "dev-cluster" {
+		t.Errorf("Expected dev-cluster unchanged, got %s (changed=%v)", current, changed)
+	}
+}
+
+func TestVerifyCurrentContextExternalChange(t *testing.T) {
+	testCases := []struct {
+		name            string
+		reapply         bool
+		expectedContext string
+		expectedChanged bool
+		expectedOnDisk  string
+	}{
+		{"Re-apply our mapping", true, "dev-cluster", false, "dev-cluster"},
+		{"Adopt external context", false, "prod-cluster", true, "prod-cluster"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			k8s, kubeconfig := newTestManager(t)
+			writeKubeconfig(t, kubeconfig, "prod-cluster")
+
+			if err := k8s.switchK8sContext(context.Background(), "dev-cluster"); err != nil {
+				t.Fatalf("switchK8sContext failed: %v", err)
+			}
+
+			// Simulate another tool changing the context between steps
+			writeKubeconfig(t, kubeconfig, "prod-cluster")
+
+			var prompted bool
+			k8s.reapplyPrompt = func(applied, current string) bool {
+				prompted = true
+				if applied != "dev-cluster" || current != "prod-cluster" {
+					t.Errorf("Prompt got applied=%s current=%s", applied, current)
+				}
+				return tc.reapply
+			}
+
+			current, changed := k8s.VerifyCurrentContext(context.Background())
+			if !prompted {
+				t.Error("Expected a prompt after an external change")
+			}
+			if current != tc.expectedContext || changed != tc.expectedChanged {
+				t.Errorf("Got %s (changed=%v), expected %s (changed=%v)",
+					current, changed, tc.expectedContext, tc.expectedChanged)
+			}
+
+			onDisk, err := config.ReadCurrentContext(kubeconfig)
+			if err != nil {
+				t.Fatalf("ReadCurrentContext failed: %v", err)
+			}
+			if strings.TrimSpace(onDisk) != tc.expectedOnDisk {
+				t.Errorf("Kubeconfig current-context = %s, expected %s", onDisk, tc.expectedOnDisk)
+			}
+		})
+	}
+}
+
+func TestVerifyCurrentContextNothingApplied(t *testing.T) {
+	k8s, _ := newTestManager(t)
+	if current, changed := k8s.VerifyCurrentContext(context.Background()); current != "" || changed {
+		t.Errorf("Expected no verification without an applied context, got %s (changed=%v)", current, changed)
+	}
+}
+
+func TestRunPreLoginHook(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook test requires a POSIX shell")
+	}
+	k8s, _ := newTestManager(t)
+	marker := filepath.Join(t.TempDir(), "hook-ran")
+	k8s.fancyConfig.KubeOnlyProfiles = map[string]config.KubeProfileConfig{
+		"oidc":   {K8sContext: "oidc-cluster", PreLoginHook: "touch " + marker},
+		"broken": {PreLoginHook: "exit 3"},
+	}
+
+	if err := k8s.RunPreLoginHook(context.Background(), "oidc"); err != nil {
+		t.Fatalf("RunPreLoginHook failed: %v", err)
+	}
+	if _, err := os.Stat(marker); err != nil {
+		t.Errorf("Expected hook to run, marker missing: %v", err)
+	}
+
+	if err := k8s.RunPreLoginHook(context.Background(), "broken"); err == nil {
+		t.Error("Expected failing hook to return an error")
+	}
+	if err := k8s.RunPreLoginHook(context.Background(), "unknown"); err != nil {
+		t.Errorf("Expected no-op for profiles without a hook, got %v", err)
+	}
+}
+
+func TestRunPreLoginHookEnv(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook test requires a POSIX shell")
+	}
+	t.Setenv("AWS_PROFILE", "old")
+	t.Setenv("AWS_SESSION_TOKEN", "stale")
+
+	testCases := []struct {
+		name     string
+		inherit  bool
+		expected string
+	}{
+		{"Inherited AWS variables are stripped", false, "profile= token=\n"},
+		{"InheritAWSEnv keeps them", true, "profile=old token=stale\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			k8s, _ := newTestManager(t)
+			k8s.fancyConfig.Settings.InheritAWSEnv = tc.inherit
+			output := filepath.Join(t.TempDir(), "env")
+			k8s.fancyConfig.KubeOnlyProfiles = map[string]config.KubeProfileConfig{
+				"oidc": {PreLoginHook: `echo "profile=$AWS_PROFILE token=$AWS_SESSION_TOKEN" > ` + output},
+			}
+
+			if err := k8s.RunPreLoginHook(context.Background(), "oidc"); err != nil {
+				t.Fatalf("RunPreLoginHook failed: %v", err)
+			}
+			data, err := os.ReadFile(output)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tc.expected {
+				t.Errorf("Expected the hook to see %q, got %q", tc.expected, data)
+			}
+		})
+	}
+}
+
+func TestSelectKubernetesContextOverride(t *testing.T) {
+	testCases := []struct {
+		name          string
+		override      string
+		expectedError string
+	}{
+		{"Known context", "staging-cluster", ""},
+		{"Unknown context", "prod-cluster", "available contexts: dev-cluster, staging-cluster"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			k8s, kubeconfig := newTestManager(t)
+			content := "apiVersion: v1\nkind: Config\ncurrent-context: dev-cluster\ncontexts:\n" +
+				"- name: dev-cluster\n  context: {cluster: dev}\n" +
+				"- name: staging-cluster\n  context: {cluster: staging}\n"
+			if err := os.WriteFile(kubeconfig, []byte(content), 0600); err != nil {
+				t.Fatal(err)
+			}
+			// The mapping must be ignored in favor of the override
+			k8s.fancyConfig.ProfileConfigs["dev"] = config.ProfileConfig{K8sContext: "dev-cluster"}
+			k8s.SetContextOverride(tc.override)
+
+			line, err := k8s.SelectKubernetesContext(context.Background(), "dev")
+			if tc.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedError) {
+					t.Errorf("Expected error containing %q, got %v", tc.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !strings.Contains(line, tc.override) || !strings.Contains(line, "from --context") {
+				t.Errorf("Expected the overridden context in the summary, got %q", line)
+			}
+			if current, _ := config.ReadCurrentContext(""); current != tc.override {
+				t.Errorf("Expected current-context %s, got %s", tc.override, current)
+			}
+		})
+	}
+}
+
+func TestSelectKubernetesContextDryRun(t *testing.T) {
+	testCases := []struct {
+		name          string
+		mapped        string
+		expectedError string
+	}{
+		{"Mapped context exists", "staging-cluster", ""},
+		{"Mapped context missing", "prod-cluster", "available contexts: dev-cluster, staging-cluster"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			k8s, kubeconfig := newTestManager(t)
+			content := "apiVersion: v1\nkind: Config\ncurrent-context: dev-cluster\ncontexts:\n" +
+				"- name: dev-cluster\n  context: {cluster: dev}\n" +
+				"- name: staging-cluster\n  context: {cluster: staging}\n"
+			if err := os.WriteFile(kubeconfig, []byte(content), 0600); err != nil {
+				t.Fatal(err)
+			}
+			k8s.fancyConfig.ProfileConfigs["dev"] = config.ProfileConfig{K8sContext: tc.mapped}
+			k8s.SetDryRun(true)
+
+			_, err := k8s.SelectKubernetesContext(context.Background(), "dev")
+			if tc.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedError) {
+					t.Errorf("Expected error containing %q, got %v", tc.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if current, _ := config.ReadCurrentContext(""); current != "dev-cluster" {
+				t.Errorf("Expected a dry run to leave current-context dev-cluster, got %s", current)
+			}
+		})
+	}
+}
+
+func TestNamespace(t *testing.T) {
+	testCases := []struct {
+		name       string
+		configured string
+		override   string
+		selected   string
+		expected   string
+	}{
+		{"Default", "", "", "", "default"},
+		{"Configured", "apps", "", "", "apps"},
+		{"Override", "apps", "payments", "", "payments"},
+		{"Selected", "", "", "batch", "batch"},
+		{"Configured over selected", "apps", "", "batch", "apps"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			k8s, _ := newTestManager(t)
+			k8s.fancyConfig.ProfileConfigs["dev"] = config.ProfileConfig{K8sContext: "dev-cluster", Namespace: tc.configured}
+			k8s.SetNamespaceOverride(tc.override)
+			k8s.selectedNamespace = tc.selected
+
+			if got := k8s.Namespace("dev"); got != tc.expected {
+				t.Errorf("Expected %s, got %s", tc.expected, got)
+			}
+			if line := k8s.formatContextSummary("dev-cluster", "dev"); tc.expected != "default" && !strings.Contains(line, tc.expected) {
+				t.Errorf("Expected namespace %s in the summary, got %q", tc.expected, line)
+			}
+		})
+	}
+}
+
+func TestCheckNamespaceExists(t *testing.T) {
+	k8s, _ := newTestManager(t)
+
+	if err := k8s.checkNamespaceExists(context.Background(), "apps"); err != nil {
+		t.Errorf("Expected apps to exist, got %v", err)
+	}
+	if err := k8s.checkNamespaceExists(context.Background(), "payments"); !errors.Is(err, errNamespaceNotFound) {
+		t.Errorf("Expected errNamespaceNotFound, got %v", err)
+	}
+}
+
+func TestSelectKubernetesContextLast(t *testing.T) {
+	k8s, kubeconfig := newTestManager(t)
+	content := "apiVersion: v1\nkind: Config\ncurrent-context: dev-cluster\ncontexts:\n" +
+		"- name: dev-cluster\n  context: {cluster: dev}\n" +
+		"- name: staging-cluster\n  context: {cluster: staging}\n"
+	if err := os.WriteFile(kubeconfig, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	// An unconfigured profile would open the picker
+	k8s.SetLastContext("staging-cluster")
+
+	line, err := k8s.SelectKubernetesContext(context.Background(), "unconfigured")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(line, "staging-cluster") {
+		t.Errorf("Expected the last context in the summary, got %q", line)
+	}
+	if current, _ := config.ReadCurrentContext(""); current != "staging-cluster" {
+		t.Errorf("Expected current-context staging-cluster, got %s", current)
+	}
+}
@@ -0,0 +1,176 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"fancy-login/internal/config"
+	"fancy-login/internal/platform"
+	"fancy-login/internal/state"
+	"fancy-login/internal/utils"
+)
+
+// Terminal title escapes. xterm-compatible terminals keep a title stack, so
+// pushing before we set ours lets us restore whatever was there before.
+const (
+	titlePush = "\033[22;0t"
+	titlePop  = "\033[23;0t"
+)
+
+// k9sWindowTitle builds the title shown while k9s runs, e.g.
+// "k9s — dev-cluster/team-a — profile dev"
+func k9sWindowTitle(contextName, namespace, awsProfile string) string {
+	target := namespace
+	if contextName != "" {
+		target = contextName + "/" + namespace
+	}
+	return fmt.Sprintf("k9s — %s — profile %s", target, awsProfile)
+}
+
+// pushTerminalTitle saves the current title and sets a new one
+func pushTerminalTitle(w io.Writer, title string) {
+	fmt.Fprint(w, titlePush+platform.TitleEscape(title))
+}
+
+// popTerminalTitle restores the title saved by pushTerminalTitle
+func popTerminalTitle(w io.Writer) {
+	fmt.Fprint(w, titlePop)
+}
+
+// registerK9sSession records a running k9s in the state file, pruning
+// entries whose process has exited
+func (k8s *K8sManager) registerK9sSession(session state.K9sSession) {
+	err := state.Update(func(st *state.State) error {
+		st.PruneK9sSessions(utils.ProcessAlive)
+		st.AddK9sSession(session)
+		return nil
+	})
+	if err != nil {
+		k8s.logger.FancyLog(fmt.Sprintf("Could not record k9s session: %v", err))
+	}
+}
+
+// unregisterK9sSession removes a k9s session from the state file once it exits
+func (k8s *K8sManager) unregisterK9sSession(pid int) {
+	err := state.Update(func(st *state.State) error {
+		st.RemoveK9sSession(pid)
+		st.PruneK9sSessions(utils.ProcessAlive)
+		return nil
+	})
+	if err != nil {
+		k8s.logger.FancyLog(fmt.Sprintf("Could not clear k9s session: %v", err))
+	}
+}
+
+// ActiveK9sSessions returns the k9s sessions launched by fancy-login that are
+// still running. Stale entries are removed from the state file.
+func ActiveK9sSessions() ([]state.K9sSession, error) {
+	var sessions []state.K9sSession
+	err := state.Update(func(st *state.State) error {
+		st.PruneK9sSessions(utils.ProcessAlive)
+		sessions = st.K9sSessions
+		return nil
+	})
+	return sessions, err
+}
+
+// newK9sSession describes a k9s process that was just started
+func newK9sSession(pid int, awsProfile, contextName, namespace string) state.K9sSession {
+	return state.K9sSession{
+		PID:       pid,
+		Profile:   awsProfile,
+		Context:   contextName,
+		Namespace: namespace,
+		StartedAt: time.Now(),
+	}
+}
+
+// K9sNotInstalled reports whether k9s should launch for awsProfile but
+// isn't on PATH
+func (k8s *K8sManager) K9sNotInstalled(awsProfile string) bool {
+	if !k8s.fancyConfig.ShouldAutoLaunchK9s(awsProfile) {
+		return false
+	}
+	_, err := exec.LookPath("k9s")
+	return err != nil
+}
+
+// k9sContext returns the context k9s will open against
+func (k8s *K8sManager) k9sContext() string {
+	if k8s.appliedContext != "" {
+		return k8s.appliedContext
+	}
+	current, err := config.ReadCurrentContext(k8s.kubeconfigPath)
+	if err != nil {
+		return ""
+	}
+	return current
+}
+
+// k9sArgs returns the arguments k9s is started with: the namespace,
+// --readonly for read-only profiles, then the profile's k9s_args with
+// {{namespace}} and {{context}} replaced. Each entry stays one argument,
+// spaces and all.
+func (k8s *K8sManager) k9sArgs(awsProfile, namespace, contextName string) []string {
+	args := []string{"-n", namespace}
+	if k8s.fancyConfig.ShouldK9sReadonly(awsProfile) {
+		args = append(args, "--readonly")
+	}
+	pc, err := k8s.fancyConfig.GetProfileConfig(awsProfile)
+	if err != nil {
+		return args
+	}
+	replacer := strings.NewReplacer("{{namespace}}", namespace, "{{context}}", contextName)
+	for _, arg := range pc.K9sArgs {
+		args = append(args, replacer.Replace(arg))
+	}
+	return args
+}
+
+// k9sCommandLine renders the k9s command of awsProfile for dry runs,
+// quoting arguments with spaces
+func (k8s *K8sManager) k9sCommandLine(awsProfile string) string {
+	args := k8s.k9sArgs(awsProfile, k8s.Namespace(awsProfile), k8s.k9sContext())
+	for i, arg := range args {
+		if arg == "" || strings.ContainsAny(arg, " \t") {
+			args[i] = strconv.Quote(arg)
+		}
+	}
+	return "k9s " + strings.Join(args, " ")
+}
+
+// OpenK9s switches to the profile's configured context and launches k9s in
+// its namespace, without any of the AWS steps of a login. A profile without
+// a context keeps the current one. Unlike a login, a failed switch is an
+// error, since k9s would otherwise open against the wrong cluster.
+func (k8s *K8sManager) OpenK9s(ctx context.Context, awsProfile string) error {
+	if _, err := k8s.fancyConfig.GetProfileConfig(awsProfile); err != nil {
+		return fmt.Errorf("profile %s not configured: %w", awsProfile, err)
+	}
+	k8s.useProfileKubeconfig(awsProfile)
+	if err := k8s.RunPreLoginHook(ctx, awsProfile); err != nil {
+		return err
+	}
+
+	if contextName := k8s.fancyConfig.GetK8sContextForProfile(awsProfile); contextName != "" {
+		if err := k8s.ensureContext(ctx, awsProfile, contextName); err != nil {
+			return err
+		}
+		if err := k8s.switchK8sContext(ctx, contextName); err != nil {
+			return fmt.Errorf("failed to switch to context %s: %w", contextName, err)
+		}
+	} else {
+		k8s.logger.FancyLog(fmt.Sprintf("Profile %s has no Kubernetes context configured; keeping the current context", awsProfile))
+	}
+
+	if k8s.dryRun {
+		k8s.logger.LogPlanned("launch: " + k8s.k9sCommandLine(awsProfile))
+		return nil
+	}
+	return k8s.launchK9sWithNamespace(ctx, awsProfile)
+}
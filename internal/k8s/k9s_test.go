@@ -0,0 +1,208 @@
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"fancy-login/internal/config"
+	"fancy-login/internal/state"
+	"fancy-login/internal/utils"
+)
+
+func TestK9sWindowTitle(t *testing.T) {
+	testCases := []struct {
+		name      string
+		context   string
+		namespace string
+		expected  string
+	}{
+		{"With context", "dev-cluster", "team-a", "k9s — dev-cluster/team-a — profile dev"},
+		{"Unknown context", "", "team-a", "k9s — team-a — profile dev"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := k9sWindowTitle(tc.context, tc.namespace, "dev"); got != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestTerminalTitleRestore(t *testing.T) {
+	var buf bytes.Buffer
+	pushTerminalTitle(&buf, "k9s — dev")
+	popTerminalTitle(&buf)
+
+	expected := "\033[22;0t\033]0;k9s — dev\007\033[23;0t"
+	if buf.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, buf.String())
+	}
+}
+
+func TestActiveK9sSessionsPrunesStalePids(t *testing.T) {
+	t.Setenv("FANCY_STATE_DIR", t.TempDir())
+
+	st := &state.State{}
+	st.AddK9sSession(newK9sSession(os.Getpid(), "dev", "dev-cluster", "team-a"))
+	// Far above any real pid_max, so never alive
+	st.AddK9sSession(newK9sSession(1<<30, "prod", "prod-cluster", "default"))
+	if err := st.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+
+	sessions, err := ActiveK9sSessions()
+	if err != nil {
+		t.Fatalf("ActiveK9sSessions failed: %v", err)
+	}
+	if len(sessions) != 1 || sessions[0].Profile != "dev" {
+		t.Errorf("Expected only the dev session, got %+v", sessions)
+	}
+
+	reloaded, err := state.Load()
+	if err != nil {
+		t.Fatalf("state.Load failed: %v", err)
+	}
+	if len(reloaded.K9sSessions) != 1 {
+		t.Errorf("Expected stale session removed from state file, got %+v", reloaded.K9sSessions)
+	}
+}
+
+func TestRemoveK9sSession(t *testing.T) {
+	st := &state.State{}
+	st.AddK9sSession(newK9sSession(100, "dev", "", "default"))
+	st.AddK9sSession(newK9sSession(200, "prod", "", "default"))
+	st.AddK9sSession(newK9sSession(100, "dev", "", "team-a"))
+
+	if len(st.K9sSessions) != 2 {
+		t.Fatalf("Expected re-adding a pid to replace its entry, got %+v", st.K9sSessions)
+	}
+	st.RemoveK9sSession(100)
+	if len(st.K9sSessions) != 1 || st.K9sSessions[0].PID != 200 {
+		t.Errorf("Expected only pid 200 left, got %+v", st.K9sSessions)
+	}
+}
+
+func TestOpenK9s(t *testing.T) {
+	t.Setenv("FANCY_STATE_DIR", t.TempDir())
+	t.Setenv("TERM", "dumb")
+	k8s, kubeconfig := newTestManager(t)
+	content := "apiVersion: v1\nkind: Config\ncurrent-context: dev-cluster\ncontexts:\n" +
+		"- name: dev-cluster\n  context: {cluster: dev}\n" +
+		"- name: staging-cluster\n  context: {cluster: staging}\n"
+	if err := os.WriteFile(kubeconfig, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	k8s.fancyConfig.ProfileConfigs["staging"] = config.ProfileConfig{K8sContext: "staging-cluster", Namespace: "apps"}
+	k8s.fancyConfig.ProfileConfigs["gone"] = config.ProfileConfig{K8sContext: "gone-cluster"}
+
+	// The fake k9s records its arguments and AWS_PROFILE
+	binDir := t.TempDir()
+	record := filepath.Join(binDir, "record")
+	script := "#!/bin/sh\necho \"$* $AWS_PROFILE\" > " + record + "\n"
+	if err := os.WriteFile(filepath.Join(binDir, "k9s"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	if err := k8s.OpenK9s(context.Background(), "staging"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if current, _ := config.ReadCurrentContext(""); current != "staging-cluster" {
+		t.Errorf("Expected current-context staging-cluster, got %s", current)
+	}
+	if data, _ := os.ReadFile(record); strings.TrimSpace(string(data)) != "-n apps staging" {
+		t.Errorf("Expected k9s -n apps with AWS_PROFILE staging, got %q", data)
+	}
+
+	for _, profile := range []string{"gone", "missing"} {
+		if err := k8s.OpenK9s(context.Background(), profile); err == nil {
+			t.Errorf("Expected an error for %s", profile)
+		}
+	}
+}
+
+func TestOpenK9sProfileKubeconfig(t *testing.T) {
+	t.Setenv("FANCY_STATE_DIR", t.TempDir())
+	t.Setenv("TERM", "dumb")
+	k8s, kubeconfig := newTestManager(t)
+	writeKubeconfig(t, kubeconfig, "dev-cluster")
+	prod := filepath.Join(t.TempDir(), "prod.yaml")
+	writeKubeconfig(t, prod, "dev-cluster")
+	k8s.fancyConfig.ProfileConfigs["prod"] = config.ProfileConfig{K8sContext: "prod-cluster", Kubeconfig: prod}
+
+	binDir := t.TempDir()
+	record := filepath.Join(binDir, "record")
+	script := "#!/bin/sh\necho \"$KUBECONFIG\" > " + record + "\n"
+	if err := os.WriteFile(filepath.Join(binDir, "k9s"), []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	if err := k8s.OpenK9s(context.Background(), "prod"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if data, _ := os.ReadFile(record); strings.TrimSpace(string(data)) != prod {
+		t.Errorf("Expected k9s to get KUBECONFIG=%s, got %q", prod, data)
+	}
+	if current, _ := config.ReadCurrentContext(kubeconfig); current != "dev-cluster" {
+		t.Errorf("Expected the default kubeconfig to keep dev-cluster, got %s", current)
+	}
+}
+
+func TestK9sArgs(t *testing.T) {
+	fc := config.DefaultFancyConfig()
+	fc.ProfileConfigs["prod"] = config.ProfileConfig{K9sArgs: []string{"--refresh", "5", "--command", "pods", "--logFile", "/tmp/k9s {{context}}/{{namespace}}.log"}}
+	fc.ProfileConfigs["dev"] = config.ProfileConfig{}
+	fc.ProfileConfigs["live"] = config.ProfileConfig{Environment: "prod", K9sArgs: []string{"--command", "pods"}}
+	k8s := NewK8sManager(&config.Config{}, utils.NewLogger(false), fc)
+
+	testCases := []struct {
+		profile  string
+		expected []string
+	}{
+		{"prod", []string{"-n", "apps", "--refresh", "5", "--command", "pods", "--logFile", "/tmp/k9s prod-cluster/apps.log"}},
+		{"dev", []string{"-n", "apps"}},
+		{"live", []string{"-n", "apps", "--readonly", "--command", "pods"}},
+		{"missing", []string{"-n", "apps"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.profile, func(t *testing.T) {
+			if got := k8s.k9sArgs(tc.profile, "apps", "prod-cluster"); !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("Expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestHandleK9sLaunchNotInstalled(t *testing.T) {
+	fc := config.DefaultFancyConfig()
+	fc.ProfileConfigs["dev"] = config.ProfileConfig{K9sAutoLaunch: true}
+	fc.ProfileConfigs["quiet"] = config.ProfileConfig{}
+	k8s := NewK8sManager(&config.Config{UseK9S: true}, utils.NewLogger(false), fc)
+	binDir := t.TempDir()
+	t.Setenv("PATH", binDir)
+
+	if !k8s.K9sNotInstalled("dev") {
+		t.Error("Expected k9s to be reported missing for dev")
+	}
+	if k8s.K9sNotInstalled("quiet") {
+		t.Error("Expected no report for a profile without k9s_auto_launch")
+	}
+	if err := k8s.HandleK9sLaunch(context.Background(), "dev"); err != nil {
+		t.Errorf("Expected a missing k9s not to fail the login, got %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(binDir, "k9s"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if k8s.K9sNotInstalled("dev") {
+		t.Error("Expected k9s on PATH to be found")
+	}
+}
@@ -0,0 +1,253 @@
+package k8s
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+
+	"fancy-login/internal/utils"
+)
+
+// Kubeconfig lists the contexts of the kubeconfig, reads and switches its
+// current-context, sets a context's namespace and asks the current
+// context's cluster whether it answers and for its namespaces. The
+// built-in one edits the files and calls the API server itself, so kubectl
+// doesn't need to be installed; kubectlKubeconfig is the fallback for
+// setups only kubectl understands.
+type Kubeconfig interface {
+	Contexts(ctx context.Context) ([]string, error)
+	CurrentContext(ctx context.Context) (string, error)
+	UseContext(ctx context.Context, name string) error
+	SetNamespace(ctx context.Context, name, namespace string) (bool, error)
+	Namespaces(ctx context.Context) ([]string, error)
+	CheckCluster(ctx context.Context) error
+}
+
+// clientcmdKubeconfig reads and writes the kubeconfig with clientcmd, the
+// loader kubectl uses. A path is used alone, like kubectl --kubeconfig; an
+// empty one honors KUBECONFIG, including a list of files, and falls back
+// to ~/.kube/config.
+type clientcmdKubeconfig struct {
+	path string
+}
+
+// loadingRules are built per call, so changes to KUBECONFIG and to the
+// files by other tools are seen
+func (k clientcmdKubeconfig) loadingRules() *clientcmd.ClientConfigLoadingRules {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = k.path
+	return rules
+}
+
+func (k clientcmdKubeconfig) Contexts(ctx context.Context) ([]string, error) {
+	cfg, err := k.loadingRules().Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+	names := make([]string, 0, len(cfg.Contexts))
+	for name := range cfg.Contexts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (k clientcmdKubeconfig) CurrentContext(ctx context.Context) (string, error) {
+	cfg, err := k.loadingRules().Load()
+	if err != nil {
+		return "", fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+	if cfg.CurrentContext == "" {
+		return "", fmt.Errorf("current-context is not set")
+	}
+	return cfg.CurrentContext, nil
+}
+
+// UseContext sets current-context like `kubectl config use-context`: in
+// the first existing file of KUBECONFIG
+func (k clientcmdKubeconfig) UseContext(ctx context.Context, name string) error {
+	rules := k.loadingRules()
+	cfg, err := rules.GetStartingConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+	if _, ok := cfg.Contexts[name]; !ok {
+		return fmt.Errorf("no context exists with the name %q", name)
+	}
+	cfg.CurrentContext = name
+	if err := clientcmd.ModifyConfig(rules, *cfg, true); err != nil {
+		return fmt.Errorf("failed to write kubeconfig: %w", err)
+	}
+	return nil
+}
+
+// SetNamespace sets the namespace of the context name like `kubectl config
+// set-context name --namespace`, in the file defining the context. It
+// reports whether the namespace changed.
+func (k clientcmdKubeconfig) SetNamespace(ctx context.Context, name, namespace string) (bool, error) {
+	rules := k.loadingRules()
+	cfg, err := rules.GetStartingConfig()
+	if err != nil {
+		return false, fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+	kubeContext, ok := cfg.Contexts[name]
+	if !ok {
+		return false, fmt.Errorf("no context exists with the name %q", name)
+	}
+	if kubeContext.Namespace == namespace {
+		return false, nil
+	}
+	kubeContext.Namespace = namespace
+	if err := clientcmd.ModifyConfig(rules, *cfg, true); err != nil {
+		return false, fmt.Errorf("failed to write kubeconfig: %w", err)
+	}
+	return true, nil
+}
+
+// Namespaces lists the namespaces of the current context's cluster
+func (k clientcmdKubeconfig) Namespaces(ctx context.Context) ([]string, error) {
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+		} `json:"items"`
+	}
+	if err := k.get(ctx, "/api/v1/namespaces", &list); err != nil {
+		return nil, err
+	}
+	names := make([]string, len(list.Items))
+	for i, item := range list.Items {
+		names[i] = item.Metadata.Name
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// CheckCluster asks the current context's cluster for its version, which
+// every authenticated user may read
+func (k clientcmdKubeconfig) CheckCluster(ctx context.Context) error {
+	var version struct {
+		GitVersion string `json:"gitVersion"`
+	}
+	return k.get(ctx, "/version", &version)
+}
+
+// get GETs path from the API server of the current context, with the
+// context's credentials, and decodes the JSON answer into v
+func (k clientcmdKubeconfig) get(ctx context.Context, path string, v interface{}) error {
+	restConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(k.loadingRules(), &clientcmd.ConfigOverrides{}).ClientConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load kubeconfig: %w", err)
+	}
+	client, err := rest.HTTPClientFor(restConfig)
+	if err != nil {
+		return err
+	}
+	u, err := url.JoinPath(restConfig.Host, path)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		// The API server explains refusals in a Status object
+		var status struct {
+			Message string `json:"message"`
+		}
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		if json.Unmarshal(body, &status) == nil && status.Message != "" {
+			return fmt.Errorf("%s: %s", resp.Status, status.Message)
+		}
+		return fmt.Errorf("%s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// kubectlKubeconfig shells out to kubectl, under the kubectl timeout. A
+// path is passed as --kubeconfig.
+type kubectlKubeconfig struct {
+	timeout time.Duration
+	path    string
+}
+
+func (k kubectlKubeconfig) Contexts(ctx context.Context) ([]string, error) {
+	output, err := k.run(ctx, "config", "get-contexts", "-o", "name")
+	if err != nil {
+		return nil, err
+	}
+	if output == "" {
+		return nil, nil
+	}
+	return strings.Split(output, "\n"), nil
+}
+
+func (k kubectlKubeconfig) CurrentContext(ctx context.Context) (string, error) {
+	return k.run(ctx, "config", "current-context")
+}
+
+func (k kubectlKubeconfig) UseContext(ctx context.Context, name string) error {
+	_, err := k.run(ctx, "config", "use-context", name)
+	return err
+}
+
+// SetNamespace can't tell whether kubectl changed anything, so it always
+// reports a change
+func (k kubectlKubeconfig) SetNamespace(ctx context.Context, name, namespace string) (bool, error) {
+	if _, err := k.run(ctx, "config", "set-context", name, "--namespace", namespace); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (k kubectlKubeconfig) Namespaces(ctx context.Context) ([]string, error) {
+	output, err := k.run(ctx, "get", "namespaces", "-o", "name")
+	if err != nil || output == "" {
+		return nil, err
+	}
+	names := strings.Split(output, "\n")
+	for i, name := range names {
+		names[i] = strings.TrimPrefix(name, "namespace/")
+	}
+	return names, nil
+}
+
+func (k kubectlKubeconfig) CheckCluster(ctx context.Context) error {
+	_, err := k.run(ctx, "get", "--raw", "/version")
+	return err
+}
+
+// run runs `kubectl args...` and returns its trimmed output
+func (k kubectlKubeconfig) run(ctx context.Context, args ...string) (string, error) {
+	ctx, cancel := utils.WithStepTimeout(ctx, k.timeout)
+	defer cancel()
+
+	name := "kubectl " + args[0] + " " + args[1]
+	if k.path != "" {
+		args = append([]string{"--kubeconfig", k.path}, args...)
+	}
+	cmd := utils.CommandContext(ctx, "kubectl", args...)
+	output, err := cmd.Output()
+	if err != nil {
+		return "", utils.StepError(ctx, name, k.timeout, err)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
@@ -0,0 +1,253 @@
+package k8s
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"fancy-login/internal/config"
+	"fancy-login/internal/utils"
+)
+
+func TestClientcmdKubeconfig(t *testing.T) {
+	kubeconfig := filepath.Join(t.TempDir(), "config")
+	t.Setenv("KUBECONFIG", kubeconfig)
+	writeKubeconfig(t, kubeconfig, "prod-cluster")
+	k := clientcmdKubeconfig{}
+	ctx := context.Background()
+
+	contexts, err := k.Contexts(ctx)
+	if err != nil || !reflect.DeepEqual(contexts, []string{"dev-cluster", "prod-cluster"}) {
+		t.Errorf("Expected [dev-cluster prod-cluster], got %v, %v", contexts, err)
+	}
+	if current, err := k.CurrentContext(ctx); err != nil || current != "prod-cluster" {
+		t.Errorf("Expected prod-cluster, got %q, %v", current, err)
+	}
+
+	if err := k.UseContext(ctx, "dev-cluster"); err != nil {
+		t.Fatalf("UseContext failed: %v", err)
+	}
+	if onDisk, _ := config.ReadCurrentContext(kubeconfig); onDisk != "dev-cluster" {
+		t.Errorf("Expected current-context dev-cluster on disk, got %q", onDisk)
+	}
+	if current, err := k.CurrentContext(ctx); err != nil || current != "dev-cluster" {
+		t.Errorf("Expected dev-cluster, got %q, %v", current, err)
+	}
+
+	if err := k.UseContext(ctx, "gone-cluster"); err == nil || !strings.Contains(err.Error(), "gone-cluster") {
+		t.Errorf("Expected an error naming gone-cluster, got %v", err)
+	}
+	if onDisk, _ := config.ReadCurrentContext(kubeconfig); onDisk != "dev-cluster" {
+		t.Errorf("Expected a failed switch to keep dev-cluster, got %q", onDisk)
+	}
+}
+
+// TestClientcmdKubeconfigList checks that with a KUBECONFIG list the
+// current-context is written where kubectl would write it
+func TestClientcmdKubeconfigList(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "config")
+	second := filepath.Join(dir, "work-config")
+	if err := os.WriteFile(first, []byte("apiVersion: v1\nkind: Config\ncontexts:\n- name: dev-cluster\n  context: {cluster: dev}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	writeKubeconfig(t, second, "prod-cluster")
+	t.Setenv("KUBECONFIG", first+string(os.PathListSeparator)+second)
+	k := clientcmdKubeconfig{}
+
+	if err := k.UseContext(context.Background(), "dev-cluster"); err != nil {
+		t.Fatalf("UseContext failed: %v", err)
+	}
+	if onDisk, _ := config.ReadCurrentContext(first); onDisk != "dev-cluster" {
+		t.Errorf("Expected the first file to get current-context dev-cluster, got %q", onDisk)
+	}
+	if onDisk, _ := config.ReadCurrentContext(second); onDisk != "prod-cluster" {
+		t.Errorf("Expected the second file to keep prod-cluster, got %q", onDisk)
+	}
+	if current, err := k.CurrentContext(context.Background()); err != nil || current != "dev-cluster" {
+		t.Errorf("Expected the merged current-context dev-cluster, got %q, %v", current, err)
+	}
+}
+
+// writeClusterKubeconfig writes a kubeconfig whose current context points
+// at server
+func writeClusterKubeconfig(t *testing.T, path, server string) {
+	t.Helper()
+	content := "apiVersion: v1\nkind: Config\ncurrent-context: dev-cluster\n" +
+		"clusters:\n- name: dev\n  cluster: {server: \"" + server + "\", insecure-skip-tls-verify: true}\n" +
+		"users:\n- name: dev\n  user: {token: secret}\n" +
+		"contexts:\n- name: dev-cluster\n  context: {cluster: dev, user: dev}\n"
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClientcmdNamespaces(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Header.Get("Authorization") != "Bearer secret":
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"kind": "Status", "message": "Unauthorized"}`))
+		case r.URL.Path == "/api/v1/namespaces":
+			w.Write([]byte(`{"items": [{"metadata": {"name": "kube-system"}}, {"metadata": {"name": "apps"}}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	kubeconfig := filepath.Join(t.TempDir(), "config")
+	t.Setenv("KUBECONFIG", kubeconfig)
+	writeClusterKubeconfig(t, kubeconfig, server.URL)
+
+	namespaces, err := clientcmdKubeconfig{}.Namespaces(context.Background())
+	if err != nil || !reflect.DeepEqual(namespaces, []string{"apps", "kube-system"}) {
+		t.Errorf("Expected [apps kube-system], got %v, %v", namespaces, err)
+	}
+
+	content, _ := os.ReadFile(kubeconfig)
+	if err := os.WriteFile(kubeconfig, []byte(strings.Replace(string(content), "token: secret", "token: wrong", 1)), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (clientcmdKubeconfig{}).Namespaces(context.Background()); err == nil || !strings.Contains(err.Error(), "Unauthorized") {
+		t.Errorf("Expected the API server's message, got %v", err)
+	}
+}
+
+func TestKubectlKubeClient(t *testing.T) {
+	kubeconfig := filepath.Join(t.TempDir(), "config")
+	t.Setenv("KUBECONFIG", kubeconfig)
+	installFakeKubectl(t, kubeconfig)
+
+	fc := config.DefaultFancyConfig()
+	fc.Settings.KubeClient = config.KubeClientKubectl
+	k8s := NewK8sManager(&config.Config{}, utils.NewLogger(false), fc)
+
+	// The fake kubectl switches to contexts the kubeconfig doesn't have,
+	// which the built-in client would refuse
+	if err := k8s.switchK8sContext(context.Background(), "exotic-cluster"); err != nil {
+		t.Fatalf("switchK8sContext failed: %v", err)
+	}
+	if onDisk, _ := config.ReadCurrentContext(kubeconfig); onDisk != "exotic-cluster" {
+		t.Errorf("Expected kubectl to switch to exotic-cluster, got %q", onDisk)
+	}
+}
+
+func TestSelectKubernetesContextClusterCheck(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"gitVersion": "v1.31.0"}`))
+	}))
+	defer server.Close()
+	gone := httptest.NewTLSServer(http.NotFoundHandler())
+	gone.Close()
+
+	testCases := []struct {
+		name     string
+		server   string
+		skip     bool
+		expected string
+	}{
+		{"Reachable", server.URL, false, "(reachable)"},
+		{"Unreachable", gone.URL, false, "(unreachable: connection refused)"},
+		{"Skipped", gone.URL, true, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			k8s, kubeconfig := newTestManager(t)
+			writeClusterKubeconfig(t, kubeconfig, tc.server)
+			k8s.fancyConfig.ProfileConfigs["dev"] = config.ProfileConfig{K8sContext: "dev-cluster"}
+			k8s.SetSkipClusterCheck(tc.skip)
+
+			line, err := k8s.SelectKubernetesContext(context.Background(), "dev")
+			if err != nil {
+				t.Fatalf("Expected an unreachable cluster not to fail the run, got %v", err)
+			}
+			if tc.expected == "" {
+				if strings.Contains(line, "reachable") {
+					t.Errorf("Expected no cluster status with the check skipped, got %q", line)
+				}
+				return
+			}
+			if !strings.Contains(line, tc.expected) {
+				t.Errorf("Expected %q in the summary, got %q", tc.expected, line)
+			}
+		})
+	}
+}
+
+func TestSelectKubernetesContextProfileKubeconfig(t *testing.T) {
+	k8s, kubeconfig := newTestManager(t)
+	writeKubeconfig(t, kubeconfig, "dev-cluster")
+	prod := filepath.Join(t.TempDir(), "prod.yaml")
+	if err := os.WriteFile(prod, []byte("apiVersion: v1\nkind: Config\ncontexts:\n- name: prod-only\n  context: {cluster: prod}\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	k8s.fancyConfig.ProfileConfigs["prod"] = config.ProfileConfig{K8sContext: "prod-only", Kubeconfig: prod}
+	k8s.SetSkipClusterCheck(true)
+
+	line, err := k8s.SelectKubernetesContext(context.Background(), "prod")
+	if err != nil {
+		t.Fatalf("SelectKubernetesContext failed: %v", err)
+	}
+	if !strings.Contains(line, "(kubeconfig: "+prod+")") {
+		t.Errorf("Expected the summary to name %s, got %q", prod, line)
+	}
+	if onDisk, _ := config.ReadCurrentContext(prod); onDisk != "prod-only" {
+		t.Errorf("Expected the profile's kubeconfig to get current-context prod-only, got %q", onDisk)
+	}
+	if onDisk, _ := config.ReadCurrentContext(kubeconfig); onDisk != "dev-cluster" {
+		t.Errorf("Expected the default kubeconfig to keep dev-cluster, got %q", onDisk)
+	}
+	if err := k8s.checkContextExists("prod-only"); err != nil {
+		t.Errorf("Expected prod-only to be found in the profile's kubeconfig, got %v", err)
+	}
+}
+
+func TestSelectKubernetesContextSavesNamespace(t *testing.T) {
+	testCases := []struct {
+		name      string
+		enabled   bool
+		namespace string
+		expected  string
+		saved     bool
+	}{
+		{"Saved", true, "apps", "apps", true},
+		{"Setting off", false, "apps", "", false},
+		{"No namespace chosen", true, "", "", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			k8s, kubeconfig := newTestManager(t)
+			writeKubeconfig(t, kubeconfig, "dev-cluster")
+			k8s.fancyConfig.Settings.SetContextNamespace = tc.enabled
+			k8s.fancyConfig.ProfileConfigs["dev"] = config.ProfileConfig{K8sContext: "prod-cluster", Namespace: tc.namespace}
+			k8s.SetSkipClusterCheck(true)
+
+			line, err := k8s.SelectKubernetesContext(context.Background(), "dev")
+			if err != nil {
+				t.Fatalf("SelectKubernetesContext failed: %v", err)
+			}
+			contexts, _ := config.ParseKubernetesContexts(kubeconfig)
+			for _, c := range contexts {
+				if c.Name == "prod-cluster" && c.Namespace != tc.expected {
+					t.Errorf("Expected namespace %q in the kubeconfig, got %q", tc.expected, c.Namespace)
+				}
+			}
+			if saved := strings.Contains(line, "namespace saved to kubeconfig"); saved != tc.saved {
+				t.Errorf("Expected the summary to mention the save: %v, got %q", tc.saved, line)
+			}
+
+			// Saving the same namespace again changes nothing
+			if changed, err := k8s.kubeconfig.SetNamespace(context.Background(), "prod-cluster", tc.expected); err != nil || changed {
+				t.Errorf("Expected no change, got %v, %v", changed, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+// Package kubeplugin runs fancy-login as a kubectl plugin. kubectl finds
+// plugins by executable name, so `kubectl fancy-login` runs a binary (or
+// symlink) called kubectl-fancy_login. The plugin starts from the Kubernetes
+// side: the user picks a context and the AWS profile is inferred from it.
+package kubeplugin
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"fancy-login/internal/config"
+)
+
+// BinaryName is the executable name kubectl runs for `kubectl fancy-login`
+const BinaryName = "kubectl-fancy_login"
+
+// names are the executable names that run the plugin. kubectl-fancy is the
+// short alias for users who expect `kubectl fancy`.
+var names = []string{BinaryName, "kubectl-fancy"}
+
+// Invoked reports whether argv0 is one of the plugin names. kubectl passes
+// the full path of the plugin, and krew installs it as a symlink (a .exe
+// shim on Windows), so only the base name is compared.
+func Invoked(argv0 string) bool {
+	name := strings.TrimSuffix(filepath.Base(argv0), ".exe")
+	for _, n := range names {
+		if name == n {
+			return true
+		}
+	}
+	return false
+}
+
+// eksARN matches the account ID in an EKS cluster ARN, which `aws eks
+// update-kubeconfig` uses as context, cluster and user name
+var eksARN = regexp.MustCompile(`^arn:aws[a-z-]*:eks:[a-z0-9-]+:(\d{12}):cluster/`)
+
+// AccountForContext returns the AWS account ID of an EKS context, or "" if
+// neither its name, cluster nor user is an EKS cluster ARN
+func AccountForContext(c config.KubernetesContext) string {
+	for _, candidate := range []string{c.Name, c.Cluster, c.User} {
+		if matches := eksARN.FindStringSubmatch(candidate); matches != nil {
+			return matches[1]
+		}
+	}
+	return ""
+}
+
+// ProfileForContext infers the profile to log in with for context c. A
+// profile whose k8s_context is c wins; otherwise the AWS profile with the
+// context's account ID is used, which must be unambiguous.
+func ProfileForContext(fc *config.FancyConfig, c config.KubernetesContext) (string, error) {
+	if profile := mappedProfile(fc, c.Name); profile != "" {
+		return profile, nil
+	}
+
+	account := AccountForContext(c)
+	if account == "" {
+		return "", fmt.Errorf("no profile has k8s_context %s and its account cannot be inferred; set k8s_context on the profile to use", c.Name)
+	}
+
+	var candidates []string
+	for name, pc := range fc.ProfileConfigs {
+		if pc.AccountID == account {
+			candidates = append(candidates, name)
+		}
+	}
+	sort.Strings(candidates)
+	switch len(candidates) {
+	case 0:
+		return "", fmt.Errorf("no profile is configured for account %s of context %s; set account_id or k8s_context on the profile to use", account, c.Name)
+	case 1:
+		return candidates[0], nil
+	default:
+		return "", fmt.Errorf("profiles %s all use account %s; set k8s_context %s on the one to use", strings.Join(candidates, ", "), account, c.Name)
+	}
+}
+
+// mappedProfile returns the profile, AWS or kube-only, whose k8s_context is
+// context. Sorting keeps the choice stable if several map to it.
+func mappedProfile(fc *config.FancyConfig, context string) string {
+	var mapped []string
+	for name, pc := range fc.ProfileConfigs {
+		if pc.K8sContext == context {
+			mapped = append(mapped, name)
+		}
+	}
+	for name, kp := range fc.KubeOnlyProfiles {
+		if kp.K8sContext == context {
+			mapped = append(mapped, name)
+		}
+	}
+	if len(mapped) == 0 {
+		return ""
+	}
+	sort.Strings(mapped)
+	return mapped[0]
+}
+
+// PickerEntry is one line of the context picker. Context is empty for group
+// headers and spacers.
+type PickerEntry struct {
+	Context     string
+	DisplayText string
+}
+
+// PickerEntries groups contexts by AWS account, accounts in ID order and
+// contexts without an account last. Each context shows the profile it logs
+// in with, or why none could be inferred.
+func PickerEntries(fc *config.FancyConfig, contexts []config.KubernetesContext) []PickerEntry {
+	groups := make(map[string][]config.KubernetesContext)
+	for _, c := range contexts {
+		account := accountForEntry(fc, c)
+		groups[account] = append(groups[account], c)
+	}
+
+	accounts := make([]string, 0, len(groups))
+	for account := range groups {
+		if account != "" {
+			accounts = append(accounts, account)
+		}
+	}
+	sort.Strings(accounts)
+	if len(groups[""]) > 0 {
+		accounts = append(accounts, "")
+	}
+
+	var entries []PickerEntry
+	for i, account := range accounts {
+		if i > 0 {
+			entries = append(entries, PickerEntry{})
+		}
+		entries = append(entries, PickerEntry{DisplayText: groupHeader(fc, account)})
+
+		group := groups[account]
+		sort.Slice(group, func(i, j int) bool { return group[i].Name < group[j].Name })
+		for _, c := range group {
+			label := "no matching profile"
+			if profile, err := ProfileForContext(fc, c); err == nil {
+				label = "→ " + profile
+			}
+			entries = append(entries, PickerEntry{
+				Context:     c.Name,
+				DisplayText: fmt.Sprintf("%s  (%s)", c.Name, label),
+			})
+		}
+	}
+	return entries
+}
+
+// accountForEntry is the account a context is grouped under: the account of
+// its mapped profile if it has one, else the one in its EKS ARN
+func accountForEntry(fc *config.FancyConfig, c config.KubernetesContext) string {
+	if profile := mappedProfile(fc, c.Name); profile != "" {
+		if pc, ok := fc.ProfileConfigs[profile]; ok && pc.AccountID != "" {
+			return pc.AccountID
+		}
+	}
+	return AccountForContext(c)
+}
+
+// groupHeader labels an account group with the alias of a profile using it
+func groupHeader(fc *config.FancyConfig, account string) string {
+	if account == "" {
+		return "=== OTHER CONTEXTS ==="
+	}
+	names := make([]string, 0, len(fc.ProfileConfigs))
+	for name := range fc.ProfileConfigs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if pc := fc.ProfileConfigs[name]; pc.AccountID == account && pc.AccountAlias != "" {
+			return fmt.Sprintf("=== ACCOUNT %s (%s) ===", account, pc.AccountAlias)
+		}
+	}
+	return fmt.Sprintf("=== ACCOUNT %s ===", account)
+}
+
+// SelectedContext maps the line fzf printed back to its context. fzf may
+// strip leading whitespace, so lines are compared trimmed.
+func SelectedContext(entries []PickerEntry, selected string) (string, error) {
+	selected = strings.TrimSpace(selected)
+	for _, entry := range entries {
+		if entry.Context != "" && strings.TrimSpace(entry.DisplayText) == selected {
+			return entry.Context, nil
+		}
+	}
+	return "", fmt.Errorf("invalid context selection %q", selected)
+}
@@ -0,0 +1,139 @@
+package kubeplugin
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"fancy-login/internal/config"
+)
+
+func TestInvoked(t *testing.T) {
+	testCases := []struct {
+		argv0    string
+		expected bool
+	}{
+		{"kubectl-fancy_login", true},
+		{"/usr/local/bin/kubectl-fancy_login", true},
+		{"/home/me/.krew/bin/kubectl-fancy_login", true},
+		{"kubectl-fancy_login.exe", true},
+		{"kubectl-fancy", true},
+		{"fancy-login-go", false},
+		{"kubectl-fancy_login-go", false},
+		{"fancy", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.argv0, func(t *testing.T) {
+			if got := Invoked(tc.argv0); got != tc.expected {
+				t.Errorf("Expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func testConfig() *config.FancyConfig {
+	fc := config.DefaultFancyConfig()
+	fc.ProfileConfigs = map[string]config.ProfileConfig{
+		"dev":       {AccountID: "111111111111", AccountAlias: "acme-dev"},
+		"prod":      {AccountID: "222222222222", K8sContext: "prod-cluster"},
+		"prod-read": {AccountID: "222222222222"},
+	}
+	fc.KubeOnlyProfiles = map[string]config.KubeProfileConfig{
+		"lab": {K8sContext: "lab"},
+	}
+	return fc
+}
+
+func eksContext(account, cluster string) config.KubernetesContext {
+	arn := "arn:aws:eks:eu-central-1:" + account + ":cluster/" + cluster
+	return config.KubernetesContext{Name: arn, Cluster: arn, User: arn}
+}
+
+func TestAccountForContext(t *testing.T) {
+	testCases := []struct {
+		name     string
+		context  config.KubernetesContext
+		expected string
+	}{
+		{"EKS ARN context", eksContext("111111111111", "dev"), "111111111111"},
+		{"Renamed context", config.KubernetesContext{Name: "dev", Cluster: "arn:aws:eks:us-east-1:333333333333:cluster/dev"}, "333333333333"},
+		{"GovCloud", config.KubernetesContext{Name: "arn:aws-us-gov:eks:us-gov-west-1:444444444444:cluster/gov"}, "444444444444"},
+		{"Not EKS", config.KubernetesContext{Name: "kind-kind", Cluster: "kind-kind", User: "kind-kind"}, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := AccountForContext(tc.context); got != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestProfileForContext(t *testing.T) {
+	testCases := []struct {
+		name          string
+		context       config.KubernetesContext
+		expected      string
+		expectedError string
+	}{
+		{"Mapped context", config.KubernetesContext{Name: "prod-cluster"}, "prod", ""},
+		{"Kube-only profile", config.KubernetesContext{Name: "lab"}, "lab", ""},
+		{"Inferred from account", eksContext("111111111111", "dev"), "dev", ""},
+		{"Ambiguous account", eksContext("222222222222", "other"), "", "prod, prod-read all use account 222222222222"},
+		{"Unknown account", eksContext("999999999999", "x"), "", "no profile is configured for account 999999999999"},
+		{"No account", config.KubernetesContext{Name: "kind-kind"}, "", "cannot be inferred"},
+	}
+
+	fc := testConfig()
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			profile, err := ProfileForContext(fc, tc.context)
+			if tc.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.expectedError) {
+					t.Errorf("Expected error containing %q, got %v", tc.expectedError, err)
+				}
+				return
+			}
+			if err != nil || profile != tc.expected {
+				t.Errorf("Expected %s, got %s (%v)", tc.expected, profile, err)
+			}
+		})
+	}
+}
+
+func TestPickerEntries(t *testing.T) {
+	contexts := []config.KubernetesContext{
+		{Name: "kind-kind"},
+		{Name: "prod-cluster"},
+		eksContext("111111111111", "dev"),
+	}
+	devARN := contexts[2].Name
+
+	entries := PickerEntries(testConfig(), contexts)
+	var lines []string
+	for _, entry := range entries {
+		lines = append(lines, entry.DisplayText)
+	}
+	expected := []string{
+		"=== ACCOUNT 111111111111 (acme-dev) ===",
+		devARN + "  (→ dev)",
+		"",
+		"=== ACCOUNT 222222222222 ===",
+		"prod-cluster  (→ prod)",
+		"",
+		"=== OTHER CONTEXTS ===",
+		"kind-kind  (no matching profile)",
+	}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Fatalf("Expected %q, got %q", expected, lines)
+	}
+
+	if name, err := SelectedContext(entries, "  prod-cluster  (→ prod)\n"); err != nil || name != "prod-cluster" {
+		t.Errorf("Expected prod-cluster, got %q (%v)", name, err)
+	}
+	if _, err := SelectedContext(entries, "=== OTHER CONTEXTS ==="); err == nil {
+		t.Error("Expected a group header to be rejected")
+	}
+}
@@ -0,0 +1,137 @@
+package kubeplugin
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PluginName is the name the plugin is published under in the krew index
+const PluginName = "fancy-login"
+
+// Archive is a release archive for one platform
+type Archive struct {
+	OS     string
+	Arch   string
+	URI    string
+	SHA256 string
+	// Bin is the path of the executable inside the archive
+	Bin string
+}
+
+// releaseArchive matches the archive names `make release` creates
+var releaseArchive = regexp.MustCompile(`^fancy-login-go-([a-z0-9]+)-([a-z0-9]+)\.(tar\.gz|zip)$`)
+
+// ReleaseArchives lists the release archives in dir, with their checksums
+// and download URLs under baseURL
+func ReleaseArchives(dir, baseURL string) ([]Archive, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var archives []Archive
+	for _, entry := range entries {
+		matches := releaseArchive.FindStringSubmatch(entry.Name())
+		if matches == nil {
+			continue
+		}
+		sum, err := fileSHA256(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		bin := fmt.Sprintf("fancy-login-go-%s-%s", matches[1], matches[2])
+		if matches[1] == "windows" {
+			bin += ".exe"
+		}
+		archives = append(archives, Archive{
+			OS:     matches[1],
+			Arch:   matches[2],
+			URI:    baseURL + "/" + entry.Name(),
+			SHA256: sum,
+			Bin:    bin,
+		})
+	}
+	if len(archives) == 0 {
+		return nil, fmt.Errorf("no release archives in %s; run make release first", dir)
+	}
+	return archives, nil
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// krewManifest is the krew plugin manifest format
+type krewManifest struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+	Metadata   struct {
+		Name string `yaml:"name"`
+	} `yaml:"metadata"`
+	Spec struct {
+		Version          string         `yaml:"version"`
+		Homepage         string         `yaml:"homepage"`
+		ShortDescription string         `yaml:"shortDescription"`
+		Description      string         `yaml:"description"`
+		Platforms        []krewPlatform `yaml:"platforms"`
+	} `yaml:"spec"`
+}
+
+type krewPlatform struct {
+	Selector struct {
+		MatchLabels map[string]string `yaml:"matchLabels"`
+	} `yaml:"selector"`
+	URI    string `yaml:"uri"`
+	SHA256 string `yaml:"sha256"`
+	Bin    string `yaml:"bin"`
+}
+
+// Manifest renders the krew manifest for version. krew links Bin as
+// kubectl-fancy_login, which is how the binary knows it runs as a plugin.
+func Manifest(version string, archives []Archive) ([]byte, error) {
+	var m krewManifest
+	m.APIVersion = "krew.googlecontainertools.github.com/v1alpha2"
+	m.Kind = "Plugin"
+	m.Metadata.Name = PluginName
+	m.Spec.Version = version
+	m.Spec.Homepage = "https://github.com/reinkes/go-fancy-login"
+	m.Spec.ShortDescription = "Pick a context and log in to its AWS account"
+	m.Spec.Description = "Lists your contexts grouped by AWS account, logs in to the AWS profile\n" +
+		"of the selected context via SSO, switches to it and optionally opens k9s.\n"
+
+	sorted := append([]Archive(nil), archives...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].OS != sorted[j].OS {
+			return sorted[i].OS < sorted[j].OS
+		}
+		return sorted[i].Arch < sorted[j].Arch
+	})
+	for _, a := range sorted {
+		var p krewPlatform
+		p.Selector.MatchLabels = map[string]string{"os": a.OS, "arch": a.Arch}
+		p.URI = a.URI
+		p.SHA256 = a.SHA256
+		p.Bin = a.Bin
+		m.Spec.Platforms = append(m.Spec.Platforms, p)
+	}
+
+	return yaml.Marshal(&m)
+}
@@ -0,0 +1,63 @@
+package kubeplugin
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestReleaseArchivesAndManifest(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		"fancy-login-go-linux-amd64.tar.gz",
+		"fancy-login-go-darwin-arm64.tar.gz",
+		"fancy-login-go-windows-amd64.zip",
+		"checksums.txt",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	archives, err := ReleaseArchives(dir, "https://example.com/download/v1.2.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(archives) != 3 {
+		t.Fatalf("Expected 3 archives, got %+v", archives)
+	}
+
+	data, err := Manifest("v1.2.0", archives)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var manifest krewManifest
+	if err := yaml.Unmarshal(data, &manifest); err != nil {
+		t.Fatalf("Expected valid YAML, got %v:\n%s", err, data)
+	}
+	if manifest.Metadata.Name != PluginName || manifest.Spec.Version != "v1.2.0" {
+		t.Errorf("Unexpected metadata:\n%s", data)
+	}
+
+	platforms := manifest.Spec.Platforms
+	if len(platforms) != 3 {
+		t.Fatalf("Expected 3 platforms, got %d", len(platforms))
+	}
+	windows := platforms[2]
+	if windows.Selector.MatchLabels["os"] != "windows" || windows.Bin != "fancy-login-go-windows-amd64.exe" {
+		t.Errorf("Expected the Windows platform last with an .exe bin, got %+v", windows)
+	}
+	if !strings.HasSuffix(windows.URI, "/v1.2.0/fancy-login-go-windows-amd64.zip") {
+		t.Errorf("Unexpected URI %s", windows.URI)
+	}
+	if len(platforms[1].SHA256) != 64 {
+		t.Errorf("Expected a sha256 checksum, got %q", platforms[1].SHA256)
+	}
+
+	if _, err := ReleaseArchives(t.TempDir(), ""); err == nil {
+		t.Error("Expected an error without archives")
+	}
+}
@@ -0,0 +1,234 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Step is one timed phase of a run
+type Step struct {
+	Name     string
+	Duration time.Duration
+	Success  bool
+}
+
+// Totals are a profile's counts across every run since the textfile was
+// enabled
+type Totals struct {
+	Profile          string
+	Environment      string
+	Logins           int
+	Failures         int
+	SessionCacheHits int
+	ECRCacheHits     int
+}
+
+// Run collects the metrics of a single fancy-login run
+type Run struct {
+	Profile     string
+	Environment string
+	Started     time.Time
+	Finished    time.Time
+	// LoggedIn is set once the profile's credentials are known to work
+	LoggedIn bool
+	// Aborted is set when the run exited early on a fatal error
+	Aborted bool
+	// SessionExpiry is when the SSO session expires, if known
+	SessionExpiry time.Time
+	// SessionCached is set when a valid session made the login unnecessary
+	SessionCached bool
+	// ECRCacheHits counts the registries whose fresh token was reused
+	ECRCacheHits int
+	Steps        []Step
+	// Totals are the counters of every profile, this run included, sorted
+	// by profile
+	Totals []Totals
+
+	now func() time.Time
+}
+
+// NewRun starts collecting metrics for a run
+func NewRun() *Run {
+	return &Run{Started: time.Now(), now: time.Now}
+}
+
+// Step records a phase that began at start and finished now
+func (r *Run) Step(name string, start time.Time, err error) {
+	r.Steps = append(r.Steps, Step{Name: name, Duration: r.now().Sub(start), Success: err == nil})
+}
+
+// Finish marks the end of the run
+func (r *Run) Finish() {
+	r.Finished = r.now()
+}
+
+// Success reports whether the run completed and every step succeeded
+func (r *Run) Success() bool {
+	if r.Aborted {
+		return false
+	}
+	for _, step := range r.Steps {
+		if !step.Success {
+			return false
+		}
+	}
+	return true
+}
+
+// WriteTo renders the run in the Prometheus text exposition format
+func (r *Run) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	base := [][2]string{{"profile", r.Profile}, {"environment", r.Environment}}
+
+	writeFamily(&buf, "fancy_login_last_run_timestamp_seconds", "gauge",
+		"Unix time the last fancy-login run finished.")
+	writeSample(&buf, "fancy_login_last_run_timestamp_seconds", base, unixSeconds(r.Finished))
+
+	writeFamily(&buf, "fancy_login_last_run_success", "gauge",
+		"Whether every step of the last run succeeded.")
+	writeSample(&buf, "fancy_login_last_run_success", base, boolValue(r.Success()))
+
+	writeFamily(&buf, "fancy_login_run_duration_seconds", "gauge",
+		"Wall-clock duration of the last run.")
+	writeSample(&buf, "fancy_login_run_duration_seconds", base, r.Finished.Sub(r.Started).Seconds())
+
+	if r.LoggedIn {
+		writeFamily(&buf, "fancy_login_last_login_timestamp_seconds", "gauge",
+			"Unix time of the last successful login.")
+		writeSample(&buf, "fancy_login_last_login_timestamp_seconds", base, unixSeconds(r.Finished))
+	}
+
+	if len(r.Steps) > 0 {
+		writeFamily(&buf, "fancy_login_phase_duration_seconds", "gauge",
+			"Duration of each phase of the last run.")
+		for _, step := range r.Steps {
+			writeSample(&buf, "fancy_login_phase_duration_seconds", withLabel(base, "phase", step.Name), step.Duration.Seconds())
+		}
+
+		writeFamily(&buf, "fancy_login_step_success", "gauge",
+			"Whether each step of the last run succeeded (1) or failed (0).")
+		for _, step := range r.Steps {
+			writeSample(&buf, "fancy_login_step_success", withLabel(base, "step", step.Name), boolValue(step.Success))
+		}
+	}
+
+	if !r.SessionExpiry.IsZero() {
+		writeFamily(&buf, "fancy_login_session_expiry_timestamp_seconds", "gauge",
+			"Unix time the AWS SSO session expires.")
+		writeSample(&buf, "fancy_login_session_expiry_timestamp_seconds", base, unixSeconds(r.SessionExpiry))
+	}
+
+	if len(r.Totals) > 0 {
+		writeFamily(&buf, "fancy_login_logins_total", "counter",
+			"Runs that logged in successfully.")
+		for _, t := range r.Totals {
+			writeSample(&buf, "fancy_login_logins_total", t.labels(), float64(t.Logins))
+		}
+
+		writeFamily(&buf, "fancy_login_failures_total", "counter",
+			"Runs that failed or had a failed step.")
+		for _, t := range r.Totals {
+			writeSample(&buf, "fancy_login_failures_total", t.labels(), float64(t.Failures))
+		}
+
+		writeFamily(&buf, "fancy_login_cache_hits_total", "counter",
+			"Logins skipped for a valid cached SSO session or a fresh ECR token.")
+		for _, t := range r.Totals {
+			writeSample(&buf, "fancy_login_cache_hits_total", withLabel(t.labels(), "cache", "sso_session"), float64(t.SessionCacheHits))
+			writeSample(&buf, "fancy_login_cache_hits_total", withLabel(t.labels(), "cache", "ecr_token"), float64(t.ECRCacheHits))
+		}
+	}
+
+	return buf.WriteTo(w)
+}
+
+// labels returns the profile and environment labels of t
+func (t Totals) labels() [][2]string {
+	return [][2]string{{"profile", t.Profile}, {"environment", t.Environment}}
+}
+
+// WriteTextfile atomically replaces path with the rendered metrics. The
+// temporary file doesn't end in .prom so the textfile collector never reads
+// a partial write.
+func (r *Run) WriteTextfile(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".fancy-login-metrics-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create metrics file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := r.WriteTo(tmp); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write metrics file: %w", err)
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write metrics file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write metrics file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace metrics file %s: %w", path, err)
+	}
+	return nil
+}
+
+// writeFamily writes the HELP and TYPE lines of a metric family
+func writeFamily(buf *bytes.Buffer, name, kind, help string) {
+	fmt.Fprintf(buf, "# HELP %s %s\n", name, escapeHelp(help))
+	fmt.Fprintf(buf, "# TYPE %s %s\n", name, kind)
+}
+
+// writeSample writes one sample line
+func writeSample(buf *bytes.Buffer, name string, labels [][2]string, value float64) {
+	buf.WriteString(name)
+	buf.WriteByte('{')
+	for i, label := range labels {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		fmt.Fprintf(buf, "%s=\"%s\"", label[0], escapeLabelValue(label[1]))
+	}
+	buf.WriteByte('}')
+	buf.WriteByte(' ')
+	buf.WriteString(strconv.FormatFloat(value, 'f', -1, 64))
+	buf.WriteByte('\n')
+}
+
+// withLabel returns base with one more label appended
+func withLabel(base [][2]string, name, value string) [][2]string {
+	labels := make([][2]string, 0, len(base)+1)
+	labels = append(labels, base...)
+	return append(labels, [2]string{name, value})
+}
+
+// escapeLabelValue escapes backslash, double quote and newline as the
+// exposition format requires
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// escapeHelp escapes backslash and newline in HELP text
+func escapeHelp(help string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+}
+
+// unixSeconds converts a time to fractional Unix seconds
+func unixSeconds(t time.Time) float64 {
+	return float64(t.UnixMilli()) / 1000
+}
+
+// boolValue renders a boolean as 1 or 0
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
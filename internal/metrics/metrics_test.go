@@ -0,0 +1,376 @@
+package metrics
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// sample is one parsed series of the exposition format
+type sample struct {
+	name   string
+	labels map[string]string
+	value  float64
+}
+
+var (
+	metricNameRe = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	labelNameRe  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// parseExposition validates text against the Prometheus text exposition
+// format (version 0.0.4) and returns its samples. It implements the subset
+// of the grammar that writers may produce: HELP/TYPE comments, label sets
+// with escaped values and float values.
+func parseExposition(text string) ([]sample, error) {
+	if !strings.HasSuffix(text, "\n") {
+		return nil, errors.New("exposition must end with a newline")
+	}
+
+	types := make(map[string]string)
+	seen := make(map[string]bool)
+	var samples []sample
+
+	for i, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		lineNo := i + 1
+		if strings.HasPrefix(line, "# ") {
+			fields := strings.SplitN(line, " ", 4)
+			if len(fields) < 4 || (fields[1] != "HELP" && fields[1] != "TYPE") {
+				return nil, fmt.Errorf("line %d: malformed comment %q", lineNo, line)
+			}
+			if !metricNameRe.MatchString(fields[2]) {
+				return nil, fmt.Errorf("line %d: invalid metric name %q", lineNo, fields[2])
+			}
+			if fields[1] == "TYPE" {
+				if _, dup := types[fields[2]]; dup {
+					return nil, fmt.Errorf("line %d: duplicate TYPE for %s", lineNo, fields[2])
+				}
+				switch fields[3] {
+				case "counter", "gauge", "histogram", "summary", "untyped":
+				default:
+					return nil, fmt.Errorf("line %d: unknown type %q", lineNo, fields[3])
+				}
+				types[fields[2]] = fields[3]
+			}
+			continue
+		}
+
+		s, rest, err := parseSample(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		if _, typed := types[s.name]; !typed {
+			return nil, fmt.Errorf("line %d: sample %s before its TYPE line", lineNo, s.name)
+		}
+		value, err := strconv.ParseFloat(rest, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid value %q", lineNo, rest)
+		}
+		s.value = value
+
+		key := s.name + fmt.Sprint(s.labels)
+		if seen[key] {
+			return nil, fmt.Errorf("line %d: duplicate series %s", lineNo, key)
+		}
+		seen[key] = true
+		samples = append(samples, s)
+	}
+	return samples, nil
+}
+
+// parseSample parses `name{label="value",...} ` and returns the remainder
+func parseSample(line string) (sample, string, error) {
+	s := sample{labels: make(map[string]string)}
+	brace := strings.IndexAny(line, "{ ")
+	if brace < 0 {
+		return s, "", fmt.Errorf("missing value in %q", line)
+	}
+	s.name = line[:brace]
+	if !metricNameRe.MatchString(s.name) {
+		return s, "", fmt.Errorf("invalid metric name %q", s.name)
+	}
+
+	rest := line[brace:]
+	if strings.HasPrefix(rest, "{") {
+		rest = rest[1:]
+		for !strings.HasPrefix(rest, "}") {
+			eq := strings.Index(rest, "=\"")
+			if eq < 0 {
+				return s, "", fmt.Errorf("malformed label in %q", line)
+			}
+			name := rest[:eq]
+			if !labelNameRe.MatchString(name) {
+				return s, "", fmt.Errorf("invalid label name %q", name)
+			}
+			rest = rest[eq+2:]
+
+			var value strings.Builder
+			closed := false
+			for i := 0; i < len(rest); i++ {
+				c := rest[i]
+				if c == '\\' {
+					if i+1 >= len(rest) {
+						return s, "", fmt.Errorf("dangling escape in %q", line)
+					}
+					switch rest[i+1] {
+					case '\\':
+						value.WriteByte('\\')
+					case '"':
+						value.WriteByte('"')
+					case 'n':
+						value.WriteByte('\n')
+					default:
+						return s, "", fmt.Errorf("invalid escape \\%c in %q", rest[i+1], line)
+					}
+					i++
+					continue
+				}
+				if c == '"' {
+					rest = rest[i+1:]
+					closed = true
+					break
+				}
+				if c == '\n' {
+					return s, "", fmt.Errorf("raw newline in label value")
+				}
+				value.WriteByte(c)
+			}
+			if !closed {
+				return s, "", fmt.Errorf("unterminated label value in %q", line)
+			}
+			if _, dup := s.labels[name]; dup {
+				return s, "", fmt.Errorf("duplicate label %q", name)
+			}
+			s.labels[name] = value.String()
+			rest = strings.TrimPrefix(rest, ",")
+		}
+		rest = rest[1:]
+	}
+
+	if !strings.HasPrefix(rest, " ") {
+		return s, "", fmt.Errorf("missing space before value in %q", line)
+	}
+	return s, strings.TrimSpace(rest), nil
+}
+
+func newTestRun() *Run {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	clock := start
+	r := &Run{Profile: "dev", Environment: "dev", Started: start, now: func() time.Time { return clock }}
+
+	clock = clock.Add(2 * time.Second)
+	r.Step("aws_login", start, nil)
+	stepStart := clock
+	clock = clock.Add(1500 * time.Millisecond)
+	r.Step("ecr_login", stepStart, errors.New("docker not running"))
+	r.LoggedIn = true
+	r.SessionExpiry = start.Add(8 * time.Hour)
+	r.Finish()
+	return r
+}
+
+func TestRunExpositionFormat(t *testing.T) {
+	var buf bytes.Buffer
+	if _, err := newTestRun().WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo failed: %v", err)
+	}
+
+	samples, err := parseExposition(buf.String())
+	if err != nil {
+		t.Fatalf("Invalid exposition format: %v\n%s", err, buf.String())
+	}
+
+	find := func(name, extraLabel, extraValue string) *sample {
+		for i := range samples {
+			if samples[i].name == name && samples[i].labels[extraLabel] == extraValue {
+				return &samples[i]
+			}
+		}
+		return nil
+	}
+
+	testCases := []struct {
+		name       string
+		metric     string
+		extraLabel string
+		extraValue string
+		expected   float64
+	}{
+		{"Last run", "fancy_login_last_run_timestamp_seconds", "profile", "dev", 1714564803.5},
+		{"Last login", "fancy_login_last_login_timestamp_seconds", "profile", "dev", 1714564803.5},
+		{"Run failed", "fancy_login_last_run_success", "profile", "dev", 0},
+		{"Run duration", "fancy_login_run_duration_seconds", "profile", "dev", 3.5},
+		{"Login phase", "fancy_login_phase_duration_seconds", "phase", "aws_login", 2},
+		{"ECR phase", "fancy_login_phase_duration_seconds", "phase", "ecr_login", 1.5},
+		{"Login succeeded", "fancy_login_step_success", "step", "aws_login", 1},
+		{"ECR failed", "fancy_login_step_success", "step", "ecr_login", 0},
+		{"Session expiry", "fancy_login_session_expiry_timestamp_seconds", "profile", "dev", 1714593600},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := find(tc.metric, tc.extraLabel, tc.extraValue)
+			if s == nil {
+				t.Fatalf("Expected sample %s{%s=%q}", tc.metric, tc.extraLabel, tc.extraValue)
+			}
+			if s.value != tc.expected {
+				t.Errorf("Expected %v, got %v", tc.expected, s.value)
+			}
+			if s.labels["environment"] != "dev" {
+				t.Errorf("Expected environment label dev, got %q", s.labels["environment"])
+			}
+		})
+	}
+}
+
+func TestRunEscapesLabelValues(t *testing.T) {
+	r := newTestRun()
+	r.Profile = "weird \"profile\"\\with\nnewline"
+	r.Environment = ""
+
+	var buf bytes.Buffer
+	r.WriteTo(&buf)
+
+	samples, err := parseExposition(buf.String())
+	if err != nil {
+		t.Fatalf("Invalid exposition format: %v\n%s", err, buf.String())
+	}
+	for _, s := range samples {
+		if s.labels["profile"] != r.Profile {
+			t.Fatalf("Expected profile label to round-trip, got %q", s.labels["profile"])
+		}
+	}
+}
+
+func TestRunOmitsUnknownValues(t *testing.T) {
+	r := &Run{Profile: "dev", Started: time.Now(), now: time.Now}
+	r.Finish()
+
+	var buf bytes.Buffer
+	r.WriteTo(&buf)
+
+	for _, name := range []string{"fancy_login_last_login_timestamp_seconds", "fancy_login_session_expiry_timestamp_seconds", "fancy_login_step_success", "fancy_login_logins_total"} {
+		if strings.Contains(buf.String(), name) {
+			t.Errorf("Expected %s to be omitted, got:\n%s", name, buf.String())
+		}
+	}
+	if _, err := parseExposition(buf.String()); err != nil {
+		t.Errorf("Invalid exposition format: %v", err)
+	}
+}
+
+func TestRunCounters(t *testing.T) {
+	r := newTestRun()
+	r.Totals = []Totals{
+		{Profile: "dev", Environment: "dev", Logins: 5, Failures: 2, SessionCacheHits: 4, ECRCacheHits: 3},
+		{Profile: "prod", Environment: "prod", Logins: 1},
+	}
+
+	var buf bytes.Buffer
+	r.WriteTo(&buf)
+	if !strings.Contains(buf.String(), "# TYPE fancy_login_logins_total counter\n") {
+		t.Errorf("Expected the totals to be counters, got:\n%s", buf.String())
+	}
+	samples, err := parseExposition(buf.String())
+	if err != nil {
+		t.Fatalf("Invalid exposition format: %v\n%s", err, buf.String())
+	}
+
+	value := func(name string, labels map[string]string) float64 {
+		for _, s := range samples {
+			if s.name != name {
+				continue
+			}
+			match := true
+			for k, v := range labels {
+				match = match && s.labels[k] == v
+			}
+			if match {
+				return s.value
+			}
+		}
+		t.Fatalf("Expected sample %s%v", name, labels)
+		return 0
+	}
+
+	testCases := []struct {
+		name     string
+		metric   string
+		labels   map[string]string
+		expected float64
+	}{
+		{"Logins", "fancy_login_logins_total", map[string]string{"profile": "dev"}, 5},
+		{"Failures", "fancy_login_failures_total", map[string]string{"profile": "dev"}, 2},
+		{"Session cache hits", "fancy_login_cache_hits_total", map[string]string{"profile": "dev", "cache": "sso_session"}, 4},
+		{"ECR cache hits", "fancy_login_cache_hits_total", map[string]string{"profile": "dev", "cache": "ecr_token"}, 3},
+		{"Other profile", "fancy_login_logins_total", map[string]string{"profile": "prod", "environment": "prod"}, 1},
+		{"Other profile without failures", "fancy_login_failures_total", map[string]string{"profile": "prod"}, 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := value(tc.metric, tc.labels); got != tc.expected {
+				t.Errorf("Expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestRunSuccess(t *testing.T) {
+	testCases := []struct {
+		name     string
+		steps    []Step
+		aborted  bool
+		expected bool
+	}{
+		{"No steps", nil, false, true},
+		{"All succeeded", []Step{{Name: "aws_login", Success: true}}, false, true},
+		{"Step failed", []Step{{Name: "aws_login", Success: true}, {Name: "ecr_login"}}, false, false},
+		{"Aborted", []Step{{Name: "aws_login", Success: true}}, true, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := &Run{Steps: tc.steps, Aborted: tc.aborted}
+			if got := r.Success(); got != tc.expected {
+				t.Errorf("Expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestWriteTextfile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "fancy_login.prom")
+	if err := os.WriteFile(path, []byte("stale\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := newTestRun().WriteTextfile(path); err != nil {
+		t.Fatalf("WriteTextfile failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read metrics file: %v", err)
+	}
+	if _, err := parseExposition(string(data)); err != nil {
+		t.Errorf("Invalid exposition format in file: %v", err)
+	}
+
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("Expected temporary files to be cleaned up, got %d entries", len(entries))
+	}
+
+	if err := newTestRun().WriteTextfile(filepath.Join(dir, "missing", "x.prom")); err == nil {
+		t.Error("Expected an error for a missing directory")
+	}
+}
@@ -0,0 +1,367 @@
+package platform
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// host holds the facts that path, script and escape decisions depend on, so
+// they can be tested for every platform from any platform
+type host struct {
+	goos    string
+	getenv  func(string) string
+	home    string
+	tempDir string
+}
+
+// currentHost describes the machine fancy-login runs on
+func currentHost() host {
+	home, _ := os.UserHomeDir()
+	return host{goos: runtime.GOOS, getenv: os.Getenv, home: home, tempDir: os.TempDir()}
+}
+
+// join builds a path with the host's separator, regardless of the OS the
+// tests run on
+func (h host) join(elem ...string) string {
+	if h.goos == "windows" {
+		return strings.Join(elem, `\`)
+	}
+	return path.Join(elem...)
+}
+
+// BinDir returns the default directory for the fancy-login binary
+func BinDir() string {
+	return currentHost().binDir()
+}
+
+func (h host) binDir() string {
+	if h.goos == "windows" {
+		return h.join(h.home, "AppData", "Local", "fancy-login")
+	}
+	return h.join(h.home, ".local", "bin")
+}
+
+// ProfileScriptPath returns the default file the shell integration sources
+// AWS_PROFILE from. Each terminal running the integration from init gets
+// its own file, so two terminals logging in at once don't clobber each
+// other's profile; older integrations keep their single shared file.
+func ProfileScriptPath() string {
+	return currentHost().profileScriptPath()
+}
+
+func (h host) profileScriptPath() string {
+	return h.sessionProfileScriptPath(h.sessionID())
+}
+
+// sessionProfileScriptPath is the profile script of the terminal session
+// id; without one it is the single file used before per-terminal files
+func (h host) sessionProfileScriptPath(id string) string {
+	name := "aws_profile"
+	if id != "" {
+		name += "." + id
+	}
+	if h.goos == "windows" {
+		return h.join(h.tempDir, name+".ps1")
+	}
+	return "/tmp/" + name + ".sh"
+}
+
+// sessionID identifies the terminal fancy-login runs in by the
+// FANCY_SESSION_ID the shell integration sets. It is "" without one, e.g.
+// for shell functions installed before init existed, which source the
+// legacy file.
+func (h host) sessionID() string {
+	if id := h.getenv("FANCY_SESSION_ID"); validSessionID(id) {
+		return id
+	}
+	return ""
+}
+
+// validSessionID reports whether id is safe to put in a file name
+func validSessionID(id string) bool {
+	if id == "" {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// SessionProfileScripts returns the per-terminal profile scripts of every
+// session, including ones whose terminal is long gone
+func SessionProfileScripts() ([]string, error) {
+	var paths []string
+	for _, pattern := range currentHost().sessionProfileScriptPatterns() {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, matches...)
+	}
+	return paths, nil
+}
+
+func (h host) sessionProfileScriptPatterns() []string {
+	script := h.sessionProfileScriptPath("*")
+	if h.goos == "windows" {
+		return []string{script, strings.Replace(script, ".ps1", ".bat", 1)}
+	}
+	return []string{script}
+}
+
+// Script is a generated file and its content
+type Script struct {
+	Path    string
+	Content string
+}
+
+// ExportVar is an extra environment variable a profile script sets
+type ExportVar struct {
+	Name  string
+	Value string
+}
+
+// ProfileScripts returns the files that export profile, and any extra
+// variables after it, for the shell integration. Windows gets a PowerShell
+// script plus a .bat next to it for Command Prompt users. They first remove
+// the aws wrapper VaultProfileScripts may have defined for an earlier
+// profile, so aws doesn't keep running as that one.
+func ProfileScripts(scriptPath, profile string, extra ...ExportVar) []Script {
+	return currentHost().profileScripts(scriptPath, profile, extra)
+}
+
+func (h host) profileScripts(scriptPath, profile string, extra []ExportVar) []Script {
+	if h.goos != "windows" {
+		content := fmt.Sprintf("unalias aws 2>/dev/null\nexport AWS_PROFILE=%s\n", profile)
+		for _, v := range extra {
+			content += fmt.Sprintf("export %s='%s'\n", v.Name, v.Value)
+		}
+		return []Script{{Path: scriptPath, Content: content}}
+	}
+	ps := fmt.Sprintf("Remove-Item Function:aws -ErrorAction SilentlyContinue\n$env:AWS_PROFILE=\"%s\"\n", profile)
+	bat := fmt.Sprintf("doskey aws=\nset AWS_PROFILE=%s\n", profile)
+	for _, v := range extra {
+		ps += fmt.Sprintf("$env:%s=\"%s\"\n", v.Name, v.Value)
+		bat += fmt.Sprintf("set %s=%s\n", v.Name, v.Value)
+	}
+	return []Script{
+		{Path: scriptPath, Content: ps},
+		{Path: strings.Replace(scriptPath, ".ps1", ".bat", 1), Content: bat},
+	}
+}
+
+// VaultProfileScripts returns the files that, instead of exporting
+// AWS_PROFILE, unset it and make aws run command, the aws-vault exec
+// wrapper of profile. A comment names the profile for ReadProfileScript.
+func VaultProfileScripts(scriptPath, profile, command string) []Script {
+	return currentHost().vaultProfileScripts(scriptPath, profile, command)
+}
+
+func (h host) vaultProfileScripts(scriptPath, profile, command string) []Script {
+	if h.goos != "windows" {
+		content := fmt.Sprintf("# fancy-login profile %s\nunset AWS_PROFILE\nalias aws='%s'\n", profile, command)
+		return []Script{{Path: scriptPath, Content: content}}
+	}
+	ps := fmt.Sprintf("# fancy-login profile %s\nRemove-Item Env:AWS_PROFILE -ErrorAction SilentlyContinue\nfunction aws { %s @args }\n", profile, command)
+	bat := fmt.Sprintf("rem fancy-login profile %s\nset AWS_PROFILE=\ndoskey aws=%s $*\n", profile, command)
+	return []Script{
+		{Path: scriptPath, Content: ps},
+		{Path: strings.Replace(scriptPath, ".ps1", ".bat", 1), Content: bat},
+	}
+}
+
+// ShellIntegration returns the fancy function for shell ("sh", "bash",
+// "zsh" or "powershell"; "" picks the platform's), which hands the shell's
+// PID to fancy-login and sources that terminal's profile script. For "cmd"
+// it is a fancy.bat to put on the PATH, which picks a random session ID
+// once per Command Prompt window.
+func ShellIntegration(shell string) (string, error) {
+	return currentHost().shellIntegration(shell)
+}
+
+func (h host) shellIntegration(shell string) (string, error) {
+	if shell == "" {
+		shell = "sh"
+		if h.goos == "windows" {
+			shell = "powershell"
+		}
+	}
+	switch shell {
+	case "sh", "bash", "zsh":
+		script := h.sessionProfileScriptPath("$$")
+		return fmt.Sprintf(`fancy() {
+    if FANCY_SESSION_ID=$$ fancy-login-go "$@"; then
+        [ -f %[1]s ] && . %[1]s
+    fi
+}
+`, script), nil
+	case "powershell":
+		return fmt.Sprintf(`function fancy {
+    $env:FANCY_SESSION_ID = $PID
+    fancy-login-go.exe $args
+    if ($LASTEXITCODE -eq 0) {
+        $script = "%s"
+        if (Test-Path $script) {
+            . $script
+        }
+    }
+}
+`, h.sessionProfileScriptPath("$PID")), nil
+	case "cmd":
+		script := strings.Replace(h.sessionProfileScriptPath("%FANCY_SESSION_ID%"), ".ps1", ".bat", 1)
+		return fmt.Sprintf(`@echo off
+if not defined FANCY_SESSION_ID set FANCY_SESSION_ID=%%RANDOM%%%%RANDOM%%
+fancy-login-go.exe %%*
+if errorlevel 1 exit /b %%errorlevel%%
+if exist "%[1]s" call "%[1]s"
+`, script), nil
+	}
+	return "", fmt.Errorf("unknown shell %q, expected sh, bash, zsh, powershell or cmd", shell)
+}
+
+// ReadProfileScript returns the profile the file at path exports, or "" if
+// the file doesn't exist
+func ReadProfileScript(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return ParseProfileScript(string(data)), nil
+}
+
+// ParseProfileScript returns the profile a file written by ProfileScripts
+// exports, or "" if it exports none
+func ParseProfileScript(content string) string {
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(line)
+		for _, prefix := range []string{"export AWS_PROFILE=", "$env:AWS_PROFILE=", "set AWS_PROFILE=", "# fancy-login profile ", "rem fancy-login profile "} {
+			if rest, ok := strings.CutPrefix(line, prefix); ok {
+				return strings.Trim(rest, `"`)
+			}
+		}
+	}
+	return ""
+}
+
+// TitleSupported reports whether terminal title escapes are safe to write.
+// The classic Windows console prints them verbatim, Windows Terminal doesn't.
+func TitleSupported() bool {
+	return currentHost().titleSupported()
+}
+
+func (h host) titleSupported() bool {
+	return h.goos != "windows" || h.getenv("WT_SESSION") != ""
+}
+
+// TitleEscape returns the escape sequence setting the terminal title, or ""
+// where the terminal would print it verbatim
+func TitleEscape(title string) string {
+	return currentHost().titleEscape(title)
+}
+
+func (h host) titleEscape(title string) string {
+	if !h.titleSupported() {
+		return ""
+	}
+	return fmt.Sprintf("\033]0;%s\007", title)
+}
+
+// NamespaceIndicator returns the escapes showing namespace in the terminal:
+// the tab title and badge in iTerm2, the title elsewhere, nothing in the
+// classic Windows console
+func NamespaceIndicator(namespace string) string {
+	return currentHost().namespaceIndicator(namespace)
+}
+
+func (h host) namespaceIndicator(namespace string) string {
+	if namespace == "" {
+		return ""
+	}
+	if h.goos != "darwin" {
+		return h.titleEscape("ns:" + namespace)
+	}
+	if h.getenv("TERM_PROGRAM") != "iTerm.app" {
+		return ""
+	}
+	badge := base64.StdEncoding.EncodeToString([]byte("🟢 ns:" + namespace))
+	return fmt.Sprintf("\033]1;ns:%s\007\033]1337;SetBadgeFormat=%s\a", namespace, badge)
+}
+
+// ResetNamespaceIndicator returns the escapes clearing what
+// NamespaceIndicator set
+func ResetNamespaceIndicator() string {
+	return currentHost().resetNamespaceIndicator()
+}
+
+func (h host) resetNamespaceIndicator() string {
+	if h.goos != "darwin" {
+		return h.titleEscape("")
+	}
+	if h.getenv("TERM_PROGRAM") != "iTerm.app" {
+		return ""
+	}
+	return "\033]1;\007\033]1337;SetBadgeFormat=\a"
+}
+
+// ShellCommand runs a command line through the platform shell
+func ShellCommand(ctx context.Context, commandLine string) *exec.Cmd {
+	args := currentHost().shellArgs(commandLine)
+	return exec.CommandContext(ctx, args[0], args[1:]...)
+}
+
+func (h host) shellArgs(commandLine string) []string {
+	if h.goos == "windows" {
+		return []string{"cmd", "/C", commandLine}
+	}
+	return []string{"sh", "-c", commandLine}
+}
+
+// OpenTTY opens the controlling terminal, which keeps working after fzf has
+// consumed stdin
+func OpenTTY() (*os.File, error) {
+	open := func(name string) (*os.File, error) { return os.OpenFile(name, os.O_RDWR, 0) }
+	return openTTY(currentHost().ttyPath(), IsWSL, os.Stdin.Stat, open)
+}
+
+// wslStdinTTY reopens the terminal stdin is connected to
+const wslStdinTTY = "/proc/self/fd/0"
+
+// openTTY opens ttyPath with open. Under WSL, Windows Terminal profiles
+// that run `wsl.exe -e` start the shell without a controlling terminal, so
+// /dev/tty fails with ENXIO although stdin is the terminal; then the
+// terminal is reopened through stdin instead.
+func openTTY(ttyPath string, wsl func() bool, stdin func() (os.FileInfo, error), open func(string) (*os.File, error)) (*os.File, error) {
+	tty, err := open(ttyPath)
+	if err == nil {
+		return tty, nil
+	}
+	if wsl() {
+		if info, statErr := stdin(); statErr == nil && info.Mode()&os.ModeCharDevice != 0 {
+			if tty, fallbackErr := open(wslStdinTTY); fallbackErr == nil {
+				return tty, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("failed to open %s: %w", ttyPath, err)
+}
+
+func (h host) ttyPath() string {
+	if h.goos == "windows" {
+		return "CONIN$"
+	}
+	return "/dev/tty"
+}
@@ -0,0 +1,294 @@
+package platform
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+// testHost builds a host for goos with the given environment
+func testHost(goos string, env map[string]string) host {
+	h := host{goos: goos, getenv: func(key string) string { return env[key] }, home: "/home/me", tempDir: "/tmp"}
+	if goos == "windows" {
+		h.home = `C:\Users\me`
+		h.tempDir = `C:\Users\me\AppData\Local\Temp`
+	}
+	return h
+}
+
+func TestHostPaths(t *testing.T) {
+	testCases := []struct {
+		name          string
+		goos          string
+		binDir        string
+		profileScript string
+		ttyPath       string
+	}{
+		{"Linux", "linux", "/home/me/.local/bin", "/tmp/aws_profile.sh", "/dev/tty"},
+		{"macOS", "darwin", "/home/me/.local/bin", "/tmp/aws_profile.sh", "/dev/tty"},
+		{"Windows", "windows", `C:\Users\me\AppData\Local\fancy-login`, `C:\Users\me\AppData\Local\Temp\aws_profile.ps1`, "CONIN$"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := testHost(tc.goos, nil)
+			if got := h.binDir(); got != tc.binDir {
+				t.Errorf("Expected bin dir %s, got %s", tc.binDir, got)
+			}
+			if got := h.profileScriptPath(); got != tc.profileScript {
+				t.Errorf("Expected profile script %s, got %s", tc.profileScript, got)
+			}
+			if got := h.ttyPath(); got != tc.ttyPath {
+				t.Errorf("Expected TTY %s, got %s", tc.ttyPath, got)
+			}
+		})
+	}
+}
+
+// fakeFileInfo reports mode; nothing else of it is used
+type fakeFileInfo struct {
+	os.FileInfo
+	mode os.FileMode
+}
+
+func (f fakeFileInfo) Mode() os.FileMode { return f.mode }
+
+func TestOpenTTY(t *testing.T) {
+	terminal := fakeFileInfo{mode: os.ModeDevice | os.ModeCharDevice}
+	pipe := fakeFileInfo{mode: os.ModeNamedPipe}
+
+	testCases := []struct {
+		name     string
+		ttyOK    bool
+		wsl      bool
+		stdin    os.FileInfo
+		expected string
+	}{
+		{"Controlling terminal", true, false, terminal, "/dev/tty"},
+		{"Controlling terminal under WSL", true, true, terminal, "/dev/tty"},
+		{"WSL without a controlling terminal", false, true, terminal, wslStdinTTY},
+		{"WSL with stdin redirected", false, true, pipe, ""},
+		{"Linux without a controlling terminal", false, false, terminal, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			opened := ""
+			open := func(name string) (*os.File, error) {
+				if name == "/dev/tty" && !tc.ttyOK {
+					return nil, syscall.ENXIO
+				}
+				opened = name
+				return os.CreateTemp(t.TempDir(), "tty")
+			}
+			stdin := func() (os.FileInfo, error) { return tc.stdin, nil }
+
+			tty, err := openTTY("/dev/tty", func() bool { return tc.wsl }, stdin, open)
+			if tc.expected == "" {
+				if err == nil || !strings.Contains(err.Error(), "/dev/tty") {
+					t.Errorf("Expected an error naming /dev/tty, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			tty.Close()
+			if opened != tc.expected {
+				t.Errorf("Expected %s to be opened, got %s", tc.expected, opened)
+			}
+		})
+	}
+}
+
+func TestSessionProfileScriptPath(t *testing.T) {
+	testCases := []struct {
+		name     string
+		goos     string
+		env      map[string]string
+		expected string
+	}{
+		{"Session ID", "linux", map[string]string{"FANCY_SESSION_ID": "4711"}, "/tmp/aws_profile.4711.sh"},
+		{"Unsafe session ID", "linux", map[string]string{"FANCY_SESSION_ID": "../etc"}, "/tmp/aws_profile.sh"},
+		{"Legacy integration", "linux", nil, "/tmp/aws_profile.sh"},
+		{"Windows", "windows", map[string]string{"FANCY_SESSION_ID": "4711"}, `C:\Users\me\AppData\Local\Temp\aws_profile.4711.ps1`},
+		{"Windows legacy integration", "windows", nil, `C:\Users\me\AppData\Local\Temp\aws_profile.ps1`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := testHost(tc.goos, tc.env)
+			if got := h.profileScriptPath(); got != tc.expected {
+				t.Errorf("Expected %s, got %s", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestShellIntegration(t *testing.T) {
+	snippet, err := testHost("linux", nil).shellIntegration("zsh")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(snippet, "FANCY_SESSION_ID=$$ fancy-login-go") || !strings.Contains(snippet, ". /tmp/aws_profile.$$.sh") {
+		t.Errorf("Expected the snippet to pass and source the shell's session, got:\n%s", snippet)
+	}
+
+	snippet, err = testHost("windows", nil).shellIntegration("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(snippet, `"C:\Users\me\AppData\Local\Temp\aws_profile.$PID.ps1"`) {
+		t.Errorf("Expected PowerShell on Windows, got:\n%s", snippet)
+	}
+
+	snippet, err = testHost("windows", nil).shellIntegration("cmd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(snippet, "set FANCY_SESSION_ID=%RANDOM%%RANDOM%") || !strings.Contains(snippet, `call "C:\Users\me\AppData\Local\Temp\aws_profile.%FANCY_SESSION_ID%.bat"`) {
+		t.Errorf("Expected a batch file sourcing the window's session, got:\n%s", snippet)
+	}
+
+	if _, err := testHost("linux", nil).shellIntegration("fish"); err == nil {
+		t.Error("Expected an error for an unsupported shell")
+	}
+}
+
+func TestWindowsPathsStayUnderUserProfile(t *testing.T) {
+	h := testHost("windows", nil)
+	for _, path := range []string{h.binDir(), h.profileScriptPath()} {
+		if !strings.HasPrefix(path, h.home+`\`) {
+			t.Errorf("Expected %s under %s", path, h.home)
+		}
+		if strings.Contains(path, "/") {
+			t.Errorf("Expected only backslashes in %s", path)
+		}
+	}
+}
+
+func TestProfileScripts(t *testing.T) {
+	creds := []ExportVar{{"AWS_ACCESS_KEY_ID", "ASIATEMP"}, {"AWS_SESSION_TOKEN", "to/ken+="}}
+	testCases := []struct {
+		name     string
+		goos     string
+		path     string
+		extra    []ExportVar
+		expected []Script
+	}{
+		{"Linux", "linux", "/tmp/aws_profile.sh", nil, []Script{
+			{Path: "/tmp/aws_profile.sh", Content: "unalias aws 2>/dev/null\nexport AWS_PROFILE=dev\n"},
+		}},
+		{"Windows", "windows", `C:\Temp\aws_profile.ps1`, nil, []Script{
+			{Path: `C:\Temp\aws_profile.ps1`, Content: "Remove-Item Function:aws -ErrorAction SilentlyContinue\n$env:AWS_PROFILE=\"dev\"\n"},
+			{Path: `C:\Temp\aws_profile.bat`, Content: "doskey aws=\nset AWS_PROFILE=dev\n"},
+		}},
+		{"Linux with credentials", "linux", "/tmp/aws_profile.sh", creds, []Script{
+			{Path: "/tmp/aws_profile.sh", Content: "unalias aws 2>/dev/null\nexport AWS_PROFILE=dev\nexport AWS_ACCESS_KEY_ID='ASIATEMP'\nexport AWS_SESSION_TOKEN='to/ken+='\n"},
+		}},
+		{"Windows with credentials", "windows", `C:\Temp\aws_profile.ps1`, creds, []Script{
+			{Path: `C:\Temp\aws_profile.ps1`, Content: "Remove-Item Function:aws -ErrorAction SilentlyContinue\n$env:AWS_PROFILE=\"dev\"\n$env:AWS_ACCESS_KEY_ID=\"ASIATEMP\"\n$env:AWS_SESSION_TOKEN=\"to/ken+=\"\n"},
+			{Path: `C:\Temp\aws_profile.bat`, Content: "doskey aws=\nset AWS_PROFILE=dev\nset AWS_ACCESS_KEY_ID=ASIATEMP\nset AWS_SESSION_TOKEN=to/ken+=\n"},
+		}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			scripts := testHost(tc.goos, nil).profileScripts(tc.path, "dev", tc.extra)
+			if !reflect.DeepEqual(scripts, tc.expected) {
+				t.Errorf("Expected %+v, got %+v", tc.expected, scripts)
+			}
+			for _, script := range scripts {
+				if got := ParseProfileScript(script.Content); got != "dev" {
+					t.Errorf("Expected %s to export dev, got %q", script.Path, got)
+				}
+			}
+		})
+	}
+}
+
+func TestVaultProfileScripts(t *testing.T) {
+	testCases := []struct {
+		name     string
+		goos     string
+		path     string
+		expected []Script
+	}{
+		{"Linux", "linux", "/tmp/aws_profile.sh", []Script{
+			{Path: "/tmp/aws_profile.sh", Content: "# fancy-login profile dev\nunset AWS_PROFILE\nalias aws='aws-vault exec dev -- aws'\n"},
+		}},
+		{"Windows", "windows", `C:\Temp\aws_profile.ps1`, []Script{
+			{Path: `C:\Temp\aws_profile.ps1`, Content: "# fancy-login profile dev\nRemove-Item Env:AWS_PROFILE -ErrorAction SilentlyContinue\nfunction aws { aws-vault exec dev -- aws @args }\n"},
+			{Path: `C:\Temp\aws_profile.bat`, Content: "rem fancy-login profile dev\nset AWS_PROFILE=\ndoskey aws=aws-vault exec dev -- aws $*\n"},
+		}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			scripts := testHost(tc.goos, nil).vaultProfileScripts(tc.path, "dev", "aws-vault exec dev -- aws")
+			if !reflect.DeepEqual(scripts, tc.expected) {
+				t.Errorf("Expected %+v, got %+v", tc.expected, scripts)
+			}
+			for _, script := range scripts {
+				if got := ParseProfileScript(script.Content); got != "dev" {
+					t.Errorf("Expected %s to export dev, got %q", script.Path, got)
+				}
+			}
+		})
+	}
+}
+
+func TestTerminalEscapes(t *testing.T) {
+	testCases := []struct {
+		name      string
+		goos      string
+		env       map[string]string
+		title     string
+		namespace string
+	}{
+		{"Linux", "linux", nil, "\033]0;k9s\007", "\033]0;ns:apps\007"},
+		{"macOS Terminal", "darwin", nil, "\033]0;k9s\007", ""},
+		{"iTerm2", "darwin", map[string]string{"TERM_PROGRAM": "iTerm.app"}, "\033]0;k9s\007", "\033]1;ns:apps\007\033]1337;SetBadgeFormat="},
+		{"Windows Terminal", "windows", map[string]string{"WT_SESSION": "1"}, "\033]0;k9s\007", "\033]0;ns:apps\007"},
+		{"cmd.exe", "windows", nil, "", ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			h := testHost(tc.goos, tc.env)
+			if got := h.titleEscape("k9s"); got != tc.title {
+				t.Errorf("Expected title escape %q, got %q", tc.title, got)
+			}
+			got := h.namespaceIndicator("apps")
+			if !strings.HasPrefix(got, tc.namespace) || (tc.namespace == "") != (got == "") {
+				t.Errorf("Expected namespace escape %q, got %q", tc.namespace, got)
+			}
+			if h.namespaceIndicator("") != "" {
+				t.Error("Expected no escape for an empty namespace")
+			}
+			if reset := h.resetNamespaceIndicator(); (reset == "") != (got == "") {
+				t.Errorf("Expected a reset escape exactly where a namespace is shown, got %q", reset)
+			}
+		})
+	}
+}
+
+func TestShellArgs(t *testing.T) {
+	testCases := []struct {
+		goos     string
+		expected []string
+	}{
+		{"linux", []string{"sh", "-c", "kubelogin get-token"}},
+		{"windows", []string{"cmd", "/C", "kubelogin get-token"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.goos, func(t *testing.T) {
+			if got := testHost(tc.goos, nil).shellArgs("kubelogin get-token"); !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("Expected %v, got %v", tc.expected, got)
+			}
+		})
+	}
+}
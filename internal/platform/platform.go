@@ -0,0 +1,173 @@
+package platform
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Runner abstracts command lookup so integrations can be tested
+type Runner interface {
+	LookPath(name string) (string, error)
+}
+
+// execRunner looks up real commands
+type execRunner struct{}
+
+func (execRunner) LookPath(name string) (string, error) {
+	return exec.LookPath(name)
+}
+
+// DefaultRunner looks up real commands
+var DefaultRunner Runner = execRunner{}
+
+// IsWSL reports whether fancy-login is running under Windows Subsystem for Linux
+func IsWSL() bool {
+	return detectWSL(runtime.GOOS, os.Getenv, os.ReadFile)
+}
+
+// detectWSL checks WSL_DISTRO_NAME and falls back to /proc/version
+func detectWSL(goos string, getenv func(string) string, readFile func(string) ([]byte, error)) bool {
+	if goos != "linux" {
+		return false
+	}
+	if getenv("WSL_DISTRO_NAME") != "" || getenv("WSL_INTEROP") != "" {
+		return true
+	}
+	data, err := readFile("/proc/version")
+	if err != nil {
+		return false
+	}
+	version := strings.ToLower(string(data))
+	return strings.Contains(version, "microsoft") || strings.Contains(version, "wsl")
+}
+
+// Integration describes how URLs are opened and text is copied on this host
+type Integration struct {
+	WSL       bool
+	Opener    []string // command + args; the URL is appended
+	Clipboard []string // command + args; text is written to stdin
+}
+
+// Detect chooses URL and clipboard helpers for the current platform
+func Detect(r Runner) Integration {
+	return detectIntegration(r, runtime.GOOS, IsWSL())
+}
+
+// detectIntegration picks the first available helper for each capability
+func detectIntegration(r Runner, goos string, wsl bool) Integration {
+	integration := Integration{WSL: wsl}
+
+	var openers, clipboards [][]string
+	switch {
+	case wsl:
+		openers = [][]string{{"wslview"}, {"powershell.exe", "-NoProfile", "-Command", "Start-Process"}}
+		clipboards = [][]string{{"clip.exe"}}
+	case goos == "darwin":
+		openers = [][]string{{"open"}}
+		clipboards = [][]string{{"pbcopy"}}
+	case goos == "windows":
+		openers = [][]string{{"rundll32", "url.dll,FileProtocolHandler"}}
+		clipboards = [][]string{{"clip"}}
+	default:
+		openers = [][]string{{"xdg-open"}}
+		clipboards = [][]string{{"wl-copy"}, {"xclip", "-selection", "clipboard"}, {"xsel", "--clipboard", "--input"}}
+	}
+
+	for _, candidate := range openers {
+		if _, err := r.LookPath(candidate[0]); err == nil {
+			integration.Opener = candidate
+			break
+		}
+	}
+	for _, candidate := range clipboards {
+		if _, err := r.LookPath(candidate[0]); err == nil {
+			integration.Clipboard = candidate
+			break
+		}
+	}
+	return integration
+}
+
+// BrowserEnv returns a BROWSER value for child processes such as `aws sso
+// login`, whose default browser launch fails silently under WSL. It returns
+// an empty string when no override is needed.
+func (i Integration) BrowserEnv() string {
+	if !i.WSL || len(i.Opener) == 0 {
+		return ""
+	}
+	return strings.Join(i.Opener, " ") + " %s"
+}
+
+// Report describes the detected platform integration for diagnostics
+func (i Integration) Report() []string {
+	var lines []string
+	if i.WSL {
+		lines = append(lines, "WSL detected")
+	}
+	opener, clipboard := "none", "none"
+	if len(i.Opener) > 0 {
+		opener = i.Opener[0]
+	}
+	if len(i.Clipboard) > 0 {
+		clipboard = i.Clipboard[0]
+	}
+	lines = append(lines, fmt.Sprintf("URL opener: %s", opener), fmt.Sprintf("Clipboard: %s", clipboard))
+	return lines
+}
+
+// AWSCLIInstallHint returns instructions for installing the AWS CLI v2 on
+// the current platform
+func AWSCLIInstallHint() []string {
+	return awsCLIInstallHint(runtime.GOOS, IsWSL())
+}
+
+// K9sInstallHint returns the command installing k9s on the current platform
+func K9sInstallHint() string {
+	return K9sInstallHintFor(runtime.GOOS, IsWSL())
+}
+
+// K9sInstallHintFor returns the command installing k9s on goos; WSL uses
+// the Linux one, since fancy-login runs inside WSL
+func K9sInstallHintFor(goos string, wsl bool) string {
+	switch {
+	case wsl:
+	case goos == "darwin":
+		return "brew install derailed/k9s/k9s"
+	case goos == "windows":
+		return "winget install -e --id Derailed.k9s"
+	}
+	return "sudo snap install k9s (or download a release from https://github.com/derailed/k9s/releases)"
+}
+
+// AWSCLIInstallHintFor returns the AWS CLI install instructions for goos
+func AWSCLIInstallHintFor(goos string, wsl bool) []string {
+	return awsCLIInstallHint(goos, wsl)
+}
+
+// awsCLIInstallHint returns the install instructions for a platform
+func awsCLIInstallHint(goos string, wsl bool) []string {
+	switch {
+	case goos == "darwin":
+		return []string{
+			"brew install awscli",
+			"or download https://awscli.amazonaws.com/AWSCLIV2.pkg and run the installer",
+		}
+	case goos == "windows":
+		return []string{
+			"winget install -e --id Amazon.AWSCLI",
+			"or run: msiexec.exe /i https://awscli.amazonaws.com/AWSCLIV2.msi",
+		}
+	case wsl:
+		return []string{
+			"Install the Linux AWS CLI inside WSL (the Windows aws.exe can't use WSL paths):",
+			"curl \"https://awscli.amazonaws.com/awscli-exe-linux-x86_64.zip\" -o awscliv2.zip && unzip awscliv2.zip && sudo ./aws/install",
+		}
+	}
+	return []string{
+		"curl \"https://awscli.amazonaws.com/awscli-exe-linux-x86_64.zip\" -o awscliv2.zip && unzip awscliv2.zip && sudo ./aws/install",
+		"(use awscli-exe-linux-aarch64.zip on ARM)",
+	}
+}
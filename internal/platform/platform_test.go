@@ -0,0 +1,148 @@
+package platform
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeRunner only knows the listed binaries
+type fakeRunner struct {
+	available map[string]bool
+}
+
+func (f *fakeRunner) LookPath(name string) (string, error) {
+	if f.available[name] {
+		return "/usr/bin/" + name, nil
+	}
+	return "", errors.New("not found")
+}
+
+func TestDetectWSL(t *testing.T) {
+	testCases := []struct {
+		name     string
+		goos     string
+		env      map[string]string
+		version  string
+		expected bool
+	}{
+		{"WSL_DISTRO_NAME set", "linux", map[string]string{"WSL_DISTRO_NAME": "Ubuntu"}, "", true},
+		{"Microsoft kernel", "linux", nil, "Linux version 5.15.90.1-microsoft-standard-WSL2", true},
+		{"Plain Linux", "linux", nil, "Linux version 6.1.0-13-amd64", false},
+		{"macOS ignores env", "darwin", map[string]string{"WSL_DISTRO_NAME": "Ubuntu"}, "", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			getenv := func(key string) string { return tc.env[key] }
+			readFile := func(string) ([]byte, error) {
+				if tc.version == "" {
+					return nil, errors.New("missing")
+				}
+				return []byte(tc.version), nil
+			}
+			if got := detectWSL(tc.goos, getenv, readFile); got != tc.expected {
+				t.Errorf("detectWSL() = %v, expected %v", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestWSLDispatch(t *testing.T) {
+	testCases := []struct {
+		name           string
+		available      []string
+		expectedOpen   []string
+		expectedBrowse string
+	}{
+		{
+			name:           "Prefers wslview",
+			available:      []string{"wslview", "powershell.exe", "clip.exe"},
+			expectedOpen:   []string{"wslview"},
+			expectedBrowse: "wslview %s",
+		},
+		{
+			name:           "Falls back to powershell Start-Process",
+			available:      []string{"powershell.exe", "clip.exe"},
+			expectedOpen:   []string{"powershell.exe", "-NoProfile", "-Command", "Start-Process"},
+			expectedBrowse: "powershell.exe -NoProfile -Command Start-Process %s",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runner := &fakeRunner{available: map[string]bool{}}
+			for _, name := range tc.available {
+				runner.available[name] = true
+			}
+
+			integration := detectIntegration(runner, "linux", true)
+			if !reflect.DeepEqual(integration.Opener, tc.expectedOpen) {
+				t.Errorf("Opener = %v, expected %v", integration.Opener, tc.expectedOpen)
+			}
+			if got := integration.BrowserEnv(); got != tc.expectedBrowse {
+				t.Errorf("BrowserEnv() = %q, expected %q", got, tc.expectedBrowse)
+			}
+			if len(integration.Clipboard) == 0 || integration.Clipboard[0] != "clip.exe" {
+				t.Errorf("Expected clip.exe clipboard, got %v", integration.Clipboard)
+			}
+			if integration.Report()[0] != "WSL detected" {
+				t.Errorf("Report should start with WSL detected, got %v", integration.Report())
+			}
+		})
+	}
+}
+
+func TestNativeLinuxHasNoBrowserOverride(t *testing.T) {
+	runner := &fakeRunner{available: map[string]bool{"xdg-open": true, "xclip": true}}
+	integration := detectIntegration(runner, "linux", false)
+
+	if integration.BrowserEnv() != "" {
+		t.Errorf("Native Linux should not override BROWSER, got %q", integration.BrowserEnv())
+	}
+	if integration.Clipboard[0] != "xclip" {
+		t.Errorf("Expected xclip clipboard, got %v", integration.Clipboard)
+	}
+}
+
+func TestMissingHelpers(t *testing.T) {
+	runner := &fakeRunner{available: map[string]bool{}}
+	integration := detectIntegration(runner, "linux", true)
+
+	if integration.BrowserEnv() != "" {
+		t.Errorf("Expected no BROWSER override without a URL opener, got %q", integration.BrowserEnv())
+	}
+	if report := integration.Report(); report[1] != "URL opener: none" || report[2] != "Clipboard: none" {
+		t.Errorf("Expected missing helpers in the report, got %v", report)
+	}
+}
+
+func TestAWSCLIInstallHint(t *testing.T) {
+	testCases := []struct {
+		name     string
+		goos     string
+		wsl      bool
+		contains string
+	}{
+		{"macOS", "darwin", false, "brew install awscli"},
+		{"Windows", "windows", false, "winget"},
+		{"WSL", "linux", true, "inside WSL"},
+		{"Linux", "linux", false, "awscli-exe-linux"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			hint := awsCLIInstallHint(tc.goos, tc.wsl)
+			found := false
+			for _, line := range hint {
+				if strings.Contains(line, tc.contains) {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("Expected hint containing %q, got %v", tc.contains, hint)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package platform
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// SudoUser is the user who invoked fancy-login through sudo
+type SudoUser struct {
+	Name string
+	Home string
+	UID  int
+	GID  int
+}
+
+// LookupSudoUser returns the invoking user when running under sudo, or nil
+// when SUDO_USER is unset
+func LookupSudoUser() (*SudoUser, error) {
+	return lookupSudoUser(os.Getenv, user.Lookup)
+}
+
+// lookupSudoUser resolves SUDO_USER, preferring the SUDO_UID and SUDO_GID
+// sudo exports over the user database
+func lookupSudoUser(getenv func(string) string, lookup func(string) (*user.User, error)) (*SudoUser, error) {
+	name := getenv("SUDO_USER")
+	if name == "" || name == "root" {
+		return nil, nil
+	}
+
+	u, err := lookup(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up sudo user %s: %w", name, err)
+	}
+
+	uidValue, gidValue := getenv("SUDO_UID"), getenv("SUDO_GID")
+	if uidValue == "" {
+		uidValue = u.Uid
+	}
+	if gidValue == "" {
+		gidValue = u.Gid
+	}
+	uid, err := strconv.Atoi(uidValue)
+	if err != nil {
+		return nil, fmt.Errorf("invalid uid %q for sudo user %s", uidValue, name)
+	}
+	gid, err := strconv.Atoi(gidValue)
+	if err != nil {
+		return nil, fmt.Errorf("invalid gid %q for sudo user %s", gidValue, name)
+	}
+
+	return &SudoUser{Name: name, Home: u.HomeDir, UID: uid, GID: gid}, nil
+}
+
+// OwnershipFixer hands files written while running as root back to the
+// sudo user, so their config doesn't end up unreadable to them
+type OwnershipFixer struct {
+	User *SudoUser
+
+	// owner and chown are replaced in tests
+	owner func(path string, info fs.FileInfo) (uid int, ok bool)
+	chown func(path string, uid, gid int) error
+}
+
+// NewOwnershipFixer creates a fixer for the given sudo user
+func NewOwnershipFixer(u *SudoUser) *OwnershipFixer {
+	return &OwnershipFixer{User: u, owner: fileOwner, chown: os.Lchown}
+}
+
+// Fix changes every root-owned file below each path, and any root-owned
+// parent directories up to the user's home, to the sudo user. Missing paths
+// are skipped. It returns the number of entries changed.
+func (f *OwnershipFixer) Fix(paths []string) (int, error) {
+	fixed := 0
+	var errs []string
+
+	fixOne := func(path string, info fs.FileInfo) {
+		if uid, ok := f.owner(path, info); !ok || uid != 0 {
+			return
+		}
+		if err := f.chown(path, f.User.UID, f.User.GID); err != nil {
+			errs = append(errs, err.Error())
+			return
+		}
+		fixed++
+	}
+
+	for _, path := range paths {
+		if _, err := os.Lstat(path); err != nil {
+			continue
+		}
+		filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if info, err := d.Info(); err == nil {
+				fixOne(p, info)
+			}
+			return nil
+		})
+
+		for dir := filepath.Dir(path); isBelow(dir, f.User.Home); dir = filepath.Dir(dir) {
+			if info, err := os.Lstat(dir); err == nil {
+				fixOne(dir, info)
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return fixed, fmt.Errorf("failed to change ownership: %s", strings.Join(errs, "; "))
+	}
+	return fixed, nil
+}
+
+// isBelow reports whether path is strictly inside dir
+func isBelow(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != "." && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
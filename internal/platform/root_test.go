@@ -0,0 +1,161 @@
+package platform
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestLookupSudoUser(t *testing.T) {
+	alice := &user.User{Username: "alice", Uid: "1000", Gid: "1000", HomeDir: "/home/alice"}
+	lookup := func(name string) (*user.User, error) {
+		if name == "alice" {
+			return alice, nil
+		}
+		return nil, user.UnknownUserError(name)
+	}
+
+	testCases := []struct {
+		name        string
+		env         map[string]string
+		expected    *SudoUser
+		expectError bool
+	}{
+		{"Not under sudo", map[string]string{}, nil, false},
+		{"sudo from root", map[string]string{"SUDO_USER": "root"}, nil, false},
+		{"Ids from sudo", map[string]string{"SUDO_USER": "alice", "SUDO_UID": "1001", "SUDO_GID": "20"},
+			&SudoUser{Name: "alice", Home: "/home/alice", UID: 1001, GID: 20}, false},
+		{"Ids from user database", map[string]string{"SUDO_USER": "alice"},
+			&SudoUser{Name: "alice", Home: "/home/alice", UID: 1000, GID: 1000}, false},
+		{"Unknown user", map[string]string{"SUDO_USER": "bob"}, nil, true},
+		{"Invalid uid", map[string]string{"SUDO_USER": "alice", "SUDO_UID": "abc"}, nil, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := lookupSudoUser(func(key string) string { return tc.env[key] }, lookup)
+			if tc.expectError {
+				if err == nil {
+					t.Errorf("Expected error, got %+v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if (got == nil) != (tc.expected == nil) || (got != nil && *got != *tc.expected) {
+				t.Errorf("Expected %+v, got %+v", tc.expected, got)
+			}
+		})
+	}
+}
+
+// newFakeFixer returns a fixer that treats rootOwned paths as owned by root
+// and records chowns instead of performing them
+func newFakeFixer(home string, rootOwned map[string]bool, chowned *[]string, failOn string) *OwnershipFixer {
+	return &OwnershipFixer{
+		User: &SudoUser{Name: "alice", Home: home, UID: 1000, GID: 1000},
+		owner: func(path string, info fs.FileInfo) (int, bool) {
+			if rootOwned[path] {
+				return 0, true
+			}
+			return 1000, true
+		},
+		chown: func(path string, uid, gid int) error {
+			if path == failOn {
+				return errors.New("operation not permitted")
+			}
+			*chowned = append(*chowned, path)
+			return nil
+		},
+	}
+}
+
+// writeTestFile creates a file below home, including its directories
+func writeTestFile(t *testing.T, home, rel string) string {
+	t.Helper()
+	path := filepath.Join(home, rel)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("x"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestOwnershipFixer(t *testing.T) {
+	home := t.TempDir()
+	config := writeTestFile(t, home, ".fancy-config.yaml")
+	state := writeTestFile(t, home, ".fancy-login/state.json")
+	token := writeTestFile(t, home, ".aws/sso/cache/abc.json")
+	writeTestFile(t, home, ".aws/sso/cache/mine.json")
+	writeTestFile(t, home, ".aws/config")
+
+	// .aws/sso and its cache were created by the root run, .aws was not
+	rootOwned := map[string]bool{
+		config:                              true,
+		filepath.Join(home, ".fancy-login"): true,
+		state:                               true,
+		filepath.Join(home, ".aws", "sso"):  true,
+		filepath.Join(home, ".aws", "sso", "cache"): true,
+		token: true,
+	}
+
+	var chowned []string
+	fixer := newFakeFixer(home, rootOwned, &chowned, "")
+	fixed, err := fixer.Fix([]string{
+		config,
+		filepath.Join(home, ".fancy-login"),
+		filepath.Join(home, ".aws", "sso", "cache"),
+		filepath.Join(home, ".kube", "config"), // missing
+	})
+	if err != nil {
+		t.Fatalf("Fix failed: %v", err)
+	}
+
+	var expected []string
+	for path := range rootOwned {
+		expected = append(expected, path)
+	}
+	sort.Strings(expected)
+	sort.Strings(chowned)
+	if fixed != len(expected) || len(chowned) != len(expected) {
+		t.Fatalf("Expected %d entries fixed, got %d: %v", len(expected), fixed, chowned)
+	}
+	for i := range expected {
+		if chowned[i] != expected[i] {
+			t.Errorf("Expected %s chowned, got %s", expected[i], chowned[i])
+		}
+	}
+}
+
+func TestOwnershipFixerStaysInsideHome(t *testing.T) {
+	home := t.TempDir()
+	path := writeTestFile(t, home, ".fancy-config.yaml")
+
+	// Even a root-owned home or parent must not be touched
+	rootOwned := map[string]bool{path: true, home: true, filepath.Dir(home): true}
+	var chowned []string
+	if _, err := newFakeFixer(home, rootOwned, &chowned, "").Fix([]string{path}); err != nil {
+		t.Fatalf("Fix failed: %v", err)
+	}
+	if len(chowned) != 1 || chowned[0] != path {
+		t.Errorf("Expected only %s chowned, got %v", path, chowned)
+	}
+}
+
+func TestOwnershipFixerReportsFailures(t *testing.T) {
+	home := t.TempDir()
+	path := writeTestFile(t, home, ".fancy-config.yaml")
+
+	var chowned []string
+	fixer := newFakeFixer(home, map[string]bool{path: true}, &chowned, path)
+	if _, err := fixer.Fix([]string{path}); err == nil {
+		t.Error("Expected chown failure to be reported")
+	}
+}
@@ -0,0 +1,23 @@
+//go:build !windows
+
+package platform
+
+import (
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+// IsElevated reports whether fancy-login is running as root
+func IsElevated() bool {
+	return os.Geteuid() == 0
+}
+
+// fileOwner returns the uid owning a file
+func fileOwner(path string, info fs.FileInfo) (int, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, false
+	}
+	return int(stat.Uid), true
+}
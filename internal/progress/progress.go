@@ -0,0 +1,109 @@
+// Package progress reports the phases of a login as they start and finish,
+// so a wrapper watching the run from outside knows which phase it is in.
+package progress
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Phase names are a stable contract for progress file consumers; they are
+// also the phase labels of the metrics. A login runs them in this order,
+// skipping the ones that don't apply: pre_login_hook only for kube-only
+// profiles, aws_login, account_id and ecr_login only for AWS profiles,
+// kube_context not with --no-k8s and ecr_login only when the profile logs
+// in to ECR with ecr_login_mode blocking.
+const (
+	PhaseSelectProfile = "select_profile"
+	PhasePreLoginHook  = "pre_login_hook"
+	PhaseAWSLogin      = "aws_login"
+	PhaseKubeContext   = "kube_context"
+	PhaseAccountID     = "account_id"
+	PhaseECRLogin      = "ecr_login"
+)
+
+// Statuses of an Event
+const (
+	StatusStarted   = "started"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// Sink is a recipient of phase progress
+type Sink interface {
+	Start(phase string)
+	Finish(phase string, err error)
+}
+
+// Discard is the sink used when progress isn't reported anywhere
+var Discard Sink = discard{}
+
+type discard struct{}
+
+func (discard) Start(string)         {}
+func (discard) Finish(string, error) {}
+
+// Event is one line of a progress file
+type Event struct {
+	Phase  string    `json:"phase"`
+	Status string    `json:"status"`
+	Time   time.Time `json:"time"`
+	Error  string    `json:"error,omitempty"`
+}
+
+// FileSink appends one JSON line per event to a file. Each line is synced
+// before the phase continues, so a watchdog that kills the process still
+// finds the phase it was stuck in as the last started one.
+type FileSink struct {
+	f    *os.File
+	warn func(string)
+	now  func() time.Time
+}
+
+// OpenFile opens path for appending, creating it if needed. Write failures
+// are passed to warn and never fail the login.
+func OpenFile(path string, warn func(string)) (*FileSink, error) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open progress file: %w", err)
+	}
+	return &FileSink{f: f, warn: warn, now: time.Now}, nil
+}
+
+// Start records that phase began
+func (s *FileSink) Start(phase string) {
+	s.write(Event{Phase: phase, Status: StatusStarted})
+}
+
+// Finish records that phase ended, failed if err is non-nil
+func (s *FileSink) Finish(phase string, err error) {
+	event := Event{Phase: phase, Status: StatusSucceeded}
+	if err != nil {
+		event.Status = StatusFailed
+		event.Error = err.Error()
+	}
+	s.write(event)
+}
+
+// Close closes the file
+func (s *FileSink) Close() error {
+	return s.f.Close()
+}
+
+func (s *FileSink) write(event Event) {
+	event.Time = s.now().UTC()
+	line, err := json.Marshal(event)
+	if err != nil {
+		s.warn(fmt.Sprintf("Could not write progress: %v", err))
+		return
+	}
+	if _, err := s.f.Write(append(line, '\n')); err != nil {
+		s.warn(fmt.Sprintf("Could not write progress: %v", err))
+		return
+	}
+	if err := s.f.Sync(); err != nil {
+		s.warn(fmt.Sprintf("Could not sync progress file: %v", err))
+	}
+}
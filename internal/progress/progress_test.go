@@ -0,0 +1,82 @@
+package progress
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// readEvents parses every line of a progress file
+func readEvents(t *testing.T, path string) []Event {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var events []Event
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("Invalid progress line %q: %v", scanner.Text(), err)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "progress.jsonl")
+	// An earlier run's lines are kept
+	if err := os.WriteFile(path, []byte(`{"phase":"aws_login","status":"started","time":"2024-01-01T00:00:00Z"}`+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	sink, err := OpenFile(path, func(msg string) { t.Errorf("Unexpected warning: %s", msg) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	sink.now = func() time.Time { return now }
+
+	sink.Start(PhaseAWSLogin)
+	sink.Finish(PhaseAWSLogin, nil)
+	sink.Start(PhaseECRLogin)
+	sink.Finish(PhaseECRLogin, errors.New("docker not running"))
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Event{
+		{Phase: PhaseAWSLogin, Status: StatusStarted, Time: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{Phase: PhaseAWSLogin, Status: StatusStarted, Time: now},
+		{Phase: PhaseAWSLogin, Status: StatusSucceeded, Time: now},
+		{Phase: PhaseECRLogin, Status: StatusStarted, Time: now},
+		{Phase: PhaseECRLogin, Status: StatusFailed, Time: now, Error: "docker not running"},
+	}
+	events := readEvents(t, path)
+	if len(events) != len(expected) {
+		t.Fatalf("Expected %d events, got %d: %+v", len(expected), len(events), events)
+	}
+	for i := range expected {
+		if !events[i].Time.Equal(expected[i].Time) {
+			t.Errorf("Event %d: expected time %v, got %v", i, expected[i].Time, events[i].Time)
+		}
+		events[i].Time = expected[i].Time
+		if events[i] != expected[i] {
+			t.Errorf("Event %d: expected %+v, got %+v", i, expected[i], events[i])
+		}
+	}
+}
+
+func TestOpenFileMissingDirectory(t *testing.T) {
+	if _, err := OpenFile(filepath.Join(t.TempDir(), "missing", "progress.jsonl"), nil); err == nil {
+		t.Error("Expected an error for a missing directory")
+	}
+}
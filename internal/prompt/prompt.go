@@ -0,0 +1,374 @@
+package prompt
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"fancy-login/internal/a11y"
+	"fancy-login/internal/platform"
+)
+
+// DefaultAffirmativeAnswers are accepted as "yes" (English, German, French, Spanish/Italian)
+var DefaultAffirmativeAnswers = []string{"y", "yes", "j", "ja", "o", "oui", "s", "si", "sí"}
+
+// DefaultNegativeAnswers are accepted as "no"
+var DefaultNegativeAnswers = []string{"n", "no", "nein", "non"}
+
+// Answer is the interpretation of a y/n response
+type Answer int
+
+const (
+	Unrecognized Answer = iota
+	Yes
+	No
+	Empty
+)
+
+// Prompter asks y/n questions with locale-tolerant answer matching
+type Prompter struct {
+	reader *bufio.Reader
+	out    io.Writer
+	// input is what reader reads from, closed by Menu to stop a read its
+	// ctx outlived; nil if it can't be closed
+	input       io.Closer
+	affirmative []string
+	negative    []string
+	// auto answers every question without reading input, see NewAutoPrompter
+	auto      bool
+	assumeYes bool
+}
+
+var nonInteractive, assumeYes bool
+
+// output is where questions go; nil means stdout
+var output io.Writer
+
+// SetOutput sends the questions of NewTTYPrompter and the wizards to w
+// instead of stdout, e.g. to keep stdout free for JSON output. nil goes
+// back to stdout.
+func SetOutput(w io.Writer) {
+	output = w
+}
+
+// Output returns where questions go
+func Output() io.Writer {
+	if output == nil {
+		return os.Stdout
+	}
+	return output
+}
+
+// SetNonInteractive turns prompts off or on for this run. Off,
+// NewTTYPrompter no longer opens the terminal, y/n questions take their
+// default, or yes with yes set, and pickers and free-form questions get no
+// answer.
+func SetNonInteractive(on, yes bool) {
+	nonInteractive, assumeYes = on, yes
+}
+
+// Interactive reports whether this run may ask the user anything, so
+// callers know not to start fzf or other full-screen tools
+func Interactive() bool {
+	return !nonInteractive
+}
+
+// NewPrompter creates a prompter; empty answer lists fall back to the defaults
+func NewPrompter(reader *bufio.Reader, out io.Writer, affirmative, negative []string) *Prompter {
+	if len(affirmative) == 0 {
+		affirmative = DefaultAffirmativeAnswers
+	}
+	if len(negative) == 0 {
+		negative = DefaultNegativeAnswers
+	}
+	return &Prompter{
+		reader:      reader,
+		out:         out,
+		affirmative: affirmative,
+		negative:    negative,
+	}
+}
+
+// NewAutoPrompter creates a prompter that never reads input. Confirm
+// answers with its default, or yes with assumeYes, and prints the answer so
+// logs show what was decided; everything else gets no answer.
+func NewAutoPrompter(out io.Writer, assumeYes bool) *Prompter {
+	p := NewPrompter(bufio.NewReader(strings.NewReader("")), out, nil, nil)
+	p.auto, p.assumeYes = true, assumeYes
+	return p
+}
+
+// NewTTYPrompter creates a prompter reading from the terminal, which keeps
+// working after fzf has consumed stdin. The returned close func releases the
+// terminal. In non-interactive mode it returns an auto prompter instead.
+func NewTTYPrompter(affirmative, negative []string) (*Prompter, func(), error) {
+	if nonInteractive {
+		return NewAutoPrompter(Output(), assumeYes), func() {}, nil
+	}
+	tty, err := platform.OpenTTY()
+	if err != nil {
+		return nil, func() {}, err
+	}
+	p := NewPrompter(bufio.NewReader(tty), Output(), affirmative, negative)
+	p.SetInput(tty)
+	return p, func() { tty.Close() }, nil
+}
+
+// SetInput tells the prompter what its reader reads from, so Menu can close
+// it when its ctx ends rather than leave a read blocked on it. The prompter
+// reads nothing more after that.
+func (p *Prompter) SetInput(input io.Closer) {
+	p.input = input
+}
+
+// MatchAnswer classifies input against the affirmative and negative sets,
+// ignoring case and surrounding whitespace
+func MatchAnswer(input string, affirmative, negative []string) Answer {
+	normalized := strings.ToLower(strings.TrimSpace(input))
+	if normalized == "" {
+		return Empty
+	}
+	for _, a := range affirmative {
+		if normalized == strings.ToLower(a) {
+			return Yes
+		}
+	}
+	for _, n := range negative {
+		if normalized == strings.ToLower(n) {
+			return No
+		}
+	}
+	return Unrecognized
+}
+
+// Confirm asks a y/n question. Empty input returns def; unrecognized input
+// re-prompts once before falling back to def.
+func (p *Prompter) Confirm(question string, def bool) bool {
+	hint := "(y/N)"
+	if def {
+		hint = "(Y/n)"
+	}
+	if a11y.Enabled() {
+		defaultWord := "no"
+		if def {
+			defaultWord = "yes"
+		}
+		hint = fmt.Sprintf("(answer %s or %s, default %s)", p.affirmative[0], p.negative[0], defaultWord)
+	}
+	if p.auto {
+		answer := def || p.assumeYes
+		p.ask(question, hint)
+		if answer {
+			fmt.Fprintln(p.out, p.affirmative[0])
+		} else {
+			fmt.Fprintln(p.out, p.negative[0])
+		}
+		return answer
+	}
+
+	for attempt := 0; attempt < 2; attempt++ {
+		p.ask(question, hint)
+		input, err := p.reader.ReadString('\n')
+		switch MatchAnswer(input, p.affirmative, p.negative) {
+		case Yes:
+			return true
+		case No:
+			return false
+		case Empty:
+			return def
+		}
+		if err != nil {
+			return def
+		}
+		fmt.Fprintf(p.out, "Please answer %s or %s.\n", p.affirmative[0], p.negative[0])
+	}
+	return def
+}
+
+// ConfirmDestructive asks for confirmation of a destructive action. Only a
+// full affirmative word (e.g. "yes" or "ja", never a single letter) counts.
+func (p *Prompter) ConfirmDestructive(question string) bool {
+	p.ask(question, "(type 'yes' to confirm)")
+	input := p.ReadLine()
+
+	var words []string
+	for _, a := range p.affirmative {
+		if len([]rune(a)) > 1 {
+			words = append(words, a)
+		}
+	}
+	return MatchAnswer(input, words, nil) == Yes
+}
+
+// ConfirmTyped asks the user to type word to confirm an action that can't
+// be undone. Anything else, including an empty line, declines.
+func (p *Prompter) ConfirmTyped(question, word string) bool {
+	p.ask(question, fmt.Sprintf("(type '%s' to confirm)", word))
+	return strings.EqualFold(p.ReadLine(), word)
+}
+
+// Choose shows options as a numbered list and asks for one by number. It
+// is the picker in screen reader mode, where fzf's full-screen redraws
+// can't be followed. Invalid input re-prompts once; ok is false if no
+// option was chosen.
+func (p *Prompter) Choose(question string, options []string) (index int, ok bool) {
+	if p.auto {
+		return 0, false
+	}
+	out := a11y.Writer(p.out)
+	for i, option := range options {
+		fmt.Fprintf(out, "%d. %s\n", i+1, option)
+	}
+
+	hint := fmt.Sprintf("(number from 1 to %d, empty cancels)", len(options))
+	for attempt := 0; attempt < 2; attempt++ {
+		p.ask(question, hint)
+		input, err := p.reader.ReadString('\n')
+		input = strings.TrimSpace(input)
+		if input == "" {
+			return 0, false
+		}
+		if n, convErr := strconv.Atoi(input); convErr == nil && n >= 1 && n <= len(options) {
+			return n - 1, true
+		}
+		if err != nil {
+			return 0, false
+		}
+		fmt.Fprintf(p.out, "Please enter a number from 1 to %d.\n", len(options))
+	}
+	return 0, false
+}
+
+// MenuItem is a line of a Menu; header lines, including blank ones, are
+// shown without a number and can't be chosen
+type MenuItem struct {
+	Text   string
+	Header bool
+}
+
+// Menu is the built-in picker used when fzf isn't available. It lists the
+// items with the selectable ones numbered and accepts a number or part of
+// an item's text that matches exactly one item. Invalid input re-prompts;
+// ok is false if nothing was chosen or ctx ended first, so callers check
+// ctx.Err() for a timeout.
+func (p *Prompter) Menu(ctx context.Context, question string, items []MenuItem) (index int, ok bool) {
+	if p.auto {
+		return 0, false
+	}
+	var choices []int
+	for i, item := range items {
+		if item.Header {
+			fmt.Fprintln(p.out, item.Text)
+			continue
+		}
+		choices = append(choices, i)
+		fmt.Fprintf(p.out, "%3d. %s\n", len(choices), strings.TrimSpace(item.Text))
+	}
+	if len(choices) == 0 {
+		return 0, false
+	}
+
+	hint := fmt.Sprintf("(number from 1 to %d or part of a name, empty cancels)", len(choices))
+	for {
+		p.ask(question, hint)
+		var input string
+		select {
+		case <-ctx.Done():
+			// Closing the input ends the read still waiting on it
+			if p.input != nil {
+				p.input.Close()
+			}
+			fmt.Fprintln(p.out)
+			return 0, false
+		case line := <-p.readLine():
+			if line.input == "" && line.err != nil {
+				return 0, false
+			}
+			input = strings.TrimSpace(line.input)
+		}
+		if input == "" {
+			return 0, false
+		}
+		if n, err := strconv.Atoi(input); err == nil {
+			if n >= 1 && n <= len(choices) {
+				return choices[n-1], true
+			}
+			fmt.Fprintf(p.out, "Please enter a number from 1 to %d.\n", len(choices))
+			continue
+		}
+
+		var matches []int
+		for _, i := range choices {
+			if strings.Contains(strings.ToLower(items[i].Text), strings.ToLower(input)) {
+				matches = append(matches, i)
+			}
+		}
+		switch len(matches) {
+		case 1:
+			return matches[0], true
+		case 0:
+			fmt.Fprintf(p.out, "Nothing matches %q.\n", input)
+		default:
+			fmt.Fprintf(p.out, "%d items match %q, please be more specific.\n", len(matches), input)
+		}
+	}
+}
+
+// readResult is one line read by readLine
+type readResult struct {
+	input string
+	err   error
+}
+
+// readLine reads one line in the background, so Menu can watch its ctx
+// meanwhile. The channel is buffered: the read finishes and its goroutine
+// exits even if nobody waits for it anymore.
+func (p *Prompter) readLine() <-chan readResult {
+	result := make(chan readResult, 1)
+	go func() {
+		input, err := p.reader.ReadString('\n')
+		result <- readResult{input, err}
+	}()
+	return result
+}
+
+// AskLine asks for free-form input and returns it trimmed; an empty answer
+// means def, which the prompt shows. The caller substitutes def.
+func (p *Prompter) AskLine(question, def string) string {
+	switch {
+	case a11y.Enabled() && def != "":
+		p.ask(question, fmt.Sprintf("(default %s)", def))
+	case a11y.Enabled():
+		p.ask(question, "(no default)")
+	case def != "":
+		p.ask(question, "["+def+"]")
+	default:
+		fmt.Fprintf(p.out, "%s: ", question)
+	}
+	return p.ReadLine()
+}
+
+// ask prints a question with its answer hint. In screen reader mode it is
+// announced with a "question:" prefix and stripped of decoration.
+func (p *Prompter) ask(question, hint string) {
+	if a11y.Enabled() {
+		fmt.Fprintf(p.out, "question: %s %s: ", a11y.Plain(question), hint)
+		return
+	}
+	fmt.Fprintf(p.out, "%s %s: ", question, hint)
+}
+
+// ReadLine reads a trimmed line of free-form input. An auto prompter reads
+// nothing and ends the question's line.
+func (p *Prompter) ReadLine() string {
+	if p.auto {
+		fmt.Fprintln(p.out)
+		return ""
+	}
+	input, _ := p.reader.ReadString('\n')
+	return strings.TrimSpace(input)
+}
@@ -0,0 +1,299 @@
+package prompt
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"fancy-login/internal/a11y"
+)
+
+func TestMatchAnswer(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected Answer
+	}{
+		{"y", Yes},
+		{"Yes", Yes},
+		{"  j \n", Yes},
+		{"JA", Yes},
+		{"oui", Yes},
+		{"si", Yes},
+		{"n", No},
+		{"Nein", No},
+		{"non", No},
+		{"", Empty},
+		{"   ", Empty},
+		{"maybe", Unrecognized},
+		{"yess", Unrecognized},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			if got := MatchAnswer(tc.input, DefaultAffirmativeAnswers, DefaultNegativeAnswers); got != tc.expected {
+				t.Errorf("MatchAnswer(%q) = %v, expected %v", tc.input, got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		def      bool
+		expected bool
+		prompts  int
+	}{
+		{"German yes", "j\n", false, true, 1},
+		{"Empty uses default yes", "\n", true, true, 1},
+		{"Empty uses default no", "\n", false, false, 1},
+		{"Re-prompts once then accepts", "what\nja\n", false, true, 2},
+		{"Falls back to default after two unrecognized", "what\nhuh\ny\n", false, false, 2},
+		{"EOF uses default", "", true, true, 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			p := NewPrompter(bufio.NewReader(strings.NewReader(tc.input)), &out, nil, nil)
+
+			if got := p.Confirm("Continue?", tc.def); got != tc.expected {
+				t.Errorf("Confirm() = %v, expected %v", got, tc.expected)
+			}
+			if prompts := strings.Count(out.String(), "Continue?"); prompts != tc.prompts {
+				t.Errorf("Prompted %d times, expected %d", prompts, tc.prompts)
+			}
+		})
+	}
+}
+
+func TestConfirmDestructiveRequiresFullWord(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected bool
+	}{
+		{"yes\n", true},
+		{"ja\n", true},
+		{"y\n", false},
+		{"j\n", false},
+		{"\n", false},
+		{"no\n", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(strings.TrimSpace(tc.input), func(t *testing.T) {
+			var out bytes.Buffer
+			p := NewPrompter(bufio.NewReader(strings.NewReader(tc.input)), &out, nil, nil)
+			if got := p.ConfirmDestructive("Override?"); got != tc.expected {
+				t.Errorf("ConfirmDestructive(%q) = %v, expected %v", tc.input, got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestConfirmTyped(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected bool
+	}{
+		{"override\n", true},
+		{"  Override \n", true},
+		{"yes\n", false},
+		{"y\n", false},
+		{"overide\n", false},
+		{"\n", false},
+		{"", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(strings.TrimSpace(tc.input), func(t *testing.T) {
+			var out bytes.Buffer
+			p := NewPrompter(bufio.NewReader(strings.NewReader(tc.input)), &out, nil, nil)
+			if got := p.ConfirmTyped("Discard?", "override"); got != tc.expected {
+				t.Errorf("ConfirmTyped(%q) = %v, expected %v", tc.input, got, tc.expected)
+			}
+			if !strings.Contains(out.String(), "type 'override' to confirm") {
+				t.Errorf("Expected the word in the prompt, got %q", out.String())
+			}
+		})
+	}
+}
+
+func TestCustomAnswers(t *testing.T) {
+	p := NewPrompter(bufio.NewReader(strings.NewReader("da\n")), &bytes.Buffer{}, []string{"da"}, []string{"nyet"})
+	if !p.Confirm("Continue?", false) {
+		t.Error("Expected custom affirmative answer to be accepted")
+	}
+}
+
+func TestChoose(t *testing.T) {
+	options := []string{"dev", "staging", "prod"}
+	testCases := []struct {
+		name          string
+		input         string
+		expectedIndex int
+		expectedOK    bool
+	}{
+		{"Valid number", "2\n", 1, true},
+		{"Re-prompts once", "9\n3\n", 2, true},
+		{"Gives up after two invalid", "x\n0\n1\n", 0, false},
+		{"Empty cancels", "\n", 0, false},
+		{"EOF cancels", "", 0, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			p := NewPrompter(bufio.NewReader(strings.NewReader(tc.input)), &out, nil, nil)
+
+			index, ok := p.Choose("Select AWS Profile", options)
+			if index != tc.expectedIndex || ok != tc.expectedOK {
+				t.Errorf("Expected (%d, %v), got (%d, %v)", tc.expectedIndex, tc.expectedOK, index, ok)
+			}
+			if !strings.Contains(out.String(), "1. dev\n2. staging\n3. prod\n") {
+				t.Errorf("Expected a numbered list, got %q", out.String())
+			}
+		})
+	}
+}
+
+func TestMenu(t *testing.T) {
+	items := []MenuItem{
+		{Text: "=== QUICK ACCESS ===", Header: true},
+		{Text: "  dev      (123)"},
+		{Text: "", Header: true},
+		{Text: "=== OTHER ===", Header: true},
+		{Text: "  staging  (456)"},
+		{Text: "  prod     (789)"},
+	}
+	testCases := []struct {
+		name          string
+		input         string
+		expectedIndex int
+		expectedOK    bool
+	}{
+		{"Number skips headers", "2\n", 4, true},
+		{"Unique match", "prod\n", 5, true},
+		{"Re-prompts until valid", "9\nOTHER\n1\n", 1, true},
+		{"Ambiguous match re-prompts", "(\nstag\n", 4, true},
+		{"Empty cancels", "\n", 0, false},
+		{"EOF cancels", "", 0, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			p := NewPrompter(bufio.NewReader(strings.NewReader(tc.input)), &out, nil, nil)
+
+			index, ok := p.Menu(context.Background(), "Select AWS Profile", items)
+			if index != tc.expectedIndex || ok != tc.expectedOK {
+				t.Errorf("Expected (%d, %v), got (%d, %v)", tc.expectedIndex, tc.expectedOK, index, ok)
+			}
+			expected := "=== QUICK ACCESS ===\n  1. dev      (123)\n\n=== OTHER ===\n  2. staging  (456)\n  3. prod     (789)\n"
+			if !strings.HasPrefix(out.String(), expected) {
+				t.Errorf("Expected headers without numbers, got %q", out.String())
+			}
+		})
+	}
+}
+
+func TestMenuTimeout(t *testing.T) {
+	// A pipe nobody writes to blocks like a user who walked away
+	r, w := io.Pipe()
+	defer w.Close()
+	p := NewPrompter(bufio.NewReader(r), io.Discard, nil, nil)
+	p.SetInput(r)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, ok := p.Menu(ctx, "Select", []MenuItem{{Text: "dev"}}); ok {
+		t.Error("Expected nothing to be chosen")
+	}
+	if ctx.Err() != context.DeadlineExceeded {
+		t.Errorf("Expected the menu to return at the deadline, got %v", ctx.Err())
+	}
+	// The pending read must have been stopped, not left waiting for input
+	if _, err := w.Write([]byte("1\n")); err != io.ErrClosedPipe {
+		t.Errorf("Expected the menu to close its input, write got %v", err)
+	}
+}
+
+func TestMenuReadsOnlyTheAnswer(t *testing.T) {
+	p := NewPrompter(bufio.NewReader(strings.NewReader("1\nnext answer\n")), io.Discard, nil, nil)
+	if index, ok := p.Menu(context.Background(), "Select", []MenuItem{{Text: "dev"}}); !ok || index != 0 {
+		t.Fatalf("Expected dev to be chosen, got %d, %v", index, ok)
+	}
+	if line := p.ReadLine(); line != "next answer" {
+		t.Errorf("Expected the next prompt to read %q, got %q", "next answer", line)
+	}
+}
+
+func TestScreenReaderPrompts(t *testing.T) {
+	a11y.Enable(true)
+	defer a11y.Enable(false)
+
+	testCases := []struct {
+		name     string
+		ask      func(p *Prompter)
+		expected string
+	}{
+		{"Confirm", func(p *Prompter) { p.Confirm("✅ Continue?", false) }, "question: Continue? (answer y or n, default no): "},
+		{"Confirm default yes", func(p *Prompter) { p.Confirm("Continue?", true) }, "question: Continue? (answer y or n, default yes): "},
+		{"Line with default", func(p *Prompter) { p.AskLine("Default AWS region", "eu-central-1") }, "question: Default AWS region (default eu-central-1): "},
+		{"Line without default", func(p *Prompter) { p.AskLine("Namespace", "") }, "question: Namespace (no default): "},
+		{"Typed", func(p *Prompter) { p.ConfirmTyped("Discard them?", "override") }, "question: Discard them? (type 'override' to confirm): "},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			tc.ask(NewPrompter(bufio.NewReader(strings.NewReader("\n")), &out, nil, nil))
+			if out.String() != tc.expected {
+				t.Errorf("Expected %q, got %q", tc.expected, out.String())
+			}
+		})
+	}
+}
+
+func TestAutoPrompter(t *testing.T) {
+	testCases := []struct {
+		name      string
+		assumeYes bool
+		def       bool
+		expected  bool
+		answer    string
+	}{
+		{"Default no", false, false, false, ": n\n"},
+		{"Default yes", false, true, true, ": y\n"},
+		{"Assume yes", true, false, true, ": y\n"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out bytes.Buffer
+			p := NewAutoPrompter(&out, tc.assumeYes)
+
+			if got := p.Confirm("Continue?", tc.def); got != tc.expected {
+				t.Errorf("Confirm() = %v, expected %v", got, tc.expected)
+			}
+			if !strings.HasSuffix(out.String(), tc.answer) {
+				t.Errorf("Expected the answer %q to be logged, got %q", tc.answer, out.String())
+			}
+			// Nothing but y/n questions is ever answered, not even with assumeYes
+			if p.ConfirmTyped("Delete?", "delete") || p.ConfirmDestructive("Wipe?") {
+				t.Error("Expected typed confirmations to decline")
+			}
+			if _, ok := p.Choose("Pick", []string{"a", "b"}); ok {
+				t.Error("Expected Choose to pick nothing")
+			}
+			if _, ok := p.Menu(context.Background(), "Pick", []MenuItem{{Text: "a"}}); ok {
+				t.Error("Expected Menu to pick nothing")
+			}
+		})
+	}
+}
@@ -0,0 +1,299 @@
+// Package selfupdate replaces the running binary with the latest GitHub
+// release, after checking the downloaded archive against the release's
+// checksums file.
+package selfupdate
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// Repo is the GitHub repository releases are published to
+	Repo = "reinkes/go-fancy-login"
+	// DefaultAPIBase is the GitHub REST API
+	DefaultAPIBase = "https://api.github.com"
+	// ChecksumsAsset is the release asset holding `sha256sum` output for
+	// every archive
+	ChecksumsAsset = "checksums.txt"
+	// CheckTTL is how long a cached release check is trusted by `version`
+	CheckTTL = 24 * time.Hour
+
+	binaryName = "fancy-login-go"
+	// maxDownload bounds release downloads; archives are a few MB
+	maxDownload = 200 << 20
+)
+
+// Release is a GitHub release
+type Release struct {
+	TagName string  `json:"tag_name"`
+	Assets  []Asset `json:"assets"`
+}
+
+// Asset is a file attached to a release
+type Asset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// find returns the asset called name
+func (r *Release) find(name string) (Asset, bool) {
+	for _, a := range r.Assets {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return Asset{}, false
+}
+
+// Client talks to GitHub; tests point APIBase at a local server
+type Client struct {
+	HTTP    *http.Client
+	APIBase string
+}
+
+// NewClient returns a client for github.com. The default transport honors
+// HTTPS_PROXY and NO_PROXY.
+func NewClient() *Client {
+	return &Client{HTTP: &http.Client{Timeout: 60 * time.Second}, APIBase: DefaultAPIBase}
+}
+
+// Latest returns the latest published release
+func (c *Client) Latest(ctx context.Context) (*Release, error) {
+	body, err := c.get(ctx, fmt.Sprintf("%s/repos/%s/releases/latest", c.APIBase, Repo))
+	if err != nil {
+		return nil, fmt.Errorf("failed to check the latest release: %w", err)
+	}
+	var release Release
+	if err := json.Unmarshal(body, &release); err != nil {
+		return nil, fmt.Errorf("failed to parse the latest release: %w", err)
+	}
+	if release.TagName == "" {
+		return nil, errors.New("the latest release has no tag")
+	}
+	return &release, nil
+}
+
+// Download fetches the archive for goos/goarch from release, verifies it
+// against the checksums file and returns the binary inside it
+func (c *Client) Download(ctx context.Context, release *Release, goos, goarch string) ([]byte, error) {
+	name := AssetName(release.TagName, goos, goarch)
+	archive, ok := release.find(name)
+	if !ok {
+		return nil, fmt.Errorf("release %s has no asset %s for this platform", release.TagName, name)
+	}
+	sums, ok := release.find(ChecksumsAsset)
+	if !ok {
+		return nil, fmt.Errorf("release %s has no %s, refusing to install an unverified binary", release.TagName, ChecksumsAsset)
+	}
+
+	sumData, err := c.get(ctx, sums.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", ChecksumsAsset, err)
+	}
+	expected, err := findChecksum(sumData, name)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := c.get(ctx, archive.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", name, err)
+	}
+	sum := sha256.Sum256(data)
+	if actual := hex.EncodeToString(sum[:]); actual != expected {
+		return nil, fmt.Errorf("checksum mismatch for %s: expected %s, got %s", name, expected, actual)
+	}
+
+	if strings.HasSuffix(name, ".zip") {
+		return extractZip(data, binaryName+".exe")
+	}
+	return extractTarGz(data, binaryName)
+}
+
+// get fetches url, sending GITHUB_TOKEN if set to avoid the anonymous rate
+// limit
+func (c *Client) get(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" && strings.HasPrefix(url, c.APIBase) {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxDownload))
+}
+
+// AssetName returns the archive the release pipeline builds for goos/goarch
+func AssetName(tag, goos, goarch string) string {
+	if goos == "windows" {
+		return fmt.Sprintf("%s-%s-%s-%s.exe.zip", binaryName, tag, goos, goarch)
+	}
+	return fmt.Sprintf("%s-%s-%s-%s.tar.gz", binaryName, tag, goos, goarch)
+}
+
+// findChecksum returns the SHA-256 for name from `sha256sum` output
+func findChecksum(data []byte, name string) (string, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && strings.TrimPrefix(fields[1], "*") == name {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	return "", fmt.Errorf("%s has no checksum for %s", ChecksumsAsset, name)
+}
+
+// extractTarGz returns the file called name from a .tar.gz archive
+func extractTarGz(data []byte, name string) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive: %w", err)
+	}
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read archive: %w", err)
+		}
+		if filepath.Base(header.Name) == name && header.Typeflag == tar.TypeReg {
+			return io.ReadAll(io.LimitReader(tr, maxDownload))
+		}
+	}
+	return nil, fmt.Errorf("archive has no %s", name)
+}
+
+// extractZip returns the file called name from a .zip archive
+func extractZip(data []byte, name string) ([]byte, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive: %w", err)
+	}
+	for _, f := range zr.File {
+		if filepath.Base(f.Name) != name {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return io.ReadAll(io.LimitReader(rc, maxDownload))
+	}
+	return nil, fmt.Errorf("archive has no %s", name)
+}
+
+// Replace swaps the executable at exePath for binary. The new binary is
+// written next to it and renamed over it, so a failure never leaves a
+// half-written executable. Windows can't overwrite a running executable but
+// can rename it, so there the old one is moved aside first and removed by
+// CleanupOld on a later run.
+func Replace(exePath string, binary []byte, goos string) error {
+	info, err := os.Stat(exePath)
+	if err != nil {
+		return err
+	}
+	newPath := exePath + ".new"
+	if err := os.WriteFile(newPath, binary, info.Mode().Perm()); err != nil {
+		return fmt.Errorf("failed to write the new binary: %w", err)
+	}
+
+	if goos == "windows" {
+		oldPath := exePath + ".old"
+		os.Remove(oldPath)
+		if err := os.Rename(exePath, oldPath); err != nil {
+			os.Remove(newPath)
+			return fmt.Errorf("failed to move the current binary aside: %w", err)
+		}
+		if err := os.Rename(newPath, exePath); err != nil {
+			// Put the old binary back so the install keeps working
+			os.Rename(oldPath, exePath)
+			os.Remove(newPath)
+			return fmt.Errorf("failed to install the new binary: %w", err)
+		}
+		return nil
+	}
+
+	if err := os.Rename(newPath, exePath); err != nil {
+		os.Remove(newPath)
+		return fmt.Errorf("failed to install the new binary: %w", err)
+	}
+	return nil
+}
+
+// CleanupOld removes the binary a Windows update moved aside
+func CleanupOld(exePath string) {
+	os.Remove(exePath + ".old")
+}
+
+// Newer reports whether latest is a higher vMAJOR.MINOR.PATCH version than
+// current. Development builds, whose version isn't a release tag, are never
+// considered outdated.
+func Newer(current, latest string) bool {
+	c, ok := parseVersion(current)
+	if !ok {
+		return false
+	}
+	l, ok := parseVersion(latest)
+	if !ok {
+		return false
+	}
+	for i := range c {
+		if l[i] != c[i] {
+			return l[i] > c[i]
+		}
+	}
+	return false
+}
+
+// IsRelease reports whether version is a release tag rather than a
+// development build
+func IsRelease(version string) bool {
+	_, ok := parseVersion(version)
+	return ok
+}
+
+// parseVersion parses "v1.2.3" or "1.2.3"; pre-release and build suffixes
+// such as git describe's "-3-gabc123" make it unparseable
+func parseVersion(v string) ([3]int, bool) {
+	var parsed [3]int
+	parts := strings.Split(strings.TrimPrefix(v, "v"), ".")
+	if len(parts) != 3 {
+		return parsed, false
+	}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return parsed, false
+		}
+		parsed[i] = n
+	}
+	return parsed, true
+}
@@ -0,0 +1,185 @@
+package selfupdate
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func tarGz(t *testing.T, name string, content []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+		t.Fatal(err)
+	}
+	tw.Write(content)
+	tw.Close()
+	gz.Close()
+	return buf.Bytes()
+}
+
+func zipped(t *testing.T, name string, content []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(content)
+	zw.Close()
+	return buf.Bytes()
+}
+
+// releaseServer serves a release v1.2.0 with the given archives and a
+// checksums file; sums overrides individual checksums
+func releaseServer(t *testing.T, archives map[string][]byte, sums map[string]string) *Client {
+	t.Helper()
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	release := Release{TagName: "v1.2.0"}
+	var checksums strings.Builder
+	for name, data := range archives {
+		sum := sha256.Sum256(data)
+		hexSum := hex.EncodeToString(sum[:])
+		if override, ok := sums[name]; ok {
+			hexSum = override
+		}
+		checksums.WriteString(hexSum + "  " + name + "\n")
+		release.Assets = append(release.Assets, Asset{Name: name, URL: server.URL + "/download/" + name})
+		mux.HandleFunc("/download/"+name, func(w http.ResponseWriter, r *http.Request) { w.Write(data) })
+	}
+	release.Assets = append(release.Assets, Asset{Name: ChecksumsAsset, URL: server.URL + "/download/" + ChecksumsAsset})
+	mux.HandleFunc("/download/"+ChecksumsAsset, func(w http.ResponseWriter, r *http.Request) { w.Write([]byte(checksums.String())) })
+	mux.HandleFunc("/repos/"+Repo+"/releases/latest", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(release)
+	})
+	return &Client{HTTP: server.Client(), APIBase: server.URL}
+}
+
+func TestDownload(t *testing.T) {
+	binary := []byte("#!/bin/sh\necho new\n")
+	linux := AssetName("v1.2.0", "linux", "amd64")
+	windows := AssetName("v1.2.0", "windows", "arm64")
+	client := releaseServer(t, map[string][]byte{
+		linux:   tarGz(t, "fancy-login-go", binary),
+		windows: zipped(t, "fancy-login-go.exe", binary),
+	}, nil)
+
+	release, err := client.Latest(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if release.TagName != "v1.2.0" {
+		t.Errorf("Expected v1.2.0, got %s", release.TagName)
+	}
+
+	for _, platform := range [][2]string{{"linux", "amd64"}, {"windows", "arm64"}} {
+		got, err := client.Download(context.Background(), release, platform[0], platform[1])
+		if err != nil {
+			t.Fatalf("%s: %v", platform[0], err)
+		}
+		if !bytes.Equal(got, binary) {
+			t.Errorf("%s: expected the binary from the archive, got %q", platform[0], got)
+		}
+	}
+
+	if _, err := client.Download(context.Background(), release, "darwin", "arm64"); err == nil || !strings.Contains(err.Error(), "no asset") {
+		t.Errorf("Expected a missing asset error, got %v", err)
+	}
+}
+
+func TestDownloadChecksumMismatch(t *testing.T) {
+	name := AssetName("v1.2.0", "linux", "amd64")
+	client := releaseServer(t, map[string][]byte{name: tarGz(t, "fancy-login-go", []byte("tampered"))},
+		map[string]string{name: strings.Repeat("0", 64)})
+
+	release, err := client.Latest(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Download(context.Background(), release, "linux", "amd64"); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("Expected a checksum mismatch, got %v", err)
+	}
+
+	release.Assets = release.Assets[:1]
+	if _, err := client.Download(context.Background(), release, "linux", "amd64"); err == nil || !strings.Contains(err.Error(), ChecksumsAsset) {
+		t.Errorf("Expected a release without checksums to be refused, got %v", err)
+	}
+}
+
+func TestReplace(t *testing.T) {
+	for _, goos := range []string{"linux", "windows"} {
+		t.Run(goos, func(t *testing.T) {
+			exePath := filepath.Join(t.TempDir(), "fancy-login-go")
+			if err := os.WriteFile(exePath, []byte("old"), 0755); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := Replace(exePath, []byte("new"), goos); err != nil {
+				t.Fatal(err)
+			}
+
+			data, err := os.ReadFile(exePath)
+			if err != nil || string(data) != "new" {
+				t.Errorf("Expected the new binary, got %q (%v)", data, err)
+			}
+			if info, err := os.Stat(exePath); err != nil || info.Mode().Perm() != 0755 {
+				t.Errorf("Expected the mode to be kept, got %v (%v)", info.Mode(), err)
+			}
+			if _, err := os.Stat(exePath + ".new"); !os.IsNotExist(err) {
+				t.Error("Expected no leftover .new file")
+			}
+			CleanupOld(exePath)
+			if _, err := os.Stat(exePath + ".old"); !os.IsNotExist(err) {
+				t.Error("Expected CleanupOld to remove the old binary")
+			}
+		})
+	}
+}
+
+func TestNewer(t *testing.T) {
+	testCases := []struct {
+		current, latest string
+		expected        bool
+	}{
+		{"v1.2.0", "v1.2.1", true},
+		{"v1.2.0", "v1.10.0", true},
+		{"1.2.0", "v2.0.0", true},
+		{"v1.2.0", "v1.2.0", false},
+		{"v1.3.0", "v1.2.9", false},
+		{"dev", "v9.9.9", false},
+		{"v1.2.0-3-gabc123", "v1.3.0", false},
+		{"v1.2.0", "nightly", false},
+	}
+
+	for _, tc := range testCases {
+		if got := Newer(tc.current, tc.latest); got != tc.expected {
+			t.Errorf("Newer(%q, %q) = %v, expected %v", tc.current, tc.latest, got, tc.expected)
+		}
+	}
+}
+
+func TestAssetName(t *testing.T) {
+	if got := AssetName("v1.2.0", "darwin", "arm64"); got != "fancy-login-go-v1.2.0-darwin-arm64.tar.gz" {
+		t.Errorf("Unexpected darwin asset %s", got)
+	}
+	if got := AssetName("v1.2.0", "windows", "amd64"); got != "fancy-login-go-v1.2.0-windows-amd64.exe.zip" {
+		t.Errorf("Unexpected windows asset %s", got)
+	}
+}
@@ -0,0 +1,366 @@
+package state
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"fancy-login/internal/config"
+)
+
+// FzfProbe caches the result of `fzf --version`, keyed by the binary's path and mtime
+type FzfProbe struct {
+	Path    string `json:"path"`
+	ModTime int64  `json:"mod_time"`
+	Version string `json:"version"`
+}
+
+// SessionRecord is the last observed session status of an AWS profile
+type SessionRecord struct {
+	Status    string    `json:"status"`
+	Account   string    `json:"account,omitempty"`
+	Arn       string    `json:"arn,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// K9sSession is a k9s process launched by fancy-login
+type K9sSession struct {
+	PID       int       `json:"pid"`
+	Profile   string    `json:"profile"`
+	Context   string    `json:"context,omitempty"`
+	Namespace string    `json:"namespace,omitempty"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+// ECR login statuses
+const (
+	ECRPending = "pending"
+	ECROK      = "ok"
+	ECRFailed  = "failed"
+)
+
+// ECRLoginRecord is the outcome of a profile's last ECR login
+type ECRLoginRecord struct {
+	Status    string    `json:"status"`
+	Registry  string    `json:"registry,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// GitIdentityChange records the repository git config fancy-login changed,
+// so `undo` can restore it
+type GitIdentityChange struct {
+	Repo    string `json:"repo"`
+	Profile string `json:"profile"`
+	// Changed lists the keys that were set, e.g. user.email
+	Changed []string `json:"changed"`
+	// Previous holds the old value of each changed key; keys that were
+	// unset before are absent
+	Previous  map[string]string `json:"previous,omitempty"`
+	ChangedAt time.Time         `json:"changed_at"`
+}
+
+// UpdateCheck is the result of the last check for a newer release
+type UpdateCheck struct {
+	Latest    string    `json:"latest"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// LastLogin is the profile, and the context it ended up in, of the last
+// completed login, for --last and the picker
+type LastLogin struct {
+	Profile string    `json:"profile"`
+	Context string    `json:"context,omitempty"`
+	At      time.Time `json:"at"`
+}
+
+// ProfileUsage counts the logins to a profile, for the picker's recent
+// and frequent sorts
+type ProfileUsage struct {
+	LastUsed time.Time `json:"last_used"`
+	Count    int       `json:"count"`
+}
+
+// RunTotals are a profile's running totals for the metrics textfile. They
+// only ever grow, so they can be exported as counters.
+type RunTotals struct {
+	Environment string `json:"environment,omitempty"`
+	Logins      int    `json:"logins"`
+	Failures    int    `json:"failures"`
+	// SessionCacheHits counts runs that found a valid session and needed
+	// no login
+	SessionCacheHits int `json:"session_cache_hits"`
+	// ECRCacheHits counts registries whose fresh docker token was reused
+	ECRCacheHits int `json:"ecr_cache_hits"`
+}
+
+// State is fancy-login's persisted per-user runtime state
+type State struct {
+	Fzf      *FzfProbe                 `json:"fzf,omitempty"`
+	Sessions map[string]*SessionRecord `json:"sessions,omitempty"`
+	// MetadataRefreshedAt rate-limits the account metadata refresh per profile
+	MetadataRefreshedAt map[string]time.Time `json:"metadata_refreshed_at,omitempty"`
+	K9sSessions         []K9sSession         `json:"k9s_sessions,omitempty"`
+	// ECRLogins holds the last ECR login outcome per profile
+	ECRLogins map[string]*ECRLoginRecord `json:"ecr_logins,omitempty"`
+	// ECRTokens holds when docker last logged in to each ECR registry
+	// host, so a fresh token can be reused
+	ECRTokens map[string]time.Time `json:"ecr_tokens,omitempty"`
+	// GitIdentityChanges are undoable git identity changes, oldest first
+	GitIdentityChanges []GitIdentityChange `json:"git_identity_changes,omitempty"`
+	// UpdateCheck lets `version` mention a newer release without asking GitHub
+	UpdateCheck *UpdateCheck `json:"update_check,omitempty"`
+	// LastLogin is what `--last` logs in to again
+	LastLogin *LastLogin `json:"last_login,omitempty"`
+	// RecentLogins are the last logins of distinct profiles, most recent
+	// first, for `switch`
+	RecentLogins []LastLogin `json:"recent_logins,omitempty"`
+	// ProfileUsage holds when each profile was last logged in to and how
+	// often
+	ProfileUsage map[string]*ProfileUsage `json:"profile_usage,omitempty"`
+	// RunTotals holds the metrics counters of each profile, keyed by
+	// profile name
+	RunTotals map[string]*RunTotals `json:"run_totals,omitempty"`
+}
+
+// MaxRecentLogins is how many profiles RecentLogins remembers
+const MaxRecentLogins = 10
+
+// Path returns the location of the state file
+func Path() string {
+	return filepath.Join(config.GetStateDir(), "state.json")
+}
+
+// Load reads the state file, returning empty state if it doesn't exist yet
+func Load() (*State, error) {
+	data, err := os.ReadFile(Path())
+	if os.IsNotExist(err) {
+		return &State{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file %s: %w", Path(), err)
+	}
+
+	var s State
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse state file %s: %w", Path(), err)
+	}
+	return &s, nil
+}
+
+// Save writes the state file atomically. Each write goes through its own
+// temporary file, so concurrent runs never rename each other's partial
+// writes into place.
+func (s *State) Save() error {
+	if err := os.MkdirAll(filepath.Dir(Path()), 0700); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal state: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(Path()), ".state-*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	return os.Rename(tmp.Name(), Path())
+}
+
+// updateMu serializes Update, since background work such as the ECR login
+// may write the state while the main flow does
+var updateMu sync.Mutex
+
+// Update loads the state, applies fn and saves the result. If fn returns an
+// error nothing is saved and that error is returned. Concurrent Updates never
+// lose each other's changes: within the process they are serialized by a
+// mutex, across processes by a lock file.
+func Update(fn func(s *State) error) error {
+	updateMu.Lock()
+	defer updateMu.Unlock()
+
+	unlock, err := lockFile(Path() + ".lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	s, err := Load()
+	if err != nil {
+		return err
+	}
+	if err := fn(s); err != nil {
+		return err
+	}
+	return s.Save()
+}
+
+// Lock file timing: how long Update waits for another run to finish, and
+// after how long a lock is considered left behind by a crashed run
+const (
+	lockWait  = 5 * time.Second
+	lockStale = 30 * time.Second
+)
+
+// lockFile takes the lock at path by creating it exclusively, waiting
+// while another process holds it. A lock older than lockStale is taken
+// over. The returned function releases the lock.
+func lockFile(path string) (func(), error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create state directory: %w", err)
+	}
+	deadline := time.Now().Add(lockWait)
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("failed to lock state file: %w", err)
+		}
+		if lockIsStale(path) && removeStaleLock(path) {
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("state file is locked by another fancy-login run; remove %s if none is running", path)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// lockIsStale reports whether the lock at path was left behind by a run that
+// no longer holds it
+func lockIsStale(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && time.Since(info.ModTime()) > lockStale
+}
+
+// removeStaleLock removes a stale lock so it can be taken over. Two runs
+// seeing the same stale lock must not both remove it: the second would
+// delete the lock the first just took. The removal is therefore guarded by
+// a takeover lock, and staleness is checked again while holding it. It
+// reports whether the lock is gone.
+func removeStaleLock(path string) bool {
+	guard := path + ".takeover"
+	f, err := os.OpenFile(guard, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		// A takeover lock is only held for a stat and a remove, so one
+		// this old belongs to a run that crashed in between
+		if lockIsStale(guard) {
+			os.Remove(guard)
+		}
+		return false
+	}
+	f.Close()
+	defer os.Remove(guard)
+
+	if !lockIsStale(path) {
+		return false
+	}
+	err = os.Remove(path)
+	return err == nil || errors.Is(err, os.ErrNotExist)
+}
+
+// RecordSession stores the observed session status of a profile
+func (s *State) RecordSession(profile string, record SessionRecord) {
+	if s.Sessions == nil {
+		s.Sessions = make(map[string]*SessionRecord)
+	}
+	s.Sessions[profile] = &record
+}
+
+// RecordECRLogin stores the ECR login outcome of a profile
+func (s *State) RecordECRLogin(profile string, record ECRLoginRecord) {
+	if s.ECRLogins == nil {
+		s.ECRLogins = make(map[string]*ECRLoginRecord)
+	}
+	s.ECRLogins[profile] = &record
+}
+
+// RecordECRToken stores when docker logged in to an ECR registry host
+func (s *State) RecordECRToken(host string, at time.Time) {
+	if s.ECRTokens == nil {
+		s.ECRTokens = make(map[string]time.Time)
+	}
+	s.ECRTokens[host] = at
+}
+
+// MarkMetadataRefreshed records when a profile's metadata was last refreshed
+func (s *State) MarkMetadataRefreshed(profile string, at time.Time) {
+	if s.MetadataRefreshedAt == nil {
+		s.MetadataRefreshedAt = make(map[string]time.Time)
+	}
+	s.MetadataRefreshedAt[profile] = at
+}
+
+// AddRecentLogin puts login at the front of RecentLogins, dropping an
+// older entry of the same profile and anything beyond MaxRecentLogins
+func (s *State) AddRecentLogin(login LastLogin) {
+	recent := []LastLogin{login}
+	for _, entry := range s.RecentLogins {
+		if entry.Profile != login.Profile && len(recent) < MaxRecentLogins {
+			recent = append(recent, entry)
+		}
+	}
+	s.RecentLogins = recent
+}
+
+// RecordProfileUse counts a login to profile at the given time
+func (s *State) RecordProfileUse(profile string, at time.Time) {
+	if s.ProfileUsage == nil {
+		s.ProfileUsage = make(map[string]*ProfileUsage)
+	}
+	usage := s.ProfileUsage[profile]
+	if usage == nil {
+		usage = &ProfileUsage{}
+		s.ProfileUsage[profile] = usage
+	}
+	usage.LastUsed = at
+	usage.Count++
+}
+
+// AddK9sSession records a running k9s, replacing any entry with the same pid
+func (s *State) AddK9sSession(session K9sSession) {
+	s.RemoveK9sSession(session.PID)
+	s.K9sSessions = append(s.K9sSessions, session)
+}
+
+// RemoveK9sSession forgets the k9s session with the given pid
+func (s *State) RemoveK9sSession(pid int) {
+	kept := s.K9sSessions[:0]
+	for _, session := range s.K9sSessions {
+		if session.PID != pid {
+			kept = append(kept, session)
+		}
+	}
+	s.K9sSessions = kept
+}
+
+// PruneK9sSessions drops sessions whose process is no longer alive, such as
+// those left behind by a crash, and returns how many were removed
+func (s *State) PruneK9sSessions(alive func(pid int) bool) int {
+	kept := s.K9sSessions[:0]
+	for _, session := range s.K9sSessions {
+		if alive(session.PID) {
+			kept = append(kept, session)
+		}
+	}
+	removed := len(s.K9sSessions) - len(kept)
+	s.K9sSessions = kept
+	return removed
+}
@@ -0,0 +1,213 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// updateProcessEnv makes the test binary act as one of several processes
+// updating the state at once; its value is the number of updates to make
+const updateProcessEnv = "FANCY_STATE_TEST_UPDATES"
+
+// countUpdate adds one login to the dev profile
+func countUpdate() error {
+	return Update(func(s *State) error {
+		s.RecordProfileUse("dev", time.Now())
+		return nil
+	})
+}
+
+// loadCount returns the logins counted for the dev profile
+func loadCount(t *testing.T) int {
+	t.Helper()
+	s, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.ProfileUsage["dev"] == nil {
+		return 0
+	}
+	return s.ProfileUsage["dev"].Count
+}
+
+func TestUpdateConcurrentGoroutines(t *testing.T) {
+	t.Setenv("FANCY_STATE_DIR", t.TempDir())
+
+	const workers, updates = 8, 20
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*updates)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < updates; j++ {
+				if err := countUpdate(); err != nil {
+					errs <- err
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	if got := loadCount(t); got != workers*updates {
+		t.Errorf("Expected %d logins, got %d", workers*updates, got)
+	}
+}
+
+// TestUpdateProcess is run by TestUpdateConcurrentProcesses in child
+// processes; on its own it does nothing
+func TestUpdateProcess(t *testing.T) {
+	n, err := strconv.Atoi(os.Getenv(updateProcessEnv))
+	if err != nil {
+		t.Skip("only runs as a child of TestUpdateConcurrentProcesses")
+	}
+	for i := 0; i < n; i++ {
+		if err := countUpdate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestUpdateConcurrentProcesses(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("FANCY_STATE_DIR", dir)
+	binary, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const processes, updates = 4, 25
+	cmds := make([]*exec.Cmd, processes)
+	for i := range cmds {
+		cmds[i] = exec.Command(binary, "-test.run=^TestUpdateProcess$")
+		cmds[i].Env = append(os.Environ(), fmt.Sprintf("%s=%d", updateProcessEnv, updates))
+		if err := cmds[i].Start(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, cmd := range cmds {
+		if err := cmd.Wait(); err != nil {
+			t.Errorf("update process failed: %v", err)
+		}
+	}
+
+	if got := loadCount(t); got != processes*updates {
+		t.Errorf("Expected %d logins, got %d", processes*updates, got)
+	}
+	if _, err := os.Stat(Path() + ".lock"); !os.IsNotExist(err) {
+		t.Errorf("Expected the lock to be released, stat returned %v", err)
+	}
+}
+
+func TestUpdateTakesOverStaleLock(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("FANCY_STATE_DIR", dir)
+	lock := Path() + ".lock"
+	if err := os.WriteFile(lock, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-2 * lockStale)
+	if err := os.Chtimes(lock, old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	if err := countUpdate(); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed >= lockWait {
+		t.Errorf("Expected the stale lock to be taken over right away, took %v", elapsed)
+	}
+	if got := loadCount(t); got != 1 {
+		t.Errorf("Expected 1 login, got %d", got)
+	}
+	for _, path := range []string{lock, lock + ".takeover"} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("Expected %s to be gone, stat returned %v", filepath.Base(path), err)
+		}
+	}
+}
+
+func TestUpdateWaitsForFreshLock(t *testing.T) {
+	t.Setenv("FANCY_STATE_DIR", t.TempDir())
+	lock := Path() + ".lock"
+	if err := os.WriteFile(lock, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// The run holding the lock finishes a little later
+	const held = 200 * time.Millisecond
+	released := make(chan time.Time, 1)
+	go func() {
+		time.Sleep(held)
+		released <- time.Now()
+		os.Remove(lock)
+	}()
+
+	var ranAt time.Time
+	err := Update(func(s *State) error {
+		ranAt = time.Now()
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if releasedAt := <-released; ranAt.Before(releasedAt) {
+		t.Errorf("Update ran %v before the fresh lock was released", releasedAt.Sub(ranAt))
+	}
+}
+
+func TestRemoveStaleLock(t *testing.T) {
+	testCases := []struct {
+		name string
+		// lockAge and guardAge are the ages of the lock and the takeover
+		// guard; a negative age means the file doesn't exist
+		lockAge, guardAge time.Duration
+		expectedRemoved   bool
+		expectedLock      bool
+		expectedGuard     bool
+	}{
+		{"Stale lock", 2 * lockStale, -1, true, false, false},
+		{"Fresh lock", time.Second, -1, false, true, false},
+		{"Takeover in progress", 2 * lockStale, time.Second, false, true, true},
+		{"Takeover guard left behind", 2 * lockStale, 2 * lockStale, false, true, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			lock := filepath.Join(t.TempDir(), "state.json.lock")
+			for path, age := range map[string]time.Duration{lock: tc.lockAge, lock + ".takeover": tc.guardAge} {
+				if age < 0 {
+					continue
+				}
+				if err := os.WriteFile(path, nil, 0600); err != nil {
+					t.Fatal(err)
+				}
+				at := time.Now().Add(-age)
+				if err := os.Chtimes(path, at, at); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if removed := removeStaleLock(lock); removed != tc.expectedRemoved {
+				t.Errorf("Expected removeStaleLock to return %v, got %v", tc.expectedRemoved, removed)
+			}
+			if _, err := os.Stat(lock); (err == nil) != tc.expectedLock {
+				t.Errorf("Expected lock to exist: %v, stat returned %v", tc.expectedLock, err)
+			}
+			if _, err := os.Stat(lock + ".takeover"); (err == nil) != tc.expectedGuard {
+				t.Errorf("Expected takeover guard to exist: %v, stat returned %v", tc.expectedGuard, err)
+			}
+		})
+	}
+}
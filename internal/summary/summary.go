@@ -0,0 +1,480 @@
+package summary
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"fancy-login/internal/a11y"
+	"fancy-login/internal/config"
+)
+
+// Summary is the outcome of a login run, shown once the login finished
+type Summary struct {
+	Profile string
+	// ProfileSource is where the profile came from: flag, query,
+	// AWS_PROFILE, AWS_DEFAULT_PROFILE, last or interactive
+	ProfileSource string
+	KubeOnly      bool
+	// ContextLine is the Kubernetes line as formatted by the k8s manager
+	ContextLine string
+	// Context is the Kubernetes context in effect, if any
+	Context string
+	// KubernetesSkipped is set when --no-k8s left the context untouched
+	KubernetesSkipped bool
+	ECRAttempted      bool
+	ECRSucceeded      bool
+	AccountID         string
+	AccountAlias      string
+	Timeouts          []string
+	// Region is the AWS profile's region; Namespace the one in effect
+	Region    string
+	Namespace string
+	// SSOLoginPerformed is set when the run had to log in via SSO
+	SSOLoginPerformed bool
+	// Duration is how long the login took up to the summary
+	Duration time.Duration
+	// ECRRegion is the region of the ECR login and ECRRegionSource where it
+	// came from: flag, config, env or default
+	ECRRegion       string
+	ECRRegionSource string
+	// ECRRegistries is the outcome per registry when the login covered
+	// more than one
+	ECRRegistries []ECRRegistry
+	// ECRCached is set when the ECR login was skipped for fresh tokens
+	ECRCached bool
+	// SwitchedFrom is the profile `switch` swapped away from
+	SwitchedFrom string
+	// SessionExpiresAt is when the cached SSO token expires, zero if unknown
+	SessionExpiresAt time.Time
+	// AssumedRoleARN is the role a role_arn/source_profile profile assumed
+	AssumedRoleARN string
+	// CredentialsExported is set when plaintext credentials were written to
+	// the shell export file; CredentialsExpireAt is zero if they don't expire
+	CredentialsExported bool
+	CredentialsExpireAt time.Time
+	// K9sNotInstalled is set when k9s should launch but isn't on PATH
+	K9sNotInstalled bool
+}
+
+// ECRRegistry is the outcome of the login to one ECR registry
+type ECRRegistry struct {
+	// Host is empty for a private registry whose account wasn't known
+	Host string
+	// Error is empty if the login succeeded
+	Error string
+	// Cached is set when a fresh earlier token was reused
+	Cached bool
+}
+
+// name returns the registry host, or what it stands for if unknown
+func (r ECRRegistry) name() string {
+	if r.Host == "" {
+		return "private registry"
+	}
+	return r.Host
+}
+
+// Sink is a recipient of the summary
+type Sink interface {
+	// Name identifies the sink in warnings
+	Name() string
+	Write(s *Summary) error
+}
+
+// ParseSinks builds sinks from `summary_sinks` entries: "terminal",
+// "file:PATH" and "notify". An empty list means the terminal only.
+func ParseSinks(specs []string) ([]Sink, error) {
+	if len(specs) == 0 {
+		return []Sink{&TerminalSink{Out: os.Stdout}}, nil
+	}
+
+	var sinks []Sink
+	for _, spec := range specs {
+		kind, arg, _ := strings.Cut(spec, ":")
+		switch kind {
+		case "terminal":
+			sinks = append(sinks, &TerminalSink{Out: os.Stdout})
+		case "notify":
+			sinks = append(sinks, &NotifySink{Out: os.Stdout})
+		case "file":
+			if arg == "" {
+				return nil, fmt.Errorf("summary sink %q needs a path, e.g. file:/home/me/standup-{date}.log", spec)
+			}
+			sinks = append(sinks, &FileSink{Path: arg, now: time.Now})
+		default:
+			return nil, fmt.Errorf("unknown summary sink %q (use terminal, file:PATH or notify)", spec)
+		}
+	}
+	return sinks, nil
+}
+
+// Deliver writes the summary to every sink. A failing sink produces one
+// warning and never keeps the others from receiving the summary.
+func Deliver(s *Summary, sinks []Sink, warn func(string)) {
+	for _, sink := range sinks {
+		if err := sink.Write(s); err != nil {
+			warn(fmt.Sprintf("Summary %s failed: %v", sink.Name(), err))
+		}
+	}
+}
+
+// TerminalSink prints the colored summary box, as plain lines without
+// icons or borders in screen reader mode
+type TerminalSink struct {
+	Out io.Writer
+}
+
+func (t *TerminalSink) Name() string { return "terminal" }
+
+func (t *TerminalSink) Write(s *Summary) error {
+	_, err := io.WriteString(a11y.Writer(t.Out), RenderTerminal(s))
+	return err
+}
+
+// FileSink appends a plain-text summary with a timestamp header. "{date}" in
+// the path is replaced with the current date, giving one file per day.
+type FileSink struct {
+	Path string
+
+	now func() time.Time
+}
+
+func (f *FileSink) Name() string { return "file " + f.Path }
+
+func (f *FileSink) Write(s *Summary) error {
+	now := f.now()
+	path := expandPath(f.Path, now)
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = io.WriteString(file, RenderPlain(s, now))
+	return err
+}
+
+// NotifySink emits a one-line desktop notification via OSC 9, which
+// iTerm2, Windows Terminal, kitty and others surface as a notification
+type NotifySink struct {
+	Out io.Writer
+}
+
+func (n *NotifySink) Name() string { return "notify" }
+
+func (n *NotifySink) Write(s *Summary) error {
+	_, err := fmt.Fprintf(n.Out, "\033]9;%s\007", RenderCompact(s))
+	return err
+}
+
+// JSONSink writes the summary as a single JSON object, for tooling
+type JSONSink struct {
+	Out io.Writer
+}
+
+func (j *JSONSink) Name() string { return "json" }
+
+func (j *JSONSink) Write(s *Summary) error {
+	data, err := json.Marshal(s.Report())
+	if err != nil {
+		return err
+	}
+	_, err = j.Out.Write(append(data, '\n'))
+	return err
+}
+
+// ECR login outcomes in a Report
+const (
+	ECRLoginSuccess = "success"
+	ECRLoginFailed  = "failed"
+	ECRLoginSkipped = "skipped"
+	ECRLoginCached  = "cached"
+)
+
+// Report is the machine-readable summary. Every field is always present;
+// values that don't apply, e.g. the account of a kube-only profile, are
+// empty.
+type Report struct {
+	Profile           string `json:"profile"`
+	ProfileSource     string `json:"profile_source"`
+	AccountID         string `json:"account_id"`
+	Region            string `json:"region"`
+	K8sContext        string `json:"k8s_context"`
+	Namespace         string `json:"namespace"`
+	ECRLogin          string `json:"ecr_login"`
+	ECRRegion         string `json:"ecr_region"`
+	ECRRegionSource   string `json:"ecr_region_source"`
+	SSOLoginPerformed bool   `json:"sso_login_performed"`
+	// SessionExpiresAt is RFC 3339, empty if unknown
+	SessionExpiresAt string `json:"session_expires_at"`
+	DurationMS       int64  `json:"duration_ms"`
+}
+
+// Report converts the summary into its machine-readable form
+func (s *Summary) Report() Report {
+	r := Report{
+		Profile:           s.Profile,
+		ProfileSource:     s.ProfileSource,
+		AccountID:         s.AccountID,
+		Region:            s.Region,
+		ECRLogin:          ECRLoginSkipped,
+		SSOLoginPerformed: s.SSOLoginPerformed,
+		DurationMS:        s.Duration.Milliseconds(),
+	}
+	if !s.KubernetesSkipped {
+		r.K8sContext = s.Context
+		r.Namespace = s.Namespace
+	}
+	if !s.SessionExpiresAt.IsZero() {
+		r.SessionExpiresAt = s.SessionExpiresAt.UTC().Format(time.RFC3339)
+	}
+	if s.ECRAttempted {
+		r.ECRLogin = ECRLoginFailed
+		if s.ECRSucceeded {
+			r.ECRLogin = ECRLoginSuccess
+		}
+		if s.ECRCached {
+			r.ECRLogin = ECRLoginCached
+		}
+		r.ECRRegion = s.ECRRegion
+		r.ECRRegionSource = s.ECRRegionSource
+	}
+	return r
+}
+
+// RenderTerminal renders the summary box in the active theme
+func RenderTerminal(s *Summary) string {
+	var b strings.Builder
+	b.WriteString("\n")
+	fmt.Fprintf(&b, "%s🦄  %sFancy Login Summary%s\n", config.Heading, config.Bold, config.Reset)
+	fmt.Fprintf(&b, "%s───────────────────────────────────────────────%s\n", config.Muted, config.Reset)
+	if s.KubeOnly {
+		fmt.Fprintf(&b, "%s⎈  Kube-only Profile:%s %s%s%s\n", config.Heading, config.Reset, config.Bold, s.Profile, config.Reset)
+		fmt.Fprintf(&b, "%s🔑 AWS: no AWS login performed%s\n", config.Muted, config.Reset)
+	} else {
+		fmt.Fprintf(&b, "%s🔑 AWS Profile:%s %s%s%s\n", config.Heading, config.Reset, config.Bold, s.Profile, config.Reset)
+	}
+	if s.SwitchedFrom != "" {
+		fmt.Fprintf(&b, "%s🔀 Switched: %s → %s%s\n", config.Muted, s.SwitchedFrom, s.Profile, config.Reset)
+	}
+	if s.KubernetesSkipped {
+		fmt.Fprintf(&b, "%s🌱 Kubernetes: skipped%s\n", config.Muted, config.Reset)
+	} else if s.ContextLine != "" {
+		b.WriteString(s.ContextLine + "\n")
+	}
+	if s.K9sNotInstalled {
+		fmt.Fprintf(&b, "%s🐶 k9s: not installed%s\n", config.Warning, config.Reset)
+	}
+	if s.ECRAttempted {
+		switch {
+		case s.ECRCached:
+			fmt.Fprintf(&b, "%s🐳 ECR login: cached%s%s\n", config.Success, s.ecrRegion(), config.Reset)
+		case s.ECRSucceeded:
+			fmt.Fprintf(&b, "%s🐳 ECR login: successful%s%s\n", config.Success, s.ecrRegion(), config.Reset)
+		default:
+			fmt.Fprintf(&b, "%s🐳 ECR login: failed%s%s\n", config.Error, s.ecrRegion(), config.Reset)
+		}
+		for _, r := range s.registries() {
+			switch {
+			case r.Cached:
+				fmt.Fprintf(&b, "%s   %s: cached%s\n", config.Success, r.name(), config.Reset)
+			case r.Error == "":
+				fmt.Fprintf(&b, "%s   %s: successful%s\n", config.Success, r.name(), config.Reset)
+			default:
+				fmt.Fprintf(&b, "%s   %s: failed (%s)%s\n", config.Error, r.name(), r.Error, config.Reset)
+			}
+		}
+	}
+	if account := s.account(); account != "" {
+		fmt.Fprintf(&b, "%s☁️  AWS Account:%s %s%s%s\n", config.Accent, config.Reset, config.Bold, account, config.Reset)
+	}
+	if s.Region != "" && !s.KubeOnly {
+		fmt.Fprintf(&b, "%s🌍 Region:%s %s\n", config.Accent, config.Reset, s.Region)
+	}
+	if s.AssumedRoleARN != "" {
+		fmt.Fprintf(&b, "%s🎭 Assumed Role:%s %s\n", config.Accent, config.Reset, s.AssumedRoleARN)
+	}
+	if !s.SessionExpiresAt.IsZero() {
+		b.WriteString(s.sessionLine(time.Now()))
+	}
+	if s.CredentialsExported {
+		fmt.Fprintf(&b, "%s⚠️  Plaintext credentials exported to the shell, %s%s\n", config.Warning, s.credentialsExpiry(), config.Reset)
+	}
+	for _, t := range s.Timeouts {
+		fmt.Fprintf(&b, "%s⏱  %s%s\n", config.Error, t, config.Reset)
+	}
+	fmt.Fprintf(&b, "%s───────────────────────────────────────────────%s\n", config.Muted, config.Reset)
+	b.WriteString("\n")
+	return b.String()
+}
+
+// RenderPlain renders the summary without colors or decoration, under a
+// "## <RFC 3339 timestamp>" header so appended entries stay parseable
+func RenderPlain(s *Summary, now time.Time) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## %s\n", now.Format(time.RFC3339))
+	if s.KubeOnly {
+		fmt.Fprintf(&b, "profile: %s (kube-only, no AWS login)\n", s.Profile)
+	} else {
+		fmt.Fprintf(&b, "profile: %s\n", s.Profile)
+	}
+	if s.SwitchedFrom != "" {
+		fmt.Fprintf(&b, "switched: %s -> %s\n", s.SwitchedFrom, s.Profile)
+	}
+	if s.KubernetesSkipped {
+		b.WriteString("kubernetes: skipped\n")
+	} else if s.ContextLine != "" {
+		fmt.Fprintf(&b, "kubernetes: %s\n", strings.TrimSpace(strings.TrimPrefix(plainContextLine(s.ContextLine), "🌱 Kubernetes Context:")))
+	}
+	if s.K9sNotInstalled {
+		b.WriteString("k9s: not installed\n")
+	}
+	if s.ECRAttempted {
+		fmt.Fprintf(&b, "ecr: %s%s\n", s.ecrStatus(), s.ecrRegion())
+		for _, r := range s.registries() {
+			switch {
+			case r.Cached:
+				fmt.Fprintf(&b, "ecr registry: %s cached\n", r.name())
+			case r.Error == "":
+				fmt.Fprintf(&b, "ecr registry: %s ok\n", r.name())
+			default:
+				fmt.Fprintf(&b, "ecr registry: %s failed: %s\n", r.name(), r.Error)
+			}
+		}
+	}
+	if account := s.account(); account != "" {
+		fmt.Fprintf(&b, "account: %s\n", account)
+	}
+	if s.Region != "" && !s.KubeOnly {
+		fmt.Fprintf(&b, "region: %s\n", s.Region)
+	}
+	if s.AssumedRoleARN != "" {
+		fmt.Fprintf(&b, "assumed role: %s\n", s.AssumedRoleARN)
+	}
+	if !s.SessionExpiresAt.IsZero() {
+		fmt.Fprintf(&b, "session expires: %s\n", s.SessionExpiresAt.Format(time.RFC3339))
+	}
+	if s.CredentialsExported {
+		fmt.Fprintf(&b, "credentials exported: %s\n", s.credentialsExpiry())
+	}
+	for _, t := range s.Timeouts {
+		fmt.Fprintf(&b, "timeout: %s\n", t)
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// credentialsExpiry tells when exported credentials stop working
+func (s *Summary) credentialsExpiry() string {
+	if s.CredentialsExpireAt.IsZero() {
+		return "they don't expire"
+	}
+	return "valid until " + s.CredentialsExpireAt.Local().Format("15:04")
+}
+
+// RenderCompact renders the summary as a single short line
+func RenderCompact(s *Summary) string {
+	parts := []string{"fancy-login: " + s.Profile}
+	if s.Context != "" {
+		parts = append(parts, "⎈ "+s.Context)
+	}
+	if s.ECRAttempted {
+		parts = append(parts, "ECR "+s.ecrStatus())
+	}
+	if len(s.Timeouts) > 0 {
+		parts = append(parts, fmt.Sprintf("%d timed out", len(s.Timeouts)))
+	}
+	return strings.Join(parts, " · ")
+}
+
+// Remaining session lifetimes below which the summary warns
+const (
+	sessionWarnLeft     = 30 * time.Minute
+	sessionCriticalLeft = 5 * time.Minute
+)
+
+// sessionLine describes how long the SSO session lasts, in yellow when it
+// ends within half an hour and in red, suggesting a fresh login, within
+// five minutes
+func (s *Summary) sessionLine(now time.Time) string {
+	left := s.SessionExpiresAt.Sub(now)
+	color, hint := config.Muted, ""
+	switch {
+	case left < sessionCriticalLeft:
+		color, hint = config.Error, "; renew it with --force-aws-login"
+	case left < sessionWarnLeft:
+		color = config.Warning
+	}
+	return fmt.Sprintf("%s⏳ Session valid until %s (%s)%s%s\n",
+		color, s.SessionExpiresAt.Local().Format("15:04"), formatLeft(left), hint, config.Reset)
+}
+
+// formatLeft renders a remaining lifetime, e.g. "6h 12m left"
+func formatLeft(left time.Duration) string {
+	switch {
+	case left <= 0:
+		return "expired"
+	case left < time.Hour:
+		return fmt.Sprintf("%dm left", int(left.Minutes()))
+	}
+	return fmt.Sprintf("%dh %dm left", int(left.Hours()), int(left.Minutes())%60)
+}
+
+// account returns the account ID with its alias, if known
+func (s *Summary) account() string {
+	if s.AccountID != "" && s.AccountAlias != "" {
+		return fmt.Sprintf("%s (%s)", s.AccountID, s.AccountAlias)
+	}
+	return s.AccountID
+}
+
+// ecrRegion describes the ECR region and its source, e.g.
+// " (eu-west-1 from flag)", or "" if unknown
+func (s *Summary) ecrRegion() string {
+	switch {
+	case s.ECRRegion == "":
+		return ""
+	case s.ECRRegionSource == "":
+		return fmt.Sprintf(" (%s)", s.ECRRegion)
+	}
+	return fmt.Sprintf(" (%s from %s)", s.ECRRegion, s.ECRRegionSource)
+}
+
+// registries returns the per-registry outcomes worth listing: none when
+// the login covered a single registry, which the ECR line already describes
+func (s *Summary) registries() []ECRRegistry {
+	if len(s.ECRRegistries) < 2 {
+		return nil
+	}
+	return s.ECRRegistries
+}
+
+// ecrStatus describes the ECR login result in plain words
+func (s *Summary) ecrStatus() string {
+	switch {
+	case s.ECRCached:
+		return "cached"
+	case s.ECRSucceeded:
+		return "ok"
+	}
+	return "failed"
+}
+
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// plainContextLine strips theme colors from a preformatted line
+func plainContextLine(line string) string {
+	return ansiEscape.ReplaceAllString(line, "")
+}
+
+// expandPath substitutes {date} in a file sink path
+func expandPath(path string, now time.Time) string {
+	return filepath.Clean(strings.ReplaceAll(path, "{date}", now.Format("2006-01-02")))
+}
@@ -0,0 +1,393 @@
+package summary
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"fancy-login/internal/config"
+)
+
+func testSummary() *Summary {
+	return &Summary{
+		Profile:      "dev",
+		ContextLine:  config.Success + "🌱 Kubernetes Context:" + config.Reset + " " + config.Bold + "dev-cluster" + config.Reset,
+		Context:      "dev-cluster",
+		ECRAttempted: true,
+		ECRSucceeded: true,
+		AccountID:    "123456789012",
+		AccountAlias: "acme-dev",
+	}
+}
+
+func TestParseSinks(t *testing.T) {
+	testCases := []struct {
+		name        string
+		specs       []string
+		expected    []string
+		expectError bool
+	}{
+		{"Default is terminal", nil, []string{"terminal"}, false},
+		{"All kinds", []string{"terminal", "file:/tmp/standup.log", "notify"}, []string{"terminal", "file /tmp/standup.log", "notify"}, false},
+		{"File without path", []string{"file:"}, nil, true},
+		{"Unknown kind", []string{"email:me@example.com"}, nil, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sinks, err := ParseSinks(tc.specs)
+			if tc.expectError {
+				if err == nil {
+					t.Error("Expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(sinks) != len(tc.expected) {
+				t.Fatalf("Expected %d sinks, got %d", len(tc.expected), len(sinks))
+			}
+			for i, sink := range sinks {
+				if sink.Name() != tc.expected[i] {
+					t.Errorf("Expected sink %q, got %q", tc.expected[i], sink.Name())
+				}
+			}
+		})
+	}
+}
+
+func TestRenderPlain(t *testing.T) {
+	now := time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC)
+	got := RenderPlain(testSummary(), now)
+
+	expected := "## 2024-05-01T09:30:00Z\n" +
+		"profile: dev\n" +
+		"kubernetes: dev-cluster\n" +
+		"ecr: ok\n" +
+		"account: 123456789012 (acme-dev)\n\n"
+	if got != expected {
+		t.Errorf("Expected:\n%q\ngot:\n%q", expected, got)
+	}
+}
+
+func TestRenderECRRegion(t *testing.T) {
+	s := testSummary()
+	s.ECRRegion, s.ECRRegionSource = "eu-west-1", "flag"
+
+	if got := RenderTerminal(s); !strings.Contains(got, "ECR login: successful (eu-west-1 from flag)") {
+		t.Errorf("Expected the region and its source in %q", got)
+	}
+	if got := RenderPlain(s, time.Now()); !strings.Contains(got, "ecr: ok (eu-west-1 from flag)\n") {
+		t.Errorf("Expected the region and its source in %q", got)
+	}
+}
+
+func TestRenderECRRegistries(t *testing.T) {
+	s := testSummary()
+	s.ECRSucceeded = false
+	s.ECRRegistries = []ECRRegistry{
+		{Host: "123456789012.dkr.ecr.eu-west-1.amazonaws.com", Error: "docker login failed"},
+		{Host: "public.ecr.aws"},
+	}
+
+	terminal := RenderTerminal(s)
+	for _, expected := range []string{"123456789012.dkr.ecr.eu-west-1.amazonaws.com: failed (docker login failed)", "public.ecr.aws: successful"} {
+		if !strings.Contains(terminal, expected) {
+			t.Errorf("Expected %q in %q", expected, terminal)
+		}
+	}
+	plain := RenderPlain(s, time.Now())
+	for _, expected := range []string{"ecr registry: 123456789012.dkr.ecr.eu-west-1.amazonaws.com failed: docker login failed\n", "ecr registry: public.ecr.aws ok\n"} {
+		if !strings.Contains(plain, expected) {
+			t.Errorf("Expected %q in %q", expected, plain)
+		}
+	}
+
+	// A single registry is described by the ECR line alone
+	s.ECRRegistries = s.ECRRegistries[1:]
+	if got := RenderPlain(s, time.Now()); strings.Contains(got, "ecr registry:") {
+		t.Errorf("Expected no registry list for one registry, got %q", got)
+	}
+}
+
+func TestRenderECRCached(t *testing.T) {
+	s := testSummary()
+	s.ECRCached = true
+
+	if got := RenderTerminal(s); !strings.Contains(got, "ECR login: cached") {
+		t.Errorf("Expected a cached ECR login in %q", got)
+	}
+	if got := RenderPlain(s, time.Now()); !strings.Contains(got, "ecr: cached\n") {
+		t.Errorf("Expected a cached ECR login in %q", got)
+	}
+	if got := RenderCompact(s); !strings.Contains(got, "ECR cached") {
+		t.Errorf("Expected a cached ECR login in %q", got)
+	}
+	if got := s.Report().ECRLogin; got != ECRLoginCached {
+		t.Errorf("Expected ecr_login %q, got %q", ECRLoginCached, got)
+	}
+}
+
+func TestRenderKubernetesSkipped(t *testing.T) {
+	s := testSummary()
+	s.ContextLine, s.Context = "", ""
+	s.KubernetesSkipped = true
+
+	if got := RenderTerminal(s); !strings.Contains(got, "Kubernetes: skipped") {
+		t.Errorf("Expected the terminal summary to say Kubernetes was skipped, got:\n%s", got)
+	}
+	if got := RenderPlain(s, time.Now()); !strings.Contains(got, "kubernetes: skipped\n") {
+		t.Errorf("Expected the plain summary to say Kubernetes was skipped, got:\n%s", got)
+	}
+}
+
+func TestRenderK9sNotInstalled(t *testing.T) {
+	s := testSummary()
+	s.K9sNotInstalled = true
+
+	if got := RenderTerminal(s); !strings.Contains(got, "k9s: not installed") {
+		t.Errorf("Expected the terminal summary to say k9s is missing, got:\n%s", got)
+	}
+	if got := RenderPlain(s, time.Now()); !strings.Contains(got, "k9s: not installed\n") {
+		t.Errorf("Expected the plain summary to say k9s is missing, got:\n%s", got)
+	}
+}
+
+func TestRenderSwitchedFrom(t *testing.T) {
+	s := testSummary()
+	s.SwitchedFrom = "prod"
+
+	if got := RenderTerminal(s); !strings.Contains(got, "Switched: prod → dev") {
+		t.Errorf("Expected the swap direction in %q", got)
+	}
+	if got := RenderPlain(s, time.Now()); !strings.Contains(got, "switched: prod -> dev\n") {
+		t.Errorf("Expected the swap direction in %q", got)
+	}
+}
+
+func TestRenderRegion(t *testing.T) {
+	s := testSummary()
+	s.Region = "eu-west-1"
+
+	if got := RenderTerminal(s); !strings.Contains(got, "Region:"+config.Reset+" eu-west-1") {
+		t.Errorf("Expected the region in %q", got)
+	}
+	if got := RenderPlain(s, time.Now()); !strings.Contains(got, "region: eu-west-1\n") {
+		t.Errorf("Expected the region in %q", got)
+	}
+
+	s.KubeOnly = true
+	if got := RenderTerminal(s); strings.Contains(got, "Region:") {
+		t.Errorf("Expected no region for a kube-only profile, got %q", got)
+	}
+}
+
+func TestRenderAssumedRole(t *testing.T) {
+	s := testSummary()
+	s.AssumedRoleARN = "arn:aws:sts::123456789012:assumed-role/Deploy/me"
+
+	if got := RenderTerminal(s); !strings.Contains(got, "Assumed Role:"+config.Reset+" arn:aws:sts::123456789012:assumed-role/Deploy/me") {
+		t.Errorf("Expected the assumed role in %q", got)
+	}
+	if got := RenderPlain(s, time.Now()); !strings.Contains(got, "assumed role: arn:aws:sts::123456789012:assumed-role/Deploy/me\n") {
+		t.Errorf("Expected the assumed role in %q", got)
+	}
+}
+
+func TestRenderCredentialsExported(t *testing.T) {
+	expiresAt := time.Date(2030, 1, 1, 15, 4, 0, 0, time.Local)
+	testCases := []struct {
+		name      string
+		expiresAt time.Time
+		expected  string
+	}{
+		{"Expiring", expiresAt, "valid until 15:04"},
+		{"Long-term", time.Time{}, "they don't expire"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := testSummary()
+			s.CredentialsExported, s.CredentialsExpireAt = true, tc.expiresAt
+
+			if got := RenderTerminal(s); !strings.Contains(got, "Plaintext credentials exported to the shell, "+tc.expected) {
+				t.Errorf("Expected the credentials warning in %q", got)
+			}
+			if got := RenderPlain(s, time.Now()); !strings.Contains(got, "credentials exported: "+tc.expected+"\n") {
+				t.Errorf("Expected the credentials warning in %q", got)
+			}
+		})
+	}
+	if got := RenderPlain(testSummary(), time.Now()); strings.Contains(got, "credentials exported") {
+		t.Errorf("Expected no credentials warning without an export, got %q", got)
+	}
+}
+
+func TestRenderSessionExpiry(t *testing.T) {
+	now := time.Now()
+	testCases := []struct {
+		name          string
+		left          time.Duration
+		expectedText  string
+		expectedColor string
+	}{
+		{"Hours left", 6*time.Hour + 12*time.Minute + 30*time.Second, "(6h 12m left)", config.Muted},
+		{"Under 30 minutes", 20*time.Minute + 30*time.Second, "(20m left)", config.Warning},
+		{"Under 5 minutes", 3*time.Minute + 30*time.Second, "(3m left); renew it with --force-aws-login", config.Error},
+		{"Expired", -time.Minute, "(expired); renew it with --force-aws-login", config.Error},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := testSummary()
+			s.SessionExpiresAt = now.Add(tc.left)
+			got := s.sessionLine(now)
+			expected := tc.expectedColor + "⏳ Session valid until " + s.SessionExpiresAt.Local().Format("15:04") + " " + tc.expectedText
+			if !strings.HasPrefix(got, expected) {
+				t.Errorf("Expected %q, got %q", expected, got)
+			}
+		})
+	}
+
+	s := testSummary()
+	s.SessionExpiresAt = time.Date(2024, 5, 1, 18, 30, 0, 0, time.Local)
+	expected := "session expires: " + s.SessionExpiresAt.Format(time.RFC3339) + "\n"
+	if got := RenderPlain(s, time.Now()); !strings.Contains(got, expected) {
+		t.Errorf("Expected %q in %q", expected, got)
+	}
+}
+
+func TestRenderCompact(t *testing.T) {
+	s := testSummary()
+	s.ECRSucceeded = false
+	s.Timeouts = []string{"aws sts get-caller-identity timed out after 5s"}
+
+	got := RenderCompact(s)
+	expected := "fancy-login: dev · ⎈ dev-cluster · ECR failed · 1 timed out"
+	if got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+	if strings.Contains(got, "\n") {
+		t.Error("Compact summary must be a single line")
+	}
+}
+
+func TestFileSinkAppendsDailyFile(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC)
+	sink := &FileSink{Path: filepath.Join(dir, "standup-{date}.log"), now: func() time.Time { return now }}
+
+	for i := 0; i < 2; i++ {
+		if err := sink.Write(testSummary()); err != nil {
+			t.Fatalf("Write failed: %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "standup-2024-05-01.log"))
+	if err != nil {
+		t.Fatalf("Expected dated log file: %v", err)
+	}
+	if count := strings.Count(string(data), "## 2024-05-01T09:30:00Z\n"); count != 2 {
+		t.Errorf("Expected 2 appended entries, got %d:\n%s", count, data)
+	}
+	if strings.Contains(string(data), "\033") {
+		t.Error("File summary must not contain escape sequences")
+	}
+}
+
+func TestNotifySink(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (&NotifySink{Out: &buf}).Write(testSummary()); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if !strings.HasPrefix(buf.String(), "\033]9;fancy-login: dev") || !strings.HasSuffix(buf.String(), "\007") {
+		t.Errorf("Expected an OSC 9 notification, got %q", buf.String())
+	}
+}
+
+// failingSink always fails to write
+type failingSink struct{}
+
+func (failingSink) Name() string         { return "broken" }
+func (failingSink) Write(*Summary) error { return errors.New("read-only file system") }
+
+func TestDeliverIsolatesFailingSinks(t *testing.T) {
+	var buf bytes.Buffer
+	var warnings []string
+	sinks := []Sink{failingSink{}, &TerminalSink{Out: &buf}, &FileSink{Path: filepath.Join(t.TempDir(), "missing", "x.log"), now: time.Now}}
+
+	Deliver(testSummary(), sinks, func(msg string) { warnings = append(warnings, msg) })
+
+	if !strings.Contains(buf.String(), "Fancy Login Summary") {
+		t.Error("Expected the terminal sink to receive the summary")
+	}
+	if len(warnings) != 2 {
+		t.Errorf("Expected one warning per failing sink, got %v", warnings)
+	}
+}
+
+func TestJSONSink(t *testing.T) {
+	testCases := []struct {
+		name     string
+		modify   func(s *Summary)
+		expected string
+	}{
+		{
+			name: "Full login",
+			modify: func(s *Summary) {
+				s.ProfileSource = "AWS_DEFAULT_PROFILE"
+				s.Region, s.Namespace = "eu-central-1", "apps"
+				s.SSOLoginPerformed = true
+				s.SessionExpiresAt = time.Date(2024, 5, 1, 17, 42, 0, 0, time.UTC)
+				s.Duration = 2500 * time.Millisecond
+				s.ECRRegion, s.ECRRegionSource = "eu-west-1", "flag"
+			},
+			expected: `{"profile":"dev","profile_source":"AWS_DEFAULT_PROFILE","account_id":"123456789012","region":"eu-central-1","k8s_context":"dev-cluster","namespace":"apps","ecr_login":"success","ecr_region":"eu-west-1","ecr_region_source":"flag","sso_login_performed":true,"session_expires_at":"2024-05-01T17:42:00Z","duration_ms":2500}`,
+		},
+		{
+			name:     "ECR failed",
+			modify:   func(s *Summary) { s.ECRSucceeded = false },
+			expected: `{"profile":"dev","profile_source":"","account_id":"123456789012","region":"","k8s_context":"dev-cluster","namespace":"","ecr_login":"failed","ecr_region":"","ecr_region_source":"","sso_login_performed":false,"session_expires_at":"","duration_ms":0}`,
+		},
+		{
+			name:     "ECR skipped",
+			modify:   func(s *Summary) { s.ECRAttempted, s.ECRSucceeded = false, false },
+			expected: `{"profile":"dev","profile_source":"","account_id":"123456789012","region":"","k8s_context":"dev-cluster","namespace":"","ecr_login":"skipped","ecr_region":"","ecr_region_source":"","sso_login_performed":false,"session_expires_at":"","duration_ms":0}`,
+		},
+		{
+			name: "Kubernetes skipped",
+			modify: func(s *Summary) {
+				s.KubernetesSkipped = true
+				s.Namespace = "apps"
+			},
+			expected: `{"profile":"dev","profile_source":"","account_id":"123456789012","region":"","k8s_context":"","namespace":"","ecr_login":"success","ecr_region":"","ecr_region_source":"","sso_login_performed":false,"session_expires_at":"","duration_ms":0}`,
+		},
+		{
+			name: "Kube-only profile",
+			modify: func(s *Summary) {
+				*s = Summary{Profile: "oidc", ProfileSource: "interactive", KubeOnly: true, Context: "oidc-cluster", Namespace: "default"}
+			},
+			expected: `{"profile":"oidc","profile_source":"interactive","account_id":"","region":"","k8s_context":"oidc-cluster","namespace":"default","ecr_login":"skipped","ecr_region":"","ecr_region_source":"","sso_login_performed":false,"session_expires_at":"","duration_ms":0}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := testSummary()
+			tc.modify(s)
+
+			var buf bytes.Buffer
+			if err := (&JSONSink{Out: &buf}).Write(s); err != nil {
+				t.Fatal(err)
+			}
+			if buf.String() != tc.expected+"\n" {
+				t.Errorf("Expected %s, got %s", tc.expected, buf.String())
+			}
+		})
+	}
+}
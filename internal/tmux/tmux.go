@@ -0,0 +1,137 @@
+package tmux
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Pane options fancy-login sets on the current pane
+const (
+	OptionProfile   = "@fancy_profile"
+	OptionContext   = "@fancy_context"
+	OptionNamespace = "@fancy_namespace"
+)
+
+// Runner runs tmux commands; tests replace it with a fake
+type Runner interface {
+	Run(name string, args ...string) ([]byte, error)
+}
+
+type execRunner struct{}
+
+func (execRunner) Run(name string, args ...string) ([]byte, error) {
+	return exec.Command(name, args...).Output()
+}
+
+// DefaultRunner executes the real tmux binary
+var DefaultRunner Runner = execRunner{}
+
+// Identity is what gets tagged onto a pane
+type Identity struct {
+	Profile   string
+	Context   string
+	Namespace string
+}
+
+// Active reports whether we're running inside tmux
+func Active() bool {
+	return os.Getenv("TMUX") != ""
+}
+
+// Escape doubles '#' so tmux doesn't interpret values as format sequences
+func Escape(value string) string {
+	return strings.ReplaceAll(value, "#", "##")
+}
+
+// TagPane sets the pane title and @fancy_* options for the current pane.
+// Outside tmux it does nothing.
+func TagPane(r Runner, id Identity) error {
+	if !Active() {
+		return nil
+	}
+
+	target := []string{}
+	if pane := os.Getenv("TMUX_PANE"); pane != "" {
+		target = []string{"-t", pane}
+	}
+
+	if _, err := r.Run("tmux", append(append([]string{"select-pane"}, target...), "-T", Escape(Title(id)))...); err != nil {
+		return fmt.Errorf("failed to set tmux pane title: %w", err)
+	}
+
+	options := []struct{ name, value string }{
+		{OptionProfile, id.Profile},
+		{OptionContext, id.Context},
+		{OptionNamespace, id.Namespace},
+	}
+	for _, opt := range options {
+		args := append(append([]string{"set-option", "-p"}, target...), opt.name, opt.value)
+		if opt.value == "" {
+			args = append(append([]string{"set-option", "-p", "-u"}, target...), opt.name)
+		}
+		if _, err := r.Run("tmux", args...); err != nil {
+			return fmt.Errorf("failed to set tmux option %s: %w", opt.name, err)
+		}
+	}
+	return nil
+}
+
+// Title renders the pane title for an identity
+func Title(id Identity) string {
+	title := id.Profile
+	if id.Namespace != "" {
+		title += " ns:" + id.Namespace
+	}
+	return title
+}
+
+// StatusFragment reads the pane options and renders a status-right fragment.
+// pane may be empty to use the current pane. ecr, if not nil, returns a
+// short ECR login marker for the pane's profile.
+func StatusFragment(r Runner, pane string, ecr func(profile string) string) (string, error) {
+	if !Active() {
+		return "", nil
+	}
+
+	format := fmt.Sprintf("#{%s}\t#{%s}\t#{%s}", OptionProfile, OptionContext, OptionNamespace)
+	args := []string{"display-message", "-p"}
+	if pane != "" {
+		args = append(args, "-t", pane)
+	}
+	output, err := r.Run("tmux", append(args, format)...)
+	if err != nil {
+		return "", fmt.Errorf("failed to read tmux pane options: %w", err)
+	}
+
+	fields := strings.Split(strings.TrimRight(string(output), "\n"), "\t")
+	for len(fields) < 3 {
+		fields = append(fields, "")
+	}
+	id := Identity{Profile: fields[0], Context: fields[1], Namespace: fields[2]}
+	marker := ""
+	if ecr != nil && id.Profile != "" {
+		marker = ecr(id.Profile)
+	}
+	return renderStatus(id, marker), nil
+}
+
+// renderStatus formats an identity and ECR marker for the tmux status line
+func renderStatus(id Identity, ecr string) string {
+	if id.Profile == "" {
+		return ""
+	}
+	parts := []string{"☁ " + Escape(id.Profile)}
+	if id.Context != "" {
+		k8s := "⎈ " + Escape(id.Context)
+		if id.Namespace != "" {
+			k8s += "/" + Escape(id.Namespace)
+		}
+		parts = append(parts, k8s)
+	}
+	if ecr != "" {
+		parts = append(parts, Escape(ecr))
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,89 @@
+package tmux
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeRunner records tmux invocations and returns canned output
+type fakeRunner struct {
+	calls  [][]string
+	output string
+}
+
+func (f *fakeRunner) Run(name string, args ...string) ([]byte, error) {
+	f.calls = append(f.calls, append([]string{name}, args...))
+	return []byte(f.output), nil
+}
+
+func TestTagPaneOutsideTmuxIsNoop(t *testing.T) {
+	t.Setenv("TMUX", "")
+	runner := &fakeRunner{}
+
+	if err := TagPane(runner, Identity{Profile: "dev"}); err != nil {
+		t.Fatalf("TagPane failed: %v", err)
+	}
+	if len(runner.calls) != 0 {
+		t.Errorf("Expected no tmux calls outside tmux, got %v", runner.calls)
+	}
+
+	fragment, err := StatusFragment(runner, "", nil)
+	if err != nil || fragment != "" || len(runner.calls) != 0 {
+		t.Errorf("StatusFragment outside tmux should be silent, got %q, %v", fragment, err)
+	}
+}
+
+func TestTagPane(t *testing.T) {
+	t.Setenv("TMUX", "/tmp/tmux-1000/default,1234,0")
+	t.Setenv("TMUX_PANE", "%3")
+	runner := &fakeRunner{}
+
+	id := Identity{Profile: "team#1_DEV", Context: "dev-cluster", Namespace: ""}
+	if err := TagPane(runner, id); err != nil {
+		t.Fatalf("TagPane failed: %v", err)
+	}
+
+	expected := [][]string{
+		{"tmux", "select-pane", "-t", "%3", "-T", "team##1_DEV"},
+		{"tmux", "set-option", "-p", "-t", "%3", "@fancy_profile", "team#1_DEV"},
+		{"tmux", "set-option", "-p", "-t", "%3", "@fancy_context", "dev-cluster"},
+		{"tmux", "set-option", "-p", "-u", "-t", "%3", "@fancy_namespace"},
+	}
+	if !reflect.DeepEqual(runner.calls, expected) {
+		t.Errorf("tmux calls = %v\nexpected %v", runner.calls, expected)
+	}
+}
+
+func TestStatusFragment(t *testing.T) {
+	t.Setenv("TMUX", "/tmp/tmux-1000/default,1234,0")
+
+	testCases := []struct {
+		name     string
+		output   string
+		ecr      string
+		expected string
+	}{
+		{"Full identity", "dev\tdev-cluster\tpayments\n", "", "☁ dev ⎈ dev-cluster/payments"},
+		{"Profile only", "dev\t\t\n", "", "☁ dev"},
+		{"Hash is escaped", "a#b\tc#d\t\n", "", "☁ a##b ⎈ c##d"},
+		{"Untagged pane", "\t\t\n", "🐳…", ""},
+		{"ECR login pending", "dev\tdev-cluster\t\n", "🐳…", "☁ dev ⎈ dev-cluster 🐳…"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runner := &fakeRunner{output: tc.output}
+			fragment, err := StatusFragment(runner, "%1", func(string) string { return tc.ecr })
+			if err != nil {
+				t.Fatalf("StatusFragment failed: %v", err)
+			}
+			if fragment != tc.expected {
+				t.Errorf("StatusFragment = %q, expected %q", fragment, tc.expected)
+			}
+			if !strings.Contains(strings.Join(runner.calls[0], " "), "-t %1") {
+				t.Errorf("Expected pane target in %v", runner.calls[0])
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package uninstall
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"fancy-login/internal/config"
+)
+
+// Target is a single file or entry fancy-login created and can remove
+type Target struct {
+	Name        string
+	Path        string
+	Description string
+	remove      func() error
+}
+
+// Remove deletes the target
+func (t Target) Remove() error {
+	return t.remove()
+}
+
+// Plan lists everything fancy-login may have created that currently exists.
+// ~/.aws and ~/.kube are deliberately never included.
+func Plan(cfg *config.Config, includeBinary bool) []Target {
+	homeDir, _ := os.UserHomeDir()
+	var targets []Target
+
+	addFile := func(name, path, description string) {
+		if _, err := os.Stat(path); err == nil {
+			targets = append(targets, Target{
+				Name:        name,
+				Path:        path,
+				Description: description,
+				remove:      func() error { return os.Remove(path) },
+			})
+		}
+	}
+
+	addFile("config", config.GetFancyConfigPath(), "fancy-login configuration")
+	addFile("profile-export", cfg.AWSProfileTemp, "AWS_PROFILE export script")
+	if runtime.GOOS == "windows" {
+		addFile("profile-export-bat", strings.Replace(cfg.AWSProfileTemp, ".ps1", ".bat", 1), "AWS_PROFILE batch script")
+	}
+	if stateDir := config.GetStateDir(); dirExists(stateDir) {
+		targets = append(targets, Target{
+			Name:        "state",
+			Path:        stateDir,
+			Description: "state, history and cache files",
+			remove:      func() error { return os.RemoveAll(stateDir) },
+		})
+	}
+
+	for _, rc := range shellRCFiles(homeDir) {
+		if hasMarkedBlock(rc) {
+			path := rc
+			targets = append(targets, Target{
+				Name:        "shell:" + filepath.Base(path),
+				Path:        path,
+				Description: "shell integration snippet",
+				remove:      func() error { return removeMarkedBlock(path) },
+			})
+		}
+	}
+
+	dockerConfig := filepath.Join(homeDir, ".docker", "config.json")
+	if registries := fancyCredHelpers(dockerConfig); len(registries) > 0 {
+		targets = append(targets, Target{
+			Name:        "docker-cred-helpers",
+			Path:        dockerConfig,
+			Description: fmt.Sprintf("docker credential helper entries (%s)", strings.Join(registries, ", ")),
+			remove:      func() error { return removeCredHelpers(dockerConfig) },
+		})
+	}
+
+	if includeBinary {
+		binary := filepath.Join(cfg.BinDir, "fancy-login-go")
+		if runtime.GOOS == "windows" {
+			binary += ".exe"
+		}
+		addFile("binary", binary, "fancy-login-go binary")
+	}
+
+	return targets
+}
+
+// dirExists reports whether path is an existing directory
+func dirExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.IsDir()
+}
+
+// shellRCFiles returns the shell startup files that may contain our snippet
+func shellRCFiles(homeDir string) []string {
+	return []string{
+		filepath.Join(homeDir, ".zshrc"),
+		filepath.Join(homeDir, ".bashrc"),
+		filepath.Join(homeDir, ".bash_profile"),
+		filepath.Join(homeDir, ".config", "fish", "config.fish"),
+		filepath.Join(homeDir, "Documents", "PowerShell", "Microsoft.PowerShell_profile.ps1"),
+	}
+}
+
+// hasMarkedBlock reports whether path contains a fancy-login marked snippet
+func hasMarkedBlock(path string) bool {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	return strings.Contains(string(data), config.ShellInitBeginMarker)
+}
+
+// removeMarkedBlock strips every fancy-login marked snippet from path
+func removeMarkedBlock(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var kept []string
+	inBlock := false
+	for _, line := range strings.Split(string(data), "\n") {
+		switch {
+		case strings.TrimSpace(line) == config.ShellInitBeginMarker:
+			inBlock = true
+		case strings.TrimSpace(line) == config.ShellInitEndMarker:
+			inBlock = false
+		case !inBlock:
+			kept = append(kept, line)
+		}
+	}
+	if inBlock {
+		return fmt.Errorf("unterminated fancy-login block, edit %s manually", path)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(strings.Join(kept, "\n")), info.Mode().Perm())
+}
+
+// fancyCredHelpers returns the registries whose credential helper is fancy-login
+func fancyCredHelpers(path string) []string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var dockerConfig struct {
+		CredHelpers map[string]string `json:"credHelpers"`
+	}
+	if err := json.Unmarshal(data, &dockerConfig); err != nil {
+		return nil
+	}
+
+	var registries []string
+	for registry, helper := range dockerConfig.CredHelpers {
+		if helper == config.DockerCredHelperName {
+			registries = append(registries, registry)
+		}
+	}
+	return registries
+}
+
+// removeCredHelpers removes fancy-login credHelpers entries, keeping everything else
+func removeCredHelpers(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	if helpers, ok := raw["credHelpers"].(map[string]interface{}); ok {
+		for registry, helper := range helpers {
+			if helper == config.DockerCredHelperName {
+				delete(helpers, registry)
+			}
+		}
+		if len(helpers) == 0 {
+			delete(raw, "credHelpers")
+		}
+	}
+
+	out, err := json.MarshalIndent(raw, "", "\t")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(out, '\n'), 0600)
+}
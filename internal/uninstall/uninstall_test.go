@@ -0,0 +1,102 @@
+package uninstall
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"fancy-login/internal/config"
+)
+
+func setupHome(t *testing.T) string {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	t.Setenv("FANCY_STATE_DIR", filepath.Join(home, ".fancy-login"))
+	return home
+}
+
+func TestPlanNeverTouchesAWSOrKube(t *testing.T) {
+	home := setupHome(t)
+	for _, dir := range []string{".aws", ".kube"} {
+		os.MkdirAll(filepath.Join(home, dir), 0755)
+		os.WriteFile(filepath.Join(home, dir, "config"), []byte("x"), 0644)
+	}
+	os.WriteFile(filepath.Join(home, ".fancy-config.yaml"), []byte("settings: {}\n"), 0644)
+
+	cfg := &config.Config{AWSProfileTemp: filepath.Join(home, "aws_profile.sh"), BinDir: filepath.Join(home, "bin")}
+	targets := Plan(cfg, true)
+
+	for _, target := range targets {
+		if strings.Contains(target.Path, ".aws") || strings.Contains(target.Path, ".kube") {
+			t.Errorf("Plan must not include %s", target.Path)
+		}
+	}
+	if len(targets) != 1 || targets[0].Name != "config" {
+		t.Errorf("Expected only the config target, got %+v", targets)
+	}
+}
+
+func TestRemoveMarkedBlock(t *testing.T) {
+	home := setupHome(t)
+	rc := filepath.Join(home, ".zshrc")
+	content := strings.Join([]string{
+		"export EDITOR=vim",
+		config.ShellInitBeginMarker,
+		"fancy() { fancy-login-go \"$@\"; }",
+		config.ShellInitEndMarker,
+		"alias ll='ls -l'",
+	}, "\n")
+	os.WriteFile(rc, []byte(content), 0644)
+
+	targets := Plan(&config.Config{}, false)
+	if len(targets) != 1 || targets[0].Name != "shell:.zshrc" {
+		t.Fatalf("Expected shell target, got %+v", targets)
+	}
+	if err := targets[0].Remove(); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+
+	data, _ := os.ReadFile(rc)
+	if string(data) != "export EDITOR=vim\nalias ll='ls -l'" {
+		t.Errorf("Unexpected rc content after removal: %q", string(data))
+	}
+}
+
+func TestRemoveCredHelpers(t *testing.T) {
+	home := setupHome(t)
+	dockerDir := filepath.Join(home, ".docker")
+	os.MkdirAll(dockerDir, 0755)
+	dockerConfig := filepath.Join(dockerDir, "config.json")
+	os.WriteFile(dockerConfig, []byte(`{
+		"auths": {"ghcr.io": {}},
+		"credHelpers": {
+			"123456789012.dkr.ecr.eu-central-1.amazonaws.com": "fancy-login",
+			"gcr.io": "gcloud"
+		}
+	}`), 0600)
+
+	targets := Plan(&config.Config{}, false)
+	if len(targets) != 1 || targets[0].Name != "docker-cred-helpers" {
+		t.Fatalf("Expected docker target, got %+v", targets)
+	}
+	if err := targets[0].Remove(); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+
+	var result map[string]interface{}
+	data, _ := os.ReadFile(dockerConfig)
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatalf("Docker config is no longer valid JSON: %v", err)
+	}
+	helpers := result["credHelpers"].(map[string]interface{})
+	if len(helpers) != 1 || helpers["gcr.io"] != "gcloud" {
+		t.Errorf("Expected only foreign helpers to remain, got %v", helpers)
+	}
+	if _, ok := result["auths"]; !ok {
+		t.Error("Unrelated docker config keys must be preserved")
+	}
+}
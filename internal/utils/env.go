@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"sort"
+	"strings"
+)
+
+// awsScopedVars are tied to a single profile and must not leak from a
+// previous login into children started for another profile. Credentials
+// count too: an exported AWS_ACCESS_KEY_ID wins over AWS_PROFILE.
+var awsScopedVars = []string{
+	"AWS_PROFILE", "AWS_DEFAULT_PROFILE", "AWS_REGION", "AWS_DEFAULT_REGION",
+	"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN", "AWS_SECURITY_TOKEN", "AWS_CREDENTIAL_EXPIRATION",
+}
+
+// BuildChildEnv derives a child process environment from parent. By
+// default every inherited AWS profile, region and credential variable is
+// stripped and only the resolved values are set. With inherit the parent's
+// AWS variables are kept as they are and resolved values only fill in the
+// ones it doesn't set. It also returns the names of variables whose
+// inherited value was replaced or removed.
+func BuildChildEnv(parent []string, resolved map[string]string, inherit bool) ([]string, []string) {
+	strip := make(map[string]bool)
+	for _, name := range awsScopedVars {
+		strip[name] = true
+	}
+	for name := range resolved {
+		strip[name] = true
+	}
+
+	var env []string
+	inherited := make(map[string]bool)
+	overridden := make(map[string]bool)
+	for _, entry := range parent {
+		name, value, _ := strings.Cut(entry, "=")
+		if !strip[name] || inherit {
+			env = append(env, entry)
+			inherited[name] = true
+			continue
+		}
+		if newValue, ok := resolved[name]; !ok || newValue != value {
+			overridden[name] = true
+		}
+	}
+
+	names := make([]string, 0, len(resolved))
+	for name := range resolved {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if resolved[name] != "" && !inherited[name] {
+			env = append(env, name+"="+resolved[name])
+		}
+	}
+
+	var changed []string
+	for name := range overridden {
+		changed = append(changed, name)
+	}
+	sort.Strings(changed)
+	return env, changed
+}
+
+// ExtendEnv returns parent with extra added, or nil, which makes a child
+// inherit this process's environment, when extra is empty
+func ExtendEnv(parent []string, extra map[string]string) []string {
+	if len(extra) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(extra))
+	for name := range extra {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	env := append([]string{}, parent...)
+	for _, name := range names {
+		env = append(env, name+"="+extra[name])
+	}
+	return env
+}
@@ -0,0 +1,112 @@
+package utils
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestBuildChildEnv(t *testing.T) {
+	testCases := []struct {
+		name               string
+		parent             []string
+		resolved           map[string]string
+		inherit            bool
+		expectedEnv        []string
+		expectedOverridden []string
+	}{
+		{
+			name:               "Stale region from previous profile is replaced",
+			parent:             []string{"PATH=/bin", "AWS_PROFILE=old", "AWS_REGION=us-east-1"},
+			resolved:           map[string]string{"AWS_PROFILE": "prod", "AWS_REGION": "eu-central-1"},
+			expectedEnv:        []string{"AWS_PROFILE=prod", "AWS_REGION=eu-central-1", "PATH=/bin"},
+			expectedOverridden: []string{"AWS_PROFILE", "AWS_REGION"},
+		},
+		{
+			name:               "Unresolved AWS variables are stripped",
+			parent:             []string{"HOME=/home/me", "AWS_DEFAULT_REGION=us-west-2", "AWS_DEFAULT_PROFILE=legacy"},
+			resolved:           map[string]string{"AWS_PROFILE": "dev"},
+			expectedEnv:        []string{"AWS_PROFILE=dev", "HOME=/home/me"},
+			expectedOverridden: []string{"AWS_DEFAULT_PROFILE", "AWS_DEFAULT_REGION"},
+		},
+		{
+			name:               "Identical values are not reported",
+			parent:             []string{"AWS_PROFILE=dev"},
+			resolved:           map[string]string{"AWS_PROFILE": "dev"},
+			expectedEnv:        []string{"AWS_PROFILE=dev"},
+			expectedOverridden: nil,
+		},
+		{
+			name:               "Stale exported credentials are stripped",
+			parent:             []string{"AWS_ACCESS_KEY_ID=ASIAOLD", "AWS_SECRET_ACCESS_KEY=old", "AWS_SESSION_TOKEN=old"},
+			resolved:           map[string]string{"AWS_PROFILE": "dev"},
+			expectedEnv:        []string{"AWS_PROFILE=dev"},
+			expectedOverridden: []string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN"},
+		},
+		{
+			name:               "InheritAWSEnv keeps the inherited AWS variables",
+			parent:             []string{"AWS_DEFAULT_REGION=us-west-2", "AWS_PROFILE=old", "AWS_SESSION_TOKEN=vault"},
+			resolved:           map[string]string{"AWS_PROFILE": "dev", "AWS_DEFAULT_REGION": "eu-central-1"},
+			inherit:            true,
+			expectedEnv:        []string{"AWS_DEFAULT_REGION=us-west-2", "AWS_PROFILE=old", "AWS_SESSION_TOKEN=vault"},
+			expectedOverridden: nil,
+		},
+		{
+			name:               "InheritAWSEnv fills in unset variables",
+			parent:             []string{"AWS_PROFILE=old"},
+			resolved:           map[string]string{"AWS_PROFILE": "dev", "AWS_REGION": "eu-central-1"},
+			inherit:            true,
+			expectedEnv:        []string{"AWS_PROFILE=old", "AWS_REGION=eu-central-1"},
+			expectedOverridden: nil,
+		},
+		{
+			name:               "Nothing resolved still strips inherited AWS variables",
+			parent:             []string{"PATH=/bin", "AWS_PROFILE=old", "AWS_REGION=us-east-1"},
+			resolved:           nil,
+			expectedEnv:        []string{"PATH=/bin"},
+			expectedOverridden: []string{"AWS_PROFILE", "AWS_REGION"},
+		},
+		{
+			name:               "Empty resolved value removes the variable",
+			parent:             []string{"AWS_REGION=us-east-1"},
+			resolved:           map[string]string{"AWS_PROFILE": "dev", "AWS_REGION": ""},
+			expectedEnv:        []string{"AWS_PROFILE=dev"},
+			expectedOverridden: []string{"AWS_REGION"},
+		},
+		{
+			name:               "Other variables are untouched",
+			parent:             []string{"KUBECONFIG=/tmp/kube", "AWS_SDK_LOAD_CONFIG=1"},
+			resolved:           map[string]string{"AWS_PROFILE": "dev"},
+			expectedEnv:        []string{"AWS_PROFILE=dev", "AWS_SDK_LOAD_CONFIG=1", "KUBECONFIG=/tmp/kube"},
+			expectedOverridden: nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			env, overridden := BuildChildEnv(tc.parent, tc.resolved, tc.inherit)
+			sort.Strings(env)
+
+			if !reflect.DeepEqual(env, tc.expectedEnv) {
+				t.Errorf("env = %v, expected %v", env, tc.expectedEnv)
+			}
+			if !reflect.DeepEqual(overridden, tc.expectedOverridden) {
+				t.Errorf("overridden = %v, expected %v", overridden, tc.expectedOverridden)
+			}
+		})
+	}
+}
+
+func TestExtendEnv(t *testing.T) {
+	parent := []string{"HOME=/home/me"}
+	if env := ExtendEnv(parent, nil); env != nil {
+		t.Errorf("Expected nil to inherit the environment, got %v", env)
+	}
+	env := ExtendEnv(parent, map[string]string{"B": "2", "A": "1"})
+	if expected := []string{"HOME=/home/me", "A=1", "B=2"}; !reflect.DeepEqual(env, expected) {
+		t.Errorf("env = %v, expected %v", env, expected)
+	}
+	if len(parent) != 1 {
+		t.Errorf("Expected parent to be left alone, got %v", parent)
+	}
+}
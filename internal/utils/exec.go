@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"time"
+)
+
+// TimeoutError reports that a step was cancelled because it exceeded its timeout
+type TimeoutError struct {
+	Step    string
+	Timeout time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("%s hung and was cancelled after %s; check your network or VPN connection, or raise its timeout in settings", e.Step, e.Timeout)
+}
+
+// SelectionTimeoutError reports that a picker got no choice in time. It
+// names the setting and flag that change the timeout.
+type SelectionTimeoutError struct {
+	What    string // e.g. "profile selection"
+	Timeout time.Duration
+}
+
+func (e *SelectionTimeoutError) Error() string {
+	return fmt.Sprintf("%s timed out after %s; raise selection_timeout in settings or pass --select-timeout (0 waits forever)", e.What, e.Timeout)
+}
+
+// CommandContext creates a command bound to ctx. When ctx is cancelled the
+// whole process tree is killed, not just the direct child, so no helper
+// spawned by aws/docker/kubectl can outlive fancy-login.
+func CommandContext(ctx context.Context, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	setProcessGroup(cmd)
+	cmd.Cancel = func() error {
+		return killProcessTree(cmd)
+	}
+	// Don't hang on pipes still held open by orphaned grandchildren
+	cmd.WaitDelay = 2 * time.Second
+	return cmd
+}
+
+// WithStepTimeout derives a context for a named step that expires after timeout
+func WithStepTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// StepError converts err into a TimeoutError when ctx hit its deadline, and
+// into ErrCancelled when ctx was cancelled, e.g. by Ctrl-C
+func StepError(ctx context.Context, step string, timeout time.Duration, err error) error {
+	if err == nil {
+		return nil
+	}
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return &TimeoutError{Step: step, Timeout: timeout}
+	case errors.Is(ctx.Err(), context.Canceled):
+		return fmt.Errorf("%s: %w", step, ErrCancelled)
+	}
+	return err
+}
@@ -0,0 +1,117 @@
+//go:build !windows
+
+package utils
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// processAlive reports whether pid is still running (zombies count as dead)
+func processAlive(pid int) bool {
+	if err := syscall.Kill(pid, 0); err != nil {
+		return false
+	}
+	stat, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return true
+	}
+	fields := strings.Fields(string(stat))
+	return len(fields) < 3 || fields[2] != "Z"
+}
+
+func TestCommandContextKillsProcessTree(t *testing.T) {
+	dir := t.TempDir()
+	pidFile := filepath.Join(dir, "child.pid")
+	script := filepath.Join(dir, "fake-sleeper")
+
+	// The fake binary spawns a grandchild and then sleeps itself
+	content := "#!/bin/sh\nsleep 30 &\necho $! > " + pidFile + "\nsleep 30\n"
+	if err := os.WriteFile(script, []byte(content), 0755); err != nil {
+		t.Fatalf("Failed to write fake binary: %v", err)
+	}
+
+	timeout := 300 * time.Millisecond
+	ctx, cancel := WithStepTimeout(context.Background(), timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := CommandContext(ctx, script).Run()
+	if err == nil {
+		t.Fatal("Expected command to be killed")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Command outlived its timeout: took %v", elapsed)
+	}
+
+	var timeoutErr *TimeoutError
+	if !errors.As(StepError(ctx, "fake", timeout, err), &timeoutErr) {
+		t.Fatalf("Expected TimeoutError, got %v", err)
+	}
+	if timeoutErr.Error() != "fake hung and was cancelled after 300ms; check your network or VPN connection, or raise its timeout in settings" {
+		t.Errorf("Unexpected timeout message: %s", timeoutErr.Error())
+	}
+
+	data, err := os.ReadFile(pidFile)
+	if err != nil {
+		t.Fatalf("Fake binary did not record its child: %v", err)
+	}
+	childPID, _ := strconv.Atoi(strings.TrimSpace(string(data)))
+
+	deadline := time.Now().Add(2 * time.Second)
+	for processAlive(childPID) && time.Now().Before(deadline) {
+		time.Sleep(20 * time.Millisecond)
+	}
+	if processAlive(childPID) {
+		syscall.Kill(childPID, syscall.SIGKILL)
+		t.Errorf("Grandchild process %d survived cancellation", childPID)
+	}
+}
+
+func TestStepErrorWithoutTimeout(t *testing.T) {
+	ctx := context.Background()
+	original := errors.New("boom")
+	if err := StepError(ctx, "step", time.Second, original); err != original {
+		t.Errorf("Expected original error, got %v", err)
+	}
+	if err := StepError(ctx, "step", time.Second, nil); err != nil {
+		t.Errorf("Expected nil, got %v", err)
+	}
+}
+
+func TestStepErrorCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := StepError(ctx, "aws sso login", time.Second, errors.New("signal: killed"))
+	if !errors.Is(err, ErrCancelled) {
+		t.Fatalf("Expected ErrCancelled, got %v", err)
+	}
+	if code := ExitCode(err, ExitFailure); code != ExitCancelled {
+		t.Errorf("Expected exit code %d, got %d", ExitCancelled, code)
+	}
+}
+
+func TestProcessAlive(t *testing.T) {
+	if !ProcessAlive(os.Getpid()) {
+		t.Error("Expected the test process to be alive")
+	}
+
+	cmd := exec.Command("true")
+	if err := cmd.Run(); err != nil {
+		t.Fatalf("Failed to run true: %v", err)
+	}
+	if ProcessAlive(cmd.Process.Pid) {
+		t.Errorf("Expected reaped pid %d to be dead", cmd.Process.Pid)
+	}
+	if ProcessAlive(0) {
+		t.Error("Expected pid 0 to be rejected")
+	}
+}
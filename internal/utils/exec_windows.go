@@ -0,0 +1,38 @@
+//go:build windows
+
+package utils
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+)
+
+// setProcessGroup is a no-op on Windows; taskkill /T walks the tree instead
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// killProcessTree kills the command and all of its descendants
+func killProcessTree(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	kill := exec.Command("taskkill", "/T", "/F", "/PID", strconv.Itoa(cmd.Process.Pid))
+	if err := kill.Run(); err != nil {
+		return cmd.Process.Kill()
+	}
+	return nil
+}
+
+// ProcessAlive reports whether a process with the given pid exists. On
+// Windows FindProcess opens a handle, which fails for exited processes.
+func ProcessAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	p.Release()
+	return true
+}
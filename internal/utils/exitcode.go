@@ -0,0 +1,72 @@
+package utils
+
+import "errors"
+
+// Exit codes of fancy-login-go. They are documented in the README for
+// wrapper scripts, so existing values must never change meaning.
+const (
+	ExitOK      = 0
+	ExitFailure = 1
+	// ExitUsage is used for invalid flags and arguments
+	ExitUsage = 2
+	// ExitMissingDependency is the exit code used when a required external
+	// tool (aws, kubectl, fzf, ...) is not installed
+	ExitMissingDependency = 3
+	// ExitSessionExpired is the exit code used when a profile's session has
+	// expired or was never started, so scripts know to log in again
+	ExitSessionExpired = 4
+	// ExitCancelled is used when the user backed out of a picker or prompt
+	ExitCancelled = 5
+	// ExitAWSAuth is used when the AWS SSO login or credential check failed
+	ExitAWSAuth = 6
+	// ExitKubernetes is used when the Kubernetes context or the kube-only
+	// login hook failed
+	ExitKubernetes = 7
+	// ExitECR is used when the ECR login failed
+	ExitECR = 8
+	// ExitConfig is used when the AWS or fancy-login configuration is
+	// missing, invalid or doesn't know the requested profile
+	ExitConfig = 9
+)
+
+// ErrCancelled is returned when the user backed out of a picker or prompt
+var ErrCancelled = errors.New("cancelled by user")
+
+// ExitError attaches the exit code a failure should end the process with
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// WithExitCode classifies err with code; nil stays nil
+func WithExitCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &ExitError{Code: code, Err: err}
+}
+
+// ExitCode maps err to the exit code it should end the process with. A
+// cancellation wins over any classification around it, since the user
+// chose to stop; errors nobody classified map to fallback.
+func ExitCode(err error, fallback int) int {
+	if err == nil {
+		return ExitOK
+	}
+	if errors.Is(err, ErrCancelled) {
+		return ExitCancelled
+	}
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+	return fallback
+}
@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestExitCode(t *testing.T) {
+	testCases := []struct {
+		name     string
+		err      error
+		fallback int
+		expected int
+	}{
+		{"Success", nil, ExitAWSAuth, ExitOK},
+		{"Unclassified", errors.New("boom"), ExitAWSAuth, ExitAWSAuth},
+		{"Cancelled", ErrCancelled, ExitAWSAuth, ExitCancelled},
+		{"Wrapped cancellation", fmt.Errorf("no profile selected: %w", ErrCancelled), ExitConfig, ExitCancelled},
+		{"Classified", WithExitCode(ExitMissingDependency, errors.New("aws not installed")), ExitConfig, ExitMissingDependency},
+		{"Wrapped classification", fmt.Errorf("select: %w", WithExitCode(ExitConfig, errors.New("no profiles"))), ExitFailure, ExitConfig},
+		{"Cancellation wins", WithExitCode(ExitECR, fmt.Errorf("prompt: %w", ErrCancelled)), ExitFailure, ExitCancelled},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := ExitCode(tc.err, tc.fallback); got != tc.expected {
+				t.Errorf("Expected %d, got %d", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestWithExitCodeNil(t *testing.T) {
+	if err := WithExitCode(ExitConfig, nil); err != nil {
+		t.Errorf("Expected nil, got %v", err)
+	}
+}
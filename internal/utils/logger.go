@@ -2,15 +2,22 @@ package utils
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"sync"
 	"time"
 
+	"fancy-login/internal/a11y"
 	"fancy-login/internal/config"
 )
 
 // Logger provides logging functionality
 type Logger struct {
 	verbose bool
+	// out receives every log line; nil means stdout
+	out io.Writer
+	// exitHooks run before Die exits, so fatal errors still flush state
+	exitHooks []func()
 }
 
 // NewLogger creates a new logger instance
@@ -18,59 +25,111 @@ func NewLogger(verbose bool) *Logger {
 	return &Logger{verbose: verbose}
 }
 
+// SetOutput sends the log to out instead of stdout, e.g. to keep stdout
+// free for JSON output
+func (l *Logger) SetOutput(out io.Writer) {
+	l.out = out
+}
+
+// Output returns where the log goes, for child processes and progress
+// lines that belong with it
+func (l *Logger) Output() io.Writer {
+	if l.out == nil {
+		return os.Stdout
+	}
+	return l.out
+}
+
 // FancyLog prints debug messages when verbose mode is enabled
 func (l *Logger) FancyLog(message string) {
 	if l.verbose {
-		fmt.Printf("[fancy-login] %s\n", message)
+		l.printf("[fancy-login] %s\n", message)
 	}
 }
 
 // LogInfo prints informational messages
 func (l *Logger) LogInfo(message string) {
-	fmt.Printf("%s🔹 %s%s\n", config.Cyan, message, config.Reset)
+	l.printf("%s🔹 %s%s\n", config.Accent, message, config.Reset)
 }
 
 // LogSuccess prints success messages (only in verbose mode)
 func (l *Logger) LogSuccess(message string) {
 	if l.verbose {
-		fmt.Printf("%s✅ %s%s\n", config.Green, message, config.Reset)
+		l.printf("%s✅ %s%s\n", config.Success, message, config.Reset)
 	}
 }
 
-// LogWarning prints warning messages
+// LogWarning prints warning messages. Without its icon in screen reader
+// mode, the severity is spelled out.
 func (l *Logger) LogWarning(message string) {
-	fmt.Printf("%s⚠️ %s%s\n", config.Yellow, message, config.Reset)
+	if a11y.Enabled() {
+		l.printf("warning: %s\n", message)
+		return
+	}
+	l.printf("%s⚠️ %s%s\n", config.Warning, message, config.Reset)
 }
 
-// LogError prints error messages
+// LogError prints error messages, spelling out the severity in screen
+// reader mode like LogWarning
 func (l *Logger) LogError(message string) {
-	fmt.Printf("%s❌ %s%s\n", config.Red, message, config.Reset)
+	if a11y.Enabled() {
+		l.printf("error: %s\n", message)
+		return
+	}
+	l.printf("%s❌ %s%s\n", config.Error, message, config.Reset)
+}
+
+// LogPlanned prints an action a dry run would have taken
+func (l *Logger) LogPlanned(action string) {
+	l.printf("%s🔸 Would %s%s\n", config.Accent, action, config.Reset)
 }
 
 // LogCompletion prints completion messages (only in verbose mode)
 func (l *Logger) LogCompletion(message string) {
 	if l.verbose {
-		fmt.Printf("\n%s🎉 %s%s\n", config.Cyan, message, config.Reset)
+		l.printf("\n%s🎉 %s%s\n", config.Accent, message, config.Reset)
 	}
 }
 
-// Die prints error and exits
+// OnExit registers a function to run when Die or DieWithCode exits
+func (l *Logger) OnExit(hook func()) {
+	l.exitHooks = append(l.exitHooks, hook)
+}
+
+// Die prints error and exits. Only the cmd package ends the process;
+// everything below it returns errors.
 func (l *Logger) Die(message string) {
+	l.DieWithCode(message, ExitFailure)
+}
+
+// DieWithCode prints error and exits with the given code
+func (l *Logger) DieWithCode(message string, code int) {
 	l.LogError(message)
-	os.Exit(1)
+	for _, hook := range l.exitHooks {
+		hook()
+	}
+	os.Exit(code)
+}
+
+// printf prints a log line, stripped of decoration in screen reader mode
+func (l *Logger) printf(format string, args ...any) {
+	fmt.Fprintf(a11y.Writer(l.Output()), format, args...)
 }
 
 // Spinner represents a loading spinner
 type Spinner struct {
+	mu      sync.Mutex
+	out     io.Writer
 	message string
 	chars   []rune
 	index   int
 	running bool
 }
 
-// NewSpinner creates a new spinner
-func NewSpinner(message string) *Spinner {
+// NewSpinner creates a new spinner drawing on out
+func NewSpinner(out io.Writer, message string) *Spinner {
 	return &Spinner{
+		out:     out,
 		message: message,
 		chars:   []rune{'|', '/', '-', '\\'},
 		index:   0,
@@ -78,20 +137,58 @@ func NewSpinner(message string) *Spinner {
 	}
 }
 
-// Start begins the spinner animation
+// Start begins the spinner animation. In screen reader mode the message is
+// printed once instead, since redraws are read out over and over.
 func (s *Spinner) Start() {
+	if a11y.Enabled() {
+		fmt.Fprintf(a11y.Writer(s.out), "%s\n", s.message)
+		return
+	}
 	s.running = true
 	go func() {
-		for s.running {
-			fmt.Printf("\r%s%s %c %s", config.Cyan, s.message, s.chars[s.index], config.Reset)
+		for {
+			s.mu.Lock()
+			if !s.running {
+				s.mu.Unlock()
+				return
+			}
+			fmt.Fprintf(s.out, "\r%s%s %c %s", config.Accent, s.message, s.chars[s.index], config.Reset)
+			s.mu.Unlock()
 			s.index = (s.index + 1) % len(s.chars)
 			time.Sleep(100 * time.Millisecond)
 		}
 	}()
 }
 
+// SetMessage replaces the message of a running spinner. In screen reader
+// mode the new message is printed once.
+func (s *Spinner) SetMessage(message string) {
+	s.mu.Lock()
+	s.message = message
+	s.mu.Unlock()
+	if a11y.Enabled() {
+		fmt.Fprintf(a11y.Writer(s.out), "%s\n", message)
+	}
+}
+
+// Println prints a line above the spinner, which keeps running below it
+// instead of overwriting the line
+func (s *Spinner) Println(line string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !a11y.Enabled() {
+		fmt.Fprintf(s.out, "\r%60s\r", "")
+	}
+	fmt.Fprintf(a11y.Writer(s.out), "%s\n", line)
+}
+
 // Stop stops the spinner and clears the line
 func (s *Spinner) Stop() {
+	if a11y.Enabled() {
+		return
+	}
+	s.mu.Lock()
 	s.running = false
-	fmt.Printf("\r%60s\r", "") // Clear the line
+	fmt.Fprintf(s.out, "\r%60s\r", "") // Clear the line
+	s.mu.Unlock()
 }
@@ -231,6 +231,30 @@ func TestMultipleLogCalls(t *testing.T) {
 	}
 }
 
+func TestLoggerSetOutput(t *testing.T) {
+	if out := NewLogger(false).Output(); out != os.Stdout {
+		t.Errorf("Output() = %v, want os.Stdout by default", out)
+	}
+
+	var buf bytes.Buffer
+	logger := NewLogger(true)
+	logger.SetOutput(&buf)
+
+	stdout := captureOutput(func() {
+		logger.LogInfo("to the writer")
+		logger.FancyLog("debug to the writer")
+	})
+
+	if stdout != "" {
+		t.Errorf("stdout should be empty, got: %q", stdout)
+	}
+	for _, msg := range []string{"to the writer", "debug to the writer"} {
+		if !strings.Contains(buf.String(), msg) {
+			t.Errorf("writer should contain %q, got: %q", msg, buf.String())
+		}
+	}
+}
+
 // Benchmark logger operations
 func BenchmarkNewLogger(b *testing.B) {
 	for i := 0; i < b.N; i++ {
@@ -121,18 +121,12 @@ if (Test-Path "$ProjectDir\.fancy-contexts.conf") {
     Write-FancyLog "Warning: .fancy-contexts.conf not found, skipping"
 }
 
-# Copy shell integration files
-Write-FancyLog "Copying shell integration files"
-if (Test-Path "$ScriptDir\fancy-go.ps1") {
-    Copy-Item "$ScriptDir\fancy-go.ps1" "$BinDir\fancy-go.ps1" -Force
-} else {
-    Write-FancyLog "Warning: fancy-go.ps1 not found, skipping"
-}
-
-if (Test-Path "$ScriptDir\fancy-go.bat") {
-    Copy-Item "$ScriptDir\fancy-go.bat" "$BinDir\fancy-go.bat" -Force
-} else {
-    Write-FancyLog "Warning: fancy-go.bat not found, skipping"
+# Write the Command Prompt integration; PowerShell loads its own from init
+Write-FancyLog "Writing $BinDir\fancy.bat"
+& "$BinDir\fancy-login-go.exe" init cmd | Set-Content "$BinDir\fancy.bat" -Encoding ascii
+if ($LASTEXITCODE -ne 0) {
+    Write-Error "Failed to write fancy.bat"
+    exit 1
 }
 
 # Add to PATH if not already there
@@ -151,7 +145,7 @@ Write-Host "------------------------------------------------------------" -Foreg
 Write-Host "# Add the following to your PowerShell profile:"
 Write-Host "# To edit profile: notepad `$PROFILE"
 Write-Host ""
-Write-Host ". `"$BinDir\fancy-go.ps1`""
+Write-Host "fancy-login-go.exe init powershell | Out-String | Invoke-Expression"
 Write-Host ""
 Write-Host "# Then restart PowerShell or run:"
 Write-Host ". `$PROFILE"
@@ -159,12 +153,10 @@ Write-Host "------------------------------------------------------------" -Foreg
 
 Write-Host "`n🔧 Command Prompt Setup:" -ForegroundColor Yellow
 Write-Host "------------------------------------------------------------" -ForegroundColor Gray
-Write-Host "# Add the following to a batch file in your PATH:"
-Write-Host "`"$BinDir\fancy-go.bat`" %*"
+Write-Host "# $BinDir\fancy.bat is on your PATH; nothing else to do"
 Write-Host "------------------------------------------------------------" -ForegroundColor Gray
 
 Write-Host "`n🚀 Usage:" -ForegroundColor Green
-Write-Host "   PowerShell: fancy-go or fancy"
-Write-Host "   Command Prompt: fancy-go.bat"
+Write-Host "   PowerShell and Command Prompt: fancy"
 Write-Host "   Direct: fancy-login-go.exe --help"
 Write-Host "`n   Test with: fancy-login-go.exe --help" -ForegroundColor Cyan
\ No newline at end of file
@@ -89,15 +89,8 @@ echo "------------------------------------------------------------"
 echo "export PATH=\"\$HOME/.local/bin:\$PATH\""
 echo ""
 echo "# Fancy login function (Go version)"
-echo "fancy-go() {"
-echo "  if fancy-login-go \"\$@\"; then"
-echo "    [[ -f /tmp/aws_profile.sh ]] && source /tmp/aws_profile.sh"
-echo "  fi"
-echo "}"
-echo ""
-echo "# Or create an alias for the Go version"
-echo "alias fancy='fancy-go'"
+echo "eval \"\$(fancy-login-go init zsh)\""
 echo "------------------------------------------------------------"
 echo "\nThen run: source ~/.zshrc"
-echo "\n🚀 You can now run the Go version using: fancy-login-go or fancy-go"
+echo "\n🚀 You can now run the Go version using: fancy-login-go or fancy"
 echo "   Test with: fancy-login-go --help"
\ No newline at end of file
@@ -0,0 +1,46 @@
+// Command krewmanifest writes the krew plugin manifest for the archives
+// `make release` created. It is run by `make krew-manifest`.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"fancy-login/internal/kubeplugin"
+)
+
+func main() {
+	version := flag.String("version", "", "Release tag, e.g. v1.2.0")
+	dir := flag.String("dir", "build/release", "Directory with the release archives")
+	baseURL := flag.String("base-url", "https://github.com/reinkes/go-fancy-login/releases/download", "URL the release tags are downloaded from")
+	output := flag.String("o", "", "Write the manifest to this file instead of stdout")
+	flag.Parse()
+
+	// krew only accepts semantic versions with a leading v
+	if !strings.HasPrefix(*version, "v") {
+		fmt.Fprintf(os.Stderr, "krewmanifest: version must be a release tag like v1.2.0, got %q\n", *version)
+		os.Exit(2)
+	}
+
+	archives, err := kubeplugin.ReleaseArchives(*dir, strings.TrimSuffix(*baseURL, "/")+"/"+*version)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "krewmanifest: %v\n", err)
+		os.Exit(1)
+	}
+	manifest, err := kubeplugin.Manifest(*version, archives)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "krewmanifest: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *output == "" {
+		os.Stdout.Write(manifest)
+		return
+	}
+	if err := os.WriteFile(*output, manifest, 0644); err != nil {
+		fmt.Fprintf(os.Stderr, "krewmanifest: %v\n", err)
+		os.Exit(1)
+	}
+}